@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// auditTable records every insert, update and delete of the data tables,
+// with a JSON snapshot of the row before and after the change. Triggers
+// fill it in; the server then tags the rows with the tool that made them.
+const auditTable = "audit_log"
+
+// auditTriggerPrefix names the triggers createAuditTriggers creates
+const auditTriggerPrefix = "audit_"
+
+// createAuditTriggers (re)creates insert, update and delete triggers
+// recording changes to every data table in the audit log, in one
+// transaction so servers starting at the same time don't trip over each
+// other. It does nothing until the audit log migration has run.
+func createAuditTriggers(db *sql.DB) error {
+	var exists int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", auditTable).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	tables, err := dataTables(ctx, db)
+	if err != nil {
+		return err
+	}
+	var statements []string
+	for _, table := range tables {
+		columns, err := tableColumns(ctx, db, table)
+		if err != nil {
+			return err
+		}
+		statements = append(statements, auditTriggers(table, columns)...)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := dropAuditTriggers(tx); err != nil {
+		return err
+	}
+	for _, ddl := range statements {
+		if _, err := tx.Exec(ddl); err != nil {
+			return fmt.Errorf("failed to create audit trigger: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// auditTriggers returns the statements creating the audit triggers of table
+func auditTriggers(table string, columns []tableColumn) []string {
+	// A row is identified by its primary key, joined with | when it has
+	// several columns, or its rowid
+	var key []tableColumn
+	for _, c := range columns {
+		if c.primaryKey > 0 {
+			key = append(key, c)
+		}
+	}
+	sort.Slice(key, func(i, j int) bool { return key[i].primaryKey < key[j].primaryKey })
+
+	rowKey := func(row string) string {
+		if len(key) == 0 {
+			return "CAST(" + row + ".rowid AS TEXT)"
+		}
+		parts := make([]string, len(key))
+		for i, c := range key {
+			parts[i] = fmt.Sprintf("CAST(%s.%q AS TEXT)", row, c.name)
+		}
+		return strings.Join(parts, " || '|' || ")
+	}
+	snapshot := func(row string) string {
+		fields := make([]string, len(columns))
+		for i, c := range columns {
+			fields[i] = fmt.Sprintf("'%s', %s.%q", c.name, row, c.name)
+		}
+		return "json_object(" + strings.Join(fields, ", ") + ")"
+	}
+
+	insert := func(action, keyRow, before, after string) string {
+		return fmt.Sprintf("INSERT INTO %s (table_name, row_key, action, before, after) VALUES ('%s', %s, '%s', %s, %s);",
+			auditTable, table, rowKey(keyRow), action, before, after)
+	}
+	return []string{
+		fmt.Sprintf("CREATE TRIGGER %sinsert_%s AFTER INSERT ON %q BEGIN %s END",
+			auditTriggerPrefix, table, table, insert("insert", "new", "NULL", snapshot("new"))),
+		// Updates that change nothing aren't recorded
+		fmt.Sprintf("CREATE TRIGGER %supdate_%s AFTER UPDATE ON %q WHEN %s IS NOT %s BEGIN %s END",
+			auditTriggerPrefix, table, table, snapshot("old"), snapshot("new"), insert("update", "new", snapshot("old"), snapshot("new"))),
+		fmt.Sprintf("CREATE TRIGGER %sdelete_%s AFTER DELETE ON %q BEGIN %s END",
+			auditTriggerPrefix, table, table, insert("delete", "old", snapshot("old"), "NULL")),
+	}
+}
+
+// schemaExecer is a database or a transaction to change the schema on
+type schemaExecer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// dropAuditTriggers removes the audit triggers, so migrations can change
+// the columns they name
+func dropAuditTriggers(db schemaExecer) error {
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'trigger' AND name LIKE ?", auditTriggerPrefix+"%")
+	if err != nil {
+		return err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if _, err := db.Exec(fmt.Sprintf("DROP TRIGGER IF EXISTS %q", name)); err != nil {
+			return fmt.Errorf("failed to drop trigger %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackupInfo describes a backup file in the backup directory
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size"`
+}
+
+const backupTimeLayout = "20060102-150405"
+
+// DocumentDir returns the directory copies of contract documents are kept
+// in, next to the database like BackupDir: ~/.hours/documents for the
+// default database and <name>-documents for others
+func DocumentDir() (string, error) {
+	if InMemory() {
+		return "", fmt.Errorf("in-memory databases don't keep document copies; attach documents by path instead")
+	}
+	path := dbPath
+	if path == "" {
+		var err error
+		if path, err = ResolvePath(""); err != nil {
+			return "", err
+		}
+	}
+	name := filepath.Base(path)
+	if name == "db" {
+		return filepath.Join(filepath.Dir(path), "documents"), nil
+	}
+	return filepath.Join(filepath.Dir(path), strings.TrimSuffix(name, filepath.Ext(name))+"-documents"), nil
+}
+
+// BackupDir returns the directory backups are written to, next to the
+// database: ~/.hours/backups for the default database and <name>-backups
+// for others, so databases sharing a folder keep their backups apart
+func BackupDir() (string, error) {
+	if InMemory() {
+		return "", fmt.Errorf("in-memory databases are not backed up; use export_data to save their data")
+	}
+	path := dbPath
+	if path == "" {
+		var err error
+		if path, err = ResolvePath(""); err != nil {
+			return "", err
+		}
+	}
+	name := filepath.Base(path)
+	if name == "db" {
+		return filepath.Join(filepath.Dir(path), "backups"), nil
+	}
+	return filepath.Join(filepath.Dir(path), strings.TrimSuffix(name, filepath.Ext(name))+"-backups"), nil
+}
+
+// ArchiveDir returns the directory purge_old_data writes the rows it
+// removes to, next to the database like BackupDir: ~/.hours/archives for
+// the default database and <name>-archives for others
+func ArchiveDir() (string, error) {
+	if InMemory() {
+		return "", fmt.Errorf("in-memory databases have no archive folder; pass a file path to write the archive to")
+	}
+	path := dbPath
+	if path == "" {
+		var err error
+		if path, err = ResolvePath(""); err != nil {
+			return "", err
+		}
+	}
+	name := filepath.Base(path)
+	if name == "db" {
+		return filepath.Join(filepath.Dir(path), "archives"), nil
+	}
+	return filepath.Join(filepath.Dir(path), strings.TrimSuffix(name, filepath.Ext(name))+"-archives"), nil
+}
+
+// Backup writes a consistent copy of the database to the backup directory.
+// reason is recorded in the file name (e.g. scheduled, pre-migration, manual).
+func Backup(ctx context.Context, db *sql.DB, reason string) (*BackupInfo, error) {
+	dir, err := BackupDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("db-%s-%s.sqlite", now.Format(backupTimeLayout), reason)
+	path := filepath.Join(dir, name)
+
+	if err := snapshot(ctx, db, path); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	info := &BackupInfo{Name: name, Path: path, Reason: reason, CreatedAt: now}
+	if stat, err := os.Stat(path); err == nil {
+		info.Size = stat.Size()
+	}
+	return info, nil
+}
+
+// ListBackups returns backups newest first
+func ListBackups() ([]BackupInfo, error) {
+	dir, err := BackupDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []BackupInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "db-") || !strings.HasSuffix(name, ".sqlite") {
+			continue
+		}
+		stem := strings.TrimSuffix(strings.TrimPrefix(name, "db-"), ".sqlite")
+		if len(stem) < len(backupTimeLayout) {
+			continue
+		}
+		created, err := time.ParseInLocation(backupTimeLayout, stem[:len(backupTimeLayout)], time.Local)
+		if err != nil {
+			continue
+		}
+		info := BackupInfo{
+			Name:      name,
+			Path:      filepath.Join(dir, name),
+			Reason:    strings.TrimPrefix(stem[len(backupTimeLayout):], "-"),
+			CreatedAt: created,
+		}
+		if fi, err := entry.Info(); err == nil {
+			info.Size = fi.Size()
+		}
+		backups = append(backups, info)
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+// RotateBackups keeps the newest scheduled backup of each of the last
+// keepDaily days and of each of the last keepMonthly months. Backups taken
+// for other reasons (manual, pre-migration, pre-restore) are kept for
+// keepDaily days. All others are deleted and their names returned.
+func RotateBackups(keepDaily, keepMonthly int) ([]string, error) {
+	backups, err := ListBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	keep := map[string]bool{}
+	days := map[string]bool{}
+	months := map[string]bool{}
+	cutoff := time.Now().AddDate(0, 0, -keepDaily)
+	for _, b := range backups {
+		if b.Reason != "scheduled" {
+			keep[b.Name] = b.CreatedAt.After(cutoff)
+			continue
+		}
+		day := b.CreatedAt.Format("2006-01-02")
+		month := b.CreatedAt.Format("2006-01")
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep[b.Name] = true
+		}
+		if !months[month] && len(months) < keepMonthly {
+			months[month] = true
+			keep[b.Name] = true
+		}
+	}
+
+	var removed []string
+	for _, b := range backups {
+		if keep[b.Name] {
+			continue
+		}
+		if err := os.Remove(b.Path); err != nil {
+			return removed, fmt.Errorf("failed to remove backup %s: %w", b.Name, err)
+		}
+		removed = append(removed, b.Name)
+	}
+	return removed, nil
+}
+
+// RestoreBackup replaces all data with the contents of a backup file. The
+// backup must have the same schema version as the live database.
+func RestoreBackup(ctx context.Context, db *sql.DB, path string) (map[string]int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("backup not found: %w", err)
+	}
+
+	// ATTACH is per connection, so everything runs on one connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS backup", path); err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	// Detach even when ctx is cancelled, or the pooled connection keeps it
+	defer conn.ExecContext(context.WithoutCancel(ctx), "DETACH DATABASE backup")
+
+	current, err := AppliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	var backupMigrations []string
+	rows, err := conn.QueryContext(ctx, "SELECT name FROM backup.migrations ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup migrations: %w", err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		backupMigrations = append(backupMigrations, name)
+	}
+	rows.Close()
+	if strings.Join(current, ",") != strings.Join(backupMigrations, ",") {
+		return nil, fmt.Errorf("backup schema version differs from the database (backup migrations: %s)", strings.Join(backupMigrations, ", "))
+	}
+
+	tables, err := dataTables(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	columnLists := map[string]string{}
+	for _, table := range tables {
+		columns, err := tableColumns(ctx, db, table)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, len(columns))
+		for i, col := range columns {
+			names[i] = col.name
+		}
+		columnLists[table] = strings.Join(names, ", ")
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Check foreign keys once everything is copied rather than row by row
+	if _, err := tx.ExecContext(ctx, "PRAGMA defer_foreign_keys = ON"); err != nil {
+		return nil, fmt.Errorf("failed to defer foreign keys: %w", err)
+	}
+
+	// Children first when clearing, parents first when copying
+	for i := len(tables) - 1; i >= 0; i-- {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM main.%s", tables[i])); err != nil {
+			return nil, fmt.Errorf("failed to clear %s: %w", tables[i], err)
+		}
+	}
+
+	counts := map[string]int{}
+	for _, table := range tables {
+		list := columnLists[table]
+		result, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO main.%s (%s) SELECT %s FROM backup.%s", table, list, list, table))
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", table, err)
+		}
+		n, _ := result.RowsAffected()
+		counts[table] = int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit restore: %w", err)
+	}
+	return counts, nil
+}
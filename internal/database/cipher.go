@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/austin/hours-mcp/internal/secrets"
+)
+
+// dbKey is the key the open database is encrypted with, "" if it isn't
+var dbKey string
+
+// Encrypted reports whether the open database is encrypted
+func Encrypted() bool {
+	return dbKey != ""
+}
+
+// snapshot writes a consistent copy of the database to path. An encrypted
+// database is exported with its key so the copy is encrypted too.
+func snapshot(ctx context.Context, db *sql.DB, path string) error {
+	if dbKey == "" {
+		// VACUUM INTO produces a snapshot even while other connections write
+		_, err := db.ExecContext(ctx, fmt.Sprintf("VACUUM INTO '%s'", strings.ReplaceAll(path, "'", "''")))
+		return err
+	}
+	return exportEncrypted(ctx, db, path, dbKey)
+}
+
+// exportEncrypted copies the database into a new file at path encrypted
+// with key, using SQLCipher's sqlcipher_export
+func exportEncrypted(ctx context.Context, db *sql.DB, path, key string) error {
+	// ATTACH is per connection, so everything runs on one connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS export KEY ?", path, "x'"+key+"'"); err != nil {
+		return fmt.Errorf("failed to create encrypted copy: %w", err)
+	}
+	// Detach even when ctx is cancelled, or the pooled connection keeps it
+	defer conn.ExecContext(context.WithoutCancel(ctx), "DETACH DATABASE export")
+
+	if _, err := conn.ExecContext(ctx, "SELECT sqlcipher_export('export')"); err != nil {
+		return fmt.Errorf("failed to export to encrypted copy: %w", err)
+	}
+	return nil
+}
+
+// EncryptDatabase encrypts an existing plaintext database with the key from
+// HOURS_DB_KEY or the keychain. The plaintext file is kept next to it with a
+// .plaintext suffix, whose path is returned, to delete once the encrypted
+// database has been checked.
+func EncryptDatabase(path string) (string, error) {
+	if !encryptionSupported {
+		return "", fmt.Errorf("this build can't encrypt databases; build with -tags sqlcipher")
+	}
+	if path == MemoryPath {
+		return "", fmt.Errorf("in-memory databases can't be encrypted")
+	}
+	ctx := context.Background()
+	key := secrets.DatabaseKey()
+	if key == "" {
+		return "", fmt.Errorf("no database key: set %s or store one in the keychain", secrets.DatabaseKeyEnv)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("database not found: %w", err)
+	}
+
+	plain, err := sql.Open(driverName, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open database: %w", err)
+	}
+	defer plain.Close()
+
+	var tables int
+	if err := plain.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master").Scan(&tables); err != nil {
+		return "", fmt.Errorf("%s can't be read without a key; is it already encrypted?", path)
+	}
+	// Fold the write-ahead log in so the export sees every write
+	if _, err := plain.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return "", fmt.Errorf("failed to checkpoint: %w", err)
+	}
+
+	encryptedPath := path + ".encrypting"
+	os.Remove(encryptedPath)
+	if err := exportEncrypted(ctx, plain, encryptedPath, key); err != nil {
+		os.Remove(encryptedPath)
+		return "", err
+	}
+	plain.Close()
+
+	// Check the copy opens with the key before replacing anything
+	check, err := sql.Open(driverName, encryptedPath+"?"+keyParams(key))
+	if err != nil {
+		os.Remove(encryptedPath)
+		return "", fmt.Errorf("failed to open encrypted copy: %w", err)
+	}
+	problems, err := IntegrityCheck(ctx, check)
+	check.Close()
+	if err != nil || len(problems) > 0 {
+		os.Remove(encryptedPath)
+		return "", fmt.Errorf("encrypted copy failed its integrity check: %v %s", err, strings.Join(problems, "; "))
+	}
+
+	plaintextPath := path + ".plaintext"
+	if err := os.Rename(path, plaintextPath); err != nil {
+		os.Remove(encryptedPath)
+		return "", fmt.Errorf("failed to move plaintext database aside: %w", err)
+	}
+	os.Remove(path + "-wal")
+	os.Remove(path + "-shm")
+	if err := os.Rename(encryptedPath, path); err != nil {
+		return "", fmt.Errorf("failed to move encrypted database into place (plaintext kept at %s): %w", plaintextPath, err)
+	}
+	return plaintextPath, nil
+}
@@ -1,45 +1,264 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/austin/hours-mcp/internal/secrets"
 )
 
-func Initialize() (*sql.DB, error) {
+// PathEnv is the environment variable that overrides the default database
+// path
+const PathEnv = "HOURS_DB_PATH"
+
+// MemoryPath opens a temporary in-memory database, discarded when the server
+// exits
+const MemoryPath = ":memory:"
+
+// memoryDSN names the in-memory database with a shared cache so every
+// pooled connection sees the same data
+const memoryDSN = "file:hours?mode=memory&cache=shared"
+
+// dbPath is the database opened by Initialize
+var dbPath string
+
+// ResolvePath picks the database file: the path given on the command line,
+// then HOURS_DB_PATH, then ~/.hours/db. A leading ~ is expanded.
+func ResolvePath(flagPath string) (string, error) {
+	path := flagPath
+	if path == "" {
+		path = os.Getenv(PathEnv)
+	}
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return "", fmt.Errorf("failed to get home directory: %w", err)
 	}
+	if path == "" {
+		return filepath.Join(homeDir, ".hours", "db"), nil
+	}
+	if path == MemoryPath {
+		return path, nil
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		path = filepath.Join(homeDir, path[1:])
+	}
+	return filepath.Abs(path)
+}
+
+// Path returns the database opened by Initialize, or MemoryPath for an
+// in-memory one
+func Path() string {
+	return dbPath
+}
 
-	dbPath := filepath.Join(homeDir, ".hours", "db")
-	dbDir := filepath.Dir(dbPath)
+// InMemory reports whether the open database is an in-memory one
+func InMemory() bool {
+	return dbPath == MemoryPath
+}
 
-	if err := os.MkdirAll(dbDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create database directory: %w", err)
+// Initialize opens the database at path, creating it if needed, and brings
+// its schema up to date
+func Initialize(path string) (*sql.DB, error) {
+	dsn := path
+	existing := false
+	if path == MemoryPath {
+		dsn = memoryDSN
+	} else {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+		_, statErr := os.Stat(path)
+		existing = statErr == nil
+
+		dbKey = secrets.DatabaseKey()
+		if dbKey != "" && !encryptionSupported {
+			return nil, fmt.Errorf("%s is set but this build can't encrypt databases; build with -tags sqlcipher or unset it", secrets.DatabaseKeyEnv)
+		}
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	// Schema changes run on a connection that doesn't enforce foreign keys:
+	// migrations that rebuild a table drop it, which would cascade to every
+	// row referencing it
+	setup, err := sql.Open(driverName, withPragmas(dsn, path, false))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	defer setup.Close()
+	dbPath = path
 
-	if err := createTables(db); err != nil {
-		db.Close()
+	if err := createTables(setup); err != nil {
+		if strings.Contains(err.Error(), "file is not a database") {
+			return nil, fmt.Errorf("%s is encrypted or not a database; set %s to its key", path, secrets.DatabaseKeyEnv)
+		}
 		return nil, fmt.Errorf("failed to create tables: %w", err)
 	}
 
-	if err := runMigrations(db); err != nil {
-		db.Close()
+	if err := runMigrations(setup, existing); err != nil {
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	if err := createSearchIndexes(setup); err != nil {
+		return nil, fmt.Errorf("failed to create search indexes: %w", err)
+	}
+
+	if err := createAuditTriggers(setup); err != nil {
+		return nil, fmt.Errorf("failed to create audit triggers: %w", err)
+	}
+
+	// Opened before setup closes so an in-memory database stays alive
+	db, err := sql.Open(driverName, withPragmas(dsn, path, true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
 	return db, nil
 }
 
+// withPragmas adds the connection settings every connection is opened with.
+// WAL lets readers and a writer work at the same time, the busy timeout
+// waits for another server's write to finish instead of failing with
+// "database is locked", and immediate transactions take the write lock up
+// front so two writers can't deadlock.
+func withPragmas(dsn, path string, foreignKeys bool) string {
+	params := connectionParams(foreignKeys, path != MemoryPath)
+	if dbKey != "" && path != MemoryPath {
+		params = keyParams(dbKey) + "&" + params
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&" + params
+	}
+	return dsn + "?" + params
+}
+
+// SearchAvailable reports whether the FTS5 search indexes exist. They are
+// only created when mattn/go-sqlite3 is built with the sqlite_fts5 tag, or
+// with the pure Go driver.
+func SearchAvailable(db *sql.DB) bool {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'time_entries_fts'").Scan(&count)
+	return err == nil && count > 0
+}
+
+func createSearchIndexes(db *sql.DB) error {
+	// Without FTS5 compiled in, search falls back to LIKE queries
+	var fts5 bool
+	if err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5); err != nil || !fts5 {
+		return nil
+	}
+
+	// Each index is created once; databases indexed before invoice notes
+	// existed get that index on their own
+	var schemas []string
+	if !SearchAvailable(db) {
+		schemas = append(schemas, entrySearchSchema)
+	}
+	var notesIndexed int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'invoice_notes_fts'").Scan(&notesIndexed); err != nil {
+		return err
+	}
+	if notesIndexed == 0 {
+		schemas = append(schemas, invoiceNoteSearchSchema)
+	}
+	if len(schemas) == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, schema := range schemas {
+		if _, err := tx.Exec(schema); err != nil {
+			return fmt.Errorf("failed to create FTS5 indexes: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// entrySearchSchema indexes time entry descriptions and contract names and
+// notes
+const entrySearchSchema = `
+	CREATE VIRTUAL TABLE time_entries_fts USING fts5(
+		entry_id UNINDEXED,
+		description,
+		tokenize = 'porter unicode61'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS time_entries_fts_insert AFTER INSERT ON time_entries BEGIN
+		INSERT INTO time_entries_fts (entry_id, description) VALUES (new.id, COALESCE(new.description, ''));
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS time_entries_fts_update AFTER UPDATE OF description ON time_entries BEGIN
+		UPDATE time_entries_fts SET description = COALESCE(new.description, '') WHERE entry_id = old.id;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS time_entries_fts_delete AFTER DELETE ON time_entries BEGIN
+		DELETE FROM time_entries_fts WHERE entry_id = old.id;
+	END;
+
+	CREATE VIRTUAL TABLE IF NOT EXISTS contracts_fts USING fts5(
+		contract_id UNINDEXED,
+		name,
+		notes,
+		tokenize = 'porter unicode61'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS contracts_fts_insert AFTER INSERT ON contracts BEGIN
+		INSERT INTO contracts_fts (contract_id, name, notes) VALUES (new.id, new.name, COALESCE(new.notes, ''));
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS contracts_fts_update AFTER UPDATE OF name, notes ON contracts BEGIN
+		UPDATE contracts_fts SET name = new.name, notes = COALESCE(new.notes, '') WHERE contract_id = old.id;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS contracts_fts_delete AFTER DELETE ON contracts BEGIN
+		DELETE FROM contracts_fts WHERE contract_id = old.id;
+	END;
+
+	INSERT INTO time_entries_fts (entry_id, description)
+	SELECT id, COALESCE(description, '') FROM time_entries;
+
+	DELETE FROM contracts_fts;
+	INSERT INTO contracts_fts (contract_id, name, notes)
+	SELECT id, name, COALESCE(notes, '') FROM contracts;
+	`
+
+// invoiceNoteSearchSchema indexes the follow-up notes on invoices
+const invoiceNoteSearchSchema = `
+	CREATE VIRTUAL TABLE invoice_notes_fts USING fts5(
+		note_id UNINDEXED,
+		note,
+		tokenize = 'porter unicode61'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS invoice_notes_fts_insert AFTER INSERT ON invoice_notes BEGIN
+		INSERT INTO invoice_notes_fts (note_id, note) VALUES (new.id, new.note);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS invoice_notes_fts_update AFTER UPDATE OF note ON invoice_notes BEGIN
+		UPDATE invoice_notes_fts SET note = new.note WHERE note_id = old.id;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS invoice_notes_fts_delete AFTER DELETE ON invoice_notes BEGIN
+		DELETE FROM invoice_notes_fts WHERE note_id = old.id;
+	END;
+
+	INSERT INTO invoice_notes_fts (note_id, note)
+	SELECT id, note FROM invoice_notes;
+	`
+
 func createTables(db *sql.DB) error {
 	schema := `
 	CREATE TABLE IF NOT EXISTS clients (
@@ -141,6 +360,17 @@ func createTables(db *sql.DB) error {
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 
+	CREATE TABLE IF NOT EXISTS settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS holidays (
+		date DATE PRIMARY KEY,
+		name TEXT NOT NULL
+	);
+
 	CREATE INDEX IF NOT EXISTS idx_time_entries_date ON time_entries(date);
 	CREATE INDEX IF NOT EXISTS idx_time_entries_client ON time_entries(client_id);
 	CREATE INDEX IF NOT EXISTS idx_invoices_client ON invoices(client_id);
@@ -157,7 +387,9 @@ func createTables(db *sql.DB) error {
 	return nil
 }
 
-func runMigrations(db *sql.DB) error {
+// runMigrations applies pending migrations. When backupFirst is set, the
+// database is backed up before the first pending migration runs.
+func runMigrations(db *sql.DB, backupFirst bool) error {
 	// Create migrations table if it doesn't exist
 	_, err := db.Exec(`
 		CREATE TABLE IF NOT EXISTS migrations (
@@ -170,57 +402,7 @@ func runMigrations(db *sql.DB) error {
 		return fmt.Errorf("failed to create migrations table: %w", err)
 	}
 
-	migrations := []migration{
-		{
-			name: "add_contract_ref_to_time_entries",
-			apply: func(db *sql.DB) error {
-				return addColumnIfNotExists(db, "time_entries", "contract_ref", "TEXT")
-			},
-		},
-		{
-			name: "add_title_to_recipients",
-			apply: func(db *sql.DB) error {
-				return addColumnIfNotExists(db, "recipients", "title", "TEXT")
-			},
-		},
-		{
-			name: "add_phone_to_recipients",
-			apply: func(db *sql.DB) error {
-				return addColumnIfNotExists(db, "recipients", "phone", "TEXT")
-			},
-		},
-		{
-			name: "add_address_to_clients",
-			apply: func(db *sql.DB) error {
-				if err := addColumnIfNotExists(db, "clients", "address", "TEXT"); err != nil {
-					return err
-				}
-				if err := addColumnIfNotExists(db, "clients", "city", "TEXT"); err != nil {
-					return err
-				}
-				if err := addColumnIfNotExists(db, "clients", "state", "TEXT"); err != nil {
-					return err
-				}
-				if err := addColumnIfNotExists(db, "clients", "zip_code", "TEXT"); err != nil {
-					return err
-				}
-				return addColumnIfNotExists(db, "clients", "country", "TEXT")
-			},
-		},
-		{
-			name: "restructure_for_contracts",
-			apply: func(db *sql.DB) error {
-				return restructureForContracts(db)
-			},
-		},
-		{
-			name: "remove_rate_constraints_from_clients",
-			apply: func(db *sql.DB) error {
-				return removeRateConstraintsFromClients(db)
-			},
-		},
-	}
-
+	dropped := false
 	for _, migration := range migrations {
 		// Check if migration has already been applied
 		var count int
@@ -234,6 +416,23 @@ func runMigrations(db *sql.DB) error {
 			continue
 		}
 
+		if backupFirst {
+			info, err := Backup(context.Background(), db, "pre-migration")
+			if err != nil {
+				return fmt.Errorf("failed to back up before migration %s: %w", migration.name, err)
+			}
+			slog.Info("backed up the database before migrating", "path", info.Path)
+			backupFirst = false
+		}
+		// Audit triggers name every column, so they are dropped before a
+		// migration changes any and recreated once all have run
+		if !dropped {
+			if err := dropAuditTriggers(db); err != nil {
+				return fmt.Errorf("failed to drop audit triggers: %w", err)
+			}
+			dropped = true
+		}
+
 		// Apply migration
 		err = migration.apply(db)
 		if err != nil {
@@ -246,22 +445,993 @@ func runMigrations(db *sql.DB) error {
 			return fmt.Errorf("failed to record migration %s: %w", migration.name, err)
 		}
 
-		fmt.Printf("Applied migration: %s\n", migration.name)
+		slog.Info("applied migration", "name", migration.name)
 	}
 
 	return nil
 }
 
 type migration struct {
-	name  string
-	apply func(*sql.DB) error
+	name string
+	// description says what the migration changes, for dry runs
+	description string
+	apply       func(*sql.DB) error
+	// down undoes the schema changes of apply; nil when it can't be undone,
+	// e.g. because apply rebuilt a table or moved data around
+	down func(*sql.DB) error
 }
 
-func addColumnIfNotExists(db *sql.DB, tableName, columnName, columnType string) error {
-	// Check if column already exists
+// migrations are applied in order; new ones go at the end
+var migrations = []migration{
+	{
+		name:        "add_contract_ref_to_time_entries",
+		description: "Add time_entries.contract_ref",
+		apply: func(db *sql.DB) error {
+			return addColumnIfNotExists(db, "time_entries", "contract_ref", "TEXT")
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "time_entries", "contract_ref")
+		},
+	},
+	{
+		name:        "add_title_to_recipients",
+		description: "Add recipients.title",
+		apply: func(db *sql.DB) error {
+			return addColumnIfNotExists(db, "recipients", "title", "TEXT")
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "recipients", "title")
+		},
+	},
+	{
+		name:        "add_phone_to_recipients",
+		description: "Add recipients.phone",
+		apply: func(db *sql.DB) error {
+			return addColumnIfNotExists(db, "recipients", "phone", "TEXT")
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "recipients", "phone")
+		},
+	},
+	{
+		name:        "add_address_to_clients",
+		description: "Add address, city, state, zip_code and country to clients",
+		apply: func(db *sql.DB) error {
+			if err := addColumnIfNotExists(db, "clients", "address", "TEXT"); err != nil {
+				return err
+			}
+			if err := addColumnIfNotExists(db, "clients", "city", "TEXT"); err != nil {
+				return err
+			}
+			if err := addColumnIfNotExists(db, "clients", "state", "TEXT"); err != nil {
+				return err
+			}
+			if err := addColumnIfNotExists(db, "clients", "zip_code", "TEXT"); err != nil {
+				return err
+			}
+			return addColumnIfNotExists(db, "clients", "country", "TEXT")
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "clients", "address", "city", "state", "zip_code", "country")
+		},
+	},
+	{
+		name:        "restructure_for_contracts",
+		description: "Add time_entries.contract_id and move each client's rate into a LEGACY-<id> contract holding its time entries",
+		apply: func(db *sql.DB) error {
+			return restructureForContracts(db)
+		},
+	},
+	{
+		name:        "remove_rate_constraints_from_clients",
+		description: "Rebuild the clients table without its rate columns: the table is dropped and recreated",
+		apply: func(db *sql.DB) error {
+			return removeRateConstraintsFromClients(db)
+		},
+	},
+	{
+		name:        "add_paid_date_to_invoices",
+		description: "Add invoices.paid_date, set to the due date of invoices already paid",
+		apply: func(db *sql.DB) error {
+			if err := addColumnIfNotExists(db, "invoices", "paid_date", "DATE"); err != nil {
+				return err
+			}
+			// Best guess for invoices already marked paid
+			_, err := db.Exec("UPDATE invoices SET paid_date = due_date WHERE status = 'paid' AND paid_date IS NULL")
+			return err
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "invoices", "paid_date")
+		},
+	},
+	{
+		name:        "add_email_delivery",
+		description: "Create the smtp_config and email_log tables",
+		apply: func(db *sql.DB) error {
+			_, err := db.Exec(`
+				CREATE TABLE IF NOT EXISTS smtp_config (
+					id INTEGER PRIMARY KEY,
+					host TEXT NOT NULL,
+					port INTEGER NOT NULL,
+					username TEXT,
+					from_address TEXT NOT NULL,
+					from_name TEXT,
+					security TEXT NOT NULL DEFAULT 'starttls',
+					updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+				);
+
+				CREATE TABLE IF NOT EXISTS email_log (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					invoice_id INTEGER,
+					recipients TEXT NOT NULL,
+					subject TEXT NOT NULL,
+					status TEXT NOT NULL,
+					error TEXT,
+					sent_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY (invoice_id) REFERENCES invoices(id) ON DELETE SET NULL
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_email_log_invoice ON email_log(invoice_id);
+			`)
+			return err
+		},
+		down: func(db *sql.DB) error {
+			return dropTables(db, "smtp_config", "email_log")
+		},
+	},
+	{
+		name:        "add_email_templates",
+		description: "Create the email_templates table",
+		apply: func(db *sql.DB) error {
+			_, err := db.Exec(`
+				CREATE TABLE IF NOT EXISTS email_templates (
+					name TEXT PRIMARY KEY,
+					kind TEXT NOT NULL,
+					client_id INTEGER,
+					subject TEXT NOT NULL,
+					body TEXT NOT NULL,
+					is_default BOOLEAN DEFAULT FALSE,
+					updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+				);
+			`)
+			return err
+		},
+		down: func(db *sql.DB) error {
+			return dropTables(db, "email_templates")
+		},
+	},
+	{
+		name:        "add_exchange_rates",
+		description: "Create the exchange_rates table",
+		apply: func(db *sql.DB) error {
+			_, err := db.Exec(`
+				CREATE TABLE IF NOT EXISTS exchange_rates (
+					date DATE NOT NULL,
+					base_currency TEXT NOT NULL,
+					quote_currency TEXT NOT NULL,
+					rate REAL NOT NULL,
+					source TEXT NOT NULL DEFAULT 'manual',
+					PRIMARY KEY (date, base_currency, quote_currency)
+				);
+			`)
+			return err
+		},
+		down: func(db *sql.DB) error {
+			return dropTables(db, "exchange_rates")
+		},
+	},
+	{
+		name:        "add_currency_to_invoices",
+		description: "Add invoices.currency, taken from the contracts each invoice bills",
+		apply: func(db *sql.DB) error {
+			if err := addColumnIfNotExists(db, "invoices", "currency", "TEXT DEFAULT 'USD'"); err != nil {
+				return err
+			}
+			// Existing invoices take the currency of the contracts they bill
+			_, err := db.Exec(`
+				UPDATE invoices SET currency = COALESCE((
+					SELECT MIN(ct.currency) FROM time_entries te
+					JOIN contracts ct ON te.contract_id = ct.id
+					WHERE te.invoice_id = invoices.id
+				), 'USD')
+			`)
+			return err
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "invoices", "currency")
+		},
+	},
+	{
+		name:        "add_tax_to_invoices",
+		description: "Add invoices.tax_rate and invoices.tax_amount",
+		apply: func(db *sql.DB) error {
+			// total_amount stays the gross amount; the net is total_amount - tax_amount
+			if err := addColumnIfNotExists(db, "invoices", "tax_rate", "REAL DEFAULT 0"); err != nil {
+				return err
+			}
+			return addColumnIfNotExists(db, "invoices", "tax_amount", "REAL DEFAULT 0")
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "invoices", "tax_rate", "tax_amount")
+		},
+	},
+	{
+		name:        "add_tax_treatment",
+		description: "Add tax ID, tax treatment and withholding columns to clients and invoices",
+		apply: func(db *sql.DB) error {
+			columns := []struct{ table, column, definition string }{
+				{"clients", "tax_id", "TEXT DEFAULT ''"},
+				{"clients", "tax_treatment", "TEXT DEFAULT 'standard'"},
+				{"clients", "withholding_rate", "REAL DEFAULT 0"},
+				{"invoices", "tax_treatment", "TEXT DEFAULT 'standard'"},
+				{"invoices", "withholding_rate", "REAL DEFAULT 0"},
+				{"invoices", "withholding_amount", "REAL DEFAULT 0"},
+				{"invoices", "tax_note", "TEXT DEFAULT ''"},
+			}
+			for _, c := range columns {
+				if err := addColumnIfNotExists(db, c.table, c.column, c.definition); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		down: func(db *sql.DB) error {
+			if err := dropColumns(db, "clients", "tax_id", "tax_treatment", "withholding_rate"); err != nil {
+				return err
+			}
+			return dropColumns(db, "invoices", "tax_treatment", "withholding_rate", "withholding_amount", "tax_note")
+		},
+	},
+	{
+		name:        "store_amounts_as_cents",
+		description: "Convert contract rates and invoice amounts to whole cents",
+		apply: func(db *sql.DB) error {
+			columns := []struct{ table, from, to string }{
+				{"contracts", "hourly_rate", "hourly_rate_cents"},
+				{"invoices", "total_amount", "total_cents"},
+				{"invoices", "tax_amount", "tax_cents"},
+				{"invoices", "withholding_amount", "withholding_cents"},
+			}
+			for _, c := range columns {
+				if err := convertColumnToCents(db, c.table, c.from, c.to); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		down: func(db *sql.DB) error {
+			columns := []struct{ table, from, to string }{
+				{"contracts", "hourly_rate_cents", "hourly_rate"},
+				{"invoices", "total_cents", "total_amount"},
+				{"invoices", "tax_cents", "tax_amount"},
+				{"invoices", "withholding_cents", "withholding_amount"},
+			}
+			for _, c := range columns {
+				if err := convertColumnFromCents(db, c.table, c.from, c.to); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		name:        "add_contract_rates",
+		description: "Create the contract_rates table",
+		apply: func(db *sql.DB) error {
+			// Rate changes on a contract; contracts.hourly_rate_cents applies until the first one
+			_, err := db.Exec(`
+				CREATE TABLE IF NOT EXISTS contract_rates (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					contract_id INTEGER NOT NULL,
+					effective_from DATE NOT NULL,
+					hourly_rate_cents INTEGER NOT NULL,
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY (contract_id) REFERENCES contracts(id) ON DELETE CASCADE,
+					UNIQUE(contract_id, effective_from)
+				);
+			`)
+			return err
+		},
+		down: func(db *sql.DB) error {
+			return dropTables(db, "contract_rates")
+		},
+	},
+	{
+		name:        "add_rate_rules",
+		description: "Create the contract_rate_rules and invoice_lines tables",
+		apply: func(db *sql.DB) error {
+			// Premium rates per contract, and the priced lines of each
+			// invoice so later rule changes do not alter issued invoices
+			_, err := db.Exec(`
+				CREATE TABLE IF NOT EXISTS contract_rate_rules (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					contract_id INTEGER NOT NULL,
+					kind TEXT NOT NULL,
+					multiplier REAL NOT NULL,
+					daily_hours REAL NOT NULL DEFAULT 0,
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY (contract_id) REFERENCES contracts(id) ON DELETE CASCADE,
+					UNIQUE(contract_id, kind)
+				);
+
+				CREATE TABLE IF NOT EXISTS invoice_lines (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					invoice_id INTEGER NOT NULL,
+					contract_id INTEGER NOT NULL,
+					rate_kind TEXT NOT NULL DEFAULT '',
+					rate_label TEXT NOT NULL DEFAULT '',
+					hours REAL NOT NULL,
+					rate_cents INTEGER NOT NULL,
+					amount_cents INTEGER NOT NULL,
+					FOREIGN KEY (invoice_id) REFERENCES invoices(id) ON DELETE CASCADE,
+					FOREIGN KEY (contract_id) REFERENCES contracts(id)
+				);
+			`)
+			return err
+		},
+		down: func(db *sql.DB) error {
+			return dropTables(db, "contract_rate_rules", "invoice_lines")
+		},
+	},
+	{
+		name:        "add_retainer_terms",
+		description: "Add retainer terms to contracts and invoice_lines.period",
+		apply: func(db *sql.DB) error {
+			columns := []struct{ table, column, definition string }{
+				{"contracts", "retainer_hours", "REAL NOT NULL DEFAULT 0"},
+				{"contracts", "retainer_fee_cents", "INTEGER NOT NULL DEFAULT 0"},
+				{"contracts", "overage_rate_cents", "INTEGER NOT NULL DEFAULT 0"},
+				{"contracts", "rollover_months", "INTEGER NOT NULL DEFAULT 0"},
+				// Month (YYYY-MM) a retainer fee line pays for
+				{"invoice_lines", "period", "TEXT NOT NULL DEFAULT ''"},
+			}
+			for _, c := range columns {
+				if err := addColumnIfNotExists(db, c.table, c.column, c.definition); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		down: func(db *sql.DB) error {
+			if err := dropColumns(db, "contracts", "retainer_hours", "retainer_fee_cents", "overage_rate_cents", "rollover_months"); err != nil {
+				return err
+			}
+			return dropColumns(db, "invoice_lines", "period")
+		},
+	},
+	{
+		name:        "add_expenses",
+		description: "Create the expenses table",
+		apply: func(db *sql.DB) error {
+			// Costs incurred for a contract; billable ones are rebilled on
+			// the next invoice and linked to it like time entries
+			_, err := db.Exec(`
+				CREATE TABLE IF NOT EXISTS expenses (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					contract_id INTEGER NOT NULL,
+					date DATE NOT NULL,
+					amount_cents INTEGER NOT NULL,
+					currency TEXT NOT NULL,
+					category TEXT NOT NULL DEFAULT '',
+					description TEXT NOT NULL DEFAULT '',
+					receipt_path TEXT NOT NULL DEFAULT '',
+					billable BOOLEAN NOT NULL DEFAULT TRUE,
+					invoice_id INTEGER,
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY (contract_id) REFERENCES contracts(id) ON DELETE CASCADE,
+					FOREIGN KEY (invoice_id) REFERENCES invoices(id) ON DELETE SET NULL
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_expenses_contract_date ON expenses(contract_id, date);
+			`)
+			return err
+		},
+		down: func(db *sql.DB) error {
+			return dropTables(db, "expenses")
+		},
+	},
+	{
+		name:        "add_allowances",
+		description: "Create the allowance_rates table and add kind, quantity, unit and unit_rate_cents to expenses",
+		apply: func(db *sql.DB) error {
+			// Mileage and per-diem expenses are a quantity (distance or
+			// days) times the rate configured for their year
+			_, err := db.Exec(`
+				CREATE TABLE IF NOT EXISTS allowance_rates (
+					year INTEGER NOT NULL,
+					kind TEXT NOT NULL,
+					rate_cents INTEGER NOT NULL,
+					currency TEXT NOT NULL,
+					updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY (year, kind)
+				);
+			`)
+			if err != nil {
+				return err
+			}
+			columns := []struct{ column, definition string }{
+				{"kind", "TEXT NOT NULL DEFAULT 'expense'"},
+				{"quantity", "REAL NOT NULL DEFAULT 0"},
+				{"unit", "TEXT NOT NULL DEFAULT ''"},
+				{"unit_rate_cents", "INTEGER NOT NULL DEFAULT 0"},
+			}
+			for _, c := range columns {
+				if err := addColumnIfNotExists(db, "expenses", c.column, c.definition); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		down: func(db *sql.DB) error {
+			if err := dropTables(db, "allowance_rates"); err != nil {
+				return err
+			}
+			return dropColumns(db, "expenses", "kind", "quantity", "unit", "unit_rate_cents")
+		},
+	},
+	{
+		name:        "add_contract_budgets",
+		description: "Add budget hours, amount, alert and hard limit to contracts",
+		apply: func(db *sql.DB) error {
+			// Not-to-exceed hours and amount per contract, e.g. from a
+			// purchase order; 0 means no budget
+			columns := []struct{ table, column, definition string }{
+				{"contracts", "budget_hours", "REAL NOT NULL DEFAULT 0"},
+				{"contracts", "budget_amount_cents", "INTEGER NOT NULL DEFAULT 0"},
+				{"contracts", "budget_alert_percent", "REAL NOT NULL DEFAULT 80"},
+				{"contracts", "budget_hard_limit", "BOOLEAN NOT NULL DEFAULT FALSE"},
+			}
+			for _, c := range columns {
+				if err := addColumnIfNotExists(db, c.table, c.column, c.definition); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "contracts", "budget_hours", "budget_amount_cents", "budget_alert_percent", "budget_hard_limit")
+		},
+	},
+	{
+		name:        "add_client_archiving",
+		description: "Add clients.archived_at",
+		apply: func(db *sql.DB) error {
+			// Archived clients keep their history but take no new work
+			return addColumnIfNotExists(db, "clients", "archived_at", "DATETIME")
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "clients", "archived_at")
+		},
+	},
+	{
+		name:        "add_client_aliases",
+		description: "Create the client_aliases table",
+		apply: func(db *sql.DB) error {
+			// Other names a client is referred to by, e.g. "acme" or a
+			// former company name
+			_, err := db.Exec(`
+				CREATE TABLE IF NOT EXISTS client_aliases (
+					alias TEXT PRIMARY KEY COLLATE NOCASE,
+					client_id INTEGER NOT NULL,
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_client_aliases_client ON client_aliases(client_id);
+			`)
+			return err
+		},
+		down: func(db *sql.DB) error {
+			return dropTables(db, "client_aliases")
+		},
+	},
+	{
+		name:        "add_client_profile",
+		description: "Add notes, default_currency, locale and custom_fields to clients",
+		apply: func(db *sql.DB) error {
+			// Custom fields are a JSON object of free-form strings
+			columns := []struct{ table, column, definition string }{
+				{"clients", "notes", "TEXT NOT NULL DEFAULT ''"},
+				{"clients", "default_currency", "TEXT NOT NULL DEFAULT ''"},
+				{"clients", "locale", "TEXT NOT NULL DEFAULT ''"},
+				{"clients", "custom_fields", "TEXT NOT NULL DEFAULT '{}'"},
+			}
+			for _, c := range columns {
+				if err := addColumnIfNotExists(db, c.table, c.column, c.definition); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "clients", "notes", "default_currency", "locale", "custom_fields")
+		},
+	},
+	{
+		name:        "dedupe_recipients",
+		description: "Merge recipients entered twice for a client and make emails unique per client",
+		apply: func(db *sql.DB) error {
+			// Merge recipients entered twice for a client into the first
+			// one, keeping details only the copies have, then keep emails
+			// unique per client
+			_, err := db.Exec(`
+				UPDATE recipients SET email = TRIM(email);
+
+				UPDATE recipients SET
+					is_primary = (SELECT MAX(d.is_primary) FROM recipients d
+						WHERE d.client_id = recipients.client_id AND LOWER(d.email) = LOWER(recipients.email)),
+					title = COALESCE(NULLIF(title, ''), (SELECT d.title FROM recipients d
+						WHERE d.client_id = recipients.client_id AND LOWER(d.email) = LOWER(recipients.email)
+						AND COALESCE(d.title, '') != '' ORDER BY d.id LIMIT 1)),
+					phone = COALESCE(NULLIF(phone, ''), (SELECT d.phone FROM recipients d
+						WHERE d.client_id = recipients.client_id AND LOWER(d.email) = LOWER(recipients.email)
+						AND COALESCE(d.phone, '') != '' ORDER BY d.id LIMIT 1))
+				WHERE id = (SELECT MIN(d.id) FROM recipients d
+					WHERE d.client_id = recipients.client_id AND LOWER(d.email) = LOWER(recipients.email));
+
+				DELETE FROM recipients WHERE id != (SELECT MIN(d.id) FROM recipients d
+					WHERE d.client_id = recipients.client_id AND LOWER(d.email) = LOWER(recipients.email));
+
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_recipients_client_email ON recipients(client_id, email COLLATE NOCASE);
+			`)
+			return err
+		},
+		down: func(db *sql.DB) error {
+			// Merged recipients stay merged
+			_, err := db.Exec("DROP INDEX IF EXISTS idx_recipients_client_email")
+			return err
+		},
+	},
+	{
+		name:        "add_invoice_recipients",
+		description: "Create the invoice_recipients table and add invoices.cc",
+		apply: func(db *sql.DB) error {
+			// Recipients an invoice is addressed to when not all of the
+			// client's, and addresses copied when it is emailed
+			_, err := db.Exec(`
+				CREATE TABLE IF NOT EXISTS invoice_recipients (
+					invoice_id INTEGER NOT NULL,
+					recipient_id INTEGER NOT NULL,
+					PRIMARY KEY (invoice_id, recipient_id),
+					FOREIGN KEY (invoice_id) REFERENCES invoices(id) ON DELETE CASCADE,
+					FOREIGN KEY (recipient_id) REFERENCES recipients(id) ON DELETE CASCADE
+				);
+			`)
+			if err != nil {
+				return err
+			}
+			return addColumnIfNotExists(db, "invoices", "cc", "TEXT NOT NULL DEFAULT ''")
+		},
+		down: func(db *sql.DB) error {
+			if err := dropTables(db, "invoice_recipients"); err != nil {
+				return err
+			}
+			return dropColumns(db, "invoices", "cc")
+		},
+	},
+	{
+		name:        "encrypt_sensitive_fields",
+		description: "Encrypt bank details and the business tax ID stored in plain text",
+		apply: func(db *sql.DB) error {
+			// Bank numbers and the business tax ID were stored in plain
+			// text before field-level encryption
+			return encryptSensitiveFields(db)
+		},
+		down: func(db *sql.DB) error {
+			return decryptSensitiveFields(db)
+		},
+	},
+	{
+		name:        "remove_orphaned_rows",
+		description: "Delete or clear rows pointing at records that no longer exist",
+		apply: func(db *sql.DB) error {
+			// Foreign keys weren't enforced before, so deleting a row
+			// could leave rows pointing at it behind
+			fixed, err := repairForeignKeyOrphans(context.Background(), db)
+			if fixed > 0 {
+				slog.Warn("fixed orphaned rows", "count", fixed)
+			}
+			return err
+		},
+		down: func(db *sql.DB) error {
+			// The schema is unchanged; deleted rows can't be brought back
+			return nil
+		},
+	},
+	{
+		name:        "add_audit_log",
+		description: "Create the audit_log table recording every change made to the data",
+		apply: func(db *sql.DB) error {
+			// Filled by triggers; see createAuditTriggers
+			_, err := db.Exec(`
+				CREATE TABLE IF NOT EXISTS audit_log (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					changed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					tool TEXT NOT NULL DEFAULT '',
+					table_name TEXT NOT NULL,
+					row_key TEXT NOT NULL,
+					action TEXT NOT NULL,
+					before TEXT,
+					after TEXT
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_audit_log_row ON audit_log(table_name, row_key);
+				CREATE INDEX IF NOT EXISTS idx_audit_log_changed ON audit_log(changed_at);
+			`)
+			return err
+		},
+		down: func(db *sql.DB) error {
+			return dropTables(db, "audit_log")
+		},
+	},
+	{
+		name:        "add_client_time_zone",
+		description: "Add time_zone to clients",
+		apply: func(db *sql.DB) error {
+			// An IANA name such as Europe/Berlin; empty uses the time_zone
+			// setting
+			return addColumnIfNotExists(db, "clients", "time_zone", "TEXT NOT NULL DEFAULT ''")
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "clients", "time_zone")
+		},
+	},
+	{
+		name:        "add_billable_to_time_entries",
+		description: "Add billable to time_entries",
+		apply: func(db *sql.DB) error {
+			// Entries that aren't billable are never invoiced
+			return addColumnIfNotExists(db, "time_entries", "billable", "BOOLEAN NOT NULL DEFAULT TRUE")
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "time_entries", "billable")
+		},
+	},
+	{
+		name:        "add_payment_methods",
+		description: "Create the payment_methods table and add payment_method_id to clients and contracts",
+		apply: func(db *sql.DB) error {
+			// Accounts the business is paid into, e.g. a USD ACH account and
+			// a EUR IBAN, picked per client or contract; payment_details
+			// stays the fallback for clients without one
+			_, err := db.Exec(`
+				CREATE TABLE IF NOT EXISTS payment_methods (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+					kind TEXT NOT NULL DEFAULT 'bank',
+					currency TEXT NOT NULL DEFAULT '',
+					bank_name TEXT NOT NULL DEFAULT '',
+					account_number TEXT NOT NULL DEFAULT '',
+					routing_number TEXT NOT NULL DEFAULT '',
+					swift_code TEXT NOT NULL DEFAULT '',
+					notes TEXT NOT NULL DEFAULT '',
+					is_default BOOLEAN NOT NULL DEFAULT FALSE,
+					updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+				);
+			`)
+			if err != nil {
+				return err
+			}
+			for _, table := range []string{"clients", "contracts"} {
+				if err := addColumnIfNotExists(db, table, "payment_method_id", "INTEGER REFERENCES payment_methods(id)"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		down: func(db *sql.DB) error {
+			for _, table := range []string{"clients", "contracts"} {
+				if err := dropColumns(db, table, "payment_method_id"); err != nil {
+					return err
+				}
+			}
+			return dropTables(db, "payment_methods")
+		},
+	},
+	{
+		name:        "add_invoice_delivery",
+		description: "Add invoices.sent_at, sent_to and delivery_method, filled from the email log",
+		apply: func(db *sql.DB) error {
+			for _, column := range []struct{ name, definition string }{
+				{"sent_at", "DATETIME"},
+				{"sent_to", "TEXT NOT NULL DEFAULT ''"},
+				{"delivery_method", "TEXT NOT NULL DEFAULT ''"},
+			} {
+				if err := addColumnIfNotExists(db, "invoices", column.name, column.definition); err != nil {
+					return err
+				}
+			}
+			// Invoices already emailed were last sent by their latest
+			// successful email
+			_, err := db.Exec(`
+				UPDATE invoices SET
+					sent_at = (SELECT MAX(l.sent_at) FROM email_log l WHERE l.invoice_id = invoices.id AND l.status = 'sent'),
+					sent_to = COALESCE((SELECT l.recipients FROM email_log l WHERE l.invoice_id = invoices.id AND l.status = 'sent'
+					                    ORDER BY l.sent_at DESC, l.id DESC LIMIT 1), ''),
+					delivery_method = 'email'
+				WHERE sent_at IS NULL AND EXISTS (SELECT 1 FROM email_log l WHERE l.invoice_id = invoices.id AND l.status = 'sent')
+			`)
+			return err
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "invoices", "sent_at", "sent_to", "delivery_method")
+		},
+	},
+	{
+		name:        "add_contract_documents",
+		description: "Create the contract_documents table",
+		apply: func(db *sql.DB) error {
+			// Files are referenced by path; the audit log can't hold blobs
+			_, err := db.Exec(`
+				CREATE TABLE IF NOT EXISTS contract_documents (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					contract_id INTEGER NOT NULL REFERENCES contracts(id) ON DELETE CASCADE,
+					kind TEXT NOT NULL DEFAULT 'contract',
+					title TEXT NOT NULL DEFAULT '',
+					path TEXT NOT NULL,
+					copied BOOLEAN NOT NULL DEFAULT FALSE,
+					added_at DATETIME DEFAULT CURRENT_TIMESTAMP
+				);
+				CREATE INDEX IF NOT EXISTS idx_contract_documents_contract ON contract_documents(contract_id);
+			`)
+			return err
+		},
+		down: func(db *sql.DB) error {
+			return dropTables(db, "contract_documents")
+		},
+	},
+	{
+		name:        "add_services",
+		description: "Create the services rate card and contract_services, and add time_entries.service_id",
+		apply: func(db *sql.DB) error {
+			// A service's rate on the card prefills contracts; the rate a
+			// contract agrees for it is kept per contract, so changing the
+			// card never reprices existing work
+			_, err := db.Exec(`
+				CREATE TABLE IF NOT EXISTS services (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+					hourly_rate_cents INTEGER NOT NULL,
+					currency TEXT NOT NULL DEFAULT 'USD',
+					description TEXT NOT NULL DEFAULT '',
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+				);
+				CREATE TABLE IF NOT EXISTS contract_services (
+					contract_id INTEGER NOT NULL REFERENCES contracts(id) ON DELETE CASCADE,
+					service_id INTEGER NOT NULL REFERENCES services(id),
+					hourly_rate_cents INTEGER NOT NULL,
+					PRIMARY KEY (contract_id, service_id)
+				);
+			`)
+			if err != nil {
+				return err
+			}
+			return addColumnIfNotExists(db, "time_entries", "service_id", "INTEGER REFERENCES services(id)")
+		},
+		down: func(db *sql.DB) error {
+			if err := dropColumns(db, "time_entries", "service_id"); err != nil {
+				return err
+			}
+			return dropTables(db, "contract_services", "services")
+		},
+	},
+	{
+		name:        "add_no_charge_to_time_entries",
+		description: "Add no_charge to time_entries",
+		apply: func(db *sql.DB) error {
+			// No-charge entries are invoiced, with their hours, at no cost
+			return addColumnIfNotExists(db, "time_entries", "no_charge", "BOOLEAN NOT NULL DEFAULT FALSE")
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "time_entries", "no_charge")
+		},
+	},
+	{
+		name:        "add_rounding_to_invoices",
+		description: "Add rounding_cents to invoices",
+		apply: func(db *sql.DB) error {
+			// The adjustment rounding the amount due, already in total_cents
+			return addColumnIfNotExists(db, "invoices", "rounding_cents", "INTEGER NOT NULL DEFAULT 0")
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "invoices", "rounding_cents")
+		},
+	},
+	{
+		name:        "add_adjusts_entry_id_to_time_entries",
+		description: "Add adjusts_entry_id to time_entries",
+		apply: func(db *sql.DB) error {
+			// Adjustment entries correct the hours of the entry they name,
+			// which compliance mode locks against edits
+			if err := addColumnIfNotExists(db, "time_entries", "adjusts_entry_id", "TEXT"); err != nil {
+				return err
+			}
+			_, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_time_entries_adjusts ON time_entries(adjusts_entry_id)")
+			return err
+		},
+		down: func(db *sql.DB) error {
+			if _, err := db.Exec("DROP INDEX IF EXISTS idx_time_entries_adjusts"); err != nil {
+				return err
+			}
+			return dropColumns(db, "time_entries", "adjusts_entry_id")
+		},
+	},
+	{
+		name:        "add_delivery_preferences",
+		description: "Add invoice delivery preferences to clients and a language to email_templates",
+		apply: func(db *sql.DB) error {
+			// Empty preferences fall back to the settings and tool defaults
+			columns := []struct{ table, column, definition string }{
+				{"clients", "delivery_method", "TEXT NOT NULL DEFAULT ''"},
+				{"clients", "delivery_note", "TEXT NOT NULL DEFAULT ''"},
+				{"clients", "invoice_format", "TEXT NOT NULL DEFAULT ''"},
+				{"clients", "invoice_detail", "TEXT NOT NULL DEFAULT ''"},
+				{"email_templates", "language", "TEXT NOT NULL DEFAULT ''"},
+			}
+			for _, c := range columns {
+				if err := addColumnIfNotExists(db, c.table, c.column, c.definition); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		down: func(db *sql.DB) error {
+			if err := dropColumns(db, "email_templates", "language"); err != nil {
+				return err
+			}
+			return dropColumns(db, "clients", "delivery_method", "delivery_note", "invoice_format", "invoice_detail")
+		},
+	},
+	{
+		name:        "add_internal_time",
+		description: "Create the internal_categories and internal_entries tables for time not spent on clients",
+		apply: func(db *sql.DB) error {
+			// Internal time belongs to no client or contract, so it can never
+			// be invoiced; it only counts towards utilization
+			_, err := db.Exec(`
+				CREATE TABLE IF NOT EXISTS internal_categories (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+					description TEXT NOT NULL DEFAULT '',
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+				);
+				CREATE TABLE IF NOT EXISTS internal_entries (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					category_id INTEGER NOT NULL REFERENCES internal_categories(id),
+					date DATE NOT NULL,
+					hours REAL NOT NULL,
+					description TEXT NOT NULL DEFAULT '',
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+				);
+				CREATE INDEX IF NOT EXISTS idx_internal_entries_date ON internal_entries(date);
+				INSERT OR IGNORE INTO internal_categories (name, description) VALUES
+					('Admin', 'Bookkeeping, invoicing and other running of the business'),
+					('Marketing', 'Sales, proposals and networking'),
+					('Learning', 'Training, courses and reading');
+			`)
+			return err
+		},
+		down: func(db *sql.DB) error {
+			return dropTables(db, "internal_entries", "internal_categories")
+		},
+	},
+	{
+		name:        "add_timers",
+		description: "Create the timers table and add needs_review to time_entries",
+		apply: func(db *sql.DB) error {
+			// A running timer becomes a time entry when stopped; entries from
+			// timers stopped at the timer_max_hours limit need review
+			_, err := db.Exec(`
+				CREATE TABLE IF NOT EXISTS timers (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					contract_id INTEGER NOT NULL REFERENCES contracts(id),
+					service_id INTEGER REFERENCES services(id),
+					description TEXT NOT NULL DEFAULT '',
+					started_at DATETIME NOT NULL
+				)
+			`)
+			if err != nil {
+				return err
+			}
+			return addColumnIfNotExists(db, "time_entries", "needs_review", "BOOLEAN NOT NULL DEFAULT FALSE")
+		},
+		down: func(db *sql.DB) error {
+			if err := dropColumns(db, "time_entries", "needs_review"); err != nil {
+				return err
+			}
+			return dropTables(db, "timers")
+		},
+	},
+	{
+		name:        "add_name_to_timers",
+		description: "Add timers.name so several timers can run at once",
+		apply: func(db *sql.DB) error {
+			// Timers are stopped by name; unnamed ones are named after their
+			// contract
+			if err := addColumnIfNotExists(db, "timers", "name", "TEXT NOT NULL DEFAULT ''"); err != nil {
+				return err
+			}
+			_, err := db.Exec(`
+				UPDATE timers SET name = (SELECT contract_number FROM contracts WHERE id = timers.contract_id) WHERE name = '';
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_timers_name ON timers(name COLLATE NOCASE);
+			`)
+			return err
+		},
+		down: func(db *sql.DB) error {
+			if _, err := db.Exec("DROP INDEX IF EXISTS idx_timers_name"); err != nil {
+				return err
+			}
+			return dropColumns(db, "timers", "name")
+		},
+	},
+	{
+		name:        "add_billing_cycle_to_clients",
+		description: "Add clients.billing_cycle",
+		apply: func(db *sql.DB) error {
+			// e.g. "monthly:15" or "biweekly:2026-10-16"; empty for none
+			return addColumnIfNotExists(db, "clients", "billing_cycle", "TEXT NOT NULL DEFAULT ''")
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "clients", "billing_cycle")
+		},
+	},
+	{
+		name:        "add_invoice_notes",
+		description: "Create the invoice_notes table",
+		apply: func(db *sql.DB) error {
+			// Dated notes following up an invoice, such as disputes and
+			// promises to pay
+			_, err := db.Exec(`
+				CREATE TABLE IF NOT EXISTS invoice_notes (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					invoice_id INTEGER NOT NULL,
+					date DATE NOT NULL,
+					note TEXT NOT NULL,
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY (invoice_id) REFERENCES invoices(id) ON DELETE CASCADE
+				);
+				CREATE INDEX IF NOT EXISTS idx_invoice_notes_invoice ON invoice_notes(invoice_id);
+			`)
+			return err
+		},
+		down: func(db *sql.DB) error {
+			return dropTables(db, "invoice_notes")
+		},
+	},
+	{
+		name:        "add_write_off_to_invoices",
+		description: "Add invoices.written_off_date and invoices.write_off_reason",
+		apply: func(db *sql.DB) error {
+			// Uncollectable invoices get the written_off status; the date
+			// places the bad debt in a tax period
+			if err := addColumnIfNotExists(db, "invoices", "written_off_date", "DATE"); err != nil {
+				return err
+			}
+			return addColumnIfNotExists(db, "invoices", "write_off_reason", "TEXT NOT NULL DEFAULT ''")
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "invoices", "written_off_date", "write_off_reason")
+		},
+	},
+	{
+		name:        "add_paypal_invoice_id_to_invoices",
+		description: "Add invoices.paypal_invoice_id",
+		apply: func(db *sql.DB) error {
+			// The PayPal invoice export_paypal_invoice created, so it is
+			// sent rather than created twice
+			return addColumnIfNotExists(db, "invoices", "paypal_invoice_id", "TEXT NOT NULL DEFAULT ''")
+		},
+		down: func(db *sql.DB) error {
+			return dropColumns(db, "invoices", "paypal_invoice_id")
+		},
+	},
+}
+
+func columnExists(db *sql.DB, tableName, columnName string) (bool, error) {
 	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", tableName))
 	if err != nil {
-		return fmt.Errorf("failed to get table info for %s: %w", tableName, err)
+		return false, fmt.Errorf("failed to get table info for %s: %w", tableName, err)
 	}
 	defer rows.Close()
 
@@ -272,16 +1442,28 @@ func addColumnIfNotExists(db *sql.DB, tableName, columnName, columnType string)
 		var defaultValue *string
 		err := rows.Scan(&cid, &name, &dataType, &notNull, &defaultValue, &primaryKey)
 		if err != nil {
-			return fmt.Errorf("failed to scan column info: %w", err)
+			return false, fmt.Errorf("failed to scan column info: %w", err)
 		}
 
 		if name == columnName {
-			// Column already exists
-			fmt.Printf("Column %s.%s already exists, skipping\n", tableName, columnName)
-			return nil
+			return true, nil
 		}
 	}
 
+	return false, nil
+}
+
+func addColumnIfNotExists(db *sql.DB, tableName, columnName, columnType string) error {
+	// Check if column already exists
+	exists, err := columnExists(db, tableName, columnName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		slog.Debug("column already exists, skipping", "table", tableName, "column", columnName)
+		return nil
+	}
+
 	// Column doesn't exist, add it
 	sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tableName, columnName, columnType)
 	_, err = db.Exec(sql)
@@ -289,12 +1471,32 @@ func addColumnIfNotExists(db *sql.DB, tableName, columnName, columnType string)
 		return fmt.Errorf("failed to add column %s to %s: %w", columnName, tableName, err)
 	}
 
-	fmt.Printf("Added column %s.%s\n", tableName, columnName)
+	slog.Debug("added column", "table", tableName, "column", columnName)
+	return nil
+}
+
+// convertColumnToCents replaces a REAL amount column with an INTEGER column
+// holding the same amount in cents
+func convertColumnToCents(db *sql.DB, tableName, from, to string) error {
+	exists, err := columnExists(db, tableName, from)
+	if err != nil || !exists {
+		return err
+	}
+	if err := addColumnIfNotExists(db, tableName, to, "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("UPDATE %s SET %s = CAST(ROUND(COALESCE(%s, 0) * 100) AS INTEGER)", tableName, to, from))
+	if err != nil {
+		return fmt.Errorf("failed to convert %s.%s to cents: %w", tableName, from, err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", tableName, from)); err != nil {
+		return fmt.Errorf("failed to drop %s.%s: %w", tableName, from, err)
+	}
 	return nil
 }
 
 func restructureForContracts(db *sql.DB) error {
-	fmt.Println("Restructuring database for contract-based billing...")
+	slog.Info("restructuring database for contract-based billing")
 
 	// Step 1: Create contracts table if it doesn't exist (will be created by main schema)
 	// The contracts table is already in the main schema above
@@ -304,15 +1506,25 @@ func restructureForContracts(db *sql.DB) error {
 		return fmt.Errorf("failed to add contract_id to time_entries: %w", err)
 	}
 
-	// Step 3: Check if we have any existing clients with rates that need migration
+	// Step 3: Check if we have any existing clients with rates that need migration.
+	// Databases created after the contract restructure never had client rates.
+	hasRates, err := columnExists(db, "clients", "hourly_rate")
+	if err != nil {
+		return err
+	}
+	if !hasRates {
+		slog.Info("contract restructuring completed")
+		return nil
+	}
+
 	var clientCount int
-	err := db.QueryRow("SELECT COUNT(*) FROM clients WHERE hourly_rate IS NOT NULL AND hourly_rate > 0").Scan(&clientCount)
+	err = db.QueryRow("SELECT COUNT(*) FROM clients WHERE hourly_rate IS NOT NULL AND hourly_rate > 0").Scan(&clientCount)
 	if err != nil {
 		return fmt.Errorf("failed to check existing clients: %w", err)
 	}
 
 	if clientCount > 0 {
-		fmt.Printf("Migrating %d clients to contract-based structure...\n", clientCount)
+		slog.Info("migrating clients to contracts", "clients", clientCount)
 
 		// Step 4: Create default contracts for existing clients
 		rows, err := db.Query(`
@@ -363,19 +1575,19 @@ func restructureForContracts(db *sql.DB) error {
 				return fmt.Errorf("failed to update time entries for client %s: %w", clientName, err)
 			}
 
-			fmt.Printf("Created legacy contract %s for client %s\n", contractNumber, clientName)
+			slog.Info("created legacy contract", "contract", contractNumber, "client", clientName)
 		}
 	}
 
 	// Step 6: Make contract_id required and add foreign key constraint for new time entries
 	// We'll handle this in business logic rather than database constraints for easier migration
 
-	fmt.Println("Contract restructuring completed successfully!")
+	slog.Info("contract restructuring completed")
 	return nil
 }
 
 func removeRateConstraintsFromClients(db *sql.DB) error {
-	fmt.Println("Removing rate constraints from clients table...")
+	slog.Info("removing rate constraints from clients table")
 
 	// SQLite doesn't support ALTER TABLE DROP COLUMN or modifying constraints directly
 	// We need to recreate the table without the rate fields
@@ -419,6 +1631,49 @@ func removeRateConstraintsFromClients(db *sql.DB) error {
 		return fmt.Errorf("failed to rename new clients table: %w", err)
 	}
 
-	fmt.Println("Successfully removed rate constraints from clients table")
+	slog.Info("removed rate constraints from clients table")
+	return nil
+}
+
+// sensitiveFields lists the columns kept encrypted at rest
+var sensitiveFields = []struct{ table, column string }{
+	{"payment_details", "account_number"},
+	{"payment_details", "routing_number"},
+	{"payment_details", "swift_code"},
+	{"payment_details", "notes"},
+	{"business_info", "tax_id"},
+}
+
+// encryptSensitiveFields encrypts any sensitive values still in plain text
+func encryptSensitiveFields(db *sql.DB) error {
+	for _, f := range sensitiveFields {
+		rows, err := db.Query(fmt.Sprintf("SELECT id, %s FROM %s WHERE COALESCE(%s, '') != ''", f.column, f.table, f.column))
+		if err != nil {
+			return err
+		}
+		plain := map[int]string{}
+		for rows.Next() {
+			var id int
+			var value string
+			if err := rows.Scan(&id, &value); err != nil {
+				rows.Close()
+				return err
+			}
+			if !secrets.IsEncrypted(value) {
+				plain[id] = value
+			}
+		}
+		rows.Close()
+
+		for id, value := range plain {
+			encrypted, err := secrets.Encrypt(value)
+			if err != nil {
+				return err
+			}
+			if _, err := db.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = ?", f.table, f.column), encrypted, id); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
@@ -0,0 +1,33 @@
+//go:build !purego && !sqlcipher
+
+package database
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// driverName is the database/sql driver used: mattn/go-sqlite3, which
+// needs cgo. Build with the purego tag to use a driver without it.
+const driverName = "sqlite3"
+
+// encryptionSupported reports whether the driver can encrypt the whole
+// database file; only the sqlcipher build can
+const encryptionSupported = false
+
+// connectionParams returns the DSN parameters setting the busy timeout,
+// transaction locking, foreign key enforcement and, for files, WAL mode
+func connectionParams(foreignKeys, wal bool) string {
+	params := "_busy_timeout=5000&_txlock=immediate&_foreign_keys=off"
+	if foreignKeys {
+		params = "_busy_timeout=5000&_txlock=immediate&_foreign_keys=on"
+	}
+	if wal {
+		params += "&_journal_mode=WAL&_synchronous=NORMAL"
+	}
+	return params
+}
+
+// keyParams returns the DSN parameters opening a database with a key
+func keyParams(key string) string {
+	return ""
+}
@@ -0,0 +1,35 @@
+//go:build purego
+
+package database
+
+import (
+	_ "modernc.org/sqlite"
+)
+
+// driverName is the database/sql driver used: modernc.org/sqlite, a pure Go
+// translation of SQLite that cross-compiles without cgo and includes FTS5
+const driverName = "sqlite"
+
+// encryptionSupported reports whether the driver can encrypt the whole
+// database file; only the sqlcipher build can
+const encryptionSupported = false
+
+// connectionParams returns the DSN parameters setting the busy timeout,
+// transaction locking, foreign key enforcement and, for files, WAL mode.
+// Times are written in the same layout mattn/go-sqlite3 uses so databases
+// can move between builds.
+func connectionParams(foreignKeys, wal bool) string {
+	params := "_pragma=busy_timeout(5000)&_txlock=immediate&_time_format=sqlite&_pragma=foreign_keys(0)"
+	if foreignKeys {
+		params = "_pragma=busy_timeout(5000)&_txlock=immediate&_time_format=sqlite&_pragma=foreign_keys(1)"
+	}
+	if wal {
+		params += "&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)"
+	}
+	return params
+}
+
+// keyParams returns the DSN parameters opening a database with a key
+func keyParams(key string) string {
+	return ""
+}
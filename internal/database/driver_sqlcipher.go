@@ -0,0 +1,36 @@
+//go:build sqlcipher && !purego
+
+package database
+
+import (
+	"net/url"
+
+	_ "github.com/mutecomm/go-sqlcipher/v4"
+)
+
+// driverName is the database/sql driver used: go-sqlcipher, a fork of
+// mattn/go-sqlite3 built on SQLCipher, which encrypts the database file
+const driverName = "sqlite3"
+
+// encryptionSupported reports whether the driver can encrypt the whole
+// database file
+const encryptionSupported = true
+
+// connectionParams returns the DSN parameters setting the busy timeout,
+// transaction locking, foreign key enforcement and, for files, WAL mode
+func connectionParams(foreignKeys, wal bool) string {
+	params := "_busy_timeout=5000&_txlock=immediate&_foreign_keys=off"
+	if foreignKeys {
+		params = "_busy_timeout=5000&_txlock=immediate&_foreign_keys=on"
+	}
+	if wal {
+		params += "&_journal_mode=WAL&_synchronous=NORMAL"
+	}
+	return params
+}
+
+// keyParams returns the DSN parameters opening a database with a key,
+// given as 64 hex digits and used as the raw key
+func keyParams(key string) string {
+	return "_pragma_key=" + url.QueryEscape("x'"+key+"'")
+}
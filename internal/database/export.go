@@ -0,0 +1,359 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExportFormat identifies hours-mcp data exports
+const ExportFormat = "hours-mcp-export"
+
+// ArchiveFormat identifies files of rows exported before a purge
+const ArchiveFormat = "hours-mcp-archive"
+
+// ExportVersion is bumped when the layout of Export changes
+const ExportVersion = 1
+
+// Export is a full, portable copy of the database
+type Export struct {
+	Format     string                              `json:"format"`
+	Version    int                                 `json:"version"`
+	ExportedAt time.Time                           `json:"exported_at"`
+	Migrations []string                            `json:"migrations"`
+	Tables     map[string][]map[string]interface{} `json:"tables"`
+}
+
+// tableOrder lists parent tables first so restored rows always reference
+// rows that already exist; other tables follow alphabetically
+var tableOrder = []string{"clients", "contracts", "invoices", "time_entries"}
+
+// dataTables returns the user tables that hold data, skipping SQLite
+// internals, the migrations and audit logs, which describe this database
+// file rather than its data, and full-text search indexes (which are
+// rebuilt by triggers on restore)
+func dataTables(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name, COALESCE(sql, '') FROM sqlite_master WHERE type = 'table' ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	var virtual []string
+	for rows.Next() {
+		var name, ddl string
+		if err := rows.Scan(&name, &ddl); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		if strings.HasPrefix(strings.ToUpper(ddl), "CREATE VIRTUAL TABLE") {
+			virtual = append(virtual, name)
+		}
+		names = append(names, name)
+	}
+
+	var tables []string
+	for _, name := range names {
+		if strings.HasPrefix(name, "sqlite_") || name == "migrations" || name == auditTable {
+			continue
+		}
+		skip := false
+		for _, v := range virtual {
+			if name == v || strings.HasPrefix(name, v+"_") {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			tables = append(tables, name)
+		}
+	}
+
+	rank := func(name string) int {
+		for i, t := range tableOrder {
+			if t == name {
+				return i
+			}
+		}
+		return len(tableOrder)
+	}
+	sort.SliceStable(tables, func(i, j int) bool { return rank(tables[i]) < rank(tables[j]) })
+	return tables, nil
+}
+
+type tableColumn struct {
+	name     string
+	declType string
+	// primaryKey is the column's position in the primary key, 0 if it
+	// isn't part of it
+	primaryKey int
+}
+
+func tableColumns(ctx context.Context, db *sql.DB, table string) ([]tableColumn, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table info for %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []tableColumn
+	for rows.Next() {
+		var cid, notNull, primaryKey int
+		var name, dataType string
+		var defaultValue *string
+		if err := rows.Scan(&cid, &name, &dataType, &notNull, &defaultValue, &primaryKey); err != nil {
+			return nil, fmt.Errorf("failed to scan column info: %w", err)
+		}
+		columns = append(columns, tableColumn{name: name, declType: strings.ToUpper(dataType), primaryKey: primaryKey})
+	}
+	return columns, nil
+}
+
+// AppliedMigrations returns the names of applied migrations, which together
+// identify the schema version
+func AppliedMigrations(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM migrations ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// ExportData dumps every data table
+func ExportData(ctx context.Context, db *sql.DB) (*Export, error) {
+	migrations, err := AppliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := dataTables(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &Export{
+		Format:     ExportFormat,
+		Version:    ExportVersion,
+		ExportedAt: time.Now(),
+		Migrations: migrations,
+		Tables:     map[string][]map[string]interface{}{},
+	}
+
+	for _, table := range tables {
+		records, err := readRows(ctx, db, table, "")
+		if err != nil {
+			return nil, err
+		}
+		export.Tables[table] = records
+	}
+
+	return export, nil
+}
+
+// ExportRows dumps the rows of each table in where that match its WHERE
+// clause, every clause taking the same args, in the layout of ExportData
+// but marked as an ArchiveFormat file: a record of rows about to be purged
+// that import_data won't restore
+func ExportRows(ctx context.Context, db *sql.DB, where map[string]string, args ...interface{}) (*Export, error) {
+	migrations, err := AppliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &Export{
+		Format:     ArchiveFormat,
+		Version:    ExportVersion,
+		ExportedAt: time.Now(),
+		Migrations: migrations,
+		Tables:     map[string][]map[string]interface{}{},
+	}
+	for table, clause := range where {
+		records, err := readRows(ctx, db, table, clause, args...)
+		if err != nil {
+			return nil, err
+		}
+		export.Tables[table] = records
+	}
+	return export, nil
+}
+
+// readRows returns the rows of table matching the WHERE clause where, or
+// every row when it is empty, as column name to value
+func readRows(ctx context.Context, db *sql.DB, table, where string, args ...interface{}) ([]map[string]interface{}, error) {
+	columns, err := tableColumns(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+
+	// Date columns are read back as stored text so they round-trip exactly
+	selects := make([]string, len(columns))
+	for i, col := range columns {
+		if strings.Contains(col.declType, "DATE") || strings.Contains(col.declType, "TIME") {
+			selects[i] = fmt.Sprintf("CAST(%s AS TEXT)", col.name)
+		} else {
+			selects[i] = col.name
+		}
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(selects, ", "), table)
+	if where != "" {
+		query += " WHERE " + where
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	records := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", table, err)
+		}
+
+		record := map[string]interface{}{}
+		for i, col := range columns {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			record[col.name] = values[i]
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// ImportData restores an export into an empty database with the same schema
+func ImportData(ctx context.Context, db *sql.DB, export *Export) (map[string]int, error) {
+	if export.Format != ExportFormat {
+		return nil, fmt.Errorf("not an hours-mcp export (format '%s')", export.Format)
+	}
+	if export.Version != ExportVersion {
+		return nil, fmt.Errorf("unsupported export version %d (expected %d)", export.Version, ExportVersion)
+	}
+
+	migrations, err := AppliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Join(migrations, ",") != strings.Join(sortedCopy(export.Migrations), ",") {
+		return nil, fmt.Errorf("schema version mismatch: export has migrations [%s], database has [%s]",
+			strings.Join(export.Migrations, ", "), strings.Join(migrations, ", "))
+	}
+
+	tables, err := dataTables(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[string]bool{}
+	for _, table := range tables {
+		known[table] = true
+		var count int
+		if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", table, err)
+		}
+		if count > 0 {
+			return nil, fmt.Errorf("database is not empty (%s has %d rows); restore only into an empty database", table, count)
+		}
+	}
+	for table := range export.Tables {
+		if !known[table] {
+			return nil, fmt.Errorf("export contains unknown table '%s'", table)
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Tables after tableOrder may reference each other in any order, so
+	// foreign keys are checked once everything is inserted
+	if _, err := tx.ExecContext(ctx, "PRAGMA defer_foreign_keys = ON"); err != nil {
+		return nil, fmt.Errorf("failed to defer foreign keys: %w", err)
+	}
+
+	counts := map[string]int{}
+	for _, table := range tables {
+		records := export.Tables[table]
+		if len(records) == 0 {
+			continue
+		}
+
+		columns, err := tableColumns(ctx, db, table)
+		if err != nil {
+			return nil, err
+		}
+		valid := map[string]bool{}
+		for _, col := range columns {
+			valid[col.name] = true
+		}
+
+		for i, record := range records {
+			names := make([]string, 0, len(record))
+			for name := range record {
+				if !valid[name] {
+					return nil, fmt.Errorf("%s row %d has unknown column '%s'", table, i+1, name)
+				}
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			values := make([]interface{}, len(names))
+			for j, name := range names {
+				values[j] = jsonValue(record[name])
+			}
+
+			query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table,
+				strings.Join(names, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", "))
+			if _, err := tx.ExecContext(ctx, query, values...); err != nil {
+				return nil, fmt.Errorf("failed to restore %s row %d: %w", table, i+1, err)
+			}
+		}
+		counts[table] = len(records)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit restore: %w", err)
+	}
+	return counts, nil
+}
+
+// jsonValue converts decoded JSON numbers back to integers where possible
+func jsonValue(v interface{}) interface{} {
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+		f, _ := n.Float64()
+		return f
+	}
+	return v
+}
+
+func sortedCopy(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
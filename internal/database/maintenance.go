@@ -0,0 +1,259 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+)
+
+// Orphan counts rows whose reference points at a row that doesn't exist
+type Orphan struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	Parent string `json:"parent"`
+	Count  int    `json:"count"`
+}
+
+// unenforcedReferences are references added to existing tables with ALTER
+// TABLE, which can't declare a foreign key
+var unenforcedReferences = []struct{ table, column, parent string }{
+	{"time_entries", "contract_id", "contracts"},
+}
+
+// IntegrityCheck runs PRAGMA integrity_check and returns the problems it
+// finds, or nil if the database is intact
+func IntegrityCheck(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check integrity: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return nil, fmt.Errorf("failed to scan integrity check: %w", err)
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	return problems, rows.Err()
+}
+
+// Optimize rebuilds the database file to reclaim free space, refreshes the
+// query planner's statistics and folds the write-ahead log back into the
+// database
+func Optimize(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, "ANALYZE"); err != nil {
+		return fmt.Errorf("failed to analyze: %w", err)
+	}
+	if !InMemory() {
+		if _, err := db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+			return fmt.Errorf("failed to checkpoint: %w", err)
+		}
+	}
+	return nil
+}
+
+// Size returns the size of the database in bytes, including its
+// write-ahead log
+func Size(ctx context.Context, db *sql.DB) (int64, error) {
+	if InMemory() {
+		var pages, pageSize int64
+		if err := db.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pages); err != nil {
+			return 0, fmt.Errorf("failed to get page count: %w", err)
+		}
+		if err := db.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+			return 0, fmt.Errorf("failed to get page size: %w", err)
+		}
+		return pages * pageSize, nil
+	}
+
+	var size int64
+	for _, path := range []string{dbPath, dbPath + "-wal"} {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to get database size: %w", err)
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
+
+// RowCounts returns the number of rows in each data table
+func RowCounts(ctx context.Context, db *sql.DB) (map[string]int, error) {
+	tables, err := dataTables(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, table := range tables {
+		var count int
+		if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		counts[table] = count
+	}
+	return counts, nil
+}
+
+// FindOrphans lists rows pointing at missing rows, e.g. entries whose
+// contract or invoice was deleted, grouped by table and column
+func FindOrphans(ctx context.Context, db *sql.DB) ([]Orphan, error) {
+	violations, err := foreignKeyViolations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []Orphan
+	index := map[string]int{}
+	for _, v := range violations {
+		column, _, err := foreignKeyColumn(ctx, db, v.table, v.fkID)
+		if err != nil {
+			return nil, err
+		}
+		key := v.table + "." + column
+		if i, ok := index[key]; ok {
+			orphans[i].Count++
+			continue
+		}
+		index[key] = len(orphans)
+		orphans = append(orphans, Orphan{Table: v.table, Column: column, Parent: v.parent, Count: 1})
+	}
+
+	for _, ref := range unenforcedReferences {
+		var count int
+		err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IS NOT NULL AND %s NOT IN (SELECT id FROM %s)",
+			ref.table, ref.column, ref.column, ref.parent)).Scan(&count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s.%s: %w", ref.table, ref.column, err)
+		}
+		if count > 0 {
+			orphans = append(orphans, Orphan{Table: ref.table, Column: ref.column, Parent: ref.parent, Count: count})
+		}
+	}
+	return orphans, nil
+}
+
+// RepairOrphans clears optional references to missing rows and deletes rows
+// whose required reference is missing. It returns the number of rows fixed.
+func RepairOrphans(ctx context.Context, db *sql.DB) (int, error) {
+	fixed, err := repairForeignKeyOrphans(ctx, db)
+	if err != nil {
+		return fixed, err
+	}
+	for _, ref := range unenforcedReferences {
+		result, err := db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s IS NOT NULL AND %s NOT IN (SELECT id FROM %s)",
+			ref.table, ref.column, ref.column, ref.parent))
+		if err != nil {
+			return fixed, fmt.Errorf("failed to repair %s.%s: %w", ref.table, ref.column, err)
+		}
+		n, _ := result.RowsAffected()
+		fixed += int(n)
+	}
+	return fixed, nil
+}
+
+type foreignKeyViolation struct {
+	table  string
+	rowID  int64
+	parent string
+	fkID   int
+}
+
+// foreignKeyViolations runs PRAGMA foreign_key_check
+func foreignKeyViolations(ctx context.Context, db *sql.DB) ([]foreignKeyViolation, error) {
+	rows, err := db.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var violations []foreignKeyViolation
+	for rows.Next() {
+		var v foreignKeyViolation
+		var rowID sql.NullInt64
+		if err := rows.Scan(&v.table, &rowID, &v.parent, &v.fkID); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key check: %w", err)
+		}
+		// Only WITHOUT ROWID tables report no rowid, and there are none
+		if rowID.Valid {
+			v.rowID = rowID.Int64
+			violations = append(violations, v)
+		}
+	}
+	return violations, rows.Err()
+}
+
+// repairForeignKeyOrphans clears references to rows that no longer exist,
+// or deletes the referencing row when the reference is required. Deleting a
+// row can orphan its own children, so it repeats until nothing is left.
+func repairForeignKeyOrphans(ctx context.Context, db *sql.DB) (int, error) {
+	fixed := 0
+	for {
+		violations, err := foreignKeyViolations(ctx, db)
+		if err != nil {
+			return fixed, err
+		}
+		if len(violations) == 0 {
+			return fixed, nil
+		}
+
+		for _, v := range violations {
+			column, nullable, err := foreignKeyColumn(ctx, db, v.table, v.fkID)
+			if err != nil {
+				return fixed, err
+			}
+			query := fmt.Sprintf("DELETE FROM %s WHERE rowid = ?", v.table)
+			if nullable {
+				query = fmt.Sprintf("UPDATE %s SET %s = NULL WHERE rowid = ?", v.table, column)
+			}
+			if _, err := db.ExecContext(ctx, query, v.rowID); err != nil {
+				return fixed, fmt.Errorf("failed to repair %s.%s: %w", v.table, column, err)
+			}
+			fixed++
+		}
+	}
+}
+
+// foreignKeyColumn returns the column of a table's foreign key and whether
+// it may be NULL
+func foreignKeyColumn(ctx context.Context, db *sql.DB, table string, fkID int) (string, bool, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("PRAGMA foreign_key_list(%s)", table))
+	if err != nil {
+		return "", false, err
+	}
+	column := ""
+	for rows.Next() {
+		var id, seq int
+		var parent, from, onUpdate, onDelete, match string
+		var to sql.NullString
+		if err := rows.Scan(&id, &seq, &parent, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			rows.Close()
+			return "", false, err
+		}
+		if id == fkID {
+			column = from
+		}
+	}
+	rows.Close()
+	if column == "" {
+		return "", false, fmt.Errorf("foreign key %d not found on %s", fkID, table)
+	}
+
+	var notNull bool
+	err = db.QueryRowContext(ctx, fmt.Sprintf("SELECT \"notnull\" FROM pragma_table_info('%s') WHERE name = ?", table), column).Scan(&notNull)
+	if err != nil {
+		return "", false, err
+	}
+	return column, !notNull, nil
+}
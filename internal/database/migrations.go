@@ -0,0 +1,292 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/secrets"
+)
+
+// MigrationStep is a migration as listed by a dry run or a rollback
+type MigrationStep struct {
+	Name        string
+	Description string
+	// Reversible reports whether the migration can be rolled back
+	Reversible bool
+}
+
+func (m migration) step() MigrationStep {
+	return MigrationStep{Name: m.name, Description: m.description, Reversible: m.down != nil}
+}
+
+// SchemaVersion describes the migrations applied to the open database
+type SchemaVersion struct {
+	// Applied counts the migrations recorded in the database and Known
+	// those this build has; more applied than known means a newer build
+	// migrated the database
+	Applied         int       `json:"applied"`
+	Known           int       `json:"known"`
+	Latest          string    `json:"latest" jsonschema:"The last migration applied"`
+	LatestAppliedAt time.Time `json:"latest_applied_at"`
+}
+
+// ReadSchemaVersion returns the migrations applied to db
+func ReadSchemaVersion(ctx context.Context, db *sql.DB) (*SchemaVersion, error) {
+	v := &SchemaVersion{Known: len(migrations)}
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM migrations").Scan(&v.Applied)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	if v.Applied == 0 {
+		return v, nil
+	}
+	err = db.QueryRowContext(ctx, "SELECT name, applied_at FROM migrations ORDER BY id DESC LIMIT 1").Scan(&v.Latest, &v.LatestAppliedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	return v, nil
+}
+
+// PendingMigrations returns the migrations Initialize would apply to the
+// database at path, without changing it. A database that doesn't exist yet
+// gets them all.
+func PendingMigrations(path string) ([]MigrationStep, error) {
+	if path == MemoryPath {
+		return nil, fmt.Errorf("in-memory databases start empty; there are no migrations to plan")
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		var steps []MigrationStep
+		for _, m := range migrations {
+			steps = append(steps, m.step())
+		}
+		return steps, nil
+	}
+
+	db, err := openForMigrations(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	applied, err := appliedInOrder(db)
+	if err != nil {
+		return nil, err
+	}
+	done := map[string]bool{}
+	for _, name := range applied {
+		done[name] = true
+	}
+
+	var steps []MigrationStep
+	for _, m := range migrations {
+		if !done[m.name] {
+			steps = append(steps, m.step())
+		}
+	}
+	return steps, nil
+}
+
+// RollbackMigrations undoes the last count applied migrations of the
+// database at path, newest first, after a pre-rollback backup. Nothing is
+// changed if any of them can't be undone, or when dryRun is set; either way
+// the migrations are returned in the order they would be undone.
+func RollbackMigrations(path string, count int, dryRun bool) ([]MigrationStep, error) {
+	if path == MemoryPath {
+		return nil, fmt.Errorf("in-memory databases start empty; there are no migrations to roll back")
+	}
+	if count < 1 {
+		return nil, fmt.Errorf("the number of migrations to roll back must be at least 1")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	db, err := openForMigrations(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	applied, err := appliedInOrder(db)
+	if err != nil {
+		return nil, err
+	}
+	if count > len(applied) {
+		return nil, fmt.Errorf("only %d migrations are applied", len(applied))
+	}
+
+	known := map[string]migration{}
+	for _, m := range migrations {
+		known[m.name] = m
+	}
+	var undo []migration
+	for i := len(applied) - 1; i >= len(applied)-count; i-- {
+		m, ok := known[applied[i]]
+		if !ok {
+			return nil, fmt.Errorf("migration %s was applied by a newer version; roll it back with that version", applied[i])
+		}
+		undo = append(undo, m)
+	}
+
+	steps := make([]MigrationStep, len(undo))
+	for i, m := range undo {
+		steps[i] = m.step()
+	}
+	if dryRun {
+		return steps, nil
+	}
+	for _, m := range undo {
+		if m.down == nil {
+			return steps, fmt.Errorf("migration %s can't be rolled back (%s); restore a backup taken before it instead", m.name, m.description)
+		}
+	}
+
+	if _, err := Backup(context.Background(), db, "pre-rollback"); err != nil {
+		return nil, fmt.Errorf("failed to back up before rolling back: %w", err)
+	}
+	// Dropped columns can't be named by triggers; the next start recreates
+	// them
+	if err := dropAuditTriggers(db); err != nil {
+		return nil, err
+	}
+	for _, m := range undo {
+		if err := m.down(db); err != nil {
+			return nil, fmt.Errorf("failed to roll back migration %s: %w", m.name, err)
+		}
+		if _, err := db.Exec("DELETE FROM migrations WHERE name = ?", m.name); err != nil {
+			return nil, fmt.Errorf("failed to record rollback of %s: %w", m.name, err)
+		}
+		slog.Info("rolled back migration", "name", m.name)
+	}
+	return steps, nil
+}
+
+// openForMigrations opens an existing database the way Initialize runs
+// migrations on it: without enforcing foreign keys
+func openForMigrations(path string) (*sql.DB, error) {
+	dbKey = secrets.DatabaseKey()
+	if dbKey != "" && !encryptionSupported {
+		return nil, fmt.Errorf("%s is set but this build can't encrypt databases; build with -tags sqlcipher or unset it", secrets.DatabaseKeyEnv)
+	}
+	db, err := sql.Open(driverName, withPragmas(path, path, false))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	dbPath = path
+	return db, nil
+}
+
+// appliedInOrder returns the names of the applied migrations, oldest first
+func appliedInOrder(db *sql.DB) ([]string, error) {
+	var exists int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'migrations'").Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	if exists == 0 {
+		return nil, nil
+	}
+
+	rows, err := db.Query("SELECT name FROM migrations ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// dropColumns removes columns added by a migration, skipping any that are
+// already gone
+func dropColumns(db *sql.DB, table string, columns ...string) error {
+	for _, column := range columns {
+		exists, err := columnExists(db, table, column)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column)); err != nil {
+			return fmt.Errorf("failed to drop %s.%s: %w", table, column, err)
+		}
+	}
+	return nil
+}
+
+// dropTables removes tables created by a migration
+func dropTables(db *sql.DB, tables ...string) error {
+	for _, table := range tables {
+		if _, err := db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", table)); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// convertColumnFromCents undoes convertColumnToCents, replacing an INTEGER
+// cents column with a REAL amount column
+func convertColumnFromCents(db *sql.DB, tableName, from, to string) error {
+	exists, err := columnExists(db, tableName, from)
+	if err != nil || !exists {
+		return err
+	}
+	if err := addColumnIfNotExists(db, tableName, to, "REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("UPDATE %s SET %s = %s / 100.0", tableName, to, from))
+	if err != nil {
+		return fmt.Errorf("failed to convert %s.%s from cents: %w", tableName, from, err)
+	}
+	return dropColumns(db, tableName, from)
+}
+
+// decryptSensitiveFields undoes encryptSensitiveFields
+func decryptSensitiveFields(db *sql.DB) error {
+	for _, f := range sensitiveFields {
+		rows, err := db.Query(fmt.Sprintf("SELECT id, %s FROM %s WHERE COALESCE(%s, '') != ''", f.column, f.table, f.column))
+		if err != nil {
+			return err
+		}
+		encrypted := map[int]string{}
+		for rows.Next() {
+			var id int
+			var value string
+			if err := rows.Scan(&id, &value); err != nil {
+				rows.Close()
+				return err
+			}
+			if secrets.IsEncrypted(value) {
+				encrypted[id] = value
+			}
+		}
+		rows.Close()
+
+		for id, value := range encrypted {
+			plain, err := secrets.Decrypt(value)
+			if err != nil {
+				return err
+			}
+			if _, err := db.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = ?", f.table, f.column), plain, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package fx
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ECB reference rate feeds. Rates are quoted as units of currency per euro.
+const (
+	ECBDailyURL   = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+	ECBHistoryURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist-90d.xml"
+)
+
+// Rate is the value of one euro in Currency on Date
+type Rate struct {
+	Date     time.Time `json:"date"`
+	Currency string    `json:"currency"`
+	PerEUR   float64   `json:"per_eur"`
+}
+
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string  `xml:"currency,attr"`
+			Rate     float64 `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ParseECB reads an ECB eurofxref XML document
+func ParseECB(r io.Reader) ([]Rate, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse ECB rates: %w", err)
+	}
+
+	var rates []Rate
+	for _, day := range envelope.Days {
+		date, err := time.Parse("2006-01-02", day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ECB date '%s'", day.Time)
+		}
+		for _, r := range day.Rates {
+			if r.Currency == "" || r.Rate <= 0 {
+				continue
+			}
+			rates = append(rates, Rate{Date: date, Currency: r.Currency, PerEUR: r.Rate})
+		}
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("no rates found in ECB response")
+	}
+	return rates, nil
+}
+
+// FetchECB downloads the latest ECB reference rates, or the last 90 days of
+// them when history is set
+func FetchECB(ctx context.Context, history bool) ([]Rate, error) {
+	url := ECBDailyURL
+	if history {
+		url = ECBHistoryURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ECB rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch ECB rates: %s", resp.Status)
+	}
+	return ParseECB(resp.Body)
+}
@@ -0,0 +1,110 @@
+package ics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Calendar is a minimal iCalendar (RFC 5545) writer for all-day and timed
+// events
+type Calendar struct {
+	// Name is shown by calendar apps that support X-WR-CALNAME
+	Name   string
+	events []Event
+}
+
+// Event is a calendar event. An event with a zero Start lasts all of Date;
+// otherwise it runs from Start to End in floating time, shown at the same
+// clock time in any time zone.
+type Event struct {
+	UID         string
+	Date        time.Time
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Categories  []string
+}
+
+// Add appends an event
+func (c *Calendar) Add(e Event) {
+	c.events = append(c.events, e)
+}
+
+// Len returns the number of events
+func (c *Calendar) Len() int {
+	return len(c.events)
+}
+
+// Write encodes the calendar as an .ics file, stamped with now
+func (c *Calendar) Write(out io.Writer, now time.Time) error {
+	w := bufio.NewWriter(out)
+	line := func(name, value string) {
+		w.WriteString(fold(name + ":" + value))
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//hours-mcp//Time Entries//EN")
+	line("CALSCALE", "GREGORIAN")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escape(c.Name))
+	}
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, e := range c.events {
+		line("BEGIN", "VEVENT")
+		line("UID", e.UID)
+		line("DTSTAMP", stamp)
+		if e.Start.IsZero() {
+			line("DTSTART;VALUE=DATE", e.Date.Format("20060102"))
+			line("DTEND;VALUE=DATE", e.Date.AddDate(0, 0, 1).Format("20060102"))
+		} else {
+			line("DTSTART", e.Start.Format("20060102T150405"))
+			line("DTEND", e.End.Format("20060102T150405"))
+		}
+		line("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escape(e.Description))
+		}
+		if len(e.Categories) > 0 {
+			categories := make([]string, len(e.Categories))
+			for i, category := range e.Categories {
+				categories[i] = escape(category)
+			}
+			line("CATEGORIES", strings.Join(categories, ","))
+		}
+		// Logged time doesn't block the calendar it is overlaid on
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return w.Flush()
+}
+
+// escape escapes a TEXT value (RFC 5545 section 3.3.11)
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+// fold ends a content line with CRLF, breaking it into lines of at most 75
+// octets without splitting a UTF-8 character (RFC 5545 section 3.1)
+func fold(line string) string {
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		fmt.Fprintf(&b, "%s\r\n ", line[:cut])
+		line = line[cut:]
+		// Continuation lines start with a space, which counts
+		limit = 74
+	}
+	b.WriteString(line + "\r\n")
+	return b.String()
+}
@@ -0,0 +1,190 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Commit is a single commit read from git history
+type Commit struct {
+	Repo    string    `json:"repo,omitempty"`
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Email   string    `json:"email,omitempty"`
+	Date    time.Time `json:"date"`
+	Message string    `json:"message"`
+}
+
+// WorkDay is the estimated work for one day reconstructed from commits
+type WorkDay struct {
+	Date     time.Time
+	Hours    float64
+	Commits  []Commit
+	Sessions int
+}
+
+const gitFieldSep, gitRecordSep = "\x1f", "\x1e"
+
+// GitLog runs git log in repo and returns commits between since and until.
+// author filters by name or email when set.
+func GitLog(repo string, since, until time.Time, author string) ([]Commit, error) {
+	args := []string{"-C", repo, "log", "--no-merges", "--all",
+		"--since=" + since.Format(time.RFC3339), "--until=" + until.Format(time.RFC3339),
+		"--pretty=format:%H" + gitFieldSep + "%aI" + gitFieldSep + "%an" + gitFieldSep + "%ae" + gitFieldSep + "%s" + gitRecordSep}
+	if author != "" {
+		args = append(args, "--author="+author)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log failed in %s: %s", repo, strings.TrimSpace(stderr.String()))
+	}
+
+	name := filepath.Base(filepath.Clean(repo))
+	var commits []Commit
+	for _, record := range strings.Split(string(out), gitRecordSep) {
+		fields := strings.Split(strings.TrimSpace(record), gitFieldSep)
+		if len(fields) != 5 {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid commit date '%s': %w", fields[1], err)
+		}
+		commits = append(commits, Commit{
+			Repo:    name,
+			Hash:    fields[0],
+			Date:    date.Local(),
+			Author:  fields[2],
+			Email:   fields[3],
+			Message: fields[4],
+		})
+	}
+	return commits, nil
+}
+
+// ParseCommits reads commits from either a JSON array of Commit objects or
+// plain `git log` output in the default format.
+func ParseCommits(r io.Reader) ([]Commit, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commits: %w", err)
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var commits []Commit
+		if err := json.Unmarshal(trimmed, &commits); err != nil {
+			return nil, fmt.Errorf("invalid commit JSON: %w", err)
+		}
+		for i := range commits {
+			commits[i].Date = commits[i].Date.Local()
+		}
+		return commits, nil
+	}
+
+	var commits []Commit
+	var current *Commit
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "commit "):
+			if current != nil {
+				commits = append(commits, *current)
+			}
+			current = &Commit{Hash: strings.Fields(line)[1]}
+		case current == nil:
+			continue
+		case strings.HasPrefix(line, "Author:"):
+			author := strings.TrimSpace(strings.TrimPrefix(line, "Author:"))
+			if i := strings.LastIndex(author, " <"); i >= 0 {
+				current.Email = strings.Trim(author[i+2:], "<>")
+				author = author[:i]
+			}
+			current.Author = author
+		case strings.HasPrefix(line, "Date:"):
+			value := strings.TrimSpace(strings.TrimPrefix(line, "Date:"))
+			date, err := time.Parse("Mon Jan 2 15:04:05 2006 -0700", value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid commit date '%s': %w", value, err)
+			}
+			current.Date = date.Local()
+		case strings.HasPrefix(line, "    ") && current.Message == "":
+			// Only the subject line is kept
+			current.Message = strings.TrimSpace(line)
+		}
+	}
+	if current != nil {
+		commits = append(commits, *current)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read commits: %w", err)
+	}
+	return commits, nil
+}
+
+// ClusterCommits groups commits by day and estimates hours worked. Commits
+// less than maxGap apart form one session; each session is credited with
+// its span plus firstCommit for the work before its first commit.
+func ClusterCommits(commits []Commit, maxGap, firstCommit time.Duration) []WorkDay {
+	sorted := append([]Commit(nil), commits...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	var days []WorkDay
+	for i, commit := range sorted {
+		day := time.Date(commit.Date.Year(), commit.Date.Month(), commit.Date.Day(), 0, 0, 0, 0, time.Local)
+		if len(days) == 0 || !days[len(days)-1].Date.Equal(day) {
+			days = append(days, WorkDay{Date: day})
+		}
+		current := &days[len(days)-1]
+
+		if len(current.Commits) == 0 || commit.Date.Sub(sorted[i-1].Date) > maxGap {
+			current.Sessions++
+			current.Hours += firstCommit.Hours()
+		} else {
+			current.Hours += commit.Date.Sub(sorted[i-1].Date).Hours()
+		}
+		current.Commits = append(current.Commits, commit)
+	}
+	return days
+}
+
+// Summary joins the distinct commit subjects of the day, prefixed with the
+// repository name when commits come from more than one repository.
+func (d WorkDay) Summary(maxLen int) string {
+	repos := map[string]bool{}
+	for _, commit := range d.Commits {
+		repos[commit.Repo] = true
+	}
+
+	seen := map[string]bool{}
+	var parts []string
+	for _, commit := range d.Commits {
+		message := strings.TrimSpace(commit.Message)
+		if len(repos) > 1 && commit.Repo != "" {
+			message = commit.Repo + ": " + message
+		}
+		if message == "" || seen[message] {
+			continue
+		}
+		seen[message] = true
+		parts = append(parts, message)
+	}
+
+	summary := []rune(strings.Join(parts, "; "))
+	if maxLen > 3 && len(summary) > maxLen {
+		return strings.TrimSpace(string(summary[:maxLen-3])) + "..."
+	}
+	return string(summary)
+}
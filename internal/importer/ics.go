@@ -0,0 +1,352 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a timed calendar event read from an iCalendar file
+type Event struct {
+	Summary     string    `json:"summary"`
+	Description string    `json:"description,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+}
+
+// Hours returns the event duration in hours
+func (e Event) Hours() float64 {
+	return e.End.Sub(e.Start).Hours()
+}
+
+// icsProperty is a single content line: NAME;PARAM=VALUE:value
+type icsProperty struct {
+	params map[string]string
+	value  string
+}
+
+// ParseICS reads timed events from an iCalendar stream, expanding simple
+// recurrence rules (daily, weekly, monthly) that fall between from and to.
+// All-day and cancelled events are skipped.
+func ParseICS(r io.Reader, from, to time.Time) ([]Event, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var props map[string][]icsProperty
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			props = map[string][]icsProperty{}
+		case line == "END:VEVENT":
+			if props != nil {
+				expanded, err := buildEvents(props, from, to)
+				if err != nil {
+					return nil, err
+				}
+				events = append(events, expanded...)
+			}
+			props = nil
+		case props != nil:
+			name, prop, ok := parseICSLine(line)
+			if ok {
+				props[name] = append(props[name], prop)
+			}
+		}
+	}
+
+	return events, nil
+}
+
+// unfoldICS joins continuation lines (RFC 5545 section 3.1)
+func unfoldICS(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	return lines, nil
+}
+
+func parseICSLine(line string) (string, icsProperty, bool) {
+	// The value starts at the first colon outside a quoted parameter
+	inQuotes := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", icsProperty{}, false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := icsProperty{params: map[string]string{}, value: line[colon+1:]}
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), prop, true
+}
+
+func first(props map[string][]icsProperty, name string) (icsProperty, bool) {
+	values := props[name]
+	if len(values) == 0 {
+		return icsProperty{}, false
+	}
+	return values[0], true
+}
+
+func unescapeICS(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+// parseICSTime parses a DATE-TIME value; allDay is true for DATE values
+func parseICSTime(prop icsProperty) (t time.Time, allDay bool, err error) {
+	value := prop.value
+	if prop.params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err = time.ParseInLocation("20060102", value, time.Local)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse("20060102T150405Z", value)
+		return t.Local(), false, err
+	}
+
+	loc := time.Local
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if l, lerr := time.LoadLocation(tzid); lerr == nil {
+			loc = l
+		}
+	}
+	t, err = time.ParseInLocation("20060102T150405", value, loc)
+	return t.Local(), false, err
+}
+
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+func parseICSDuration(value string) (time.Duration, error) {
+	m := durationPattern.FindStringSubmatch(value)
+	if m == nil {
+		return 0, fmt.Errorf("invalid duration '%s'", value)
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if m[i+2] != "" {
+			n, _ := strconv.Atoi(m[i+2])
+			d += time.Duration(n) * unit
+		}
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+func buildEvents(props map[string][]icsProperty, from, to time.Time) ([]Event, error) {
+	if status, ok := first(props, "STATUS"); ok && strings.EqualFold(status.value, "CANCELLED") {
+		return nil, nil
+	}
+
+	startProp, ok := first(props, "DTSTART")
+	if !ok {
+		return nil, nil
+	}
+	start, allDay, err := parseICSTime(startProp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DTSTART '%s': %w", startProp.value, err)
+	}
+	if allDay {
+		return nil, nil
+	}
+
+	var duration time.Duration
+	if endProp, ok := first(props, "DTEND"); ok {
+		end, _, err := parseICSTime(endProp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DTEND '%s': %w", endProp.value, err)
+		}
+		duration = end.Sub(start)
+	} else if durProp, ok := first(props, "DURATION"); ok {
+		if duration, err = parseICSDuration(durProp.value); err != nil {
+			return nil, err
+		}
+	}
+	if duration <= 0 {
+		return nil, nil
+	}
+
+	base := Event{}
+	if p, ok := first(props, "SUMMARY"); ok {
+		base.Summary = strings.TrimSpace(unescapeICS(p.value))
+	}
+	if p, ok := first(props, "DESCRIPTION"); ok {
+		base.Description = strings.TrimSpace(unescapeICS(p.value))
+	}
+	if p, ok := first(props, "LOCATION"); ok {
+		base.Location = strings.TrimSpace(unescapeICS(p.value))
+	}
+
+	excluded := map[string]bool{}
+	for _, p := range props["EXDATE"] {
+		for _, value := range strings.Split(p.value, ",") {
+			if t, _, err := parseICSTime(icsProperty{params: p.params, value: value}); err == nil {
+				excluded[t.Format(time.RFC3339)] = true
+			}
+		}
+	}
+
+	starts := []time.Time{start}
+	if rule, ok := first(props, "RRULE"); ok {
+		starts, err = expandRRule(start, rule.value, to)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var events []Event
+	for _, s := range starts {
+		if excluded[s.Format(time.RFC3339)] || s.Before(from) || s.After(to) {
+			continue
+		}
+		event := base
+		event.Start = s
+		event.End = s.Add(duration)
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// expandRRule lists occurrence start times for a DAILY, WEEKLY or MONTHLY rule up to limit
+func expandRRule(start time.Time, rule string, limit time.Time) ([]time.Time, error) {
+	parts := map[string]string{}
+	for _, part := range strings.Split(rule, ";") {
+		if key, value, ok := strings.Cut(part, "="); ok {
+			parts[strings.ToUpper(key)] = value
+		}
+	}
+
+	interval := 1
+	if v, ok := parts["INTERVAL"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid RRULE interval '%s'", v)
+		}
+		interval = n
+	}
+
+	count := -1
+	if v, ok := parts["COUNT"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RRULE count '%s'", v)
+		}
+		count = n
+	}
+
+	if v, ok := parts["UNTIL"]; ok {
+		until, _, err := parseICSTime(icsProperty{value: v})
+		if err != nil {
+			return nil, fmt.Errorf("invalid RRULE until '%s'", v)
+		}
+		if len(v) == 8 {
+			until = until.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		if until.Before(limit) {
+			limit = until
+		}
+	}
+
+	var byDay []time.Weekday
+	if v, ok := parts["BYDAY"]; ok {
+		for _, day := range strings.Split(v, ",") {
+			if len(day) < 2 {
+				continue
+			}
+			if wd, ok := icsWeekdays[strings.ToUpper(day[len(day)-2:])]; ok {
+				byDay = append(byDay, wd)
+			}
+		}
+	}
+
+	var starts []time.Time
+	add := func(t time.Time) bool {
+		if t.After(limit) || count == 0 {
+			return false
+		}
+		starts = append(starts, t)
+		if count > 0 {
+			count--
+		}
+		return true
+	}
+
+	switch strings.ToUpper(parts["FREQ"]) {
+	case "DAILY":
+		for t := start; add(t); t = t.AddDate(0, 0, interval) {
+		}
+	case "WEEKLY":
+		if len(byDay) == 0 {
+			byDay = []time.Weekday{start.Weekday()}
+		}
+		// Walk week by week from the Monday of the start week
+		weekStart := start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+		for week := weekStart; !week.After(limit) && count != 0; week = week.AddDate(0, 0, 7*interval) {
+			for offset := 0; offset < 7; offset++ {
+				t := week.AddDate(0, 0, offset)
+				if t.Before(start) {
+					continue
+				}
+				for _, wd := range byDay {
+					if t.Weekday() == wd && !add(t) {
+						return starts, nil
+					}
+				}
+			}
+		}
+	case "MONTHLY":
+		for i := 0; ; i += interval {
+			t := start.AddDate(0, i, 0)
+			if t.Day() != start.Day() {
+				continue
+			}
+			if !add(t) {
+				break
+			}
+		}
+	default:
+		// Unsupported frequency: keep the first occurrence only
+		add(start)
+	}
+
+	return starts, nil
+}
@@ -0,0 +1,259 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Record is a single time entry read from an external export
+type Record struct {
+	Line        int       `json:"line"`
+	Date        time.Time `json:"date"`
+	Hours       float64   `json:"hours"`
+	Client      string    `json:"client,omitempty"`
+	Project     string    `json:"project,omitempty"`
+	Contract    string    `json:"contract,omitempty"`
+	Issue       string    `json:"issue,omitempty"`
+	Description string    `json:"description,omitempty"`
+}
+
+// Formats lists the supported import formats
+var Formats = []string{"csv", "harvest", "clockify", "jira"}
+
+// columns maps the fields of a Record to the header names used by a format.
+// The first header found in the file wins.
+type columns struct {
+	date        []string
+	hours       []string
+	duration    []string
+	client      []string
+	project     []string
+	contract    []string
+	description []string
+	task        []string
+	issue       []string
+	summary     []string
+}
+
+var formatColumns = map[string]columns{
+	"csv": {
+		date:        []string{"date"},
+		hours:       []string{"hours"},
+		client:      []string{"client", "client_name"},
+		project:     []string{"project"},
+		contract:    []string{"contract", "contract_number"},
+		description: []string{"description", "notes"},
+	},
+	"harvest": {
+		date:        []string{"date"},
+		hours:       []string{"hours"},
+		client:      []string{"client"},
+		project:     []string{"project"},
+		contract:    []string{"project code"},
+		description: []string{"notes"},
+		task:        []string{"task"},
+	},
+	"clockify": {
+		date:        []string{"start date"},
+		hours:       []string{"duration (decimal)"},
+		duration:    []string{"duration (h)"},
+		client:      []string{"client"},
+		project:     []string{"project"},
+		description: []string{"description"},
+		task:        []string{"task"},
+	},
+	"jira": {
+		date:        []string{"work date", "started", "date"},
+		hours:       []string{"hours", "time spent (h)"},
+		client:      []string{"account name", "account"},
+		project:     []string{"project key"},
+		description: []string{"work description", "comment"},
+		issue:       []string{"issue key", "issue"},
+		summary:     []string{"issue summary", "summary"},
+	},
+}
+
+// dateLayouts are tried in order when parsing export dates
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05.000-0700",
+	"01/02/2006",
+	"1/2/2006",
+	"02.01.2006",
+	"2006/01/02",
+}
+
+// Parse reads all records from r using the given export format
+func Parse(format string, r io.Reader) ([]Record, error) {
+	format = strings.ToLower(format)
+	cols, ok := formatColumns[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported format '%s' (supported: %s)", format, strings.Join(Formats, ", "))
+	}
+
+	// Jira and Tempo exports come as either CSV or JSON
+	if format == "jira" {
+		buffered := bufio.NewReader(r)
+		if isJSON(buffered) {
+			return parseJiraJSON(buffered)
+		}
+		r = buffered
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, exists := index[name]; !exists {
+			index[name] = i
+		}
+	}
+
+	find := func(names []string) int {
+		for _, name := range names {
+			if i, ok := index[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+
+	dateCol := find(cols.date)
+	hoursCol := find(cols.hours)
+	durationCol := find(cols.duration)
+	if dateCol < 0 {
+		return nil, fmt.Errorf("missing date column (expected one of: %s)", strings.Join(cols.date, ", "))
+	}
+	if hoursCol < 0 && durationCol < 0 {
+		return nil, fmt.Errorf("missing hours column (expected one of: %s)", strings.Join(append(cols.hours, cols.duration...), ", "))
+	}
+	clientCol := find(cols.client)
+	projectCol := find(cols.project)
+	contractCol := find(cols.contract)
+	descriptionCol := find(cols.description)
+	taskCol := find(cols.task)
+	issueCol := find(cols.issue)
+	summaryCol := find(cols.summary)
+
+	var records []Record
+	line := 1
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		field := func(col int) string {
+			if col < 0 || col >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[col])
+		}
+
+		if strings.Join(row, "") == "" {
+			continue
+		}
+
+		date, err := parseDate(field(dateCol))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		var hours float64
+		if value := field(hoursCol); value != "" {
+			hours, err = strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+		} else {
+			hours, err = parseDuration(field(durationCol))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid hours: %w", line, err)
+		}
+
+		description := field(descriptionCol)
+		if task := field(taskCol); task != "" {
+			if description == "" {
+				description = task
+			} else if !strings.Contains(description, task) {
+				description = task + ": " + description
+			}
+		}
+		if description == "" {
+			description = field(summaryCol)
+		}
+
+		issue := field(issueCol)
+		project := field(projectCol)
+		if issue != "" {
+			description = issueDescription(issue, description)
+			if project == "" {
+				project = issueProject(issue)
+			}
+		}
+
+		records = append(records, Record{
+			Line:        line,
+			Date:        date,
+			Hours:       hours,
+			Client:      field(clientCol),
+			Project:     project,
+			Contract:    field(contractCol),
+			Issue:       issue,
+			Description: description,
+		})
+	}
+
+	return records, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date '%s'", value)
+}
+
+// parseDuration converts an "HH:MM[:SS]" duration into decimal hours
+func parseDuration(value string) (float64, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("unrecognized duration '%s'", value)
+	}
+
+	var hours float64
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, fmt.Errorf("unrecognized duration '%s'", value)
+		}
+		switch i {
+		case 0:
+			hours += float64(n)
+		case 1:
+			hours += float64(n) / 60
+		case 2:
+			hours += float64(n) / 3600
+		}
+	}
+	return hours, nil
+}
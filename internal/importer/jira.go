@@ -0,0 +1,187 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// jiraWorklog covers the fields used by Tempo (results), Jira REST
+// (worklogs) and flat JSON worklog exports
+type jiraWorklog struct {
+	Issue            json.RawMessage `json:"issue"`
+	IssueKey         string          `json:"issueKey"`
+	Key              string          `json:"key"`
+	Summary          string          `json:"summary"`
+	Project          string          `json:"project"`
+	Account          string          `json:"account"`
+	TimeSpentSeconds float64         `json:"timeSpentSeconds"`
+	Hours            float64         `json:"hours"`
+	StartDate        string          `json:"startDate"`
+	Started          string          `json:"started"`
+	Date             string          `json:"date"`
+	Description      string          `json:"description"`
+	Comment          json.RawMessage `json:"comment"`
+}
+
+// isJSON reports whether the buffered input starts with a JSON object or array
+func isJSON(r *bufio.Reader) bool {
+	for n := 1; n <= 64; n++ {
+		peek, _ := r.Peek(n)
+		if len(peek) < n {
+			return false
+		}
+		switch c := peek[n-1]; {
+		case c == '{' || c == '[':
+			return true
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == 0xEF || c == 0xBB || c == 0xBF:
+			continue
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+func parseJiraJSON(r io.Reader) ([]Record, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read worklogs: %w", err)
+	}
+	data = []byte(strings.TrimPrefix(string(data), "\ufeff"))
+
+	var worklogs []jiraWorklog
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		if err := json.Unmarshal(data, &worklogs); err != nil {
+			return nil, fmt.Errorf("invalid worklog JSON: %w", err)
+		}
+	} else {
+		var wrapper struct {
+			Results  []jiraWorklog `json:"results"`
+			Worklogs []jiraWorklog `json:"worklogs"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return nil, fmt.Errorf("invalid worklog JSON: %w", err)
+		}
+		worklogs = append(wrapper.Results, wrapper.Worklogs...)
+	}
+
+	var records []Record
+	for i, w := range worklogs {
+		issue := w.IssueKey
+		if issue == "" {
+			issue = w.Key
+		}
+		var nested struct {
+			Key     string `json:"key"`
+			Summary string `json:"summary"`
+		}
+		if len(w.Issue) > 0 && json.Unmarshal(w.Issue, &nested) == nil {
+			if issue == "" {
+				issue = nested.Key
+			}
+			if w.Summary == "" {
+				w.Summary = nested.Summary
+			}
+		}
+
+		dateValue := w.StartDate
+		if dateValue == "" {
+			dateValue = w.Started
+		}
+		if dateValue == "" {
+			dateValue = w.Date
+		}
+		date, err := parseDate(dateValue)
+		if err != nil && len(dateValue) >= 10 {
+			date, err = parseDate(dateValue[:10])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("worklog %d: %w", i+1, err)
+		}
+
+		hours := w.Hours
+		if hours == 0 {
+			hours = w.TimeSpentSeconds / 3600
+		}
+
+		description := w.Description
+		if description == "" {
+			description = commentText(w.Comment)
+		}
+		if description == "" {
+			description = w.Summary
+		}
+
+		project := w.Project
+		if issue != "" {
+			description = issueDescription(issue, description)
+			if project == "" {
+				project = issueProject(issue)
+			}
+		}
+
+		records = append(records, Record{
+			Line:        i + 1,
+			Date:        date,
+			Hours:       hours,
+			Client:      w.Account,
+			Project:     project,
+			Issue:       issue,
+			Description: description,
+		})
+	}
+
+	return records, nil
+}
+
+// commentText extracts plain text from a string comment or an Atlassian
+// Document Format object
+func commentText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return strings.TrimSpace(text)
+	}
+
+	var node struct {
+		Text    string            `json:"text"`
+		Content []json.RawMessage `json:"content"`
+	}
+	if json.Unmarshal(raw, &node) != nil {
+		return ""
+	}
+	parts := []string{}
+	if node.Text != "" {
+		parts = append(parts, node.Text)
+	}
+	for _, child := range node.Content {
+		if t := commentText(child); t != "" {
+			parts = append(parts, t)
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+// issueDescription prefixes the description with the issue key
+func issueDescription(issue, description string) string {
+	if description == "" {
+		return issue
+	}
+	if strings.HasPrefix(description, issue) {
+		return description
+	}
+	return issue + ": " + description
+}
+
+// issueProject returns the project key of an issue key such as ABC-123
+func issueProject(issue string) string {
+	if i := strings.LastIndex(issue, "-"); i > 0 {
+		return issue[:i]
+	}
+	return ""
+}
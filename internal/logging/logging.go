@@ -0,0 +1,62 @@
+// Package logging sets up the server's diagnostic logging. MCP clients talk
+// to the server over stdout, so logs go to stderr or a file, never stdout.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LevelEnv and FileEnv are the environment variables that set the log level
+// and log file when the flags aren't given
+const (
+	LevelEnv = "HOURS_LOG_LEVEL"
+	FileEnv  = "HOURS_LOG_FILE"
+)
+
+// Setup makes slog's default logger write text records at level (debug,
+// info, warn or error) to file, appending, or to stderr when file is empty.
+// Empty arguments fall back to HOURS_LOG_LEVEL and HOURS_LOG_FILE. The
+// returned function closes the log file.
+func Setup(level, file string) (func() error, error) {
+	if level == "" {
+		level = os.Getenv(LevelEnv)
+	}
+	if file == "" {
+		file = os.Getenv(FileEnv)
+	}
+
+	var slogLevel slog.Level
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		slogLevel = slog.LevelDebug
+	case "", "info":
+		slogLevel = slog.LevelInfo
+	case "warn", "warning":
+		slogLevel = slog.LevelWarn
+	case "error":
+		slogLevel = slog.LevelError
+	default:
+		return nil, fmt.Errorf("unknown log level %q; use debug, info, warn or error", level)
+	}
+
+	var out io.Writer = os.Stderr
+	closeLog := func() error { return nil }
+	if file != "" {
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create log directory: %w", err)
+		}
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		out, closeLog = f, f.Close
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slogLevel})))
+	return closeLog, nil
+}
@@ -0,0 +1,269 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Connection security modes
+const (
+	SecurityStartTLS = "starttls"
+	SecurityTLS      = "tls"
+	SecurityNone     = "none"
+)
+
+const dialTimeout = 30 * time.Second
+
+// Config holds the SMTP server settings used to send mail
+type Config struct {
+	Host        string `json:"host"`
+	Port        int    `json:"port"`
+	Username    string `json:"username"`
+	Password    string `json:"-"`
+	FromAddress string `json:"from_address"`
+	FromName    string `json:"from_name"`
+	Security    string `json:"security"`
+}
+
+// Attachment is a file sent along with a message
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Message is a plain text email with optional attachments
+type Message struct {
+	To          []string
+	Cc          []string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// PasswordPath returns the file the SMTP password is kept in. It lives
+// outside the database so exports and backups never contain it.
+func PasswordPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".hours", "smtp_password"), nil
+}
+
+// SavePassword stores the SMTP password readable only by the current user.
+// An empty password removes the stored one.
+func SavePassword(password string) error {
+	path, err := PasswordPath()
+	if err != nil {
+		return err
+	}
+	if password == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove SMTP password: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(password), 0600); err != nil {
+		return fmt.Errorf("failed to save SMTP password: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(path, 0600)
+}
+
+// LoadPassword returns the stored SMTP password, or "" if none is set
+func LoadPassword() (string, error) {
+	path, err := PasswordPath()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read SMTP password: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Validate checks that the config has everything needed to send
+func (c Config) Validate() error {
+	if c.Host == "" {
+		return fmt.Errorf("SMTP host is not set")
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("invalid SMTP port %d", c.Port)
+	}
+	if _, err := mail.ParseAddress(c.FromAddress); err != nil {
+		return fmt.Errorf("invalid from address '%s'", c.FromAddress)
+	}
+	switch c.Security {
+	case SecurityStartTLS, SecurityTLS, SecurityNone:
+	default:
+		return fmt.Errorf("invalid security '%s': must be starttls, tls or none", c.Security)
+	}
+	return nil
+}
+
+func (c Config) from() string {
+	return (&mail.Address{Name: c.FromName, Address: c.FromAddress}).String()
+}
+
+// Send delivers the message through the configured SMTP server
+func Send(cfg Config, msg *Message) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if len(msg.To) == 0 {
+		return fmt.Errorf("message has no recipients")
+	}
+
+	var recipients []string
+	for _, addr := range append(append([]string{}, msg.To...), msg.Cc...) {
+		parsed, err := mail.ParseAddress(addr)
+		if err != nil {
+			return fmt.Errorf("invalid recipient '%s': %w", addr, err)
+		}
+		recipients = append(recipients, parsed.Address)
+	}
+
+	data, err := msg.Bytes(cfg.from())
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(cfg.Host, fmt.Sprintf("%d", cfg.Port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+
+	var conn net.Conn
+	if cfg.Security == SecurityTLS {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, dialTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if cfg.Security == SecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS; use security 'tls' or 'none'", cfg.Host)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(cfg.FromAddress); err != nil {
+		return fmt.Errorf("sender rejected: %w", err)
+	}
+	for _, rcpt := range recipients {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", rcpt, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("message rejected: %w", err)
+	}
+	return client.Quit()
+}
+
+// Bytes encodes the message as a MIME document
+func (m *Message) Bytes(from string) ([]byte, error) {
+	var b bytes.Buffer
+	boundary := randomToken(12)
+
+	header := func(key, value string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", key, value)
+	}
+	header("From", from)
+	header("To", strings.Join(m.To, ", "))
+	if len(m.Cc) > 0 {
+		header("Cc", strings.Join(m.Cc, ", "))
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%s@hours-mcp>", randomToken(16)))
+	header("MIME-Version", "1.0")
+	header("Content-Type", fmt.Sprintf("multipart/mixed; boundary=%q", boundary))
+	b.WriteString("\r\n")
+
+	fmt.Fprintf(&b, "--%s\r\n", boundary)
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&b)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(m.Body, "\n", "\r\n"))); err != nil {
+		return nil, fmt.Errorf("failed to encode body: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode body: %w", err)
+	}
+	b.WriteString("\r\n")
+
+	for _, a := range m.Attachments {
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		name := mime.QEncoding.Encode("utf-8", a.Name)
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		fmt.Fprintf(&b, "Content-Type: %s; name=%q\r\n", contentType, name)
+		b.WriteString("Content-Transfer-Encoding: base64\r\n")
+		fmt.Fprintf(&b, "Content-Disposition: attachment; filename=%q\r\n\r\n", name)
+
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			b.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		b.WriteString(encoded + "\r\n")
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+
+	return b.Bytes(), nil
+}
+
+func randomToken(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
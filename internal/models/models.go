@@ -2,39 +2,80 @@ package models
 
 import (
 	"time"
+
+	"github.com/austin/hours-mcp/internal/money"
 )
 
 type Client struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	Address   string    `json:"address,omitempty"`
-	City      string    `json:"city,omitempty"`
-	State     string    `json:"state,omitempty"`
-	ZipCode   string    `json:"zip_code,omitempty"`
-	Country   string    `json:"country,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              int               `json:"id"`
+	Name            string            `json:"name"`
+	Address         string            `json:"address,omitempty"`
+	City            string            `json:"city,omitempty"`
+	State           string            `json:"state,omitempty"`
+	ZipCode         string            `json:"zip_code,omitempty"`
+	Country         string            `json:"country,omitempty"`
+	TaxID           string            `json:"tax_id,omitempty"`
+	TaxTreatment    string            `json:"tax_treatment,omitempty"`
+	WithholdingRate float64           `json:"withholding_rate,omitempty"`
+	Notes           string            `json:"notes,omitempty"`
+	DefaultCurrency string            `json:"default_currency,omitempty"`
+	Locale          string            `json:"locale,omitempty"`
+	TimeZone        string            `json:"time_zone,omitempty"`
+	CustomFields    map[string]string `json:"custom_fields,omitempty"`
+	// Delivery preferences for the client's invoices; empty ones fall back
+	// to the settings and tool defaults
+	DeliveryMethod string `json:"delivery_method,omitempty"`
+	DeliveryNote   string `json:"delivery_note,omitempty"`
+	InvoiceFormat  string `json:"invoice_format,omitempty"`
+	InvoiceDetail  string `json:"invoice_detail,omitempty"`
+	// BillingCycle is how often the client is invoiced, e.g. "monthly:15"
+	// for the 15th of every month; empty when it has none
+	BillingCycle string     `json:"billing_cycle,omitempty"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
 type Contract struct {
-	ID             int        `json:"id"`
-	ClientID       int        `json:"client_id"`
-	ContractNumber string     `json:"contract_number"`
-	Name           string     `json:"name"`
-	HourlyRate     float64    `json:"hourly_rate"`
-	Currency       string     `json:"currency"`
-	ContractType   string     `json:"contract_type"`
-	StartDate      time.Time  `json:"start_date"`
-	EndDate        *time.Time `json:"end_date,omitempty"`
-	Status         string     `json:"status"`
-	PaymentTerms   string     `json:"payment_terms,omitempty"`
-	Notes          string     `json:"notes,omitempty"`
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
+	ID             int         `json:"id"`
+	ClientID       int         `json:"client_id"`
+	ContractNumber string      `json:"contract_number"`
+	Name           string      `json:"name"`
+	HourlyRate     money.Cents `json:"hourly_rate"`
+	Currency       string      `json:"currency"`
+	ContractType   string      `json:"contract_type"`
+	StartDate      time.Time   `json:"start_date"`
+	EndDate        *time.Time  `json:"end_date,omitempty"`
+	Status         string      `json:"status"`
+	PaymentTerms   string      `json:"payment_terms,omitempty"`
+	Notes          string      `json:"notes,omitempty"`
+	RetainerHours  float64     `json:"retainer_hours,omitempty"`
+	RetainerFee    money.Cents `json:"retainer_fee,omitempty"`
+	OverageRate    money.Cents `json:"overage_rate,omitempty"`
+	RolloverMonths int         `json:"rollover_months,omitempty"`
+	BudgetHours    float64     `json:"budget_hours,omitempty"`
+	BudgetAmount   money.Cents `json:"budget_amount,omitempty"`
+	BudgetAlert    float64     `json:"budget_alert_percent,omitempty"`
+	BudgetLimit    bool        `json:"budget_hard_limit,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
 
 	Client *Client `json:"client,omitempty"`
 }
 
+// ContractDocument is a file kept with a contract, such as the signed
+// contract or a statement of work
+type ContractDocument struct {
+	ID         int       `json:"id"`
+	ContractID int       `json:"contract_id"`
+	Kind       string    `json:"kind"`
+	Title      string    `json:"title,omitempty"`
+	Path       string    `json:"path"`
+	Copied     bool      `json:"copied" jsonschema:"Whether the path is a copy kept next to the database"`
+	Missing    bool      `json:"missing,omitempty" jsonschema:"Whether the file is no longer at its path"`
+	AddedAt    time.Time `json:"added_at"`
+}
+
 type Recipient struct {
 	ID        int       `json:"id"`
 	ClientID  int       `json:"client_id"`
@@ -56,6 +97,36 @@ type PaymentDetails struct {
 	PaymentTerms  string    `json:"payment_terms,omitempty"`
 	Notes         string    `json:"notes,omitempty"`
 	UpdatedAt     time.Time `json:"updated_at"`
+	// Method and Kind are set when the details come from a payment method
+	Method string `json:"payment_method,omitempty"`
+	Kind   string `json:"kind,omitempty"`
+}
+
+// PaymentMethod is an account the business is paid into, such as a bank
+// account, an IBAN, a PayPal address or a crypto wallet
+type PaymentMethod struct {
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	Kind          string    `json:"kind"`
+	Currency      string    `json:"currency,omitempty"`
+	BankName      string    `json:"bank_name,omitempty"`
+	AccountNumber string    `json:"account_number,omitempty"`
+	RoutingNumber string    `json:"routing_number,omitempty"`
+	SwiftCode     string    `json:"swift_code,omitempty"`
+	Notes         string    `json:"notes,omitempty"`
+	IsDefault     bool      `json:"is_default"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Service is a kind of work on the business's rate card, e.g. development
+// or advisory, with the rate contracts are offered for it
+type Service struct {
+	ID          int         `json:"id"`
+	Name        string      `json:"name"`
+	HourlyRate  money.Cents `json:"hourly_rate"`
+	Currency    string      `json:"currency"`
+	Description string      `json:"description,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
 }
 
 type TimeEntry struct {
@@ -65,25 +136,129 @@ type TimeEntry struct {
 	Hours       float64   `json:"hours"`
 	Description string    `json:"description,omitempty"`
 	InvoiceID   *int      `json:"invoice_id,omitempty"`
+	// Billable is false for work that is never invoiced
+	Billable bool `json:"billable"`
+	// NoCharge work is listed on the invoice with its hours but no amount
+	NoCharge bool `json:"no_charge,omitempty"`
+	// Service is the rate card service the hours were for, billed at the
+	// contract's rate for it
+	Service string `json:"service,omitempty"`
+	// AdjustsEntryID is set on an adjustment entry: hours, possibly
+	// negative, correcting the entry it names
+	AdjustsEntryID string `json:"adjusts_entry_id,omitempty"`
+	// NeedsReview is set on entries from a timer stopped at the
+	// timer_max_hours limit, whose hours are likely wrong
+	NeedsReview bool      `json:"needs_review,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 
 	Contract *Contract `json:"contract,omitempty"`
 }
 
+// Expense is a cost incurred for a contract. Billable expenses are rebilled
+// on the client's next invoice. Mileage and per-diem expenses are a quantity
+// (distance or days) times a unit rate.
+type Expense struct {
+	ID          int         `json:"id"`
+	ContractID  int         `json:"contract_id"`
+	Kind        string      `json:"kind"`
+	Date        time.Time   `json:"date"`
+	Amount      money.Cents `json:"amount"`
+	Currency    string      `json:"currency"`
+	Quantity    float64     `json:"quantity,omitempty"`
+	Unit        string      `json:"unit,omitempty"`
+	UnitRate    money.Cents `json:"unit_rate,omitempty"`
+	Category    string      `json:"category,omitempty"`
+	Description string      `json:"description,omitempty"`
+	ReceiptPath string      `json:"receipt_path,omitempty"`
+	Billable    bool        `json:"billable"`
+	InvoiceID   *int        `json:"invoice_id,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+
+	Contract *Contract `json:"contract,omitempty"`
+}
+
+// InternalCategory is a kind of work done for the business itself rather
+// than a client, e.g. admin, marketing or learning
+type InternalCategory struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// InternalEntry is time spent on an internal category. It belongs to no
+// client, so it is never invoiced and only counts towards utilization.
+type InternalEntry struct {
+	ID          int       `json:"id"`
+	Category    string    `json:"category"`
+	Date        time.Time `json:"date"`
+	Hours       float64   `json:"hours"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
 type Invoice struct {
+	ID                int         `json:"id"`
+	ClientID          int         `json:"client_id"`
+	InvoiceNumber     string      `json:"invoice_number"`
+	IssueDate         time.Time   `json:"issue_date"`
+	DueDate           time.Time   `json:"due_date"`
+	TotalAmount       money.Cents `json:"total_amount"`
+	TaxRate           float64     `json:"tax_rate"`
+	TaxAmount         money.Cents `json:"tax_amount"`
+	TaxTreatment      string      `json:"tax_treatment"`
+	TaxNote           string      `json:"tax_note,omitempty"`
+	WithholdingRate   float64     `json:"withholding_rate,omitempty"`
+	WithholdingAmount money.Cents `json:"withholding_amount,omitempty"`
+	Rounding          money.Cents `json:"rounding,omitempty" jsonschema:"Adjustment rounding the amount due, included in the total"`
+	Currency          string      `json:"currency"`
+	Status            string      `json:"status"`
+	PDFPath           string      `json:"pdf_path,omitempty"`
+	CreatedAt         time.Time   `json:"created_at"`
+	// SentAt, SentTo and DeliveryMethod record when, to whom and how the
+	// invoice was last sent; SentAt is nil until it is
+	SentAt         *time.Time `json:"sent_at,omitempty"`
+	SentTo         string     `json:"sent_to,omitempty"`
+	DeliveryMethod string     `json:"delivery_method,omitempty"`
+	// WrittenOffDate is set when the invoice was written off as
+	// uncollectable, with the reason given
+	WrittenOffDate *time.Time `json:"written_off_date,omitempty"`
+	WriteOffReason string     `json:"write_off_reason,omitempty"`
+
+	Client      *Client       `json:"client,omitempty"`
+	TimeEntries []TimeEntry   `json:"time_entries,omitempty"`
+	Items       []InvoiceItem `json:"items,omitempty"`
+	Contracts   []Contract    `json:"contracts,omitempty"`
+}
+
+// InvoiceNote is a dated note following up an invoice, e.g. a dispute or a
+// promise to pay
+type InvoiceNote struct {
 	ID            int       `json:"id"`
-	ClientID      int       `json:"client_id"`
 	InvoiceNumber string    `json:"invoice_number"`
-	IssueDate     time.Time `json:"issue_date"`
-	DueDate       time.Time `json:"due_date"`
-	TotalAmount   float64   `json:"total_amount"`
-	Status        string    `json:"status"`
-	PDFPath       string    `json:"pdf_path,omitempty"`
+	Date          time.Time `json:"date"`
+	Note          string    `json:"note"`
 	CreatedAt     time.Time `json:"created_at"`
+}
 
-	Client      *Client     `json:"client,omitempty"`
-	TimeEntries []TimeEntry `json:"time_entries,omitempty"`
-	Contracts   []Contract  `json:"contracts,omitempty"`
+// InvoiceItem is a priced part of a time entry, a retainer fee or a rebilled
+// expense. Hours past a daily overtime threshold are split off into their own
+// item.
+type InvoiceItem struct {
+	TimeEntryID string      `json:"time_entry_id,omitempty"`
+	ExpenseID   int         `json:"expense_id,omitempty"`
+	ContractID  int         `json:"contract_id"`
+	Date        time.Time   `json:"date"`
+	Description string      `json:"description,omitempty"`
+	Hours       float64     `json:"hours"`
+	RateKind    string      `json:"rate_kind,omitempty"`
+	RateLabel   string      `json:"rate_label,omitempty"`
+	Multiplier  float64     `json:"multiplier"`
+	Rate        money.Cents `json:"rate"`
+	Amount      money.Cents `json:"amount"`
+	// Currency is set on expense items, which need not be in the
+	// contract's currency
+	Currency string `json:"currency,omitempty"`
 }
 
 type BusinessInfo struct {
@@ -0,0 +1,113 @@
+package money
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Cents is an amount of money in minor units (hundredths of the currency
+// unit). Amounts are stored and added up as integers so totals never pick up
+// floating point error; only rates and conversions use floats, and their
+// results are rounded back to whole cents.
+type Cents int64
+
+// FromFloat converts an amount in major units, rounding to the nearest cent
+func FromFloat(amount float64) Cents {
+	return Cents(math.Round(amount * 100))
+}
+
+// Parse reads a decimal amount such as "1234.5" without going through a float
+func Parse(s string) (Cents, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" {
+		whole = "0"
+	}
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("invalid amount '%s': more than two decimal places", s)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount '%s'", s)
+	}
+	cents, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount '%s'", s)
+	}
+	c := Cents(units*100 + cents)
+	if negative {
+		c = -c
+	}
+	return c, nil
+}
+
+// ForHours prices hours at an hourly rate, rounded to the nearest cent
+func ForHours(rate Cents, hours float64) Cents {
+	return Cents(math.Round(float64(rate) * hours))
+}
+
+// Times multiplies the amount by factor, rounded to the nearest cent
+func (c Cents) Times(factor float64) Cents {
+	return Cents(math.Round(float64(c) * factor))
+}
+
+// Percent returns pct percent of the amount, rounded to the nearest cent
+func (c Cents) Percent(pct float64) Cents {
+	return Cents(math.Round(float64(c) * pct / 100))
+}
+
+// Convert applies an exchange rate, rounded to the nearest cent
+func (c Cents) Convert(rate float64) Cents {
+	return Cents(math.Round(float64(c) * rate))
+}
+
+// RoundTo rounds the amount to the nearest multiple of step, halves away
+// from zero, e.g. to 0.05 for Swiss cash rounding. A step of 0 leaves it
+// as is.
+func (c Cents) RoundTo(step Cents) Cents {
+	if step <= 0 {
+		return c
+	}
+	return Cents(math.Round(float64(c)/float64(step))) * step
+}
+
+// Float returns the amount in major units, for display and export only
+func (c Cents) Float() float64 {
+	return float64(c) / 100
+}
+
+// String formats the amount with two decimals, e.g. "1234.50"
+func (c Cents) String() string {
+	sign := ""
+	if c < 0 {
+		sign = "-"
+		c = -c
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, c/100, c%100)
+}
+
+// Format prefixes the amount with its currency, e.g. "USD 1234.50"
+func (c Cents) Format(currency string) string {
+	return currency + " " + c.String()
+}
+
+// MarshalJSON writes the amount as a decimal number in major units so JSON
+// output reads the same as before amounts were stored in cents
+func (c Cents) MarshalJSON() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalJSON accepts a decimal number in major units
+func (c *Cents) UnmarshalJSON(data []byte) error {
+	parsed, err := Parse(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
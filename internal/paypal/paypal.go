@@ -0,0 +1,298 @@
+package paypal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// REST API hosts for the live and sandbox PayPal environments
+const (
+	LiveURL    = "https://api-m.paypal.com"
+	SandboxURL = "https://api-m.sandbox.paypal.com"
+)
+
+// Money is an amount in PayPal's format, e.g. {"currency_code": "USD",
+// "value": "1234.50"}
+type Money struct {
+	CurrencyCode string `json:"currency_code"`
+	Value        string `json:"value"`
+}
+
+// Invoice is the body of a create draft invoice request (Invoicing API v2)
+type Invoice struct {
+	Detail            Detail      `json:"detail"`
+	Invoicer          *Invoicer   `json:"invoicer,omitempty"`
+	PrimaryRecipients []Recipient `json:"primary_recipients,omitempty"`
+	Items             []Item      `json:"items"`
+	Amount            *Amount     `json:"amount,omitempty"`
+}
+
+// Detail holds the invoice number, dates and terms
+type Detail struct {
+	InvoiceNumber      string       `json:"invoice_number"`
+	InvoiceDate        string       `json:"invoice_date"`
+	CurrencyCode       string       `json:"currency_code"`
+	Note               string       `json:"note,omitempty"`
+	TermsAndConditions string       `json:"terms_and_conditions,omitempty"`
+	PaymentTerm        *PaymentTerm `json:"payment_term,omitempty"`
+}
+
+// PaymentTerm sets when the invoice is due
+type PaymentTerm struct {
+	DueDate string `json:"due_date"`
+}
+
+// Invoicer is the business sending the invoice. PayPal fills in the
+// account's email address.
+type Invoicer struct {
+	BusinessName string `json:"business_name,omitempty"`
+	Website      string `json:"website,omitempty"`
+}
+
+// Recipient is who the invoice is billed to
+type Recipient struct {
+	BillingInfo BillingInfo `json:"billing_info"`
+}
+
+// BillingInfo names a recipient and the address PayPal emails it to
+type BillingInfo struct {
+	BusinessName string `json:"business_name,omitempty"`
+	EmailAddress string `json:"email_address,omitempty"`
+}
+
+// Item is an invoice line; PayPal prices it at quantity times unit amount
+type Item struct {
+	Name       string `json:"name"`
+	Quantity   string `json:"quantity"`
+	UnitAmount Money  `json:"unit_amount"`
+}
+
+// Amount adjusts the total PayPal adds up from the items
+type Amount struct {
+	Breakdown Breakdown `json:"breakdown"`
+}
+
+// Breakdown holds the adjustments to the items' total
+type Breakdown struct {
+	Discount *Discount `json:"discount,omitempty"`
+}
+
+// Discount is taken off the invoice total
+type Discount struct {
+	InvoiceDiscount InvoiceDiscount `json:"invoice_discount"`
+}
+
+// InvoiceDiscount is a fixed amount off the whole invoice
+type InvoiceDiscount struct {
+	Amount Money `json:"amount"`
+}
+
+// Created is the draft invoice PayPal returns
+type Created struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Amount Money  `json:"amount"`
+}
+
+// APIError is an error response from the PayPal API
+type APIError struct {
+	Status  int
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Details []struct {
+		Field       string `json:"field"`
+		Issue       string `json:"issue"`
+		Description string `json:"description"`
+	} `json:"details"`
+}
+
+func (e *APIError) Error() string {
+	text := fmt.Sprintf("PayPal returned %d %s: %s", e.Status, e.Name, e.Message)
+	for _, d := range e.Details {
+		text += fmt.Sprintf("; %s", d.Issue)
+		if d.Field != "" {
+			text += " at " + d.Field
+		}
+		if d.Description != "" {
+			text += ": " + d.Description
+		}
+	}
+	return text
+}
+
+// SecretPath returns the file the REST app's client secret is kept in. Like
+// the SMTP password it lives outside the database so exports and backups
+// never contain it.
+func SecretPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".hours", "paypal_secret"), nil
+}
+
+// SaveSecret stores the client secret readable only by the current user.
+// An empty secret removes the stored one.
+func SaveSecret(secret string) error {
+	path, err := SecretPath()
+	if err != nil {
+		return err
+	}
+	if secret == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove PayPal secret: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(secret), 0600); err != nil {
+		return fmt.Errorf("failed to save PayPal secret: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(path, 0600)
+}
+
+// LoadSecret returns the stored client secret, or "" if none is set
+func LoadSecret() (string, error) {
+	path, err := SecretPath()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read PayPal secret: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Client calls the Invoicing API with a REST app's credentials
+type Client struct {
+	BaseURL      string
+	ClientID     string
+	ClientSecret string
+	HTTP         *http.Client
+}
+
+// New returns a client for the live environment, or the sandbox one
+func New(clientID, clientSecret string, sandbox bool) *Client {
+	baseURL := LiveURL
+	if sandbox {
+		baseURL = SandboxURL
+	}
+	return &Client{
+		BaseURL:      baseURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		HTTP:         &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// token gets an OAuth access token with the client credentials
+func (c *Client) token(ctx context.Context) (string, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/v1/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(c.ClientID, c.ClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := c.do(req, &token); err != nil {
+		return "", fmt.Errorf("failed to sign in to PayPal: %w", err)
+	}
+	return token.AccessToken, nil
+}
+
+// CreateDraft creates a draft invoice, which the recipient doesn't see
+// until it is sent
+func (c *Client) CreateDraft(ctx context.Context, invoice *Invoice) (*Created, error) {
+	var created Created
+	if err := c.call(ctx, "/v2/invoicing/invoices", invoice, &created); err != nil {
+		return nil, fmt.Errorf("failed to create PayPal invoice: %w", err)
+	}
+	return &created, nil
+}
+
+// Send sends a draft invoice; PayPal emails it to its recipients
+func (c *Client) Send(ctx context.Context, id string) error {
+	body := map[string]bool{"send_to_invoicer": false}
+	if err := c.call(ctx, "/v2/invoicing/invoices/"+url.PathEscape(id)+"/send", body, nil); err != nil {
+		return fmt.Errorf("failed to send PayPal invoice %s: %w", id, err)
+	}
+	return nil
+}
+
+// call posts body as JSON to path with an access token and decodes the
+// response into out, unless it is nil
+func (c *Client) call(ctx context.Context, path string, body, out any) error {
+	token, err := c.token(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Prefer", "return=representation")
+	return c.do(req, out)
+}
+
+// do runs a request, returning an *APIError for an error status
+func (c *Client) do(req *http.Request, out any) error {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		apiErr := &APIError{Status: resp.StatusCode}
+		// OAuth errors use error and error_description instead
+		var oauth struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Name == "" {
+			if json.Unmarshal(data, &oauth) == nil && oauth.Error != "" {
+				apiErr.Name, apiErr.Message = oauth.Error, oauth.Description
+			} else {
+				apiErr.Name, apiErr.Message = http.StatusText(resp.StatusCode), strings.TrimSpace(string(data))
+			}
+		}
+		return apiErr
+	}
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
@@ -2,24 +2,70 @@ package pdf
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
 	"github.com/johnfercher/maroto/v2"
 	"github.com/johnfercher/maroto/v2/pkg/components/col"
+	"github.com/johnfercher/maroto/v2/pkg/components/image"
+	"github.com/johnfercher/maroto/v2/pkg/components/page"
 	"github.com/johnfercher/maroto/v2/pkg/components/text"
 	"github.com/johnfercher/maroto/v2/pkg/config"
 	"github.com/johnfercher/maroto/v2/pkg/consts/align"
 	"github.com/johnfercher/maroto/v2/pkg/consts/fontstyle"
+	"github.com/johnfercher/maroto/v2/pkg/core"
 	"github.com/johnfercher/maroto/v2/pkg/props"
+	"github.com/johnfercher/maroto/v2/pkg/repository"
 )
 
-type InvoiceGenerator struct{}
+// archivalFamily is the name the fonts embedded in PDF/A invoices are
+// registered under
+const archivalFamily = "archival"
+
+type InvoiceGenerator struct {
+	// archival holds the fonts to embed when writing PDF/A documents, nil
+	// for plain PDFs
+	archival *Fonts
+
+	// Consolidated lists the hours as one line per contract and rate rather
+	// than one per time entry
+	Consolidated bool
+}
+
+// Receipt is a scan or photo of a rebilled expense's receipt, appended to the
+// invoice after its last page
+type Receipt struct {
+	Path    string
+	Caption string
+}
+
+// CanAttach reports whether a receipt file can be appended to an invoice:
+// JPEG and PNG images get a page each and PDFs are added as they are
+func CanAttach(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg", ".png", ".pdf":
+		return true
+	}
+	return false
+}
 
 func NewInvoiceGenerator() *InvoiceGenerator {
 	return &InvoiceGenerator{}
 }
 
-func (g *InvoiceGenerator) Generate(invoice models.Invoice, payment models.PaymentDetails, recipients []models.Recipient, business models.BusinessInfo, outputPath string) error {
+// NewArchivalInvoiceGenerator returns a generator writing PDF/A-2b invoices
+// for long-term archival, with every font embedded and the document
+// metadata in XMP. PDF receipts can't be attached, as they may not conform.
+func NewArchivalInvoiceGenerator(fonts Fonts) *InvoiceGenerator {
+	return &InvoiceGenerator{archival: &fonts}
+}
+
+func (g *InvoiceGenerator) Generate(invoice models.Invoice, payment models.PaymentDetails, recipients []models.Recipient, business models.BusinessInfo, receipts []Receipt, outputPath string) error {
 	// For contract-based billing, we need to group entries by contract and calculate rates per contract
 	contractGroups := make(map[int][]models.TimeEntry)
 	contractInfo := make(map[int]models.Contract)
@@ -31,7 +77,25 @@ func (g *InvoiceGenerator) Generate(invoice models.Invoice, payment models.Payme
 			contractInfo[entry.ContractID] = *entry.Contract
 		}
 	}
-	m := maroto.New(config.NewBuilder().Build())
+	cfg := config.NewBuilder()
+	if g.archival != nil {
+		// PDF/A needs every font embedded, so the standard fonts can't be used
+		fonts, err := repository.New().
+			AddUTF8Font(archivalFamily, fontstyle.Normal, g.archival.Regular).
+			AddUTF8Font(archivalFamily, fontstyle.Bold, g.archival.Bold).
+			AddUTF8Font(archivalFamily, fontstyle.Italic, g.archival.Italic).
+			AddUTF8Font(archivalFamily, fontstyle.BoldItalic, g.archival.BoldItalic).
+			Load()
+		if err != nil {
+			return fmt.Errorf("failed to load fonts: %w", err)
+		}
+		cfg = cfg.WithCustomFonts(fonts).WithDefaultFont(&props.Font{
+			Family: archivalFamily,
+			Style:  fontstyle.Normal,
+			Size:   10,
+		})
+	}
+	m := maroto.New(cfg.Build())
 
 	// Business Header
 	m.AddRow(10,
@@ -204,6 +268,16 @@ func (g *InvoiceGenerator) Generate(invoice models.Invoice, payment models.Payme
 		)
 	}
 
+	if invoice.Client.TaxID != "" {
+		m.AddRow(5,
+			col.New(12).Add(
+				text.New(fmt.Sprintf("VAT ID: %s", invoice.Client.TaxID), props.Text{
+					Size: 9,
+				}),
+			),
+		)
+	}
+
 	if len(recipients) > 0 {
 		for _, r := range recipients {
 			m.AddRow(5,
@@ -231,9 +305,23 @@ func (g *InvoiceGenerator) Generate(invoice models.Invoice, payment models.Payme
 			),
 		)
 
+		// Entries carry the rate in effect on their date
+		var rates []string
+		seen := map[money.Cents]bool{}
+		for _, entry := range invoice.TimeEntries {
+			if entry.Contract != nil && !seen[entry.Contract.HourlyRate] {
+				seen[entry.Contract.HourlyRate] = true
+				rates = append(rates, entry.Contract.HourlyRate.Format(contract.Currency))
+			}
+		}
+		rateLabel := "Rate"
+		if len(rates) > 1 {
+			rateLabel = "Rates"
+		}
+
 		m.AddRow(5,
 			col.New(12).Add(
-				text.New(fmt.Sprintf("Rate: %s %.0f per hour", contract.Currency, contract.HourlyRate), props.Text{
+				text.New(fmt.Sprintf("%s: %s per hour", rateLabel, strings.Join(rates, ", ")), props.Text{
 					Size: 9,
 				}),
 			),
@@ -290,42 +378,98 @@ func (g *InvoiceGenerator) Generate(invoice models.Invoice, payment models.Payme
 		),
 	)
 
+	// Rebilled expenses get a section of their own after the hours
 	var totalHours float64
-	var totalAmount float64
+	var totalAmount, expenseAmount money.Cents
+	var timeItems, expenseItems []models.InvoiceItem
+	for _, item := range invoice.Items {
+		if item.ExpenseID != 0 {
+			expenseItems = append(expenseItems, item)
+			expenseAmount += item.Amount
+			continue
+		}
+		timeItems = append(timeItems, item)
+		totalHours += item.Hours
+		totalAmount += item.Amount
+	}
+	if g.Consolidated {
+		timeItems = consolidateItems(timeItems, contractInfo)
+	}
 
-	for _, entry := range invoice.TimeEntries {
-		if entry.Contract == nil {
-			continue // Skip entries without contract info
+	// Premium hours (weekend, holiday, overtime) are listed in their own
+	// groups after the hours billed at the normal rate
+	var groups []string
+	grouped := map[string][]models.InvoiceItem{}
+	for _, item := range timeItems {
+		if _, ok := grouped[item.RateLabel]; !ok {
+			groups = append(groups, item.RateLabel)
+		}
+		grouped[item.RateLabel] = append(grouped[item.RateLabel], item)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i] == "" && groups[j] != ""
+	})
+
+	for _, group := range groups {
+		items := grouped[group]
+		if len(groups) > 1 {
+			label := group
+			if label == "" {
+				label = "Standard rate"
+			}
+			m.AddRow(7,
+				col.New(12).Add(
+					text.New(label, props.Text{
+						Size:  9,
+						Style: fontstyle.Bold,
+						Top:   2,
+					}),
+				),
+			)
 		}
 
-		amount := entry.Hours * entry.Contract.HourlyRate
-		totalHours += entry.Hours
-		totalAmount += amount
+		var groupAmount money.Cents
+		for _, item := range items {
+			groupAmount += item.Amount
+			// Flat fees such as a retainer have no hours
+			hours := ""
+			if item.Hours > 0 {
+				hours = fmt.Sprintf("%.2f", item.Hours)
+			}
+			// Goodwill hours are shown at no cost, marked N/C
+			amount := item.Amount.Format(invoice.Currency)
+			if item.RateKind == "no_charge" {
+				amount = "N/C " + amount
+			}
+			m.AddRow(6,
+				col.New(2).Add(
+					text.New(item.Date.Format("2006-01-02"), props.Text{
+						Size: 8,
+					}),
+				),
+				col.New(6).Add(
+					text.New(item.Description, props.Text{
+						Size: 8,
+					}),
+				),
+				col.New(1).Add(
+					text.New(hours, props.Text{
+						Size:  8,
+						Align: align.Right,
+					}),
+				),
+				col.New(3).Add(
+					text.New(amount, props.Text{
+						Size:  8,
+						Align: align.Right,
+					}),
+				),
+			)
+		}
 
-		m.AddRow(6,
-			col.New(2).Add(
-				text.New(entry.Date.Format("2006-01-02"), props.Text{
-					Size: 8,
-				}),
-			),
-			col.New(6).Add(
-				text.New(entry.Description, props.Text{
-					Size: 8,
-				}),
-			),
-			col.New(1).Add(
-				text.New(fmt.Sprintf("%.2f", entry.Hours), props.Text{
-					Size:  8,
-					Align: align.Right,
-				}),
-			),
-			col.New(3).Add(
-				text.New(fmt.Sprintf("%s %.2f", entry.Contract.Currency, amount), props.Text{
-					Size:  8,
-					Align: align.Right,
-				}),
-			),
-		)
+		if len(groups) > 1 {
+			addTotalRow(m, "Subtotal:", groupAmount.Format(invoice.Currency), false)
+		}
 	}
 
 	m.AddRow(8)
@@ -346,7 +490,7 @@ func (g *InvoiceGenerator) Generate(invoice models.Invoice, payment models.Payme
 			}),
 		),
 		col.New(3).Add(
-			text.New(fmt.Sprintf("%.2f", totalAmount), props.Text{
+			text.New(totalAmount.Format(invoice.Currency), props.Text{
 				Size:  10,
 				Style: fontstyle.Bold,
 				Align: align.Right,
@@ -354,7 +498,98 @@ func (g *InvoiceGenerator) Generate(invoice models.Invoice, payment models.Payme
 		),
 	)
 
-	if payment.BankName != "" || payment.PaymentTerms != "" {
+	if len(expenseItems) > 0 {
+		m.AddRow(8)
+		m.AddRow(8,
+			col.New(12).Add(
+				text.New("Expenses", props.Text{
+					Size:  12,
+					Style: fontstyle.Bold,
+				}),
+			),
+		)
+
+		m.AddRow(8,
+			col.New(2).Add(
+				text.New("Date", props.Text{
+					Size:  9,
+					Style: fontstyle.Bold,
+				}),
+			),
+			col.New(7).Add(
+				text.New("Description", props.Text{
+					Size:  9,
+					Style: fontstyle.Bold,
+				}),
+			),
+			col.New(3).Add(
+				text.New("Amount", props.Text{
+					Size:  9,
+					Style: fontstyle.Bold,
+					Align: align.Right,
+				}),
+			),
+		)
+
+		for _, item := range expenseItems {
+			m.AddRow(6,
+				col.New(2).Add(
+					text.New(item.Date.Format("2006-01-02"), props.Text{
+						Size: 8,
+					}),
+				),
+				col.New(7).Add(
+					text.New(item.Description, props.Text{
+						Size: 8,
+					}),
+				),
+				col.New(3).Add(
+					text.New(item.Amount.Format(invoice.Currency), props.Text{
+						Size:  8,
+						Align: align.Right,
+					}),
+				),
+			)
+		}
+
+		addTotalRow(m, "Expenses:", expenseAmount.Format(invoice.Currency), false)
+		label := "Total:"
+		if invoice.TaxAmount > 0 || invoice.WithholdingAmount > 0 || invoice.Rounding != 0 {
+			label = "Subtotal:"
+		}
+		addTotalRow(m, label, (totalAmount + expenseAmount).Format(invoice.Currency), true)
+	}
+
+	if invoice.TaxAmount > 0 {
+		addTotalRow(m, fmt.Sprintf("Tax (%g%%):", invoice.TaxRate),
+			invoice.TaxAmount.Format(invoice.Currency), false)
+	}
+	if invoice.WithholdingAmount > 0 {
+		addTotalRow(m, fmt.Sprintf("Withholding (%g%%):", invoice.WithholdingRate),
+			"-"+invoice.WithholdingAmount.Format(invoice.Currency), false)
+	}
+	if invoice.Rounding != 0 {
+		addTotalRow(m, "Rounding:", invoice.Rounding.Format(invoice.Currency), false)
+	}
+	if invoice.TaxAmount > 0 || invoice.WithholdingAmount > 0 || invoice.Rounding != 0 {
+		addTotalRow(m, "Total Due:",
+			(invoice.TotalAmount - invoice.WithholdingAmount).Format(invoice.Currency), true)
+	}
+
+	if invoice.TaxNote != "" {
+		m.AddRow(4)
+		m.AddRow(8,
+			col.New(12).Add(
+				text.New(invoice.TaxNote, props.Text{
+					Size:  8,
+					Style: fontstyle.Italic,
+				}),
+			),
+		)
+	}
+
+	if payment.BankName != "" || payment.AccountNumber != "" || payment.PaymentTerms != "" {
+		bankLabel, accountLabel := paymentLabels(payment.Kind)
 		m.AddRow(10)
 		m.AddRow(8,
 			col.New(12).Add(
@@ -378,7 +613,7 @@ func (g *InvoiceGenerator) Generate(invoice models.Invoice, payment models.Payme
 		if payment.BankName != "" {
 			m.AddRow(5,
 				col.New(12).Add(
-					text.New(fmt.Sprintf("Bank: %s", payment.BankName), props.Text{
+					text.New(fmt.Sprintf("%s: %s", bankLabel, payment.BankName), props.Text{
 						Size: 9,
 					}),
 				),
@@ -388,7 +623,7 @@ func (g *InvoiceGenerator) Generate(invoice models.Invoice, payment models.Payme
 		if payment.AccountNumber != "" {
 			m.AddRow(5,
 				col.New(12).Add(
-					text.New(fmt.Sprintf("Account: %s", payment.AccountNumber), props.Text{
+					text.New(fmt.Sprintf("%s: %s", accountLabel, payment.AccountNumber), props.Text{
 						Size: 9,
 					}),
 				),
@@ -426,14 +661,162 @@ func (g *InvoiceGenerator) Generate(invoice models.Invoice, payment models.Payme
 		}
 	}
 
+	// Image receipts get a page each; PDF receipts are merged in after them
+	var pdfReceipts [][]byte
+	for _, r := range receipts {
+		if strings.ToLower(filepath.Ext(r.Path)) == ".pdf" {
+			data, err := os.ReadFile(r.Path)
+			if err != nil {
+				return fmt.Errorf("failed to read receipt %s: %w", r.Path, err)
+			}
+			pdfReceipts = append(pdfReceipts, data)
+			continue
+		}
+		m.AddPages(page.New().Add(
+			text.NewRow(10, "Receipt: "+r.Caption, props.Text{
+				Size:  10,
+				Style: fontstyle.Bold,
+			}),
+			image.NewFromFileRow(250, r.Path, props.Rect{
+				Center:  true,
+				Percent: 100,
+			}),
+		))
+	}
+
+	if g.archival != nil && len(pdfReceipts) > 0 {
+		return fmt.Errorf("PDF receipts can't be attached to a PDF/A invoice")
+	}
+
 	document, err := m.Generate()
 	if err != nil {
 		return fmt.Errorf("failed to generate PDF document: %w", err)
 	}
 
+	if g.archival != nil {
+		author := business.BusinessName
+		if author == "" {
+			author = business.ContactName
+		}
+		data, err := toPDFA(document.GetBytes(), DocumentInfo{
+			Title:    "Invoice " + invoice.InvoiceNumber,
+			Author:   author,
+			Subject:  fmt.Sprintf("Invoice %s to %s", invoice.InvoiceNumber, invoice.Client.Name),
+			Creator:  "hours-mcp",
+			Producer: "hours-mcp",
+			Created:  time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to convert to PDF/A: %w", err)
+		}
+		if err := os.WriteFile(outputPath, data, 0644); err != nil {
+			return fmt.Errorf("failed to save PDF: %w", err)
+		}
+		return nil
+	}
+
+	for _, data := range pdfReceipts {
+		if err := document.Merge(data); err != nil {
+			return fmt.Errorf("failed to attach receipt: %w", err)
+		}
+	}
+
 	if err := document.Save(outputPath); err != nil {
 		return fmt.Errorf("failed to save PDF: %w", err)
 	}
 
 	return nil
 }
+
+// consolidateItems sums time entries billed under the same contract at the
+// same rate into one item, dated from the first entry and described by the
+// contract and the days worked. Flat fees such as a retainer are kept as
+// they are.
+func consolidateItems(items []models.InvoiceItem, contracts map[int]models.Contract) []models.InvoiceItem {
+	type key struct {
+		contractID int
+		rateKind   string
+		rateLabel  string
+		rate       money.Cents
+	}
+	var consolidated []models.InvoiceItem
+	index := map[key]int{}
+	last := map[key]time.Time{}
+	for _, item := range items {
+		if item.TimeEntryID == "" {
+			consolidated = append(consolidated, item)
+			continue
+		}
+		k := key{item.ContractID, item.RateKind, item.RateLabel, item.Rate}
+		i, ok := index[k]
+		if !ok {
+			index[k] = len(consolidated)
+			last[k] = item.Date
+			item.TimeEntryID = ""
+			consolidated = append(consolidated, item)
+			continue
+		}
+		line := &consolidated[i]
+		line.Hours += item.Hours
+		line.Amount += item.Amount
+		if item.Date.Before(line.Date) {
+			line.Date = item.Date
+		}
+		if item.Date.After(last[k]) {
+			last[k] = item.Date
+		}
+	}
+
+	for k, i := range index {
+		line := &consolidated[i]
+		description := "Hours"
+		if c, ok := contracts[k.contractID]; ok && c.Name != "" {
+			description = c.Name
+		}
+		if last[k].After(line.Date) {
+			description += fmt.Sprintf(", %s to %s", line.Date.Format("2006-01-02"), last[k].Format("2006-01-02"))
+		}
+		line.Description = description
+	}
+	return consolidated
+}
+
+// addTotalRow adds a label and amount under the line items
+func addTotalRow(m core.Maroto, label, amount string, bold bool) {
+	style := fontstyle.Normal
+	height := 6.0
+	if bold {
+		style = fontstyle.Bold
+		height = 8
+	}
+	m.AddRow(height,
+		col.New(6),
+		col.New(3).Add(
+			text.New(label, props.Text{
+				Size:  9,
+				Style: style,
+			}),
+		),
+		col.New(3).Add(
+			text.New(amount, props.Text{
+				Size:  9,
+				Style: style,
+				Align: align.Right,
+			}),
+		),
+	)
+}
+
+// paymentLabels returns what the bank name and account number of a kind of
+// payment method are called on the invoice
+func paymentLabels(kind string) (bank, account string) {
+	switch kind {
+	case "iban":
+		return "Bank", "IBAN"
+	case "paypal":
+		return "Provider", "PayPal"
+	case "crypto":
+		return "Network", "Wallet"
+	}
+	return "Bank", "Account"
+}
@@ -0,0 +1,406 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Fonts are the TrueType files of the regular, bold, italic and bold italic
+// styles of the font family embedded in PDF/A invoices
+type Fonts struct {
+	Regular    string
+	Bold       string
+	Italic     string
+	BoldItalic string
+}
+
+// systemFonts are font families commonly installed on Linux, macOS and
+// Windows, tried in order when no font is set
+var systemFonts = []Fonts{
+	{
+		Regular:    "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+		Bold:       "/usr/share/fonts/truetype/dejavu/DejaVuSans-Bold.ttf",
+		Italic:     "/usr/share/fonts/truetype/dejavu/DejaVuSans-Oblique.ttf",
+		BoldItalic: "/usr/share/fonts/truetype/dejavu/DejaVuSans-BoldOblique.ttf",
+	},
+	{
+		Regular:    "/usr/share/fonts/dejavu/DejaVuSans.ttf",
+		Bold:       "/usr/share/fonts/dejavu/DejaVuSans-Bold.ttf",
+		Italic:     "/usr/share/fonts/dejavu/DejaVuSans-Oblique.ttf",
+		BoldItalic: "/usr/share/fonts/dejavu/DejaVuSans-BoldOblique.ttf",
+	},
+	{
+		Regular:    "/usr/share/fonts/truetype/liberation/LiberationSans-Regular.ttf",
+		Bold:       "/usr/share/fonts/truetype/liberation/LiberationSans-Bold.ttf",
+		Italic:     "/usr/share/fonts/truetype/liberation/LiberationSans-Italic.ttf",
+		BoldItalic: "/usr/share/fonts/truetype/liberation/LiberationSans-BoldItalic.ttf",
+	},
+	{
+		Regular:    "/System/Library/Fonts/Supplemental/Arial.ttf",
+		Bold:       "/System/Library/Fonts/Supplemental/Arial Bold.ttf",
+		Italic:     "/System/Library/Fonts/Supplemental/Arial Italic.ttf",
+		BoldItalic: "/System/Library/Fonts/Supplemental/Arial Bold Italic.ttf",
+	},
+	{
+		Regular:    `C:\Windows\Fonts\arial.ttf`,
+		Bold:       `C:\Windows\Fonts\arialbd.ttf`,
+		Italic:     `C:\Windows\Fonts\ariali.ttf`,
+		BoldItalic: `C:\Windows\Fonts\arialbi.ttf`,
+	},
+}
+
+// FindFonts returns the font family to embed in PDF/A invoices: the one
+// whose regular style is the TrueType file at path, or with path empty the
+// first of the common system fonts installed. Styles without a file of their
+// own are printed in the regular one.
+func FindFonts(path string) (Fonts, error) {
+	if path == "" {
+		for _, fonts := range systemFonts {
+			if fileExists(fonts.Regular) {
+				return fonts.withFallbacks(), nil
+			}
+		}
+		return Fonts{}, fmt.Errorf("no TrueType font found in the usual system locations")
+	}
+
+	if !strings.EqualFold(filepath.Ext(path), ".ttf") {
+		return Fonts{}, fmt.Errorf("%s is not a TrueType (.ttf) font", path)
+	}
+	if !fileExists(path) {
+		return Fonts{}, fmt.Errorf("font %s not found", path)
+	}
+
+	// Styles are looked up next to the regular file by the usual suffixes,
+	// e.g. DejaVuSans-Bold.ttf or "Arial Bold Italic.ttf"
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for _, suffix := range []string{"-Regular", " Regular", "_Regular", "Regular"} {
+		base = strings.TrimSuffix(base, suffix)
+	}
+	find := func(styles ...string) string {
+		for _, style := range styles {
+			for _, sep := range []string{"-", " ", "_", ""} {
+				candidate := base + sep + style + ext
+				if fileExists(candidate) {
+					return candidate
+				}
+			}
+		}
+		return ""
+	}
+	fonts := Fonts{
+		Regular:    path,
+		Bold:       find("Bold"),
+		Italic:     find("Italic", "Oblique"),
+		BoldItalic: find("BoldItalic", "BoldOblique", "Bold Italic", "Bold Oblique"),
+	}
+	return fonts.withFallbacks(), nil
+}
+
+// withFallbacks fills in the styles whose files are missing with the
+// closest one installed
+func (f Fonts) withFallbacks() Fonts {
+	if !fileExists(f.Bold) {
+		f.Bold = f.Regular
+	}
+	if !fileExists(f.Italic) {
+		f.Italic = f.Regular
+	}
+	if !fileExists(f.BoldItalic) {
+		f.BoldItalic = f.Bold
+	}
+	return f
+}
+
+func fileExists(path string) bool {
+	if path == "" {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// DocumentInfo is the metadata written to both the Info dictionary and the
+// XMP metadata of a PDF/A document, which must agree
+type DocumentInfo struct {
+	Title    string
+	Author   string
+	Subject  string
+	Creator  string
+	Producer string
+	Created  time.Time
+}
+
+var trailerEntry = regexp.MustCompile(`/(Size|Root|Info) (\d+)`)
+
+// toPDFA turns a PDF written by gofpdf into a PDF/A-2b document. Fonts must
+// already be embedded; this adds what gofpdf leaves out: a binary header
+// comment, XMP metadata matching the Info dictionary, an sRGB output intent
+// and a file identifier.
+//
+// gofpdf writes a classic cross-reference table with the Info and Catalog
+// dictionaries as its last two objects, so those are rewritten and the new
+// objects appended after them.
+func toPDFA(data []byte, info DocumentInfo) ([]byte, error) {
+	xrefAt := bytes.LastIndex(data, []byte("\nxref\n"))
+	trailerAt := bytes.LastIndex(data, []byte("\ntrailer\n"))
+	headerEnd := bytes.IndexByte(data, '\n') + 1
+	if xrefAt < 0 || trailerAt < xrefAt || headerEnd == 0 || !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return nil, fmt.Errorf("unexpected PDF layout")
+	}
+
+	entries := map[string]int{}
+	for _, m := range trailerEntry.FindAllSubmatch(data[trailerAt:], -1) {
+		entries[string(m[1])], _ = strconv.Atoi(string(m[2]))
+	}
+	n := entries["Size"] - 1
+	if n < 3 || entries["Root"] != n || entries["Info"] != n-1 {
+		return nil, fmt.Errorf("unexpected PDF trailer")
+	}
+
+	// The table starts with its subsection header and the free entry
+	lines := strings.Split(strings.TrimSpace(string(data[xrefAt+len("\nxref\n"):trailerAt])), "\n")
+	if len(lines) != n+2 {
+		return nil, fmt.Errorf("unexpected PDF cross-reference table")
+	}
+	offsets := make([]int, n+4)
+	for i := 1; i <= n; i++ {
+		offset, err := strconv.Atoi(strings.Fields(lines[i+1])[0])
+		if err != nil || offset < headerEnd || offset > xrefAt {
+			return nil, fmt.Errorf("unexpected PDF cross-reference table")
+		}
+		offsets[i] = offset
+	}
+
+	catalog := data[offsets[n] : xrefAt+1]
+	start, end := bytes.Index(catalog, []byte("<<")), bytes.LastIndex(catalog, []byte(">>"))
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("unexpected PDF catalog")
+	}
+	catalog = catalog[start+2 : end]
+
+	var out bytes.Buffer
+	out.Write(data[:headerEnd])
+	// A comment of bytes above 127 marks the file as binary
+	out.WriteString("%\xe2\xe3\xcf\xd3\n")
+	shift := out.Len() - headerEnd
+	out.Write(data[headerEnd:offsets[n-1]])
+	for i := 1; i < n-1; i++ {
+		offsets[i] += shift
+	}
+
+	object := func(num int, dict string, stream []byte) {
+		offsets[num] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\n", num, dict)
+		if stream != nil {
+			out.WriteString("stream\n")
+			out.Write(stream)
+			out.WriteString("\nendstream\n")
+		}
+		out.WriteString("endobj\n")
+	}
+
+	var dict strings.Builder
+	dict.WriteString("<<\n")
+	for _, entry := range []struct{ key, value string }{
+		{"Title", info.Title},
+		{"Author", info.Author},
+		{"Subject", info.Subject},
+		{"Creator", info.Creator},
+		{"Producer", info.Producer},
+	} {
+		if entry.value != "" {
+			fmt.Fprintf(&dict, "/%s %s\n", entry.key, pdfTextString(entry.value))
+		}
+	}
+	fmt.Fprintf(&dict, "/CreationDate (%s)\n/ModDate (%s)\n>>", pdfDate(info.Created), pdfDate(info.Created))
+	object(n-1, dict.String(), nil)
+
+	object(n, fmt.Sprintf("<<%s/Metadata %d 0 R\n/OutputIntents [%d 0 R]\n>>", catalog, n+1, n+3), nil)
+
+	xmp := xmpMetadata(info)
+	object(n+1, fmt.Sprintf("<< /Type /Metadata /Subtype /XML /Length %d >>", len(xmp)), xmp)
+
+	icc := srgbProfile()
+	object(n+2, fmt.Sprintf("<< /N 3 /Length %d >>", len(icc)), icc)
+
+	object(n+3, fmt.Sprintf("<< /Type /OutputIntent /S /GTS_PDFA1 /OutputConditionIdentifier (sRGB IEC61966-2.1) /RegistryName (http://www.color.org) /Info (sRGB IEC61966-2.1) /DestOutputProfile %d 0 R >>", n+2), nil)
+
+	id := md5.Sum(out.Bytes())
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", n+4)
+	for i := 1; i <= n+3; i++ {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offsets[i])
+	}
+	fmt.Fprintf(&out, "trailer\n<<\n/Size %d\n/Root %d 0 R\n/Info %d 0 R\n/ID [<%x> <%x>]\n>>\nstartxref\n%d\n%%%%EOF\n",
+		n+4, n, n-1, id, id, xref)
+
+	return out.Bytes(), nil
+}
+
+// pdfTextString encodes text as a UTF-16 hex string, which needs no escaping
+func pdfTextString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// pdfDate formats a time as a PDF date with its UTC offset, e.g.
+// D:20250131143000+01'00'
+func pdfDate(t time.Time) string {
+	s := t.Format("20060102150405-0700")
+	return "D:" + s[:17] + "'" + s[17:] + "'"
+}
+
+// xmpMetadata returns the XMP packet declaring PDF/A-2b conformance with the
+// same document information as the Info dictionary
+func xmpMetadata(info DocumentInfo) []byte {
+	escape := func(s string) string {
+		var b bytes.Buffer
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	date := info.Created.Format("2006-01-02T15:04:05-07:00")
+
+	var b strings.Builder
+	b.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString("<rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	b.WriteString("<rdf:Description rdf:about=\"\"" +
+		" xmlns:pdfaid=\"http://www.aiim.org/pdfa/ns/id/\"" +
+		" xmlns:dc=\"http://purl.org/dc/elements/1.1/\"" +
+		" xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\"" +
+		" xmlns:pdf=\"http://ns.adobe.com/pdf/1.3/\">\n")
+	b.WriteString("<pdfaid:part>2</pdfaid:part>\n<pdfaid:conformance>B</pdfaid:conformance>\n")
+	b.WriteString("<dc:format>application/pdf</dc:format>\n")
+	if info.Title != "" {
+		fmt.Fprintf(&b, "<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n", escape(info.Title))
+	}
+	if info.Author != "" {
+		fmt.Fprintf(&b, "<dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n", escape(info.Author))
+	}
+	if info.Subject != "" {
+		fmt.Fprintf(&b, "<dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:description>\n", escape(info.Subject))
+	}
+	if info.Creator != "" {
+		fmt.Fprintf(&b, "<xmp:CreatorTool>%s</xmp:CreatorTool>\n", escape(info.Creator))
+	}
+	fmt.Fprintf(&b, "<xmp:CreateDate>%s</xmp:CreateDate>\n<xmp:ModifyDate>%s</xmp:ModifyDate>\n<xmp:MetadataDate>%s</xmp:MetadataDate>\n", date, date, date)
+	if info.Producer != "" {
+		fmt.Fprintf(&b, "<pdf:Producer>%s</pdf:Producer>\n", escape(info.Producer))
+	}
+	b.WriteString("</rdf:Description>\n</rdf:RDF>\n</x:xmpmeta>\n")
+	b.WriteString("<?xpacket end=\"w\"?>")
+	return []byte(b.String())
+}
+
+// srgbProfile builds a version 2 ICC display profile for sRGB, the colour
+// space invoices are drawn in, for the PDF/A output intent
+func srgbProfile() []byte {
+	fixed := func(v float64) uint32 {
+		return uint32(int32(math.Round(v * 65536)))
+	}
+	xyz := func(x, y, z float64) []byte {
+		b := []byte("XYZ \x00\x00\x00\x00")
+		for _, v := range []float64{x, y, z} {
+			b = binary.BigEndian.AppendUint32(b, fixed(v))
+		}
+		return b
+	}
+
+	desc := []byte("desc\x00\x00\x00\x00")
+	name := "sRGB IEC61966-2.1\x00"
+	desc = binary.BigEndian.AppendUint32(desc, uint32(len(name)))
+	desc = append(desc, name...)
+	// Empty Unicode and ScriptCode descriptions
+	desc = append(desc, make([]byte, 4+4+2+1+67)...)
+
+	cprt := append([]byte("text\x00\x00\x00\x00"), "No copyright, use freely\x00"...)
+
+	// The sRGB transfer function, sampled
+	const points = 1024
+	curve := []byte("curv\x00\x00\x00\x00")
+	curve = binary.BigEndian.AppendUint32(curve, points)
+	for i := 0; i < points; i++ {
+		v := float64(i) / (points - 1)
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		curve = binary.BigEndian.AppendUint16(curve, uint16(math.Round(v*65535)))
+	}
+
+	// Primaries adapted to the D50 white of the profile connection space
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", desc},
+		{"cprt", cprt},
+		{"wtpt", xyz(0.9642, 1, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", curve},
+		{"gTRC", nil},
+		{"bTRC", nil},
+	}
+
+	table := make([]byte, 4, 4+12*len(tags))
+	binary.BigEndian.PutUint32(table, uint32(len(tags)))
+	var data []byte
+	offset := 128 + 4 + 12*len(tags)
+	var curveOffset, curveSize int
+	for _, tag := range tags {
+		table = append(table, tag.sig...)
+		if tag.data == nil {
+			// The green and blue curves share the red one
+			table = binary.BigEndian.AppendUint32(table, uint32(curveOffset))
+			table = binary.BigEndian.AppendUint32(table, uint32(curveSize))
+			continue
+		}
+		at := offset + len(data)
+		table = binary.BigEndian.AppendUint32(table, uint32(at))
+		table = binary.BigEndian.AppendUint32(table, uint32(len(tag.data)))
+		if tag.sig == "rTRC" {
+			curveOffset, curveSize = at, len(tag.data)
+		}
+		data = append(data, tag.data...)
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+	}
+
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[0:], uint32(128+len(table)+len(data)))
+	binary.BigEndian.PutUint32(header[8:], 0x02100000)
+	copy(header[12:], "mntr")
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	for i, v := range []uint16{2025, 1, 1} {
+		binary.BigEndian.PutUint16(header[24+2*i:], v)
+	}
+	copy(header[36:], "acsp")
+	binary.BigEndian.PutUint32(header[68:], fixed(0.9642))
+	binary.BigEndian.PutUint32(header[72:], fixed(1))
+	binary.BigEndian.PutUint32(header[76:], fixed(0.8249))
+
+	profile := append(header, table...)
+	return append(profile, data...)
+}
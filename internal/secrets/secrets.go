@@ -0,0 +1,239 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// KeyEnv is the environment variable the encryption key is read from. It
+// takes precedence over the OS keychain and the key file.
+const KeyEnv = "HOURS_ENCRYPTION_KEY"
+
+// DatabaseKeyEnv is the environment variable the key for encrypting the
+// whole database file is read from. It takes precedence over the keychain.
+const DatabaseKeyEnv = "HOURS_DB_KEY"
+
+// HTTPTokenEnv is the environment variable the bearer token HTTP clients
+// must send is read from. It takes precedence over the keychain.
+const HTTPTokenEnv = "HOURS_HTTP_TOKEN"
+
+// Keychain entries keys are looked up under: the service on both macOS and
+// Linux, the account on macOS and the "key" attribute for secret-tool
+const (
+	keychainService         = "hours-mcp"
+	keychainAccount         = "encryption-key"
+	keychainDatabaseAccount = "database-key"
+	keychainHTTPAccount     = "http-token"
+)
+
+// prefix marks encrypted values so plaintext stored before encryption was
+// added still reads back as is
+const prefix = "enc:v1:"
+
+var (
+	keyMu     sync.Mutex
+	cachedKey []byte
+)
+
+// KeyPath returns the file a generated key is kept in when none is set in
+// the environment or keychain. Like the SMTP password it lives outside the
+// database, so backups and exports never contain it.
+func KeyPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".hours", "encryption_key"), nil
+}
+
+// KeySource describes where the encryption key is read from
+func KeySource() string {
+	if os.Getenv(KeyEnv) != "" {
+		return "the " + KeyEnv + " environment variable"
+	}
+	if keychainKey(keychainAccount) != "" {
+		return "the OS keychain"
+	}
+	path, err := KeyPath()
+	if err != nil {
+		return "a key file"
+	}
+	return path
+}
+
+// key returns the 256-bit encryption key, generating and saving one to the
+// key file the first time if no other source has one
+func key() ([]byte, error) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	if cachedKey != nil {
+		return cachedKey, nil
+	}
+
+	encoded := os.Getenv(KeyEnv)
+	if encoded == "" {
+		encoded = keychainKey(keychainAccount)
+	}
+	if encoded == "" {
+		var err error
+		if encoded, err = fileKey(); err != nil {
+			return nil, err
+		}
+	}
+	cachedKey = parseKey(encoded)
+	return cachedKey, nil
+}
+
+// parseKey decodes a base64 key of 32 bytes, and otherwise treats the value
+// as a passphrase and derives the key from it
+func parseKey(encoded string) []byte {
+	encoded = strings.TrimSpace(encoded)
+	if raw, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(raw) == 32 {
+		return raw
+	}
+	sum := sha256.Sum256([]byte(encoded))
+	return sum[:]
+}
+
+// DatabaseKey returns the key the database file is encrypted with as 64 hex
+// digits, or "" when none is set. Unlike the field encryption key it is
+// never generated: encrypting the whole database is opt-in.
+func DatabaseKey() string {
+	encoded := os.Getenv(DatabaseKeyEnv)
+	if encoded == "" {
+		encoded = keychainKey(keychainDatabaseAccount)
+	}
+	if encoded == "" {
+		return ""
+	}
+	return hex.EncodeToString(parseKey(encoded))
+}
+
+// HTTPToken returns the bearer token clients of the HTTP transport must
+// send, or "" when none is set. Like the database key it is never
+// generated.
+func HTTPToken() string {
+	if token := strings.TrimSpace(os.Getenv(HTTPTokenEnv)); token != "" {
+		return token
+	}
+	return keychainKey(keychainHTTPAccount)
+}
+
+// keychainKey looks a key up in the macOS keychain or the Secret Service
+// on Linux, returning "" when there is no keychain or no key in it
+func keychainKey(account string) string {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keychainService, "-a", account, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", keychainService, "key", account)
+	default:
+		return ""
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// fileKey reads the key file, creating it with a new random key readable
+// only by the current user if it doesn't exist
+func fileKey() (string, error) {
+	path, err := KeyPath()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return string(data), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read encryption key: %w", err)
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate encryption key: %w", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(raw)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(encoded+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to save encryption key: %w", err)
+	}
+	return encoded, nil
+}
+
+// IsEncrypted reports whether a stored value was encrypted by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt seals a value with AES-256-GCM. Empty and already encrypted values
+// are returned unchanged.
+func Encrypt(value string) (string, error) {
+	if value == "" || IsEncrypted(value) {
+		return value, nil
+	}
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value sealed by Encrypt. Values without the encrypted
+// prefix are plaintext and returned unchanged.
+func Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, prefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode encrypted value: %w", err)
+	}
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted value is truncated")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value; was it encrypted with a different key? Set %s to the original key", KeyEnv)
+	}
+	return string(plain), nil
+}
+
+func newGCM() (cipher.AEAD, error) {
+	k, err := key()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,307 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Expense kinds. Mileage and per-diem expenses are priced from the allowance
+// rate configured for the year they fall in.
+const (
+	expenseKindExpense = "expense"
+	expenseKindMileage = "mileage"
+	expenseKindPerDiem = "per_diem"
+)
+
+var allowanceLabels = map[string]string{
+	expenseKindMileage: "Mileage",
+	expenseKindPerDiem: "Per diem",
+}
+
+func validateAllowanceKind(kind string) error {
+	if _, ok := allowanceLabels[kind]; !ok {
+		return validationError("invalid allowance kind '%s': must be mileage or per_diem", kind)
+	}
+	return nil
+}
+
+// allowanceUnit is what an allowance's quantity counts
+func (h *Handler) allowanceUnit(ctx context.Context, kind string) string {
+	if kind == expenseKindMileage {
+		return h.getSetting(ctx, "distance_unit")
+	}
+	return "days"
+}
+
+// allowanceRate returns the rate and currency of an allowance in the year of
+// the given date
+func (h *Handler) allowanceRate(ctx context.Context, kind string, on time.Time) (money.Cents, string, error) {
+	var rate money.Cents
+	var currency string
+	err := h.db.QueryRowContext(ctx, "SELECT rate_cents, currency FROM allowance_rates WHERE year = ? AND kind = ?", on.Year(), kind).
+		Scan(&rate, &currency)
+	if err == sql.ErrNoRows {
+		return 0, "", notConfiguredError("allowance_rate", "no %s rate set for %d; use set_allowance_rate first", strings.ToLower(allowanceLabels[kind]), on.Year())
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to load allowance rate: %w", err)
+	}
+	return rate, currency, nil
+}
+
+// addAllowance records a mileage or per-diem expense against a contract
+func (h *Handler) addAllowance(ctx context.Context, contractNumber, kind string, quantity float64, date time.Time, description string, billable bool) (models.Expense, error) {
+	e := models.Expense{
+		Kind:        kind,
+		Date:        date,
+		Quantity:    quantity,
+		Unit:        h.allowanceUnit(ctx, kind),
+		Category:    allowanceLabels[kind],
+		Description: description,
+		Billable:    billable,
+	}
+	if quantity <= 0 {
+		return e, validationError("quantity must be positive")
+	}
+
+	var clientID int
+	err := h.db.QueryRowContext(ctx, "SELECT id, client_id FROM contracts WHERE contract_number = ?", contractNumber).Scan(&e.ContractID, &clientID)
+	if err == sql.ErrNoRows {
+		return e, notFoundError("contract", "contract %s not found", contractNumber)
+	}
+	if err != nil {
+		return e, fmt.Errorf("failed to find contract: %w", err)
+	}
+	if err := h.checkClientActive(ctx, clientID); err != nil {
+		return e, err
+	}
+
+	if e.UnitRate, e.Currency, err = h.allowanceRate(ctx, kind, date); err != nil {
+		return e, err
+	}
+	e.Amount = e.UnitRate.Times(quantity)
+
+	result, err := h.db.ExecContext(ctx, `
+		INSERT INTO expenses (contract_id, kind, date, amount_cents, currency, quantity, unit, unit_rate_cents,
+		                      category, description, billable)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, e.ContractID, kind, date.Format("2006-01-02"), e.Amount, e.Currency, quantity, e.Unit, e.UnitRate,
+		e.Category, description, billable)
+	if err != nil {
+		return e, fmt.Errorf("failed to add %s: %w", strings.ToLower(e.Category), err)
+	}
+	id, _ := result.LastInsertId()
+	added, err := h.loadExpense(ctx, int(id))
+	if err != nil {
+		return e, err
+	}
+	return *added, nil
+}
+
+// allowanceResult reports an added mileage or per-diem expense
+func allowanceResult(e models.Expense, contractNumber string) (*mcp.CallToolResult, *models.Expense, error) {
+	text := fmt.Sprintf("Added %s expense %d for %s on %s: %s", strings.ToLower(e.Category), e.ID, contractNumber,
+		e.Date.Format("2006-01-02"), expenseDescription(e))
+	text += fmt.Sprintf(" = %s", e.Amount.Format(e.Currency))
+	if !e.Billable {
+		text += " (not billable)"
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+	}, &e, nil
+}
+
+// registerAllowanceTools registers mileage and per-diem tools
+func registerAllowanceTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Set Allowance Rate tool
+	type setAllowanceRateArgs struct {
+		Kind     string  `json:"kind" jsonschema:"mileage or per_diem"`
+		Year     int     `json:"year,omitempty" jsonschema:"Year the rate applies to (default: this year)"`
+		Rate     float64 `json:"rate" jsonschema:"Rate per distance unit (see the distance_unit setting) for mileage, or per day for per diem"`
+		Currency string  `json:"currency,omitempty" jsonschema:"Currency of the rate (default: base_currency setting)"`
+	}
+
+	type allowanceRate struct {
+		Year     int         `json:"year"`
+		Kind     string      `json:"kind" jsonschema:"mileage or per_diem"`
+		Rate     money.Cents `json:"rate"`
+		Currency string      `json:"currency"`
+		Per      string      `json:"per" jsonschema:"What the rate is charged per: the distance unit or day"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_allowance_rate",
+		Description: "Set the mileage rate or the per-diem rate for a year",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setAllowanceRateArgs) (*mcp.CallToolResult, *allowanceRate, error) {
+		if err := validateAllowanceKind(args.Kind); err != nil {
+			return nil, nil, err
+		}
+		if args.Rate <= 0 {
+			return nil, nil, validationError("rate must be positive")
+		}
+		if args.Year == 0 {
+			args.Year = h.today(ctx).Year()
+		}
+		currency := h.baseCurrency(ctx)
+		if args.Currency != "" {
+			currency = strings.ToUpper(strings.TrimSpace(args.Currency))
+			if err := validateCurrencyCode(currency); err != nil {
+				return nil, nil, validationError("invalid currency: %w", err)
+			}
+		}
+
+		rate := money.FromFloat(args.Rate)
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO allowance_rates (year, kind, rate_cents, currency)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(year, kind) DO UPDATE SET
+				rate_cents = excluded.rate_cents,
+				currency = excluded.currency,
+				updated_at = CURRENT_TIMESTAMP
+		`, args.Year, args.Kind, rate, currency)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to save allowance rate: %w", err)
+		}
+
+		per := "day"
+		if args.Kind == expenseKindMileage {
+			per = h.getSetting(ctx, "distance_unit")
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("%s rate for %d set to %s per %s", allowanceLabels[args.Kind], args.Year, rate.Format(currency), per),
+				},
+			},
+		}, &allowanceRate{Year: args.Year, Kind: args.Kind, Rate: rate, Currency: currency, Per: per}, nil
+	})
+
+	// List Allowance Rates tool
+	type listAllowanceRatesArgs struct{}
+
+	type listAllowanceRatesResult struct {
+		Rates []allowanceRate `json:"rates"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_allowance_rates",
+		Description: "List the mileage and per-diem rates configured per year",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listAllowanceRatesArgs) (*mcp.CallToolResult, *listAllowanceRatesResult, error) {
+		distanceUnit := h.getSetting(ctx, "distance_unit")
+		rows, err := db.QueryContext(ctx, "SELECT year, kind, rate_cents, currency FROM allowance_rates ORDER BY year DESC, kind")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list allowance rates: %w", err)
+		}
+		defer rows.Close()
+
+		var rates []allowanceRate
+		text := ""
+		for rows.Next() {
+			var r allowanceRate
+			if err := rows.Scan(&r.Year, &r.Kind, &r.Rate, &r.Currency); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan allowance rate: %w", err)
+			}
+			r.Per = "day"
+			if r.Kind == expenseKindMileage {
+				r.Per = distanceUnit
+			}
+			rates = append(rates, r)
+			text += fmt.Sprintf("- %d %s: %s per %s\n", r.Year, allowanceLabels[r.Kind], r.Rate.Format(r.Currency), r.Per)
+		}
+		if len(rates) == 0 {
+			text = "No allowance rates configured. Use set_allowance_rate to add the mileage and per-diem rates for a year.\n"
+		} else {
+			text = "Allowance rates:\n" + text
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &listAllowanceRatesResult{Rates: rates}, nil
+	})
+
+	// Add Mileage tool
+	type addMileageArgs struct {
+		ContractNumber string  `json:"contract_number" jsonschema:"Contract the trip was made for"`
+		Distance       float64 `json:"distance" jsonschema:"Distance driven, in the distance_unit setting (default: km)"`
+		RoundTrip      bool    `json:"round_trip,omitempty" jsonschema:"Distance is one way; bill it twice"`
+		Date           string  `json:"date,omitempty" jsonschema:"Date of the trip (YYYY-MM-DD or natural language, default: today)"`
+		Description    string  `json:"description,omitempty" jsonschema:"Trip, e.g. 'Office to Acme HQ'"`
+		Billable       *bool   `json:"billable,omitempty" jsonschema:"Rebill the mileage on the client's next invoice (default: true)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "add_mileage",
+		Description: "Record mileage for a client site visit, priced at the mileage rate for the year",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args addMileageArgs) (*mcp.CallToolResult, *models.Expense, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		date := h.today(ctx)
+		if args.Date != "" {
+			var err error
+			if date, err = h.parseDate(ctx, args.Date); err != nil {
+				return nil, nil, validationError("invalid date: %w", err)
+			}
+		}
+		distance := args.Distance
+		description := args.Description
+		if args.RoundTrip {
+			distance *= 2
+			if description != "" {
+				description += " and back"
+			}
+		}
+
+		e, err := h.addAllowance(ctx, args.ContractNumber, expenseKindMileage, distance, date, description, args.Billable == nil || *args.Billable)
+		if err != nil {
+			return nil, nil, err
+		}
+		return allowanceResult(e, args.ContractNumber)
+	})
+
+	// Add Per Diem tool
+	type addPerDiemArgs struct {
+		ContractNumber string  `json:"contract_number" jsonschema:"Contract the travel was for"`
+		Days           float64 `json:"days,omitempty" jsonschema:"Number of days, 0.5 for a half day (default: 1)"`
+		Date           string  `json:"date,omitempty" jsonschema:"First day (YYYY-MM-DD or natural language, default: today)"`
+		Description    string  `json:"description,omitempty" jsonschema:"Trip, e.g. 'On site at Acme Berlin'"`
+		Billable       *bool   `json:"billable,omitempty" jsonschema:"Rebill the per diem on the client's next invoice (default: true)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "add_per_diem",
+		Description: "Record a per-diem allowance for days on a client site, priced at the per-diem rate for the year",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args addPerDiemArgs) (*mcp.CallToolResult, *models.Expense, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		if args.Days == 0 {
+			args.Days = 1
+		}
+		date := h.today(ctx)
+		if args.Date != "" {
+			var err error
+			if date, err = h.parseDate(ctx, args.Date); err != nil {
+				return nil, nil, validationError("invalid date: %w", err)
+			}
+		}
+
+		e, err := h.addAllowance(ctx, args.ContractNumber, expenseKindPerDiem, args.Days, date, args.Description, args.Billable == nil || *args.Billable)
+		if err != nil {
+			return nil, nil, err
+		}
+		return allowanceResult(e, args.ContractNumber)
+	})
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// withAudit tags the audit log rows written by database triggers during a
+// tool call with the tool's name, then passes the tables the call changed
+// to changed. Calls run one at a time so each call's changes can be told
+// apart.
+func withAudit(db *sql.DB, changed func(ctx context.Context, tables []string)) mcp.Middleware {
+	running := make(chan struct{}, 1)
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			call, ok := req.(*mcp.CallToolRequest)
+			if !ok || call.Params == nil {
+				return next(ctx, method, req)
+			}
+
+			select {
+			case running <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			defer func() { <-running }()
+
+			var lastID int64
+			if err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM audit_log").Scan(&lastID); err != nil {
+				return nil, fmt.Errorf("failed to read audit log: %w", err)
+			}
+
+			result, err := next(ctx, method, req)
+
+			// Tagged even when the call timed out, as its changes may have
+			// been committed
+			_, tagErr := db.ExecContext(context.WithoutCancel(ctx), "UPDATE audit_log SET tool = ? WHERE id > ? AND tool = ''", call.Params.Name, lastID)
+			if tagErr != nil {
+				slog.Warn("failed to tag audit log", "tool", call.Params.Name, "error", tagErr)
+			}
+
+			tables, tablesErr := changedTables(context.WithoutCancel(ctx), db, lastID)
+			if tablesErr != nil {
+				slog.Warn("failed to read audit log", "tool", call.Params.Name, "error", tablesErr)
+			} else if len(tables) > 0 {
+				changed(context.WithoutCancel(ctx), tables)
+			}
+			return result, err
+		}
+	}
+}
+
+// changedTables returns the tables with audit log rows after lastID
+func changedTables(ctx context.Context, db *sql.DB, lastID int64) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT DISTINCT table_name FROM audit_log WHERE id > ?", lastID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+func registerAuditTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	type viewAuditLogArgs struct {
+		Tool   string `json:"tool,omitempty" jsonschema:"Only changes made by this tool, e.g. update_time_entry"`
+		Table  string `json:"table,omitempty" jsonschema:"Only changes to this table, e.g. time_entries or invoices"`
+		RowID  string `json:"row_id,omitempty" jsonschema:"Only changes to the row with this ID (requires table)"`
+		Action string `json:"action,omitempty" jsonschema:"Only this kind of change: insert, update or delete"`
+		Since  string `json:"since,omitempty" jsonschema:"Only changes on or after this date (YYYY-MM-DD)"`
+		Until  string `json:"until,omitempty" jsonschema:"Only changes on or before this date (YYYY-MM-DD)"`
+		Limit  int    `json:"limit,omitempty" jsonschema:"Maximum number of changes to return (default 50)"`
+	}
+
+	type auditEntry struct {
+		ID        int                    `json:"id"`
+		ChangedAt time.Time              `json:"changed_at"`
+		Tool      string                 `json:"tool,omitempty" jsonschema:"Tool that made the change; empty for changes made outside a tool"`
+		Table     string                 `json:"table"`
+		RowID     string                 `json:"row_id"`
+		Action    string                 `json:"action" jsonschema:"insert, update or delete"`
+		Before    map[string]interface{} `json:"before,omitempty" jsonschema:"The row before an update or delete"`
+		After     map[string]interface{} `json:"after,omitempty" jsonschema:"The row after an insert or update"`
+	}
+
+	type viewAuditLogResult struct {
+		Changes []auditEntry `json:"changes"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "view_audit_log",
+		Description: "List changes made to the data, newest first, with the tool that made each one and the row before and after",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args viewAuditLogArgs) (*mcp.CallToolResult, *viewAuditLogResult, error) {
+		if args.Limit <= 0 {
+			args.Limit = 50
+		}
+		if args.RowID != "" && args.Table == "" {
+			return nil, nil, validationError("row_id requires table, as IDs are only unique within a table")
+		}
+		switch args.Action {
+		case "", "insert", "update", "delete":
+		default:
+			return nil, nil, validationError("unknown action %q; use insert, update or delete", args.Action)
+		}
+
+		query := `
+			SELECT id, changed_at, tool, table_name, row_key, action, COALESCE(before, ''), COALESCE(after, '')
+			FROM audit_log
+			WHERE 1=1
+		`
+		queryArgs := []interface{}{}
+		if args.Tool != "" {
+			query += " AND tool = ?"
+			queryArgs = append(queryArgs, args.Tool)
+		}
+		if args.Table != "" {
+			query += " AND table_name = ?"
+			queryArgs = append(queryArgs, args.Table)
+		}
+		if args.RowID != "" {
+			query += " AND row_key = ?"
+			queryArgs = append(queryArgs, args.RowID)
+		}
+		if args.Action != "" {
+			query += " AND action = ?"
+			queryArgs = append(queryArgs, args.Action)
+		}
+		if args.Since != "" {
+			since, err := time.Parse("2006-01-02", args.Since)
+			if err != nil {
+				return nil, nil, validationError("invalid since date: %w", err)
+			}
+			query += " AND changed_at >= ?"
+			queryArgs = append(queryArgs, since.Format("2006-01-02"))
+		}
+		if args.Until != "" {
+			until, err := time.Parse("2006-01-02", args.Until)
+			if err != nil {
+				return nil, nil, validationError("invalid until date: %w", err)
+			}
+			query += " AND changed_at < ?"
+			queryArgs = append(queryArgs, until.AddDate(0, 0, 1).Format("2006-01-02"))
+		}
+		query += " ORDER BY id DESC LIMIT ?"
+		queryArgs = append(queryArgs, args.Limit)
+
+		rows, err := db.QueryContext(ctx, query, queryArgs...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list audit log: %w", err)
+		}
+		defer rows.Close()
+
+		var entries []auditEntry
+		for rows.Next() {
+			var e auditEntry
+			var before, after string
+			if err := rows.Scan(&e.ID, &e.ChangedAt, &e.Tool, &e.Table, &e.RowID, &e.Action, &before, &after); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan audit log: %w", err)
+			}
+			if before != "" {
+				if err := json.Unmarshal([]byte(before), &e.Before); err != nil {
+					return nil, nil, fmt.Errorf("failed to read audit log entry %d: %w", e.ID, err)
+				}
+			}
+			if after != "" {
+				if err := json.Unmarshal([]byte(after), &e.After); err != nil {
+					return nil, nil, fmt.Errorf("failed to read audit log entry %d: %w", e.ID, err)
+				}
+			}
+			entries = append(entries, e)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, nil, fmt.Errorf("failed to list audit log: %w", err)
+		}
+
+		text := fmt.Sprintf("Found %d changes:\n", len(entries))
+		for _, e := range entries {
+			tool := e.Tool
+			if tool == "" {
+				tool = "outside a tool"
+			}
+			text += fmt.Sprintf("- %s %s %s %s (%s)", e.ChangedAt.Local().Format("2006-01-02 15:04:05"), e.Action, e.Table, e.RowID, tool)
+			if e.Action == "update" {
+				text += ": " + strings.Join(changedFields(e.Before, e.After), ", ")
+			}
+			text += "\n"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &viewAuditLogResult{Changes: entries}, nil
+	})
+}
+
+// changedFields describes the fields an update changed, e.g. hours 2 → 3
+func changedFields(before, after map[string]interface{}) []string {
+	var fields []string
+	for name, value := range after {
+		old := fmt.Sprint(before[name])
+		if old != fmt.Sprint(value) {
+			fields = append(fields, fmt.Sprintf("%s %s → %s", name, auditValue(before[name]), auditValue(value)))
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func auditValue(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/database"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// backupCheckInterval is how often the scheduler checks whether a backup is due
+const backupCheckInterval = 15 * time.Minute
+
+// StartBackupScheduler takes a backup whenever the newest one is older than
+// the backup_interval_hours setting, rotating old backups afterwards. It
+// runs until ctx is cancelled. In-memory databases are not backed up.
+func StartBackupScheduler(ctx context.Context, db *sql.DB) {
+	if database.InMemory() {
+		return
+	}
+	h := newHandler(db)
+	go func() {
+		ticker := time.NewTicker(backupCheckInterval)
+		defer ticker.Stop()
+		for {
+			h.backupIfDue(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (h *Handler) backupIfDue(ctx context.Context) {
+	interval := h.getIntSetting(ctx, "backup_interval_hours")
+	if interval == 0 {
+		return
+	}
+
+	backups, err := database.ListBackups()
+	if err != nil {
+		slog.Error("failed to list backups", "error", err)
+		return
+	}
+	if len(backups) > 0 && time.Since(backups[0].CreatedAt) < time.Duration(interval)*time.Hour {
+		return
+	}
+
+	if _, err := database.Backup(ctx, h.db, "scheduled"); err != nil {
+		slog.Error("scheduled backup failed", "error", err)
+		return
+	}
+	if _, err := h.rotateBackups(ctx); err != nil {
+		slog.Error("failed to rotate backups", "error", err)
+	}
+}
+
+func (h *Handler) rotateBackups(ctx context.Context) ([]string, error) {
+	return database.RotateBackups(h.getIntSetting(ctx, "backup_keep_daily"), h.getIntSetting(ctx, "backup_keep_monthly"))
+}
+
+// registerBackupTools registers tools to create, list and restore backups
+func registerBackupTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Backup Now tool
+	type backupNowArgs struct{}
+
+	type backupNowResult struct {
+		Backup  *database.BackupInfo `json:"backup"`
+		Removed []string             `json:"removed" jsonschema:"Old backups removed by rotation"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "backup_now",
+		Description: "Back up the database to its backups folder (~/.hours/backups by default) immediately",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args backupNowArgs) (*mcp.CallToolResult, *backupNowResult, error) {
+		backup, err := database.Backup(ctx, db, "manual")
+		if err != nil {
+			return nil, nil, err
+		}
+		removed, err := h.rotateBackups(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		text := fmt.Sprintf("Backup saved to %s (%.1f KB)\n", backup.Path, float64(backup.Size)/1024)
+		if len(removed) > 0 {
+			text += fmt.Sprintf("Removed %d old backups\n", len(removed))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &backupNowResult{Backup: backup, Removed: removed}, nil
+	})
+
+	// List Backups tool
+	type listBackupsArgs struct{}
+
+	type listBackupsResult struct {
+		Backups []database.BackupInfo `json:"backups"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_backups",
+		Description: "List database backups, newest first",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listBackupsArgs) (*mcp.CallToolResult, *listBackupsResult, error) {
+		backups, err := database.ListBackups()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		text := fmt.Sprintf("Found %d backups:\n", len(backups))
+		for _, b := range backups {
+			text += fmt.Sprintf("- %s (%s, %.1f KB)\n", b.Name, b.Reason, float64(b.Size)/1024)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &listBackupsResult{Backups: backups}, nil
+	})
+
+	// Restore Backup tool
+	type restoreBackupArgs struct {
+		Name    string `json:"name" jsonschema:"Backup file name from list_backups"`
+		Confirm bool   `json:"confirm,omitempty" jsonschema:"Restore the backup (default: false, only shows the data it would replace)"`
+	}
+
+	type restoreBackupResult struct {
+		Confirmed     bool           `json:"confirmed" jsonschema:"Whether the backup was restored; false for a preview"`
+		Replace       map[string]int `json:"replace,omitempty" jsonschema:"Rows per table that would be replaced"`
+		Restored      map[string]int `json:"restored,omitempty" jsonschema:"Rows per table restored from the backup"`
+		PreviousState string         `json:"previous_state,omitempty" jsonschema:"Backup holding the data from before the restore"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "restore_backup",
+		Description: "Replace all data with the contents of a backup. The current data is backed up first. Only shows what would be replaced until run with confirm=true",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args restoreBackupArgs) (*mcp.CallToolResult, *restoreBackupResult, error) {
+		dir, err := database.BackupDir()
+		if err != nil {
+			return nil, nil, err
+		}
+		if args.Name == "" || filepath.Base(args.Name) != args.Name {
+			return nil, nil, validationError("invalid backup name '%s'. Use 'list_backups' to see available backups", args.Name)
+		}
+		path := filepath.Join(dir, args.Name)
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil, notFoundError("backup", "backup '%s' not found. Use 'list_backups' to see available backups", args.Name)
+		}
+
+		if !args.Confirm {
+			counts, err := database.RowCounts(ctx, db)
+			if err != nil {
+				return nil, nil, err
+			}
+			total := 0
+			var tables []string
+			for table, n := range counts {
+				total += n
+				if n > 0 {
+					tables = append(tables, fmt.Sprintf("%s %d", table, n))
+				}
+			}
+			sort.Strings(tables)
+			text := fmt.Sprintf("Would replace the %d rows in the database with the contents of %s", total, args.Name)
+			if len(tables) > 0 {
+				text += " (current rows: " + strings.Join(tables, ", ") + ")"
+			}
+			text += ". The current data would be backed up first.\n"
+			return previewResult(text, &restoreBackupResult{Replace: counts})
+		}
+
+		safety, err := database.Backup(ctx, db, "pre-restore")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to back up current data before restoring: %w", err)
+		}
+
+		counts, err := database.RestoreBackup(ctx, db, path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to restore backup: %w", err)
+		}
+
+		total := 0
+		for _, n := range counts {
+			total += n
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Restored %d rows from %s\nPrevious data saved as %s", total, args.Name, safety.Name)},
+			},
+		}, &restoreBackupResult{Confirmed: true, Restored: counts, PreviousState: safety.Name}, nil
+	})
+}
@@ -0,0 +1,336 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Billing cycles: how often a client is invoiced
+const (
+	billingMonthly  = "monthly"
+	billingWeekly   = "weekly"
+	billingBiweekly = "biweekly"
+)
+
+var billingCycles = []string{billingMonthly, billingWeekly, billingBiweekly}
+
+// billingCycle is a client's billing cadence, stored as "monthly:15" for
+// the 15th of every month or "biweekly:2026-10-16" for every other week
+// from a day it is billed on
+type billingCycle struct {
+	kind string
+	// day is the day of the month monthly clients are billed on
+	day int
+	// anchor is a day weekly and biweekly clients are billed on
+	anchor time.Time
+}
+
+// parseBillingCycle reads a stored billing cycle; ok is false for none
+func parseBillingCycle(stored string) (cycle billingCycle, ok bool) {
+	kind, value, found := strings.Cut(stored, ":")
+	if !found {
+		return cycle, false
+	}
+	cycle.kind = kind
+	switch kind {
+	case billingMonthly:
+		day, err := strconv.Atoi(value)
+		if err != nil {
+			return cycle, false
+		}
+		cycle.day = day
+	case billingWeekly, billingBiweekly:
+		anchor, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return cycle, false
+		}
+		cycle.anchor = anchor
+	default:
+		return cycle, false
+	}
+	return cycle, true
+}
+
+// String stores the cycle
+func (c billingCycle) String() string {
+	if c.kind == billingMonthly {
+		return fmt.Sprintf("%s:%d", c.kind, c.day)
+	}
+	return c.kind + ":" + c.anchor.Format("2006-01-02")
+}
+
+// describe tells how often the cycle bills, e.g. "monthly on the 15th" or
+// "every other Friday"
+func (c billingCycle) describe() string {
+	switch c.kind {
+	case billingMonthly:
+		return "monthly on the " + ordinal(c.day)
+	case billingWeekly:
+		return "every " + c.anchor.Weekday().String()
+	}
+	return "every other " + c.anchor.Weekday().String()
+}
+
+// lastBillingDay returns the latest day on or before today the cycle bills
+// on, and the one before it: the work between them, up to the day before
+// the latest, is the cycle's to invoice
+func (c billingCycle) lastBillingDay(today time.Time) (last, previous time.Time) {
+	if c.kind == billingMonthly {
+		last = monthDay(today.Year(), today.Month(), c.day)
+		if last.After(today) {
+			last = monthDay(today.Year(), today.Month()-1, c.day)
+		}
+		return last, monthDay(last.Year(), last.Month()-1, c.day)
+	}
+	length := 7
+	if c.kind == billingBiweekly {
+		length = 14
+	}
+	days := int(today.Sub(c.anchor).Hours() / 24)
+	periods := days / length
+	if days < 0 && days%length != 0 {
+		periods--
+	}
+	last = c.anchor.AddDate(0, 0, periods*length)
+	return last, last.AddDate(0, 0, -length)
+}
+
+// nextBillingDay returns the first day after today the cycle bills on
+func (c billingCycle) nextBillingDay(today time.Time) time.Time {
+	last, _ := c.lastBillingDay(today)
+	if c.kind == billingMonthly {
+		return monthDay(last.Year(), last.Month()+1, c.day)
+	}
+	if c.kind == billingBiweekly {
+		return last.AddDate(0, 0, 14)
+	}
+	return last.AddDate(0, 0, 7)
+}
+
+// monthDay returns a day of a month, or the month's last day when it is
+// shorter; month may be out of range, as with time.Date
+func monthDay(year int, month time.Month, day int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// ordinal returns 1st, 2nd, 3rd, 4th and so on
+func ordinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// normalizeBillingCycle validates a billing cycle given to add_client or
+// edit_client and returns it as stored; an empty kind clears it. day is
+// the day of the month for monthly cycles (default 1) and a date the client
+// is billed on for weekly and biweekly ones (default today).
+func (h *Handler) normalizeBillingCycle(ctx context.Context, kind, day string) (string, error) {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	day = strings.TrimSpace(day)
+	switch kind {
+	case "", "none":
+		if day != "" {
+			return "", validationError("billing_day needs a billing_cycle")
+		}
+		return "", nil
+	case "every other week", "fortnightly":
+		kind = billingBiweekly
+	}
+	if !slices.Contains(billingCycles, kind) {
+		return "", validationError("invalid billing cycle '%s': must be %s", kind, strings.Join(billingCycles, ", "))
+	}
+
+	cycle := billingCycle{kind: kind}
+	if kind == billingMonthly {
+		cycle.day = 1
+		if day != "" {
+			n, err := strconv.Atoi(strings.TrimRight(strings.ToLower(day), "stndrh"))
+			if err != nil || n < 1 || n > 31 {
+				return "", validationError("invalid billing day '%s': monthly clients are billed on a day of the month from 1 to 31", day)
+			}
+			cycle.day = n
+		}
+		return cycle.String(), nil
+	}
+	cycle.anchor = h.today(ctx)
+	if day != "" {
+		anchor, err := h.parseDate(ctx, day)
+		if err != nil {
+			return "", validationError("invalid billing day '%s': %s clients are billed from a date such as 'friday' or 2026-10-16: %w", day, kind, err)
+		}
+		cycle.anchor = anchor
+	}
+	return cycle.String(), nil
+}
+
+// registerBillingTools registers the tools that follow the clients'
+// billing cycles
+func registerBillingTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Due For Billing tool
+	type dueForBillingArgs struct {
+		Format string `json:"format,omitempty" jsonschema:"Output format: text (default) or markdown"`
+	}
+
+	type clientDue struct {
+		ClientName  string                 `json:"client_name"`
+		Cycle       string                 `json:"billing_cycle"`
+		DueSince    string                 `json:"due_since" jsonschema:"The billing day the work became due on"`
+		StartDate   string                 `json:"start_date" jsonschema:"First day of unbilled work to invoice"`
+		EndDate     string                 `json:"end_date" jsonschema:"Last day to invoice, the day before the billing day"`
+		Hours       float64                `json:"hours"`
+		Totals      map[string]money.Cents `json:"totals"`
+		NextBilling string                 `json:"next_billing"`
+	}
+
+	type clientUpcoming struct {
+		ClientName  string `json:"client_name"`
+		Cycle       string `json:"billing_cycle"`
+		NextBilling string `json:"next_billing"`
+	}
+
+	type dueForBillingResult struct {
+		Due      []clientDue      `json:"due"`
+		Upcoming []clientUpcoming `json:"upcoming" jsonschema:"Clients with a billing cycle and nothing due, by their next billing day"`
+		NoCycle  int              `json:"clients_without_cycle" jsonschema:"Active clients with no billing cycle, left out"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "due_for_billing",
+		Description: "List the clients whose billing cycle has come due, with the unbilled hours and amounts up to their billing day and the dates to pass to create_invoice; other clients with a cycle are listed by their next billing day",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args dueForBillingArgs) (*mcp.CallToolResult, *dueForBillingResult, error) {
+		markdown, err := parseOutputFormat(args.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rows, err := db.QueryContext(ctx, "SELECT id, name, billing_cycle FROM clients WHERE archived_at IS NULL ORDER BY name")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list clients: %w", err)
+		}
+		type cycleClient struct {
+			id    int
+			name  string
+			cycle billingCycle
+		}
+		var clients []cycleClient
+		result := &dueForBillingResult{Due: []clientDue{}, Upcoming: []clientUpcoming{}}
+		for rows.Next() {
+			var c cycleClient
+			var stored string
+			if err := rows.Scan(&c.id, &c.name, &stored); err != nil {
+				rows.Close()
+				return nil, nil, fmt.Errorf("failed to scan client: %w", err)
+			}
+			var ok bool
+			if c.cycle, ok = parseBillingCycle(stored); !ok {
+				result.NoCycle++
+				continue
+			}
+			clients = append(clients, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		// A client is due once a billing day has passed with work from
+		// before it still unbilled, however old
+		for _, c := range clients {
+			today := h.clientToday(ctx, c.id)
+			last, _ := c.cycle.lastBillingDay(today)
+			next := c.cycle.nextBillingDay(today).Format("2006-01-02")
+			summary, err := h.unbilledSummary(ctx, c.id, time.Time{}, last.AddDate(0, 0, -1))
+			if err != nil {
+				return nil, nil, err
+			}
+			if summary.TotalHours == 0 {
+				result.Upcoming = append(result.Upcoming, clientUpcoming{ClientName: c.name, Cycle: c.cycle.describe(), NextBilling: next})
+				continue
+			}
+			first := summary.Contracts[0].FirstDate
+			for _, contract := range summary.Contracts {
+				if contract.FirstDate.Before(first) {
+					first = contract.FirstDate
+				}
+			}
+			result.Due = append(result.Due, clientDue{
+				ClientName:  c.name,
+				Cycle:       c.cycle.describe(),
+				DueSince:    last.Format("2006-01-02"),
+				StartDate:   first.Format("2006-01-02"),
+				EndDate:     last.AddDate(0, 0, -1).Format("2006-01-02"),
+				Hours:       summary.TotalHours,
+				Totals:      summary.Totals,
+				NextBilling: next,
+			})
+		}
+		slices.SortStableFunc(result.Upcoming, func(a, b clientUpcoming) int {
+			return strings.Compare(a.NextBilling, b.NextBilling)
+		})
+
+		var text string
+		switch {
+		case len(clients) == 0:
+			text = "No client has a billing cycle. Set one with edit_client, e.g. billing_cycle monthly and billing_day 15.\n"
+		case len(result.Due) == 0:
+			text = "No client is due for billing.\n"
+		case markdown:
+			tableRows := make([][]string, 0, len(result.Due))
+			for _, d := range result.Due {
+				tableRows = append(tableRows, []string{d.ClientName, d.Cycle, d.DueSince, d.StartDate + " to " + d.EndDate,
+					fmt.Sprintf("%.2f", d.Hours), formatCurrencyTotals(d.Totals)})
+			}
+			text = "### Due for billing\n\n" +
+				markdownTable([]string{"Client", "Cycle", "Due since", "Work", "Hours", "Amount"}, tableRows, 4, 5) + "\n"
+		default:
+			text = "Due for billing:\n"
+			for _, d := range result.Due {
+				text += fmt.Sprintf("- %s (%s): due since %s, %.2f hours = %s from %s to %s\n", d.ClientName, d.Cycle, d.DueSince,
+					d.Hours, formatCurrencyTotals(d.Totals), d.StartDate, d.EndDate)
+			}
+		}
+		if len(result.Due) > 0 {
+			text += "Invoice each with create_invoice, passing its start_date and end_date\n"
+		}
+		if len(result.Upcoming) > 0 {
+			text += "Not due yet:\n"
+			for _, u := range result.Upcoming {
+				text += fmt.Sprintf("- %s (%s): next on %s\n", u.ClientName, u.Cycle, u.NextBilling)
+			}
+		}
+		switch {
+		case len(clients) == 0:
+		case result.NoCycle == 1:
+			text += "1 client has no billing cycle\n"
+		case result.NoCycle > 1:
+			text += fmt.Sprintf("%d clients have no billing cycle\n", result.NoCycle)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+}
@@ -0,0 +1,372 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// budgetUsage is how much of a contract's budget its time entries use. The
+// amount is the hours priced as they would be invoiced, premiums included.
+type budgetUsage struct {
+	Hours  float64     `json:"hours"`
+	Amount money.Cents `json:"amount"`
+}
+
+// budgetStatus is a contract's budget terms and how much of them is used
+type budgetStatus struct {
+	ContractNumber string      `json:"contract_number"`
+	Currency       string      `json:"currency"`
+	BudgetHours    float64     `json:"budget_hours,omitempty"`
+	BudgetAmount   money.Cents `json:"budget_amount,omitempty"`
+	AlertPercent   float64     `json:"alert_percent,omitempty"`
+	HardLimit      bool        `json:"hard_limit"`
+	Used           budgetUsage `json:"used"`
+}
+
+func newBudgetStatus(c *models.Contract, used budgetUsage) *budgetStatus {
+	return &budgetStatus{
+		ContractNumber: c.ContractNumber,
+		Currency:       c.Currency,
+		BudgetHours:    c.BudgetHours,
+		BudgetAmount:   c.BudgetAmount,
+		AlertPercent:   c.BudgetAlert,
+		HardLimit:      c.BudgetLimit,
+		Used:           used,
+	}
+}
+
+// loadBudget returns the contract with its budget terms, or nil when the
+// contract has no budget
+func (h *Handler) loadBudget(ctx context.Context, contractID int) (*models.Contract, error) {
+	c := &models.Contract{ID: contractID}
+	err := h.db.QueryRowContext(ctx, `
+		SELECT contract_number, currency, budget_hours, budget_amount_cents, budget_alert_percent, budget_hard_limit
+		FROM contracts WHERE id = ?
+	`, contractID).Scan(&c.ContractNumber, &c.Currency, &c.BudgetHours, &c.BudgetAmount, &c.BudgetAlert, &c.BudgetLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contract budget: %w", err)
+	}
+	if c.BudgetHours == 0 && c.BudgetAmount == 0 {
+		return nil, nil
+	}
+	return c, nil
+}
+
+// budgetUsed sums the hours and priced amount logged against a contract
+func (h *Handler) budgetUsed(ctx context.Context, contractID int) (budgetUsage, error) {
+	var used budgetUsage
+	items, err := h.priceStoredEntries(ctx, "te.contract_id = ?", contractID)
+	if err != nil {
+		return used, err
+	}
+	for _, item := range items {
+		used.Hours += item.Hours
+		used.Amount += item.Amount
+	}
+	return used, nil
+}
+
+// budgetShares returns the share of each budget used, in percent, for the
+// budgets that are set
+func budgetShares(c *models.Contract, used budgetUsage) map[string]float64 {
+	shares := map[string]float64{}
+	if c.BudgetHours > 0 {
+		shares["hours"] = used.Hours / c.BudgetHours * 100
+	}
+	if c.BudgetAmount > 0 {
+		shares["amount"] = float64(used.Amount) / float64(c.BudgetAmount) * 100
+	}
+	return shares
+}
+
+// budgetSummary describes one budget's use, e.g. "82.0 of 100 hours (82%)"
+func budgetSummary(c *models.Contract, used budgetUsage, budget string) string {
+	shares := budgetShares(c, used)
+	if budget == "hours" {
+		return fmt.Sprintf("%.2f of %g hours (%.0f%%)", used.Hours, c.BudgetHours, shares["hours"])
+	}
+	return fmt.Sprintf("%s of %s (%.0f%%)", used.Amount.Format(c.Currency), c.BudgetAmount.Format(c.Currency), shares["amount"])
+}
+
+// parseBudgetThresholds reads the budget_alert_thresholds setting, a
+// comma-separated list of percentages, into ascending order
+func parseBudgetThresholds(value string) ([]float64, error) {
+	var thresholds []float64
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSuffix(strings.TrimSpace(part), "%")
+		if part == "" {
+			continue
+		}
+		threshold, err := strconv.ParseFloat(part, 64)
+		if err != nil || threshold <= 0 || threshold > 100 {
+			return nil, validationError("invalid threshold '%s': must be percentages between 0 and 100, e.g. 50,80,95", part)
+		}
+		if !slices.Contains(thresholds, threshold) {
+			thresholds = append(thresholds, threshold)
+		}
+	}
+	slices.Sort(thresholds)
+	return thresholds, nil
+}
+
+// budgetThresholds returns the shares of a contract's budget add_hours
+// alerts at: the budget_alert_thresholds setting and the contract's own
+// alert_percent
+func (h *Handler) budgetThresholds(ctx context.Context, c *models.Contract) []float64 {
+	thresholds, err := parseBudgetThresholds(h.getSetting(ctx, "budget_alert_thresholds"))
+	if err != nil {
+		thresholds, _ = parseBudgetThresholds(knownSettings["budget_alert_thresholds"].defaultValue)
+	}
+	if c.BudgetAlert > 0 && !slices.Contains(thresholds, c.BudgetAlert) {
+		thresholds = append(thresholds, c.BudgetAlert)
+		slices.Sort(thresholds)
+	}
+	return thresholds
+}
+
+// budgetAlerts warns about each budget whose limit or alert thresholds were
+// crossed going from before to after, naming only the highest threshold
+// when one entry crosses several
+func budgetAlerts(c *models.Contract, before, after budgetUsage, thresholds []float64) []string {
+	var alerts []string
+	previous := budgetShares(c, before)
+	for _, budget := range []string{"hours", "amount"} {
+		share, ok := budgetShares(c, after)[budget]
+		if !ok {
+			continue
+		}
+		if share > 100 {
+			if previous[budget] <= 100 {
+				alerts = append(alerts, fmt.Sprintf("Budget exceeded: %s has used %s of its %s budget", c.ContractNumber, budgetSummary(c, after, budget), budget))
+			}
+			continue
+		}
+		crossed := 0.0
+		for _, threshold := range thresholds {
+			if share >= threshold && previous[budget] < threshold {
+				crossed = threshold
+			}
+		}
+		if crossed > 0 {
+			alerts = append(alerts, fmt.Sprintf("Budget alert: %s has passed %g%% of its %s budget, using %s; %s left",
+				c.ContractNumber, crossed, budget, budgetSummary(c, after, budget), budgetLeft(c, after, budget)))
+		}
+	}
+	return alerts
+}
+
+// budgetLeft describes what remains of one budget, e.g. "18.00 hours"
+func budgetLeft(c *models.Contract, used budgetUsage, budget string) string {
+	if budget == "hours" {
+		return fmt.Sprintf("%.2f hours", c.BudgetHours-used.Hours)
+	}
+	return (c.BudgetAmount - used.Amount).Format(c.Currency)
+}
+
+// budgetExceeded reports which budgets usage goes over
+func budgetExceeded(c *models.Contract, used budgetUsage) []string {
+	var over []string
+	for _, budget := range []string{"hours", "amount"} {
+		if share, ok := budgetShares(c, used)[budget]; ok && share > 100 {
+			over = append(over, budgetSummary(c, used, budget))
+		}
+	}
+	return over
+}
+
+// registerBudgetTools registers tools to set and check contract budgets
+func registerBudgetTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Set Contract Budget tool
+	type setContractBudgetArgs struct {
+		ContractNumber string   `json:"contract_number" jsonschema:"Contract number or name"`
+		BudgetHours    *float64 `json:"budget_hours,omitempty" jsonschema:"Maximum hours, 0 for none (optional)"`
+		BudgetAmount   *float64 `json:"budget_amount,omitempty" jsonschema:"Maximum amount billed for hours in the contract currency, e.g. a purchase order's not-to-exceed value, 0 for none (optional)"`
+		AlertPercent   *float64 `json:"alert_percent,omitempty" jsonschema:"Also warn when logging hours crosses this share of a budget, besides the budget_alert_thresholds setting (default: 80)"`
+		HardLimit      *bool    `json:"hard_limit,omitempty" jsonschema:"Refuse hours that would go over a budget (default: false, only warn)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_contract_budget",
+		Description: "Set a contract's hours and/or amount budget with an alert threshold; add_hours warns when it or a budget_alert_thresholds share is crossed and can refuse hours over budget",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setContractBudgetArgs) (*mcp.CallToolResult, *budgetStatus, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		var contractID int
+		err := db.QueryRowContext(ctx, "SELECT id FROM contracts WHERE contract_number = ?", args.ContractNumber).Scan(&contractID)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("contract", "contract %s not found", args.ContractNumber)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find contract: %w", err)
+		}
+
+		updates := []string{}
+		updateArgs := []interface{}{}
+		if args.BudgetHours != nil {
+			if *args.BudgetHours < 0 {
+				return nil, nil, validationError("budget_hours cannot be negative")
+			}
+			updates = append(updates, "budget_hours = ?")
+			updateArgs = append(updateArgs, *args.BudgetHours)
+		}
+		if args.BudgetAmount != nil {
+			if *args.BudgetAmount < 0 {
+				return nil, nil, validationError("budget_amount cannot be negative")
+			}
+			updates = append(updates, "budget_amount_cents = ?")
+			updateArgs = append(updateArgs, money.FromFloat(*args.BudgetAmount))
+		}
+		if args.AlertPercent != nil {
+			if *args.AlertPercent <= 0 || *args.AlertPercent > 100 {
+				return nil, nil, validationError("alert_percent must be between 0 and 100")
+			}
+			updates = append(updates, "budget_alert_percent = ?")
+			updateArgs = append(updateArgs, *args.AlertPercent)
+		}
+		if args.HardLimit != nil {
+			updates = append(updates, "budget_hard_limit = ?")
+			updateArgs = append(updateArgs, *args.HardLimit)
+		}
+		if len(updates) == 0 {
+			return nil, nil, validationError("no updates provided")
+		}
+
+		updates = append(updates, "updated_at = CURRENT_TIMESTAMP")
+		updateArgs = append(updateArgs, contractID)
+		query := fmt.Sprintf("UPDATE contracts SET %s WHERE id = ?", strings.Join(updates, ", "))
+		if _, err := db.ExecContext(ctx, query, updateArgs...); err != nil {
+			return nil, nil, fmt.Errorf("failed to update contract budget: %w", err)
+		}
+
+		c, err := h.loadBudget(ctx, contractID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if c == nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("%s has no budget", args.ContractNumber)},
+				},
+			}, &budgetStatus{ContractNumber: args.ContractNumber}, nil
+		}
+
+		used, err := h.budgetUsed(ctx, contractID)
+		if err != nil {
+			return nil, nil, err
+		}
+		var alertAt []string
+		for _, threshold := range h.budgetThresholds(ctx, c) {
+			alertAt = append(alertAt, fmt.Sprintf("%g%%", threshold))
+		}
+		text := fmt.Sprintf("Budget for %s set, alerting at %s", args.ContractNumber, strings.Join(alertAt, ", "))
+		if c.BudgetLimit {
+			text += " and refusing hours over budget"
+		}
+		text += "\n" + budgetText(c, used)
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, newBudgetStatus(c, used), nil
+	})
+
+	// Contract Budget Status tool
+	type contractBudgetStatusArgs struct {
+		ContractNumber string `json:"contract_number,omitempty" jsonschema:"Contract number (default: every active contract with a budget)"`
+	}
+
+	type contractBudgetStatusResult struct {
+		Budgets []budgetStatus `json:"budgets"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "contract_budget_status",
+		Description: "Show how much of their hours and amount budgets contracts have used",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args contractBudgetStatusArgs) (*mcp.CallToolResult, *contractBudgetStatusResult, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		query := "SELECT id FROM contracts WHERE (budget_hours > 0 OR budget_amount_cents > 0) AND status = 'active' ORDER BY contract_number"
+		queryArgs := []interface{}{}
+		if args.ContractNumber != "" {
+			query = "SELECT id FROM contracts WHERE contract_number = ?"
+			queryArgs = append(queryArgs, args.ContractNumber)
+		}
+		rows, err := db.QueryContext(ctx, query, queryArgs...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list contracts: %w", err)
+		}
+		var ids []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, nil, fmt.Errorf("failed to scan contract: %w", err)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if args.ContractNumber != "" && len(ids) == 0 {
+			return nil, nil, notFoundError("contract", "contract %s not found", args.ContractNumber)
+		}
+
+		var statuses []budgetStatus
+		text := ""
+		for _, id := range ids {
+			c, err := h.loadBudget(ctx, id)
+			if err != nil {
+				return nil, nil, err
+			}
+			if c == nil {
+				text += fmt.Sprintf("%s has no budget. Use set_contract_budget to add one.\n", args.ContractNumber)
+				continue
+			}
+			used, err := h.budgetUsed(ctx, id)
+			if err != nil {
+				return nil, nil, err
+			}
+			statuses = append(statuses, *newBudgetStatus(c, used))
+			text += fmt.Sprintf("%s:\n%s", c.ContractNumber, budgetText(c, used))
+		}
+		if text == "" {
+			text = "No active contracts have a budget.\n"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &contractBudgetStatusResult{Budgets: statuses}, nil
+	})
+}
+
+// budgetText lists the use of each budget a contract has, flagging those
+// over their alert threshold
+func budgetText(c *models.Contract, used budgetUsage) string {
+	text := ""
+	shares := budgetShares(c, used)
+	for _, budget := range []string{"hours", "amount"} {
+		share, ok := shares[budget]
+		if !ok {
+			continue
+		}
+		text += fmt.Sprintf("- %s: %s", strings.ToUpper(budget[:1])+budget[1:], budgetSummary(c, used, budget))
+		if share > 100 {
+			text += " - over budget"
+		} else if share >= c.BudgetAlert {
+			text += " - alert"
+		}
+		text += "\n"
+	}
+	return text
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// registerBusinessTools registers tools to set the business and payment
+// details printed on invoices
+func registerBusinessTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Set Business Info tool
+	type setBusinessInfoArgs struct {
+		BusinessName  string `json:"business_name" jsonschema:"Business name"`
+		ContactName   string `json:"contact_name" jsonschema:"Contact person name"`
+		Email         string `json:"email" jsonschema:"Business email address"`
+		Phone         string `json:"phone,omitempty" jsonschema:"Phone number (optional)"`
+		Address       string `json:"address,omitempty" jsonschema:"Street address (optional)"`
+		City          string `json:"city,omitempty" jsonschema:"City (optional)"`
+		State         string `json:"state,omitempty" jsonschema:"State/Province (optional)"`
+		ZipCode       string `json:"zip_code,omitempty" jsonschema:"ZIP/Postal code (optional)"`
+		Country       string `json:"country,omitempty" jsonschema:"Country (optional)"`
+		TaxID         string `json:"tax_id,omitempty" jsonschema:"Tax ID/EIN (optional)"`
+		Website       string `json:"website,omitempty" jsonschema:"Website URL (optional)"`
+		LogoPath      string `json:"logo_path,omitempty" jsonschema:"Path to logo file (optional)"`
+		InvoicePrefix string `json:"invoice_prefix,omitempty" jsonschema:"Invoice number prefix (optional, defaults to 'INV')"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_business_info",
+		Description: "Set or update your business information for invoices",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setBusinessInfoArgs) (*mcp.CallToolResult, *models.BusinessInfo, error) {
+		if args.InvoicePrefix == "" {
+			args.InvoicePrefix = "INV"
+		}
+		taxID := args.TaxID
+		if err := encryptFields(&taxID); err != nil {
+			return nil, nil, err
+		}
+
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO business_info (id, business_name, contact_name, email, phone, address, city, state, zip_code, country, tax_id, website, logo_path, invoice_prefix, updated_at)
+			VALUES (1, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				business_name = excluded.business_name,
+				contact_name = excluded.contact_name,
+				email = excluded.email,
+				phone = excluded.phone,
+				address = excluded.address,
+				city = excluded.city,
+				state = excluded.state,
+				zip_code = excluded.zip_code,
+				country = excluded.country,
+				tax_id = excluded.tax_id,
+				website = excluded.website,
+				logo_path = excluded.logo_path,
+				invoice_prefix = excluded.invoice_prefix,
+				updated_at = excluded.updated_at
+		`, args.BusinessName, args.ContactName, args.Email, args.Phone, args.Address,
+			args.City, args.State, args.ZipCode, args.Country, taxID,
+			args.Website, args.LogoPath, args.InvoicePrefix, time.Now())
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to set business info: %w", err)
+		}
+		business, err := h.loadBusinessInfo(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("Business information updated successfully for '%s'", args.BusinessName),
+				},
+			},
+		}, business, nil
+	})
+
+	// Get Business Info tool
+	type getBusinessInfoArgs struct{}
+
+	type getBusinessInfoResult struct {
+		Configured bool                 `json:"configured"`
+		Business   *models.BusinessInfo `json:"business,omitempty"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_business_info",
+		Description: "Get current business information settings",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getBusinessInfoArgs) (*mcp.CallToolResult, *getBusinessInfoResult, error) {
+		business, err := h.loadBusinessInfo(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if business == nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{
+						Text: "No business information configured. Use 'set_business_info' to configure your business details.",
+					},
+				},
+			}, &getBusinessInfoResult{}, nil
+		}
+
+		text := fmt.Sprintf("Business Information:\n")
+		text += fmt.Sprintf("Business Name: %s\n", business.BusinessName)
+		text += fmt.Sprintf("Contact: %s\n", business.ContactName)
+		text += fmt.Sprintf("Email: %s\n", business.Email)
+		if business.Phone != "" {
+			text += fmt.Sprintf("Phone: %s\n", business.Phone)
+		}
+		if business.Address != "" {
+			text += fmt.Sprintf("Address: %s\n", business.Address)
+		}
+		if business.City != "" {
+			text += fmt.Sprintf("City: %s\n", business.City)
+		}
+		if business.State != "" {
+			text += fmt.Sprintf("State: %s\n", business.State)
+		}
+		if business.ZipCode != "" {
+			text += fmt.Sprintf("ZIP: %s\n", business.ZipCode)
+		}
+		if business.Country != "" {
+			text += fmt.Sprintf("Country: %s\n", business.Country)
+		}
+		if business.TaxID != "" {
+			text += fmt.Sprintf("Tax ID: %s\n", business.TaxID)
+		}
+		if business.Website != "" {
+			text += fmt.Sprintf("Website: %s\n", business.Website)
+		}
+		if business.LogoPath != "" {
+			text += fmt.Sprintf("Logo: %s\n", business.LogoPath)
+		}
+		text += fmt.Sprintf("Invoice Prefix: %s\n", business.InvoicePrefix)
+		text += fmt.Sprintf("Last Updated: %s\n", business.UpdatedAt.Format("2006-01-02 15:04:05"))
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &getBusinessInfoResult{Configured: true, Business: business}, nil
+	})
+
+	// Set Payment Details tool
+	type setPaymentDetailsArgs struct {
+		ClientName    string `json:"client_name" jsonschema:"Client name"`
+		BankName      string `json:"bank_name,omitempty" jsonschema:"Bank name"`
+		AccountNumber string `json:"account_number,omitempty" jsonschema:"Account number"`
+		RoutingNumber string `json:"routing_number,omitempty" jsonschema:"Routing number"`
+		SwiftCode     string `json:"swift_code,omitempty" jsonschema:"SWIFT/BIC code"`
+		PaymentTerms  string `json:"payment_terms,omitempty" jsonschema:"Payment terms (e.g. Net 30)"`
+		Notes         string `json:"notes,omitempty" jsonschema:"Additional payment notes"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_payment_details",
+		Description: "Set payment details for a client",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setPaymentDetailsArgs) (*mcp.CallToolResult, *models.PaymentDetails, error) {
+		clientID, err := h.getClientIDByName(ctx, args.ClientName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("client not found: %w", err)
+		}
+
+		// Bank numbers are only stored encrypted
+		accountNumber, routingNumber, swiftCode, notes := args.AccountNumber, args.RoutingNumber, args.SwiftCode, args.Notes
+		if err := encryptFields(&accountNumber, &routingNumber, &swiftCode, &notes); err != nil {
+			return nil, nil, err
+		}
+
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO payment_details (client_id, bank_name, account_number, routing_number, swift_code, payment_terms, notes, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(client_id) DO UPDATE SET
+				bank_name = excluded.bank_name,
+				account_number = excluded.account_number,
+				routing_number = excluded.routing_number,
+				swift_code = excluded.swift_code,
+				payment_terms = excluded.payment_terms,
+				notes = excluded.notes,
+				updated_at = excluded.updated_at
+		`, clientID, args.BankName, accountNumber, routingNumber,
+			swiftCode, args.PaymentTerms, notes, time.Now())
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to set payment details: %w", err)
+		}
+		details, err := h.loadPaymentDetails(ctx, clientID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Bank numbers are masked in results, as when listing a client
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("Payment details updated for client '%s'", args.ClientName),
+				},
+			},
+		}, maskPaymentDetails(details), nil
+	})
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// cliOption is a flag of a subcommand, passed to its tool as the key argument
+type cliOption struct {
+	name  string
+	key   string
+	usage string
+	// number passes the value as a number instead of a string
+	number   bool
+	required bool
+	// def is passed when the flag isn't set, unless it is ""
+	def string
+}
+
+// cliCommand runs one tool from the command line
+type cliCommand struct {
+	name    string
+	tool    string
+	summary string
+	options []cliOption
+	// rest is the tool argument the words after the flags are joined into,
+	// "" when the command takes none
+	rest string
+}
+
+var (
+	clientOption = cliOption{name: "client", key: "client_name", usage: "client name"}
+	periodOption = cliOption{name: "period", key: "period", usage: "period, e.g. 'last week', 'last month' or 'January 2025'"}
+	startOption  = cliOption{name: "start", key: "start_date", usage: "start date (YYYY-MM-DD or natural language)"}
+	endOption    = cliOption{name: "end", key: "end_date", usage: "end date (YYYY-MM-DD or natural language)"}
+)
+
+var cliCommands = []cliCommand{
+	{
+		name: "add", tool: "add_hours", summary: "Log hours against a contract",
+		options: []cliOption{
+			{name: "contract", key: "contract_number", usage: "contract number", required: true},
+			{name: "hours", key: "hours", usage: "hours worked, e.g. 1.5, 1h30m or 1:30", required: true},
+			{name: "date", key: "date", usage: "date (YYYY-MM-DD or natural language; default: today)"},
+		},
+		rest: "description",
+	},
+	{
+		name: "list", tool: "list_hours", summary: "List time entries",
+		options: []cliOption{
+			clientOption, startOption, endOption,
+			{name: "group-by", key: "group_by", usage: "entry or description, to total the hours per task"},
+		},
+	},
+	{
+		name: "invoice", tool: "create_invoice", summary: "Invoice a client's unbilled work for a period",
+		options: []cliOption{
+			{name: "client", key: "client_name", usage: "client name", required: true},
+			{name: "period", key: "period", usage: "period to invoice", def: "last month"},
+			{name: "start", key: "start_date", usage: "first day to bill, overrides the start of the period"},
+			{name: "end", key: "end_date", usage: "last day to bill, overrides the end of the period"},
+			{name: "currency", key: "currency", usage: "only bill contracts in this currency"},
+			{name: "due-days", key: "due_days", usage: "days until due (default: 30)", number: true},
+		},
+	},
+	{
+		name: "report unbilled", tool: "unbilled_summary", summary: "Hours and amounts not yet invoiced",
+		options: []cliOption{clientOption},
+	},
+	{
+		name: "report recap", tool: "recap", summary: "Hours worked per client with descriptions",
+		options: []cliOption{periodOption, clientOption},
+	},
+	{
+		name: "report missing", tool: "find_missing_days", summary: "Working days with no hours logged",
+		options: []cliOption{startOption, endOption},
+	},
+	{
+		name: "report forecast", tool: "forecast", summary: "Projected revenue from the recent run rate",
+		options: []cliOption{clientOption, {name: "months", key: "months", usage: "months to project, 1-3", number: true}},
+	},
+	{
+		name: "report tax", tool: "tax_report", summary: "Net, tax and gross per tax rate for a filing period",
+		options: []cliOption{periodOption, {name: "basis", key: "basis", usage: "invoice or cash"}},
+	},
+}
+
+// RunCLI runs a subcommand such as "add" or "report unbilled" by calling its
+// tool on mcpServer over an in-memory connection, so it goes through the
+// same handlers, validation and audit log as a call from an MCP client. The
+// tool's text, or its structured result with -json, is written to stdout.
+func RunCLI(ctx context.Context, mcpServer *mcp.Server, args []string, stdout io.Writer) error {
+	if len(args) == 0 || args[0] == "help" {
+		fmt.Fprintln(stdout, cliUsage())
+		return nil
+	}
+	cmd, rest, err := findCLICommand(args)
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "%s\n\nUsage: hours-mcp %s [flags]", cmd.summary, cmd.name)
+		if cmd.rest != "" {
+			fmt.Fprintf(fs.Output(), " [%s]", cmd.rest)
+		}
+		fmt.Fprintf(fs.Output(), "\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	values := map[string]*string{}
+	for _, o := range cmd.options {
+		values[o.name] = fs.String(o.name, o.def, o.usage)
+	}
+	asJSON := fs.Bool("json", false, "print the structured result as JSON instead of text")
+	if err := fs.Parse(rest); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	toolArgs := map[string]any{}
+	for _, o := range cmd.options {
+		value := *values[o.name]
+		if value == "" {
+			if o.required {
+				return fmt.Errorf("%s: -%s is required", cmd.name, o.name)
+			}
+			continue
+		}
+		if !o.number {
+			toolArgs[o.key] = value
+			continue
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s: -%s must be a number", cmd.name, o.name)
+		}
+		toolArgs[o.key] = n
+	}
+	if words := strings.Join(fs.Args(), " "); words != "" {
+		if cmd.rest == "" {
+			return fmt.Errorf("%s: unexpected arguments %q", cmd.name, words)
+		}
+		toolArgs[cmd.rest] = words
+	}
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "hours-cli"}, nil)
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := mcpServer.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+	defer serverSession.Close()
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer session.Close()
+
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: cmd.tool, Arguments: toolArgs})
+	if err != nil {
+		return err
+	}
+
+	var text strings.Builder
+	for _, c := range result.Content {
+		if t, ok := c.(*mcp.TextContent); ok {
+			text.WriteString(t.Text)
+		}
+	}
+	if result.IsError {
+		return errors.New(text.String())
+	}
+	if *asJSON {
+		out, err := json.MarshalIndent(result.StructuredContent, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+		fmt.Fprintln(stdout, string(out))
+		return nil
+	}
+	fmt.Fprintln(stdout, strings.TrimRight(text.String(), "\n"))
+	return nil
+}
+
+// findCLICommand picks the command named by the first words of args and
+// returns it with the words after its name
+func findCLICommand(args []string) (*cliCommand, []string, error) {
+	name, rest := args[0], args[1:]
+	if name == "report" {
+		if len(rest) == 0 {
+			return nil, nil, errors.New("report: name a report\n\n" + cliUsage())
+		}
+		name, rest = "report "+rest[0], rest[1:]
+	}
+	for i := range cliCommands {
+		if cliCommands[i].name == name {
+			return &cliCommands[i], rest, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("unknown command %q\n\n%s", name, cliUsage())
+}
+
+// cliUsage lists the subcommands
+func cliUsage() string {
+	var b strings.Builder
+	b.WriteString("Usage: hours-mcp [flags] <command> [command flags]\n\nCommands (pass -h to one for its flags, -json for structured output):\n")
+	for _, cmd := range cliCommands {
+		fmt.Fprintf(&b, "  %-18s %s\n", cmd.name, cmd.summary)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
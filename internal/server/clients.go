@@ -0,0 +1,1173 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/austin/hours-mcp/internal/store"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// localePattern matches language tags like en, en-US or pt_BR
+var localePattern = regexp.MustCompile(`^([a-zA-Z]{2,3})(?:[-_]([a-zA-Z]{2}))?$`)
+
+// normalizeLocale validates a language tag and writes it as en-US; empty
+// clears it
+func normalizeLocale(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	m := localePattern.FindStringSubmatch(value)
+	if m == nil {
+		return "", validationError("invalid locale '%s': use a language tag like en-US or de", value)
+	}
+	locale := strings.ToLower(m[1])
+	if m[2] != "" {
+		locale += "-" + strings.ToUpper(m[2])
+	}
+	return locale, nil
+}
+
+// normalizeClientCurrency validates a client's default currency; empty
+// clears it
+func normalizeClientCurrency(value string) (string, error) {
+	currency := strings.ToUpper(strings.TrimSpace(value))
+	if currency == "" {
+		return "", nil
+	}
+	if err := validateCurrencyCode(currency); err != nil {
+		return "", validationError("invalid currency: %w", err)
+	}
+	return currency, nil
+}
+
+// mergeCustomFields applies changes to a client's stored custom fields,
+// removing fields set to an empty value, and returns the new JSON
+func mergeCustomFields(current string, changes map[string]string) (string, error) {
+	fields := map[string]string{}
+	if current != "" {
+		if err := json.Unmarshal([]byte(current), &fields); err != nil {
+			return "", fmt.Errorf("failed to read custom fields: %w", err)
+		}
+	}
+	for key, value := range changes {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return "", validationError("custom field names cannot be empty")
+		}
+		if value == "" {
+			delete(fields, key)
+		} else {
+			fields[key] = value
+		}
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to save custom fields: %w", err)
+	}
+	return string(data), nil
+}
+
+// loadClient returns a client's full record
+func (h *Handler) loadClient(ctx context.Context, clientID int) (*models.Client, error) {
+	c := &models.Client{}
+	var customFields string
+	err := h.db.QueryRowContext(ctx, `
+		SELECT id, name, COALESCE(address, ''), COALESCE(city, ''), COALESCE(state, ''), COALESCE(zip_code, ''),
+		       COALESCE(country, ''), COALESCE(tax_id, ''), COALESCE(tax_treatment, 'standard'), COALESCE(withholding_rate, 0),
+		       notes, default_currency, locale, time_zone, custom_fields, delivery_method, delivery_note,
+		       invoice_format, invoice_detail, billing_cycle, archived_at, created_at, updated_at
+		FROM clients WHERE id = ?
+	`, clientID).Scan(&c.ID, &c.Name, &c.Address, &c.City, &c.State, &c.ZipCode, &c.Country, &c.TaxID,
+		&c.TaxTreatment, &c.WithholdingRate, &c.Notes, &c.DefaultCurrency, &c.Locale, &c.TimeZone, &customFields,
+		&c.DeliveryMethod, &c.DeliveryNote, &c.InvoiceFormat, &c.InvoiceDetail, &c.BillingCycle, &c.ArchivedAt, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client: %w", err)
+	}
+	if err := json.Unmarshal([]byte(customFields), &c.CustomFields); err != nil {
+		return nil, fmt.Errorf("failed to read custom fields: %w", err)
+	}
+	return c, nil
+}
+
+// clientText describes a client's record for tool output
+func clientText(c *models.Client) string {
+	text := fmt.Sprintf("Client: %s", c.Name)
+	if c.ArchivedAt != nil {
+		text += " [archived]"
+	}
+	text += "\n"
+
+	address := strings.Join(nonEmpty(c.Address, c.City, c.State, c.ZipCode, c.Country), ", ")
+	if address != "" {
+		text += fmt.Sprintf("Address: %s\n", address)
+	}
+	if c.TaxID != "" {
+		text += fmt.Sprintf("Tax ID: %s\n", c.TaxID)
+	}
+	text += fmt.Sprintf("Tax treatment: %s", c.TaxTreatment)
+	if c.WithholdingRate > 0 {
+		text += fmt.Sprintf(", withholding %g%%", c.WithholdingRate)
+	}
+	text += "\n"
+	if c.DefaultCurrency != "" {
+		text += fmt.Sprintf("Default currency: %s\n", c.DefaultCurrency)
+	}
+	if c.Locale != "" {
+		text += fmt.Sprintf("Locale: %s\n", c.Locale)
+	}
+	if c.TimeZone != "" {
+		text += fmt.Sprintf("Time zone: %s\n", c.TimeZone)
+	}
+	if c.DeliveryMethod != "" {
+		text += fmt.Sprintf("Invoices delivered %s\n", deliveryText(c))
+	} else if c.DeliveryNote != "" {
+		text += fmt.Sprintf("Delivery note: %s\n", c.DeliveryNote)
+	}
+	if c.InvoiceFormat != "" || c.InvoiceDetail != "" {
+		text += fmt.Sprintf("Invoice preferences: %s\n", strings.Join(nonEmpty(c.InvoiceFormat, c.InvoiceDetail), ", "))
+	}
+	if cycle, ok := parseBillingCycle(c.BillingCycle); ok {
+		text += fmt.Sprintf("Billed %s\n", cycle.describe())
+	}
+	if c.Notes != "" {
+		text += fmt.Sprintf("Notes: %s\n", c.Notes)
+	}
+	if len(c.CustomFields) > 0 {
+		keys := make([]string, 0, len(c.CustomFields))
+		for key := range c.CustomFields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		text += "Custom fields:\n"
+		for _, key := range keys {
+			text += fmt.Sprintf("- %s: %s\n", key, c.CustomFields[key])
+		}
+	}
+	return text
+}
+
+// clientContract is an active contract in a client's details, at the rate
+// in effect today
+type clientContract struct {
+	ContractNumber string      `json:"contract_number"`
+	Name           string      `json:"name"`
+	ContractType   string      `json:"contract_type"`
+	HourlyRate     money.Cents `json:"hourly_rate"`
+	Currency       string      `json:"currency"`
+	EndDate        *time.Time  `json:"end_date,omitempty"`
+}
+
+// clientInvoice is an invoice in a client's details that is not yet paid,
+// or in a contract's details
+type clientInvoice struct {
+	InvoiceNumber string      `json:"invoice_number"`
+	IssueDate     time.Time   `json:"issue_date"`
+	DueDate       time.Time   `json:"due_date"`
+	Total         money.Cents `json:"total"`
+	Currency      string      `json:"currency"`
+	Status        string      `json:"status"`
+}
+
+// clientDetails is everything about one client
+type clientDetails struct {
+	Client          *models.Client         `json:"client"`
+	Recipients      []models.Recipient     `json:"recipients"`
+	PaymentDetails  *models.PaymentDetails `json:"payment_details,omitempty"`
+	PaymentMethod   string                 `json:"payment_method,omitempty" jsonschema:"Payment method the client's invoices ask to be paid into"`
+	Contracts       []clientContract       `json:"active_contracts"`
+	UnbilledHours   float64                `json:"unbilled_hours"`
+	Unbilled        map[string]money.Cents `json:"unbilled"`
+	OpenInvoices    []clientInvoice        `json:"open_invoices"`
+	LastInvoiceDate *time.Time             `json:"last_invoice_date,omitempty"`
+}
+
+// loadClientDetails gathers a client's record with its recipients, payment
+// details, active contracts, unbilled work and open invoices
+func (h *Handler) loadClientDetails(ctx context.Context, clientID int) (*clientDetails, error) {
+	client, err := h.loadClient(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	d := &clientDetails{Client: client, Unbilled: map[string]money.Cents{}}
+
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT id, name, email, COALESCE(title, ''), COALESCE(phone, ''), is_primary
+		FROM recipients WHERE client_id = ?
+		ORDER BY is_primary DESC, name
+	`, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recipients: %w", err)
+	}
+	for rows.Next() {
+		r := models.Recipient{ClientID: clientID}
+		if err := rows.Scan(&r.ID, &r.Name, &r.Email, &r.Title, &r.Phone, &r.IsPrimary); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan recipient: %w", err)
+		}
+		d.Recipients = append(d.Recipients, r)
+	}
+	rows.Close()
+
+	if d.PaymentDetails, err = h.loadPaymentDetails(ctx, clientID); err != nil {
+		return nil, err
+	}
+	if err := h.db.QueryRowContext(ctx, `
+		SELECT COALESCE(m.name, '') FROM clients c LEFT JOIN payment_methods m ON m.id = c.payment_method_id WHERE c.id = ?
+	`, clientID).Scan(&d.PaymentMethod); err != nil {
+		return nil, fmt.Errorf("failed to find payment method: %w", err)
+	}
+
+	rows, err = h.db.QueryContext(ctx, `
+		SELECT c.contract_number, c.name, c.contract_type, `+effectiveRateSQL("c", "date('now', 'localtime')")+`, c.currency, c.end_date
+		FROM contracts c
+		WHERE c.client_id = ? AND c.status = 'active'
+		ORDER BY c.start_date DESC, c.contract_number
+	`, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contracts: %w", err)
+	}
+	for rows.Next() {
+		var c clientContract
+		var endDate sql.NullTime
+		if err := rows.Scan(&c.ContractNumber, &c.Name, &c.ContractType, &c.HourlyRate, &c.Currency, &endDate); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan contract: %w", err)
+		}
+		if endDate.Valid {
+			c.EndDate = &endDate.Time
+		}
+		d.Contracts = append(d.Contracts, c)
+	}
+	rows.Close()
+
+	// Unbilled hours are priced as they would be invoiced
+	items, err := h.priceStoredEntries(ctx, "te.invoice_id IS NULL AND te.billable AND ct.client_id = ?", clientID)
+	if err != nil {
+		return nil, err
+	}
+	currencies := map[int]string{}
+	for _, item := range items {
+		currency, ok := currencies[item.ContractID]
+		if !ok {
+			if err := h.db.QueryRowContext(ctx, "SELECT currency FROM contracts WHERE id = ?", item.ContractID).Scan(&currency); err != nil {
+				return nil, fmt.Errorf("failed to find contract: %w", err)
+			}
+			currencies[item.ContractID] = currency
+		}
+		d.UnbilledHours += item.Hours
+		d.Unbilled[currency] += item.Amount
+	}
+
+	rows, err = h.db.QueryContext(ctx, `
+		SELECT invoice_number, issue_date, due_date, total_cents, currency, status
+		FROM invoices
+		WHERE client_id = ? AND status NOT IN ('paid', 'written_off', 'cancelled')
+		ORDER BY issue_date
+	`, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invoices: %w", err)
+	}
+	for rows.Next() {
+		var inv clientInvoice
+		if err := rows.Scan(&inv.InvoiceNumber, &inv.IssueDate, &inv.DueDate, &inv.Total, &inv.Currency, &inv.Status); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan invoice: %w", err)
+		}
+		d.OpenInvoices = append(d.OpenInvoices, inv)
+	}
+	rows.Close()
+
+	var last sql.NullString
+	if err := h.db.QueryRowContext(ctx, "SELECT MAX(issue_date) FROM invoices WHERE client_id = ?", clientID).Scan(&last); err != nil {
+		return nil, fmt.Errorf("failed to find last invoice: %w", err)
+	}
+	if last.Valid {
+		// Aggregates lose the DATE column type, so parse the stored text
+		if t, err := time.Parse("2006-01-02", last.String[:min(10, len(last.String))]); err == nil {
+			d.LastInvoiceDate = &t
+		}
+	}
+
+	return d, nil
+}
+
+// clientDetailsText describes everything about a client for tool output
+func clientDetailsText(d *clientDetails) string {
+	text := clientText(d.Client)
+
+	text += "\nRecipients:\n"
+	for _, r := range d.Recipients {
+		text += fmt.Sprintf("- %s <%s>", r.Name, r.Email)
+		if r.Title != "" {
+			text += ", " + r.Title
+		}
+		if r.IsPrimary {
+			text += " (primary)"
+		}
+		text += "\n"
+	}
+	if len(d.Recipients) == 0 {
+		text += "- none\n"
+	}
+
+	if d.PaymentMethod != "" {
+		text += fmt.Sprintf("\nPaid into: %s\n", d.PaymentMethod)
+	}
+	if p := d.PaymentDetails; p != nil {
+		text += "\nPayment details:\n"
+		for _, field := range []struct{ label, value string }{
+			{"Bank", p.BankName}, {"Account", p.AccountNumber}, {"Routing", p.RoutingNumber},
+			{"SWIFT", p.SwiftCode}, {"Terms", p.PaymentTerms}, {"Notes", p.Notes},
+		} {
+			if field.value != "" {
+				text += fmt.Sprintf("- %s: %s\n", field.label, field.value)
+			}
+		}
+	}
+
+	text += "\nActive contracts:\n"
+	for _, c := range d.Contracts {
+		text += fmt.Sprintf("- %s: %s (%s) at %s/hour", c.ContractNumber, c.Name, c.ContractType, c.HourlyRate.Format(c.Currency))
+		if c.EndDate != nil {
+			text += fmt.Sprintf(", ends %s", c.EndDate.Format("2006-01-02"))
+		}
+		text += "\n"
+	}
+	if len(d.Contracts) == 0 {
+		text += "- none\n"
+	}
+
+	text += fmt.Sprintf("\nUnbilled: %.2f hours", d.UnbilledHours)
+	if len(d.Unbilled) > 0 {
+		text += " = " + formatCurrencyTotals(d.Unbilled)
+	}
+	text += "\n"
+
+	text += "\nOpen invoices:\n"
+	for _, inv := range d.OpenInvoices {
+		text += fmt.Sprintf("- %s: %s [%s] issued %s, due %s\n", inv.InvoiceNumber, inv.Total.Format(inv.Currency), inv.Status,
+			inv.IssueDate.Format("2006-01-02"), inv.DueDate.Format("2006-01-02"))
+	}
+	if len(d.OpenInvoices) == 0 {
+		text += "- none\n"
+	}
+
+	if d.LastInvoiceDate != nil {
+		text += fmt.Sprintf("\nLast invoiced: %s\n", d.LastInvoiceDate.Format("2006-01-02"))
+	} else {
+		text += "\nNever invoiced\n"
+	}
+	return text
+}
+
+// nonEmpty returns the values that are not empty
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// legalSuffixes are ignored when matching client names, so "Acme" finds
+// "Acme Corp." and "ACME, Inc."
+var legalSuffixes = map[string]bool{
+	"inc": true, "incorporated": true, "corp": true, "corporation": true, "co": true, "company": true,
+	"llc": true, "llp": true, "ltd": true, "limited": true, "plc": true, "gmbh": true, "ag": true,
+	"sa": true, "sarl": true, "bv": true, "nv": true, "pty": true, "oy": true, "ab": true, "as": true,
+}
+
+// normalizeClientName lowercases a name, drops punctuation and legal suffixes
+// and collapses whitespace
+func normalizeClientName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for len(words) > 1 && legalSuffixes[words[len(words)-1]] {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// clientName is a name or alias a client can be found by
+type clientName struct {
+	clientID int
+	client   string
+	name     string
+}
+
+// resolveClient finds a client by a name that is not an exact match: a
+// case-insensitive name or alias, the same name without punctuation and
+// legal suffixes, or a unique partial match. Otherwise the error suggests
+// the closest names.
+func (h *Handler) resolveClient(ctx context.Context, name string) (int, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT id, name, name FROM clients
+		UNION ALL
+		SELECT a.client_id, c.name, a.alias FROM client_aliases a JOIN clients c ON a.client_id = c.id
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load clients: %w", err)
+	}
+	defer rows.Close()
+
+	var names []clientName
+	for rows.Next() {
+		var n clientName
+		if err := rows.Scan(&n.clientID, &n.client, &n.name); err != nil {
+			return 0, fmt.Errorf("failed to scan client: %w", err)
+		}
+		names = append(names, n)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// unique returns the one client the matching names belong to
+	unique := func(match func(clientName) bool) (int, []string) {
+		ids := map[int]bool{}
+		var clients []string
+		for _, n := range names {
+			if match(n) && !ids[n.clientID] {
+				ids[n.clientID] = true
+				clients = append(clients, n.client)
+			}
+		}
+		if len(clients) == 1 {
+			for id := range ids {
+				return id, nil
+			}
+		}
+		return 0, clients
+	}
+
+	wanted := normalizeClientName(name)
+	stages := []func(clientName) bool{
+		func(n clientName) bool { return strings.EqualFold(n.name, strings.TrimSpace(name)) },
+		func(n clientName) bool { return wanted != "" && normalizeClientName(n.name) == wanted },
+		func(n clientName) bool {
+			return wanted != "" && strings.Contains(" "+normalizeClientName(n.name)+" ", " "+wanted+" ")
+		},
+	}
+	for _, stage := range stages {
+		id, ambiguous := unique(stage)
+		if id != 0 {
+			return id, nil
+		}
+		if len(ambiguous) > 1 {
+			sort.Strings(ambiguous)
+			return 0, validationError("client '%s' matches several clients: %s; use the full name", name, strings.Join(ambiguous, ", "))
+		}
+	}
+
+	// Suggest clients whose names are a few edits away
+	type suggestion struct {
+		client   string
+		distance int
+	}
+	best := map[string]int{}
+	for _, n := range names {
+		candidate := normalizeClientName(n.name)
+		distance := editDistance(wanted, candidate)
+		if distance > max(2, len([]rune(candidate))/3) && !strings.HasPrefix(candidate, wanted) {
+			continue
+		}
+		if d, ok := best[n.client]; !ok || distance < d {
+			best[n.client] = distance
+		}
+	}
+	var suggestions []suggestion
+	for client, distance := range best {
+		suggestions = append(suggestions, suggestion{client, distance})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].distance != suggestions[j].distance {
+			return suggestions[i].distance < suggestions[j].distance
+		}
+		return suggestions[i].client < suggestions[j].client
+	})
+	if len(suggestions) == 0 {
+		return 0, notFoundError("client", "client '%s' not found", name)
+	}
+	var quoted []string
+	for _, s := range suggestions[:min(3, len(suggestions))] {
+		quoted = append(quoted, "'"+s.client+"'")
+	}
+	return 0, notFoundError("client", "client '%s' not found; did you mean %s?", name, strings.Join(quoted, " or "))
+}
+
+// checkClientActive refuses new work for archived clients
+func (h *Handler) checkClientActive(ctx context.Context, clientID int) error {
+	var name string
+	var archived bool
+	err := h.db.QueryRowContext(ctx, "SELECT name, archived_at IS NOT NULL FROM clients WHERE id = ?", clientID).Scan(&name, &archived)
+	if err != nil {
+		return fmt.Errorf("failed to find client: %w", err)
+	}
+	if archived {
+		return conflictError("client '%s' is archived; use unarchive_client first", name)
+	}
+	return nil
+}
+
+// clientData counts the records belonging to a client, by kind, in the
+// order they are reported
+var clientData = []struct {
+	label string
+	query string
+}{
+	{"contracts", "SELECT COUNT(*) FROM contracts WHERE client_id = ?"},
+	{"time entries", "SELECT COUNT(*) FROM time_entries WHERE client_id = ?"},
+	{"expenses", "SELECT COUNT(*) FROM expenses WHERE contract_id IN (SELECT id FROM contracts WHERE client_id = ?)"},
+	{"invoices", "SELECT COUNT(*) FROM invoices WHERE client_id = ?"},
+	{"recipients", "SELECT COUNT(*) FROM recipients WHERE client_id = ?"},
+	{"email templates", "SELECT COUNT(*) FROM email_templates WHERE client_id = ?"},
+	{"aliases", "SELECT COUNT(*) FROM client_aliases WHERE client_id = ?"},
+}
+
+// clientDeletes removes everything belonging to a client, dependents first.
+// Most of these would cascade from the client, but time_entries.contract_id
+// has no foreign key and invoice_lines must go before their contracts.
+var clientDeletes = []string{
+	"DELETE FROM invoice_lines WHERE invoice_id IN (SELECT id FROM invoices WHERE client_id = ?)",
+	"UPDATE email_log SET invoice_id = NULL WHERE invoice_id IN (SELECT id FROM invoices WHERE client_id = ?)",
+	"DELETE FROM invoice_notes WHERE invoice_id IN (SELECT id FROM invoices WHERE client_id = ?)",
+	"DELETE FROM expenses WHERE contract_id IN (SELECT id FROM contracts WHERE client_id = ?)",
+	"DELETE FROM contract_rates WHERE contract_id IN (SELECT id FROM contracts WHERE client_id = ?)",
+	"DELETE FROM contract_rate_rules WHERE contract_id IN (SELECT id FROM contracts WHERE client_id = ?)",
+	"DELETE FROM contract_documents WHERE contract_id IN (SELECT id FROM contracts WHERE client_id = ?)",
+	"DELETE FROM contract_services WHERE contract_id IN (SELECT id FROM contracts WHERE client_id = ?)",
+	"DELETE FROM timers WHERE contract_id IN (SELECT id FROM contracts WHERE client_id = ?)",
+	"DELETE FROM time_entries WHERE client_id = ?",
+	"DELETE FROM invoices WHERE client_id = ?",
+	"DELETE FROM contracts WHERE client_id = ?",
+	"DELETE FROM recipients WHERE client_id = ?",
+	"DELETE FROM payment_details WHERE client_id = ?",
+	"DELETE FROM email_templates WHERE client_id = ?",
+	"DELETE FROM client_aliases WHERE client_id = ?",
+	"DELETE FROM clients WHERE id = ?",
+}
+
+// registerClientTools registers tools to show client details, manage client
+// aliases and archive and delete clients
+func registerClientTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Add Client tool
+	type addClientArgs struct {
+		Name    string `json:"name" jsonschema:"Client name"`
+		Address string `json:"address,omitempty" jsonschema:"Street address"`
+		City    string `json:"city,omitempty" jsonschema:"City"`
+		State   string `json:"state,omitempty" jsonschema:"State or province"`
+		ZipCode string `json:"zip_code,omitempty" jsonschema:"ZIP or postal code"`
+		Country string `json:"country,omitempty" jsonschema:"Country"`
+
+		TaxID           string  `json:"tax_id,omitempty" jsonschema:"Client VAT/tax ID, printed on invoices"`
+		TaxTreatment    string  `json:"tax_treatment,omitempty" jsonschema:"standard (default) or reverse_charge for cross-border B2B clients that account for VAT themselves"`
+		WithholdingRate float64 `json:"withholding_rate,omitempty" jsonschema:"Percentage of the net amount the client withholds as tax (default: 0)"`
+
+		Notes           string            `json:"notes,omitempty" jsonschema:"Free-form notes about the client"`
+		DefaultCurrency string            `json:"default_currency,omitempty" jsonschema:"Currency new contracts for the client use by default (e.g. EUR)"`
+		Locale          string            `json:"locale,omitempty" jsonschema:"Preferred language/locale (e.g. en-US, de-DE)"`
+		TimeZone        string            `json:"time_zone,omitempty" jsonschema:"Time zone work for the client is logged in, e.g. Europe/Berlin (default: the time_zone setting)"`
+		CustomFields    map[string]string `json:"custom_fields,omitempty" jsonschema:"Custom fields, e.g. {\"vendor_number\": \"V-1234\"}"`
+
+		DeliveryMethod string `json:"delivery_method,omitempty" jsonschema:"How the client wants invoices delivered: email, portal, post, hand, paypal or other"`
+		DeliveryNote   string `json:"delivery_note,omitempty" jsonschema:"Where or how to deliver, e.g. the portal URL or a PO to quote"`
+		InvoiceFormat  string `json:"invoice_format,omitempty" jsonschema:"Files create_invoice writes for the client: pdf, pdf_a, pdf_json or pdf_a_json (default: per the pdf_archival and invoice_json_sidecar settings)"`
+		InvoiceDetail  string `json:"invoice_detail,omitempty" jsonschema:"detailed (default) lists every entry, consolidated one line per contract and rate"`
+
+		BillingCycle string `json:"billing_cycle,omitempty" jsonschema:"How often the client is invoiced: monthly, weekly or biweekly; due_for_billing lists the clients it has come due for (optional)"`
+		BillingDay   string `json:"billing_day,omitempty" jsonschema:"Day of the month monthly clients are billed on, e.g. 15 (default: 1), or a date weekly and biweekly clients are billed on, e.g. 'friday' (default: today)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "add_client",
+		Description: "Add a new client (note: rates are now managed through contracts)",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args addClientArgs) (*mcp.CallToolResult, *models.Client, error) {
+		if args.TaxTreatment == "" {
+			args.TaxTreatment = taxTreatmentStandard
+		}
+		if err := validateTaxTreatment(args.TaxTreatment); err != nil {
+			return nil, nil, err
+		}
+		if err := validateWithholdingRate(args.WithholdingRate); err != nil {
+			return nil, nil, err
+		}
+		currency, err := normalizeClientCurrency(args.DefaultCurrency)
+		if err != nil {
+			return nil, nil, err
+		}
+		locale, err := normalizeLocale(args.Locale)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := validateTimeZone(args.TimeZone); err != nil {
+			return nil, nil, err
+		}
+		customFields, err := mergeCustomFields("{}", args.CustomFields)
+		if err != nil {
+			return nil, nil, err
+		}
+		deliveryMethod, err := normalizeDeliveryMethod(args.DeliveryMethod)
+		if err != nil {
+			return nil, nil, err
+		}
+		invoiceFormat, err := normalizeInvoiceFormat(args.InvoiceFormat)
+		if err != nil {
+			return nil, nil, err
+		}
+		invoiceDetail, err := normalizeInvoiceDetail(args.InvoiceDetail)
+		if err != nil {
+			return nil, nil, err
+		}
+		billingCycle, err := h.normalizeBillingCycle(ctx, args.BillingCycle, args.BillingDay)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		id, err := h.store.Clients.Create(ctx, &models.Client{
+			Name: args.Name, Address: args.Address, City: args.City, State: args.State, ZipCode: args.ZipCode,
+			Country: args.Country, TaxID: args.TaxID, TaxTreatment: args.TaxTreatment, WithholdingRate: args.WithholdingRate,
+			Notes: args.Notes, DefaultCurrency: currency, Locale: locale, TimeZone: args.TimeZone,
+			DeliveryMethod: deliveryMethod, DeliveryNote: args.DeliveryNote, InvoiceFormat: invoiceFormat, InvoiceDetail: invoiceDetail,
+			BillingCycle: billingCycle,
+		}, customFields)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to add client: %w", err)
+		}
+		client, err := h.loadClient(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("Client '%s' added successfully (ID: %d)", args.Name, id),
+				},
+			},
+		}, client, nil
+	})
+
+	// List Clients tool
+	type listClientsArgs struct {
+		IncludeArchived bool `json:"include_archived,omitempty" jsonschema:"Also list archived clients"`
+	}
+
+	type listClientsResult struct {
+		Clients []models.Client `json:"clients"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_clients",
+		Description: "List all clients that are not archived",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listClientsArgs) (*mcp.CallToolResult, *listClientsResult, error) {
+		clients, err := h.store.Clients.List(ctx, args.IncludeArchived)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list clients: %w", err)
+		}
+		aliases, err := h.store.Clients.Aliases(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list client aliases: %w", err)
+		}
+		contractCounts, err := h.store.Contracts.ActiveCounts(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to count contracts: %w", err)
+		}
+
+		text := fmt.Sprintf("Found %d clients:\n", len(clients))
+		for _, c := range clients {
+			text += fmt.Sprintf("- %s (%d active contracts)", c.Name, contractCounts[c.ID])
+			if len(aliases[c.ID]) > 0 {
+				text += fmt.Sprintf(" aka %s", strings.Join(aliases[c.ID], ", "))
+			}
+			if c.TaxTreatment == taxTreatmentReverseCharge {
+				text += " [reverse charge]"
+			}
+			if c.WithholdingRate > 0 {
+				text += fmt.Sprintf(" [withholding %g%%]", c.WithholdingRate)
+			}
+			if c.ArchivedAt != nil {
+				text += " [archived]"
+			}
+			text += "\n"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &listClientsResult{Clients: clients}, nil
+	})
+
+	// Edit Client tool
+	type editClientArgs struct {
+		Name    string `json:"name" jsonschema:"Current client name"`
+		NewName string `json:"new_name,omitempty" jsonschema:"New client name (optional)"`
+		Address string `json:"address,omitempty" jsonschema:"New street address (optional)"`
+		City    string `json:"city,omitempty" jsonschema:"New city (optional)"`
+		State   string `json:"state,omitempty" jsonschema:"New state or province (optional)"`
+		ZipCode string `json:"zip_code,omitempty" jsonschema:"New ZIP or postal code (optional)"`
+		Country string `json:"country,omitempty" jsonschema:"New country (optional)"`
+
+		TaxID           string   `json:"tax_id,omitempty" jsonschema:"New client VAT/tax ID (optional)"`
+		TaxTreatment    string   `json:"tax_treatment,omitempty" jsonschema:"New tax treatment: standard or reverse_charge (optional)"`
+		WithholdingRate *float64 `json:"withholding_rate,omitempty" jsonschema:"New withholding percentage, 0 to stop withholding (optional)"`
+
+		Notes           *string           `json:"notes,omitempty" jsonschema:"New notes, empty to clear (optional)"`
+		DefaultCurrency *string           `json:"default_currency,omitempty" jsonschema:"New default currency for contracts, empty to clear (optional)"`
+		Locale          *string           `json:"locale,omitempty" jsonschema:"New preferred language/locale, empty to clear (optional)"`
+		TimeZone        *string           `json:"time_zone,omitempty" jsonschema:"New time zone, e.g. Europe/Berlin, empty to use the time_zone setting (optional)"`
+		CustomFields    map[string]string `json:"custom_fields,omitempty" jsonschema:"Custom fields to set; an empty value removes the field (optional)"`
+		PaymentMethod   *string           `json:"payment_method,omitempty" jsonschema:"Payment method its invoices ask to be paid into, empty for none (optional; see list_payment_methods)"`
+
+		DeliveryMethod *string `json:"delivery_method,omitempty" jsonschema:"How the client wants invoices delivered: email, portal, post, hand, paypal or other; empty to clear (optional)"`
+		DeliveryNote   *string `json:"delivery_note,omitempty" jsonschema:"Where or how to deliver, e.g. the portal URL or a PO to quote; empty to clear (optional)"`
+		InvoiceFormat  *string `json:"invoice_format,omitempty" jsonschema:"Files create_invoice writes for the client: pdf, pdf_a, pdf_json or pdf_a_json; empty to use the settings (optional)"`
+		InvoiceDetail  *string `json:"invoice_detail,omitempty" jsonschema:"detailed lists every entry, consolidated one line per contract and rate; empty for detailed (optional)"`
+
+		BillingCycle *string `json:"billing_cycle,omitempty" jsonschema:"How often the client is invoiced: monthly, weekly or biweekly; empty to clear (optional)"`
+		BillingDay   string  `json:"billing_day,omitempty" jsonschema:"Day of the month monthly clients are billed on, e.g. 15, or a date weekly and biweekly clients are billed on, e.g. 'friday'; on its own it moves the current cycle (optional)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "edit_client",
+		Description: "Edit an existing client's information",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args editClientArgs) (*mcp.CallToolResult, *models.Client, error) {
+		// Get current client ID
+		clientID, err := h.getClientIDByName(ctx, args.Name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find client: %w", err)
+		}
+
+		// Text fields left empty are not changed
+		optional := func(value string) *string {
+			if value == "" {
+				return nil
+			}
+			return &value
+		}
+		changes := store.ClientChanges{
+			Name:    optional(args.NewName),
+			Address: optional(args.Address),
+			City:    optional(args.City),
+			State:   optional(args.State),
+			ZipCode: optional(args.ZipCode),
+			Country: optional(args.Country),
+			TaxID:   optional(args.TaxID),
+			Notes:   args.Notes,
+		}
+		if args.TaxTreatment != "" {
+			if err := validateTaxTreatment(args.TaxTreatment); err != nil {
+				return nil, nil, err
+			}
+			changes.TaxTreatment = &args.TaxTreatment
+		}
+		if args.WithholdingRate != nil {
+			if err := validateWithholdingRate(*args.WithholdingRate); err != nil {
+				return nil, nil, err
+			}
+			changes.WithholdingRate = args.WithholdingRate
+		}
+		if args.DefaultCurrency != nil {
+			currency, err := normalizeClientCurrency(*args.DefaultCurrency)
+			if err != nil {
+				return nil, nil, err
+			}
+			changes.DefaultCurrency = &currency
+		}
+		if args.Locale != nil {
+			locale, err := normalizeLocale(*args.Locale)
+			if err != nil {
+				return nil, nil, err
+			}
+			changes.Locale = &locale
+		}
+		if args.TimeZone != nil {
+			if err := validateTimeZone(*args.TimeZone); err != nil {
+				return nil, nil, err
+			}
+			changes.TimeZone = args.TimeZone
+		}
+		if args.PaymentMethod != nil {
+			methodID, err := h.paymentMethodID(ctx, *args.PaymentMethod)
+			if err != nil {
+				return nil, nil, err
+			}
+			changes.PaymentMethodID = &methodID
+		}
+		if args.DeliveryMethod != nil {
+			method, err := normalizeDeliveryMethod(*args.DeliveryMethod)
+			if err != nil {
+				return nil, nil, err
+			}
+			changes.DeliveryMethod = &method
+		}
+		changes.DeliveryNote = args.DeliveryNote
+		if args.InvoiceFormat != nil {
+			format, err := normalizeInvoiceFormat(*args.InvoiceFormat)
+			if err != nil {
+				return nil, nil, err
+			}
+			changes.InvoiceFormat = &format
+		}
+		if args.InvoiceDetail != nil {
+			detail, err := normalizeInvoiceDetail(*args.InvoiceDetail)
+			if err != nil {
+				return nil, nil, err
+			}
+			changes.InvoiceDetail = &detail
+		}
+		if args.BillingCycle != nil || args.BillingDay != "" {
+			var kind string
+			if args.BillingCycle != nil {
+				kind = *args.BillingCycle
+			} else {
+				current, err := h.loadClient(ctx, clientID)
+				if err != nil {
+					return nil, nil, err
+				}
+				cycle, ok := parseBillingCycle(current.BillingCycle)
+				if !ok {
+					return nil, nil, validationError("%s has no billing cycle; pass billing_cycle with billing_day", current.Name)
+				}
+				kind = cycle.kind
+			}
+			cycle, err := h.normalizeBillingCycle(ctx, kind, args.BillingDay)
+			if err != nil {
+				return nil, nil, err
+			}
+			changes.BillingCycle = &cycle
+		}
+		if len(args.CustomFields) > 0 {
+			current, err := h.store.Clients.CustomFields(ctx, clientID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to load custom fields: %w", err)
+			}
+			customFields, err := mergeCustomFields(current, args.CustomFields)
+			if err != nil {
+				return nil, nil, err
+			}
+			changes.CustomFields = &customFields
+		}
+
+		if err := h.store.Clients.Update(ctx, clientID, changes); err == store.ErrNoChanges {
+			return nil, nil, err
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to update client: %w", err)
+		}
+
+		client, err := h.loadClient(ctx, clientID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Successfully updated client: %s", client.Name)},
+			},
+		}, client, nil
+	})
+
+	// Get Client Details tool
+	type getClientDetailsArgs struct {
+		Name        string `json:"name" jsonschema:"Client name or alias"`
+		ShowBanking bool   `json:"show_banking,omitempty" jsonschema:"Show full account and routing numbers and payment notes (default: false, only the last 4 digits)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_client_details",
+		Description: "Show everything about a client in one call: address, tax settings, notes and custom fields, recipients, payment details, active contracts with rates, unbilled work, open invoices and the last invoice date",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getClientDetailsArgs) (*mcp.CallToolResult, *clientDetails, error) {
+		clientID, err := h.getClientIDByName(ctx, args.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		d, err := h.loadClientDetails(ctx, clientID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !args.ShowBanking {
+			d.PaymentDetails = maskPaymentDetails(d.PaymentDetails)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: clientDetailsText(d)},
+			},
+		}, d, nil
+	})
+
+	// Add Client Alias tool
+	type addClientAliasArgs struct {
+		ClientName string `json:"client_name" jsonschema:"Client name"`
+		Alias      string `json:"alias" jsonschema:"Other name the client can be referred to by, e.g. an abbreviation or former name"`
+	}
+
+	type clientAliasResult struct {
+		Alias      string `json:"alias"`
+		ClientName string `json:"client_name"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "add_client_alias",
+		Description: "Add another name a client can be referred to by in every tool",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args addClientAliasArgs) (*mcp.CallToolResult, *clientAliasResult, error) {
+		alias := strings.TrimSpace(args.Alias)
+		if alias == "" {
+			return nil, nil, validationError("alias is required")
+		}
+		clientID, err := h.getClientIDByName(ctx, args.ClientName)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// An alias must not shadow another client's name or alias
+		var owner string
+		err = db.QueryRowContext(ctx, `
+			SELECT name FROM clients WHERE name = ? COLLATE NOCASE AND id != ?
+			UNION ALL
+			SELECT c.name FROM client_aliases a JOIN clients c ON a.client_id = c.id WHERE a.alias = ?
+		`, alias, clientID, alias).Scan(&owner)
+		if err == nil {
+			return nil, nil, conflictError("'%s' already refers to client '%s'", alias, owner)
+		}
+		if err != sql.ErrNoRows {
+			return nil, nil, fmt.Errorf("failed to check alias: %w", err)
+		}
+
+		if _, err := db.ExecContext(ctx, "INSERT INTO client_aliases (alias, client_id) VALUES (?, ?)", alias, clientID); err != nil {
+			return nil, nil, fmt.Errorf("failed to add alias: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("'%s' now refers to client '%s'", alias, args.ClientName)},
+			},
+		}, &clientAliasResult{Alias: alias, ClientName: args.ClientName}, nil
+	})
+
+	// Remove Client Alias tool
+	type removeClientAliasArgs struct {
+		Alias string `json:"alias" jsonschema:"Alias to remove"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "remove_client_alias",
+		Description: "Remove a client alias",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args removeClientAliasArgs) (*mcp.CallToolResult, *clientAliasResult, error) {
+		alias := strings.TrimSpace(args.Alias)
+		var clientName string
+		err := db.QueryRowContext(ctx, "SELECT c.name FROM client_aliases a JOIN clients c ON a.client_id = c.id WHERE a.alias = ?", alias).Scan(&clientName)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("alias", "alias '%s' not found", args.Alias)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find alias: %w", err)
+		}
+		if _, err := db.ExecContext(ctx, "DELETE FROM client_aliases WHERE alias = ?", alias); err != nil {
+			return nil, nil, fmt.Errorf("failed to remove alias: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Removed alias '%s'", args.Alias)},
+			},
+		}, &clientAliasResult{Alias: alias, ClientName: clientName}, nil
+	})
+
+	setArchived := func(ctx context.Context, name string, archive bool) (*mcp.CallToolResult, *models.Client, error) {
+		clientID, err := h.getClientIDByName(ctx, name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var archived bool
+		if err := db.QueryRowContext(ctx, "SELECT archived_at IS NOT NULL FROM clients WHERE id = ?", clientID).Scan(&archived); err != nil {
+			return nil, nil, fmt.Errorf("failed to find client: %w", err)
+		}
+		if archived == archive {
+			state := "archived"
+			if !archive {
+				state = "not archived"
+			}
+			return nil, nil, conflictError("client '%s' is already %s", name, state)
+		}
+
+		query := "UPDATE clients SET archived_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+		text := fmt.Sprintf("Archived client '%s'. It is hidden from client and contract lists and no new hours, expenses or contracts can be added.", name)
+		if !archive {
+			query = "UPDATE clients SET archived_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+			text = fmt.Sprintf("Client '%s' is no longer archived", name)
+		}
+		if _, err := db.ExecContext(ctx, query, clientID); err != nil {
+			return nil, nil, fmt.Errorf("failed to update client: %w", err)
+		}
+		client, err := h.loadClient(ctx, clientID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, client, nil
+	}
+
+	// Archive Client tool
+	type archiveClientArgs struct {
+		Name string `json:"name" jsonschema:"Client name"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "archive_client",
+		Description: "Archive a former client: hide it from default lists and block new hours, expenses and contracts, keeping its history",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args archiveClientArgs) (*mcp.CallToolResult, *models.Client, error) {
+		return setArchived(ctx, args.Name, true)
+	})
+
+	// Unarchive Client tool
+	type unarchiveClientArgs struct {
+		Name string `json:"name" jsonschema:"Client name"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "unarchive_client",
+		Description: "Restore an archived client so work can be logged for it again",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args unarchiveClientArgs) (*mcp.CallToolResult, *models.Client, error) {
+		return setArchived(ctx, args.Name, false)
+	})
+
+	// Delete Client tool
+	type deleteClientArgs struct {
+		Name    string `json:"name" jsonschema:"Client name"`
+		Cascade bool   `json:"cascade,omitempty" jsonschema:"Also delete the client's time entries, expenses and invoices (default: false)"`
+		Confirm bool   `json:"confirm,omitempty" jsonschema:"Delete the client (default: false, only shows what would be deleted)"`
+	}
+
+	type deleteClientResult struct {
+		Confirmed bool           `json:"confirmed" jsonschema:"Whether the client was deleted; false for a preview"`
+		Delete    map[string]int `json:"delete,omitempty" jsonschema:"Rows per kind of data that would be deleted"`
+		Deleted   map[string]int `json:"deleted,omitempty" jsonschema:"Rows per kind of data deleted"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "delete_client",
+		Description: "Delete a client with its contracts and recipients. Refuses when the client has time entries, expenses or invoices unless cascade is set, and only shows what would be deleted until run with confirm=true; consider archive_client instead",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args deleteClientArgs) (*mcp.CallToolResult, *deleteClientResult, error) {
+		clientID, err := h.getClientIDByName(ctx, args.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		counts := map[string]int{}
+		var summary []string
+		for _, d := range clientData {
+			var count int
+			if err := db.QueryRowContext(ctx, d.query, clientID).Scan(&count); err != nil {
+				return nil, nil, fmt.Errorf("failed to count %s: %w", d.label, err)
+			}
+			counts[d.label] = count
+			if count > 0 {
+				summary = append(summary, fmt.Sprintf("%d %s", count, d.label))
+			}
+		}
+
+		if !args.Cascade && counts["time entries"]+counts["expenses"]+counts["invoices"] > 0 {
+			return nil, nil, conflictError("client '%s' has %s; deleting it with cascade would remove all of them. Use archive_client to keep the history instead",
+				args.Name, strings.Join(summary, ", "))
+		}
+
+		if counts["time entries"] > 0 {
+			// Compliance mode locks the client's entries like any other;
+			// the oldest decides, being locked first
+			var oldest time.Time
+			err := db.QueryRowContext(ctx, "SELECT created_at FROM time_entries WHERE client_id = ? ORDER BY created_at LIMIT 1", clientID).Scan(&oldest)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to check locked time entries: %w", err)
+			}
+			locked, err := h.entryLocked(ctx, oldest)
+			if err != nil {
+				return nil, nil, err
+			}
+			if locked {
+				return nil, nil, conflictError("client '%s' has time entries logged more than 24 hours ago, which compliance mode locks; use archive_client to keep the history instead",
+					args.Name)
+			}
+		}
+
+		if !args.Confirm {
+			text := fmt.Sprintf("Would delete client '%s'", args.Name)
+			if len(summary) > 0 {
+				text += " with " + strings.Join(summary, ", ")
+			}
+			text += ". Use archive_client to keep the history instead.\n"
+			return previewResult(text, &deleteClientResult{Delete: counts})
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		for _, query := range clientDeletes {
+			if _, err := tx.ExecContext(ctx, query, clientID); err != nil {
+				return nil, nil, fmt.Errorf("failed to delete client: %w", err)
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		text := fmt.Sprintf("Deleted client '%s'", args.Name)
+		if len(summary) > 0 {
+			text += " with " + strings.Join(summary, ", ")
+		}
+		if counts["invoices"] > 0 {
+			text += "\nInvoice PDFs on disk were left in place."
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &deleteClientResult{Confirmed: true, Deleted: counts}, nil
+	})
+}
@@ -0,0 +1,21 @@
+package server
+
+import (
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Destructive tools (bulk deletes, deleting a client, voiding an invoice,
+// restoring a backup) only change anything when called with confirm=true.
+// Without it they return a preview of what would be removed, so a single
+// mistaken call can't wipe data.
+
+// previewResult is the result of a destructive tool called without
+// confirm: the preview text, and as structured content the tool's result
+// describing the affected rows, whose confirmed field is left false
+func previewResult[T any](preview string, affected *T) (*mcp.CallToolResult, *T, error) {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: preview + "\nNothing changed yet. Run again with confirm=true to go ahead."},
+		},
+	}, affected, nil
+}
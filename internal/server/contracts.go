@@ -0,0 +1,1265 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/austin/hours-mcp/internal/store"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// contractTransitions lists the statuses a contract may move to from each
+// status. Completed and cancelled contracts are final.
+var contractTransitions = map[string][]string{
+	"active":  {"completed", "on_hold", "cancelled"},
+	"on_hold": {"active", "completed", "cancelled"},
+}
+
+func validateContractTransition(from, to string) error {
+	for _, allowed := range contractTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	if len(contractTransitions[from]) == 0 {
+		return conflictError("contract is %s; its status can no longer change", from)
+	}
+	return conflictError("cannot change status from %s to %s; allowed: %s", from, to, strings.Join(contractTransitions[from], ", "))
+}
+
+// loadContract returns a contract by number
+func (h *Handler) loadContract(ctx context.Context, number string) (*models.Contract, error) {
+	c := &models.Contract{}
+	var endDate sql.NullTime
+	err := h.db.QueryRowContext(ctx, `
+		SELECT id, client_id, contract_number, name, hourly_rate_cents, currency, contract_type,
+		       start_date, end_date, status, COALESCE(payment_terms, ''), COALESCE(notes, ''), created_at, updated_at
+		FROM contracts WHERE contract_number = ?
+	`, number).Scan(&c.ID, &c.ClientID, &c.ContractNumber, &c.Name, &c.HourlyRate, &c.Currency, &c.ContractType,
+		&c.StartDate, &endDate, &c.Status, &c.PaymentTerms, &c.Notes, &c.CreatedAt, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, notFoundError("contract", "contract %s not found", number)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find contract: %w", err)
+	}
+	if endDate.Valid {
+		c.EndDate = &endDate.Time
+	}
+	return c, nil
+}
+
+// contractDetails is everything about one contract
+type contractDetails struct {
+	Contract      *models.Contract `json:"contract"`
+	ClientName    string           `json:"client_name"`
+	PaymentMethod string           `json:"payment_method,omitempty" jsonschema:"Payment method the contract's invoices ask to be paid into"`
+	CurrentRate   money.Cents      `json:"current_rate"`
+	Rates         []contractRate   `json:"rates" jsonschema:"The contract's own rate from its start, then each scheduled change"`
+	RateRules     []rateRule       `json:"rate_rules"`
+	ServiceRates  []serviceRate    `json:"service_rates" jsonschema:"Rates agreed for services, which entries for them are billed at"`
+	TotalHours    float64          `json:"total_hours"`
+	BilledHours   float64          `json:"billed_hours"`
+	Billed        money.Cents      `json:"billed" jsonschema:"Invoiced hours, priced as invoiced"`
+	UnbilledHours float64          `json:"unbilled_hours"`
+	Unbilled      money.Cents      `json:"unbilled" jsonschema:"Hours not invoiced yet, priced as they would be"`
+	// NonBillableHours are logged but never invoiced
+	NonBillableHours float64 `json:"non_billable_hours,omitempty"`
+	// NoChargeHours are invoiced, or to be invoiced, as N/C at no cost
+	NoChargeHours float64                   `json:"no_charge_hours,omitempty"`
+	Budget        *budgetStatus             `json:"budget,omitempty"`
+	Invoices      []clientInvoice           `json:"invoices" jsonschema:"Invoices billing the contract's hours or expenses, oldest first"`
+	Documents     []models.ContractDocument `json:"documents" jsonschema:"Files kept with the contract, such as the signed contract"`
+	DaysLeft      *int                      `json:"days_left,omitempty" jsonschema:"Days until the end date, negative once it has passed"`
+}
+
+// loadContractDetails gathers a contract's terms with its rate history and
+// rules, the hours logged, billed and unbilled, its budget use, the invoices
+// billing it and the days left until it ends
+func (h *Handler) loadContractDetails(ctx context.Context, number string) (*contractDetails, error) {
+	c, err := h.loadContract(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	d := &contractDetails{Contract: c, RateRules: []rateRule{}, Invoices: []clientInvoice{}}
+	err = h.db.QueryRowContext(ctx, `
+		SELECT cl.name, c.retainer_hours, c.retainer_fee_cents, c.overage_rate_cents, c.rollover_months,
+		       c.budget_hours, c.budget_amount_cents, c.budget_alert_percent, c.budget_hard_limit, COALESCE(m.name, '')
+		FROM contracts c
+		JOIN clients cl ON c.client_id = cl.id
+		LEFT JOIN payment_methods m ON m.id = c.payment_method_id
+		WHERE c.id = ?
+	`, c.ID).Scan(&d.ClientName, &c.RetainerHours, &c.RetainerFee, &c.OverageRate, &c.RolloverMonths,
+		&c.BudgetHours, &c.BudgetAmount, &c.BudgetAlert, &c.BudgetLimit, &d.PaymentMethod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contract terms: %w", err)
+	}
+
+	rates, current, err := h.contractRateHistory(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	d.Rates, d.CurrentRate = rates, rates[current].HourlyRate
+	rules, err := h.contractRateRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, kind := range []string{rateRuleOvertime, rateRuleWeekend, rateRuleHoliday} {
+		if r, ok := rules[c.ID][kind]; ok {
+			d.RateRules = append(d.RateRules, r)
+		}
+	}
+
+	if d.ServiceRates, err = h.contractServiceRates(ctx, c.ID); err != nil {
+		return nil, err
+	}
+
+	billed, err := h.priceStoredEntries(ctx, "te.contract_id = ? AND te.invoice_id IS NOT NULL", c.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range billed {
+		d.BilledHours += item.Hours
+		d.Billed += item.Amount
+		if item.RateKind == rateKindNoCharge {
+			d.NoChargeHours += item.Hours
+		}
+	}
+	unbilled, err := h.priceStoredEntries(ctx, "te.contract_id = ? AND te.invoice_id IS NULL AND te.billable", c.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range unbilled {
+		d.UnbilledHours += item.Hours
+		d.Unbilled += item.Amount
+		if item.RateKind == rateKindNoCharge {
+			d.NoChargeHours += item.Hours
+		}
+	}
+	err = h.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(hours), 0) FROM time_entries
+		WHERE contract_id = ? AND invoice_id IS NULL AND NOT billable
+	`, c.ID).Scan(&d.NonBillableHours)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load non-billable hours: %w", err)
+	}
+	d.TotalHours = d.BilledHours + d.UnbilledHours + d.NonBillableHours
+
+	if c.BudgetHours > 0 || c.BudgetAmount > 0 {
+		used, err := h.budgetUsed(ctx, c.ID)
+		if err != nil {
+			return nil, err
+		}
+		d.Budget = newBudgetStatus(c, used)
+	}
+
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT invoice_number, issue_date, due_date, total_cents, currency, status
+		FROM invoices
+		WHERE id IN (SELECT invoice_id FROM time_entries WHERE contract_id = ?
+		             UNION SELECT invoice_id FROM expenses WHERE contract_id = ?)
+		ORDER BY issue_date, invoice_number
+	`, c.ID, c.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invoices: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var inv clientInvoice
+		if err := rows.Scan(&inv.InvoiceNumber, &inv.IssueDate, &inv.DueDate, &inv.Total, &inv.Currency, &inv.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice: %w", err)
+		}
+		d.Invoices = append(d.Invoices, inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if d.Documents, err = h.contractDocuments(ctx, c.ID); err != nil {
+		return nil, err
+	}
+
+	if c.EndDate != nil {
+		days := int(calendarDay(*c.EndDate).Sub(h.today(ctx)).Hours() / 24)
+		d.DaysLeft = &days
+	}
+	return d, nil
+}
+
+// contractDetailsText describes everything about a contract for tool output
+func contractDetailsText(d *contractDetails) string {
+	c := d.Contract
+	text := fmt.Sprintf("Contract %s: %s (%s)\n", c.ContractNumber, c.Name, d.ClientName)
+	end := "ongoing"
+	if c.EndDate != nil {
+		end = c.EndDate.Format("2006-01-02")
+	}
+	text += fmt.Sprintf("Type: %s, %s, %s to %s\n", c.ContractType, c.Status, c.StartDate.Format("2006-01-02"), end)
+	text += fmt.Sprintf("Rate: %s/hour\n", d.CurrentRate.Format(c.Currency))
+	if c.ContractType == "retainer" && c.RetainerHours > 0 {
+		text += fmt.Sprintf("Retainer: %g hours/month for %s, rollover %d months", c.RetainerHours, c.RetainerFee.Format(c.Currency), c.RolloverMonths)
+		if c.OverageRate > 0 {
+			text += fmt.Sprintf(", overage %s/hour", c.OverageRate.Format(c.Currency))
+		}
+		text += "\n"
+	}
+	if c.PaymentTerms != "" {
+		text += fmt.Sprintf("Payment terms: %s\n", c.PaymentTerms)
+	}
+	if d.PaymentMethod != "" {
+		text += fmt.Sprintf("Paid into: %s\n", d.PaymentMethod)
+	}
+	if c.Notes != "" {
+		text += fmt.Sprintf("Notes: %s\n", c.Notes)
+	}
+
+	if len(d.Rates) > 1 {
+		text += "\nRate history:\n"
+		for _, r := range d.Rates {
+			text += fmt.Sprintf("- from %s: %s per hour\n", r.EffectiveFrom.Format("2006-01-02"), r.HourlyRate.Format(c.Currency))
+		}
+	}
+	if len(d.RateRules) > 0 {
+		text += "\nRate rules:\n"
+		for _, r := range d.RateRules {
+			text += fmt.Sprintf("- %s: %gx", rateRuleLabels[r.Kind], r.Multiplier)
+			if r.Kind == rateRuleOvertime {
+				text += fmt.Sprintf(" after %g hours a day", r.DailyHours)
+			}
+			text += "\n"
+		}
+	}
+	if len(d.ServiceRates) > 0 {
+		text += "\nService rates:\n"
+		for _, r := range d.ServiceRates {
+			text += fmt.Sprintf("- %s: %s per hour\n", r.Service, r.HourlyRate.Format(c.Currency))
+		}
+	}
+
+	text += fmt.Sprintf("\nHours: %.2f logged, %.2f billed (%s), %.2f unbilled (%s)\n", d.TotalHours,
+		d.BilledHours, d.Billed.Format(c.Currency), d.UnbilledHours, d.Unbilled.Format(c.Currency))
+	if d.NonBillableHours > 0 {
+		text += fmt.Sprintf("Not billable: %.2f hours\n", d.NonBillableHours)
+	}
+	if d.NoChargeHours > 0 {
+		text += fmt.Sprintf("No charge (N/C): %.2f hours\n", d.NoChargeHours)
+	}
+	if d.Budget != nil {
+		var budgets []string
+		if c.BudgetHours > 0 {
+			budgets = append(budgets, budgetSummary(c, d.Budget.Used, "hours"))
+		}
+		if c.BudgetAmount > 0 {
+			budgets = append(budgets, budgetSummary(c, d.Budget.Used, "amount"))
+		}
+		text += fmt.Sprintf("Budget: %s\n", strings.Join(budgets, ", "))
+	}
+
+	text += "\nInvoices:\n"
+	for _, inv := range d.Invoices {
+		text += fmt.Sprintf("- %s: %s [%s] issued %s, due %s\n", inv.InvoiceNumber, inv.Total.Format(inv.Currency), inv.Status,
+			inv.IssueDate.Format("2006-01-02"), inv.DueDate.Format("2006-01-02"))
+	}
+	if len(d.Invoices) == 0 {
+		text += "- none\n"
+	}
+
+	if len(d.Documents) > 0 {
+		text += "\nDocuments:\n"
+		for _, doc := range d.Documents {
+			text += "- " + documentLabel(doc) + "\n"
+		}
+	}
+
+	switch {
+	case d.DaysLeft == nil:
+		text += "\nNo end date\n"
+	case *d.DaysLeft > 0:
+		text += fmt.Sprintf("\nEnds in %d days\n", *d.DaysLeft)
+	case *d.DaysLeft == 0:
+		text += "\nEnds today\n"
+	default:
+		text += fmt.Sprintf("\nEnded %d days ago\n", -*d.DaysLeft)
+	}
+	return text
+}
+
+// contractStopWords are words that don't help tell contracts apart, as in
+// "the Acme maintenance contract"
+var contractStopWords = map[string]bool{"the": true, "a": true, "an": true, "contract": true, "for": true, "of": true, "s": true}
+
+// contractWords splits a contract description into lowercase words,
+// without punctuation and stop words
+func contractWords(s string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !contractStopWords[word] {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// contractNumber returns the number of the contract ref refers to: its
+// number, its name, or words from its client's and its own name such as
+// "the Acme maintenance contract" or "acme maint". Matches are tried from
+// exact to partial; when several contracts match, the only active one among
+// them is taken, or else the error lists them.
+func (h *Handler) contractNumber(ctx context.Context, ref string) (string, error) {
+	var number string
+	err := h.db.QueryRowContext(ctx, "SELECT contract_number FROM contracts WHERE contract_number = ?", ref).Scan(&number)
+	if err == nil {
+		return number, nil
+	} else if err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to find contract: %w", err)
+	}
+
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT c.contract_number, c.name, c.status, cl.name
+		FROM contracts c
+		JOIN clients cl ON c.client_id = cl.id
+		ORDER BY c.contract_number
+	`)
+	if err != nil {
+		return "", fmt.Errorf("failed to load contracts: %w", err)
+	}
+	defer rows.Close()
+
+	type candidate struct {
+		number, name, status, client string
+		words                        []string
+	}
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.number, &c.name, &c.status, &c.client); err != nil {
+			return "", fmt.Errorf("failed to scan contract: %w", err)
+		}
+		c.words = contractWords(c.client + " " + c.name + " " + c.number)
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	ref = strings.TrimSpace(ref)
+	wanted := contractWords(ref)
+	stages := []func(candidate) bool{
+		func(c candidate) bool { return strings.EqualFold(c.number, ref) },
+		func(c candidate) bool { return strings.EqualFold(c.name, ref) },
+		// Every word given starts a word of the client or contract
+		func(c candidate) bool {
+			if len(wanted) == 0 {
+				return false
+			}
+			for _, w := range wanted {
+				found := false
+				for _, word := range c.words {
+					found = found || strings.HasPrefix(word, w)
+				}
+				if !found {
+					return false
+				}
+			}
+			return true
+		},
+	}
+	for _, stage := range stages {
+		var matches, active []candidate
+		for _, c := range candidates {
+			if stage(c) {
+				matches = append(matches, c)
+				if c.status == "active" {
+					active = append(active, c)
+				}
+			}
+		}
+		switch {
+		case len(matches) == 1:
+			return matches[0].number, nil
+		case len(active) == 1:
+			return active[0].number, nil
+		case len(matches) > 1:
+			listed := make([]string, len(matches))
+			for i, c := range matches {
+				listed[i] = fmt.Sprintf("%s (%s: %s, %s)", c.number, c.client, c.name, c.status)
+			}
+			return "", validationError("contract '%s' matches several contracts: %s; use the contract number", ref, strings.Join(listed, "; "))
+		}
+	}
+	return "", notFoundError("contract", "contract %s not found", ref)
+}
+
+// resolveContractNumber replaces *ref, a contract argument, with the number
+// of the contract it refers to (see contractNumber); an empty ref is left
+// empty
+func (h *Handler) resolveContractNumber(ctx context.Context, ref *string) error {
+	if *ref == "" {
+		return nil
+	}
+	number, err := h.contractNumber(ctx, *ref)
+	if err != nil {
+		return err
+	}
+	*ref = number
+	return nil
+}
+
+// exactContractID returns the contract whose number or name is ref, ignoring
+// case; ok is false when none or several match. Filters use it to narrow to
+// one contract without contractNumber's fuzzy matching.
+func (h *Handler) exactContractID(ctx context.Context, ref string) (id int, ok bool, err error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT id FROM contracts
+		WHERE contract_number = ? COLLATE NOCASE OR name = ? COLLATE NOCASE
+	`, strings.TrimSpace(ref), strings.TrimSpace(ref))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to find contract: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		if err := rows.Scan(&id); err != nil {
+			return 0, false, fmt.Errorf("failed to scan contract: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, false, err
+	}
+	if len(ids) != 1 {
+		return 0, false, nil
+	}
+	return ids[0], true, nil
+}
+
+// countOutside counts the contract's time entries dated before start or,
+// when end is set, after end
+func (h *Handler) countOutside(ctx context.Context, contractID int, start time.Time, end *time.Time) (int, error) {
+	query := "SELECT COUNT(*) FROM time_entries WHERE contract_id = ? AND (date < ?"
+	args := []interface{}{contractID, start.Format("2006-01-02")}
+	if end != nil {
+		query += " OR date > ?"
+		args = append(args, end.Format("2006-01-02"))
+	}
+	var count int
+	if err := h.db.QueryRowContext(ctx, query+")", args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to check time entries: %w", err)
+	}
+	return count, nil
+}
+
+// contractCovers reports whether date falls within the contract's dates
+func contractCovers(c *models.Contract, date time.Time) bool {
+	return !date.Before(c.StartDate) && (c.EndDate == nil || !date.After(*c.EndDate))
+}
+
+// contractExpiry is an active contract ending soon or already past its end date
+type contractExpiry struct {
+	ContractNumber string    `json:"contract_number"`
+	Name           string    `json:"name"`
+	ClientName     string    `json:"client_name"`
+	EndDate        time.Time `json:"end_date"`
+	DaysLeft       int       `json:"days_left"`
+}
+
+func (e contractExpiry) String() string {
+	end := e.EndDate.Format("2006-01-02")
+	switch {
+	case e.DaysLeft < 0:
+		return fmt.Sprintf("%s (%s, %s) ended %s, %d days ago, but is still active", e.ContractNumber, e.Name, e.ClientName, end, -e.DaysLeft)
+	case e.DaysLeft == 0:
+		return fmt.Sprintf("%s (%s, %s) ends today", e.ContractNumber, e.Name, e.ClientName)
+	default:
+		return fmt.Sprintf("%s (%s, %s) ends %s, in %d days", e.ContractNumber, e.Name, e.ClientName, end, e.DaysLeft)
+	}
+}
+
+// expiringContracts lists active contracts whose end date is at most within
+// days away, including those already past it, soonest first
+func (h *Handler) expiringContracts(ctx context.Context, within int) ([]contractExpiry, error) {
+	today := h.today(ctx)
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT c.contract_number, c.name, cl.name, c.end_date
+		FROM contracts c
+		JOIN clients cl ON c.client_id = cl.id
+		WHERE c.status = 'active' AND c.end_date IS NOT NULL AND date(c.end_date) <= ?
+		ORDER BY c.end_date, c.contract_number
+	`, today.AddDate(0, 0, within).Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check contract end dates: %w", err)
+	}
+	defer rows.Close()
+
+	var expiring []contractExpiry
+	for rows.Next() {
+		var e contractExpiry
+		if err := rows.Scan(&e.ContractNumber, &e.Name, &e.ClientName, &e.EndDate); err != nil {
+			return nil, fmt.Errorf("failed to scan contract: %w", err)
+		}
+		// Stored dates are UTC midnight, so count whole calendar days
+		end := time.Date(e.EndDate.Year(), e.EndDate.Month(), e.EndDate.Day(), 0, 0, 0, 0, time.UTC)
+		e.DaysLeft = int(end.Sub(today).Hours() / 24)
+		expiring = append(expiring, e)
+	}
+	return expiring, rows.Err()
+}
+
+// WarnExpiringContracts logs a warning for every active contract
+// ending within the contract_expiry_days setting or already past its end date
+func WarnExpiringContracts(db *sql.DB) {
+	ctx := context.Background()
+	h := newHandler(db)
+	expiring, err := h.expiringContracts(ctx, h.getIntSetting(ctx, "contract_expiry_days"))
+	if err != nil {
+		slog.Error("failed to check contract end dates", "error", err)
+		return
+	}
+	for _, e := range expiring {
+		slog.Warn(e.String())
+	}
+}
+
+// registerContractTools registers tools to edit, close, renew and delete
+// contracts
+func registerContractTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Add Contract tool
+	type addContractArgs struct {
+		ClientName     string  `json:"client_name" jsonschema:"Client name"`
+		ContractNumber string  `json:"contract_number" jsonschema:"Contract number (unique identifier)"`
+		Name           string  `json:"name" jsonschema:"Contract name/description"`
+		HourlyRate     float64 `json:"hourly_rate,omitempty" jsonschema:"Hourly rate for this contract (default: the rate card rate of the first service)"`
+		Currency       string  `json:"currency,omitempty" jsonschema:"Currency code (e.g. USD, EUR; default: the currency of the first service, else the client's default currency, or USD)"`
+		ContractType   string  `json:"contract_type,omitempty" jsonschema:"Contract type (hourly, fixed, retainer)"`
+		StartDate      string  `json:"start_date" jsonschema:"Contract start date (YYYY-MM-DD)"`
+		EndDate        string  `json:"end_date,omitempty" jsonschema:"Contract end date (YYYY-MM-DD, optional)"`
+		PaymentTerms   string  `json:"payment_terms,omitempty" jsonschema:"Payment terms (e.g. 'Net 30')"`
+		Notes          string  `json:"notes,omitempty" jsonschema:"Additional notes"`
+
+		Services []string `json:"services,omitempty" jsonschema:"Services from the rate card billed under the contract at their rate card rates, e.g. ['Development', 'Advisory'] (optional)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "add_contract",
+		Description: "Add a new contract for a client with specific rates and terms",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args addContractArgs) (*mcp.CallToolResult, *models.Contract, error) {
+		// Get client ID
+		clientID, err := h.getClientIDByName(ctx, args.ClientName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find client: %w", err)
+		}
+		if err := h.checkClientActive(ctx, clientID); err != nil {
+			return nil, nil, err
+		}
+
+		// Rates come from the rate card unless the contract names its own
+		var services []*models.Service
+		for _, name := range args.Services {
+			service, err := h.loadService(ctx, name)
+			if err != nil {
+				return nil, nil, err
+			}
+			services = append(services, service)
+		}
+		if len(services) > 0 {
+			if args.HourlyRate == 0 {
+				args.HourlyRate = services[0].HourlyRate.Float()
+			}
+			if args.Currency == "" {
+				args.Currency = services[0].Currency
+			}
+			for _, service := range services {
+				if service.Currency != args.Currency {
+					return nil, nil, validationError("%s is priced in %s on the rate card but the contract is in %s; add the contract without it and use set_contract_service_rate with a rate in %s",
+						service.Name, service.Currency, args.Currency, args.Currency)
+				}
+			}
+		}
+
+		// Set defaults
+		if args.Currency == "" {
+			if args.Currency, err = h.store.Clients.DefaultCurrency(ctx, clientID); err != nil {
+				return nil, nil, fmt.Errorf("failed to load client default currency: %w", err)
+			}
+		}
+		if args.Currency == "" {
+			args.Currency = "USD"
+		}
+		if args.ContractType == "" {
+			args.ContractType = "hourly"
+		}
+
+		// Parse dates
+		startDate, err := time.Parse("2006-01-02", args.StartDate)
+		if err != nil {
+			return nil, nil, validationError("invalid start date format: %w", err)
+		}
+
+		var endDate *time.Time
+		if args.EndDate != "" {
+			ed, err := time.Parse("2006-01-02", args.EndDate)
+			if err != nil {
+				return nil, nil, validationError("invalid end date format: %w", err)
+			}
+			endDate = &ed
+		}
+
+		contractID, err := h.store.Contracts.Create(ctx, &models.Contract{
+			ClientID:       clientID,
+			ContractNumber: args.ContractNumber,
+			Name:           args.Name,
+			HourlyRate:     money.FromFloat(args.HourlyRate),
+			Currency:       args.Currency,
+			ContractType:   args.ContractType,
+			StartDate:      startDate,
+			EndDate:        endDate,
+			PaymentTerms:   args.PaymentTerms,
+			Notes:          args.Notes,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to add contract: %w", err)
+		}
+		text := fmt.Sprintf("Successfully added contract %s for %s (ID: %d)", args.ContractNumber, args.ClientName, contractID)
+		for _, service := range services {
+			_, err := db.ExecContext(ctx, `
+				INSERT INTO contract_services (contract_id, service_id, hourly_rate_cents) VALUES (?, ?, ?)
+				ON CONFLICT(contract_id, service_id) DO NOTHING
+			`, contractID, service.ID, service.HourlyRate)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to add service rate: %w", err)
+			}
+			text += fmt.Sprintf("\n%s: %s per hour", service.Name, service.HourlyRate.Format(args.Currency))
+		}
+		c, err := h.loadContract(ctx, args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, c, nil
+	})
+
+	// List Contracts tool
+	type listContractsArgs struct {
+		ClientName string `json:"client_name,omitempty" jsonschema:"Filter by client name (optional)"`
+		Status     string `json:"status,omitempty" jsonschema:"Filter by status (active, completed, on_hold, cancelled)"`
+		Search     string `json:"search,omitempty" jsonschema:"Search contract names and notes (optional)"`
+
+		IncludeArchived bool `json:"include_archived,omitempty" jsonschema:"Include contracts of archived clients"`
+	}
+
+	type listContractsResult struct {
+		Contracts []models.Contract `json:"contracts"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_contracts",
+		Description: "List contracts with optional filtering by client or status",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listContractsArgs) (*mcp.CallToolResult, *listContractsResult, error) {
+		filter := store.ContractFilter{
+			ClientName:      args.ClientName,
+			Status:          args.Status,
+			Search:          args.Search,
+			IncludeArchived: args.IncludeArchived,
+		}
+		if h.search {
+			filter.FTSQuery = buildFTSQuery(args.Search)
+		}
+		contracts, err := h.store.Contracts.List(ctx, filter)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list contracts: %w", err)
+		}
+
+		text := fmt.Sprintf("Found %d contracts:\n", len(contracts))
+		for _, c := range contracts {
+			endDateStr := "ongoing"
+			if c.EndDate != nil {
+				endDateStr = c.EndDate.Format("2006-01-02")
+			}
+			text += fmt.Sprintf("- %s: %s (%s) - %s%s/%s [%s] %s to %s\n",
+				c.ContractNumber, c.Client.Name, c.Name, c.Currency, c.HourlyRate, c.Currency,
+				c.Status, c.StartDate.Format("2006-01-02"), endDateStr)
+			if c.ContractType == "retainer" && c.RetainerHours > 0 {
+				text += fmt.Sprintf("  Retainer: %g hours/month for %s, rollover %d months\n",
+					c.RetainerHours, c.RetainerFee.Format(c.Currency), c.RolloverMonths)
+			}
+			if c.BudgetHours > 0 || c.BudgetAmount > 0 {
+				var budgets []string
+				if c.BudgetHours > 0 {
+					budgets = append(budgets, fmt.Sprintf("%g hours", c.BudgetHours))
+				}
+				if c.BudgetAmount > 0 {
+					budgets = append(budgets, c.BudgetAmount.Format(c.Currency))
+				}
+				limit := "alert"
+				if c.BudgetLimit {
+					limit = "hard limit, alert"
+				}
+				text += fmt.Sprintf("  Budget: %s (%s at %g%%)\n", strings.Join(budgets, ", "), limit, c.BudgetAlert)
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &listContractsResult{Contracts: contracts}, nil
+	})
+
+	// Get Contract Details tool
+	type getContractDetailsArgs struct {
+		ContractNumber string `json:"contract_number" jsonschema:"Contract number or name"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_contract_details",
+		Description: "Show everything about a contract in one call: terms, rate history and rules, hours logged, billed and unbilled amounts, budget use, the invoices billing it and the days until its end date",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getContractDetailsArgs) (*mcp.CallToolResult, *contractDetails, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		d, err := h.loadContractDetails(ctx, args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: contractDetailsText(d)},
+			},
+		}, d, nil
+	})
+
+	// Edit Contract tool
+	type editContractArgs struct {
+		ContractNumber string   `json:"contract_number" jsonschema:"Contract number or name to edit"`
+		Name           string   `json:"name,omitempty" jsonschema:"New contract name (optional)"`
+		HourlyRate     *float64 `json:"hourly_rate,omitempty" jsonschema:"New base hourly rate; use set_contract_rate for a rate change from a date (optional)"`
+		Currency       string   `json:"currency,omitempty" jsonschema:"New currency code (optional)"`
+		ContractType   string   `json:"contract_type,omitempty" jsonschema:"New contract type: hourly, fixed or retainer (optional)"`
+		StartDate      string   `json:"start_date,omitempty" jsonschema:"New start date (optional)"`
+		EndDate        *string  `json:"end_date,omitempty" jsonschema:"New end date, empty for ongoing (optional)"`
+		PaymentTerms   *string  `json:"payment_terms,omitempty" jsonschema:"New payment terms (optional)"`
+		PaymentMethod  *string  `json:"payment_method,omitempty" jsonschema:"Payment method its invoices ask to be paid into, empty for the client's (optional; see list_payment_methods)"`
+		Notes          *string  `json:"notes,omitempty" jsonschema:"New notes (optional)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "edit_contract",
+		Description: "Edit a contract's name, rate, currency, dates, payment terms, payment method or notes",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args editContractArgs) (*mcp.CallToolResult, *models.Contract, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		c, err := h.loadContract(ctx, args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var invoiced int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM time_entries WHERE contract_id = ? AND invoice_id IS NOT NULL", c.ID).Scan(&invoiced); err != nil {
+			return nil, nil, fmt.Errorf("failed to check invoiced hours: %w", err)
+		}
+
+		setParts := []string{}
+		values := []interface{}{}
+
+		if args.Name != "" {
+			setParts = append(setParts, "name = ?")
+			values = append(values, args.Name)
+		}
+		if args.HourlyRate != nil {
+			if *args.HourlyRate <= 0 {
+				return nil, nil, validationError("hourly rate must be positive")
+			}
+			// The base rate applies from the contract start, so changing it
+			// reprices every entry before the first scheduled rate
+			if err := h.checkRateChangeAllowed(ctx, c.ID, c.StartDate); err != nil {
+				return nil, nil, fmt.Errorf("%w; use set_contract_rate to change the rate from a later date", err)
+			}
+			setParts = append(setParts, "hourly_rate_cents = ?")
+			values = append(values, money.FromFloat(*args.HourlyRate))
+		}
+		if args.Currency != "" {
+			currency := strings.ToUpper(strings.TrimSpace(args.Currency))
+			if err := validateCurrencyCode(currency); err != nil {
+				return nil, nil, validationError("invalid currency: %w", err)
+			}
+			if invoiced > 0 && currency != c.Currency {
+				return nil, nil, conflictError("cannot change the currency of %s; %d time entries have been invoiced in %s", c.ContractNumber, invoiced, c.Currency)
+			}
+			setParts = append(setParts, "currency = ?")
+			values = append(values, currency)
+		}
+		if args.ContractType != "" {
+			if args.ContractType != "hourly" && args.ContractType != "fixed" && args.ContractType != "retainer" {
+				return nil, nil, validationError("invalid contract type '%s': must be hourly, fixed or retainer", args.ContractType)
+			}
+			setParts = append(setParts, "contract_type = ?")
+			values = append(values, args.ContractType)
+		}
+
+		start, end := c.StartDate, c.EndDate
+		if args.StartDate != "" {
+			if start, err = h.parseDate(ctx, args.StartDate); err != nil {
+				return nil, nil, validationError("invalid start date: %w", err)
+			}
+			schedules, err := h.contractRateSchedules(ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+			if rates := schedules[c.ID]; len(rates) > 0 && !start.Before(rates[0].EffectiveFrom) {
+				return nil, nil, validationError("start date must be before the first scheduled rate change (%s)", rates[0].EffectiveFrom.Format("2006-01-02"))
+			}
+			setParts = append(setParts, "start_date = ?")
+			values = append(values, start.Format("2006-01-02"))
+		}
+		if args.EndDate != nil {
+			end = nil
+			var endValue interface{}
+			if *args.EndDate != "" {
+				endDate, err := h.parseDate(ctx, *args.EndDate)
+				if err != nil {
+					return nil, nil, validationError("invalid end date: %w", err)
+				}
+				end = &endDate
+				endValue = endDate.Format("2006-01-02")
+			}
+			setParts = append(setParts, "end_date = ?")
+			values = append(values, endValue)
+		}
+		if args.StartDate != "" || args.EndDate != nil {
+			if end != nil && end.Before(start) {
+				return nil, nil, validationError("end date %s is before the start date %s", end.Format("2006-01-02"), start.Format("2006-01-02"))
+			}
+			outside, err := h.countOutside(ctx, c.ID, start, end)
+			if err != nil {
+				return nil, nil, err
+			}
+			if outside > 0 {
+				return nil, nil, conflictError("%d time entries on %s fall outside the new dates", outside, c.ContractNumber)
+			}
+		}
+
+		if args.PaymentTerms != nil {
+			setParts = append(setParts, "payment_terms = ?")
+			values = append(values, *args.PaymentTerms)
+		}
+		if args.PaymentMethod != nil {
+			methodID, err := h.paymentMethodID(ctx, *args.PaymentMethod)
+			if err != nil {
+				return nil, nil, err
+			}
+			var methodValue interface{}
+			if methodID != 0 {
+				methodValue = methodID
+			}
+			setParts = append(setParts, "payment_method_id = ?")
+			values = append(values, methodValue)
+		}
+		if args.Notes != nil {
+			setParts = append(setParts, "notes = ?")
+			values = append(values, *args.Notes)
+		}
+
+		if len(setParts) == 0 {
+			return nil, nil, validationError("no fields provided to update")
+		}
+
+		setParts = append(setParts, "updated_at = CURRENT_TIMESTAMP")
+		values = append(values, c.ID)
+
+		query := fmt.Sprintf("UPDATE contracts SET %s WHERE id = ?", strings.Join(setParts, ", "))
+		if _, err := db.ExecContext(ctx, query, values...); err != nil {
+			return nil, nil, fmt.Errorf("failed to update contract: %w", err)
+		}
+		updated, err := h.loadContract(ctx, c.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Successfully updated contract: %s", c.ContractNumber)},
+			},
+		}, updated, nil
+	})
+
+	// Update Contract Status tool
+	type updateContractStatusArgs struct {
+		ContractNumber string `json:"contract_number" jsonschema:"Contract number or name"`
+		Status         string `json:"status" jsonschema:"New status: active, completed, on_hold or cancelled"`
+		EndDate        string `json:"end_date,omitempty" jsonschema:"End date to record when completing or cancelling (default: the contract's end date, or today)"`
+	}
+
+	type updateContractStatusResult struct {
+		PreviousStatus string           `json:"previous_status"`
+		Contract       *models.Contract `json:"contract"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "update_contract_status",
+		Description: "Complete, pause, resume or cancel a contract; only active contracts accept new hours",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args updateContractStatusArgs) (*mcp.CallToolResult, *updateContractStatusResult, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		c, err := h.loadContract(ctx, args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := validateContractTransition(c.Status, args.Status); err != nil {
+			return nil, nil, err
+		}
+
+		// A finished contract gets an end date so reports and retainers stop
+		// counting it
+		var endValue interface{}
+		if c.EndDate != nil {
+			endValue = c.EndDate.Format("2006-01-02")
+		}
+		if args.Status == "completed" || args.Status == "cancelled" {
+			end := h.today(ctx)
+			if c.EndDate != nil {
+				end = *c.EndDate
+			}
+			if args.EndDate != "" {
+				if end, err = h.parseDate(ctx, args.EndDate); err != nil {
+					return nil, nil, validationError("invalid end date: %w", err)
+				}
+			}
+			if end.Before(c.StartDate) {
+				return nil, nil, validationError("end date %s is before the start date %s", end.Format("2006-01-02"), c.StartDate.Format("2006-01-02"))
+			}
+			outside, err := h.countOutside(ctx, c.ID, c.StartDate, &end)
+			if err != nil {
+				return nil, nil, err
+			}
+			if outside > 0 {
+				return nil, nil, conflictError("%d time entries on %s are dated after %s", outside, c.ContractNumber, end.Format("2006-01-02"))
+			}
+			endValue = end.Format("2006-01-02")
+		} else if args.EndDate != "" {
+			return nil, nil, validationError("end_date only applies when completing or cancelling a contract")
+		}
+
+		_, err = db.ExecContext(ctx, `
+			UPDATE contracts SET status = ?, end_date = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+		`, args.Status, endValue, c.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to update contract status: %w", err)
+		}
+
+		text := fmt.Sprintf("Contract %s status updated from '%s' to '%s'", c.ContractNumber, c.Status, args.Status)
+		if endValue != nil && (args.Status == "completed" || args.Status == "cancelled") {
+			text += fmt.Sprintf(" (ended %s)", endValue)
+		}
+		updated, err := h.loadContract(ctx, c.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &updateContractStatusResult{PreviousStatus: c.Status, Contract: updated}, nil
+	})
+
+	// Delete Contract tool
+	type deleteContractArgs struct {
+		ContractNumber string `json:"contract_number" jsonschema:"Contract number or name to delete"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "delete_contract",
+		Description: "Delete a contract that has no time entries, expenses or invoice lines, e.g. one added by mistake",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args deleteContractArgs) (*mcp.CallToolResult, *models.Contract, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		c, err := h.loadContract(ctx, args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Anything recorded against the contract keeps it alive; use
+		// update_contract_status to close it instead
+		used := map[string]int{}
+		for _, table := range []string{"time_entries", "expenses", "invoice_lines"} {
+			var count int
+			if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE contract_id = ?", table), c.ID).Scan(&count); err != nil {
+				return nil, nil, fmt.Errorf("failed to check %s: %w", table, err)
+			}
+			if count > 0 {
+				used[strings.ReplaceAll(table, "_", " ")] = count
+			}
+		}
+		if len(used) > 0 {
+			var parts []string
+			for name, count := range used {
+				parts = append(parts, fmt.Sprintf("%d %s", count, name))
+			}
+			sort.Strings(parts)
+			return nil, nil, conflictError("cannot delete %s; it has %s. Use update_contract_status to complete or cancel it instead",
+				c.ContractNumber, strings.Join(parts, ", "))
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		for _, table := range []string{"contract_rates", "contract_rate_rules", "contract_documents", "contract_services", "timers"} {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE contract_id = ?", table), c.ID); err != nil {
+				return nil, nil, fmt.Errorf("failed to delete %s: %w", table, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM contracts WHERE id = ?", c.ID); err != nil {
+			return nil, nil, fmt.Errorf("failed to delete contract: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Deleted contract %s (%s)", c.ContractNumber, c.Name)},
+			},
+		}, c, nil
+	})
+
+	// Check Contract Expirations tool
+	type checkContractExpirationsArgs struct {
+		Days int `json:"days,omitempty" jsonschema:"Warn about contracts ending within this many days (default: contract_expiry_days setting)"`
+	}
+
+	type checkContractExpirationsResult struct {
+		Days      int              `json:"days" jsonschema:"How many days ahead contracts were checked"`
+		Contracts []contractExpiry `json:"contracts"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "check_contract_expirations",
+		Description: "List active contracts ending soon or already past their end date",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args checkContractExpirationsArgs) (*mcp.CallToolResult, *checkContractExpirationsResult, error) {
+		if args.Days < 0 {
+			return nil, nil, validationError("days cannot be negative")
+		}
+		if args.Days == 0 {
+			args.Days = h.getIntSetting(ctx, "contract_expiry_days")
+		}
+
+		expiring, err := h.expiringContracts(ctx, args.Days)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		text := fmt.Sprintf("No active contracts end within %d days.\n", args.Days)
+		if len(expiring) > 0 {
+			text = fmt.Sprintf("Contracts ending within %d days:\n", args.Days)
+			for _, e := range expiring {
+				text += fmt.Sprintf("- %s\n", e)
+			}
+			text += "\nUse renew_contract to continue a contract, or update_contract_status to complete it.\n"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &checkContractExpirationsResult{Days: args.Days, Contracts: expiring}, nil
+	})
+
+	// Renew Contract tool
+	type renewContractArgs struct {
+		ContractNumber    string   `json:"contract_number" jsonschema:"Contract to renew"`
+		NewContractNumber string   `json:"new_contract_number" jsonschema:"Contract number for the renewal"`
+		Name              string   `json:"name,omitempty" jsonschema:"Name of the renewal (default: same as the contract)"`
+		StartDate         string   `json:"start_date,omitempty" jsonschema:"Start of the renewal (default: the day after the contract ends, or today)"`
+		EndDate           string   `json:"end_date,omitempty" jsonschema:"End of the renewal (default: same length as the contract, or ongoing)"`
+		HourlyRate        *float64 `json:"hourly_rate,omitempty" jsonschema:"Hourly rate of the renewal (default: the rate in effect when the contract ends)"`
+		CompletePrevious  *bool    `json:"complete_previous,omitempty" jsonschema:"Mark the renewed contract completed (default: true)"`
+	}
+
+	type renewContractResult struct {
+		Contract *models.Contract `json:"contract" jsonschema:"The new contract"`
+		Previous *models.Contract `json:"previous" jsonschema:"The renewed contract"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "renew_contract",
+		Description: "Renew a contract as a new contract with the same client, terms, retainer and premium rates, new dates and optionally a new rate",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args renewContractArgs) (*mcp.CallToolResult, *renewContractResult, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		c, err := h.loadContract(ctx, args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		if args.NewContractNumber == "" {
+			return nil, nil, validationError("new_contract_number is required")
+		}
+		if err := h.checkClientActive(ctx, c.ClientID); err != nil {
+			return nil, nil, err
+		}
+		var exists int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM contracts WHERE contract_number = ?", args.NewContractNumber).Scan(&exists); err != nil {
+			return nil, nil, fmt.Errorf("failed to check contract number: %w", err)
+		}
+		if exists > 0 {
+			return nil, nil, conflictError("contract %s already exists", args.NewContractNumber)
+		}
+
+		start := h.today(ctx)
+		if c.EndDate != nil {
+			start = c.EndDate.AddDate(0, 0, 1)
+		}
+		if args.StartDate != "" {
+			if start, err = h.parseDate(ctx, args.StartDate); err != nil {
+				return nil, nil, validationError("invalid start date: %w", err)
+			}
+		}
+		if !start.After(c.StartDate) {
+			return nil, nil, validationError("renewal must start after %s started on %s", c.ContractNumber, c.StartDate.Format("2006-01-02"))
+		}
+
+		var end *time.Time
+		if args.EndDate != "" {
+			endDate, err := h.parseDate(ctx, args.EndDate)
+			if err != nil {
+				return nil, nil, validationError("invalid end date: %w", err)
+			}
+			end = &endDate
+		} else if c.EndDate != nil {
+			endDate := start.AddDate(0, 0, int(c.EndDate.Sub(c.StartDate).Hours()/24))
+			end = &endDate
+		}
+		var endValue interface{}
+		if end != nil {
+			if end.Before(start) {
+				return nil, nil, validationError("end date %s is before the start date %s", end.Format("2006-01-02"), start.Format("2006-01-02"))
+			}
+			endValue = end.Format("2006-01-02")
+		}
+
+		schedules, err := h.contractRateSchedules(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		rate := rateOn(c.HourlyRate, schedules[c.ID], start.AddDate(0, 0, -1))
+		if args.HourlyRate != nil {
+			if *args.HourlyRate <= 0 {
+				return nil, nil, validationError("hourly rate must be positive")
+			}
+			rate = money.FromFloat(*args.HourlyRate)
+		}
+		name := c.Name
+		if args.Name != "" {
+			name = args.Name
+		}
+
+		// The renewed contract ends the day before the renewal starts
+		complete := (args.CompletePrevious == nil || *args.CompletePrevious) && c.Status != "completed" && c.Status != "cancelled"
+		previousEnd := start.AddDate(0, 0, -1)
+		if complete && c.EndDate != nil && c.EndDate.Before(previousEnd) {
+			previousEnd = *c.EndDate
+		}
+		if complete {
+			outside, err := h.countOutside(ctx, c.ID, c.StartDate, &previousEnd)
+			if err != nil {
+				return nil, nil, err
+			}
+			if outside > 0 {
+				return nil, nil, conflictError("%d time entries on %s are dated after %s; start the renewal later, or pass complete_previous false and move them to the renewal with move_time_entries",
+					outside, c.ContractNumber, previousEnd.Format("2006-01-02"))
+			}
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO contracts (client_id, contract_number, name, hourly_rate_cents, currency, contract_type, start_date, end_date,
+			                       payment_terms, notes, retainer_hours, retainer_fee_cents, overage_rate_cents, rollover_months, payment_method_id)
+			SELECT client_id, ?, ?, ?, currency, contract_type, ?, ?,
+			       payment_terms, notes, retainer_hours, retainer_fee_cents, overage_rate_cents, rollover_months, payment_method_id
+			FROM contracts WHERE id = ?
+		`, args.NewContractNumber, name, rate, start.Format("2006-01-02"), endValue, c.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to add contract: %w", err)
+		}
+		newID, _ := result.LastInsertId()
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO contract_rate_rules (contract_id, kind, multiplier, daily_hours)
+			SELECT ?, kind, multiplier, daily_hours FROM contract_rate_rules WHERE contract_id = ?
+		`, newID, c.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to copy rate rules: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO contract_services (contract_id, service_id, hourly_rate_cents)
+			SELECT ?, service_id, hourly_rate_cents FROM contract_services WHERE contract_id = ?
+		`, newID, c.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to copy service rates: %w", err)
+		}
+
+		if complete {
+			_, err = tx.ExecContext(ctx, `
+				UPDATE contracts SET status = 'completed', end_date = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+			`, previousEnd.Format("2006-01-02"), c.ID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to complete contract: %w", err)
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		period := "from " + start.Format("2006-01-02")
+		if end != nil {
+			period += " to " + end.Format("2006-01-02")
+		}
+		text := fmt.Sprintf("Renewed %s as %s (%s) %s at %s/hour", c.ContractNumber, args.NewContractNumber, name, period, rate.Format(c.Currency))
+		if complete {
+			text += fmt.Sprintf("\n%s marked completed, ending %s", c.ContractNumber, previousEnd.Format("2006-01-02"))
+		}
+		renewal, err := h.loadContract(ctx, args.NewContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		previous, err := h.loadContract(ctx, c.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &renewContractResult{Contract: renewal, Previous: previous}, nil
+	})
+}
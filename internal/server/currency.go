@@ -0,0 +1,308 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/fx"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// validateCurrencyCode accepts three-letter ISO 4217 codes such as USD
+func validateCurrencyCode(value string) error {
+	if len(value) != 3 || strings.ToUpper(value) != value || strings.Trim(value, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return validationError("must be a three-letter uppercase currency code such as USD")
+	}
+	return nil
+}
+
+func (h *Handler) baseCurrency(ctx context.Context) string {
+	return h.getSetting(ctx, "base_currency")
+}
+
+// exchangeRate returns how many units of quote one unit of base buys on the
+// given date, using the stored rate closest to (preferably on or before)
+// that date. Inverse rates and rates via EUR are used when there is no
+// direct rate.
+func (h *Handler) exchangeRate(ctx context.Context, base, quote string, on time.Time) (float64, bool, error) {
+	if base == quote {
+		return 1, true, nil
+	}
+
+	lookup := func(base, quote string) (float64, bool, error) {
+		day := on.Format("2006-01-02")
+		var rate float64
+		err := h.db.QueryRowContext(ctx, `
+			SELECT rate FROM exchange_rates
+			WHERE base_currency = ? AND quote_currency = ?
+			ORDER BY CASE WHEN date <= ? THEN 0 ELSE 1 END, ABS(julianday(date) - julianday(?))
+			LIMIT 1
+		`, base, quote, day, day).Scan(&rate)
+		if err == sql.ErrNoRows {
+			return 0, false, nil
+		}
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to look up exchange rate: %w", err)
+		}
+		return rate, true, nil
+	}
+
+	direct := func(base, quote string) (float64, bool, error) {
+		if base == quote {
+			return 1, true, nil
+		}
+		if rate, ok, err := lookup(base, quote); ok || err != nil {
+			return rate, ok, err
+		}
+		rate, ok, err := lookup(quote, base)
+		if !ok || err != nil {
+			return 0, ok, err
+		}
+		return 1 / rate, true, nil
+	}
+
+	if rate, ok, err := direct(base, quote); ok || err != nil {
+		return rate, ok, err
+	}
+
+	// Cross rate through the euro, which is how ECB rates are quoted
+	toBase, ok, err := direct("EUR", base)
+	if !ok || err != nil {
+		return 0, false, err
+	}
+	toQuote, ok, err := direct("EUR", quote)
+	if !ok || err != nil {
+		return 0, false, err
+	}
+	return toQuote / toBase, true, nil
+}
+
+// convertTotals converts per-currency amounts into the target currency.
+// Currencies without a usable rate are returned in missing and left out of
+// the total.
+func (h *Handler) convertTotals(ctx context.Context, totals map[string]money.Cents, target string, on time.Time) (money.Cents, []string, error) {
+	var total money.Cents
+	var missing []string
+	for currency, amount := range totals {
+		rate, ok, err := h.exchangeRate(ctx, currency, target, on)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !ok {
+			missing = append(missing, currency)
+			continue
+		}
+		total += amount.Convert(rate)
+	}
+	sort.Strings(missing)
+	return total, missing, nil
+}
+
+// formatConvertedTotal describes a converted total, noting currencies that
+// could not be converted
+func formatConvertedTotal(target string, total money.Cents, missing []string) string {
+	text := total.Format(target)
+	if len(missing) > 0 {
+		text += fmt.Sprintf(" (excluding %s: no exchange rate)", strings.Join(missing, ", "))
+	}
+	return text
+}
+
+// registerCurrencyTools registers exchange rate management tools
+func registerCurrencyTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	type exchangeRate struct {
+		Date          time.Time `json:"date"`
+		BaseCurrency  string    `json:"base_currency"`
+		QuoteCurrency string    `json:"quote_currency"`
+		Rate          float64   `json:"rate"`
+		Source        string    `json:"source" jsonschema:"manual or ecb"`
+	}
+
+	// Set Exchange Rate tool
+	type setExchangeRateArgs struct {
+		BaseCurrency  string  `json:"base_currency" jsonschema:"Currency being priced (e.g. EUR)"`
+		QuoteCurrency string  `json:"quote_currency" jsonschema:"Currency the price is in (e.g. USD)"`
+		Rate          float64 `json:"rate" jsonschema:"Units of quote currency per one unit of base currency (e.g. 1.08 for 1 EUR = 1.08 USD)"`
+		Date          string  `json:"date,omitempty" jsonschema:"Date the rate applies from (default: today)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_exchange_rate",
+		Description: "Record an exchange rate used to convert report totals into the base currency",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setExchangeRateArgs) (*mcp.CallToolResult, *exchangeRate, error) {
+		base := strings.ToUpper(strings.TrimSpace(args.BaseCurrency))
+		quote := strings.ToUpper(strings.TrimSpace(args.QuoteCurrency))
+		if err := validateCurrencyCode(base); err != nil {
+			return nil, nil, validationError("invalid base currency: %w", err)
+		}
+		if err := validateCurrencyCode(quote); err != nil {
+			return nil, nil, validationError("invalid quote currency: %w", err)
+		}
+		if base == quote {
+			return nil, nil, validationError("base and quote currency must differ")
+		}
+		if args.Rate <= 0 {
+			return nil, nil, validationError("rate must be positive")
+		}
+
+		date := h.today(ctx)
+		if args.Date != "" {
+			var err error
+			date, err = h.parseDate(ctx, args.Date)
+			if err != nil {
+				return nil, nil, validationError("invalid date: %w", err)
+			}
+		}
+
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO exchange_rates (date, base_currency, quote_currency, rate, source)
+			VALUES (?, ?, ?, ?, 'manual')
+			ON CONFLICT(date, base_currency, quote_currency) DO UPDATE SET
+				rate = excluded.rate,
+				source = excluded.source
+		`, date.Format("2006-01-02"), base, quote, args.Rate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to save exchange rate: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("Exchange rate set: 1 %s = %.6g %s from %s", base, args.Rate, quote, date.Format("2006-01-02")),
+				},
+			},
+		}, &exchangeRate{Date: truncateDay(date), BaseCurrency: base, QuoteCurrency: quote, Rate: args.Rate, Source: "manual"}, nil
+	})
+
+	// List Exchange Rates tool
+	type listExchangeRatesArgs struct {
+		Currency string `json:"currency,omitempty" jsonschema:"Only show rates involving this currency (optional)"`
+		Limit    int    `json:"limit,omitempty" jsonschema:"Maximum rates to return (default: 50)"`
+	}
+
+	type listExchangeRatesResult struct {
+		BaseCurrency string         `json:"base_currency" jsonschema:"Currency report totals are converted into"`
+		Rates        []exchangeRate `json:"rates"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_exchange_rates",
+		Description: "List stored exchange rates, newest first",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listExchangeRatesArgs) (*mcp.CallToolResult, *listExchangeRatesResult, error) {
+		if args.Limit <= 0 {
+			args.Limit = 50
+		}
+		currency := strings.ToUpper(strings.TrimSpace(args.Currency))
+
+		rows, err := db.QueryContext(ctx, `
+			SELECT date, base_currency, quote_currency, rate, source
+			FROM exchange_rates
+			WHERE ? = '' OR base_currency = ? OR quote_currency = ?
+			ORDER BY date DESC, base_currency, quote_currency
+			LIMIT ?
+		`, currency, currency, currency, args.Limit)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list exchange rates: %w", err)
+		}
+		defer rows.Close()
+
+		var rates []exchangeRate
+		for rows.Next() {
+			var r exchangeRate
+			if err := rows.Scan(&r.Date, &r.BaseCurrency, &r.QuoteCurrency, &r.Rate, &r.Source); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan exchange rate: %w", err)
+			}
+			rates = append(rates, r)
+		}
+
+		text := fmt.Sprintf("Found %d exchange rates (base currency for reports: %s):\n", len(rates), h.baseCurrency(ctx))
+		for _, r := range rates {
+			text += fmt.Sprintf("- %s: 1 %s = %.6g %s (%s)\n",
+				r.Date.Format("2006-01-02"), r.BaseCurrency, r.Rate, r.QuoteCurrency, r.Source)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &listExchangeRatesResult{BaseCurrency: h.baseCurrency(ctx), Rates: rates}, nil
+	})
+
+	// Fetch Exchange Rates tool
+	type fetchExchangeRatesArgs struct {
+		History  bool   `json:"history,omitempty" jsonschema:"Fetch the last 90 days instead of only the latest rates"`
+		FilePath string `json:"file_path,omitempty" jsonschema:"Load a saved ECB eurofxref XML file instead of downloading (optional)"`
+	}
+
+	type fetchExchangeRatesResult struct {
+		Rates int `json:"rates" jsonschema:"Number of rates stored"`
+		Days  int `json:"days" jsonschema:"Number of days the rates cover"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "fetch_exchange_rates",
+		Description: "Download European Central Bank reference rates (EUR based) into the exchange rate table",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args fetchExchangeRatesArgs) (*mcp.CallToolResult, *fetchExchangeRatesResult, error) {
+		var rates []fx.Rate
+		var err error
+		if args.FilePath != "" {
+			file, openErr := os.Open(expandHome(args.FilePath))
+			if openErr != nil {
+				return nil, nil, fmt.Errorf("failed to open rates file: %w", openErr)
+			}
+			defer file.Close()
+			rates, err = fx.ParseECB(file)
+		} else {
+			rates, err = fx.FetchECB(ctx, args.History)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Manually entered rates take precedence over fetched ones
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO exchange_rates (date, base_currency, quote_currency, rate, source)
+			VALUES (?, 'EUR', ?, ?, 'ecb')
+			ON CONFLICT(date, base_currency, quote_currency) DO UPDATE SET
+				rate = excluded.rate
+			WHERE exchange_rates.source = 'ecb'
+		`)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to prepare insert: %w", err)
+		}
+		defer stmt.Close()
+
+		dates := map[string]bool{}
+		for _, r := range rates {
+			day := r.Date.Format("2006-01-02")
+			if _, err := stmt.ExecContext(ctx, day, r.Currency, r.PerEUR); err != nil {
+				return nil, nil, fmt.Errorf("failed to save rate for %s: %w", r.Currency, err)
+			}
+			dates[day] = true
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("Stored %d ECB rates for %d days", len(rates), len(dates)),
+				},
+			},
+		}, &fetchExchangeRatesResult{Rates: len(rates), Days: len(dates)}, nil
+	})
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/database"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// expandHome resolves a leading ~/ in a user supplied path
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		homeDir, _ := os.UserHomeDir()
+		return filepath.Join(homeDir, path[2:])
+	}
+	return path
+}
+
+// registerDataTools registers whole-database export and restore tools
+func registerDataTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Export Data tool
+	type exportDataArgs struct {
+		FilePath string `json:"file_path,omitempty" jsonschema:"Where to write the export (default: ~/Downloads/hours_export_YYYY-MM-DD.json)"`
+	}
+
+	type exportDataResult struct {
+		FilePath string         `json:"file_path"`
+		Version  int            `json:"version" jsonschema:"Version of the export format"`
+		Tables   map[string]int `json:"tables" jsonschema:"Rows exported per table"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_data",
+		Description: "Export every table to a single versioned JSON file as a portable, inspectable backup",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args exportDataArgs) (*mcp.CallToolResult, *exportDataResult, error) {
+		export, err := database.ExportData(ctx, db)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to export data: %w", err)
+		}
+
+		path := expandHome(args.FilePath)
+		if path == "" {
+			homeDir, _ := os.UserHomeDir()
+			path = filepath.Join(homeDir, "Downloads", fmt.Sprintf("hours_export_%s.json", time.Now().Format("2006-01-02")))
+		}
+
+		data, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode export: %w", err)
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return nil, nil, fmt.Errorf("failed to write export: %w", err)
+		}
+
+		tables := make([]string, 0, len(export.Tables))
+		counts := map[string]int{}
+		for table, rows := range export.Tables {
+			tables = append(tables, table)
+			counts[table] = len(rows)
+		}
+		sort.Strings(tables)
+
+		text := fmt.Sprintf("Exported %d tables to %s\n", len(tables), path)
+		for _, table := range tables {
+			text += fmt.Sprintf("- %s: %d rows\n", table, counts[table])
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &exportDataResult{FilePath: path, Version: export.Version, Tables: counts}, nil
+	})
+
+	// Import Data tool
+	type importDataArgs struct {
+		FilePath string `json:"file_path" jsonschema:"Path to a file written by export_data"`
+	}
+
+	type importDataResult struct {
+		Restored map[string]int `json:"restored" jsonschema:"Rows restored per table"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "import_data",
+		Description: "Restore a JSON export from export_data into an empty database with the same schema version",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args importDataArgs) (*mcp.CallToolResult, *importDataResult, error) {
+		file, err := os.Open(expandHome(args.FilePath))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open export: %w", err)
+		}
+		defer file.Close()
+
+		var export database.Export
+		decoder := json.NewDecoder(file)
+		decoder.UseNumber()
+		if err := decoder.Decode(&export); err != nil {
+			return nil, nil, fmt.Errorf("failed to read export: %w", err)
+		}
+
+		counts, err := database.ImportData(ctx, db, &export)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to import data: %w", err)
+		}
+
+		tables := make([]string, 0, len(counts))
+		total := 0
+		for table, count := range counts {
+			tables = append(tables, table)
+			total += count
+		}
+		sort.Strings(tables)
+
+		text := fmt.Sprintf("Restored %d rows from export of %s\n", total, export.ExportedAt.Format("2006-01-02 15:04"))
+		for _, table := range tables {
+			text += fmt.Sprintf("- %s: %d rows\n", table, counts[table])
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &importDataResult{Restored: counts}, nil
+	})
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/austin/hours-mcp/internal/models"
+)
+
+// invoiceFormat is what create_invoice writes for an invoice: a plain or a
+// PDF/A file, with or without the JSON sidecar
+type invoiceFormat struct {
+	archival bool
+	json     bool
+}
+
+// invoiceFormats are the file formats a client can prefer, by name
+var invoiceFormats = map[string]invoiceFormat{
+	"pdf":        {},
+	"pdf_a":      {archival: true},
+	"pdf_json":   {json: true},
+	"pdf_a_json": {archival: true, json: true},
+}
+
+// Invoice details: every time entry listed, or one line per contract and
+// rate
+const (
+	invoiceDetailDetailed     = "detailed"
+	invoiceDetailConsolidated = "consolidated"
+)
+
+var invoiceDetails = []string{invoiceDetailDetailed, invoiceDetailConsolidated}
+
+// normalizeDeliveryMethod validates a client's preferred delivery method;
+// empty clears it
+func normalizeDeliveryMethod(value string) (string, error) {
+	method := strings.ToLower(strings.TrimSpace(value))
+	if method != "" && !slices.Contains(deliveryMethods, method) {
+		return "", validationError("invalid delivery method '%s': must be %s", value, strings.Join(deliveryMethods, ", "))
+	}
+	return method, nil
+}
+
+// normalizeInvoiceFormat validates a client's preferred file format; empty
+// clears it
+func normalizeInvoiceFormat(value string) (string, error) {
+	format := strings.ToLower(strings.TrimSpace(value))
+	if _, ok := invoiceFormats[format]; format != "" && !ok {
+		return "", validationError("invalid invoice format '%s': must be pdf, pdf_a, pdf_json or pdf_a_json", value)
+	}
+	return format, nil
+}
+
+// normalizeInvoiceDetail validates a level of invoice detail; empty clears
+// it
+func normalizeInvoiceDetail(value string) (string, error) {
+	detail := strings.ToLower(strings.TrimSpace(value))
+	if detail != "" && !slices.Contains(invoiceDetails, detail) {
+		return "", validationError("invalid invoice detail '%s': must be %s", value, strings.Join(invoiceDetails, " or "))
+	}
+	return detail, nil
+}
+
+// deliveryText describes how a client wants its invoices delivered, e.g.
+// "by portal (https://ap.example.com)"; empty when no method is set
+func deliveryText(c *models.Client) string {
+	if c.DeliveryMethod == "" {
+		return ""
+	}
+	text := "by " + c.DeliveryMethod
+	if c.DeliveryNote != "" {
+		text += fmt.Sprintf(" (%s)", c.DeliveryNote)
+	}
+	return text
+}
+
+// deliveryHint tells how to deliver a client's new invoices, following its
+// preferred method; empty when it has none
+func deliveryHint(c *models.Client) string {
+	switch c.DeliveryMethod {
+	case "":
+		return ""
+	case deliveryEmail:
+		return fmt.Sprintf("Delivery: %s, with email_invoice", deliveryText(c))
+	case deliveryPayPal:
+		return fmt.Sprintf("Delivery: %s, with export_paypal_invoice and send=true", deliveryText(c))
+	}
+	return fmt.Sprintf("Delivery: %s as %s prefers; record it with mark_invoice_sent once delivered", deliveryText(c), c.Name)
+}
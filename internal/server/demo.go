@@ -0,0 +1,319 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/austin/hours-mcp/internal/store"
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// demoTables are the tables that must be empty before demo data is added,
+// so it never mixes with real records
+var demoTables = []string{"business_info", "payment_methods", "clients", "contracts", "time_entries", "invoices", "expenses"}
+
+// demoClient is a made-up client with one or more contracts. Addresses use
+// reserved example domains so no demo email can reach anyone. A slow payer
+// leaves its latest invoice past due unpaid.
+type demoClient struct {
+	client    models.Client
+	recipient string
+	email     string
+	slowPayer bool
+	contracts []demoContract
+}
+
+// demoContract is a made-up contract; endsAgo ends it that many months
+// before the current one, or never when 0
+type demoContract struct {
+	number  string
+	name    string
+	rate    money.Cents
+	endsAgo int
+	work    []string
+}
+
+var demoClients = []demoClient{
+	{
+		client: models.Client{Name: "Northwind Traders", Address: "500 Market Street", City: "San Francisco", State: "CA",
+			ZipCode: "94105", Country: "USA", DefaultCurrency: "USD"},
+		recipient: "Dana Rivers",
+		email:     "ap@northwind.example.com",
+		contracts: []demoContract{
+			{number: "NW-001", name: "Web Platform", rate: 15000, work: []string{
+				"Checkout flow redesign", "API pagination fixes", "Code review and pairing", "Deploy pipeline cleanup", "Sprint planning"}},
+			{number: "NW-002", name: "Mobile App Discovery", rate: 13500, endsAgo: 1, work: []string{
+				"Stakeholder interviews", "Prototype navigation", "Discovery report"}},
+		},
+	},
+	{
+		client: models.Client{Name: "Contoso Health", Address: "200 Clarendon Street", City: "Boston", State: "MA",
+			ZipCode: "02116", Country: "USA", DefaultCurrency: "USD"},
+		recipient: "Sam Lee",
+		email:     "billing@contoso.example.com",
+		slowPayer: true,
+		contracts: []demoContract{
+			{number: "CH-001", name: "Data Pipeline", rate: 16000, work: []string{
+				"Ingestion job tuning", "Schema migration", "On-call handover", "Data quality checks"}},
+		},
+	},
+	{
+		client: models.Client{Name: "Fabrikam GmbH", Address: "Friedrichstraße 100", City: "Berlin", ZipCode: "10117",
+			Country: "Germany", DefaultCurrency: "EUR", Locale: "de-DE", TimeZone: "Europe/Berlin"},
+		recipient: "Jonas Weber",
+		email:     "rechnung@fabrikam.example.com",
+		contracts: []demoContract{
+			{number: "FB-001", name: "API Integration", rate: 12000, work: []string{
+				"Partner API integration", "Webhook retries", "Integration tests", "Weekly sync call"}},
+		},
+	},
+}
+
+// registerDemoTools registers the tool that fills an empty database with
+// sample data
+func registerDemoTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	type seedDemoDataArgs struct {
+		Months int `json:"months,omitempty" jsonschema:"Months of history to create before the current one, 1-12 (default 3)"`
+	}
+
+	type seedDemoDataResult struct {
+		Clients     int                    `json:"clients"`
+		Contracts   int                    `json:"contracts"`
+		TimeEntries int                    `json:"time_entries"`
+		Hours       float64                `json:"hours"`
+		Invoices    int                    `json:"invoices"`
+		Unbilled    map[string]money.Cents `json:"unbilled" jsonschema:"Value of this month's work left to invoice, per currency"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "seed_demo_data",
+		Description: "Fill an empty database with made-up sample data to try every tool on: a business, payment methods, clients with recipients, contracts, months of time entries and paid, sent and overdue invoices. Refuses to run once any real data exists",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args seedDemoDataArgs) (*mcp.CallToolResult, *seedDemoDataResult, error) {
+		if args.Months == 0 {
+			args.Months = 3
+		}
+		if args.Months < 1 || args.Months > 12 {
+			return nil, nil, validationError("months must be between 1 and 12")
+		}
+
+		for _, table := range demoTables {
+			var count int
+			if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&count); err != nil {
+				return nil, nil, fmt.Errorf("failed to check %s: %w", table, err)
+			}
+			if count > 0 {
+				return nil, nil, conflictError("the database is not empty (%s has %d rows); demo data is only added to an empty database, e.g. one opened with --db :memory:", table, count)
+			}
+		}
+
+		today := h.today(ctx)
+		thisMonth := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+		start := thisMonth.AddDate(0, -args.Months, 0)
+		// The same data every time, so demos and tests can rely on it
+		rng := rand.New(rand.NewSource(1))
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+		txStore := h.store.WithTx(tx)
+
+		if err := seedDemoBusiness(ctx, tx); err != nil {
+			return nil, nil, err
+		}
+
+		result := &seedDemoDataResult{Unbilled: map[string]money.Cents{}}
+		for _, dc := range demoClients {
+			c := dc.client
+			c.TaxTreatment = taxTreatmentStandard
+			c.Notes = "Demo client created by seed_demo_data"
+			clientID, err := txStore.Clients.Create(ctx, &c, "{}")
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to add demo client: %w", err)
+			}
+			// Clients date from when their contracts start, for client_timeline
+			if _, err := tx.ExecContext(ctx, "UPDATE clients SET created_at = ? WHERE id = ?", start.Format("2006-01-02 15:04:05"), clientID); err != nil {
+				return nil, nil, fmt.Errorf("failed to date demo client: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO recipients (client_id, name, email, is_primary) VALUES (?, ?, ?, TRUE)
+			`, clientID, dc.recipient, dc.email); err != nil {
+				return nil, nil, fmt.Errorf("failed to add demo recipient: %w", err)
+			}
+			result.Clients++
+
+			// Work per month, to invoice at the start of the next one
+			type monthWork struct {
+				entries []string
+				lines   []store.InvoiceLine
+				total   money.Cents
+			}
+			months := map[time.Time]*monthWork{}
+			for _, dct := range dc.contracts {
+				var end *time.Time
+				if dct.endsAgo > 0 {
+					e := thisMonth.AddDate(0, -dct.endsAgo, 0).AddDate(0, 0, -1)
+					end = &e
+				}
+				contract := &models.Contract{
+					ClientID: clientID, ContractNumber: dct.number, Name: dct.name, HourlyRate: dct.rate,
+					Currency: c.DefaultCurrency, ContractType: "hourly", StartDate: start, EndDate: end, PaymentTerms: "Net 30",
+				}
+				contractID, err := txStore.Contracts.Create(ctx, contract)
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to add demo contract: %w", err)
+				}
+				if end != nil {
+					if _, err := tx.ExecContext(ctx, "UPDATE contracts SET status = 'completed' WHERE id = ?", contractID); err != nil {
+						return nil, nil, fmt.Errorf("failed to complete demo contract: %w", err)
+					}
+				}
+				result.Contracts++
+
+				lines := map[time.Time]*store.InvoiceLine{}
+				for day := start; day.Before(today) && (end == nil || !day.After(*end)); day = day.AddDate(0, 0, 1) {
+					// Most weekdays, a few hours each
+					if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday || rng.Intn(10) < 4 {
+						continue
+					}
+					hours := float64(2+rng.Intn(10)) / 2
+					description := dct.work[rng.Intn(len(dct.work))]
+					entryID, err := txStore.Entries.Create(ctx, clientID, contractID, dct.number, day, hours, description, nil, false)
+					if err != nil {
+						return nil, nil, fmt.Errorf("failed to add demo time entry: %w", err)
+					}
+					result.TimeEntries++
+					result.Hours += hours
+
+					amount := money.ForHours(dct.rate, hours)
+					month := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+					if month.Equal(thisMonth) {
+						result.Unbilled[c.DefaultCurrency] += amount
+						continue
+					}
+					if months[month] == nil {
+						months[month] = &monthWork{}
+					}
+					months[month].entries = append(months[month].entries, entryID)
+					months[month].total += amount
+					if lines[month] == nil {
+						lines[month] = &store.InvoiceLine{ContractID: contractID, Rate: dct.rate}
+					}
+					lines[month].Hours += hours
+					lines[month].Amount += amount
+				}
+				for month, line := range lines {
+					months[month].lines = append(months[month].lines, *line)
+				}
+			}
+
+			for month := start; month.Before(thisMonth); month = month.AddDate(0, 1, 0) {
+				work := months[month]
+				if work == nil {
+					continue
+				}
+				issued := month.AddDate(0, 1, 0)
+				due := issued.AddDate(0, 0, 30)
+				invoice := &models.Invoice{
+					ClientID:      clientID,
+					InvoiceNumber: fmt.Sprintf("INV-%s-%s", issued.Format("200601"), uuid.New().String()[:8]),
+					IssueDate:     issued,
+					DueDate:       due,
+					TotalAmount:   work.total,
+					TaxTreatment:  taxTreatmentStandard,
+					Currency:      c.DefaultCurrency,
+				}
+				invoiceID, err := txStore.Invoices.Create(ctx, invoice, "")
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to add demo invoice: %w", err)
+				}
+				if err := txStore.Entries.SetInvoiceAll(ctx, work.entries, invoiceID); err != nil {
+					return nil, nil, fmt.Errorf("failed to link demo time entries: %w", err)
+				}
+				if err := txStore.Invoices.AddLines(ctx, invoiceID, work.lines); err != nil {
+					return nil, nil, fmt.Errorf("failed to add demo invoice lines: %w", err)
+				}
+				if err := txStore.Invoices.MarkSent(ctx, invoiceID, issued.Add(10*time.Hour), dc.email, "email"); err != nil {
+					return nil, nil, fmt.Errorf("failed to send demo invoice: %w", err)
+				}
+				// Invoices past due are paid, apart from a slow payer's
+				// latest one, left overdue to chase
+				switch {
+				case !due.Before(today):
+				case dc.slowPayer && !due.AddDate(0, 1, 0).Before(today):
+					if _, err := txStore.Invoices.SetStatus(ctx, invoice.InvoiceNumber, "overdue", nil); err != nil {
+						return nil, nil, fmt.Errorf("failed to update demo invoice: %w", err)
+					}
+				default:
+					paid := issued.AddDate(0, 0, 10+rng.Intn(20))
+					if _, err := txStore.Invoices.SetStatus(ctx, invoice.InvoiceNumber, "paid", &paid); err != nil {
+						return nil, nil, fmt.Errorf("failed to update demo invoice: %w", err)
+					}
+				}
+				result.Invoices++
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit demo data: %w", err)
+		}
+
+		text := fmt.Sprintf("Added demo data from %s: %d clients, %d contracts, %d time entries (%.2f hours) and %d invoices\n",
+			start.Format("2006-01-02"), result.Clients, result.Contracts, result.TimeEntries, result.Hours, result.Invoices)
+		text += fmt.Sprintf("This month's work is left unbilled: %s\n", formatCurrencyTotals(result.Unbilled))
+		text += "Try list_invoices, unbilled_summary, client_timeline for Northwind Traders or create_invoice for this month"
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+}
+
+// seedDemoBusiness adds the made-up business invoices are issued by and
+// accounts in USD and EUR to be paid into
+func seedDemoBusiness(ctx context.Context, tx *sql.Tx) error {
+	taxID := "00-0000000"
+	if err := encryptFields(&taxID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO business_info (id, business_name, contact_name, email, phone, address, city, state, zip_code, country, tax_id, website, logo_path, invoice_prefix, updated_at)
+		VALUES (1, 'Sample Studio LLC', 'Alex Example', 'alex@example.com', '555-0100', '100 Demo Way', 'Springfield', 'OR', '97477', 'USA', ?, 'https://example.com', '', 'INV', ?)
+	`, taxID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to add demo business: %w", err)
+	}
+
+	for _, m := range []struct {
+		name, currency, bank, account, routing, swift string
+		isDefault                                     bool
+	}{
+		{"Demo Checking", "USD", "Example Bank", "000123456789", "110000000", "", true},
+		{"Demo EUR IBAN", "EUR", "Example Bank Europe", "DE00 0000 0000 0000 0000 00", "", "EXAMDEFFXXX", false},
+	} {
+		if err := encryptFields(&m.account, &m.routing, &m.swift); err != nil {
+			return err
+		}
+		notes := ""
+		if err := encryptFields(&notes); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO payment_methods (name, kind, currency, bank_name, account_number, routing_number, swift_code, notes, is_default)
+			VALUES (?, 'bank', ?, ?, ?, ?, ?, ?, ?)
+		`, m.name, m.currency, m.bank, m.account, m.routing, m.swift, notes, m.isDefault)
+		if err != nil {
+			return fmt.Errorf("failed to add demo payment method: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,431 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/mailer"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// digestCheckInterval is how often the scheduler checks whether the weekly
+// digest is due
+const digestCheckInterval = time.Hour
+
+// StartDigestScheduler emails the digest of the previous week on the day
+// named by the weekly_digest_day setting, once per week. It runs until ctx
+// is cancelled; a digest that fails to send is retried at the next check.
+func StartDigestScheduler(ctx context.Context, db *sql.DB) {
+	h := newHandler(db)
+	go func() {
+		ticker := time.NewTicker(digestCheckInterval)
+		defer ticker.Stop()
+		for {
+			h.digestIfDue(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (h *Handler) digestIfDue(ctx context.Context) {
+	day, ok := parseDigestDay(h.getSetting(ctx, "weekly_digest_day"))
+	if !ok || day != h.now(ctx).Weekday() {
+		return
+	}
+
+	start, end, err := h.parsePeriod(ctx, "last week")
+	if err != nil {
+		slog.Error("failed to find last week", "error", err)
+		return
+	}
+	subject := digestSubject(start, end)
+	var sent int
+	err = h.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM email_log WHERE invoice_id IS NULL AND subject = ? AND status = 'sent'
+	`, subject).Scan(&sent)
+	if err != nil {
+		slog.Error("failed to read email log", "error", err)
+		return
+	}
+	if sent > 0 {
+		return
+	}
+
+	digest, err := h.weeklyDigest(ctx, start, end)
+	if err != nil {
+		slog.Error("failed to compose weekly digest", "error", err)
+		return
+	}
+	to, err := h.digestRecipients(ctx, nil)
+	if err != nil {
+		slog.Error("failed to address weekly digest", "error", err)
+		return
+	}
+	if _, err := h.sendDigest(ctx, digest, to, false); err != nil {
+		slog.Error("failed to send weekly digest", "error", err)
+		return
+	}
+	slog.Info("sent weekly digest", "start", start.Format("2006-01-02"), "to", strings.Join(to, ", "))
+}
+
+// parseDigestDay reads the weekly_digest_day setting, reporting false when
+// the scheduled digest is off
+func parseDigestDay(value string) (time.Weekday, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if len(value) < 3 {
+		return 0, false
+	}
+	day, ok := weekdayNames[value[:3]]
+	return day, ok
+}
+
+// validateDigestDay accepts a weekday name or empty, which turns the
+// scheduled digest off
+func validateDigestDay(value string) error {
+	if _, ok := parseDigestDay(value); !ok && strings.TrimSpace(value) != "" {
+		return validationError("must be a weekday such as mon or monday, or empty to turn the digest off")
+	}
+	return nil
+}
+
+// validateAddressList accepts comma-separated email addresses
+func validateAddressList(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	if _, err := mail.ParseAddressList(value); err != nil {
+		return validationError("must be comma-separated email addresses")
+	}
+	return nil
+}
+
+// digestClient is one client's hours in a digest
+type digestClient struct {
+	ClientName    string  `json:"client_name"`
+	Hours         float64 `json:"hours"`
+	BillableHours float64 `json:"billable_hours"`
+}
+
+// digestInvoice is an invoice issued or paid in a digest's week
+type digestInvoice struct {
+	InvoiceNumber string      `json:"invoice_number"`
+	ClientName    string      `json:"client_name"`
+	Date          time.Time   `json:"date" jsonschema:"Issue date, or the date it was paid"`
+	Amount        money.Cents `json:"amount"`
+	Currency      string      `json:"currency"`
+}
+
+// weeklyDigest summarizes a week's hours, invoices issued and payments
+// received, with what is still owed at the end of it
+type weeklyDigest struct {
+	StartDate        string                 `json:"start_date"`
+	EndDate          string                 `json:"end_date"`
+	TotalHours       float64                `json:"total_hours"`
+	BillableHours    float64                `json:"billable_hours"`
+	Clients          []digestClient         `json:"clients"`
+	Issued           []digestInvoice        `json:"issued"`
+	IssuedTotals     map[string]money.Cents `json:"issued_totals"`
+	Payments         []digestInvoice        `json:"payments"`
+	ReceivedTotals   map[string]money.Cents `json:"received_totals"`
+	Outstanding      map[string]money.Cents `json:"outstanding" jsonschema:"Amount of the invoices issued by the end of the week that are still unpaid, per currency"`
+	OutstandingCount int                    `json:"outstanding_count"`
+	OverdueCount     int                    `json:"overdue_count" jsonschema:"Unpaid invoices past their due date"`
+}
+
+// weeklyDigest gathers the digest for the days from start to end
+func (h *Handler) weeklyDigest(ctx context.Context, start, end time.Time) (*weeklyDigest, error) {
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+	d := &weeklyDigest{
+		StartDate:      from,
+		EndDate:        to,
+		Clients:        []digestClient{},
+		Issued:         []digestInvoice{},
+		IssuedTotals:   map[string]money.Cents{},
+		Payments:       []digestInvoice{},
+		ReceivedTotals: map[string]money.Cents{},
+		Outstanding:    map[string]money.Cents{},
+	}
+
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT cl.name, SUM(te.hours), SUM(CASE WHEN te.billable AND NOT te.no_charge THEN te.hours ELSE 0 END)
+		FROM time_entries te
+		JOIN contracts ct ON te.contract_id = ct.id
+		JOIN clients cl ON ct.client_id = cl.id
+		WHERE te.date >= ? AND te.date <= ?
+		GROUP BY cl.id
+		ORDER BY SUM(te.hours) DESC, cl.name
+	`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize hours: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c digestClient
+		if err := rows.Scan(&c.ClientName, &c.Hours, &c.BillableHours); err != nil {
+			return nil, fmt.Errorf("failed to scan hours: %w", err)
+		}
+		d.Clients = append(d.Clients, c)
+		d.TotalHours += c.Hours
+		d.BillableHours += c.BillableHours
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if d.Issued, err = h.digestInvoices(ctx, "i.issue_date", "i.status != 'cancelled'", from, to); err != nil {
+		return nil, err
+	}
+	for _, inv := range d.Issued {
+		d.IssuedTotals[inv.Currency] += inv.Amount
+	}
+	if d.Payments, err = h.digestInvoices(ctx, "i.paid_date", "i.status = 'paid'", from, to); err != nil {
+		return nil, err
+	}
+	for _, inv := range d.Payments {
+		d.ReceivedTotals[inv.Currency] += inv.Amount
+	}
+
+	rows, err = h.db.QueryContext(ctx, `
+		SELECT currency, total_cents, due_date < ? FROM invoices
+		WHERE status NOT IN ('paid', 'written_off', 'cancelled', 'draft') AND issue_date <= ?
+	`, h.today(ctx).Format("2006-01-02"), to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load unpaid invoices: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var currency string
+		var amount money.Cents
+		var overdue bool
+		if err := rows.Scan(&currency, &amount, &overdue); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice: %w", err)
+		}
+		d.Outstanding[currency] += amount
+		d.OutstandingCount++
+		if overdue {
+			d.OverdueCount++
+		}
+	}
+	return d, rows.Err()
+}
+
+// digestInvoices returns the invoices matching where whose dateColumn falls
+// from from to to, oldest first
+func (h *Handler) digestInvoices(ctx context.Context, dateColumn, where, from, to string) ([]digestInvoice, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT i.invoice_number, c.name, `+dateColumn+`, i.total_cents, i.currency
+		FROM invoices i
+		JOIN clients c ON i.client_id = c.id
+		WHERE `+where+` AND `+dateColumn+` >= ? AND `+dateColumn+` <= ?
+		ORDER BY `+dateColumn+`, i.invoice_number
+	`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoices: %w", err)
+	}
+	defer rows.Close()
+
+	invoices := []digestInvoice{}
+	for rows.Next() {
+		var inv digestInvoice
+		if err := rows.Scan(&inv.InvoiceNumber, &inv.ClientName, &inv.Date, &inv.Amount, &inv.Currency); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice: %w", err)
+		}
+		invoices = append(invoices, inv)
+	}
+	return invoices, rows.Err()
+}
+
+// digestSubject is the subject of the digest email for a week, which the
+// scheduler also looks for in the email log to send each week only once
+func digestSubject(start, end time.Time) string {
+	return fmt.Sprintf("Weekly digest: %s - %s", start.Format("Jan 2"), end.Format("Jan 2, 2006"))
+}
+
+// render formats the digest as plain text or markdown
+func (d *weeklyDigest) render(markdown bool) string {
+	start, _ := time.Parse("2006-01-02", d.StartDate)
+	end, _ := time.Parse("2006-01-02", d.EndDate)
+	title := fmt.Sprintf("Week of %s - %s", start.Format("Mon Jan 2"), end.Format("Mon Jan 2, 2006"))
+
+	var text string
+	if markdown {
+		text = fmt.Sprintf("### %s\n\n", title)
+		text += fmt.Sprintf("#### Hours (%.2f, %.2f billable)\n\n", d.TotalHours, d.BillableHours)
+		if len(d.Clients) == 0 {
+			text += "No time logged.\n\n"
+		} else {
+			rows := make([][]string, 0, len(d.Clients))
+			for _, c := range d.Clients {
+				rows = append(rows, []string{c.ClientName, fmt.Sprintf("%.2f", c.Hours), fmt.Sprintf("%.2f", c.BillableHours)})
+			}
+			text += markdownTable([]string{"Client", "Hours", "Billable"}, rows, 1, 2) + "\n"
+		}
+		for _, section := range []struct {
+			heading  string
+			invoices []digestInvoice
+			totals   map[string]money.Cents
+			none     string
+		}{
+			{"Invoices issued", d.Issued, d.IssuedTotals, "No invoices issued.\n\n"},
+			{"Payments received", d.Payments, d.ReceivedTotals, "No payments received.\n\n"},
+		} {
+			text += fmt.Sprintf("#### %s\n\n", section.heading)
+			if len(section.invoices) == 0 {
+				text += section.none
+				continue
+			}
+			rows := make([][]string, 0, len(section.invoices))
+			for _, inv := range section.invoices {
+				rows = append(rows, []string{inv.Date.Format("2006-01-02"), inv.InvoiceNumber, inv.ClientName, inv.Amount.Format(inv.Currency)})
+			}
+			text += markdownTable([]string{"Date", "Invoice", "Client", "Amount"}, rows, 3) + "\n"
+			text += fmt.Sprintf("Total: %s\n\n", formatCurrencyTotals(section.totals))
+		}
+	} else {
+		text = title + "\n\n"
+		text += fmt.Sprintf("Hours: %.2f (%.2f billable)\n", d.TotalHours, d.BillableHours)
+		for _, c := range d.Clients {
+			text += fmt.Sprintf("- %s: %.2fh (%.2fh billable)\n", c.ClientName, c.Hours, c.BillableHours)
+		}
+		text += "\nInvoices issued: " + digestCount(len(d.Issued), d.IssuedTotals) + "\n"
+		for _, inv := range d.Issued {
+			text += fmt.Sprintf("- %s %s to %s, %s\n", inv.Date.Format("2006-01-02"), inv.InvoiceNumber, inv.ClientName, inv.Amount.Format(inv.Currency))
+		}
+		text += "\nPayments received: " + digestCount(len(d.Payments), d.ReceivedTotals) + "\n"
+		for _, inv := range d.Payments {
+			text += fmt.Sprintf("- %s %s from %s, %s\n", inv.Date.Format("2006-01-02"), inv.InvoiceNumber, inv.ClientName, inv.Amount.Format(inv.Currency))
+		}
+		text += "\n"
+	}
+
+	if d.OutstandingCount == 0 {
+		text += "No invoices are waiting for payment.\n"
+	} else {
+		text += "Unpaid invoices: " + digestCount(d.OutstandingCount, d.Outstanding)
+		if d.OverdueCount > 0 {
+			text += fmt.Sprintf(" (%d overdue)", d.OverdueCount)
+		}
+		text += "\n"
+	}
+	return text
+}
+
+// digestCount renders a number of invoices with their totals, or none
+func digestCount(n int, totals map[string]money.Cents) string {
+	if n == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d, %s", n, formatCurrencyTotals(totals))
+}
+
+// digestRecipients returns the addresses to send the digest to: to when
+// given, else the weekly_digest_to setting, else the business email
+func (h *Handler) digestRecipients(ctx context.Context, to []string) ([]string, error) {
+	if len(to) > 0 {
+		return normalizeCcAddresses(to)
+	}
+	if setting := h.getSetting(ctx, "weekly_digest_to"); setting != "" {
+		return normalizeCcAddresses(strings.Split(setting, ","))
+	}
+	business, err := h.loadBusinessInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if business == nil || business.Email == "" {
+		return nil, notConfiguredError("digest_recipients", "no address to send the digest to. Pass 'to', set weekly_digest_to or add an email with set_business_info")
+	}
+	return []string{business.Email}, nil
+}
+
+// sendDigest emails a digest and records the attempt in the email log
+func (h *Handler) sendDigest(ctx context.Context, d *weeklyDigest, to []string, markdown bool) (*mailer.Message, error) {
+	cfg, err := h.loadSMTPConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.FromName == "" {
+		if business, err := h.loadBusinessInfo(ctx); err == nil && business != nil {
+			cfg.FromName = business.BusinessName
+		}
+	}
+
+	start, _ := time.Parse("2006-01-02", d.StartDate)
+	end, _ := time.Parse("2006-01-02", d.EndDate)
+	msg := &mailer.Message{To: to, Subject: digestSubject(start, end), Body: d.render(markdown)}
+	sendErr := mailer.Send(cfg, msg)
+	h.logEmail(ctx, 0, to, msg.Subject, sendErr)
+	return msg, sendErr
+}
+
+// registerDigestTools registers the weekly digest tool
+func registerDigestTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	type weeklyDigestArgs struct {
+		Week   string   `json:"week,omitempty" jsonschema:"Week to summarize: 'last week' (default), 'this week' or any date in the week; weeks run Sunday to Saturday"`
+		Format string   `json:"format,omitempty" jsonschema:"Output format: text (default) or markdown"`
+		Email  bool     `json:"email,omitempty" jsonschema:"Email the digest over SMTP instead of only returning it"`
+		To     []string `json:"to,omitempty" jsonschema:"Addresses to email it to (default: the weekly_digest_to setting, or the business email)"`
+	}
+
+	type weeklyDigestResult struct {
+		weeklyDigest
+		SentTo []string `json:"sent_to,omitempty" jsonschema:"Addresses the digest was emailed to"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "weekly_digest",
+		Description: "Summarize a week's hours per client, invoices issued, payments received and what is still outstanding, optionally emailing it. With the weekly_digest_day setting, a server running over HTTP emails last week's digest every week",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args weeklyDigestArgs) (*mcp.CallToolResult, *weeklyDigestResult, error) {
+		markdown, err := parseOutputFormat(args.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+		if args.Week == "" {
+			args.Week = "last week"
+		}
+
+		start, end, err := h.parsePeriod(ctx, args.Week)
+		if err != nil {
+			date, dateErr := h.parseDate(ctx, args.Week)
+			if dateErr != nil {
+				return nil, nil, validationError("invalid week: %s", args.Week)
+			}
+			start = date.AddDate(0, 0, -int(date.Weekday()))
+			end = start.AddDate(0, 0, 6)
+		}
+
+		digest, err := h.weeklyDigest(ctx, start, end)
+		if err != nil {
+			return nil, nil, err
+		}
+		result := &weeklyDigestResult{weeklyDigest: *digest}
+		text := digest.render(markdown)
+
+		if args.Email {
+			to, err := h.digestRecipients(ctx, args.To)
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, err := h.sendDigest(ctx, digest, to, markdown); err != nil {
+				return nil, nil, fmt.Errorf("failed to send digest: %w", err)
+			}
+			result.SentTo = to
+			text = fmt.Sprintf("Digest emailed to %s\n\n", strings.Join(to, ", ")) + text
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+}
@@ -0,0 +1,262 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/austin/hours-mcp/internal/database"
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// documentKinds are the kinds of document a contract can keep
+var documentKinds = []string{"contract", "sow", "amendment", "nda", "other"}
+
+// contractDocuments returns the documents of a contract, oldest first,
+// noting those no longer at their path
+func (h *Handler) contractDocuments(ctx context.Context, contractID int) ([]models.ContractDocument, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT id, contract_id, kind, title, path, copied, added_at
+		FROM contract_documents WHERE contract_id = ?
+		ORDER BY added_at, id
+	`, contractID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contract documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents := []models.ContractDocument{}
+	for rows.Next() {
+		var d models.ContractDocument
+		if err := rows.Scan(&d.ID, &d.ContractID, &d.Kind, &d.Title, &d.Path, &d.Copied, &d.AddedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan contract document: %w", err)
+		}
+		if _, err := os.Stat(d.Path); err != nil {
+			d.Missing = true
+		}
+		documents = append(documents, d)
+	}
+	return documents, rows.Err()
+}
+
+// documentLabel describes a document in a line of text
+func documentLabel(d models.ContractDocument) string {
+	label := fmt.Sprintf("#%d %s", d.ID, d.Kind)
+	if d.Title != "" {
+		label += ": " + d.Title
+	}
+	label += " - " + d.Path
+	if d.Missing {
+		label += " (missing)"
+	}
+	return label
+}
+
+// copyDocument copies a contract document into the document folder next to
+// the database, under the contract's number, and returns the copy's path
+func copyDocument(contractNumber, path string) (string, error) {
+	dir, err := database.DocumentDir()
+	if err != nil {
+		return "", validationError("%w", err)
+	}
+	// Contract numbers may contain slashes, e.g. PO/2025/1
+	dir = filepath.Join(dir, strings.NewReplacer("/", "_", `\`, "_").Replace(contractNumber))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create document folder: %w", err)
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open document: %w", err)
+	}
+	defer src.Close()
+
+	// Keep earlier copies of a file with the same name, e.g. each year's SOW
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(filepath.Base(path), ext)
+	target := filepath.Join(dir, base+ext)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(target); os.IsNotExist(err) {
+			break
+		}
+		target = filepath.Join(dir, fmt.Sprintf("%s-%d%s", base, n, ext))
+	}
+	dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to copy document: %w", err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(target)
+		return "", fmt.Errorf("failed to copy document: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(target)
+		return "", fmt.Errorf("failed to copy document: %w", err)
+	}
+	return target, nil
+}
+
+// registerDocumentTools registers the tools keeping signed contracts,
+// statements of work and other files with a contract
+func registerDocumentTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Attach Contract Document tool
+	type attachContractDocumentArgs struct {
+		ContractNumber string `json:"contract_number" jsonschema:"Contract number or name"`
+		Path           string `json:"path" jsonschema:"Path to the file, e.g. ~/Documents/acme-msa-signed.pdf"`
+		Kind           string `json:"kind,omitempty" jsonschema:"contract, sow, amendment, nda or other (default: contract)"`
+		Title          string `json:"title,omitempty" jsonschema:"Short description, e.g. 'Signed MSA' (optional)"`
+		Copy           bool   `json:"copy,omitempty" jsonschema:"Keep a copy next to the database, so the document stays available if the original is moved (default: false)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "attach_contract_document",
+		Description: "Keep a file with a contract, such as the signed contract PDF or a statement of work; get_contract_details lists them",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args attachContractDocumentArgs) (*mcp.CallToolResult, *models.ContractDocument, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		c, err := h.loadContract(ctx, args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		if args.Kind == "" {
+			args.Kind = "contract"
+		}
+		if !slices.Contains(documentKinds, args.Kind) {
+			return nil, nil, validationError("invalid document kind '%s': must be %s", args.Kind, strings.Join(documentKinds, ", "))
+		}
+
+		path, err := filepath.Abs(expandHome(strings.TrimSpace(args.Path)))
+		if err != nil {
+			return nil, nil, validationError("invalid path: %w", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, nil, notFoundError("document", "document not found: %w", err)
+		}
+		if info.IsDir() {
+			return nil, nil, validationError("%s is a folder; attach the files in it one by one", path)
+		}
+		if args.Copy {
+			if path, err = copyDocument(c.ContractNumber, path); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		result, err := db.ExecContext(ctx, `
+			INSERT INTO contract_documents (contract_id, kind, title, path, copied) VALUES (?, ?, ?, ?, ?)
+		`, c.ID, args.Kind, strings.TrimSpace(args.Title), path, args.Copy)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to attach document: %w", err)
+		}
+		id, _ := result.LastInsertId()
+
+		documents, err := h.contractDocuments(ctx, c.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		var document models.ContractDocument
+		for _, d := range documents {
+			if d.ID == int(id) {
+				document = d
+			}
+		}
+
+		text := fmt.Sprintf("Attached %s to %s (ID: %d)", path, c.ContractNumber, id)
+		if args.Copy {
+			text += "; the copy is kept next to the database"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &document, nil
+	})
+
+	// List Contract Documents tool
+	type listContractDocumentsArgs struct {
+		ContractNumber string `json:"contract_number" jsonschema:"Contract number or name"`
+	}
+
+	type listContractDocumentsResult struct {
+		ContractNumber string                    `json:"contract_number"`
+		Documents      []models.ContractDocument `json:"documents"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_contract_documents",
+		Description: "List the files kept with a contract and where to find them",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listContractDocumentsArgs) (*mcp.CallToolResult, *listContractDocumentsResult, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		c, err := h.loadContract(ctx, args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		documents, err := h.contractDocuments(ctx, c.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		text := fmt.Sprintf("No documents attached to %s. Use attach_contract_document to keep the signed contract with it.", c.ContractNumber)
+		if len(documents) > 0 {
+			text = fmt.Sprintf("Documents of %s:\n", c.ContractNumber)
+			for _, d := range documents {
+				text += fmt.Sprintf("- %s, added %s\n", documentLabel(d), d.AddedAt.Local().Format("2006-01-02"))
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &listContractDocumentsResult{ContractNumber: c.ContractNumber, Documents: documents}, nil
+	})
+
+	// Remove Contract Document tool
+	type removeContractDocumentArgs struct {
+		ID int `json:"id" jsonschema:"ID of the document (see list_contract_documents)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "remove_contract_document",
+		Description: "Stop keeping a document with its contract; a copy kept next to the database is deleted, an original file is left alone",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args removeContractDocumentArgs) (*mcp.CallToolResult, *models.ContractDocument, error) {
+		var d models.ContractDocument
+		err := db.QueryRowContext(ctx, `
+			SELECT id, contract_id, kind, title, path, copied, added_at FROM contract_documents WHERE id = ?
+		`, args.ID).Scan(&d.ID, &d.ContractID, &d.Kind, &d.Title, &d.Path, &d.Copied, &d.AddedAt)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("document", "document %d not found", args.ID)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to find document: %w", err)
+		}
+
+		if _, err := db.ExecContext(ctx, "DELETE FROM contract_documents WHERE id = ?", d.ID); err != nil {
+			return nil, nil, fmt.Errorf("failed to remove document: %w", err)
+		}
+		text := fmt.Sprintf("Removed document %d (%s)", d.ID, d.Path)
+		if d.Copied {
+			if err := os.Remove(d.Path); err != nil && !os.IsNotExist(err) {
+				text += fmt.Sprintf("; the copy could not be deleted: %v", err)
+			} else {
+				text += "; its copy was deleted"
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &d, nil
+	})
+}
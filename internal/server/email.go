@@ -0,0 +1,542 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/mailer"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// invoiceEmail holds what is needed to address and fill an invoice email
+type invoiceEmail struct {
+	ID            int
+	InvoiceNumber string
+	ClientID      int
+	ClientName    string
+	IssueDate     time.Time
+	DueDate       time.Time
+	TotalAmount   money.Cents
+	Withholding   money.Cents
+	Currency      string
+	Status        string
+	PaidDate      sql.NullTime
+	PDFPath       string
+	BusinessName  string
+	ContactName   string
+
+	// Recipients chosen when the invoice was created, and addresses to copy
+	RecipientIDs []int
+	Cc           []string
+
+	// Today is the business's current day, which days overdue count to
+	Today time.Time
+}
+
+func (h *Handler) loadInvoiceEmail(ctx context.Context, invoiceNumber string) (*invoiceEmail, error) {
+	var inv invoiceEmail
+	err := h.db.QueryRowContext(ctx, `
+		SELECT i.id, i.invoice_number, i.client_id, c.name, i.issue_date, i.due_date, i.total_cents, i.withholding_cents, i.currency, i.status,
+		       i.paid_date, COALESCE(i.pdf_path, ''), COALESCE(b.business_name, ''), COALESCE(b.contact_name, '')
+		FROM invoices i
+		JOIN clients c ON i.client_id = c.id
+		LEFT JOIN business_info b ON b.id = 1
+		WHERE i.invoice_number = ?
+	`, invoiceNumber).Scan(&inv.ID, &inv.InvoiceNumber, &inv.ClientID, &inv.ClientName, &inv.IssueDate,
+		&inv.DueDate, &inv.TotalAmount, &inv.Withholding, &inv.Currency, &inv.Status, &inv.PaidDate, &inv.PDFPath, &inv.BusinessName, &inv.ContactName)
+	if err == sql.ErrNoRows {
+		return nil, notFoundError("invoice", "invoice %s not found", invoiceNumber)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoice: %w", err)
+	}
+
+	inv.Today = h.today(ctx)
+
+	var cc string
+	if err := h.db.QueryRowContext(ctx, "SELECT cc FROM invoices WHERE id = ?", inv.ID).Scan(&cc); err != nil {
+		return nil, fmt.Errorf("failed to load invoice cc: %w", err)
+	}
+	for _, addr := range strings.Split(cc, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			inv.Cc = append(inv.Cc, addr)
+		}
+	}
+
+	rows, err := h.db.QueryContext(ctx, "SELECT recipient_id FROM invoice_recipients WHERE invoice_id = ?", inv.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoice recipients: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice recipient: %w", err)
+		}
+		inv.RecipientIDs = append(inv.RecipientIDs, id)
+	}
+	return &inv, rows.Err()
+}
+
+// templateVars returns the placeholders available to invoice emails
+func (inv *invoiceEmail) templateVars(recipientName string) map[string]string {
+	// Tax withheld by the client is never paid to us
+	balance := inv.TotalAmount - inv.Withholding
+	if inv.Status == "paid" || inv.Status == "cancelled" {
+		balance = 0
+	}
+	if recipientName == "" {
+		recipientName = inv.ClientName
+	}
+	paidDate := ""
+	if inv.PaidDate.Valid {
+		paidDate = inv.PaidDate.Time.Format("2006-01-02")
+	}
+	daysOverdue := int(inv.Today.Sub(truncateDay(inv.DueDate)).Hours() / 24)
+	if daysOverdue < 0 {
+		daysOverdue = 0
+	}
+	return map[string]string{
+		"client":         inv.ClientName,
+		"invoice_number": inv.InvoiceNumber,
+		"amount":         inv.TotalAmount.Format(inv.Currency),
+		"balance":        balance.Format(inv.Currency),
+		"issue_date":     inv.IssueDate.Format("2006-01-02"),
+		"due_date":       inv.DueDate.Format("2006-01-02"),
+		"paid_date":      paidDate,
+		"days_overdue":   fmt.Sprintf("%d", daysOverdue),
+		"business":       inv.BusinessName,
+		"contact_name":   inv.ContactName,
+		"recipient_name": recipientName,
+	}
+}
+
+// clientRecipientEmails returns the client's recipients with the given IDs,
+// or all of them when ids is empty, that have an email address, primary
+// contact first
+func (h *Handler) clientRecipientEmails(ctx context.Context, clientID int, ids []int) ([]string, string, error) {
+	recipients, err := h.selectRecipients(ctx, clientID, ids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var addresses []string
+	var firstName string
+	for _, r := range recipients {
+		if r.Email == "" {
+			continue
+		}
+		if firstName == "" {
+			firstName = r.Name
+		}
+		addresses = append(addresses, fmt.Sprintf("%s <%s>", r.Name, r.Email))
+	}
+	return addresses, firstName, nil
+}
+
+// loadSMTPConfig reads the SMTP settings and the stored password
+func (h *Handler) loadSMTPConfig(ctx context.Context) (mailer.Config, error) {
+	var cfg mailer.Config
+	err := h.db.QueryRowContext(ctx, `
+		SELECT host, port, COALESCE(username, ''), from_address, COALESCE(from_name, ''), security
+		FROM smtp_config WHERE id = 1
+	`).Scan(&cfg.Host, &cfg.Port, &cfg.Username, &cfg.FromAddress, &cfg.FromName, &cfg.Security)
+	if err == sql.ErrNoRows {
+		return cfg, notConfiguredError("smtp", "SMTP is not configured. Use 'set_smtp_config' to set up outgoing email")
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to load SMTP config: %w", err)
+	}
+
+	cfg.Password, err = mailer.LoadPassword()
+	return cfg, err
+}
+
+// composeInvoiceEmail fills a template for an invoice and addresses it to
+// the given addresses, or else to the given recipients, the recipients the
+// invoice was created for or all of the client's recipients. Addresses
+// stored on the invoice are copied along with cc. The invoice PDF is
+// attached when attach is set.
+func (h *Handler) composeInvoiceEmail(ctx context.Context, inv *invoiceEmail, tpl *emailTemplate, to []string, recipientIDs []int, cc []string, attach bool) (*mailer.Message, error) {
+	recipientName := ""
+	if len(to) > 0 && len(recipientIDs) > 0 {
+		return nil, validationError("pass either 'to' or 'recipient_ids', not both")
+	}
+	if len(to) == 0 {
+		if len(recipientIDs) == 0 {
+			recipientIDs = inv.RecipientIDs
+		}
+		var err error
+		to, recipientName, err = h.clientRecipientEmails(ctx, inv.ClientID, recipientIDs)
+		if err != nil {
+			return nil, err
+		}
+		if len(to) == 0 {
+			return nil, notConfiguredError("recipients", "client '%s' has no recipients with an email address. Use 'add_recipient' or pass 'to'", inv.ClientName)
+		}
+	}
+
+	vars := inv.templateVars(recipientName)
+	msg := &mailer.Message{
+		To:      to,
+		Cc:      append(append([]string{}, inv.Cc...), cc...),
+		Subject: renderTemplate(tpl.Subject, vars),
+		Body:    renderTemplate(tpl.Body, vars),
+	}
+
+	if attach {
+		if inv.PDFPath == "" {
+			return nil, notFoundError("invoice_pdf", "invoice %s has no PDF on record", inv.InvoiceNumber)
+		}
+		data, err := os.ReadFile(inv.PDFPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read invoice PDF %s: %w", inv.PDFPath, err)
+		}
+		msg.Attachments = append(msg.Attachments, mailer.Attachment{
+			Name:        inv.InvoiceNumber + filepath.Ext(inv.PDFPath),
+			ContentType: "application/pdf",
+			Data:        data,
+		})
+	}
+	return msg, nil
+}
+
+// sendInvoiceEmail sends a message about an invoice and records the attempt
+func (h *Handler) sendInvoiceEmail(ctx context.Context, inv *invoiceEmail, msg *mailer.Message) error {
+	cfg, err := h.loadSMTPConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if cfg.FromName == "" {
+		cfg.FromName = inv.BusinessName
+	}
+
+	sendErr := mailer.Send(cfg, msg)
+	h.logEmail(ctx, inv.ID, append(append([]string{}, msg.To...), msg.Cc...), msg.Subject, sendErr)
+	return sendErr
+}
+
+// messagePreview renders a message's headers and body for review
+func messagePreview(msg *mailer.Message) string {
+	text := fmt.Sprintf("To: %s\n", strings.Join(msg.To, ", "))
+	if len(msg.Cc) > 0 {
+		text += fmt.Sprintf("Cc: %s\n", strings.Join(msg.Cc, ", "))
+	}
+	text += fmt.Sprintf("Subject: %s\n", msg.Subject)
+	for _, a := range msg.Attachments {
+		text += fmt.Sprintf("Attachment: %s (%.1f KB)\n", a.Name, float64(len(a.Data))/1024)
+	}
+	return text + "\n" + msg.Body + "\n"
+}
+
+// logEmail records an attempt to send an email; invoiceID is 0 for mail
+// about no invoice, such as the weekly digest
+func (h *Handler) logEmail(ctx context.Context, invoiceID int, recipients []string, subject string, sendErr error) {
+	status, errText := "sent", ""
+	if sendErr != nil {
+		status, errText = "failed", sendErr.Error()
+	}
+	var invoice any
+	if invoiceID != 0 {
+		invoice = invoiceID
+	}
+	if _, err := h.db.ExecContext(ctx, `
+		INSERT INTO email_log (invoice_id, recipients, subject, status, error)
+		VALUES (?, ?, ?, ?, ?)
+	`, invoice, strings.Join(recipients, ", "), subject, status, errText); err != nil {
+		slog.Error("failed to log email", "invoice_id", invoiceID, "error", err)
+	}
+}
+
+// invoiceEmailResult is the result of emailing an invoice or reminder, or of
+// drafting one without sending it
+type invoiceEmailResult struct {
+	Template string   `json:"template"`
+	To       []string `json:"to"`
+	Cc       []string `json:"cc,omitempty"`
+	Subject  string   `json:"subject"`
+	Body     string   `json:"body,omitempty" jsonschema:"Message body, shown for a draft"`
+	Sent     bool     `json:"sent" jsonschema:"Whether the email was sent; false for a draft"`
+}
+
+func newInvoiceEmailResult(tpl *emailTemplate, msg *mailer.Message) *invoiceEmailResult {
+	return &invoiceEmailResult{Template: tpl.Name, To: msg.To, Cc: msg.Cc, Subject: msg.Subject}
+}
+
+// registerEmailTools registers SMTP configuration and invoice delivery tools
+func registerEmailTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Set SMTP Config tool
+	type setSMTPConfigArgs struct {
+		Host        string `json:"host" jsonschema:"SMTP server host name"`
+		Port        int    `json:"port,omitempty" jsonschema:"SMTP port (default: 587, or 465 for tls and 25 for none)"`
+		Username    string `json:"username,omitempty" jsonschema:"SMTP login (optional)"`
+		Password    string `json:"password,omitempty" jsonschema:"SMTP password; kept in ~/.hours/smtp_password, not in the database. Omit to keep the stored password"`
+		FromAddress string `json:"from_address" jsonschema:"Sender email address"`
+		FromName    string `json:"from_name,omitempty" jsonschema:"Sender display name (default: business name)"`
+		Security    string `json:"security,omitempty" jsonschema:"Connection security: starttls (default), tls or none"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_smtp_config",
+		Description: "Configure the SMTP server used to email invoices",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setSMTPConfigArgs) (*mcp.CallToolResult, *mailer.Config, error) {
+		cfg := mailer.Config{
+			Host:        strings.TrimSpace(args.Host),
+			Port:        args.Port,
+			Username:    args.Username,
+			FromAddress: strings.TrimSpace(args.FromAddress),
+			FromName:    args.FromName,
+			Security:    strings.ToLower(args.Security),
+		}
+		if cfg.Security == "" {
+			cfg.Security = mailer.SecurityStartTLS
+			if cfg.Port == 465 {
+				cfg.Security = mailer.SecurityTLS
+			}
+		}
+		if cfg.Port == 0 {
+			switch cfg.Security {
+			case mailer.SecurityTLS:
+				cfg.Port = 465
+			case mailer.SecurityNone:
+				cfg.Port = 25
+			default:
+				cfg.Port = 587
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, nil, err
+		}
+
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO smtp_config (id, host, port, username, from_address, from_name, security, updated_at)
+			VALUES (1, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				host = excluded.host,
+				port = excluded.port,
+				username = excluded.username,
+				from_address = excluded.from_address,
+				from_name = excluded.from_name,
+				security = excluded.security,
+				updated_at = excluded.updated_at
+		`, cfg.Host, cfg.Port, cfg.Username, cfg.FromAddress, cfg.FromName, cfg.Security, time.Now())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to save SMTP config: %w", err)
+		}
+
+		if args.Password != "" {
+			if err := mailer.SavePassword(args.Password); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("SMTP configured: %s:%d (%s), sending as %s", cfg.Host, cfg.Port, cfg.Security, cfg.FromAddress),
+				},
+			},
+		}, &cfg, nil
+	})
+
+	// Get SMTP Config tool
+	type getSMTPConfigArgs struct{}
+
+	type getSMTPConfigResult struct {
+		Config         mailer.Config `json:"config"`
+		PasswordStored bool          `json:"password_stored"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_smtp_config",
+		Description: "Show the SMTP settings used to email invoices (the password is never shown)",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getSMTPConfigArgs) (*mcp.CallToolResult, *getSMTPConfigResult, error) {
+		cfg, err := h.loadSMTPConfig(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		text := "SMTP Configuration:\n"
+		text += fmt.Sprintf("Server: %s:%d (%s)\n", cfg.Host, cfg.Port, cfg.Security)
+		text += fmt.Sprintf("From: %s\n", cfg.FromAddress)
+		if cfg.FromName != "" {
+			text += fmt.Sprintf("From Name: %s\n", cfg.FromName)
+		}
+		if cfg.Username != "" {
+			text += fmt.Sprintf("Username: %s\n", cfg.Username)
+		}
+		text += fmt.Sprintf("Password stored: %t\n", cfg.Password != "")
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &getSMTPConfigResult{Config: cfg, PasswordStored: cfg.Password != ""}, nil
+	})
+
+	// Email Invoice tool
+	type emailInvoiceArgs struct {
+		InvoiceNumber string   `json:"invoice_number" jsonschema:"Invoice number to send"`
+		To            []string `json:"to,omitempty" jsonschema:"Recipient addresses (default: the recipients the invoice was created for, primary first)"`
+		RecipientIDs  []int    `json:"recipient_ids,omitempty" jsonschema:"IDs of the client's recipients to send to instead of 'to' (see list_recipients)"`
+		Cc            []string `json:"cc,omitempty" jsonschema:"Addresses to copy in addition to those stored on the invoice (optional)"`
+		Template      string   `json:"template,omitempty" jsonschema:"Email template name (default: the client's invoice template, the default one, or the built-in)"`
+		Subject       string   `json:"subject,omitempty" jsonschema:"Subject line overriding the template; may use placeholders such as {invoice_number}"`
+		Message       string   `json:"message,omitempty" jsonschema:"Message body overriding the template; may use placeholders such as {recipient_name}"`
+		DryRun        bool     `json:"dry_run,omitempty" jsonschema:"Preview the email without sending it"`
+
+		IgnorePreference bool `json:"ignore_preference,omitempty" jsonschema:"Email the invoice even though the client prefers another delivery method"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "email_invoice",
+		Description: "Email an invoice PDF to the client's recipients and record the delivery. Pending invoices are marked as sent. Clients preferring delivery by portal, post or hand are not emailed unless ignore_preference is set",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args emailInvoiceArgs) (*mcp.CallToolResult, *invoiceEmailResult, error) {
+		inv, err := h.loadInvoiceEmail(ctx, args.InvoiceNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		client, err := h.loadClient(ctx, inv.ClientID)
+		if err != nil {
+			return nil, nil, err
+		}
+		preferenceNote := ""
+		if client.DeliveryMethod != "" && client.DeliveryMethod != deliveryEmail && !args.IgnorePreference {
+			if !args.DryRun {
+				return nil, nil, conflictError("%s wants invoices delivered %s, not by email. Deliver it that way and record it with mark_invoice_sent, or set ignore_preference to email it anyway",
+					client.Name, deliveryText(client))
+			}
+			preferenceNote = fmt.Sprintf("Note: %s wants invoices delivered %s; sending needs ignore_preference.\n", client.Name, deliveryText(client))
+		}
+
+		tpl, err := h.resolveTemplate(ctx, args.Template, templateInvoice, inv.ClientID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if args.Subject != "" {
+			tpl.Subject = args.Subject
+		}
+		if args.Message != "" {
+			tpl.Body = args.Message
+		}
+
+		msg, err := h.composeInvoiceEmail(ctx, inv, tpl, args.To, args.RecipientIDs, args.Cc, true)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		result := newInvoiceEmailResult(tpl, msg)
+		if args.DryRun {
+			result.Body = msg.Body
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("%sDry run - email not sent (template '%s'):\n\n%s", preferenceNote, tpl.Name, messagePreview(msg))},
+				},
+			}, result, nil
+		}
+
+		if err := h.sendInvoiceEmail(ctx, inv, msg); err != nil {
+			return nil, nil, fmt.Errorf("failed to send invoice %s: %w", inv.InvoiceNumber, err)
+		}
+
+		text := fmt.Sprintf("Invoice %s emailed to %s\n", inv.InvoiceNumber, strings.Join(msg.To, ", "))
+		if err := h.store.Invoices.MarkSent(ctx, inv.ID, time.Now(), strings.Join(msg.To, ", "), deliveryEmail); err != nil {
+			return nil, nil, fmt.Errorf("email sent but failed to record the delivery: %w", err)
+		}
+		if inv.Status == "pending" || inv.Status == "draft" {
+			text += "Status updated to 'sent'\n"
+		}
+		result.Sent = true
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+
+	// List Email Log tool
+	type listEmailLogArgs struct {
+		InvoiceNumber string `json:"invoice_number,omitempty" jsonschema:"Only show emails for this invoice (optional)"`
+		Limit         int    `json:"limit,omitempty" jsonschema:"Maximum entries to return (default: 50)"`
+	}
+
+	type emailLogEntry struct {
+		ID            int       `json:"id"`
+		InvoiceNumber string    `json:"invoice_number,omitempty"`
+		Recipients    string    `json:"recipients"`
+		Subject       string    `json:"subject"`
+		Status        string    `json:"status"`
+		Error         string    `json:"error,omitempty"`
+		SentAt        time.Time `json:"sent_at"`
+	}
+
+	type listEmailLogResult struct {
+		Emails []emailLogEntry `json:"emails"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_email_log",
+		Description: "List invoice emails that were sent or failed, newest first",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listEmailLogArgs) (*mcp.CallToolResult, *listEmailLogResult, error) {
+		if args.Limit <= 0 {
+			args.Limit = 50
+		}
+
+		query := `
+			SELECT l.id, COALESCE(i.invoice_number, ''), l.recipients, l.subject, l.status, COALESCE(l.error, ''), l.sent_at
+			FROM email_log l
+			LEFT JOIN invoices i ON l.invoice_id = i.id
+			WHERE 1=1
+		`
+		queryArgs := []interface{}{}
+		if args.InvoiceNumber != "" {
+			query += " AND i.invoice_number = ?"
+			queryArgs = append(queryArgs, args.InvoiceNumber)
+		}
+		query += " ORDER BY l.sent_at DESC, l.id DESC LIMIT ?"
+		queryArgs = append(queryArgs, args.Limit)
+
+		rows, err := db.QueryContext(ctx, query, queryArgs...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list email log: %w", err)
+		}
+		defer rows.Close()
+
+		var entries []emailLogEntry
+		for rows.Next() {
+			var e emailLogEntry
+			if err := rows.Scan(&e.ID, &e.InvoiceNumber, &e.Recipients, &e.Subject, &e.Status, &e.Error, &e.SentAt); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan email log: %w", err)
+			}
+			entries = append(entries, e)
+		}
+
+		text := fmt.Sprintf("Found %d emails:\n", len(entries))
+		for _, e := range entries {
+			// Mail about no invoice, such as the weekly digest, goes by its subject
+			about := e.InvoiceNumber
+			if about == "" {
+				about = e.Subject
+			}
+			text += fmt.Sprintf("- %s %s: %s to %s", e.SentAt.Local().Format("2006-01-02 15:04"), e.Status, about, e.Recipients)
+			if e.Error != "" {
+				text += fmt.Sprintf(" (%s)", e.Error)
+			}
+			text += "\n"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &listEmailLogResult{Emails: entries}, nil
+	})
+}
@@ -0,0 +1,1648 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/austin/hours-mcp/internal/store"
+	"github.com/austin/hours-mcp/internal/timeparse"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// parseDateFilter parses an optional start or end date of a filter; empty
+// leaves the filter open
+func (h *Handler) parseDateFilter(ctx context.Context, value, which string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := h.parseDate(ctx, value)
+	if err != nil {
+		return nil, validationError("invalid %s date: %w", which, err)
+	}
+	return &date, nil
+}
+
+// checkEntryDate looks for a time entry date after today or more than
+// max_entry_age_days before it, which usually means a date was misread, e.g.
+// with the wrong year. Following the entry_date_check setting, the problem
+// is returned as a warning or as an error, or ignored.
+func (h *Handler) checkEntryDate(ctx context.Context, date, today time.Time) (string, error) {
+	var problem string
+	maxAge := h.getIntSetting(ctx, "max_entry_age_days")
+	switch {
+	case date.After(today):
+		problem = fmt.Sprintf("%s is in the future", date.Format("2006-01-02"))
+	case maxAge > 0 && date.Before(today.AddDate(0, 0, -maxAge)):
+		problem = fmt.Sprintf("%s is more than %d days ago", date.Format("2006-01-02"), maxAge)
+	default:
+		return "", nil
+	}
+
+	switch h.getSetting(ctx, "entry_date_check") {
+	case "off":
+		return "", nil
+	case "reject":
+		return "", validationError("%s; check the date, or set entry_date_check to warn to log it anyway", problem)
+	}
+	return problem, nil
+}
+
+// checkContractDates refuses a time entry dated outside its contract's
+// dates, e.g. hours logged against a contract that ended months ago. When
+// allowed anyway, the problem is returned as a warning.
+func checkContractDates(c *models.Contract, date time.Time, allow bool) (string, error) {
+	if contractCovers(c, date) {
+		return "", nil
+	}
+	span := "from " + c.StartDate.Format("2006-01-02")
+	if c.EndDate != nil {
+		span = c.StartDate.Format("2006-01-02") + " to " + c.EndDate.Format("2006-01-02")
+	}
+	problem := fmt.Sprintf("%s is outside contract %s, which runs %s", date.Format("2006-01-02"), c.ContractNumber, span)
+	if !allow {
+		return "", conflictError("%s; check the date and contract, or pass allow_outside_contract to log it anyway", problem)
+	}
+	return problem, nil
+}
+
+// checkInvoicedPeriod warns about hours dated in a period already invoiced
+// for the contract. Invoices only bill the unbilled work of their own
+// period, so such hours are forgotten unless an invoice covers their date.
+func (h *Handler) checkInvoicedPeriod(ctx context.Context, contractID int, contractNumber string, date time.Time) (string, error) {
+	number, invoiced, err := h.store.Entries.InvoicedFrom(ctx, contractID, date)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check invoiced work: %w", err)
+	}
+	day := date.Format("2006-01-02")
+	return fmt.Sprintf("%s is in a period already invoiced for %s (its work of %s is on %s). Back-bill these hours by starting the next create_invoice at %s, or bill them now on a supplemental invoice with start_date and end_date %s",
+		day, contractNumber, invoiced.Format("2006-01-02"), number, day, day), nil
+}
+
+// entryLockAge is how long after an entry is logged compliance mode still
+// lets it be edited or deleted
+const entryLockAge = 24 * time.Hour
+
+// entryLocked reports whether compliance mode locks an entry logged at
+// createdAt. An error reading the compliance_mode setting is returned rather
+// than taken to mean it is off, so a locked entry can't slip through.
+func (h *Handler) entryLocked(ctx context.Context, createdAt time.Time) (bool, error) {
+	if time.Since(createdAt) <= entryLockAge {
+		return false, nil
+	}
+	return h.lookupBoolSetting(ctx, "compliance_mode")
+}
+
+// checkEntryLocked refuses changes to an entry logged more than
+// entryLockAge ago while the compliance_mode setting is on; such entries
+// are corrected with an adjustment entry from adjust_time_entry instead
+func (h *Handler) checkEntryLocked(ctx context.Context, id string, createdAt time.Time) error {
+	locked, err := h.entryLocked(ctx, createdAt)
+	if err != nil || !locked {
+		return err
+	}
+	return conflictError("time entry %s was logged on %s and compliance mode locks entries after 24 hours; correct it with adjust_time_entry instead",
+		id, createdAt.In(h.location(ctx)).Format("2006-01-02 15:04"))
+}
+
+// parsedEntryContract finds the contract hours said to be for name go on:
+// the contract name refers to, or else the only active contract of the
+// client called name. It returns the client's name and the contract number.
+func (h *Handler) parsedEntryContract(ctx context.Context, name string) (string, string, error) {
+	// A contract found by name takes precedence; a client is only looked
+	// up when no contract matches
+	number, err := h.contractNumber(ctx, name)
+	if err == nil {
+		contract, err := h.store.Contracts.ByNumber(ctx, number)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to find contract: %w", err)
+		}
+		return contract.Client.Name, contract.ContractNumber, nil
+	} else if code, _ := classifyError(err); code != codeNotFound {
+		return "", "", err
+	}
+
+	clientID, err := h.getClientIDByName(ctx, name)
+	if err != nil {
+		return "", "", err
+	}
+	client, err := h.store.Clients.Get(ctx, clientID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to load client: %w", err)
+	}
+
+	rows, err := h.db.QueryContext(ctx, "SELECT contract_number FROM contracts WHERE client_id = ? AND status = 'active' ORDER BY contract_number", clientID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to load contracts: %w", err)
+	}
+	defer rows.Close()
+	var numbers []string
+	for rows.Next() {
+		var number string
+		if err := rows.Scan(&number); err != nil {
+			return "", "", fmt.Errorf("failed to scan contract: %w", err)
+		}
+		numbers = append(numbers, number)
+	}
+	if err := rows.Err(); err != nil {
+		return "", "", err
+	}
+
+	switch len(numbers) {
+	case 0:
+		return "", "", conflictError("%s has no active contract to log hours against", client.Name)
+	case 1:
+		return client.Name, numbers[0], nil
+	}
+	return "", "", validationError("%s has %d active contracts (%s); name the contract instead, e.g. '2 hours for %s'",
+		client.Name, len(numbers), strings.Join(numbers, ", "), numbers[0])
+}
+
+// hoursArg is a number of hours given either as a number or as a duration
+// such as "1h30m", "90 minutes" or "2:15"
+type hoursArg float64
+
+func (h *hoursArg) UnmarshalJSON(data []byte) error {
+	var n float64
+	if err := json.Unmarshal(data, &n); err == nil {
+		*h = hoursArg(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("hours must be a number or a duration like 1h30m")
+	}
+	// A leading minus takes hours off, for adjust_time_entry; each tool
+	// checks the sign it accepts
+	s = strings.TrimSpace(s)
+	sign := 1.0
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		s, sign = rest, -1
+	}
+	hours, err := timeparse.ParseHours(s)
+	if err != nil {
+		return err
+	}
+	*h = hoursArg(sign * hours)
+	return nil
+}
+
+// roundHours rounds logged hours to the nearest multiple of the
+// hours_rounding setting's minutes, but never down to 0. The setting is 0 by
+// default, leaving hours exactly as given.
+func (h *Handler) roundHours(ctx context.Context, hours float64) float64 {
+	minutes := h.getIntSetting(ctx, "hours_rounding")
+	if minutes <= 0 {
+		return hours
+	}
+	step := float64(minutes) / 60
+	rounded := max(math.Round(hours/step), 1) * step
+	// Steps like 0.1 hours aren't exact in binary
+	return math.Round(rounded*1e6) / 1e6
+}
+
+// registerEntryTools registers tools to log, list, search, edit and delete
+// time entries and to link them to invoices
+func registerEntryTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Add Hours tool
+	type addHoursArgs struct {
+		ContractNumber string   `json:"contract_number" jsonschema:"Contract number, or the client and contract name like 'Acme maintenance', to log hours against"`
+		Hours          hoursArg `json:"hours" jsonschema:"Hours worked, as decimal hours (0.25, 0.5, 1.25) or a duration ('1h30m' '90 minutes' '2:15')"`
+		Date           string   `json:"date,omitempty" jsonschema:"Date (YYYY-MM-DD or natural language like 'today' 'yesterday' 'last tuesday' '3 days ago')"`
+		Description    string   `json:"description,omitempty" jsonschema:"Description of work done"`
+		Service        string   `json:"service,omitempty" jsonschema:"Service from the rate card the hours were for, billed at the contract's rate for it (optional)"`
+		NoCharge       bool     `json:"no_charge,omitempty" jsonschema:"Goodwill work: listed on the invoice with its hours, marked N/C, at no cost (default: false)"`
+
+		AllowOutsideContract bool `json:"allow_outside_contract,omitempty" jsonschema:"Log the hours even if dated before the contract's start or after its end (default: false)"`
+	}
+
+	type addHoursResult struct {
+		Entry      *models.TimeEntry `json:"entry"`
+		ClientName string            `json:"client_name"`
+		Alerts     []string          `json:"alerts,omitempty" jsonschema:"Budget thresholds the entry crossed, to tell the user about"`
+		Warning    string            `json:"warning,omitempty" jsonschema:"Why the date looks mistaken: outside the contract's dates, or see the entry_date_check setting"`
+		LateEntry  string            `json:"late_entry,omitempty" jsonschema:"Set when the date is in a period already invoiced for the contract, which later invoices won't bill unless they cover it"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "add_hours",
+		Description: "Add hours worked against a specific contract (supports 15-minute increments: 0.25 = 15 min, 0.5 = 30 min, 0.75 = 45 min)",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args addHoursArgs) (*mcp.CallToolResult, *addHoursResult, error) {
+		if args.Hours <= 0 {
+			return nil, nil, validationError("hours must be more than 0")
+		}
+		args.Hours = hoursArg(h.roundHours(ctx, float64(args.Hours)))
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		// Get contract and verify it's active
+		contract, err := h.store.Contracts.ByNumber(ctx, args.ContractNumber)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("contract", "contract %s not found", args.ContractNumber)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find contract: %w", err)
+		}
+
+		if contract.Status != "active" {
+			return nil, nil, conflictError("contract %s is not active (status: %s)", args.ContractNumber, contract.Status)
+		}
+		if err := h.checkClientActive(ctx, contract.ClientID); err != nil {
+			return nil, nil, err
+		}
+
+		// Days are the client's, so work logged near midnight or while
+		// travelling lands on the day it was done there
+		today := h.clientToday(ctx, contract.ClientID)
+		date := today
+		if args.Date != "" {
+			date, err = h.parseClientDate(ctx, contract.ClientID, args.Date)
+			if err != nil {
+				return nil, nil, validationError("invalid date: %w", err)
+			}
+		}
+		outside, err := checkContractDates(contract, date, args.AllowOutsideContract)
+		if err != nil {
+			return nil, nil, err
+		}
+		warning, err := h.checkEntryDate(ctx, date, today)
+		if err != nil {
+			return nil, nil, err
+		}
+		if outside != "" {
+			warning = strings.TrimPrefix(warning+"; "+outside, "; ")
+		}
+		serviceID, serviceWarning, err := h.entryService(ctx, contract.ID, contract.ContractNumber, args.Service)
+		if err != nil {
+			return nil, nil, err
+		}
+		lateEntry, err := h.checkInvoicedPeriod(ctx, contract.ID, contract.ContractNumber, date)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Budgets are checked against the hours as they would be invoiced,
+		// so the usage is compared before and after the entry is stored
+		budget, err := h.loadBudget(ctx, contract.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		var before budgetUsage
+		if budget != nil {
+			if before, err = h.budgetUsed(ctx, contract.ID); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		entryID, err := h.store.Entries.Create(ctx, contract.ClientID, contract.ID, args.ContractNumber, date, float64(args.Hours), args.Description, serviceID, args.NoCharge)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to add hours: %w", err)
+		}
+
+		text := fmt.Sprintf("Added %.2f hours for %s (%s) on %s - %s (ID: %s)", float64(args.Hours), contract.Client.Name, contract.Name, date.Format("2006-01-02"), args.Description, entryID)
+		if warning != "" {
+			text += "\nWarning: " + warning
+		}
+		if lateEntry != "" {
+			text += "\nWarning: " + lateEntry
+		}
+		if args.NoCharge {
+			text += "\nNo charge: the hours will be listed on the invoice as N/C"
+		}
+		if serviceWarning != "" {
+			text += "\nNote: " + serviceWarning
+		}
+		var alerts []string
+		if budget != nil {
+			after, err := h.budgetUsed(ctx, contract.ID)
+			if err != nil {
+				return nil, nil, err
+			}
+			if over := budgetExceeded(budget, after); budget.BudgetLimit && len(over) > 0 {
+				if _, err := h.store.Entries.Delete(ctx, entryID); err != nil {
+					return nil, nil, fmt.Errorf("failed to remove hours over budget: %w", err)
+				}
+				return nil, nil, conflictError("hours not added: %s would reach %s, over its budget", args.ContractNumber, strings.Join(over, " and "))
+			}
+			// Set apart so the alerts aren't lost among the entry's details
+			alerts = budgetAlerts(budget, before, after, h.budgetThresholds(ctx, budget))
+			if len(alerts) > 0 {
+				text += "\n\nBudget alerts:"
+				for _, alert := range alerts {
+					text += "\n- " + alert
+				}
+			}
+		}
+
+		entry, clientName, err := h.store.Entries.Get(ctx, entryID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load time entry: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &addHoursResult{Entry: entry, ClientName: clientName, Alerts: alerts, Warning: warning, LateEntry: lateEntry}, nil
+	})
+
+	// List Hours tool
+	type listHoursArgs struct {
+		ClientName string `json:"client_name,omitempty" jsonschema:"Client name (optional shows all if not specified)"`
+		StartDate  string `json:"start_date,omitempty" jsonschema:"Start date (YYYY-MM-DD or natural language)"`
+		EndDate    string `json:"end_date,omitempty" jsonschema:"End date (YYYY-MM-DD or natural language)"`
+		GroupBy    string `json:"group_by,omitempty" jsonschema:"entry (default) lists each entry; description totals the hours per task, with descriptions differing only in case, spacing or trailing punctuation grouped together"`
+		Format     string `json:"format,omitempty" jsonschema:"Output format: text (default) or markdown"`
+	}
+
+	// entryListResult is the result of listing or searching time entries
+	type entryListResult struct {
+		Entries    []store.EntryListing `json:"entries"`
+		Count      int                  `json:"count"`
+		TotalHours float64              `json:"total_hours"`
+		Groups     []descriptionGroup   `json:"groups,omitempty" jsonschema:"Hours per description, most first, with group_by description"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_hours",
+		Description: "List hours for a client within a date range, or total them per task with group_by description",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listHoursArgs) (*mcp.CallToolResult, *entryListResult, error) {
+		markdown, err := parseOutputFormat(args.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+		grouped, err := validateGroupBy(args.GroupBy)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var filter store.EntryFilter
+		if args.ClientName != "" {
+			if filter.ClientID, err = h.getClientIDByName(ctx, args.ClientName); err != nil {
+				return nil, nil, fmt.Errorf("client not found: %w", err)
+			}
+		}
+		if filter.StartDate, err = h.parseDateFilter(ctx, args.StartDate, "start"); err != nil {
+			return nil, nil, err
+		}
+		if filter.EndDate, err = h.parseDateFilter(ctx, args.EndDate, "end"); err != nil {
+			return nil, nil, err
+		}
+
+		entries, err := h.store.Entries.List(ctx, filter)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list hours: %w", err)
+		}
+		var totalHours float64
+		for _, e := range entries {
+			totalHours += e.Hours
+		}
+
+		result := &entryListResult{Entries: entries, Count: len(entries), TotalHours: totalHours}
+		var text string
+		if grouped {
+			result.Groups = groupEntriesByDescription(entries)
+			text = descriptionGroupsText(result.Groups, totalHours, len(entries), markdown)
+		} else if markdown {
+			tableRows := make([][]string, 0, len(entries)+1)
+			for _, e := range entries {
+				description := e.Description
+				if e.NeedsReview {
+					description = strings.TrimSpace(description + " **(needs review)**")
+				}
+				tableRows = append(tableRows, []string{e.ID, e.Date.Format("2006-01-02"), e.ClientName,
+					e.ContractNumber, fmt.Sprintf("%.2f", e.Hours), description})
+			}
+			tableRows = append(tableRows, []string{"", "", "**Total**", "", fmt.Sprintf("**%.2f**", totalHours), ""})
+			text = fmt.Sprintf("**%d entries, %.2f total hours**\n\n", len(entries), totalHours) +
+				markdownTable([]string{"ID", "Date", "Client", "Contract", "Hours", "Description"}, tableRows, 4)
+		} else {
+			text = fmt.Sprintf("Found %d entries (%.2f total hours):\n", len(entries), totalHours)
+			for _, e := range entries {
+				text += fmt.Sprintf("- ID %s: %s: %s - %.2f hours", e.ID, e.Date.Format("2006-01-02"), e.ClientName, e.Hours)
+				if e.Description != "" {
+					text += fmt.Sprintf(" (%s)", e.Description)
+				}
+				if e.ContractNumber != "" {
+					text += fmt.Sprintf(" [Contract: %s]", e.ContractNumber)
+				}
+				if e.AdjustsEntryID != "" {
+					text += fmt.Sprintf(" [adjusts %s]", e.AdjustsEntryID)
+				}
+				if e.NeedsReview {
+					text += " [needs review]"
+				}
+				text += "\n"
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+
+	// Delete Time Entry tool
+	type deleteTimeEntryArgs struct {
+		EntryID string `json:"entry_id" jsonschema:"Time entry UUID to delete"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "delete_time_entry",
+		Description: "Delete a specific time entry by ID",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args deleteTimeEntryArgs) (*mcp.CallToolResult, *store.EntrySummary, error) {
+		entry, err := h.store.Entries.Summary(ctx, args.EntryID)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("time_entry", "time entry with ID %s not found", args.EntryID)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to find time entry: %w", err)
+		}
+		if err := h.checkEntryLocked(ctx, args.EntryID, entry.CreatedAt); err != nil {
+			return nil, nil, err
+		}
+
+		deleted, err := h.store.Entries.Delete(ctx, args.EntryID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to delete time entry: %w", err)
+		}
+		if !deleted {
+			return nil, nil, notFoundError("time_entry", "time entry with ID %s not found", args.EntryID)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: "Deleted time entry " + entrySummaryText(entry),
+				},
+			},
+		}, entry, nil
+	})
+
+	// Bulk Delete Time Entries tool
+	type bulkDeleteTimeEntriesArgs struct {
+		EntryIDs []string `json:"entry_ids" jsonschema:"List of time entry UUIDs to delete"`
+		Confirm  bool     `json:"confirm,omitempty" jsonschema:"Delete the entries (default: false, only shows what would be deleted)"`
+	}
+
+	type bulkDeleteTimeEntriesResult struct {
+		Confirmed  bool                  `json:"confirmed" jsonschema:"Whether the entries were deleted; false for a preview"`
+		Entries    []*store.EntrySummary `json:"entries" jsonschema:"Entries deleted, or that would be deleted"`
+		TotalHours float64               `json:"total_hours"`
+		NotFound   int                   `json:"not_found,omitempty" jsonschema:"Number of IDs with no time entry"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "bulk_delete_time_entries",
+		Description: "Delete multiple time entries by their IDs; shows the entries that would be deleted until run with confirm=true",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args bulkDeleteTimeEntriesArgs) (*mcp.CallToolResult, *bulkDeleteTimeEntriesResult, error) {
+		if len(args.EntryIDs) == 0 {
+			return nil, nil, validationError("no entry IDs provided")
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+		entries := h.store.WithTx(tx).Entries
+
+		result := &bulkDeleteTimeEntriesResult{}
+		var deletedEntries []string
+		var invoiced int
+		for _, entryID := range args.EntryIDs {
+			entry, err := entries.Summary(ctx, entryID)
+			if err == sql.ErrNoRows {
+				continue
+			} else if err != nil {
+				return nil, nil, fmt.Errorf("failed to find time entry %s: %w", entryID, err)
+			}
+			if err := h.checkEntryLocked(ctx, entryID, entry.CreatedAt); err != nil {
+				return nil, nil, err
+			}
+			result.Entries = append(result.Entries, entry)
+			deletedEntries = append(deletedEntries, entrySummaryText(entry))
+			result.TotalHours += entry.Hours
+			if entry.InvoiceID != nil {
+				invoiced++
+			}
+		}
+
+		result.NotFound = len(args.EntryIDs) - len(result.Entries)
+
+		if !args.Confirm {
+			text := fmt.Sprintf("Would delete %d time entries (%.2f hours):\n", len(result.Entries), result.TotalHours)
+			for _, entry := range deletedEntries {
+				text += fmt.Sprintf("- %s\n", entry)
+			}
+			if invoiced > 0 {
+				text += fmt.Sprintf("%d of them are on invoices, which keep their totals.\n", invoiced)
+			}
+			if result.NotFound > 0 {
+				text += fmt.Sprintf("%d IDs were not found.\n", result.NotFound)
+			}
+			return previewResult(text, result)
+		}
+
+		for _, entry := range result.Entries {
+			if _, err := entries.Delete(ctx, entry.ID); err != nil {
+				return nil, nil, fmt.Errorf("failed to delete time entry %s: %w", entry.ID, err)
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		text := fmt.Sprintf("Deleted %d time entries:\n", len(result.Entries))
+		for _, entry := range deletedEntries {
+			text += fmt.Sprintf("- %s\n", entry)
+		}
+
+		result.Confirmed = true
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+
+	// Bulk Update Time Entries tool
+	type bulkUpdateTimeEntriesArgs struct {
+		EntryIDs          []string `json:"entry_ids,omitempty" jsonschema:"Time entry UUIDs to change; or pick entries with the filters below"`
+		ClientName        string   `json:"client_name,omitempty" jsonschema:"Change the entries of this client (optional filter)"`
+		ContractNumber    string   `json:"contract_number,omitempty" jsonschema:"Contract number or name whose entries to change (optional filter)"`
+		StartDate         string   `json:"start_date,omitempty" jsonschema:"Change entries from this date (optional filter, YYYY-MM-DD or natural language)"`
+		EndDate           string   `json:"end_date,omitempty" jsonschema:"Change entries up to this date (optional filter, YYYY-MM-DD or natural language)"`
+		MoveToContract    string   `json:"move_to_contract,omitempty" jsonschema:"Contract number or name to move the entries to (optional)"`
+		ShiftDays         int      `json:"shift_days,omitempty" jsonschema:"Days to move each entry's date by, negative for earlier (optional)"`
+		DescriptionPrefix string   `json:"description_prefix,omitempty" jsonschema:"Text to put in front of each description (optional)"`
+		Billable          *bool    `json:"billable,omitempty" jsonschema:"Whether the entries are billed; entries that aren't are never invoiced (optional)"`
+		NoCharge          *bool    `json:"no_charge,omitempty" jsonschema:"Whether the entries are listed on the invoice as N/C, at no cost (optional)"`
+		Confirm           bool     `json:"confirm,omitempty" jsonschema:"Change the entries (default: false, only shows what would change)"`
+	}
+
+	type bulkUpdateTimeEntriesResult struct {
+		Confirmed bool                 `json:"confirmed" jsonschema:"Whether the entries were changed; false for a preview"`
+		Entries   []store.EntryListing `json:"entries" jsonschema:"Entries as they are after the change, or would be"`
+		Invoiced  int                  `json:"invoiced,omitempty" jsonschema:"Number of matching entries left alone because they are invoiced"`
+		NotFound  int                  `json:"not_found,omitempty" jsonschema:"Number of IDs with no time entry matching the filters"`
+		Warnings  []string             `json:"warnings,omitempty" jsonschema:"Shifted dates that look mistaken, see the entry_date_check setting"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "bulk_update_time_entries",
+		Description: "Change many time entries at once, picked by ID or by client, contract and dates: move them to another contract, shift their dates, prefix their descriptions or set whether they are billable or no charge; shows the changes until run with confirm=true. Invoiced entries are left alone.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args bulkUpdateTimeEntriesArgs) (*mcp.CallToolResult, *bulkUpdateTimeEntriesResult, error) {
+		if len(args.EntryIDs) == 0 && args.ClientName == "" && args.ContractNumber == "" && args.StartDate == "" && args.EndDate == "" {
+			return nil, nil, validationError("no entries picked: give entry_ids or a client, contract or dates")
+		}
+		if args.MoveToContract == "" && args.ShiftDays == 0 && args.DescriptionPrefix == "" && args.Billable == nil && args.NoCharge == nil {
+			return nil, nil, validationError("no updates provided")
+		}
+
+		filter := store.EntryFilter{IDs: args.EntryIDs}
+		var err error
+		if args.ClientName != "" {
+			if filter.ClientID, err = h.getClientIDByName(ctx, args.ClientName); err != nil {
+				return nil, nil, fmt.Errorf("client not found: %w", err)
+			}
+		}
+		if args.ContractNumber != "" {
+			if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+				return nil, nil, err
+			}
+			if filter.ContractID, err = h.store.Contracts.IDByNumber(ctx, args.ContractNumber); err != nil {
+				return nil, nil, notFoundError("contract", "contract %s not found", args.ContractNumber)
+			}
+		}
+		if filter.StartDate, err = h.parseDateFilter(ctx, args.StartDate, "start"); err != nil {
+			return nil, nil, err
+		}
+		if filter.EndDate, err = h.parseDateFilter(ctx, args.EndDate, "end"); err != nil {
+			return nil, nil, err
+		}
+
+		var target *models.Contract
+		if args.MoveToContract != "" {
+			if err := h.resolveContractNumber(ctx, &args.MoveToContract); err != nil {
+				return nil, nil, err
+			}
+			target, err = h.store.Contracts.ByNumber(ctx, args.MoveToContract)
+			if err == sql.ErrNoRows {
+				return nil, nil, notFoundError("contract", "contract %s not found", args.MoveToContract)
+			} else if err != nil {
+				return nil, nil, fmt.Errorf("failed to find contract: %w", err)
+			}
+			if target.Status != "active" {
+				return nil, nil, conflictError("contract %s is not active (status: %s)", args.MoveToContract, target.Status)
+			}
+			if err := h.checkClientActive(ctx, target.ClientID); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+		entries := h.store.WithTx(tx).Entries
+
+		matched, err := entries.List(ctx, filter)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find time entries: %w", err)
+		}
+		result := &bulkUpdateTimeEntriesResult{Entries: []store.EntryListing{}}
+		if len(args.EntryIDs) > 0 {
+			result.NotFound = len(args.EntryIDs) - len(matched)
+		}
+		today := h.today(ctx)
+		var changes []store.EntryChanges
+		for _, e := range matched {
+			// Invoices keep the entries they were built from as they were
+			if e.InvoiceID != nil {
+				result.Invoiced++
+				continue
+			}
+			if err := h.checkEntryLocked(ctx, e.ID, e.CreatedAt); err != nil {
+				return nil, nil, err
+			}
+			change := store.EntryChanges{Billable: args.Billable, NoCharge: args.NoCharge, Contract: target}
+			if args.ShiftDays != 0 {
+				date := e.Date.AddDate(0, 0, args.ShiftDays)
+				warning, err := h.checkEntryDate(ctx, date, today)
+				if err != nil {
+					return nil, nil, fmt.Errorf("entry %s: %w", e.ID, err)
+				}
+				if warning != "" {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("entry %s: %s", e.ID, warning))
+				}
+				change.Date, e.Date = &date, date
+			}
+			if target != nil && !contractCovers(target, e.Date) {
+				return nil, nil, conflictError("entry %s on %s falls outside the dates of contract %s", e.ID, e.Date.Format("2006-01-02"), target.ContractNumber)
+			}
+			if args.DescriptionPrefix != "" {
+				description := args.DescriptionPrefix + e.Description
+				change.Description, e.Description = &description, description
+			}
+			if args.Billable != nil {
+				e.Billable = *args.Billable
+			}
+			if args.NoCharge != nil {
+				e.NoCharge = *args.NoCharge
+			}
+			if target != nil {
+				e.ContractID, e.ClientName = target.ID, target.Client.Name
+				e.ContractNumber, e.ContractName, e.Currency = target.ContractNumber, target.Name, target.Currency
+			}
+			result.Entries = append(result.Entries, e)
+			changes = append(changes, change)
+		}
+
+		verb := "Would change"
+		if args.Confirm {
+			for i, e := range result.Entries {
+				if err := entries.Update(ctx, e.ID, changes[i]); err != nil {
+					return nil, nil, fmt.Errorf("failed to update time entry %s: %w", e.ID, err)
+				}
+			}
+			if err := tx.Commit(); err != nil {
+				return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+			}
+			verb, result.Confirmed = "Changed", true
+		}
+
+		text := fmt.Sprintf("%s %d time entries:\n", verb, len(result.Entries))
+		for _, e := range result.Entries {
+			text += fmt.Sprintf("- ID %s: %s: %s [Contract: %s] - %.2f hours", e.ID, e.Date.Format("2006-01-02"), e.ClientName, e.ContractNumber, e.Hours)
+			if e.Description != "" {
+				text += fmt.Sprintf(" (%s)", e.Description)
+			}
+			if !e.Billable {
+				text += " [not billable]"
+			} else if e.NoCharge {
+				text += " [N/C]"
+			}
+			text += "\n"
+		}
+		if result.Invoiced > 0 {
+			text += fmt.Sprintf("%d invoiced entries were left alone.\n", result.Invoiced)
+		}
+		if result.NotFound > 0 {
+			text += fmt.Sprintf("%d IDs matched no time entry.\n", result.NotFound)
+		}
+		for _, warning := range result.Warnings {
+			text += fmt.Sprintf("Warning: %s\n", warning)
+		}
+		if !args.Confirm {
+			return previewResult(text, result)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+
+	// Move Time Entries tool
+	type moveTimeEntriesArgs struct {
+		EntryIDs     []string `json:"entry_ids,omitempty" jsonschema:"Time entry UUIDs to move; or pick entries with from_contract and the dates"`
+		FromContract string   `json:"from_contract,omitempty" jsonschema:"Contract number or name to move uninvoiced entries from"`
+		StartDate    string   `json:"start_date,omitempty" jsonschema:"Move entries from this date (optional, YYYY-MM-DD or natural language)"`
+		EndDate      string   `json:"end_date,omitempty" jsonschema:"Move entries up to this date (optional, YYYY-MM-DD or natural language)"`
+		ToContract   string   `json:"to_contract" jsonschema:"Contract number or name to move the entries to; its dates must cover theirs"`
+		Confirm      bool     `json:"confirm,omitempty" jsonschema:"Move the entries (default: false, only shows what would move)"`
+	}
+
+	// movedEntry is a time entry with its amount under the contract it is
+	// moved from and the one it is moved to
+	type movedEntry struct {
+		ID           string      `json:"id"`
+		Date         time.Time   `json:"date"`
+		Hours        float64     `json:"hours"`
+		Description  string      `json:"description,omitempty"`
+		FromContract string      `json:"from_contract"`
+		FromAmount   money.Cents `json:"from_amount"`
+		FromCurrency string      `json:"from_currency"`
+		Amount       money.Cents `json:"amount" jsonschema:"Amount under the new contract, in its currency"`
+	}
+
+	type moveTimeEntriesResult struct {
+		Confirmed   bool         `json:"confirmed" jsonschema:"Whether the entries were moved; false for a preview"`
+		ToContract  string       `json:"to_contract"`
+		ClientName  string       `json:"client_name"`
+		Currency    string       `json:"currency"`
+		Entries     []movedEntry `json:"entries" jsonschema:"Entries moved, or that would be moved"`
+		TotalHours  float64      `json:"total_hours"`
+		TotalAmount money.Cents  `json:"total_amount" jsonschema:"Amount of the entries under the new contract"`
+		NotFound    int          `json:"not_found,omitempty" jsonschema:"Number of IDs with no time entry"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "move_time_entries",
+		Description: "Move uninvoiced time entries to another contract, possibly of another client, e.g. entries left on an expired contract after a mid-month renewal; prices them under the new contract and shows the change until run with confirm=true",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args moveTimeEntriesArgs) (*mcp.CallToolResult, *moveTimeEntriesResult, error) {
+		if len(args.EntryIDs) == 0 && args.FromContract == "" {
+			return nil, nil, validationError("no entries picked: give entry_ids or from_contract")
+		}
+		if err := h.resolveContractNumber(ctx, &args.ToContract); err != nil {
+			return nil, nil, err
+		}
+		target, err := h.store.Contracts.ByNumber(ctx, args.ToContract)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("contract", "contract %s not found", args.ToContract)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to find contract: %w", err)
+		}
+		if target.Status != "active" {
+			return nil, nil, conflictError("contract %s is not active (status: %s)", args.ToContract, target.Status)
+		}
+		if err := h.checkClientActive(ctx, target.ClientID); err != nil {
+			return nil, nil, err
+		}
+
+		filter := store.EntryFilter{IDs: args.EntryIDs}
+		if args.FromContract != "" {
+			if err := h.resolveContractNumber(ctx, &args.FromContract); err != nil {
+				return nil, nil, err
+			}
+			if filter.ContractID, err = h.store.Contracts.IDByNumber(ctx, args.FromContract); err != nil {
+				return nil, nil, notFoundError("contract", "contract %s not found", args.FromContract)
+			}
+			uninvoiced := false
+			filter.Invoiced = &uninvoiced
+		}
+		if filter.StartDate, err = h.parseDateFilter(ctx, args.StartDate, "start"); err != nil {
+			return nil, nil, err
+		}
+		if filter.EndDate, err = h.parseDateFilter(ctx, args.EndDate, "end"); err != nil {
+			return nil, nil, err
+		}
+
+		matched, err := h.store.Entries.List(ctx, filter)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find time entries: %w", err)
+		}
+
+		result := &moveTimeEntriesResult{
+			ToContract: target.ContractNumber,
+			ClientName: target.Client.Name,
+			Currency:   target.Currency,
+			Entries:    []movedEntry{},
+		}
+		if len(args.EntryIDs) > 0 {
+			result.NotFound = len(args.EntryIDs) - len(matched)
+		}
+		var ids, outside []string
+		for _, e := range matched {
+			if e.InvoiceID != nil {
+				return nil, nil, conflictError("time entry %s is invoiced; unmark it from its invoice before moving it", e.ID)
+			}
+			if e.ContractID == target.ID {
+				continue
+			}
+			if err := h.checkEntryLocked(ctx, e.ID, e.CreatedAt); err != nil {
+				return nil, nil, err
+			}
+			if !contractCovers(target, e.Date) {
+				outside = append(outside, e.Date.Format("2006-01-02"))
+				continue
+			}
+			ids = append(ids, e.ID)
+			result.Entries = append(result.Entries, movedEntry{
+				ID:           e.ID,
+				Date:         e.Date,
+				Hours:        e.Hours,
+				Description:  e.Description,
+				FromContract: e.ContractNumber,
+				FromCurrency: e.Currency,
+			})
+			result.TotalHours += e.Hours
+		}
+		if len(outside) > 0 {
+			span := "from " + target.StartDate.Format("2006-01-02")
+			if target.EndDate != nil {
+				span = target.StartDate.Format("2006-01-02") + " to " + target.EndDate.Format("2006-01-02")
+			}
+			return nil, nil, conflictError("contract %s runs %s, so it doesn't cover entries on %s",
+				target.ContractNumber, span, strings.Join(outside, ", "))
+		}
+
+		// Amounts come from the rates in effect on each entry's day, with
+		// the premiums of the contract it is priced under
+		from, to, err := h.priceMove(ctx, target.ID, ids)
+		if err != nil {
+			return nil, nil, err
+		}
+		fromAmounts, toAmounts := map[string]money.Cents{}, map[string]money.Cents{}
+		for _, item := range from {
+			fromAmounts[item.TimeEntryID] += item.Amount
+		}
+		for _, item := range to {
+			toAmounts[item.TimeEntryID] += item.Amount
+		}
+		for i := range result.Entries {
+			e := &result.Entries[i]
+			e.FromAmount, e.Amount = fromAmounts[e.ID], toAmounts[e.ID]
+			result.TotalAmount += e.Amount
+		}
+
+		verb := "Would move"
+		if args.Confirm && len(result.Entries) > 0 {
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+			}
+			defer tx.Rollback()
+			entries := h.store.WithTx(tx).Entries
+			for _, e := range result.Entries {
+				if err := entries.Update(ctx, e.ID, store.EntryChanges{Contract: target}); err != nil {
+					return nil, nil, fmt.Errorf("failed to move time entry %s: %w", e.ID, err)
+				}
+			}
+			if err := tx.Commit(); err != nil {
+				return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+			}
+		}
+		if args.Confirm {
+			verb, result.Confirmed = "Moved", true
+		}
+
+		text := fmt.Sprintf("%s %d time entries (%.2f hours) to %s (%s), worth %s:\n", verb, len(result.Entries), result.TotalHours,
+			target.ContractNumber, target.Client.Name, result.TotalAmount.Format(target.Currency))
+		for _, e := range result.Entries {
+			text += fmt.Sprintf("- ID %s: %s - %.2f hours from %s, %s -> %s", e.ID, e.Date.Format("2006-01-02"), e.Hours,
+				e.FromContract, e.FromAmount.Format(e.FromCurrency), e.Amount.Format(target.Currency))
+			if e.Description != "" {
+				text += fmt.Sprintf(" (%s)", e.Description)
+			}
+			text += "\n"
+		}
+		if result.NotFound > 0 {
+			text += fmt.Sprintf("%d IDs matched no time entry.\n", result.NotFound)
+		}
+		if !args.Confirm {
+			return previewResult(text, result)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+
+	// Bulk Add Hours tool
+	type bulkAddHoursEntry struct {
+		ClientName  string   `json:"client_name" jsonschema:"Client name"`
+		Hours       hoursArg `json:"hours" jsonschema:"Hours worked, as decimal hours (0.25, 0.5, 1.25) or a duration ('1h30m' '90 minutes' '2:15')"`
+		Date        string   `json:"date" jsonschema:"Date (YYYY-MM-DD or natural language like 'today' 'yesterday' 'last tuesday' '3 days ago')"`
+		Description string   `json:"description,omitempty" jsonschema:"Description of work done"`
+		ContractRef string   `json:"contract_ref,omitempty" jsonschema:"Contract reference number (optional)"`
+		Service     string   `json:"service,omitempty" jsonschema:"Service from the rate card the hours were for (optional)"`
+		NoCharge    bool     `json:"no_charge,omitempty" jsonschema:"List the hours on the invoice as N/C, at no cost (default: false)"`
+
+		AllowOutsideContract bool `json:"allow_outside_contract,omitempty" jsonschema:"Log the hours even if dated outside the contract's dates (default: false)"`
+	}
+
+	type bulkAddHoursArgs struct {
+		Entries []bulkAddHoursEntry `json:"entries" jsonschema:"List of time entries to add"`
+	}
+
+	type bulkAddHoursResult struct {
+		Entries    []store.EntrySummary `json:"entries" jsonschema:"Entries added"`
+		TotalHours float64              `json:"total_hours"`
+		Warnings   []string             `json:"warnings,omitempty" jsonschema:"Dates that look mistaken (see the entry_date_check setting), are outside their contract or are in periods already invoiced"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "bulk_add_hours",
+		Description: "Add multiple time entries at once (supports 15-minute increments: 0.25 = 15 min, 0.5 = 30 min, 0.75 = 45 min)",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args bulkAddHoursArgs) (*mcp.CallToolResult, *bulkAddHoursResult, error) {
+		if len(args.Entries) == 0 {
+			return nil, nil, validationError("no entries provided")
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+		txStore := h.store.WithTx(tx)
+
+		result := &bulkAddHoursResult{}
+
+		for _, entry := range args.Entries {
+			if entry.Hours <= 0 {
+				return nil, nil, validationError("hours must be more than 0 for %s on %s", entry.ClientName, entry.Date)
+			}
+			entry.Hours = hoursArg(h.roundHours(ctx, float64(entry.Hours)))
+			clientID, err := h.getClientIDByName(ctx, entry.ClientName)
+			if err != nil {
+				return nil, nil, fmt.Errorf("client '%s' not found: %w", entry.ClientName, err)
+			}
+			if err := h.checkClientActive(ctx, clientID); err != nil {
+				return nil, nil, err
+			}
+
+			if err := h.resolveContractNumber(ctx, &entry.ContractRef); err != nil {
+				return nil, nil, err
+			}
+			contract, err := txStore.Contracts.ByNumber(ctx, entry.ContractRef)
+			if err != nil {
+				return nil, nil, fmt.Errorf("contract '%s' not found: %w", entry.ContractRef, err)
+			}
+			contractID := contract.ID
+
+			today := h.clientToday(ctx, clientID)
+			date := today
+			if entry.Date != "" {
+				date, err = h.parseClientDate(ctx, clientID, entry.Date)
+				if err != nil {
+					return nil, nil, validationError("invalid date '%s': %w", entry.Date, err)
+				}
+			}
+			outside, err := checkContractDates(contract, date, entry.AllowOutsideContract)
+			if err != nil {
+				return nil, nil, fmt.Errorf("entry for %s: %w", entry.ClientName, err)
+			}
+			if outside != "" {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("entry for %s: %s", entry.ClientName, outside))
+			}
+			warning, err := h.checkEntryDate(ctx, date, today)
+			if err != nil {
+				return nil, nil, fmt.Errorf("entry for %s: %w", entry.ClientName, err)
+			}
+			if warning != "" {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("entry for %s: %s", entry.ClientName, warning))
+			}
+
+			serviceID, serviceWarning, err := h.entryService(ctx, contractID, entry.ContractRef, entry.Service)
+			if err != nil {
+				return nil, nil, fmt.Errorf("entry for %s: %w", entry.ClientName, err)
+			}
+			if serviceWarning != "" {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("entry for %s: %s", entry.ClientName, serviceWarning))
+			}
+			lateEntry, err := h.checkInvoicedPeriod(ctx, contractID, entry.ContractRef, date)
+			if err != nil {
+				return nil, nil, err
+			}
+			if lateEntry != "" {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("entry for %s: %s", entry.ClientName, lateEntry))
+			}
+
+			entryID, err := txStore.Entries.Create(ctx, clientID, contractID, entry.ContractRef, date, float64(entry.Hours), entry.Description, serviceID, entry.NoCharge)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to add entry for %s: %w", entry.ClientName, err)
+			}
+
+			result.Entries = append(result.Entries, store.EntrySummary{
+				ID:          entryID,
+				ClientName:  entry.ClientName,
+				Date:        date,
+				Hours:       float64(entry.Hours),
+				Description: entry.Description,
+			})
+			result.TotalHours += float64(entry.Hours)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		text := fmt.Sprintf("Added %d time entries (%.2f total hours):\n", len(result.Entries), result.TotalHours)
+		for i := range result.Entries {
+			text += fmt.Sprintf("- %s\n", entrySummaryText(&result.Entries[i]))
+		}
+		for _, warning := range result.Warnings {
+			text += fmt.Sprintf("Warning: %s\n", warning)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+
+	// Parse Time Entries tool
+	type parseTimeEntriesArgs struct {
+		Text string `json:"text" jsonschema:"Work done in plain words, e.g. '2 hours for Acme Monday and 3 hours for Globex Tuesday \"API work\"'; a contract number can stand in for the client"`
+	}
+
+	type parseTimeEntriesResult struct {
+		Entries    []bulkAddHoursEntry `json:"entries" jsonschema:"Entries to pass to bulk_add_hours once the user confirms them"`
+		TotalHours float64             `json:"total_hours"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "parse_time_entries",
+		Description: "Read time entries from a sentence such as '2 hours for Acme Monday and 3 hours for Globex Tuesday' without saving them; confirm the entries with the user, then pass them to bulk_add_hours",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args parseTimeEntriesArgs) (*mcp.CallToolResult, *parseTimeEntriesResult, error) {
+		parsed, err := timeparse.ParseNaturalLanguageAt(args.Text, h.now(ctx), h.dateOrder(ctx))
+		if err != nil {
+			return nil, nil, validationError("%w", err)
+		}
+
+		type target struct{ client, contract string }
+		targets := map[string]target{}
+		result := &parseTimeEntriesResult{Entries: []bulkAddHoursEntry{}}
+		for _, p := range parsed {
+			t, ok := targets[strings.ToLower(p.ClientName)]
+			if !ok {
+				if t.client, t.contract, err = h.parsedEntryContract(ctx, p.ClientName); err != nil {
+					return nil, nil, err
+				}
+				targets[strings.ToLower(p.ClientName)] = t
+			}
+			hours := h.roundHours(ctx, p.Hours)
+			for _, date := range p.Dates {
+				result.Entries = append(result.Entries, bulkAddHoursEntry{
+					ClientName:  t.client,
+					Hours:       hoursArg(hours),
+					Date:        calendarDay(date).Format("2006-01-02"),
+					Description: p.Description,
+					ContractRef: t.contract,
+				})
+				result.TotalHours += hours
+			}
+		}
+
+		text := fmt.Sprintf("Read %d time entries (%.2f total hours); nothing is saved until they are passed to bulk_add_hours:\n", len(result.Entries), result.TotalHours)
+		for _, e := range result.Entries {
+			text += fmt.Sprintf("- %s (%s) - %.2f hours on %s", e.ClientName, e.ContractRef, float64(e.Hours), e.Date)
+			if e.Description != "" {
+				text += fmt.Sprintf(" (%s)", e.Description)
+			}
+			text += "\n"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+
+	// Helper: Get Time Entry Details tool
+	type getTimeEntryDetailsArgs struct {
+		EntryID string `json:"entry_id" jsonschema:"Time entry UUID to get details for"`
+	}
+
+	type timeEntryResult struct {
+		Entry         *models.TimeEntry  `json:"entry"`
+		ClientName    string             `json:"client_name"`
+		InvoiceNumber string             `json:"invoice_number,omitempty" jsonschema:"Invoice the entry is billed on, if any"`
+		Warning       string             `json:"warning,omitempty" jsonschema:"Why a new date looks mistaken: outside the contract's dates, or see the entry_date_check setting"`
+		Adjustments   []models.TimeEntry `json:"adjustments,omitempty" jsonschema:"Adjustment entries correcting this one, oldest first"`
+		NetHours      *float64           `json:"net_hours,omitempty" jsonschema:"Hours with the adjustments applied, when there are any"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "get_time_entry_details",
+		Description: "Get detailed information about a specific time entry",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getTimeEntryDetailsArgs) (*mcp.CallToolResult, *timeEntryResult, error) {
+		entry, clientName, err := h.store.Entries.Get(ctx, args.EntryID)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("time_entry", "time entry with ID %s not found", args.EntryID)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to get time entry details: %w", err)
+		}
+
+		invoiceStatus := "Not invoiced"
+		var invoiceNumber string
+		if entry.InvoiceID != nil {
+			invoiceNumber, _ = h.store.Invoices.NumberByID(ctx, *entry.InvoiceID)
+			invoiceStatus = fmt.Sprintf("Invoiced (%s)", invoiceNumber)
+		}
+
+		text := fmt.Sprintf("Time Entry Details (ID: %s):\n", entry.ID)
+		text += fmt.Sprintf("Client: %s\n", clientName)
+		text += fmt.Sprintf("Date: %s\n", entry.Date.Format("2006-01-02"))
+		text += fmt.Sprintf("Hours: %.2f\n", entry.Hours)
+		text += fmt.Sprintf("Description: %s\n", entry.Description)
+		if entry.Service != "" {
+			text += fmt.Sprintf("Service: %s\n", entry.Service)
+		}
+		if !entry.Billable {
+			text += "Billable: No (never invoiced)\n"
+		} else if entry.NoCharge {
+			text += "No charge: Yes (listed on the invoice as N/C)\n"
+		}
+		text += fmt.Sprintf("Invoice Status: %s\n", invoiceStatus)
+		text += fmt.Sprintf("Created: %s\n", entry.CreatedAt.Format("2006-01-02 15:04:05"))
+		if entry.AdjustsEntryID != "" {
+			text += fmt.Sprintf("Adjusts: time entry %s\n", entry.AdjustsEntryID)
+		}
+
+		result := &timeEntryResult{Entry: entry, ClientName: clientName, InvoiceNumber: invoiceNumber}
+		adjustments, err := h.store.Entries.Adjustments(ctx, entry.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load adjustments: %w", err)
+		}
+		if len(adjustments) > 0 {
+			net := entry.Hours
+			text += "Adjustments:\n"
+			for _, a := range adjustments {
+				net += a.Hours
+				text += fmt.Sprintf("- %s: %+.2f hours (%s) ID %s\n", a.CreatedAt.Format("2006-01-02 15:04"), a.Hours, a.Description, a.ID)
+			}
+			text += fmt.Sprintf("Net hours: %.2f\n", net)
+			result.Adjustments, result.NetHours = adjustments, &net
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+
+	// Helper: Update Time Entry tool
+	type updateTimeEntryArgs struct {
+		EntryID     string    `json:"entry_id" jsonschema:"Time entry UUID to update"`
+		Hours       *hoursArg `json:"hours,omitempty" jsonschema:"New hours, as decimal hours or a duration like '1h30m' (optional)"`
+		Date        string    `json:"date,omitempty" jsonschema:"New date (optional, YYYY-MM-DD or natural language)"`
+		Description *string   `json:"description,omitempty" jsonschema:"New description (optional)"`
+		Billable    *bool     `json:"billable,omitempty" jsonschema:"Whether the entry is billed (optional)"`
+		Service     *string   `json:"service,omitempty" jsonschema:"Service from the rate card the hours were for; empty to clear it (optional)"`
+		NoCharge    *bool     `json:"no_charge,omitempty" jsonschema:"Whether the entry is listed on the invoice as N/C, at no cost (optional)"`
+		Reviewed    bool      `json:"reviewed,omitempty" jsonschema:"Clear the review flag of an entry from a timer stopped at the timer_max_hours limit, keeping its hours; setting hours clears it too (default: false)"`
+
+		AllowOutsideContract bool `json:"allow_outside_contract,omitempty" jsonschema:"Accept a new date outside the contract's dates (default: false)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "update_time_entry",
+		Description: "Update an existing time entry (hours support 15-minute increments: 0.25 = 15 min, 0.5 = 30 min, 0.75 = 45 min)",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args updateTimeEntryArgs) (*mcp.CallToolResult, *timeEntryResult, error) {
+		entry, clientName, err := h.store.Entries.Get(ctx, args.EntryID)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("time_entry", "time entry with ID %s not found", args.EntryID)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to find time entry: %w", err)
+		}
+
+		if entry.InvoiceID != nil {
+			return nil, nil, conflictError("cannot update time entry that has already been invoiced")
+		}
+		if err := h.checkEntryLocked(ctx, args.EntryID, entry.CreatedAt); err != nil {
+			return nil, nil, err
+		}
+
+		changes := store.EntryChanges{Description: args.Description, Billable: args.Billable, NoCharge: args.NoCharge}
+		if args.Hours != nil {
+			hours := float64(*args.Hours)
+			if hours <= 0 {
+				return nil, nil, validationError("hours must be more than 0; delete the entry to remove it")
+			}
+			changes.Hours = &hours
+		}
+		if entry.NeedsReview && (args.Reviewed || args.Hours != nil) {
+			reviewed := false
+			changes.NeedsReview = &reviewed
+		}
+		var contractNumber string
+		if args.Date != "" || args.Service != nil {
+			if err := db.QueryRowContext(ctx, "SELECT contract_number FROM contracts WHERE id = ?", entry.ContractID).Scan(&contractNumber); err != nil {
+				return nil, nil, fmt.Errorf("failed to find contract: %w", err)
+			}
+		}
+		var warning string
+		if args.Date != "" {
+			date, err := h.parseDate(ctx, args.Date)
+			if err != nil {
+				return nil, nil, validationError("invalid date: %w", err)
+			}
+			contract, err := h.store.Contracts.ByNumber(ctx, contractNumber)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to find contract: %w", err)
+			}
+			outside, err := checkContractDates(contract, date, args.AllowOutsideContract)
+			if err != nil {
+				return nil, nil, err
+			}
+			if warning, err = h.checkEntryDate(ctx, date, h.today(ctx)); err != nil {
+				return nil, nil, err
+			}
+			if outside != "" {
+				warning = strings.TrimPrefix(warning+"; "+outside, "; ")
+			}
+			changes.Date = &date
+		}
+		var serviceWarning string
+		if args.Service != nil {
+			serviceID, warning, err := h.entryService(ctx, entry.ContractID, contractNumber, *args.Service)
+			if err != nil {
+				return nil, nil, err
+			}
+			if serviceID == nil {
+				serviceID = new(int)
+			}
+			changes.ServiceID, serviceWarning = serviceID, warning
+		}
+
+		if err := h.store.Entries.Update(ctx, args.EntryID, changes); err == store.ErrNoChanges {
+			return nil, nil, validationError("no updates provided")
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to update time entry: %w", err)
+		}
+		if entry, _, err = h.store.Entries.Get(ctx, args.EntryID); err != nil {
+			return nil, nil, fmt.Errorf("failed to load time entry: %w", err)
+		}
+
+		text := fmt.Sprintf("Updated time entry ID %s for %s", args.EntryID, clientName)
+		if warning != "" {
+			text += "\nWarning: " + warning
+		}
+		if serviceWarning != "" {
+			text += "\nNote: " + serviceWarning
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &timeEntryResult{Entry: entry, ClientName: clientName, Warning: warning}, nil
+	})
+
+	// Adjust Time Entry tool
+	type adjustTimeEntryArgs struct {
+		EntryID string   `json:"entry_id" jsonschema:"Time entry UUID to correct"`
+		Hours   hoursArg `json:"hours" jsonschema:"Hours to add to the entry, negative to take hours off (e.g. -0.5)"`
+		Reason  string   `json:"reason" jsonschema:"Why the entry is corrected; becomes the adjustment's description"`
+	}
+
+	type adjustTimeEntryResult struct {
+		Adjustment *models.TimeEntry `json:"adjustment"`
+		ClientName string            `json:"client_name"`
+		NetHours   float64           `json:"net_hours" jsonschema:"Hours of the original entry with all its adjustments"`
+		LateEntry  string            `json:"late_entry,omitempty" jsonschema:"Set when the entry's date is in a period already invoiced, which later invoices won't bill unless they cover it"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "adjust_time_entry",
+		Description: "Correct a time entry's hours with a separate adjustment entry that references it, on the same date, contract and service, leaving the original as it was. The way to fix entries locked by the compliance_mode setting; get_time_entry_details shows an entry's adjustments",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args adjustTimeEntryArgs) (*mcp.CallToolResult, *adjustTimeEntryResult, error) {
+		if args.Hours == 0 {
+			return nil, nil, validationError("hours must not be zero")
+		}
+		reason := strings.TrimSpace(args.Reason)
+		if reason == "" {
+			return nil, nil, validationError("a reason is required for an adjustment")
+		}
+
+		original, clientName, err := h.store.Entries.Get(ctx, args.EntryID)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("time_entry", "time entry with ID %s not found", args.EntryID)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to find time entry: %w", err)
+		}
+		if original.AdjustsEntryID != "" {
+			return nil, nil, validationError("time entry %s is itself an adjustment of %s; adjust %s instead", args.EntryID, original.AdjustsEntryID, original.AdjustsEntryID)
+		}
+
+		adjustments, err := h.store.Entries.Adjustments(ctx, original.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load adjustments: %w", err)
+		}
+		net := original.Hours + float64(args.Hours)
+		for _, a := range adjustments {
+			net += a.Hours
+		}
+		if net < 0 {
+			return nil, nil, validationError("the adjustment would leave the entry with %.2f hours; it can take off at most %.2f", net, net-float64(args.Hours))
+		}
+
+		var contractNumber string
+		if err := db.QueryRowContext(ctx, "SELECT contract_number FROM contracts WHERE id = ?", original.ContractID).Scan(&contractNumber); err != nil {
+			return nil, nil, fmt.Errorf("failed to find contract: %w", err)
+		}
+		lateEntry, err := h.checkInvoicedPeriod(ctx, original.ContractID, contractNumber, original.Date)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		adjustmentID, err := h.store.Entries.CreateAdjustment(ctx, original.ID, float64(args.Hours), "Adjustment: "+reason)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to add adjustment: %w", err)
+		}
+		adjustment, _, err := h.store.Entries.Get(ctx, adjustmentID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load adjustment: %w", err)
+		}
+
+		text := fmt.Sprintf("Adjusted time entry %s (%s, %s) by %+.2f hours: %s (ID: %s)\nNet hours: %.2f",
+			original.ID, clientName, original.Date.Format("2006-01-02"), float64(args.Hours), reason, adjustmentID, net)
+		if lateEntry != "" {
+			text += "\nWarning: " + lateEntry
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &adjustTimeEntryResult{Adjustment: adjustment, ClientName: clientName, NetHours: net, LateEntry: lateEntry}, nil
+	})
+
+	// Helper: Search Time Entries tool
+	type searchTimeEntriesArgs struct {
+		ClientName  string   `json:"client_name,omitempty" jsonschema:"Client name to filter by (optional)"`
+		Description string   `json:"description,omitempty" jsonschema:"Search description text; supports \"quoted phrases\" and prefix* terms, best matches first (optional)"`
+		ContractRef string   `json:"contract_ref,omitempty" jsonschema:"A contract's number or name, or part of contract numbers to match (optional)"`
+		MinHours    *float64 `json:"min_hours,omitempty" jsonschema:"Minimum hours (optional)"`
+		MaxHours    *float64 `json:"max_hours,omitempty" jsonschema:"Maximum hours (optional)"`
+		StartDate   string   `json:"start_date,omitempty" jsonschema:"Start date (optional)"`
+		EndDate     string   `json:"end_date,omitempty" jsonschema:"End date (optional)"`
+		Invoiced    *bool    `json:"invoiced,omitempty" jsonschema:"Filter by invoice status: true=invoiced, false=not invoiced, null=all (optional)"`
+		GroupBy     string   `json:"group_by,omitempty" jsonschema:"entry (default) lists each match; description totals the hours per task"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "search_time_entries",
+		Description: "Search time entries with various filters, optionally totalling the matches per task with group_by description",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args searchTimeEntriesArgs) (*mcp.CallToolResult, *entryListResult, error) {
+		grouped, err := validateGroupBy(args.GroupBy)
+		if err != nil {
+			return nil, nil, err
+		}
+		filter := store.EntryFilter{
+			Description: args.Description,
+			MinHours:    args.MinHours,
+			MaxHours:    args.MaxHours,
+			Invoiced:    args.Invoiced,
+		}
+		// A contract's exact number or name picks it; anything else matches
+		// part of the contract number, so a partial ref still finds entries
+		// on several contracts, completed ones included
+		if args.ContractRef != "" {
+			id, ok, err := h.exactContractID(ctx, args.ContractRef)
+			if err != nil {
+				return nil, nil, err
+			}
+			if ok {
+				filter.ContractID = id
+			} else {
+				filter.ContractRef = args.ContractRef
+			}
+		}
+
+		// Use the FTS5 index for ranked phrase/prefix matching when available
+		if h.search {
+			filter.FTSQuery = buildFTSQuery(args.Description)
+		}
+
+		if args.ClientName != "" {
+			if filter.ClientID, err = h.getClientIDByName(ctx, args.ClientName); err != nil {
+				return nil, nil, fmt.Errorf("client not found: %w", err)
+			}
+		}
+		if filter.StartDate, err = h.parseDateFilter(ctx, args.StartDate, "start"); err != nil {
+			return nil, nil, err
+		}
+		if filter.EndDate, err = h.parseDateFilter(ctx, args.EndDate, "end"); err != nil {
+			return nil, nil, err
+		}
+
+		entries, err := h.store.Entries.List(ctx, filter)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to search time entries: %w", err)
+		}
+
+		var totalHours float64
+		for _, e := range entries {
+			totalHours += e.Hours
+		}
+
+		result := &entryListResult{Entries: entries, Count: len(entries), TotalHours: totalHours}
+		if grouped {
+			result.Groups = groupEntriesByDescription(entries)
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: descriptionGroupsText(result.Groups, totalHours, len(entries), false)},
+				},
+			}, result, nil
+		}
+
+		text := fmt.Sprintf("Found %d entries (%.2f total hours):\n", len(entries), totalHours)
+		for _, e := range entries {
+			invoiceStatus := "Not invoiced"
+			if e.InvoiceID != nil {
+				invoiceStatus = fmt.Sprintf("Invoiced (ID: %d)", *e.InvoiceID)
+			}
+			text += fmt.Sprintf("- ID %s: %s: %s - %.2f hours (%s)",
+				e.ID, e.Date.Format("2006-01-02"), e.ClientName, e.Hours, invoiceStatus)
+			if e.Description != "" {
+				text += fmt.Sprintf(" - %s", e.Description)
+			}
+			if e.ContractNumber != "" {
+				text += fmt.Sprintf(" [Contract: %s]", e.ContractNumber)
+			}
+			text += "\n"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+
+	// Mark Time Entries as Invoiced tool
+	type markTimeEntriesInvoicedArgs struct {
+		InvoiceNumber string   `json:"invoice_number" jsonschema:"Invoice number to link entries to"`
+		EntryIDs      []string `json:"entry_ids" jsonschema:"List of time entry UUIDs to mark as invoiced"`
+	}
+
+	type markTimeEntriesInvoicedResult struct {
+		InvoiceNumber string                `json:"invoice_number"`
+		Marked        []*store.EntrySummary `json:"marked" jsonschema:"Entries linked to the invoice"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "mark_time_entries_invoiced",
+		Description: "Mark specific time entries as invoiced by linking them to an invoice",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args markTimeEntriesInvoicedArgs) (*mcp.CallToolResult, *markTimeEntriesInvoicedResult, error) {
+		if len(args.EntryIDs) == 0 {
+			return nil, nil, validationError("no entry IDs provided")
+		}
+
+		invoiceID, err := h.store.Invoices.IDByNumber(ctx, args.InvoiceNumber)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("invoice", "invoice %s not found", args.InvoiceNumber)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to find invoice: %w", err)
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+		entries := h.store.WithTx(tx).Entries
+
+		result := &markTimeEntriesInvoicedResult{InvoiceNumber: args.InvoiceNumber}
+
+		for _, entryID := range args.EntryIDs {
+			entry, err := entries.Summary(ctx, entryID)
+			if err == sql.ErrNoRows {
+				continue
+			} else if err != nil {
+				return nil, nil, fmt.Errorf("failed to find time entry %s: %w", entryID, err)
+			}
+
+			if entry.InvoiceID != nil {
+				return nil, nil, conflictError("time entry %s is already invoiced (%s)", entryID, *entry.InvoiceNumber)
+			}
+
+			marked, err := entries.SetInvoice(ctx, entryID, &invoiceID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to mark time entry %s as invoiced: %w", entryID, err)
+			}
+			if marked {
+				entry.InvoiceID, entry.InvoiceNumber = &invoiceID, &args.InvoiceNumber
+				result.Marked = append(result.Marked, entry)
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		text := fmt.Sprintf("Marked %d time entries as invoiced (%s):\n", len(result.Marked), args.InvoiceNumber)
+		for _, entry := range result.Marked {
+			text += fmt.Sprintf("- %s\n", entrySummaryText(entry))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+
+	// Unmark Time Entries from Invoice tool
+	type unmarkTimeEntriesArgs struct {
+		EntryIDs []string `json:"entry_ids" jsonschema:"List of time entry UUIDs to unmark from invoices"`
+	}
+
+	type unmarkTimeEntriesResult struct {
+		Unmarked []*store.EntrySummary `json:"unmarked" jsonschema:"Entries unlinked, with the invoice each was on"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "unmark_time_entries_from_invoice",
+		Description: "Remove invoice association from time entries, making them available for billing again",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args unmarkTimeEntriesArgs) (*mcp.CallToolResult, *unmarkTimeEntriesResult, error) {
+		if len(args.EntryIDs) == 0 {
+			return nil, nil, validationError("no entry IDs provided")
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+		entries := h.store.WithTx(tx).Entries
+
+		result := &unmarkTimeEntriesResult{}
+		var unmarkedEntries []string
+
+		for _, entryID := range args.EntryIDs {
+			entry, err := entries.Summary(ctx, entryID)
+			if err == sql.ErrNoRows {
+				continue
+			} else if err != nil {
+				return nil, nil, fmt.Errorf("failed to find time entry %s: %w", entryID, err)
+			}
+
+			unmarked, err := entries.SetInvoice(ctx, entryID, nil)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to unmark time entry %s: %w", entryID, err)
+			}
+			if unmarked {
+				invoiceInfo := "No invoice"
+				if entry.InvoiceNumber != nil {
+					invoiceInfo = fmt.Sprintf("was %s", *entry.InvoiceNumber)
+				}
+				unmarkedEntries = append(unmarkedEntries, fmt.Sprintf("%s [%s]", entrySummaryText(entry), invoiceInfo))
+				result.Unmarked = append(result.Unmarked, entry)
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		text := fmt.Sprintf("Unmarked %d time entries from invoices:\n", len(result.Unmarked))
+		for _, entry := range unmarkedEntries {
+			text += fmt.Sprintf("- %s\n", entry)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+}
+
+// entrySummaryText describes a time entry in the results of deleting or
+// (un)marking entries
+func entrySummaryText(e *store.EntrySummary) string {
+	return fmt.Sprintf("ID %s: %s - %.2f hours on %s (%s)",
+		e.ID, e.ClientName, e.Hours, e.Date.Format("2006-01-02"), e.Description)
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// errorCode tells a client what kind of problem made a tool call fail, so
+// it can react (e.g. offer to create a missing client) without parsing the
+// message
+type errorCode string
+
+const (
+	// codeNotFound means a client, contract, invoice or other record
+	// named in the arguments doesn't exist
+	codeNotFound errorCode = "NOT_FOUND"
+	// codeValidation means an argument is missing, malformed or out of range
+	codeValidation errorCode = "VALIDATION"
+	// codeConflict means the change clashes with the current data, e.g. a
+	// duplicate name or an entry that has already been invoiced
+	codeConflict errorCode = "CONFLICT"
+	// codeNotConfigured means something has to be set up first, e.g. SMTP
+	// or the business information
+	codeNotConfigured errorCode = "NOT_CONFIGURED"
+	// codeInternal means the server failed, e.g. the database couldn't be
+	// read
+	codeInternal errorCode = "INTERNAL"
+)
+
+// toolError is an error with the code reported to clients
+type toolError struct {
+	code errorCode
+	// entity is what was not found or not configured, e.g. client or smtp
+	entity string
+	err    error
+}
+
+func (e *toolError) Error() string { return e.err.Error() }
+
+func (e *toolError) Unwrap() error { return e.err }
+
+// notFoundError reports that a record of kind entity doesn't exist
+func notFoundError(entity, format string, args ...any) error {
+	return &toolError{code: codeNotFound, entity: entity, err: fmt.Errorf(format, args...)}
+}
+
+// validationError reports an invalid argument
+func validationError(format string, args ...any) error {
+	return &toolError{code: codeValidation, err: fmt.Errorf(format, args...)}
+}
+
+// conflictError reports a change that clashes with the current data
+func conflictError(format string, args ...any) error {
+	return &toolError{code: codeConflict, err: fmt.Errorf(format, args...)}
+}
+
+// notConfiguredError reports that entity has to be set up first
+func notConfiguredError(entity, format string, args ...any) error {
+	return &toolError{code: codeNotConfigured, entity: entity, err: fmt.Errorf(format, args...)}
+}
+
+// classifyError returns the code and entity of err. Errors not created by
+// the functions above are classified by their cause: missing rows are not
+// found and constraint violations are conflicts.
+func classifyError(err error) (errorCode, string) {
+	var te *toolError
+	switch {
+	case errors.As(err, &te):
+		return te.code, te.entity
+	case errors.Is(err, sql.ErrNoRows):
+		return codeNotFound, ""
+	case strings.Contains(err.Error(), "UNIQUE constraint failed"):
+		return codeConflict, ""
+	}
+	return codeInternal, ""
+}
+
+// toolErrorContent is the structured content of a failed tool call
+type toolErrorContent struct {
+	Error toolErrorDetail `json:"error"`
+}
+
+type toolErrorDetail struct {
+	Code    errorCode `json:"code" jsonschema:"NOT_FOUND, VALIDATION, CONFLICT, NOT_CONFIGURED or INTERNAL"`
+	Message string    `json:"message"`
+	Entity  string    `json:"entity,omitempty" jsonschema:"What was not found or not configured, e.g. client, contract, invoice or smtp"`
+}
+
+// errorResult reports a failed tool call with its message as text and its
+// code as structured content
+func errorResult(err error) *mcp.CallToolResult {
+	code, entity := classifyError(err)
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: err.Error()},
+		},
+		StructuredContent: &toolErrorContent{
+			Error: toolErrorDetail{Code: code, Message: err.Error(), Entity: entity},
+		},
+	}
+}
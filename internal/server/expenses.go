@@ -0,0 +1,522 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/austin/hours-mcp/internal/pdf"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// rateKindExpense marks invoice items and lines that rebill an expense
+const rateKindExpense = "expense"
+
+// expenseItems returns a client's billable expenses dated start to end that
+// no invoice has billed yet, with markup percent added to each
+func (h *Handler) expenseItems(ctx context.Context, clientID int, start, end time.Time, markup float64) ([]models.InvoiceItem, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT e.id, e.contract_id, e.kind, e.date, e.amount_cents, e.currency, e.quantity, e.unit, e.unit_rate_cents,
+		       e.category, e.description
+		FROM expenses e
+		JOIN contracts ct ON e.contract_id = ct.id
+		WHERE ct.client_id = ? AND e.date >= ? AND e.date <= ? AND e.billable AND e.invoice_id IS NULL
+		ORDER BY e.date, e.id
+	`, clientID, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expenses: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.InvoiceItem
+	for rows.Next() {
+		var e models.Expense
+		if err := rows.Scan(&e.ID, &e.ContractID, &e.Kind, &e.Date, &e.Amount, &e.Currency, &e.Quantity, &e.Unit, &e.UnitRate,
+			&e.Category, &e.Description); err != nil {
+			return nil, fmt.Errorf("failed to scan expense: %w", err)
+		}
+		items = append(items, models.InvoiceItem{
+			ExpenseID:   e.ID,
+			ContractID:  e.ContractID,
+			Date:        e.Date,
+			Description: expenseDescription(e),
+			RateKind:    rateKindExpense,
+			RateLabel:   "Expenses",
+			Multiplier:  1 + markup/100,
+			Rate:        e.Amount,
+			Amount:      e.Amount + e.Amount.Percent(markup),
+			Currency:    e.Currency,
+		})
+	}
+	return items, rows.Err()
+}
+
+// expenseDescription describes an expense on invoices, e.g. "Travel: Train
+// to Berlin" or "Mileage: Office to Acme HQ (42 km at USD 0.30)"
+func expenseDescription(e models.Expense) string {
+	description := "Expense"
+	switch {
+	case e.Category != "" && e.Description != "":
+		description = e.Category + ": " + e.Description
+	case e.Category != "":
+		description = e.Category
+	case e.Description != "":
+		description = e.Description
+	}
+	if e.Quantity > 0 {
+		description += fmt.Sprintf(" (%g %s at %s)", e.Quantity, e.Unit, e.UnitRate.Format(e.Currency))
+	}
+	return description
+}
+
+// checkReceiptPath expands a receipt path and makes sure the file exists
+func checkReceiptPath(path string) (string, error) {
+	path = expandHome(path)
+	if _, err := os.Stat(path); err != nil {
+		return "", notFoundError("receipt", "receipt not found: %w", err)
+	}
+	return path, nil
+}
+
+// invoiceReceipts returns the receipts of the expenses among items, in
+// order, to append to the invoice PDF. skipped names the receipts that are
+// missing or not an image or PDF.
+func (h *Handler) invoiceReceipts(ctx context.Context, items []models.InvoiceItem) (receipts []pdf.Receipt, skipped []string, err error) {
+	for _, item := range items {
+		if item.ExpenseID == 0 {
+			continue
+		}
+		var path string
+		err := h.db.QueryRowContext(ctx, "SELECT receipt_path FROM expenses WHERE id = ?", item.ExpenseID).Scan(&path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load receipt: %w", err)
+		}
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil || !pdf.CanAttach(path) {
+			skipped = append(skipped, path)
+			continue
+		}
+		receipts = append(receipts, pdf.Receipt{
+			Path:    path,
+			Caption: fmt.Sprintf("%s, %s", item.Description, item.Date.Format("2006-01-02")),
+		})
+	}
+	return receipts, skipped, nil
+}
+
+// loadExpense returns an expense as stored
+func (h *Handler) loadExpense(ctx context.Context, id int) (*models.Expense, error) {
+	var e models.Expense
+	err := h.db.QueryRowContext(ctx, `
+		SELECT id, contract_id, kind, date, amount_cents, currency, quantity, unit, unit_rate_cents,
+		       category, description, receipt_path, billable, invoice_id, created_at
+		FROM expenses
+		WHERE id = ?
+	`, id).Scan(&e.ID, &e.ContractID, &e.Kind, &e.Date, &e.Amount, &e.Currency, &e.Quantity, &e.Unit, &e.UnitRate,
+		&e.Category, &e.Description, &e.ReceiptPath, &e.Billable, &e.InvoiceID, &e.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expense %d: %w", id, err)
+	}
+	return &e, nil
+}
+
+// registerExpenseTools registers tools to record and rebill expenses
+func registerExpenseTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Add Expense tool
+	type addExpenseArgs struct {
+		ContractNumber string  `json:"contract_number" jsonschema:"Contract the expense was incurred for"`
+		Amount         float64 `json:"amount" jsonschema:"Amount spent"`
+		Date           string  `json:"date,omitempty" jsonschema:"Date (YYYY-MM-DD or natural language, default: today)"`
+		Currency       string  `json:"currency,omitempty" jsonschema:"Currency code (default: the contract's currency)"`
+		Category       string  `json:"category,omitempty" jsonschema:"Category such as travel, software or hardware"`
+		Description    string  `json:"description,omitempty" jsonschema:"What the expense was for"`
+		ReceiptPath    string  `json:"receipt_path,omitempty" jsonschema:"Path to a scan or photo of the receipt"`
+		Billable       *bool   `json:"billable,omitempty" jsonschema:"Rebill the expense on the client's next invoice (default: true)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "add_expense",
+		Description: "Record an expense against a contract; billable expenses are added to the client's next invoice",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args addExpenseArgs) (*mcp.CallToolResult, *models.Expense, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		if args.Amount <= 0 {
+			return nil, nil, validationError("amount must be positive")
+		}
+
+		var contractID, clientID int
+		var contractCurrency string
+		err := db.QueryRowContext(ctx, "SELECT id, client_id, currency FROM contracts WHERE contract_number = ?", args.ContractNumber).
+			Scan(&contractID, &clientID, &contractCurrency)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("contract", "contract %s not found", args.ContractNumber)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find contract: %w", err)
+		}
+		if err := h.checkClientActive(ctx, clientID); err != nil {
+			return nil, nil, err
+		}
+
+		currency := contractCurrency
+		if args.Currency != "" {
+			currency = strings.ToUpper(strings.TrimSpace(args.Currency))
+			if err := validateCurrencyCode(currency); err != nil {
+				return nil, nil, validationError("invalid currency: %w", err)
+			}
+		}
+
+		date := h.clientToday(ctx, clientID)
+		if args.Date != "" {
+			date, err = h.parseClientDate(ctx, clientID, args.Date)
+			if err != nil {
+				return nil, nil, validationError("invalid date: %w", err)
+			}
+		}
+
+		receiptPath := ""
+		if args.ReceiptPath != "" {
+			if receiptPath, err = checkReceiptPath(args.ReceiptPath); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		billable := args.Billable == nil || *args.Billable
+		amount := money.FromFloat(args.Amount)
+		result, err := db.ExecContext(ctx, `
+			INSERT INTO expenses (contract_id, date, amount_cents, currency, category, description, receipt_path, billable)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, contractID, date.Format("2006-01-02"), amount, currency, args.Category, args.Description, receiptPath, billable)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to add expense: %w", err)
+		}
+		expenseID, _ := result.LastInsertId()
+
+		text := fmt.Sprintf("Added expense %d: %s for %s on %s", expenseID, amount.Format(currency), args.ContractNumber, date.Format("2006-01-02"))
+		if args.Category != "" || args.Description != "" {
+			text += " - " + expenseDescription(models.Expense{Category: args.Category, Description: args.Description})
+		}
+		if !billable {
+			text += " (not billable)"
+		}
+		if currency != contractCurrency {
+			text += fmt.Sprintf("\nNote: the contract is billed in %s; the expense can only go on a %s invoice", contractCurrency, currency)
+		}
+		expense, err := h.loadExpense(ctx, int(expenseID))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, expense, nil
+	})
+
+	// List Expenses tool
+	type listExpensesArgs struct {
+		ClientName     string `json:"client_name,omitempty" jsonschema:"Filter by client name (optional)"`
+		ContractNumber string `json:"contract_number,omitempty" jsonschema:"Filter by contract number (optional)"`
+		StartDate      string `json:"start_date,omitempty" jsonschema:"Start date (YYYY-MM-DD or natural language)"`
+		EndDate        string `json:"end_date,omitempty" jsonschema:"End date (YYYY-MM-DD or natural language)"`
+		Category       string `json:"category,omitempty" jsonschema:"Filter by category (optional)"`
+		Billable       *bool  `json:"billable,omitempty" jsonschema:"Filter by billable flag (optional)"`
+		Invoiced       *bool  `json:"invoiced,omitempty" jsonschema:"Filter by invoice status: true=invoiced, false=not invoiced (optional)"`
+		Format         string `json:"format,omitempty" jsonschema:"Output format: text (default) or markdown"`
+	}
+
+	type expenseWithContract struct {
+		models.Expense
+		ClientName     string `json:"client_name"`
+		ContractNumber string `json:"contract_number"`
+		InvoiceNumber  string `json:"invoice_number,omitempty"`
+	}
+
+	type listExpensesResult struct {
+		Expenses []expenseWithContract  `json:"expenses"`
+		Totals   map[string]money.Cents `json:"totals" jsonschema:"Total amount per currency"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_expenses",
+		Description: "List expenses with optional filters and totals per currency",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listExpensesArgs) (*mcp.CallToolResult, *listExpensesResult, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		markdown, err := parseOutputFormat(args.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		query := `
+			SELECT e.id, e.contract_id, e.kind, e.date, e.amount_cents, e.currency, e.quantity, e.unit,
+			       e.unit_rate_cents, e.category, e.description, e.receipt_path, e.billable, e.invoice_id, e.created_at, cl.name, ct.contract_number,
+			       COALESCE(i.invoice_number, '')
+			FROM expenses e
+			JOIN contracts ct ON e.contract_id = ct.id
+			JOIN clients cl ON ct.client_id = cl.id
+			LEFT JOIN invoices i ON e.invoice_id = i.id
+			WHERE 1=1
+		`
+		queryArgs := []interface{}{}
+
+		if args.ClientName != "" {
+			clientID, err := h.getClientIDByName(ctx, args.ClientName)
+			if err != nil {
+				return nil, nil, fmt.Errorf("client not found: %w", err)
+			}
+			query += " AND cl.id = ?"
+			queryArgs = append(queryArgs, clientID)
+		}
+		if args.ContractNumber != "" {
+			query += " AND ct.contract_number = ?"
+			queryArgs = append(queryArgs, args.ContractNumber)
+		}
+		if args.StartDate != "" {
+			startDate, err := h.parseDate(ctx, args.StartDate)
+			if err != nil {
+				return nil, nil, validationError("invalid start date: %w", err)
+			}
+			query += " AND e.date >= ?"
+			queryArgs = append(queryArgs, startDate.Format("2006-01-02"))
+		}
+		if args.EndDate != "" {
+			endDate, err := h.parseDate(ctx, args.EndDate)
+			if err != nil {
+				return nil, nil, validationError("invalid end date: %w", err)
+			}
+			query += " AND e.date <= ?"
+			queryArgs = append(queryArgs, endDate.Format("2006-01-02"))
+		}
+		if args.Category != "" {
+			query += " AND e.category = ? COLLATE NOCASE"
+			queryArgs = append(queryArgs, args.Category)
+		}
+		if args.Billable != nil {
+			query += " AND e.billable = ?"
+			queryArgs = append(queryArgs, *args.Billable)
+		}
+		if args.Invoiced != nil {
+			if *args.Invoiced {
+				query += " AND e.invoice_id IS NOT NULL"
+			} else {
+				query += " AND e.invoice_id IS NULL"
+			}
+		}
+
+		query += " ORDER BY e.date DESC, e.id DESC"
+
+		rows, err := db.QueryContext(ctx, query, queryArgs...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list expenses: %w", err)
+		}
+		defer rows.Close()
+
+		var expenses []expenseWithContract
+		totals := map[string]money.Cents{}
+		for rows.Next() {
+			var e expenseWithContract
+			if err := rows.Scan(&e.ID, &e.ContractID, &e.Kind, &e.Date, &e.Amount, &e.Currency, &e.Quantity, &e.Unit,
+				&e.UnitRate, &e.Category, &e.Description, &e.ReceiptPath, &e.Billable, &e.InvoiceID, &e.CreatedAt, &e.ClientName, &e.ContractNumber, &e.InvoiceNumber); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan expense: %w", err)
+			}
+			expenses = append(expenses, e)
+			totals[e.Currency] += e.Amount
+		}
+
+		status := func(e expenseWithContract) string {
+			switch {
+			case e.InvoiceNumber != "":
+				return "invoiced " + e.InvoiceNumber
+			case e.Billable:
+				return "unbilled"
+			}
+			return "not billable"
+		}
+
+		var text string
+		if markdown {
+			tableRows := make([][]string, 0, len(expenses)+1)
+			for _, e := range expenses {
+				tableRows = append(tableRows, []string{fmt.Sprint(e.ID), e.Date.Format("2006-01-02"), e.ClientName,
+					e.ContractNumber, e.Category, e.Description, e.Amount.Format(e.Currency), status(e)})
+			}
+			tableRows = append(tableRows, []string{"", "", "**Total**", "", "", "", "**" + formatCurrencyTotals(totals) + "**", ""})
+			text = fmt.Sprintf("**%d expenses**\n\n", len(expenses)) +
+				markdownTable([]string{"ID", "Date", "Client", "Contract", "Category", "Description", "Amount", "Status"}, tableRows, 6)
+		} else {
+			text = fmt.Sprintf("Found %d expenses:\n", len(expenses))
+			for _, e := range expenses {
+				text += fmt.Sprintf("- ID %d: %s: %s %s - %s (%s) [%s]", e.ID, e.Date.Format("2006-01-02"), e.ClientName,
+					e.ContractNumber, e.Amount.Format(e.Currency), expenseDescription(e.Expense), status(e))
+				if e.ReceiptPath != "" {
+					text += " receipt: " + e.ReceiptPath
+				}
+				text += "\n"
+			}
+			if len(expenses) > 0 {
+				text += fmt.Sprintf("Total: %s\n", formatCurrencyTotals(totals))
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &listExpensesResult{Expenses: expenses, Totals: totals}, nil
+	})
+
+	// Edit Expense tool
+	type editExpenseArgs struct {
+		ExpenseID      int      `json:"expense_id" jsonschema:"Expense ID to edit"`
+		ContractNumber string   `json:"contract_number,omitempty" jsonschema:"Move the expense to another contract (optional)"`
+		Amount         *float64 `json:"amount,omitempty" jsonschema:"New amount (optional)"`
+		Quantity       *float64 `json:"quantity,omitempty" jsonschema:"New distance or number of days for mileage and per-diem expenses (optional)"`
+		Date           string   `json:"date,omitempty" jsonschema:"New date (optional)"`
+		Currency       string   `json:"currency,omitempty" jsonschema:"New currency code (optional)"`
+		Category       *string  `json:"category,omitempty" jsonschema:"New category (optional)"`
+		Description    *string  `json:"description,omitempty" jsonschema:"New description (optional)"`
+		ReceiptPath    *string  `json:"receipt_path,omitempty" jsonschema:"New receipt path, empty to remove it (optional)"`
+		Billable       *bool    `json:"billable,omitempty" jsonschema:"New billable flag (optional)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "edit_expense",
+		Description: "Edit an expense that has not been invoiced yet",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args editExpenseArgs) (*mcp.CallToolResult, *models.Expense, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		var invoiceID sql.NullInt64
+		var kind string
+		var quantity float64
+		var date time.Time
+		err := db.QueryRowContext(ctx, "SELECT invoice_id, kind, quantity, date FROM expenses WHERE id = ?", args.ExpenseID).
+			Scan(&invoiceID, &kind, &quantity, &date)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("expense", "expense %d not found", args.ExpenseID)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find expense: %w", err)
+		}
+		if invoiceID.Valid {
+			return nil, nil, conflictError("cannot edit expense %d; it has already been invoiced", args.ExpenseID)
+		}
+
+		// Mileage and per diem are priced from the quantity and the rate
+		// for their year rather than entered as an amount
+		allowance := kind != expenseKindExpense
+		if allowance && (args.Amount != nil || args.Currency != "") {
+			return nil, nil, validationError("the amount of %s expenses comes from the allowance rate; change quantity instead",
+				strings.ToLower(allowanceLabels[kind]))
+		}
+		if !allowance && args.Quantity != nil {
+			return nil, nil, validationError("quantity only applies to mileage and per-diem expenses; change amount instead")
+		}
+
+		updates := []string{}
+		updateArgs := []interface{}{}
+
+		if args.ContractNumber != "" {
+			var contractID int
+			err := db.QueryRowContext(ctx, "SELECT id FROM contracts WHERE contract_number = ?", args.ContractNumber).Scan(&contractID)
+			if err == sql.ErrNoRows {
+				return nil, nil, notFoundError("contract", "contract %s not found", args.ContractNumber)
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to find contract: %w", err)
+			}
+			updates = append(updates, "contract_id = ?")
+			updateArgs = append(updateArgs, contractID)
+		}
+		if args.Amount != nil {
+			if *args.Amount <= 0 {
+				return nil, nil, validationError("amount must be positive")
+			}
+			updates = append(updates, "amount_cents = ?")
+			updateArgs = append(updateArgs, money.FromFloat(*args.Amount))
+		}
+		if args.Date != "" {
+			date, err = h.parseDate(ctx, args.Date)
+			if err != nil {
+				return nil, nil, validationError("invalid date: %w", err)
+			}
+			updates = append(updates, "date = ?")
+			updateArgs = append(updateArgs, date.Format("2006-01-02"))
+		}
+		if allowance && (args.Quantity != nil || args.Date != "") {
+			if args.Quantity != nil {
+				if *args.Quantity <= 0 {
+					return nil, nil, validationError("quantity must be positive")
+				}
+				quantity = *args.Quantity
+			}
+			rate, currency, err := h.allowanceRate(ctx, kind, date)
+			if err != nil {
+				return nil, nil, err
+			}
+			updates = append(updates, "quantity = ?", "unit_rate_cents = ?", "amount_cents = ?", "currency = ?")
+			updateArgs = append(updateArgs, quantity, rate, rate.Times(quantity), currency)
+		}
+		if args.Currency != "" {
+			currency := strings.ToUpper(strings.TrimSpace(args.Currency))
+			if err := validateCurrencyCode(currency); err != nil {
+				return nil, nil, validationError("invalid currency: %w", err)
+			}
+			updates = append(updates, "currency = ?")
+			updateArgs = append(updateArgs, currency)
+		}
+		if args.Category != nil {
+			updates = append(updates, "category = ?")
+			updateArgs = append(updateArgs, *args.Category)
+		}
+		if args.Description != nil {
+			updates = append(updates, "description = ?")
+			updateArgs = append(updateArgs, *args.Description)
+		}
+		if args.ReceiptPath != nil {
+			receiptPath := ""
+			if *args.ReceiptPath != "" {
+				if receiptPath, err = checkReceiptPath(*args.ReceiptPath); err != nil {
+					return nil, nil, err
+				}
+			}
+			updates = append(updates, "receipt_path = ?")
+			updateArgs = append(updateArgs, receiptPath)
+		}
+		if args.Billable != nil {
+			updates = append(updates, "billable = ?")
+			updateArgs = append(updateArgs, *args.Billable)
+		}
+
+		if len(updates) == 0 {
+			return nil, nil, validationError("no updates provided")
+		}
+
+		updateArgs = append(updateArgs, args.ExpenseID)
+		query := fmt.Sprintf("UPDATE expenses SET %s WHERE id = ?", strings.Join(updates, ", "))
+		if _, err := db.ExecContext(ctx, query, updateArgs...); err != nil {
+			return nil, nil, fmt.Errorf("failed to update expense: %w", err)
+		}
+		expense, err := h.loadExpense(ctx, args.ExpenseID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Updated expense %d", args.ExpenseID)},
+			},
+		}, expense, nil
+	})
+}
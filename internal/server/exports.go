@@ -0,0 +1,803 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/ics"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/austin/hours-mcp/internal/xlsx"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// exportInvoice is an invoice with its billed lines, as needed by accounting exports
+type exportInvoice struct {
+	id           int
+	number       string
+	clientName   string
+	email        string
+	issueDate    time.Time
+	dueDate      time.Time
+	paidDate     *time.Time
+	total        money.Cents
+	tax          money.Cents
+	taxRate      float64
+	withholding  money.Cents
+	rounding     money.Cents
+	currency     string
+	paymentTerms string
+	lines        []exportLine
+}
+
+type exportLine struct {
+	description string
+	quantity    float64
+	rate        money.Cents
+	amount      money.Cents
+}
+
+// accountingTargets lists the supported export targets
+var accountingTargets = map[string]string{
+	"quickbooks":         "QuickBooks Online CSV",
+	"quickbooks_desktop": "QuickBooks Desktop IIF",
+	"xero":               "Xero CSV",
+}
+
+// loadExportInvoices returns non-cancelled invoices issued in the range with their lines
+func (h *Handler) loadExportInvoices(ctx context.Context, start, end time.Time) ([]*exportInvoice, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT i.id, i.invoice_number, c.name, i.issue_date, i.due_date, i.paid_date, i.total_cents, i.tax_cents,
+		       COALESCE(i.tax_rate, 0), i.withholding_cents, i.rounding_cents, i.currency,
+		       COALESCE((SELECT email FROM recipients r WHERE r.client_id = c.id ORDER BY r.is_primary DESC, r.id LIMIT 1), ''),
+		       COALESCE(pd.payment_terms, '')
+		FROM invoices i
+		JOIN clients c ON i.client_id = c.id
+		LEFT JOIN payment_details pd ON pd.client_id = c.id
+		WHERE i.issue_date >= ? AND i.issue_date <= ? AND i.status != 'cancelled'
+		ORDER BY i.issue_date, i.invoice_number
+	`, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoices: %w", err)
+	}
+	defer rows.Close()
+
+	var invoices []*exportInvoice
+	byID := map[int]*exportInvoice{}
+	for rows.Next() {
+		inv := &exportInvoice{}
+		var paidDate sql.NullTime
+		if err := rows.Scan(&inv.id, &inv.number, &inv.clientName, &inv.issueDate, &inv.dueDate,
+			&paidDate, &inv.total, &inv.tax, &inv.taxRate, &inv.withholding, &inv.rounding, &inv.currency, &inv.email, &inv.paymentTerms); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice: %w", err)
+		}
+		if paidDate.Valid {
+			inv.paidDate = &paidDate.Time
+		}
+		invoices = append(invoices, inv)
+		byID[inv.id] = inv
+	}
+	rows.Close()
+
+	// Invoices keep the lines they were priced with
+	storedRows, err := h.db.QueryContext(ctx, `
+		SELECT l.invoice_id, ct.contract_number, ct.name, l.rate_kind, l.rate_label, l.period, l.rate_cents, l.hours, l.amount_cents
+		FROM invoice_lines l
+		JOIN contracts ct ON l.contract_id = ct.id
+		JOIN invoices i ON l.invoice_id = i.id
+		WHERE i.issue_date >= ? AND i.issue_date <= ?
+		ORDER BY l.id
+	`, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoice lines: %w", err)
+	}
+	defer storedRows.Close()
+
+	for storedRows.Next() {
+		var invoiceID int
+		var number, name, kind, label, period string
+		var rate, amount money.Cents
+		var hours float64
+		if err := storedRows.Scan(&invoiceID, &number, &name, &kind, &label, &period, &rate, &hours, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice line: %w", err)
+		}
+		inv, ok := byID[invoiceID]
+		if !ok {
+			continue
+		}
+		description := fmt.Sprintf("%s (%s)", name, number)
+		if label != "" {
+			description += " - " + label
+		}
+		// Retainer fees are a flat amount for a month
+		if period != "" {
+			description += " " + period
+			hours = 1
+		}
+		// and rebilled expenses a flat amount per contract
+		if kind == rateKindExpense {
+			hours = 1
+		}
+		inv.lines = append(inv.lines, exportLine{
+			description: description,
+			quantity:    hours,
+			rate:        rate,
+			amount:      amount,
+		})
+	}
+	storedRows.Close()
+
+	// Older invoices are rebuilt from their time entries
+	lineRows, err := h.db.QueryContext(ctx, `
+		SELECT te.invoice_id, ct.contract_number, ct.name, `+entryRateSQL+` AS rate, SUM(te.hours),
+		       CAST(SUM(ROUND(te.hours * `+entryRateSQL+`)) AS INTEGER)
+		FROM time_entries te
+		JOIN contracts ct ON te.contract_id = ct.id
+		JOIN invoices i ON te.invoice_id = i.id
+		WHERE i.issue_date >= ? AND i.issue_date <= ?
+		  AND NOT EXISTS (SELECT 1 FROM invoice_lines l WHERE l.invoice_id = i.id)
+		GROUP BY te.invoice_id, ct.id, rate
+		ORDER BY ct.contract_number, MIN(te.date)
+	`, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoice lines: %w", err)
+	}
+	defer lineRows.Close()
+
+	for lineRows.Next() {
+		var invoiceID int
+		var number, name string
+		var rate, amount money.Cents
+		var hours float64
+		if err := lineRows.Scan(&invoiceID, &number, &name, &rate, &hours, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice line: %w", err)
+		}
+		inv, ok := byID[invoiceID]
+		if !ok {
+			continue
+		}
+		inv.lines = append(inv.lines, exportLine{
+			description: fmt.Sprintf("%s (%s)", name, number),
+			quantity:    hours,
+			rate:        rate,
+			amount:      amount,
+		})
+	}
+
+	// Invoices whose entries were unlinked still export as a single line,
+	// and tax and rounding get lines of their own so lines add up to the
+	// invoice total
+	for _, inv := range invoices {
+		if len(inv.lines) == 0 {
+			inv.lines = []exportLine{{
+				description: fmt.Sprintf("Invoice %s", inv.number),
+				quantity:    1,
+				rate:        inv.total - inv.tax - inv.rounding,
+				amount:      inv.total - inv.tax - inv.rounding,
+			}}
+		}
+		if inv.tax != 0 {
+			inv.lines = append(inv.lines, exportLine{
+				description: fmt.Sprintf("Tax (%g%%)", inv.taxRate),
+				quantity:    1,
+				rate:        inv.tax,
+				amount:      inv.tax,
+			})
+		}
+		if inv.rounding != 0 {
+			inv.lines = append(inv.lines, exportLine{
+				description: "Rounding",
+				quantity:    1,
+				rate:        inv.rounding,
+				amount:      inv.rounding,
+			})
+		}
+	}
+
+	return invoices, nil
+}
+
+// iifField strips characters that would break the tab-separated IIF layout
+func iifField(value string) string {
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(value)
+}
+
+// registerExportTools registers tools that export data for accounting software
+func registerExportTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Export Accounting tool
+	type exportAccountingArgs struct {
+		Target    string `json:"target" jsonschema:"Accounting software: quickbooks (Online CSV), quickbooks_desktop (IIF) or xero"`
+		Period    string `json:"period,omitempty" jsonschema:"Period to export (e.g. 'last month' 'Q1 2025' 'this year' '2024'; default: last month)"`
+		StartDate string `json:"start_date,omitempty" jsonschema:"Start date, overrides period (optional)"`
+		EndDate   string `json:"end_date,omitempty" jsonschema:"End date, overrides period (optional)"`
+	}
+
+	type exportAccountingResult struct {
+		Target   string   `json:"target"`
+		Invoices int      `json:"invoices" jsonschema:"Number of invoices exported"`
+		Payments int      `json:"payments" jsonschema:"Number of payments exported"`
+		Files    []string `json:"files" jsonschema:"Paths of the files written"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_accounting",
+		Description: "Export invoices and payments as QuickBooks or Xero import files. Account names and codes come from the quickbooks_* and xero_* settings",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args exportAccountingArgs) (*mcp.CallToolResult, *exportAccountingResult, error) {
+		if _, ok := accountingTargets[args.Target]; !ok {
+			return nil, nil, validationError("unknown target '%s'. Valid targets are: quickbooks, quickbooks_desktop, xero", args.Target)
+		}
+
+		period := args.Period
+		if period == "" {
+			period = "last month"
+		}
+		start, end, err := h.parsePeriod(ctx, period)
+		if err != nil {
+			return nil, nil, validationError("invalid period: %w", err)
+		}
+		if args.StartDate != "" {
+			if start, err = h.parseDate(ctx, args.StartDate); err != nil {
+				return nil, nil, validationError("invalid start date: %w", err)
+			}
+		}
+		if args.EndDate != "" {
+			if end, err = h.parseDate(ctx, args.EndDate); err != nil {
+				return nil, nil, validationError("invalid end date: %w", err)
+			}
+		}
+
+		invoices, err := h.loadExportInvoices(ctx, start, end)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Payments are exported by the date they were received
+		paymentsStart, paymentsEnd := truncateDay(start), truncateDay(end)
+		var payments []*exportInvoice
+		paidInvoices, err := h.loadExportInvoices(ctx, time.Time{}, end)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, inv := range paidInvoices {
+			if inv.paidDate != nil && !inv.paidDate.Before(paymentsStart) && !inv.paidDate.After(paymentsEnd) {
+				payments = append(payments, inv)
+			}
+		}
+
+		layout := dateOrderLayouts[h.getSetting(ctx, "accounting_date_format")]
+		amount := func(v money.Cents) string { return v.String() }
+		qty := func(v float64) string { return fmt.Sprintf("%.2f", v) }
+
+		homeDir, _ := os.UserHomeDir()
+		suffix := fmt.Sprintf("%s_%s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+		var files []string
+
+		writeFile := func(name, content string) error {
+			path := filepath.Join(homeDir, "Downloads", name)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", name, err)
+			}
+			files = append(files, path)
+			return nil
+		}
+
+		writeCSV := func(name string, header []string, rows [][]string) error {
+			var buf strings.Builder
+			w := csv.NewWriter(&buf)
+			w.Write(header)
+			w.WriteAll(rows)
+			if err := w.Error(); err != nil {
+				return fmt.Errorf("failed to build CSV: %w", err)
+			}
+			return writeFile(name, buf.String())
+		}
+
+		switch args.Target {
+		case "quickbooks":
+			item := h.getSetting(ctx, "quickbooks_item")
+			var invoiceRows [][]string
+			for _, inv := range invoices {
+				for _, line := range inv.lines {
+					invoiceRows = append(invoiceRows, []string{
+						inv.number, inv.clientName, inv.issueDate.Format(layout), inv.dueDate.Format(layout),
+						inv.paymentTerms, item, line.description, qty(line.quantity), amount(line.rate),
+						amount(line.amount), inv.currency,
+					})
+				}
+			}
+			err = writeCSV("quickbooks_invoices_"+suffix+".csv", []string{
+				"InvoiceNo", "Customer", "InvoiceDate", "DueDate", "Terms", "Item(Product/Service)",
+				"ItemDescription", "ItemQuantity", "ItemRate", "ItemAmount", "Currency",
+			}, invoiceRows)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			// QuickBooks Online has no payment import; a bank upload lets the
+			// bookkeeper match each deposit to its open invoice
+			var paymentRows [][]string
+			for _, inv := range payments {
+				paymentRows = append(paymentRows, []string{
+					inv.paidDate.Format(layout),
+					fmt.Sprintf("Payment %s %s", inv.clientName, inv.number),
+					amount(inv.total - inv.withholding),
+				})
+			}
+			err = writeCSV("quickbooks_payments_"+suffix+".csv", []string{"Date", "Description", "Amount"}, paymentRows)
+			if err != nil {
+				return nil, nil, err
+			}
+
+		case "quickbooks_desktop":
+			income := h.getSetting(ctx, "quickbooks_income_account")
+			deposit := h.getSetting(ctx, "quickbooks_deposit_account")
+			item := h.getSetting(ctx, "quickbooks_item")
+			date := func(t time.Time) string { return t.Format("01/02/2006") }
+
+			var b strings.Builder
+			b.WriteString("!TRNS\tTRNSTYPE\tDATE\tACCNT\tNAME\tAMOUNT\tDOCNUM\tMEMO\tDUEDATE\n")
+			b.WriteString("!SPL\tTRNSTYPE\tDATE\tACCNT\tNAME\tAMOUNT\tDOCNUM\tMEMO\tQNTY\tPRICE\tINVITEM\n")
+			b.WriteString("!ENDTRNS\n")
+			for _, inv := range invoices {
+				name := iifField(inv.clientName)
+				b.WriteString(strings.Join([]string{"TRNS", "INVOICE", date(inv.issueDate), "Accounts Receivable",
+					name, amount(inv.total), inv.number, "", date(inv.dueDate)}, "\t") + "\n")
+				for _, line := range inv.lines {
+					b.WriteString(strings.Join([]string{"SPL", "INVOICE", date(inv.issueDate), iifField(income),
+						name, amount(-line.amount), inv.number, iifField(line.description),
+						qty(-line.quantity), amount(line.rate), iifField(item)}, "\t") + "\n")
+				}
+				b.WriteString("ENDTRNS\n")
+			}
+			for _, inv := range payments {
+				name := iifField(inv.clientName)
+				b.WriteString(strings.Join([]string{"TRNS", "PAYMENT", date(*inv.paidDate), iifField(deposit),
+					name, amount(inv.total - inv.withholding), inv.number, "Payment for " + inv.number, ""}, "\t") + "\n")
+				b.WriteString(strings.Join([]string{"SPL", "PAYMENT", date(*inv.paidDate), "Accounts Receivable",
+					name, amount(inv.withholding - inv.total), inv.number, "", "", "", ""}, "\t") + "\n")
+				b.WriteString("ENDTRNS\n")
+			}
+			if err := writeFile("quickbooks_"+suffix+".iif", b.String()); err != nil {
+				return nil, nil, err
+			}
+
+		case "xero":
+			account := h.getSetting(ctx, "xero_sales_account")
+			taxType := h.getSetting(ctx, "xero_tax_type")
+			var invoiceRows [][]string
+			for _, inv := range invoices {
+				for _, line := range inv.lines {
+					invoiceRows = append(invoiceRows, []string{
+						inv.clientName, inv.email, inv.number, inv.issueDate.Format(layout), inv.dueDate.Format(layout),
+						line.description, qty(line.quantity), amount(line.rate), account, taxType, inv.currency,
+					})
+				}
+			}
+			err = writeCSV("xero_invoices_"+suffix+".csv", []string{
+				"ContactName", "EmailAddress", "InvoiceNumber", "InvoiceDate", "DueDate",
+				"Description", "Quantity", "UnitAmount", "AccountCode", "TaxType", "Currency",
+			}, invoiceRows)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			// Bank statement layout, reconciled against the imported invoices in Xero
+			var paymentRows [][]string
+			for _, inv := range payments {
+				paymentRows = append(paymentRows, []string{
+					inv.paidDate.Format(layout), amount(inv.total - inv.withholding), inv.clientName,
+					"Payment for " + inv.number, inv.number,
+				})
+			}
+			err = writeCSV("xero_payments_"+suffix+".csv", []string{"Date", "Amount", "Payee", "Description", "Reference"}, paymentRows)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		text := fmt.Sprintf("Exported %d invoices and %d payments (%s to %s) for %s:\n",
+			len(invoices), len(payments), start.Format("2006-01-02"), end.Format("2006-01-02"), accountingTargets[args.Target])
+		for _, file := range files {
+			text += fmt.Sprintf("- %s\n", file)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &exportAccountingResult{Target: args.Target, Invoices: len(invoices), Payments: len(payments), Files: files}, nil
+	})
+	// Export Excel tool
+	type exportExcelArgs struct {
+		Period     string `json:"period,omitempty" jsonschema:"Period to export (e.g. 'this month' 'last month' 'this year' 'year to date'; default: this month)"`
+		StartDate  string `json:"start_date,omitempty" jsonschema:"Start date, overrides period (optional)"`
+		EndDate    string `json:"end_date,omitempty" jsonschema:"End date, overrides period (optional)"`
+		ClientName string `json:"client_name,omitempty" jsonschema:"Only include this client (optional)"`
+	}
+
+	type exportExcelResult struct {
+		FilePath string `json:"file_path"`
+		Entries  int    `json:"entries" jsonschema:"Number of time entry rows"`
+		Invoices int    `json:"invoices" jsonschema:"Number of invoice rows"`
+		Clients  int    `json:"clients" jsonschema:"Number of clients in the summary"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_excel",
+		Description: "Export an Excel workbook with time entries, invoices and a per-client summary for a period",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args exportExcelArgs) (*mcp.CallToolResult, *exportExcelResult, error) {
+		period := args.Period
+		if period == "" {
+			period = "this month"
+		}
+		start, end, err := h.parsePeriod(ctx, period)
+		if err != nil {
+			return nil, nil, validationError("invalid period: %w", err)
+		}
+		if args.StartDate != "" {
+			if start, err = h.parseDate(ctx, args.StartDate); err != nil {
+				return nil, nil, validationError("invalid start date: %w", err)
+			}
+		}
+		if args.EndDate != "" {
+			if end, err = h.parseDate(ctx, args.EndDate); err != nil {
+				return nil, nil, validationError("invalid end date: %w", err)
+			}
+		}
+
+		clientFilter, entryFilter := "", "te.date >= ? AND te.date <= ?"
+		queryArgs := []interface{}{start.Format("2006-01-02"), end.Format("2006-01-02")}
+		if args.ClientName != "" {
+			clientID, err := h.getClientIDByName(ctx, args.ClientName)
+			if err != nil {
+				return nil, nil, fmt.Errorf("client not found: %w", err)
+			}
+			clientFilter = " AND cl.id = ?"
+			entryFilter += " AND ct.client_id = ?"
+			queryArgs = append(queryArgs, clientID)
+		}
+
+		workbook := &xlsx.Workbook{}
+
+		// Time entries sheet
+		entries := workbook.AddSheet("Time Entries")
+		entries.AddRow("Date", "Client", "Contract", "Contract Name", "Hours", "Rate", "Amount", "Currency", "Description", "Invoice")
+		rows, err := db.QueryContext(ctx, `
+			SELECT te.id, te.date, cl.name, ct.contract_number, ct.name, te.hours, `+entryRateSQL+`,
+			       COALESCE(ct.currency, 'USD'), COALESCE(te.description, ''), COALESCE(i.invoice_number, '')
+			FROM time_entries te
+			JOIN contracts ct ON te.contract_id = ct.id
+			JOIN clients cl ON ct.client_id = cl.id
+			LEFT JOIN invoices i ON te.invoice_id = i.id
+			WHERE te.date >= ? AND te.date <= ?`+clientFilter+`
+			ORDER BY te.date, cl.name
+		`, queryArgs...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to query time entries: %w", err)
+		}
+
+		// Amounts include weekend, holiday and overtime premiums
+		items, err := h.priceStoredEntries(ctx, entryFilter, queryArgs...)
+		if err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		entryAmounts := map[string]money.Cents{}
+		for _, item := range items {
+			entryAmounts[item.TimeEntryID] += item.Amount
+		}
+
+		type clientSummary struct {
+			hours    float64
+			amounts  map[string]money.Cents
+			invoiced map[string]money.Cents
+			paid     map[string]money.Cents
+		}
+		summaries := map[string]*clientSummary{}
+		summaryFor := func(name string) *clientSummary {
+			if summaries[name] == nil {
+				summaries[name] = &clientSummary{
+					amounts:  map[string]money.Cents{},
+					invoiced: map[string]money.Cents{},
+					paid:     map[string]money.Cents{},
+				}
+			}
+			return summaries[name]
+		}
+
+		entryCount := 0
+		for rows.Next() {
+			var date time.Time
+			var id, client, number, name, currency, description, invoice string
+			var hours float64
+			var rate money.Cents
+			if err := rows.Scan(&id, &date, &client, &number, &name, &hours, &rate, &currency, &description, &invoice); err != nil {
+				rows.Close()
+				return nil, nil, fmt.Errorf("failed to scan time entry: %w", err)
+			}
+			amount := entryAmounts[id]
+			entries.AddRow(date, client, number, name, hours, rate.Float(), amount.Float(), currency, description, invoice)
+			s := summaryFor(client)
+			s.hours += hours
+			s.amounts[currency] += amount
+			entryCount++
+		}
+		rows.Close()
+
+		// Invoices sheet
+		invoices := workbook.AddSheet("Invoices")
+		invoices.AddRow("Invoice", "Client", "Issue Date", "Due Date", "Status", "Total", "Currency", "Paid Date")
+		rows, err = db.QueryContext(ctx, `
+			SELECT i.invoice_number, cl.name, i.issue_date, i.due_date, COALESCE(i.status, ''), i.total_cents, i.currency, i.paid_date
+			FROM invoices i
+			JOIN clients cl ON i.client_id = cl.id
+			WHERE i.issue_date >= ? AND i.issue_date <= ?`+clientFilter+`
+			ORDER BY i.issue_date, i.invoice_number
+		`, queryArgs...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to query invoices: %w", err)
+		}
+
+		invoiceCount := 0
+		for rows.Next() {
+			var number, client, status, currency string
+			var issueDate, dueDate time.Time
+			var paidDate sql.NullTime
+			var total money.Cents
+			if err := rows.Scan(&number, &client, &issueDate, &dueDate, &status, &total, &currency, &paidDate); err != nil {
+				rows.Close()
+				return nil, nil, fmt.Errorf("failed to scan invoice: %w", err)
+			}
+			var paid interface{}
+			if paidDate.Valid {
+				paid = paidDate.Time
+			}
+			invoices.AddRow(number, client, issueDate, dueDate, status, total.Float(), currency, paid)
+			if status != "cancelled" {
+				s := summaryFor(client)
+				s.invoiced[currency] += total
+				if status == "paid" {
+					s.paid[currency] += total
+				}
+			}
+			invoiceCount++
+		}
+		rows.Close()
+
+		// Summary sheet, one row per client and currency
+		summary := workbook.AddSheet("Summary")
+		summary.AddRow("Client", "Hours", "Amount", "Currency", "Invoiced", "Paid")
+		clients := make([]string, 0, len(summaries))
+		for client := range summaries {
+			clients = append(clients, client)
+		}
+		sort.Strings(clients)
+		for _, client := range clients {
+			s := summaries[client]
+			seen := map[string]bool{}
+			var currencies []string
+			for _, amounts := range []map[string]money.Cents{s.amounts, s.invoiced} {
+				for currency := range amounts {
+					if !seen[currency] {
+						seen[currency] = true
+						currencies = append(currencies, currency)
+					}
+				}
+			}
+			sort.Strings(currencies)
+			if len(currencies) == 0 {
+				summary.AddRow(client, s.hours, 0.0, "", 0.0, 0.0)
+				continue
+			}
+			for i, currency := range currencies {
+				var hours interface{}
+				if i == 0 {
+					hours = s.hours
+				}
+				summary.AddRow(client, hours, s.amounts[currency].Float(), currency, s.invoiced[currency].Float(), s.paid[currency].Float())
+			}
+		}
+
+		homeDir, _ := os.UserHomeDir()
+		path := filepath.Join(homeDir, "Downloads", fmt.Sprintf("hours_report_%s_%s.xlsx",
+			start.Format("2006-01-02"), end.Format("2006-01-02")))
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create workbook: %w", err)
+		}
+		if err := workbook.Write(file); err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("failed to write workbook: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			return nil, nil, fmt.Errorf("failed to write workbook: %w", err)
+		}
+
+		text := fmt.Sprintf("Excel report for %s to %s saved to: %s\n- Time Entries: %d rows\n- Invoices: %d rows\n- Summary: %d clients\n",
+			start.Format("2006-01-02"), end.Format("2006-01-02"), path, entryCount, invoiceCount, len(clients))
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &exportExcelResult{FilePath: path, Entries: entryCount, Invoices: invoiceCount, Clients: len(clients)}, nil
+	})
+
+	// Export Calendar tool
+	type exportCalendarArgs struct {
+		Period     string `json:"period,omitempty" jsonschema:"Period to export (e.g. 'this month' 'last week' 'this year'; default: this month)"`
+		StartDate  string `json:"start_date,omitempty" jsonschema:"Start date, overrides period (optional)"`
+		EndDate    string `json:"end_date,omitempty" jsonschema:"End date, overrides period (optional)"`
+		ClientName string `json:"client_name,omitempty" jsonschema:"Only include this client, leaving out internal time (optional)"`
+		DayStart   string `json:"day_start,omitempty" jsonschema:"Time of day, like 09:00, from which each day's entries are laid out back to back as timed events (default: all-day events)"`
+	}
+
+	type exportCalendarResult struct {
+		FilePath string  `json:"file_path"`
+		Events   int     `json:"events" jsonschema:"Number of events, one per time entry"`
+		Hours    float64 `json:"hours"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_calendar",
+		Description: "Export time entries as an iCalendar (.ics) file, one event per entry with the client, hours and description, to overlay logged time on a calendar and spot discrepancies",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args exportCalendarArgs) (*mcp.CallToolResult, *exportCalendarResult, error) {
+		period := args.Period
+		if period == "" {
+			period = "this month"
+		}
+		start, end, err := h.parsePeriod(ctx, period)
+		if err != nil {
+			return nil, nil, validationError("invalid period: %w", err)
+		}
+		if args.StartDate != "" {
+			if start, err = h.parseDate(ctx, args.StartDate); err != nil {
+				return nil, nil, validationError("invalid start date: %w", err)
+			}
+		}
+		if args.EndDate != "" {
+			if end, err = h.parseDate(ctx, args.EndDate); err != nil {
+				return nil, nil, validationError("invalid end date: %w", err)
+			}
+		}
+		var dayStart *time.Time
+		if args.DayStart != "" {
+			t, err := time.Parse("15:04", strings.TrimSpace(args.DayStart))
+			if err != nil {
+				return nil, nil, validationError("invalid day_start '%s': use HH:MM, e.g. 09:00", args.DayStart)
+			}
+			dayStart = &t
+		}
+
+		clientFilter := ""
+		queryArgs := []interface{}{start.Format("2006-01-02"), end.Format("2006-01-02")}
+		if args.ClientName != "" {
+			clientID, err := h.getClientIDByName(ctx, args.ClientName)
+			if err != nil {
+				return nil, nil, fmt.Errorf("client not found: %w", err)
+			}
+			clientFilter = " AND cl.id = ?"
+			queryArgs = append(queryArgs, clientID)
+		}
+
+		// Internal time goes in too, unless the export is for one client
+		query := `
+			SELECT te.id, te.date, te.hours, COALESCE(te.description, ''), cl.name, ct.contract_number, ct.name,
+			       COALESCE(s.name, ''), te.billable, te.no_charge, COALESCE(i.invoice_number, ''), te.created_at
+			FROM time_entries te
+			JOIN contracts ct ON te.contract_id = ct.id
+			JOIN clients cl ON ct.client_id = cl.id
+			LEFT JOIN services s ON te.service_id = s.id
+			LEFT JOIN invoices i ON te.invoice_id = i.id
+			WHERE te.date >= ? AND te.date <= ?` + clientFilter
+		if args.ClientName == "" {
+			query += `
+			UNION ALL
+			SELECT 'internal-' || e.id, e.date, e.hours, e.description, 'Internal', '', c.name, '', FALSE, FALSE, '', e.created_at
+			FROM internal_entries e
+			JOIN internal_categories c ON c.id = e.category_id
+			WHERE e.date >= ? AND e.date <= ?`
+			queryArgs = append(queryArgs, queryArgs...)
+		}
+		rows, err := db.QueryContext(ctx, query+" ORDER BY 2, 12", queryArgs...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to query time entries: %w", err)
+		}
+		defer rows.Close()
+
+		calendar := &ics.Calendar{Name: "Logged hours"}
+		var totalHours float64
+		var cursor time.Time
+		for rows.Next() {
+			var id, description, client, number, name, service, invoice string
+			var date, createdAt time.Time
+			var hours float64
+			var billable, noCharge bool
+			if err := rows.Scan(&id, &date, &hours, &description, &client, &number, &name, &service,
+				&billable, &noCharge, &invoice, &createdAt); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan time entry: %w", err)
+			}
+			totalHours += hours
+
+			event := ics.Event{UID: id + "@hours-mcp", Date: date, Categories: []string{client}}
+			who := client
+			if number == "" {
+				who = name + " (internal)"
+			}
+			event.Summary = fmt.Sprintf("%.2fh %s", hours, who)
+			if description != "" {
+				event.Summary += " - " + description
+			}
+			var details []string
+			switch {
+			case number == "":
+				details = append(details, "Internal time: "+name)
+			default:
+				details = append(details, fmt.Sprintf("Contract: %s (%s)", number, name))
+				if service != "" {
+					details = append(details, "Service: "+service)
+				}
+				switch {
+				case invoice != "":
+					details = append(details, "Invoice: "+invoice)
+				case !billable:
+					details = append(details, "Not billable")
+				default:
+					details = append(details, "Unbilled")
+				}
+				if noCharge {
+					details = append(details, "No charge")
+				}
+			}
+			event.Description = strings.Join(append(details, "Entry: "+strings.TrimPrefix(id, "internal-")), "\n")
+
+			// Timed events follow each other from day_start; adjustments
+			// taking hours off stay all-day
+			if dayStart != nil && hours > 0 {
+				if cursor.Format("2006-01-02") != date.Format("2006-01-02") {
+					cursor = time.Date(date.Year(), date.Month(), date.Day(), dayStart.Hour(), dayStart.Minute(), 0, 0, time.UTC)
+				}
+				event.Start = cursor
+				event.End = cursor.Add(time.Duration(hours * float64(time.Hour)).Round(time.Minute))
+				cursor = event.End
+			}
+			calendar.Add(event)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		homeDir, _ := os.UserHomeDir()
+		path := filepath.Join(homeDir, "Downloads", fmt.Sprintf("hours_calendar_%s_%s.ics",
+			start.Format("2006-01-02"), end.Format("2006-01-02")))
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create calendar: %w", err)
+		}
+		if err := calendar.Write(file, time.Now()); err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("failed to write calendar: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			return nil, nil, fmt.Errorf("failed to write calendar: %w", err)
+		}
+
+		text := fmt.Sprintf("Calendar of %d time entries (%.2f hours) for %s to %s saved to: %s",
+			calendar.Len(), totalHours, start.Format("2006-01-02"), end.Format("2006-01-02"), path)
+		if dayStart != nil {
+			text += fmt.Sprintf("\nEach day's entries run back to back from %s; the times are laid out, not recorded", dayStart.Format("15:04"))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &exportCalendarResult{FilePath: path, Events: calendar.Len(), Hours: totalHours}, nil
+	})
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// HTTPPath is where the streamable HTTP transport is served
+const HTTPPath = "/mcp"
+
+// httpShutdownTimeout bounds how long open sessions get to finish when the
+// server stops
+const httpShutdownTimeout = 10 * time.Second
+
+// ServeHTTP runs the MCP server over the streamable HTTP transport on addr
+// until ctx is cancelled. Every session shares the server, so tool calls
+// from several machines go through the same middleware. Requests must carry
+// token as a bearer token.
+func ServeHTTP(ctx context.Context, mcpServer *mcp.Server, addr, token string) error {
+	if token == "" {
+		return errors.New("a bearer token is required to serve over HTTP")
+	}
+
+	mcpHandler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server {
+		return mcpServer
+	}, nil)
+	mux := http.NewServeMux()
+	mux.Handle(HTTPPath, requireBearerToken(token, mcpHandler))
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		slog.Info("serving MCP over HTTP", "addr", addr, "path", HTTPPath)
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("failed to serve HTTP: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), httpShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to stop HTTP server: %w", err)
+	}
+	return nil
+}
+
+// requireBearerToken rejects requests without an Authorization header
+// carrying token
+func requireBearerToken(token string, next http.Handler) http.Handler {
+	// Hashing first makes the comparison constant time whatever the length
+	want := sha256.Sum256([]byte(token))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		gotSum := sha256.Sum256([]byte(strings.TrimSpace(got)))
+		if !ok || subtle.ConstantTimeCompare(gotSum[:], want[:]) != 1 {
+			slog.Warn("rejected HTTP request without a valid token", "remote", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="hours-mcp"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,635 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/importer"
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// importContract is the contract a record will be imported against
+type importContract struct {
+	id       int
+	clientID int
+	number   string
+}
+
+// importPlan is the outcome of mapping and duplicate-checking a set of records
+type importPlan struct {
+	ready      []importer.Record
+	contracts  []importContract
+	duplicates []importer.Record
+	unmapped   map[string]int
+}
+
+// planImport resolves each record to a contract and flags entries that already exist.
+// Records are matched by contract number, then by the mapping keys issue,
+// "Client / Project", "Project" and "Client", then by the default contract.
+func (h *Handler) planImport(ctx context.Context, records []importer.Record, mapping map[string]string, defaultContract string, includeDuplicates bool) (*importPlan, error) {
+	// Contracts of archived clients take no new hours, so rows for them
+	// are reported as unmapped
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT c.id, c.client_id, c.contract_number
+		FROM contracts c
+		JOIN clients cl ON c.client_id = cl.id
+		WHERE cl.archived_at IS NULL
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contracts: %w", err)
+	}
+	contracts := map[string]importContract{}
+	for rows.Next() {
+		var c importContract
+		if err := rows.Scan(&c.id, &c.clientID, &c.number); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan contract: %w", err)
+		}
+		contracts[strings.ToLower(c.number)] = c
+	}
+	rows.Close()
+
+	lowerMapping := map[string]string{}
+	for key, value := range mapping {
+		lowerMapping[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+
+	lookup := func(number string) (importContract, error) {
+		c, ok := contracts[strings.ToLower(number)]
+		if !ok {
+			return c, notFoundError("contract", "contract '%s' not found", number)
+		}
+		return c, nil
+	}
+
+	// Existing entries per contract/date/hours/description, consumed as matches are found
+	// so that identical rows within one file are still imported once each.
+	existing := map[string]int{}
+	plan := &importPlan{unmapped: map[string]int{}}
+
+	for _, record := range records {
+		var contract importContract
+		var found bool
+
+		if record.Contract != "" {
+			if c, ok := contracts[strings.ToLower(record.Contract)]; ok {
+				contract, found = c, true
+			}
+		}
+
+		if !found {
+			for _, key := range []string{record.Issue, record.Client + " / " + record.Project, record.Project, record.Client} {
+				key = strings.ToLower(strings.Trim(key, " /"))
+				if key == "" {
+					continue
+				}
+				if number, ok := lowerMapping[key]; ok {
+					c, err := lookup(number)
+					if err != nil {
+						return nil, fmt.Errorf("invalid mapping for '%s': %w", key, err)
+					}
+					contract, found = c, true
+					break
+				}
+			}
+		}
+
+		if !found && defaultContract != "" {
+			c, err := lookup(defaultContract)
+			if err != nil {
+				return nil, fmt.Errorf("invalid default contract: %w", err)
+			}
+			contract, found = c, true
+		}
+
+		if !found {
+			source := strings.Trim(record.Client+" / "+record.Project, " /")
+			if source == "" {
+				source = "(no client or project)"
+			}
+			plan.unmapped[source]++
+			continue
+		}
+
+		if !includeDuplicates {
+			date := record.Date.Format("2006-01-02")
+			key := fmt.Sprintf("%d|%s|%.4f|%s", contract.id, date, record.Hours, record.Description)
+			count, seen := existing[key]
+			if !seen {
+				err := h.db.QueryRowContext(ctx, `
+					SELECT COUNT(*) FROM time_entries
+					WHERE contract_id = ? AND date = ? AND ABS(hours - ?) < 0.001 AND COALESCE(description, '') = ?
+				`, contract.id, date, record.Hours, record.Description).Scan(&count)
+				if err != nil {
+					return nil, fmt.Errorf("failed to check for duplicates: %w", err)
+				}
+			}
+			if count > 0 {
+				existing[key] = count - 1
+				plan.duplicates = append(plan.duplicates, record)
+				continue
+			}
+			existing[key] = 0
+		}
+
+		plan.ready = append(plan.ready, record)
+		plan.contracts = append(plan.contracts, contract)
+	}
+
+	return plan, nil
+}
+
+// apply inserts the planned entries in a single transaction
+func (p *importPlan) apply(ctx context.Context, db *sql.DB, source string) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i, record := range p.ready {
+		contract := p.contracts[i]
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO time_entries (id, client_id, contract_id, date, hours, description, contract_ref)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, uuid.New().String(), contract.clientID, contract.id, record.Date.Format("2006-01-02"), record.Hours, record.Description, contract.number)
+		if err != nil {
+			return 0, fmt.Errorf("failed to import %s line %d: %w", source, record.Line, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(p.ready), nil
+}
+
+// summary describes the plan as text
+func (p *importPlan) summary(applied bool) string {
+	var totalHours float64
+	for _, record := range p.ready {
+		totalHours += record.Hours
+	}
+
+	verb := "Would import"
+	if applied {
+		verb = "Imported"
+	}
+	text := fmt.Sprintf("%s %d entries (%.2f hours)\n", verb, len(p.ready), totalHours)
+
+	perContract := map[string]float64{}
+	for i, record := range p.ready {
+		perContract[p.contracts[i].number] += record.Hours
+	}
+	numbers := make([]string, 0, len(perContract))
+	for number := range perContract {
+		numbers = append(numbers, number)
+	}
+	sort.Strings(numbers)
+	for _, number := range numbers {
+		text += fmt.Sprintf("- %s: %.2f hours\n", number, math.Round(perContract[number]*100)/100)
+	}
+
+	if len(p.duplicates) > 0 {
+		text += fmt.Sprintf("\nSkipped %d duplicate entries already in the database\n", len(p.duplicates))
+	}
+
+	if len(p.unmapped) > 0 {
+		sources := make([]string, 0, len(p.unmapped))
+		for source := range p.unmapped {
+			sources = append(sources, source)
+		}
+		sort.Strings(sources)
+		text += "\nUnmapped entries (add a mapping or set default_contract):\n"
+		for _, source := range sources {
+			text += fmt.Sprintf("- %s: %d entries\n", source, p.unmapped[source])
+		}
+	}
+
+	return text
+}
+
+// registerImportTools registers tools for importing time entries from other trackers
+func registerImportTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Import Time Entries tool
+	type importTimeEntriesArgs struct {
+		FilePath          string            `json:"file_path" jsonschema:"Path to the export file"`
+		Format            string            `json:"format,omitempty" jsonschema:"Export format: csv (columns date, hours, contract or client, description), harvest, clockify or jira (Jira/Tempo worklog CSV or JSON) (default: csv)"`
+		ContractMapping   map[string]string `json:"contract_mapping,omitempty" jsonschema:"Map of Jira issue key, 'Client / Project', project (or Jira project key) or client name to contract number"`
+		DefaultContract   string            `json:"default_contract,omitempty" jsonschema:"Contract number for entries that match no mapping (optional)"`
+		IncludeDuplicates bool              `json:"include_duplicates,omitempty" jsonschema:"Import entries even if an identical entry already exists"`
+		DryRun            bool              `json:"dry_run,omitempty" jsonschema:"Preview the import without saving anything"`
+	}
+
+	type importTimeEntriesResult struct {
+		Read       int            `json:"read" jsonschema:"Entries read from the file"`
+		Imported   int            `json:"imported"`
+		Ready      int            `json:"ready" jsonschema:"Entries matched to a contract"`
+		Duplicates int            `json:"duplicates" jsonschema:"Entries skipped as already logged"`
+		Unmapped   map[string]int `json:"unmapped" jsonschema:"Entries matching no contract, per client or project"`
+		DryRun     bool           `json:"dry_run"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "import_time_entries",
+		Description: "Import time entries from a CSV, Harvest, Clockify or Jira/Tempo worklog export, mapping clients/projects to contracts and skipping duplicates",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args importTimeEntriesArgs) (*mcp.CallToolResult, *importTimeEntriesResult, error) {
+		if err := h.resolveContractNumber(ctx, &args.DefaultContract); err != nil {
+			return nil, nil, err
+		}
+		format := args.Format
+		if format == "" {
+			format = "csv"
+		}
+
+		path := expandHome(args.FilePath)
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open import file: %w", err)
+		}
+		defer file.Close()
+
+		records, err := importer.Parse(format, file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s export: %w", format, err)
+		}
+
+		plan, err := h.planImport(ctx, records, args.ContractMapping, args.DefaultContract, args.IncludeDuplicates)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		imported := 0
+		if !args.DryRun && len(plan.ready) > 0 {
+			imported, err = plan.apply(ctx, db, format)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		text := fmt.Sprintf("Read %d entries from %s\n", len(records), filepath.Base(path))
+		text += plan.summary(!args.DryRun)
+		if args.DryRun {
+			text += "\nDry run: nothing was saved."
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &importTimeEntriesResult{
+			Read:       len(records),
+			Imported:   imported,
+			Ready:      len(plan.ready),
+			Duplicates: len(plan.duplicates),
+			Unmapped:   plan.unmapped,
+			DryRun:     args.DryRun,
+		}, nil
+	})
+	// Import Calendar tool
+	type importCalendarArgs struct {
+		FilePath        string            `json:"file_path,omitempty" jsonschema:"Path to an .ics file"`
+		URL             string            `json:"url,omitempty" jsonschema:"Calendar URL (https or webcal), used when file_path is not set"`
+		Period          string            `json:"period,omitempty" jsonschema:"Period to import (e.g. 'last week' 'this month'; default: last week)"`
+		StartDate       string            `json:"start_date,omitempty" jsonschema:"Start date, overrides period (optional)"`
+		EndDate         string            `json:"end_date,omitempty" jsonschema:"End date, overrides period (optional)"`
+		KeywordMapping  map[string]string `json:"keyword_mapping" jsonschema:"Map of keyword found in the event title, description or location to contract number"`
+		DefaultContract string            `json:"default_contract,omitempty" jsonschema:"Contract number for events that match no keyword (optional; unmatched events are skipped otherwise)"`
+		Confirm         bool              `json:"confirm,omitempty" jsonschema:"Save the proposed entries (default: false, only shows the proposal)"`
+	}
+
+	type proposedEntry struct {
+		Date           string  `json:"date"`
+		Hours          float64 `json:"hours"`
+		ContractNumber string  `json:"contract_number"`
+		Description    string  `json:"description"`
+	}
+
+	type importCalendarResult struct {
+		Events     int             `json:"events" jsonschema:"Timed events in the period"`
+		Proposed   []proposedEntry `json:"proposed"`
+		Duplicates int             `json:"duplicates" jsonschema:"Events skipped as already logged"`
+		Unmatched  map[string]int  `json:"unmatched" jsonschema:"Events matching no keyword"`
+		Saved      int             `json:"saved"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "import_calendar",
+		Description: "Propose time entries from calendar events (.ics file or URL) matching client keywords; run again with confirm=true to save them",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args importCalendarArgs) (*mcp.CallToolResult, *importCalendarResult, error) {
+		if err := h.resolveContractNumber(ctx, &args.DefaultContract); err != nil {
+			return nil, nil, err
+		}
+		period := args.Period
+		if period == "" {
+			period = "last week"
+		}
+		start, end, err := h.parsePeriod(ctx, period)
+		if err != nil {
+			return nil, nil, validationError("invalid period: %w", err)
+		}
+		if args.StartDate != "" {
+			if start, err = h.parseDate(ctx, args.StartDate); err != nil {
+				return nil, nil, validationError("invalid start date: %w", err)
+			}
+		}
+		if args.EndDate != "" {
+			if end, err = h.parseDate(ctx, args.EndDate); err != nil {
+				return nil, nil, validationError("invalid end date: %w", err)
+			}
+		}
+		start = truncateDay(start)
+		end = truncateDay(end).AddDate(0, 0, 1).Add(-time.Second)
+
+		var source io.Reader
+		switch {
+		case args.FilePath != "":
+			file, err := os.Open(expandHome(args.FilePath))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to open calendar file: %w", err)
+			}
+			defer file.Close()
+			source = file
+		case args.URL != "":
+			url := args.URL
+			if strings.HasPrefix(url, "webcal://") {
+				url = "https://" + strings.TrimPrefix(url, "webcal://")
+			}
+			client := &http.Client{Timeout: 30 * time.Second}
+			resp, err := client.Get(url)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to fetch calendar: %w", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return nil, nil, fmt.Errorf("failed to fetch calendar: %s", resp.Status)
+			}
+			source = resp.Body
+		default:
+			return nil, nil, validationError("either file_path or url is required")
+		}
+
+		events, err := importer.ParseICS(source, start, end)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse calendar: %w", err)
+		}
+		sort.Slice(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+
+		// Longest keywords first so "Acme Mobile" wins over "Acme"
+		keywords := make([]string, 0, len(args.KeywordMapping))
+		for keyword, contractNumber := range args.KeywordMapping {
+			var count int
+			db.QueryRowContext(ctx, "SELECT COUNT(*) FROM contracts WHERE contract_number = ?", contractNumber).Scan(&count)
+			if count == 0 {
+				return nil, nil, notFoundError("contract", "contract '%s' for keyword '%s' not found", contractNumber, keyword)
+			}
+			keywords = append(keywords, keyword)
+		}
+		sort.Slice(keywords, func(i, j int) bool { return len(keywords[i]) > len(keywords[j]) })
+
+		var records []importer.Record
+		for i, event := range events {
+			// Round to the nearest 15 minutes
+			hours := math.Round(event.Hours()*4) / 4
+			if hours == 0 {
+				continue
+			}
+
+			record := importer.Record{
+				Line:        i + 1,
+				Date:        event.Start,
+				Hours:       hours,
+				Project:     event.Summary,
+				Description: event.Summary,
+			}
+			haystack := strings.ToLower(event.Summary + " " + event.Description + " " + event.Location)
+			for _, keyword := range keywords {
+				if strings.Contains(haystack, strings.ToLower(keyword)) {
+					record.Contract = args.KeywordMapping[keyword]
+					break
+				}
+			}
+			records = append(records, record)
+		}
+
+		plan, err := h.planImport(ctx, records, nil, args.DefaultContract, false)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var proposed []proposedEntry
+		text := fmt.Sprintf("Found %d timed events between %s and %s\n",
+			len(events), start.Format("2006-01-02"), end.Format("2006-01-02"))
+		if len(plan.ready) > 0 {
+			text += "\nProposed entries:\n"
+		}
+		for i, record := range plan.ready {
+			entry := proposedEntry{
+				Date:           record.Date.Format("2006-01-02"),
+				Hours:          record.Hours,
+				ContractNumber: plan.contracts[i].number,
+				Description:    record.Description,
+			}
+			proposed = append(proposed, entry)
+			text += fmt.Sprintf("- %s: %.2f hours [%s] %s\n", entry.Date, entry.Hours, entry.ContractNumber, entry.Description)
+		}
+		text += "\n"
+
+		saved := 0
+		if args.Confirm && len(plan.ready) > 0 {
+			saved, err = plan.apply(ctx, db, "calendar event")
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		text += plan.summary(args.Confirm)
+		if !args.Confirm && len(plan.ready) > 0 {
+			text += "\nNothing saved yet. Run again with confirm=true to add these entries."
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &importCalendarResult{
+			Events:     len(events),
+			Proposed:   proposed,
+			Duplicates: len(plan.duplicates),
+			Unmatched:  plan.unmapped,
+			Saved:      saved,
+		}, nil
+	})
+	// Import Git Log tool
+	type importGitLogArgs struct {
+		RepoPaths          []string `json:"repo_paths,omitempty" jsonschema:"Local git repositories to read history from"`
+		FilePath           string   `json:"file_path,omitempty" jsonschema:"Saved 'git log' output or JSON array of commits (hash, author, email, date, message), used instead of repo_paths"`
+		ContractNumber     string   `json:"contract_number" jsonschema:"Contract to draft entries for"`
+		Author             string   `json:"author,omitempty" jsonschema:"Only include commits by this author name or email (optional)"`
+		Period             string   `json:"period,omitempty" jsonschema:"Period to reconstruct (e.g. 'last week' 'last month'; default: last week)"`
+		StartDate          string   `json:"start_date,omitempty" jsonschema:"Start date, overrides period (optional)"`
+		EndDate            string   `json:"end_date,omitempty" jsonschema:"End date, overrides period (optional)"`
+		SessionGapMinutes  int      `json:"session_gap_minutes,omitempty" jsonschema:"Commits further apart than this start a new work session (default: 120)"`
+		FirstCommitMinutes int      `json:"first_commit_minutes,omitempty" jsonschema:"Time credited before the first commit of each session (default: 60)"`
+		Confirm            bool     `json:"confirm,omitempty" jsonschema:"Save the drafted entries (default: false, only shows the draft)"`
+	}
+
+	type draftEntry struct {
+		Date        string  `json:"date"`
+		Hours       float64 `json:"hours"`
+		Commits     int     `json:"commits"`
+		Description string  `json:"description"`
+	}
+
+	type importGitLogResult struct {
+		Commits    int          `json:"commits" jsonschema:"Commits in the period"`
+		Drafts     []draftEntry `json:"drafts"`
+		Duplicates int          `json:"duplicates" jsonschema:"Days skipped as already logged"`
+		Saved      int          `json:"saved"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "import_git_log",
+		Description: "Reconstruct time entries from git commit history, one entry per day with estimated hours and summarized commit messages; run again with confirm=true to save them",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args importGitLogArgs) (*mcp.CallToolResult, *importGitLogResult, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		if args.SessionGapMinutes == 0 {
+			args.SessionGapMinutes = 120
+		}
+		if args.FirstCommitMinutes == 0 {
+			args.FirstCommitMinutes = 60
+		}
+
+		period := args.Period
+		if period == "" {
+			period = "last week"
+		}
+		start, end, err := h.parsePeriod(ctx, period)
+		if err != nil {
+			return nil, nil, validationError("invalid period: %w", err)
+		}
+		if args.StartDate != "" {
+			if start, err = h.parseDate(ctx, args.StartDate); err != nil {
+				return nil, nil, validationError("invalid start date: %w", err)
+			}
+		}
+		if args.EndDate != "" {
+			if end, err = h.parseDate(ctx, args.EndDate); err != nil {
+				return nil, nil, validationError("invalid end date: %w", err)
+			}
+		}
+		start = truncateDay(start)
+		end = truncateDay(end).AddDate(0, 0, 1).Add(-time.Second)
+
+		var commits []importer.Commit
+		switch {
+		case args.FilePath != "":
+			file, err := os.Open(expandHome(args.FilePath))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to open commit log: %w", err)
+			}
+			defer file.Close()
+
+			parsed, err := importer.ParseCommits(file)
+			if err != nil {
+				return nil, nil, err
+			}
+			author := strings.ToLower(args.Author)
+			for _, commit := range parsed {
+				if commit.Date.Before(start) || commit.Date.After(end) {
+					continue
+				}
+				if author != "" && !strings.Contains(strings.ToLower(commit.Author+" "+commit.Email), author) {
+					continue
+				}
+				commits = append(commits, commit)
+			}
+		case len(args.RepoPaths) > 0:
+			for _, repo := range args.RepoPaths {
+				repoCommits, err := importer.GitLog(expandHome(repo), start, end, args.Author)
+				if err != nil {
+					return nil, nil, err
+				}
+				commits = append(commits, repoCommits...)
+			}
+		default:
+			return nil, nil, validationError("either repo_paths or file_path is required")
+		}
+
+		days := importer.ClusterCommits(commits,
+			time.Duration(args.SessionGapMinutes)*time.Minute,
+			time.Duration(args.FirstCommitMinutes)*time.Minute)
+
+		var records []importer.Record
+		for i, day := range days {
+			// Round to the nearest 15 minutes
+			hours := math.Round(day.Hours*4) / 4
+			if hours == 0 {
+				hours = 0.25
+			}
+			records = append(records, importer.Record{
+				Line:        i + 1,
+				Date:        day.Date,
+				Hours:       hours,
+				Description: day.Summary(500),
+			})
+		}
+
+		plan, err := h.planImport(ctx, records, nil, args.ContractNumber, false)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		commitCounts := map[string]int{}
+		for _, day := range days {
+			commitCounts[day.Date.Format("2006-01-02")] = len(day.Commits)
+		}
+
+		var drafts []draftEntry
+		text := fmt.Sprintf("Found %d commits on %d days between %s and %s\n",
+			len(commits), len(days), start.Format("2006-01-02"), end.Format("2006-01-02"))
+		if len(plan.ready) > 0 {
+			text += "\nDrafted entries:\n"
+		}
+		for _, record := range plan.ready {
+			draft := draftEntry{
+				Date:        record.Date.Format("2006-01-02"),
+				Hours:       record.Hours,
+				Commits:     commitCounts[record.Date.Format("2006-01-02")],
+				Description: record.Description,
+			}
+			drafts = append(drafts, draft)
+			text += fmt.Sprintf("- %s: %.2f hours (%d commits) %s\n", draft.Date, draft.Hours, draft.Commits, draft.Description)
+		}
+		text += "\n"
+
+		saved := 0
+		if args.Confirm && len(plan.ready) > 0 {
+			saved, err = plan.apply(ctx, db, "git day")
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		text += plan.summary(args.Confirm)
+		if !args.Confirm && len(plan.ready) > 0 {
+			text += "\nNothing saved yet. Adjust hours if needed, then run again with confirm=true to add these entries."
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &importGitLogResult{Commits: len(commits), Drafts: drafts, Duplicates: len(plan.duplicates), Saved: saved}, nil
+	})
+}
@@ -0,0 +1,519 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// loadInternalCategory returns the internal category with a name
+func (h *Handler) loadInternalCategory(ctx context.Context, name string) (*models.InternalCategory, error) {
+	var c models.InternalCategory
+	err := h.db.QueryRowContext(ctx, `
+		SELECT id, name, description, created_at FROM internal_categories WHERE name = ?
+	`, strings.TrimSpace(name)).Scan(&c.ID, &c.Name, &c.Description, &c.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, notFoundError("internal_category", "internal category '%s' not found; see list_internal_categories", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find internal category: %w", err)
+	}
+	return &c, nil
+}
+
+// loadInternalEntry returns an internal time entry with its category name
+func (h *Handler) loadInternalEntry(ctx context.Context, id int) (*models.InternalEntry, error) {
+	var e models.InternalEntry
+	err := h.db.QueryRowContext(ctx, `
+		SELECT e.id, c.name, e.date, e.hours, e.description, e.created_at
+		FROM internal_entries e
+		JOIN internal_categories c ON c.id = e.category_id
+		WHERE e.id = ?
+	`, id).Scan(&e.ID, &e.Category, &e.Date, &e.Hours, &e.Description, &e.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, notFoundError("internal_entry", "internal time entry %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load internal time entry: %w", err)
+	}
+	return &e, nil
+}
+
+// registerInternalTools registers the tools logging time spent on the
+// business itself, such as admin, marketing and learning, and the
+// utilization report comparing it with client work
+func registerInternalTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Set Internal Category tool
+	type setInternalCategoryArgs struct {
+		Name        string `json:"name" jsonschema:"Category name, e.g. 'Admin', 'Marketing' or 'Learning'"`
+		Description string `json:"description,omitempty" jsonschema:"What the category covers (optional)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_internal_category",
+		Description: "Add or update a category of internal, non-client time such as admin, marketing or learning",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setInternalCategoryArgs) (*mcp.CallToolResult, *models.InternalCategory, error) {
+		name := strings.TrimSpace(args.Name)
+		if name == "" {
+			return nil, nil, validationError("name is required")
+		}
+
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO internal_categories (name, description) VALUES (?, ?)
+			ON CONFLICT(name) DO UPDATE SET description = excluded.description
+		`, name, strings.TrimSpace(args.Description))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to save internal category: %w", err)
+		}
+		c, err := h.loadInternalCategory(ctx, name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Internal category '%s' saved", c.Name)},
+			},
+		}, c, nil
+	})
+
+	// List Internal Categories tool
+	type listInternalCategoriesArgs struct{}
+
+	type internalCategoryListing struct {
+		models.InternalCategory
+		Hours float64 `json:"hours" jsonschema:"Hours logged for the category"`
+	}
+
+	type listInternalCategoriesResult struct {
+		Categories []internalCategoryListing `json:"categories"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_internal_categories",
+		Description: "List the categories internal time can be logged for, with the hours logged for each",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listInternalCategoriesArgs) (*mcp.CallToolResult, *listInternalCategoriesResult, error) {
+		rows, err := db.QueryContext(ctx, `
+			SELECT c.id, c.name, c.description, c.created_at, COALESCE(SUM(e.hours), 0)
+			FROM internal_categories c
+			LEFT JOIN internal_entries e ON e.category_id = c.id
+			GROUP BY c.id
+			ORDER BY c.name
+		`)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list internal categories: %w", err)
+		}
+		defer rows.Close()
+
+		result := &listInternalCategoriesResult{Categories: []internalCategoryListing{}}
+		for rows.Next() {
+			var c internalCategoryListing
+			if err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.CreatedAt, &c.Hours); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan internal category: %w", err)
+			}
+			result.Categories = append(result.Categories, c)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		if len(result.Categories) == 0 {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: "No internal categories. Use set_internal_category to add one, e.g. Admin."},
+				},
+			}, result, nil
+		}
+
+		text := "Internal categories:\n"
+		for _, c := range result.Categories {
+			text += fmt.Sprintf("- %s: %.2f hours", c.Name, c.Hours)
+			if c.Description != "" {
+				text += " - " + c.Description
+			}
+			text += "\n"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+
+	// Delete Internal Category tool
+	type deleteInternalCategoryArgs struct {
+		Name string `json:"name" jsonschema:"Name of the internal category to delete"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "delete_internal_category",
+		Description: "Delete an internal category; one with time logged for it can't be deleted",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args deleteInternalCategoryArgs) (*mcp.CallToolResult, *models.InternalCategory, error) {
+		c, err := h.loadInternalCategory(ctx, args.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		var entries int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM internal_entries WHERE category_id = ?", c.ID).Scan(&entries); err != nil {
+			return nil, nil, fmt.Errorf("failed to check internal time: %w", err)
+		}
+		if entries > 0 {
+			return nil, nil, conflictError("cannot delete internal category '%s'; %d entries were logged for it", c.Name, entries)
+		}
+		if _, err := db.ExecContext(ctx, "DELETE FROM internal_categories WHERE id = ?", c.ID); err != nil {
+			return nil, nil, fmt.Errorf("failed to delete internal category: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Deleted internal category '%s'", c.Name)},
+			},
+		}, c, nil
+	})
+
+	// Add Internal Time tool
+	type addInternalTimeArgs struct {
+		Category    string   `json:"category" jsonschema:"Internal category, e.g. Admin, Marketing or Learning (see list_internal_categories)"`
+		Hours       hoursArg `json:"hours" jsonschema:"Hours spent, as decimal hours (0.25, 0.5, 1.25) or a duration ('1h30m' '90 minutes' '2:15')"`
+		Date        string   `json:"date,omitempty" jsonschema:"Date (YYYY-MM-DD or natural language like 'today' 'yesterday'; default: today)"`
+		Description string   `json:"description,omitempty" jsonschema:"What the time was spent on"`
+	}
+
+	type addInternalTimeResult struct {
+		Entry   *models.InternalEntry `json:"entry"`
+		Warning string                `json:"warning,omitempty" jsonschema:"Why the date looks mistaken; see the entry_date_check setting"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "add_internal_time",
+		Description: "Log time spent on the business itself, e.g. admin, marketing or learning. Internal time belongs to no client, is never invoiced and counts towards utilization_report",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args addInternalTimeArgs) (*mcp.CallToolResult, *addInternalTimeResult, error) {
+		if args.Hours <= 0 || args.Hours > 24 {
+			return nil, nil, validationError("hours must be more than 0 and at most 24")
+		}
+		c, err := h.loadInternalCategory(ctx, args.Category)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		today := h.today(ctx)
+		date := today
+		if args.Date != "" {
+			if date, err = h.parseDate(ctx, args.Date); err != nil {
+				return nil, nil, validationError("invalid date: %w", err)
+			}
+		}
+		warning, err := h.checkEntryDate(ctx, date, today)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		result, err := db.ExecContext(ctx, `
+			INSERT INTO internal_entries (category_id, date, hours, description) VALUES (?, ?, ?, ?)
+		`, c.ID, date.Format("2006-01-02"), float64(args.Hours), strings.TrimSpace(args.Description))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to add internal time: %w", err)
+		}
+		id, _ := result.LastInsertId()
+		entry, err := h.loadInternalEntry(ctx, int(id))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		text := fmt.Sprintf("Added %.2f internal hours for %s on %s", entry.Hours, entry.Category, date.Format("2006-01-02"))
+		if entry.Description != "" {
+			text += " - " + entry.Description
+		}
+		text += fmt.Sprintf(" (ID: %d)", entry.ID)
+		if warning != "" {
+			text += "\nWarning: " + warning
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &addInternalTimeResult{Entry: entry, Warning: warning}, nil
+	})
+
+	// List Internal Time tool
+	type listInternalTimeArgs struct {
+		Category  string `json:"category,omitempty" jsonschema:"Only list one internal category (optional)"`
+		StartDate string `json:"start_date,omitempty" jsonschema:"Start date (YYYY-MM-DD or natural language)"`
+		EndDate   string `json:"end_date,omitempty" jsonschema:"End date (YYYY-MM-DD or natural language)"`
+		Format    string `json:"format,omitempty" jsonschema:"Output format: text (default) or markdown"`
+	}
+
+	type listInternalTimeResult struct {
+		Entries    []models.InternalEntry `json:"entries"`
+		TotalHours float64                `json:"total_hours"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_internal_time",
+		Description: "List internal time entries, newest first, optionally for one category or date range",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listInternalTimeArgs) (*mcp.CallToolResult, *listInternalTimeResult, error) {
+		markdown, err := parseOutputFormat(args.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		query := `
+			SELECT e.id, c.name, e.date, e.hours, e.description, e.created_at
+			FROM internal_entries e
+			JOIN internal_categories c ON c.id = e.category_id
+			WHERE 1=1
+		`
+		queryArgs := []interface{}{}
+		if args.Category != "" {
+			c, err := h.loadInternalCategory(ctx, args.Category)
+			if err != nil {
+				return nil, nil, err
+			}
+			query += " AND e.category_id = ?"
+			queryArgs = append(queryArgs, c.ID)
+		}
+		startDate, err := h.parseDateFilter(ctx, args.StartDate, "start")
+		if err != nil {
+			return nil, nil, err
+		}
+		if startDate != nil {
+			query += " AND e.date >= ?"
+			queryArgs = append(queryArgs, startDate.Format("2006-01-02"))
+		}
+		endDate, err := h.parseDateFilter(ctx, args.EndDate, "end")
+		if err != nil {
+			return nil, nil, err
+		}
+		if endDate != nil {
+			query += " AND e.date <= ?"
+			queryArgs = append(queryArgs, endDate.Format("2006-01-02"))
+		}
+		query += " ORDER BY e.date DESC, e.id DESC"
+
+		rows, err := db.QueryContext(ctx, query, queryArgs...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list internal time: %w", err)
+		}
+		defer rows.Close()
+
+		result := &listInternalTimeResult{Entries: []models.InternalEntry{}}
+		for rows.Next() {
+			var e models.InternalEntry
+			if err := rows.Scan(&e.ID, &e.Category, &e.Date, &e.Hours, &e.Description, &e.CreatedAt); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan internal time entry: %w", err)
+			}
+			result.Entries = append(result.Entries, e)
+			result.TotalHours += e.Hours
+		}
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		var text string
+		if markdown {
+			tableRows := make([][]string, 0, len(result.Entries)+1)
+			for _, e := range result.Entries {
+				tableRows = append(tableRows, []string{fmt.Sprintf("%d", e.ID), e.Date.Format("2006-01-02"), e.Category,
+					fmt.Sprintf("%.2f", e.Hours), e.Description})
+			}
+			tableRows = append(tableRows, []string{"", "", "**Total**", fmt.Sprintf("**%.2f**", result.TotalHours), ""})
+			text = fmt.Sprintf("**%d internal entries, %.2f total hours**\n\n", len(result.Entries), result.TotalHours) +
+				markdownTable([]string{"ID", "Date", "Category", "Hours", "Description"}, tableRows, 3)
+		} else {
+			text = fmt.Sprintf("Found %d internal entries (%.2f total hours):\n", len(result.Entries), result.TotalHours)
+			for _, e := range result.Entries {
+				text += fmt.Sprintf("- ID %d: %s: %s - %.2f hours", e.ID, e.Date.Format("2006-01-02"), e.Category, e.Hours)
+				if e.Description != "" {
+					text += fmt.Sprintf(" (%s)", e.Description)
+				}
+				text += "\n"
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+
+	// Delete Internal Time tool
+	type deleteInternalTimeArgs struct {
+		EntryID int `json:"entry_id" jsonschema:"ID of the internal time entry to delete"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "delete_internal_time",
+		Description: "Delete an internal time entry by ID",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args deleteInternalTimeArgs) (*mcp.CallToolResult, *models.InternalEntry, error) {
+		entry, err := h.loadInternalEntry(ctx, args.EntryID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := db.ExecContext(ctx, "DELETE FROM internal_entries WHERE id = ?", entry.ID); err != nil {
+			return nil, nil, fmt.Errorf("failed to delete internal time entry: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Deleted internal time entry %d: %.2f hours of %s on %s", entry.ID, entry.Hours, entry.Category, entry.Date.Format("2006-01-02"))},
+			},
+		}, entry, nil
+	})
+
+	// Utilization Report tool
+	type utilizationReportArgs struct {
+		Period string `json:"period,omitempty" jsonschema:"Period (e.g. 'this month' 'last quarter' 'this year'; default: this month)"`
+		Format string `json:"format,omitempty" jsonschema:"Output format: text (default) or markdown"`
+	}
+
+	type utilizationRow struct {
+		Name     string  `json:"name" jsonschema:"Client or internal category"`
+		Internal bool    `json:"internal,omitempty"`
+		Hours    float64 `json:"hours"`
+		// Billable is the part of a client's hours that can be invoiced
+		Billable float64 `json:"billable_hours,omitempty"`
+	}
+
+	type utilizationReportResult struct {
+		StartDate     string           `json:"start_date"`
+		EndDate       string           `json:"end_date"`
+		TotalHours    float64          `json:"total_hours"`
+		BillableHours float64          `json:"billable_hours" jsonschema:"Client hours that can be invoiced"`
+		ClientHours   float64          `json:"client_hours" jsonschema:"All client hours, including non-billable and no-charge ones"`
+		InternalHours float64          `json:"internal_hours"`
+		Utilization   float64          `json:"utilization" jsonschema:"Billable hours as a percentage of all hours"`
+		Rows          []utilizationRow `json:"rows"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "utilization_report",
+		Description: "Show how time in a period splits between billable client work, non-billable client work and internal categories such as admin, marketing and learning, with utilization as the billable share of all hours",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args utilizationReportArgs) (*mcp.CallToolResult, *utilizationReportResult, error) {
+		markdown, err := parseOutputFormat(args.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+		if args.Period == "" {
+			args.Period = "this month"
+		}
+		startDate, endDate, err := h.parsePeriod(ctx, args.Period)
+		if err != nil {
+			return nil, nil, validationError("invalid period: %w", err)
+		}
+		start, end := startDate.Format("2006-01-02"), endDate.Format("2006-01-02")
+
+		result := &utilizationReportResult{StartDate: start, EndDate: end, Rows: []utilizationRow{}}
+
+		// Non-billable and no-charge hours are client work that is never
+		// paid for
+		rows, err := db.QueryContext(ctx, `
+			SELECT cl.name, SUM(te.hours), SUM(CASE WHEN te.billable AND NOT te.no_charge THEN te.hours ELSE 0 END)
+			FROM time_entries te
+			JOIN contracts ct ON te.contract_id = ct.id
+			JOIN clients cl ON ct.client_id = cl.id
+			WHERE te.date >= ? AND te.date <= ?
+			GROUP BY cl.id
+			ORDER BY cl.name
+		`, start, end)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load client hours: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var r utilizationRow
+			if err := rows.Scan(&r.Name, &r.Hours, &r.Billable); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan client hours: %w", err)
+			}
+			result.Rows = append(result.Rows, r)
+			result.ClientHours += r.Hours
+			result.BillableHours += r.Billable
+		}
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		internalRows, err := db.QueryContext(ctx, `
+			SELECT c.name, SUM(e.hours)
+			FROM internal_entries e
+			JOIN internal_categories c ON c.id = e.category_id
+			WHERE e.date >= ? AND e.date <= ?
+			GROUP BY c.id
+			ORDER BY c.name
+		`, start, end)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load internal hours: %w", err)
+		}
+		defer internalRows.Close()
+		for internalRows.Next() {
+			r := utilizationRow{Internal: true}
+			if err := internalRows.Scan(&r.Name, &r.Hours); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan internal hours: %w", err)
+			}
+			result.Rows = append(result.Rows, r)
+			result.InternalHours += r.Hours
+		}
+		if err := internalRows.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		result.TotalHours = result.ClientHours + result.InternalHours
+		if result.TotalHours > 0 {
+			result.Utilization = result.BillableHours / result.TotalHours * 100
+		}
+
+		label := fmt.Sprintf("%s to %s", start, end)
+		if result.TotalHours == 0 {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("No time logged from %s.\n", label)},
+				},
+			}, result, nil
+		}
+
+		share := func(hours float64) string {
+			return fmt.Sprintf("%.1f%%", hours/result.TotalHours*100)
+		}
+		var text string
+		if markdown {
+			tableRows := make([][]string, 0, len(result.Rows)+1)
+			for _, r := range result.Rows {
+				kind, billable := "Client", fmt.Sprintf("%.2f", r.Billable)
+				if r.Internal {
+					kind, billable = "Internal", ""
+				}
+				tableRows = append(tableRows, []string{r.Name, kind, fmt.Sprintf("%.2f", r.Hours), billable, share(r.Hours)})
+			}
+			tableRows = append(tableRows, []string{"**Total**", "", fmt.Sprintf("**%.2f**", result.TotalHours),
+				fmt.Sprintf("**%.2f**", result.BillableHours), "**100%**"})
+			text = fmt.Sprintf("### Utilization %s: %.1f%%\n\n", label, result.Utilization) +
+				markdownTable([]string{"Client / category", "Kind", "Hours", "Billable", "Share"}, tableRows, 2, 3, 4)
+		} else {
+			text = fmt.Sprintf("Utilization from %s: %.1f%% (%.2f billable of %.2f hours)\n", label, result.Utilization, result.BillableHours, result.TotalHours)
+			text += fmt.Sprintf("Client work: %.2f hours (%s), of which %.2f non-billable\n", result.ClientHours, share(result.ClientHours), result.ClientHours-result.BillableHours)
+			for _, r := range result.Rows {
+				if !r.Internal {
+					text += fmt.Sprintf("- %s: %.2f hours, %.2f billable\n", r.Name, r.Hours, r.Billable)
+				}
+			}
+			text += fmt.Sprintf("Internal: %.2f hours (%s)\n", result.InternalHours, share(result.InternalHours))
+			for _, r := range result.Rows {
+				if r.Internal {
+					text += fmt.Sprintf("- %s: %.2f hours (%s)\n", r.Name, r.Hours, share(r.Hours))
+				}
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+}
@@ -0,0 +1,1362 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/austin/hours-mcp/internal/pdf"
+	"github.com/austin/hours-mcp/internal/store"
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// registerInvoiceTools registers tools to create, list and show invoices
+// and to track their status
+func registerInvoiceTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Create Invoice tool
+	type createInvoiceArgs struct {
+		ClientName string   `json:"client_name" jsonschema:"Client name"`
+		Period     string   `json:"period,omitempty" jsonschema:"Period (e.g. 'this month' 'last month' 'January 2025' 'last quarter' 'Q1 2025' 'June 1-15' 'last 30 days')"`
+		StartDate  string   `json:"start_date,omitempty" jsonschema:"First day to bill, overrides the start of period (required without period)"`
+		EndDate    string   `json:"end_date,omitempty" jsonschema:"Last day to bill, overrides the end of period (required without period)"`
+		DueDays    int      `json:"due_days,omitempty" jsonschema:"Days until due (default: 30)"`
+		Currency   string   `json:"currency,omitempty" jsonschema:"Only bill contracts in this currency; required when the client's unbilled hours span several currencies"`
+		TaxRate    *float64 `json:"tax_rate,omitempty" jsonschema:"Tax rate in percent to add, e.g. 20 for VAT or 0 for zero-rated (default: tax_rate setting)"`
+
+		TotalRounding *float64 `json:"total_rounding,omitempty" jsonschema:"Round the amount due to a multiple of this, e.g. 0.05 or 1, adding a rounding line (default: total_rounding setting)"`
+
+		ExpenseMarkup *float64 `json:"expense_markup,omitempty" jsonschema:"Markup in percent added to billable expenses (default: expense_markup setting)"`
+
+		RecipientIDs []int    `json:"recipient_ids,omitempty" jsonschema:"IDs of the client's recipients to address the invoice to (default: all; see list_recipients)"`
+		Cc           []string `json:"cc,omitempty" jsonschema:"Addresses to copy whenever the invoice is emailed (optional)"`
+
+		MaskAccountNumber *bool  `json:"mask_account_number,omitempty" jsonschema:"Print only the last 4 digits of the account number on the PDF (default: mask_account_numbers setting)"`
+		AttachReceipts    *bool  `json:"attach_receipts,omitempty" jsonschema:"Append the receipts of rebilled expenses to the PDF (default: attach_receipts setting)"`
+		Archival          *bool  `json:"archival,omitempty" jsonschema:"Write the PDF as PDF/A-2b for long-term archival, with fonts embedded (default: the client's invoice_format, else pdf_archival setting)"`
+		JSONSidecar       *bool  `json:"json_sidecar,omitempty" jsonschema:"Also write the invoice data as JSON next to the PDF, for automation (default: the client's invoice_format, else invoice_json_sidecar setting)"`
+		Detail            string `json:"detail,omitempty" jsonschema:"detailed lists every time entry, consolidated one line per contract and rate (default: the client's invoice_detail, else detailed)"`
+		PaymentMethod     string `json:"payment_method,omitempty" jsonschema:"Payment method to ask to be paid into (default: the contracts', the client's, one in the invoice currency or the default; see list_payment_methods)"`
+
+		GroupBy string `json:"group_by,omitempty" jsonschema:"How to split the work into invoices: combined, per_contract or per_project, where a project is the contracts sharing a name such as a contract and its renewals (default: combined)"`
+	}
+
+	type createdInvoice struct {
+		InvoiceNumber   string      `json:"invoice_number"`
+		Group           string      `json:"group,omitempty" jsonschema:"Contract or project the invoice bills, when split by group_by"`
+		DueDate         time.Time   `json:"due_date"`
+		Subtotal        money.Cents `json:"subtotal"`
+		TaxRate         float64     `json:"tax_rate"`
+		TaxAmount       money.Cents `json:"tax_amount"`
+		Withholding     money.Cents `json:"withholding" jsonschema:"Amount the client withholds when paying"`
+		Rounding        money.Cents `json:"rounding,omitempty" jsonschema:"Adjustment rounding the amount due, included in the total"`
+		TotalAmount     money.Cents `json:"total_amount"`
+		Currency        string      `json:"currency"`
+		TotalHours      float64     `json:"total_hours"`
+		PDFPath         string      `json:"pdf_path"`
+		JSONPath        string      `json:"json_path,omitempty" jsonschema:"JSON with the invoice data, written next to the PDF"`
+		Receipts        int         `json:"receipts,omitempty" jsonschema:"Number of receipts appended to the PDF"`
+		SkippedReceipts []string    `json:"skipped_receipts,omitempty" jsonschema:"Receipts not appended because they are missing, not a JPEG, PNG or PDF, or a PDF when writing PDF/A"`
+		PaymentMethod   string      `json:"payment_method,omitempty" jsonschema:"Payment method the invoice asks to be paid into, empty for the client's payment details"`
+	}
+
+	// The fields of the first invoice are kept at the top level for callers
+	// creating a single one
+	type createInvoiceResult struct {
+		createdInvoice
+		Invoices []createdInvoice `json:"invoices" jsonschema:"Every invoice created, with its number and PDF path"`
+
+		DeliveryMethod string `json:"delivery_method,omitempty" jsonschema:"How the client wants its invoices delivered, when set with edit_client"`
+		DeliveryNote   string `json:"delivery_note,omitempty"`
+	}
+
+	// invoicePlan is the unbilled work of a client a create_invoice call
+	// bills, checked and priced but not saved yet
+	type invoicePlan struct {
+		args           createInvoiceArgs
+		groupBy        string
+		clientID       int
+		client         *models.Client
+		business       *models.BusinessInfo
+		cc             []string
+		recipients     []models.Recipient
+		bills          []*invoiceBill
+		maskAccount    bool
+		attachReceipts bool
+		// archivalFonts are embedded in PDF/A invoices, nil for plain PDFs
+		archivalFonts *pdf.Fonts
+		jsonSidecar   bool
+		consolidated  bool
+	}
+
+	// prepareInvoices checks and prices the work a create_invoice call asks
+	// to bill, splitting it into invoices without saving anything
+	prepareInvoices := func(ctx context.Context, args createInvoiceArgs) (*invoicePlan, error) {
+		if args.DueDays == 0 {
+			args.DueDays = 30
+		}
+		groupBy := strings.ToLower(strings.TrimSpace(args.GroupBy))
+		if groupBy == "" {
+			groupBy = invoiceGroupCombined
+		}
+		if !slices.Contains(invoiceGroupings, groupBy) {
+			return nil, validationError("invalid group_by '%s': must be %s", args.GroupBy, strings.Join(invoiceGroupings, ", "))
+		}
+		detail, err := normalizeInvoiceDetail(args.Detail)
+		if err != nil {
+			return nil, err
+		}
+		cc, err := normalizeCcAddresses(args.Cc)
+		if err != nil {
+			return nil, err
+		}
+
+		taxRate, _ := strconv.ParseFloat(h.getSetting(ctx, "tax_rate"), 64)
+		if args.TaxRate != nil {
+			taxRate = *args.TaxRate
+		}
+		if taxRate < 0 || taxRate > 100 {
+			return nil, validationError("tax_rate must be a percentage between 0 and 100")
+		}
+
+		rounding, _ := money.Parse(h.getSetting(ctx, "total_rounding"))
+		if args.TotalRounding != nil {
+			if *args.TotalRounding < 0 {
+				return nil, validationError("total_rounding must not be negative")
+			}
+			rounding = money.FromFloat(*args.TotalRounding)
+		}
+
+		expenseMarkup, _ := strconv.ParseFloat(h.getSetting(ctx, "expense_markup"), 64)
+		if args.ExpenseMarkup != nil {
+			expenseMarkup = *args.ExpenseMarkup
+		}
+		if expenseMarkup < 0 || expenseMarkup > 100 {
+			return nil, validationError("expense_markup must be a percentage between 0 and 100")
+		}
+
+		clientID, err := h.getClientIDByName(ctx, args.ClientName)
+		if err != nil {
+			return nil, fmt.Errorf("client not found: %w", err)
+		}
+
+		// Validate business information is configured
+		business, err := h.loadBusinessInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if business == nil {
+			return nil, notConfiguredError("business_info", "business information not configured. Please use 'set_business_info' to configure your business details before creating invoices")
+		}
+
+		var startDate, endDate time.Time
+		if args.Period != "" {
+			if startDate, endDate, err = h.parsePeriod(ctx, args.Period); err != nil {
+				return nil, validationError("invalid period: %w", err)
+			}
+		} else if args.StartDate == "" || args.EndDate == "" {
+			return nil, validationError("give a period, or both start_date and end_date")
+		}
+		if args.StartDate != "" {
+			if startDate, err = h.parseDate(ctx, args.StartDate); err != nil {
+				return nil, validationError("invalid start date: %w", err)
+			}
+		}
+		if args.EndDate != "" {
+			if endDate, err = h.parseDate(ctx, args.EndDate); err != nil {
+				return nil, validationError("invalid end date: %w", err)
+			}
+		}
+		if endDate.Before(startDate) {
+			return nil, validationError("end date must not be before start date")
+		}
+		period := args.Period
+		if args.StartDate != "" || args.EndDate != "" {
+			period = fmt.Sprintf("%s to %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+		}
+
+		// The full record carries the client's invoice preferences
+		client, err := h.loadClient(ctx, clientID)
+		if err != nil {
+			return nil, err
+		}
+
+		unbilled, err := h.store.Entries.Unbilled(ctx, clientID, startDate, endDate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get time entries: %w", err)
+		}
+
+		// Each item is rounded to the cent, as it is printed on the invoice.
+		// Retainer fees for the period come first.
+		items, err := h.retainerFeeItems(ctx, clientID, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		priced, err := h.priceEntries(ctx, unbilled)
+		if err != nil {
+			return nil, fmt.Errorf("failed to price time entries: %w", err)
+		}
+		items = append(items, priced...)
+
+		// Billable expenses follow the hours in a section of their own
+		expenses, err := h.expenseItems(ctx, clientID, startDate, endDate, expenseMarkup)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, expenses...)
+
+		currencies, err := h.store.Contracts.Currencies(ctx, clientID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load contracts: %w", err)
+		}
+		groups, err := h.groupInvoiceWork(ctx, clientID, groupBy, items, unbilled)
+		if err != nil {
+			return nil, err
+		}
+
+		// Every invoice bills work in a single currency, and everything is
+		// checked before the first one is saved
+		requestedCurrency := strings.ToUpper(strings.TrimSpace(args.Currency))
+		allSubtotals := map[string]money.Cents{}
+		var bills []*invoiceBill
+		for _, g := range groups {
+			bill := &invoiceBill{group: g.name, currency: requestedCurrency}
+			subtotals := map[string]money.Cents{}
+			for _, item := range g.items {
+				currency := currencies[item.ContractID]
+				if item.Currency != "" {
+					currency = item.Currency
+				}
+				subtotals[currency] += item.Amount
+				allSubtotals[currency] += item.Amount
+				if requestedCurrency != "" && currency != requestedCurrency {
+					continue
+				}
+				bill.items = append(bill.items, item)
+				bill.subtotal += item.Amount
+			}
+			for _, e := range g.entries {
+				if requestedCurrency != "" && e.Contract.Currency != requestedCurrency {
+					continue
+				}
+				bill.entries = append(bill.entries, e)
+				bill.hours += e.Hours
+			}
+
+			if requestedCurrency == "" {
+				if len(subtotals) > 1 {
+					work := args.ClientName
+					if g.name != "" {
+						work += " " + g.name
+					}
+					return nil, validationError("unbilled work for %s in %s spans several currencies (%s). Create one invoice per currency using the 'currency' argument",
+						work, period, formatCurrencyTotals(subtotals))
+				}
+				for currency := range subtotals {
+					bill.currency = currency
+				}
+			}
+			if len(bill.items) > 0 {
+				bills = append(bills, bill)
+			}
+		}
+
+		if len(bills) == 0 {
+			if requestedCurrency != "" && len(allSubtotals) > 0 {
+				return nil, notFoundError("unbilled_work", "no unbilled %s hours or expenses found for %s in %s (unbilled: %s)",
+					requestedCurrency, args.ClientName, period, formatCurrencyTotals(allSubtotals))
+			}
+			return nil, notFoundError("unbilled_work", "no unbilled hours or expenses found for %s in %s", args.ClientName, period)
+		}
+
+		if client.TaxTreatment == taxTreatmentReverseCharge && args.TaxRate != nil && *args.TaxRate > 0 {
+			return nil, conflictError("%s is a reverse-charge client; tax cannot be added to its invoices", client.Name)
+		}
+
+		// The account to pay into depends on the contracts and currency billed
+		for _, bill := range bills {
+			var contractIDs []int
+			for _, item := range bill.items {
+				if item.ContractID != 0 && !slices.Contains(contractIDs, item.ContractID) {
+					contractIDs = append(contractIDs, item.ContractID)
+				}
+			}
+			if bill.payment, err = h.invoicePaymentDetails(ctx, clientID, contractIDs, bill.currency, args.PaymentMethod); err != nil {
+				return nil, err
+			}
+			if bill.payment == nil {
+				return nil, notConfiguredError("payment_details", "no payment details for client '%s'. Please use 'set_payment_method' or 'set_payment_details' to configure payment information before creating invoices", args.ClientName)
+			}
+
+			// Tax is added on top of the hours billed; withholding is
+			// deducted by the client when paying
+			bill.tax = h.computeInvoiceTax(ctx, bill.subtotal, taxRate, client.TaxTreatment, client.WithholdingRate, client.TaxID, bill.currency)
+
+			// The amount due is rounded, and the difference shown as a line
+			// of its own
+			due := bill.subtotal + bill.tax.taxAmount - bill.tax.withholdingAmount
+			bill.rounding = due.RoundTo(rounding) - due
+		}
+
+		maskAccount := h.getBoolSetting(ctx, "mask_account_numbers")
+		if args.MaskAccountNumber != nil {
+			maskAccount = *args.MaskAccountNumber
+		}
+		attachReceipts := h.getBoolSetting(ctx, "attach_receipts")
+		if args.AttachReceipts != nil {
+			attachReceipts = *args.AttachReceipts
+		}
+		// A client's preferred format overrides the settings, and the
+		// arguments override both
+		archival := h.getBoolSetting(ctx, "pdf_archival")
+		jsonSidecar := h.getBoolSetting(ctx, "invoice_json_sidecar")
+		if format, ok := invoiceFormats[client.InvoiceFormat]; ok {
+			archival, jsonSidecar = format.archival, format.json
+		}
+		if args.Archival != nil {
+			archival = *args.Archival
+		}
+		var archivalFonts *pdf.Fonts
+		if archival {
+			fonts, err := pdf.FindFonts(expandHome(h.getSetting(ctx, "pdf_font_path")))
+			if err != nil {
+				return nil, notConfiguredError("pdf_font_path", "PDF/A invoices need a font to embed: %v. Set pdf_font_path to a TrueType (.ttf) font with set_setting", err)
+			}
+			archivalFonts = &fonts
+		}
+		if args.JSONSidecar != nil {
+			jsonSidecar = *args.JSONSidecar
+		}
+		if detail == "" {
+			detail = client.InvoiceDetail
+		}
+
+		// Only the chosen recipients are printed on the PDF
+		recipients, err := h.selectRecipients(ctx, clientID, args.RecipientIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		return &invoicePlan{
+			args:           args,
+			groupBy:        groupBy,
+			clientID:       clientID,
+			client:         client,
+			business:       business,
+			cc:             cc,
+			recipients:     recipients,
+			bills:          bills,
+			maskAccount:    maskAccount,
+			attachReceipts: attachReceipts,
+			archivalFonts:  archivalFonts,
+			jsonSidecar:    jsonSidecar,
+			consolidated:   detail == invoiceDetailConsolidated,
+		}, nil
+	}
+
+	// issueInvoices saves the invoices of a plan in one transaction and
+	// writes their PDFs
+	issueInvoices := func(ctx context.Context, plan *invoicePlan) (string, *createInvoiceResult, error) {
+		args, clientID, client, bills := plan.args, plan.clientID, plan.client, plan.bills
+
+		issueDate := h.today(ctx)
+		dueDate := issueDate.AddDate(0, 0, args.DueDays)
+		homeDir, _ := os.UserHomeDir()
+		downloadsPath := filepath.Join(homeDir, "Downloads")
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+		txStore := h.store.WithTx(tx)
+
+		// The files are written before the commit so their paths are saved
+		// with the invoice; if it doesn't commit they are removed again
+		var written []string
+		committed := false
+		defer func() {
+			if committed {
+				return
+			}
+			for _, path := range written {
+				os.Remove(path)
+			}
+		}()
+
+		var contracts map[int]sidecarContract
+		if plan.jsonSidecar {
+			if contracts, err = sidecarContracts(ctx, tx, clientID); err != nil {
+				return "", nil, err
+			}
+		}
+
+		result := &createInvoiceResult{}
+		var text string
+		for _, bill := range bills {
+			invoiceCurrency, paymentDetails, tax := bill.currency, bill.payment, bill.tax
+			subtotal, taxRate, taxAmount := bill.subtotal, tax.taxRate, tax.taxAmount
+			totalAmount := subtotal + taxAmount + bill.rounding
+
+			// Numbers are random rather than counted, so concurrent sessions
+			// don't race for the next one; the UNIQUE constraint catches a
+			// clash
+			invoiceNumber := fmt.Sprintf("INV-%s-%s", issueDate.Format("200601"), uuid.New().String()[:8])
+
+			invoice := models.Invoice{
+				ClientID:          clientID,
+				InvoiceNumber:     invoiceNumber,
+				IssueDate:         issueDate,
+				DueDate:           dueDate,
+				TotalAmount:       totalAmount,
+				TaxRate:           taxRate,
+				TaxAmount:         taxAmount,
+				TaxTreatment:      tax.treatment,
+				TaxNote:           tax.note,
+				WithholdingRate:   tax.withholdingRate,
+				WithholdingAmount: tax.withholdingAmount,
+				Rounding:          bill.rounding,
+				Currency:          invoiceCurrency,
+				Status:            "pending",
+				Client:            client,
+				TimeEntries:       bill.entries,
+				Items:             bill.items,
+			}
+			invoiceID, err := txStore.Invoices.Create(ctx, &invoice, strings.Join(plan.cc, ", "))
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to create invoice: %w", err)
+			}
+			invoice.ID = invoiceID
+
+			if plan.maskAccount {
+				paymentDetails.AccountNumber = maskNumber(paymentDetails.AccountNumber)
+			}
+			for _, r := range plan.recipients {
+				if len(args.RecipientIDs) == 0 {
+					break
+				}
+				if err := txStore.Invoices.AddRecipient(ctx, invoiceID, r.ID); err != nil {
+					return "", nil, fmt.Errorf("failed to save invoice recipient: %w", err)
+				}
+			}
+
+			// The number keeps invoices issued on the same day from
+			// overwriting each other's files
+			pdfPath := filepath.Join(downloadsPath, fmt.Sprintf("invoice_%s_%s.pdf", issueDate.Format("2006-01-02"), invoiceNumber))
+
+			// Link time entries and expenses to the invoice
+			entryIDs := make([]string, len(bill.entries))
+			for i, entry := range bill.entries {
+				entryIDs[i] = entry.ID
+			}
+			// The work was priced before the transaction began, so another
+			// session may have billed some of it since. Linking only
+			// unbilled rows inside the write transaction leaves it on one
+			// invoice.
+			if err := txStore.Entries.SetInvoiceAll(ctx, entryIDs, invoiceID); err != nil {
+				return "", nil, invoiceLinkError("time entries", args.ClientName, err)
+			}
+			var expenseIDs []int
+			for _, item := range bill.items {
+				if item.ExpenseID != 0 {
+					expenseIDs = append(expenseIDs, item.ExpenseID)
+				}
+				if item.RateKind == rateKindRetainerFee {
+					billed, err := txStore.Invoices.FeeBilled(ctx, invoiceID, item.ContractID, rateKindRetainerFee, item.Date.Format("2006-01"))
+					if err != nil {
+						return "", nil, fmt.Errorf("failed to check retainer fees: %w", err)
+					}
+					if billed {
+						return "", nil, invoiceLinkError("retainer fees", args.ClientName, store.ErrAlreadyInvoiced)
+					}
+				}
+			}
+			if err := txStore.Invoices.LinkExpenses(ctx, invoiceID, expenseIDs); err != nil {
+				return "", nil, invoiceLinkError("expenses", args.ClientName, err)
+			}
+
+			// Keep the priced lines so exports match the invoice even if
+			// rates or rules change later
+			var lines []store.InvoiceLine
+			for _, line := range groupInvoiceItems(bill.items) {
+				lines = append(lines, store.InvoiceLine{
+					ContractID: line.contractID,
+					RateKind:   line.rateKind,
+					RateLabel:  line.rateLabel,
+					Period:     line.period,
+					Hours:      line.hours,
+					Rate:       line.rate,
+					Amount:     line.amount,
+				})
+			}
+			if err := txStore.Invoices.AddLines(ctx, invoiceID, lines); err != nil {
+				return "", nil, fmt.Errorf("failed to save invoice lines: %w", err)
+			}
+
+			var receipts []pdf.Receipt
+			var skippedReceipts []string
+			if plan.attachReceipts {
+				if receipts, skippedReceipts, err = h.invoiceReceipts(ctx, bill.items); err != nil {
+					return "", nil, err
+				}
+			}
+
+			generator := pdf.NewInvoiceGenerator()
+			if plan.archivalFonts != nil {
+				generator = pdf.NewArchivalInvoiceGenerator(*plan.archivalFonts)
+				// A PDF merged in could break conformance, so only image
+				// receipts are attached
+				var images []pdf.Receipt
+				for _, r := range receipts {
+					if strings.EqualFold(filepath.Ext(r.Path), ".pdf") {
+						skippedReceipts = append(skippedReceipts, r.Path)
+						continue
+					}
+					images = append(images, r)
+				}
+				receipts = images
+			}
+			generator.Consolidated = plan.consolidated
+			written = append(written, pdfPath)
+			if err := generator.Generate(invoice, *paymentDetails, plan.recipients, *plan.business, receipts, pdfPath); err != nil {
+				return "", nil, fmt.Errorf("failed to generate PDF: %w", err)
+			}
+			var jsonPath string
+			if plan.jsonSidecar {
+				written = append(written, invoiceSidecarPath(pdfPath))
+				if jsonPath, err = writeInvoiceSidecar(pdfPath, invoice, bill, contracts, *plan.business, plan.recipients, plan.cc); err != nil {
+					return "", nil, err
+				}
+			}
+
+			if err := txStore.Invoices.SetPDFPath(ctx, invoiceID, pdfPath); err != nil {
+				return "", nil, fmt.Errorf("failed to save PDF path: %w", err)
+			}
+
+			if text != "" {
+				text += "\n\n"
+			}
+			text += fmt.Sprintf("Invoice %s created successfully", invoiceNumber)
+			if bill.group != "" {
+				text += " for " + bill.group
+			}
+			text += "\n"
+			for _, line := range groupInvoiceItems(bill.items) {
+				switch {
+				case line.rateKind == rateKindRetainerFee:
+					text += fmt.Sprintf("%s (%s): %s\n", line.rateLabel, line.period, line.amount.Format(invoiceCurrency))
+				case line.rateKind == rateKindExpense:
+					text += fmt.Sprintf("%s: %s\n", line.rateLabel, line.amount.Format(invoiceCurrency))
+				case line.rateLabel != "":
+					text += fmt.Sprintf("%s: %.2f hours = %s\n", line.rateLabel, line.hours, line.amount.Format(invoiceCurrency))
+				}
+			}
+			if taxAmount > 0 || bill.rounding != 0 {
+				text += fmt.Sprintf("Subtotal: %s\n", subtotal.Format(invoiceCurrency))
+			}
+			if taxAmount > 0 {
+				text += fmt.Sprintf("Tax (%g%%): %s\n", taxRate, taxAmount.Format(invoiceCurrency))
+			}
+			if bill.rounding != 0 {
+				text += fmt.Sprintf("Rounding: %s\n", bill.rounding.Format(invoiceCurrency))
+			}
+			text += fmt.Sprintf("Total: %s (%.2f hours)\n", totalAmount.Format(invoiceCurrency), bill.hours)
+			if tax.withholdingAmount > 0 {
+				text += fmt.Sprintf("Withholding (%g%%): -%s\nAmount due: %s\n", tax.withholdingRate,
+					tax.withholdingAmount.Format(invoiceCurrency), (totalAmount - tax.withholdingAmount).Format(invoiceCurrency))
+			}
+			if tax.note != "" {
+				text += fmt.Sprintf("Note: %s\n", tax.note)
+			}
+			if paymentDetails.Method != "" {
+				text += fmt.Sprintf("Paid into: %s\n", paymentDetails.Method)
+			}
+			if len(receipts) > 0 {
+				text += fmt.Sprintf("Receipts attached: %d\n", len(receipts))
+			}
+			if len(skippedReceipts) > 0 {
+				reason := "missing or not a JPEG, PNG or PDF"
+				if plan.archivalFonts != nil {
+					reason = "missing, not a JPEG or PNG, or a PDF, which can't be attached to PDF/A"
+				}
+				text += fmt.Sprintf("Receipts not attached (%s): %s\n", reason, strings.Join(skippedReceipts, ", "))
+			}
+			if plan.consolidated {
+				text += "Hours consolidated per contract and rate\n"
+			}
+			if review := entriesNeedingReview(bill.entries); len(review) > 0 {
+				text += fmt.Sprintf("Needs review: %s, from timers stopped at the timer_max_hours limit\n", strings.Join(review, ", "))
+			}
+			if plan.archivalFonts != nil {
+				text += "PDF/A-2b for archival\n"
+			}
+			text += fmt.Sprintf("PDF saved to: %s", pdfPath)
+			if jsonPath != "" {
+				text += fmt.Sprintf("\nJSON saved to: %s", jsonPath)
+			}
+
+			result.Invoices = append(result.Invoices, createdInvoice{
+				InvoiceNumber:   invoiceNumber,
+				Group:           bill.group,
+				DueDate:         dueDate,
+				Subtotal:        subtotal,
+				TaxRate:         taxRate,
+				TaxAmount:       taxAmount,
+				Withholding:     tax.withholdingAmount,
+				Rounding:        bill.rounding,
+				TotalAmount:     totalAmount,
+				Currency:        invoiceCurrency,
+				TotalHours:      bill.hours,
+				PDFPath:         pdfPath,
+				JSONPath:        jsonPath,
+				Receipts:        len(receipts),
+				SkippedReceipts: skippedReceipts,
+				PaymentMethod:   paymentDetails.Method,
+			})
+		}
+
+		if err := tx.Commit(); err != nil {
+			return "", nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		committed = true
+		result.createdInvoice = result.Invoices[0]
+		if len(result.Invoices) > 1 {
+			text = fmt.Sprintf("Created %d invoices for %s, one per %s:\n\n", len(result.Invoices), args.ClientName, strings.TrimPrefix(plan.groupBy, "per_")) + text
+		}
+		result.DeliveryMethod, result.DeliveryNote = client.DeliveryMethod, client.DeliveryNote
+		if hint := deliveryHint(client); hint != "" {
+			text += "\n\n" + hint
+		}
+
+		return text, result, nil
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "create_invoice",
+		Description: "Create an invoice for a client, or one per contract or project with group_by",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args createInvoiceArgs) (*mcp.CallToolResult, *createInvoiceResult, error) {
+		plan, err := prepareInvoices(ctx, args)
+		if err != nil {
+			return nil, nil, err
+		}
+		text, result, err := issueInvoices(ctx, plan)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: text,
+				},
+			},
+		}, result, nil
+	})
+
+	// Invoice All tool
+	type invoiceAllArgs struct {
+		Period    string   `json:"period,omitempty" jsonschema:"Period (e.g. 'last month' 'Q1 2025'; see create_invoice)"`
+		StartDate string   `json:"start_date,omitempty" jsonschema:"First day to bill, overrides the start of period (required without period)"`
+		EndDate   string   `json:"end_date,omitempty" jsonschema:"Last day to bill, overrides the end of period (required without period)"`
+		DueDays   int      `json:"due_days,omitempty" jsonschema:"Days until due (default: 30)"`
+		Currency  string   `json:"currency,omitempty" jsonschema:"Only bill contracts in this currency; needed for clients whose unbilled work spans several currencies"`
+		GroupBy   string   `json:"group_by,omitempty" jsonschema:"How to split each client's work into invoices: combined, per_contract or per_project (default: combined)"`
+		MinAmount *float64 `json:"min_amount,omitempty" jsonschema:"Skip invoices whose net amount is below this in the base currency, converting other currencies at the closest stored exchange rate, leaving the work unbilled (default: invoice_minimum_amount setting)"`
+		Confirm   bool     `json:"confirm,omitempty" jsonschema:"Create the invoices (default: false, only shows what would be invoiced)"`
+	}
+
+	type invoiceAllRow struct {
+		ClientName    string      `json:"client_name"`
+		Group         string      `json:"group,omitempty"`
+		Status        string      `json:"status" jsonschema:"invoiced, to invoice (preview), below minimum or skipped"`
+		InvoiceNumber string      `json:"invoice_number,omitempty"`
+		Currency      string      `json:"currency,omitempty"`
+		Hours         float64     `json:"hours"`
+		Subtotal      money.Cents `json:"subtotal"`
+		TotalAmount   money.Cents `json:"total_amount"`
+		PDFPath       string      `json:"pdf_path,omitempty"`
+		Reason        string      `json:"reason,omitempty" jsonschema:"Why the client was skipped"`
+	}
+
+	// skippedInvoiceAllRow reports a client, or one of its groups, that
+	// couldn't be invoiced. Unexpected errors are logged as well, since the
+	// run carries on with the other clients.
+	skippedInvoiceAllRow := func(clientName, group string, err error) invoiceAllRow {
+		if code, _ := classifyError(err); code == codeInternal {
+			slog.Error("invoice_all failed for a client", "client", clientName, "error", err)
+		}
+		return invoiceAllRow{ClientName: clientName, Group: group, Status: "skipped", Reason: err.Error()}
+	}
+
+	type invoiceAllResult struct {
+		Invoices  []invoiceAllRow        `json:"invoices" jsonschema:"One row per invoice, and per client that couldn't be invoiced"`
+		Totals    map[string]money.Cents `json:"totals" jsonschema:"Total amount invoiced (or to invoice) per currency"`
+		Count     int                    `json:"count" jsonschema:"Number of invoices created (or to create)"`
+		Confirmed bool                   `json:"confirmed" jsonschema:"Whether the invoices were created; false for a preview"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "invoice_all",
+		Description: "Invoice every client with unbilled work in a period, skipping amounts below a minimum; previews unless confirm=true",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args invoiceAllArgs) (*mcp.CallToolResult, *invoiceAllResult, error) {
+		minAmount, _ := money.Parse(h.getSetting(ctx, "invoice_minimum_amount"))
+		if args.MinAmount != nil {
+			if *args.MinAmount < 0 {
+				return nil, nil, validationError("min_amount must not be negative")
+			}
+			minAmount = money.FromFloat(*args.MinAmount)
+		}
+		if groupBy := strings.ToLower(strings.TrimSpace(args.GroupBy)); groupBy != "" && !slices.Contains(invoiceGroupings, groupBy) {
+			return nil, nil, validationError("invalid group_by '%s': must be %s", args.GroupBy, strings.Join(invoiceGroupings, ", "))
+		}
+		if args.Period != "" {
+			if _, _, err := h.parsePeriod(ctx, args.Period); err != nil {
+				return nil, nil, validationError("invalid period: %w", err)
+			}
+		} else if args.StartDate == "" || args.EndDate == "" {
+			return nil, nil, validationError("give a period, or both start_date and end_date")
+		}
+
+		clients, err := h.store.Clients.List(ctx, false)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list clients: %w", err)
+		}
+		baseCurrency, today := h.baseCurrency(ctx), h.today(ctx)
+
+		result := &invoiceAllResult{Totals: map[string]money.Cents{}, Confirmed: args.Confirm}
+		for _, client := range clients {
+			plan, err := prepareInvoices(ctx, createInvoiceArgs{
+				ClientName: client.Name,
+				Period:     args.Period,
+				StartDate:  args.StartDate,
+				EndDate:    args.EndDate,
+				DueDays:    args.DueDays,
+				Currency:   args.Currency,
+				GroupBy:    args.GroupBy,
+			})
+			if err != nil {
+				// Clients with nothing to bill are left out; anything else
+				// that stops one client being invoiced is reported with it
+				code, entity := classifyError(err)
+				switch {
+				case code == codeNotFound && entity == "unbilled_work":
+					continue
+				case code == codeNotConfigured && (entity == "business_info" || entity == "pdf_font_path"):
+					return nil, nil, err
+				}
+				result.Invoices = append(result.Invoices, skippedInvoiceAllRow(client.Name, "", err))
+				continue
+			}
+
+			// Small amounts wait until they add up to an invoice worth
+			// sending. The minimum is in the base currency.
+			var bills []*invoiceBill
+			for _, bill := range plan.bills {
+				subtotal := bill.subtotal
+				if minAmount > 0 && bill.currency != baseCurrency {
+					converted, missing, err := h.convertTotals(ctx, map[string]money.Cents{bill.currency: bill.subtotal}, baseCurrency, today)
+					if err != nil {
+						result.Invoices = append(result.Invoices, skippedInvoiceAllRow(client.Name, bill.group, err))
+						continue
+					}
+					if len(missing) > 0 {
+						result.Invoices = append(result.Invoices, invoiceAllRow{ClientName: client.Name, Group: bill.group, Status: "skipped",
+							Reason: fmt.Sprintf("no exchange rate from %s to %s to compare with the minimum; use set_exchange_rate or fetch_exchange_rates", bill.currency, baseCurrency)})
+						continue
+					}
+					subtotal = converted
+				}
+				if subtotal < minAmount {
+					result.Invoices = append(result.Invoices, invoiceAllRow{
+						ClientName:  client.Name,
+						Group:       bill.group,
+						Status:      "below minimum",
+						Currency:    bill.currency,
+						Hours:       bill.hours,
+						Subtotal:    bill.subtotal,
+						TotalAmount: bill.subtotal + bill.tax.taxAmount + bill.rounding,
+					})
+					continue
+				}
+				bills = append(bills, bill)
+			}
+			if len(bills) == 0 {
+				continue
+			}
+			plan.bills = bills
+
+			if !args.Confirm {
+				for _, bill := range bills {
+					total := bill.subtotal + bill.tax.taxAmount + bill.rounding
+					result.Invoices = append(result.Invoices, invoiceAllRow{
+						ClientName:  client.Name,
+						Group:       bill.group,
+						Status:      "to invoice",
+						Currency:    bill.currency,
+						Hours:       bill.hours,
+						Subtotal:    bill.subtotal,
+						TotalAmount: total,
+					})
+					result.Totals[bill.currency] += total
+					result.Count++
+				}
+				continue
+			}
+
+			// Invoices already created for other clients stay created, so a
+			// failure here is reported with the client rather than returned
+			_, created, err := issueInvoices(ctx, plan)
+			if err != nil {
+				result.Invoices = append(result.Invoices, skippedInvoiceAllRow(client.Name, "", err))
+				continue
+			}
+			for _, inv := range created.Invoices {
+				result.Invoices = append(result.Invoices, invoiceAllRow{
+					ClientName:    client.Name,
+					Group:         inv.Group,
+					Status:        "invoiced",
+					InvoiceNumber: inv.InvoiceNumber,
+					Currency:      inv.Currency,
+					Hours:         inv.TotalHours,
+					Subtotal:      inv.Subtotal,
+					TotalAmount:   inv.TotalAmount,
+					PDFPath:       inv.PDFPath,
+				})
+				result.Totals[inv.Currency] += inv.TotalAmount
+				result.Count++
+			}
+		}
+
+		if len(result.Invoices) == 0 {
+			return nil, nil, notFoundError("unbilled_work", "no client has unbilled hours or expenses in that period")
+		}
+
+		text := "| Client | Invoice | Hours | Amount | Status |\n"
+		text += "|--------|---------|-------|--------|--------|\n"
+		for _, row := range result.Invoices {
+			name := row.ClientName
+			if row.Group != "" {
+				name += " (" + row.Group + ")"
+			}
+			if row.Reason != "" {
+				text += fmt.Sprintf("| %s | | | | %s: %s |\n", name, row.Status, row.Reason)
+				continue
+			}
+			text += fmt.Sprintf("| %s | %s | %.2f | %s | %s |\n", name, row.InvoiceNumber, row.Hours, row.TotalAmount.Format(row.Currency), row.Status)
+		}
+		text += fmt.Sprintf("\nTotal: %s", formatCurrencyTotals(result.Totals))
+		if minAmount > 0 {
+			text += fmt.Sprintf(" (invoices under %s skipped)", minAmount.Format(baseCurrency))
+		}
+
+		if !args.Confirm {
+			return previewResult(fmt.Sprintf("Would create %d invoices:\n\n%s", result.Count, text), result)
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("Created %d invoices:\n\n%s", result.Count, text),
+				},
+			},
+		}, result, nil
+	})
+
+	// List Invoice Details tool
+	type listInvoiceDetailsArgs struct {
+		InvoiceNumber string `json:"invoice_number" jsonschema:"Invoice number to get details for"`
+	}
+
+	type invoiceDetailsResult struct {
+		Invoice     *models.Invoice      `json:"invoice"`
+		ClientName  string               `json:"client_name"`
+		TimeEntries []models.TimeEntry   `json:"time_entries"`
+		Expenses    []models.Expense     `json:"expenses" jsonschema:"Billed expenses, before any markup added on the invoice"`
+		TotalHours  float64              `json:"total_hours"`
+		Notes       []models.InvoiceNote `json:"notes" jsonschema:"Follow-up notes added with add_invoice_note, oldest first"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_invoice_details",
+		Description: "Get detailed information about an invoice including all associated time entries",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listInvoiceDetailsArgs) (*mcp.CallToolResult, *invoiceDetailsResult, error) {
+		invoice, err := h.store.Invoices.ByNumber(ctx, args.InvoiceNumber)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("invoice", "invoice %s not found", args.InvoiceNumber)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to get invoice details: %w", err)
+		}
+		clientName := invoice.Client.Name
+
+		entries, err := h.store.Entries.ForInvoice(ctx, invoice.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load time entries: %w", err)
+		}
+		var totalHours float64
+		for _, e := range entries {
+			totalHours += e.Hours
+		}
+
+		expenses, err := h.store.Invoices.Expenses(ctx, invoice.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load expenses: %w", err)
+		}
+		notes, err := h.invoiceNotes(ctx, "i.id = ?", invoice.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		text := fmt.Sprintf("Invoice Details: %s\n", invoice.InvoiceNumber)
+		text += fmt.Sprintf("Client: %s\n", clientName)
+		text += fmt.Sprintf("Issue Date: %s\n", invoice.IssueDate.Format("2006-01-02"))
+		text += fmt.Sprintf("Due Date: %s\n", invoice.DueDate.Format("2006-01-02"))
+		text += fmt.Sprintf("Status: %s\n", invoice.Status)
+		if invoice.WrittenOffDate != nil {
+			text += fmt.Sprintf("Written off: %s", invoice.WrittenOffDate.Format("2006-01-02"))
+			if invoice.WriteOffReason != "" {
+				text += " (" + invoice.WriteOffReason + ")"
+			}
+			text += "\n"
+		}
+		if invoice.SentAt != nil {
+			text += fmt.Sprintf("Sent: %s by %s", invoice.SentAt.In(h.location(ctx)).Format("2006-01-02"), invoice.DeliveryMethod)
+			if invoice.SentTo != "" {
+				text += " to " + invoice.SentTo
+			}
+			text += "\n"
+		} else {
+			text += "Sent: never\n"
+		}
+		if invoice.TaxAmount > 0 || invoice.Rounding != 0 {
+			text += fmt.Sprintf("Subtotal: %s\n", (invoice.TotalAmount - invoice.TaxAmount - invoice.Rounding).Format(invoice.Currency))
+		}
+		if invoice.TaxAmount > 0 {
+			text += fmt.Sprintf("Tax (%g%%): %s\n", invoice.TaxRate, invoice.TaxAmount.Format(invoice.Currency))
+		}
+		if invoice.Rounding != 0 {
+			text += fmt.Sprintf("Rounding: %s\n", invoice.Rounding.Format(invoice.Currency))
+		}
+		text += fmt.Sprintf("Total Amount: %s\n", invoice.TotalAmount.Format(invoice.Currency))
+		if invoice.WithholdingAmount > 0 {
+			text += fmt.Sprintf("Withholding (%g%%): -%s\n", invoice.WithholdingRate, invoice.WithholdingAmount.Format(invoice.Currency))
+			text += fmt.Sprintf("Amount Due: %s\n", (invoice.TotalAmount - invoice.WithholdingAmount).Format(invoice.Currency))
+		}
+		if invoice.TaxNote != "" {
+			text += fmt.Sprintf("Tax Note: %s\n", invoice.TaxNote)
+		}
+		text += fmt.Sprintf("Total Hours: %.2f\n", totalHours)
+		if invoice.PDFPath != "" {
+			text += fmt.Sprintf("PDF Path: %s\n", invoice.PDFPath)
+		}
+		text += fmt.Sprintf("\nTime Entries (%d):\n", len(entries))
+		for _, e := range entries {
+			text += fmt.Sprintf("- ID %s: %s - %.2f hours (%s)", e.ID, e.Date.Format("2006-01-02"), e.Hours, e.Description)
+			if e.NoCharge {
+				text += " [N/C]"
+			}
+			text += "\n"
+		}
+		if len(expenses) > 0 {
+			// Amounts are as recorded, before any markup added on the invoice
+			text += fmt.Sprintf("\nExpenses (%d):\n", len(expenses))
+			for _, e := range expenses {
+				text += fmt.Sprintf("- ID %d: %s - %s (%s)\n",
+					e.ID, e.Date.Format("2006-01-02"), e.Amount.Format(e.Currency), expenseDescription(e))
+			}
+		}
+		if len(notes) > 0 {
+			text += fmt.Sprintf("\nNotes (%d):\n", len(notes))
+			text += invoiceNotesText(notes)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &invoiceDetailsResult{
+			Invoice:     invoice,
+			ClientName:  clientName,
+			TimeEntries: entries,
+			Expenses:    expenses,
+			TotalHours:  totalHours,
+			Notes:       notes,
+		}, nil
+	})
+
+	// Update Invoice Status tool
+	type updateInvoiceStatusArgs struct {
+		InvoiceNumber string `json:"invoice_number" jsonschema:"Invoice number to update"`
+		Status        string `json:"status" jsonschema:"New status (draft, sent, paid, overdue, cancelled); uncollectable invoices are written off with write_off_invoice"`
+		PaidDate      string `json:"paid_date,omitempty" jsonschema:"Date payment was received when marking paid (default: today)"`
+		Confirm       bool   `json:"confirm,omitempty" jsonschema:"Required to cancel (void) an invoice; without it cancelling only shows the invoice"`
+	}
+
+	type updateInvoiceStatusResult struct {
+		Confirmed   bool            `json:"confirmed" jsonschema:"Whether the status was changed; false for a preview of cancelling"`
+		Invoice     *models.Invoice `json:"invoice"`
+		TimeEntries int             `json:"time_entries,omitempty" jsonschema:"Number of time entries linked to the invoice, shown when cancelling"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "update_invoice_status",
+		Description: "Update the status of an invoice. Cancelling (voiding) one only shows what it would affect until run with confirm=true",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args updateInvoiceStatusArgs) (*mcp.CallToolResult, *updateInvoiceStatusResult, error) {
+		validStatuses := map[string]bool{
+			"draft":     true,
+			"sent":      true,
+			"paid":      true,
+			"overdue":   true,
+			"cancelled": true,
+		}
+
+		if args.Status == "written_off" {
+			return nil, nil, validationError("use write_off_invoice to write an invoice off")
+		}
+		if !validStatuses[args.Status] {
+			return nil, nil, validationError("invalid status '%s'. Valid statuses are: draft, sent, paid, overdue, cancelled", args.Status)
+		}
+
+		// Record when payment arrived so payments can be exported
+		var paidDate *time.Time
+		if args.Status == "paid" {
+			date := h.today(ctx)
+			if args.PaidDate != "" {
+				var err error
+				date, err = h.parseDate(ctx, args.PaidDate)
+				if err != nil {
+					return nil, nil, validationError("invalid paid date: %w", err)
+				}
+			}
+			paidDate = &date
+		}
+
+		// A cancelled invoice drops out of reports and exports
+		if args.Status == "cancelled" && !args.Confirm {
+			inv, err := h.store.Invoices.ByNumber(ctx, args.InvoiceNumber)
+			if err == sql.ErrNoRows {
+				return nil, nil, notFoundError("invoice", "invoice %s not found", args.InvoiceNumber)
+			} else if err != nil {
+				return nil, nil, fmt.Errorf("failed to find invoice: %w", err)
+			}
+			if inv.Status == "cancelled" {
+				return nil, nil, conflictError("invoice %s is already cancelled", args.InvoiceNumber)
+			}
+			var entryCount int
+			if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM time_entries WHERE invoice_id = ?", inv.ID).Scan(&entryCount); err != nil {
+				return nil, nil, fmt.Errorf("failed to count time entries: %w", err)
+			}
+			text := fmt.Sprintf("Would cancel invoice %s for %s (%s, %s, issued %s).\n",
+				inv.InvoiceNumber, inv.Client.Name, inv.TotalAmount.Format(inv.Currency), inv.Status, inv.IssueDate.Format("2006-01-02"))
+			text += "It would no longer count in tax reports or accounting exports"
+			if entryCount > 0 {
+				text += fmt.Sprintf("; its %d time entries stay linked to it", entryCount)
+			}
+			text += ".\n"
+			return previewResult(text, &updateInvoiceStatusResult{Invoice: inv, TimeEntries: entryCount})
+		}
+
+		// Any other status undoes a write-off, e.g. when the client pays
+		// after all
+		var writtenOff sql.NullTime
+		err := db.QueryRowContext(ctx, "SELECT written_off_date FROM invoices WHERE invoice_number = ?", args.InvoiceNumber).Scan(&writtenOff)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("invoice", "invoice %s not found", args.InvoiceNumber)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to find invoice: %w", err)
+		}
+
+		found, err := h.store.Invoices.SetStatus(ctx, args.InvoiceNumber, args.Status, paidDate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to update invoice status: %w", err)
+		}
+		if !found {
+			return nil, nil, notFoundError("invoice", "invoice %s not found", args.InvoiceNumber)
+		}
+		invoice, err := h.store.Invoices.ByNumber(ctx, args.InvoiceNumber)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load invoice: %w", err)
+		}
+
+		text := fmt.Sprintf("Invoice %s status updated to '%s'", args.InvoiceNumber, args.Status)
+		if writtenOff.Valid {
+			text += fmt.Sprintf("; its write-off on %s is undone and it leaves the write-off report", writtenOff.Time.Format("2006-01-02"))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &updateInvoiceStatusResult{Confirmed: true, Invoice: invoice}, nil
+	})
+
+	// Mark Invoice Sent tool
+	type markInvoiceSentArgs struct {
+		InvoiceNumber  string `json:"invoice_number" jsonschema:"Invoice number that was sent"`
+		Date           string `json:"date,omitempty" jsonschema:"Date it was sent (default: now)"`
+		SentTo         string `json:"sent_to,omitempty" jsonschema:"Who it was sent to, e.g. an email address or 'accounts payable portal' (optional)"`
+		DeliveryMethod string `json:"delivery_method,omitempty" jsonschema:"How it was sent: email, portal, post, hand, paypal or other (default: the client's preferred delivery method, else email)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "mark_invoice_sent",
+		Description: "Record that an invoice was sent outside email_invoice, e.g. uploaded to a client portal or emailed by hand. Pending invoices are marked as sent",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args markInvoiceSentArgs) (*mcp.CallToolResult, *models.Invoice, error) {
+		if args.DeliveryMethod != "" && !slices.Contains(deliveryMethods, args.DeliveryMethod) {
+			return nil, nil, validationError("invalid delivery method '%s': must be %s", args.DeliveryMethod, strings.Join(deliveryMethods, ", "))
+		}
+
+		inv, err := h.store.Invoices.ByNumber(ctx, args.InvoiceNumber)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("invoice", "invoice %s not found", args.InvoiceNumber)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to find invoice: %w", err)
+		}
+		if inv.Status == "cancelled" {
+			return nil, nil, conflictError("invoice %s is cancelled", inv.InvoiceNumber)
+		}
+		if args.DeliveryMethod == "" {
+			client, err := h.loadClient(ctx, inv.ClientID)
+			if err != nil {
+				return nil, nil, err
+			}
+			args.DeliveryMethod = client.DeliveryMethod
+			if args.DeliveryMethod == "" {
+				args.DeliveryMethod = deliveryEmail
+			}
+		}
+
+		// A past day is recorded as its start in the business's time zone
+		sentAt := time.Now()
+		if args.Date != "" {
+			date, err := h.parseDate(ctx, args.Date)
+			if err != nil {
+				return nil, nil, validationError("invalid date: %w", err)
+			}
+			if date.After(h.today(ctx)) {
+				return nil, nil, validationError("date %s is in the future", date.Format("2006-01-02"))
+			}
+			if date.Before(h.today(ctx)) {
+				sentAt = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, h.location(ctx))
+			}
+		}
+		if err := h.store.Invoices.MarkSent(ctx, inv.ID, sentAt, strings.TrimSpace(args.SentTo), args.DeliveryMethod); err != nil {
+			return nil, nil, fmt.Errorf("failed to record delivery: %w", err)
+		}
+		updated, err := h.store.Invoices.ByNumber(ctx, inv.InvoiceNumber)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load invoice: %w", err)
+		}
+
+		text := fmt.Sprintf("Invoice %s marked as sent %s by %s", inv.InvoiceNumber, sentAt.In(h.location(ctx)).Format("2006-01-02"), args.DeliveryMethod)
+		if updated.SentTo != "" {
+			text += " to " + updated.SentTo
+		}
+		text += "\n"
+		if inv.Status != updated.Status {
+			text += fmt.Sprintf("Status updated to '%s'\n", updated.Status)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, updated, nil
+	})
+
+	// List Invoices tool
+	type listInvoicesArgs struct {
+		ClientName string `json:"client_name,omitempty" jsonschema:"Filter by client name (optional)"`
+		Status     string `json:"status,omitempty" jsonschema:"Filter by status (optional)"`
+		StartDate  string `json:"start_date,omitempty" jsonschema:"Filter by issue date start (optional)"`
+		EndDate    string `json:"end_date,omitempty" jsonschema:"Filter by issue date end (optional)"`
+		Format     string `json:"format,omitempty" jsonschema:"Output format: text (default) or markdown"`
+	}
+
+	type listInvoicesResult struct {
+		Invoices []store.InvoiceListing `json:"invoices"`
+		Totals   map[string]money.Cents `json:"totals" jsonschema:"Total amount per currency"`
+		Count    int                    `json:"count"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_invoices",
+		Description: "List invoices with optional filters",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listInvoicesArgs) (*mcp.CallToolResult, *listInvoicesResult, error) {
+		markdown, err := parseOutputFormat(args.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		filter := store.InvoiceFilter{Status: args.Status}
+		if args.ClientName != "" {
+			if filter.ClientID, err = h.getClientIDByName(ctx, args.ClientName); err != nil {
+				return nil, nil, fmt.Errorf("client not found: %w", err)
+			}
+		}
+		if filter.StartDate, err = h.parseDateFilter(ctx, args.StartDate, "start"); err != nil {
+			return nil, nil, err
+		}
+		if filter.EndDate, err = h.parseDateFilter(ctx, args.EndDate, "end"); err != nil {
+			return nil, nil, err
+		}
+
+		invoices, err := h.store.Invoices.List(ctx, filter)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list invoices: %w", err)
+		}
+		totals := map[string]money.Cents{}
+		for _, inv := range invoices {
+			totals[inv.Currency] += inv.TotalAmount
+		}
+
+		var text string
+		if markdown {
+			tableRows := make([][]string, 0, len(invoices)+1)
+			for _, inv := range invoices {
+				tableRows = append(tableRows, []string{inv.InvoiceNumber, inv.ClientName, inv.IssueDate.Format("2006-01-02"),
+					inv.DueDate.Format("2006-01-02"), inv.Status, h.sentAgo(ctx, inv.SentAt), inv.TotalAmount.Format(inv.Currency)})
+			}
+			tableRows = append(tableRows, []string{"**Total**", "", "", "", "", "", "**" + formatCurrencyTotals(totals) + "**"})
+			text = fmt.Sprintf("**%d invoices**\n\n", len(invoices)) +
+				markdownTable([]string{"Invoice", "Client", "Issued", "Due", "Status", "Sent", "Amount"}, tableRows, 6)
+		} else {
+			text = fmt.Sprintf("Found %d invoices (Total: %s):\n", len(invoices), formatCurrencyTotals(totals))
+			for _, inv := range invoices {
+				text += fmt.Sprintf("- %s: %s - %s (%s) - Due: %s - %s\n",
+					inv.InvoiceNumber, inv.ClientName, inv.TotalAmount.Format(inv.Currency), inv.Status,
+					inv.DueDate.Format("2006-01-02"), h.sentAgo(ctx, inv.SentAt))
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &listInvoicesResult{Invoices: invoices, Totals: totals, Count: len(invoices)}, nil
+	})
+}
+
+// deliveryEmail is the delivery method of invoices sent by email_invoice
+const deliveryEmail = "email"
+
+// deliveryPayPal is the delivery method of invoices sent through PayPal by
+// export_paypal_invoice
+const deliveryPayPal = "paypal"
+
+// deliveryMethods are the ways an invoice can be recorded as sent
+var deliveryMethods = []string{deliveryEmail, "portal", "post", "hand", deliveryPayPal, "other"}
+
+// sentAgo words how long ago an invoice was last sent, in days of the
+// business's time zone
+func (h *Handler) sentAgo(ctx context.Context, sentAt *time.Time) string {
+	if sentAt == nil {
+		return "not sent"
+	}
+	days := int(h.today(ctx).Sub(calendarDay(sentAt.In(h.location(ctx)))).Hours() / 24)
+	switch days {
+	case 0:
+		return "sent today"
+	case 1:
+		return "sent yesterday"
+	}
+	return fmt.Sprintf("sent %d days ago", days)
+}
+
+// invoiceGroupCombined bills all of a client's work on one invoice
+const invoiceGroupCombined = "combined"
+
+// invoiceGroupings are the ways create_invoice can split a client's work
+// into invoices
+var invoiceGroupings = []string{invoiceGroupCombined, "per_contract", "per_project"}
+
+// invoiceGroup is the work of a client billed on one invoice
+type invoiceGroup struct {
+	name    string
+	items   []models.InvoiceItem
+	entries []models.TimeEntry
+}
+
+// invoiceBill is an invoiceGroup checked and ready to be saved: its work
+// in the invoice currency, its tax and the account to be paid into
+type invoiceBill struct {
+	group    string
+	currency string
+	items    []models.InvoiceItem
+	entries  []models.TimeEntry
+	subtotal money.Cents
+	hours    float64
+	payment  *models.PaymentDetails
+	tax      invoiceTax
+	rounding money.Cents
+}
+
+// groupInvoiceWork splits a client's priced items and time entries into
+// the invoices groupBy asks for, in the order their work first appears.
+// Projects are the contracts sharing a name, such as a contract and its
+// renewals.
+func (h *Handler) groupInvoiceWork(ctx context.Context, clientID int, groupBy string, items []models.InvoiceItem, entries []models.TimeEntry) ([]*invoiceGroup, error) {
+	if groupBy == invoiceGroupCombined {
+		return []*invoiceGroup{{items: items, entries: entries}}, nil
+	}
+
+	rows, err := h.db.QueryContext(ctx, "SELECT id, contract_number, name FROM contracts WHERE client_id = ?", clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contracts: %w", err)
+	}
+	defer rows.Close()
+	names := map[int]string{}
+	for rows.Next() {
+		var id int
+		var number, name string
+		if err := rows.Scan(&id, &number, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan contract: %w", err)
+		}
+		names[id] = fmt.Sprintf("contract %s (%s)", number, name)
+		if groupBy == "per_project" {
+			names[id] = fmt.Sprintf("project %s", name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load contracts: %w", err)
+	}
+
+	var groups []*invoiceGroup
+	byKey := map[string]*invoiceGroup{}
+	group := func(contractID int) *invoiceGroup {
+		key := strings.ToLower(names[contractID])
+		if g, ok := byKey[key]; ok {
+			return g
+		}
+		g := &invoiceGroup{name: names[contractID]}
+		byKey[key] = g
+		groups = append(groups, g)
+		return g
+	}
+	for _, item := range items {
+		g := group(item.ContractID)
+		g.items = append(g.items, item)
+	}
+	for _, e := range entries {
+		g := group(e.ContractID)
+		g.entries = append(g.entries, e)
+	}
+	return groups, nil
+}
+
+// invoiceLinkError words a failure to bill the time entries, expenses or
+// retainer fees of a new invoice, which is not saved
+func invoiceLinkError(what, clientName string, err error) error {
+	if errors.Is(err, store.ErrAlreadyInvoiced) {
+		return conflictError("some of the %s were invoiced by another session while this invoice was prepared, so it was not saved. Run create_invoice for %s again to bill what is left", what, clientName)
+	}
+	return fmt.Errorf("failed to link %s to invoice: %w", what, err)
+}
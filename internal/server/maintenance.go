@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/austin/hours-mcp/internal/database"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// registerMaintenanceTools registers the database maintenance tool
+func registerMaintenanceTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// DB Maintenance tool
+	type dbMaintenanceArgs struct {
+		Vacuum *bool `json:"vacuum,omitempty" jsonschema:"Run VACUUM and ANALYZE to reclaim space and refresh statistics (default: true)"`
+		Repair bool  `json:"repair,omitempty" jsonschema:"Fix orphaned records: clear optional references to missing rows and delete rows whose required reference is missing. A backup is taken first (default: false, only report)"`
+	}
+
+	type dbMaintenanceResult struct {
+		IntegrityProblems []string          `json:"integrity_problems"`
+		Orphans           []database.Orphan `json:"orphans"`
+		Repaired          int               `json:"repaired" jsonschema:"Orphaned records repaired"`
+		Vacuumed          bool              `json:"vacuumed"`
+		Encrypted         bool              `json:"encrypted"`
+		SizeBefore        int64             `json:"size_before" jsonschema:"Database size in bytes before vacuuming"`
+		Size              int64             `json:"size" jsonschema:"Database size in bytes"`
+		RowCounts         map[string]int    `json:"row_counts"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "db_maintenance",
+		Description: "Check database integrity, vacuum and analyze it, and report its size, row counts per table and orphaned records, optionally repairing them",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args dbMaintenanceArgs) (*mcp.CallToolResult, *dbMaintenanceResult, error) {
+		problems, err := database.IntegrityCheck(ctx, db)
+		if err != nil {
+			return nil, nil, err
+		}
+		sizeBefore, err := database.Size(ctx, db)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		text := "Integrity check: ok\n"
+		if len(problems) > 0 {
+			text = fmt.Sprintf("Integrity check found %d problems:\n", len(problems))
+			for _, p := range problems {
+				text += fmt.Sprintf("- %s\n", p)
+			}
+			text += "Skipping vacuum and repair. Restore a backup with restore_backup, or export_data and import into a new database.\n"
+		}
+
+		orphans, err := database.FindOrphans(ctx, db)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(orphans) == 0 {
+			text += "Orphaned records: none\n"
+		} else {
+			text += "Orphaned records:\n"
+			for _, o := range orphans {
+				text += fmt.Sprintf("- %d %s rows with %s pointing at missing %s\n", o.Count, o.Table, o.Column, o.Parent)
+			}
+		}
+
+		repaired := 0
+		if args.Repair && len(orphans) > 0 && len(problems) == 0 {
+			if !database.InMemory() {
+				backup, err := database.Backup(ctx, db, "pre-repair")
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to back up before repairing: %w", err)
+				}
+				text += fmt.Sprintf("Backup saved to %s\n", backup.Path)
+			}
+			if repaired, err = database.RepairOrphans(ctx, db); err != nil {
+				return nil, nil, err
+			}
+			text += fmt.Sprintf("Repaired %d orphaned records\n", repaired)
+		} else if len(orphans) > 0 {
+			text += "Run with repair to fix them.\n"
+		}
+
+		vacuumed := false
+		if (args.Vacuum == nil || *args.Vacuum) && len(problems) == 0 {
+			if err := database.Optimize(ctx, db); err != nil {
+				return nil, nil, err
+			}
+			vacuumed = true
+		}
+
+		size, err := database.Size(ctx, db)
+		if err != nil {
+			return nil, nil, err
+		}
+		if vacuumed {
+			text += fmt.Sprintf("Vacuumed and analyzed: %.1f KB -> %.1f KB\n", float64(sizeBefore)/1024, float64(size)/1024)
+		} else {
+			text += fmt.Sprintf("Size: %.1f KB\n", float64(size)/1024)
+		}
+
+		if database.Encrypted() {
+			text += "Database file: encrypted\n"
+		}
+
+		counts, err := database.RowCounts(ctx, db)
+		if err != nil {
+			return nil, nil, err
+		}
+		tables := make([]string, 0, len(counts))
+		for table := range counts {
+			tables = append(tables, table)
+		}
+		sort.Strings(tables)
+		text += "Rows:\n"
+		for _, table := range tables {
+			text += fmt.Sprintf("- %s: %d\n", table, counts[table])
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &dbMaintenanceResult{
+			IntegrityProblems: problems,
+			Orphans:           orphans,
+			Repaired:          repaired,
+			Vacuumed:          vacuumed,
+			Encrypted:         database.Encrypted(),
+			SizeBefore:        sizeBefore,
+			Size:              size,
+			RowCounts:         counts,
+		}, nil
+	})
+}
@@ -0,0 +1,64 @@
+package server
+
+import "strings"
+
+// Output formats accepted by list and report tools
+const (
+	formatText     = "text"
+	formatMarkdown = "markdown"
+)
+
+// parseOutputFormat validates a tool's format argument and reports whether
+// markdown output was requested
+func parseOutputFormat(format string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", formatText:
+		return false, nil
+	case formatMarkdown, "md":
+		return true, nil
+	}
+	return false, validationError("invalid format '%s': must be 'text' or 'markdown'", format)
+}
+
+// markdownTable renders a GitHub-flavored markdown table. alignRight lists
+// the indexes of numeric columns to right align.
+func markdownTable(headers []string, rows [][]string, alignRight ...int) string {
+	right := map[int]bool{}
+	for _, i := range alignRight {
+		right[i] = true
+	}
+
+	var b strings.Builder
+	b.WriteString("|")
+	for _, header := range headers {
+		b.WriteString(" " + markdownCell(header) + " |")
+	}
+	b.WriteString("\n|")
+	for i := range headers {
+		if right[i] {
+			b.WriteString(" ---: |")
+		} else {
+			b.WriteString(" --- |")
+		}
+	}
+	b.WriteString("\n")
+	for _, row := range rows {
+		b.WriteString("|")
+		for i := range headers {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			b.WriteString(" " + markdownCell(cell) + " |")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// markdownCell escapes pipes and flattens newlines so a value stays in its cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r\n", " ")
+	return strings.ReplaceAll(s, "\n", " ")
+}
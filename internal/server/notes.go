@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// invoiceNotes returns the notes matching where, a condition on
+// invoice_notes n joined to invoices i, oldest first per invoice
+func (h *Handler) invoiceNotes(ctx context.Context, where string, args ...interface{}) ([]models.InvoiceNote, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT n.id, i.invoice_number, n.date, n.note, n.created_at
+		FROM invoice_notes n
+		JOIN invoices i ON i.id = n.invoice_id
+		WHERE `+where+`
+		ORDER BY i.issue_date, i.invoice_number, n.date, n.id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invoice notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := []models.InvoiceNote{}
+	for rows.Next() {
+		var n models.InvoiceNote
+		if err := rows.Scan(&n.ID, &n.InvoiceNumber, &n.Date, &n.Note, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice note: %w", err)
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+// invoiceNotesText lists notes one per line, e.g. "- 2025-06-10: promised
+// payment 6/15 (note 4)"
+func invoiceNotesText(notes []models.InvoiceNote) string {
+	text := ""
+	for _, n := range notes {
+		text += fmt.Sprintf("- %s: %s (note %d)\n", n.Date.Format("2006-01-02"), n.Note, n.ID)
+	}
+	return text
+}
+
+// registerInvoiceNoteTools registers the tools keeping a dated thread of
+// notes on each invoice, such as disputes and promises to pay
+func registerInvoiceNoteTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Add Invoice Note tool
+	type addInvoiceNoteArgs struct {
+		InvoiceNumber string `json:"invoice_number" jsonschema:"Invoice number the note is about"`
+		Note          string `json:"note" jsonschema:"What happened, e.g. 'client disputed line 3' or 'promised payment 6/15'"`
+		Date          string `json:"date,omitempty" jsonschema:"Date it happened (default: today)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "add_invoice_note",
+		Description: "Append a dated note to an invoice's follow-up history, e.g. a dispute, a promise to pay or a call with accounts payable; notes show in list_invoice_details and list_invoice_notes",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args addInvoiceNoteArgs) (*mcp.CallToolResult, *models.InvoiceNote, error) {
+		note := strings.TrimSpace(args.Note)
+		if note == "" {
+			return nil, nil, validationError("note is required")
+		}
+		invoiceID, err := h.store.Invoices.IDByNumber(ctx, args.InvoiceNumber)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("invoice", "invoice %s not found", args.InvoiceNumber)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find invoice: %w", err)
+		}
+		date := h.today(ctx)
+		if args.Date != "" {
+			if date, err = h.parseDate(ctx, args.Date); err != nil {
+				return nil, nil, validationError("invalid date: %w", err)
+			}
+		}
+
+		result, err := db.ExecContext(ctx, "INSERT INTO invoice_notes (invoice_id, date, note) VALUES (?, ?, ?)",
+			invoiceID, date.Format("2006-01-02"), note)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to add invoice note: %w", err)
+		}
+		id, _ := result.LastInsertId()
+		notes, err := h.invoiceNotes(ctx, "n.id = ?", id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(notes) == 0 {
+			return nil, nil, notFoundError("invoice_note", "invoice note %d not found", id)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Added note %d to invoice %s on %s: %s", id, args.InvoiceNumber, date.Format("2006-01-02"), note)},
+			},
+		}, &notes[0], nil
+	})
+
+	// List Invoice Notes tool
+	type listInvoiceNotesArgs struct {
+		InvoiceNumber string `json:"invoice_number,omitempty" jsonschema:"Only this invoice's notes (optional)"`
+		ClientName    string `json:"client_name,omitempty" jsonschema:"Only notes on this client's invoices (optional)"`
+		Search        string `json:"search,omitempty" jsonschema:"Search note text; supports \"quoted phrases\" and prefix* terms (optional)"`
+		All           bool   `json:"all,omitempty" jsonschema:"Include notes on paid, written off and cancelled invoices (default: false, only open invoices unless invoice_number is given)"`
+	}
+
+	type listInvoiceNotesResult struct {
+		Notes []models.InvoiceNote `json:"notes"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_invoice_notes",
+		Description: "List or search the follow-up notes on invoices, grouped by invoice, oldest first; by default those on invoices not yet paid, written off or cancelled",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listInvoiceNotesArgs) (*mcp.CallToolResult, *listInvoiceNotesResult, error) {
+		conditions := []string{"1=1"}
+		queryArgs := []interface{}{}
+		if args.InvoiceNumber != "" {
+			invoiceID, err := h.store.Invoices.IDByNumber(ctx, args.InvoiceNumber)
+			if err == sql.ErrNoRows {
+				return nil, nil, notFoundError("invoice", "invoice %s not found", args.InvoiceNumber)
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to find invoice: %w", err)
+			}
+			conditions = append(conditions, "i.id = ?")
+			queryArgs = append(queryArgs, invoiceID)
+		} else if !args.All {
+			conditions = append(conditions, "i.status NOT IN ('paid', 'written_off', 'cancelled')")
+		}
+		if args.ClientName != "" {
+			clientID, err := h.getClientIDByName(ctx, args.ClientName)
+			if err != nil {
+				return nil, nil, fmt.Errorf("client not found: %w", err)
+			}
+			conditions = append(conditions, "i.client_id = ?")
+			queryArgs = append(queryArgs, clientID)
+		}
+		// Full-text search when FTS5 is available, otherwise a substring
+		// match
+		if ftsQuery := buildFTSQuery(args.Search); h.search && ftsQuery != "" {
+			conditions = append(conditions, "n.id IN (SELECT note_id FROM invoice_notes_fts WHERE invoice_notes_fts MATCH ?)")
+			queryArgs = append(queryArgs, ftsQuery)
+		} else if args.Search != "" {
+			conditions = append(conditions, "n.note LIKE ?")
+			queryArgs = append(queryArgs, "%"+strings.Trim(args.Search, "\"*")+"%")
+		}
+
+		notes, err := h.invoiceNotes(ctx, strings.Join(conditions, " AND "), queryArgs...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		text := ""
+		if len(notes) == 0 {
+			text = "No invoice notes found.\n"
+		}
+		for i, n := range notes {
+			if i == 0 || notes[i-1].InvoiceNumber != n.InvoiceNumber {
+				text += n.InvoiceNumber + ":\n"
+			}
+			text += invoiceNotesText(notes[i : i+1])
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &listInvoiceNotesResult{Notes: notes}, nil
+	})
+
+	// Delete Invoice Note tool
+	type deleteInvoiceNoteArgs struct {
+		ID int `json:"id" jsonschema:"ID of the note, shown by list_invoice_notes"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "delete_invoice_note",
+		Description: "Delete an invoice note added by mistake",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args deleteInvoiceNoteArgs) (*mcp.CallToolResult, *models.InvoiceNote, error) {
+		notes, err := h.invoiceNotes(ctx, "n.id = ?", args.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(notes) == 0 {
+			return nil, nil, notFoundError("invoice_note", "invoice note %d not found", args.ID)
+		}
+		if _, err := db.ExecContext(ctx, "DELETE FROM invoice_notes WHERE id = ?", args.ID); err != nil {
+			return nil, nil, fmt.Errorf("failed to delete invoice note: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Deleted note %d from invoice %s: %s", args.ID, notes[0].InvoiceNumber, notes[0].Note)},
+			},
+		}, &notes[0], nil
+	})
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Every tool returns a typed result alongside its text, declared as the
+// tool's output schema so clients can read results without parsing text.
+// Failed calls return a toolErrorContent instead.
+
+// addTool registers a tool whose structured result is a *Out, declaring the
+// input and output schemas inferred from In and Out. Errors are reported as
+// tool errors with their code.
+func addTool[In, Out any](server *mcp.Server, t *mcp.Tool, h func(context.Context, *mcp.CallToolRequest, In) (*mcp.CallToolResult, *Out, error)) {
+	if t.InputSchema == nil {
+		t.InputSchema = inputSchema[In]()
+	}
+	t.OutputSchema = outputSchema[Out]()
+	mcp.AddTool(server, t, func(ctx context.Context, req *mcp.CallToolRequest, in In) (*mcp.CallToolResult, any, error) {
+		res, out, err := h(ctx, req, in)
+		if err != nil {
+			return errorResult(err), nil, nil
+		}
+		if out == nil {
+			out = new(Out)
+		}
+		return res, out, nil
+	})
+}
+
+// inputSchema infers the schema of a tool's arguments, adjusted to match
+// what they accept: hours may be a duration string as well as a number
+func inputSchema[T any]() *jsonschema.Schema {
+	s, err := jsonschema.For[T](nil)
+	if err != nil {
+		panic(fmt.Sprintf("input schema of %T: %v", *new(T), err))
+	}
+	acceptDurations(reflect.TypeFor[T](), s)
+	return s
+}
+
+var hoursArgType = reflect.TypeFor[hoursArg]()
+
+func acceptDurations(t reflect.Type, s *jsonschema.Schema) {
+	if s == nil {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == hoursArgType {
+		if s.Type != "" {
+			s.Types = []string{s.Type}
+			s.Type = ""
+		}
+		s.Types = append(s.Types, "string")
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for _, field := range reflect.VisibleFields(t) {
+			if name, ok := jsonFieldName(field); ok {
+				acceptDurations(field.Type, s.Properties[name])
+			}
+		}
+	case reflect.Slice:
+		acceptDurations(t.Elem(), s.Items)
+	}
+}
+
+// outputSchema infers the schema of a result type, adjusted to match what
+// encoding/json writes: amounts of money are decimal numbers and nil slices
+// and maps are null. A failed call's toolErrorContent matches it too.
+func outputSchema[T any]() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:  "object",
+		AnyOf: []*jsonschema.Schema{inferSchema[T](), inferSchema[toolErrorContent]()},
+	}
+}
+
+func inferSchema[T any]() *jsonschema.Schema {
+	s, err := jsonschema.For[T](nil)
+	if err != nil {
+		panic(fmt.Sprintf("output schema of %T: %v", *new(T), err))
+	}
+	adjustSchema(reflect.TypeFor[T](), s)
+	return s
+}
+
+var centsType = reflect.TypeFor[money.Cents]()
+
+func adjustSchema(t reflect.Type, s *jsonschema.Schema) {
+	if s == nil {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == centsType {
+		if s.Type != "" {
+			s.Type = "number"
+		}
+		for i, typ := range s.Types {
+			if typ == "integer" {
+				s.Types[i] = "number"
+			}
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for _, field := range reflect.VisibleFields(t) {
+			if name, ok := jsonFieldName(field); ok {
+				adjustSchema(field.Type, s.Properties[name])
+			}
+		}
+	case reflect.Slice:
+		adjustSchema(t.Elem(), s.Items)
+		allowNull(s)
+	case reflect.Map:
+		adjustSchema(t.Elem(), s.AdditionalProperties)
+		allowNull(s)
+	}
+}
+
+// jsonFieldName returns the property a struct field is encoded as, false
+// for fields encoding/json leaves out or inlines
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	if field.Anonymous || !field.IsExported() {
+		return "", false
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+func allowNull(s *jsonschema.Schema) {
+	if s.Type != "" {
+		s.Types = []string{"null", s.Type}
+		s.Type = ""
+	}
+}
@@ -0,0 +1,439 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// paymentMethodKinds says what the account number of each kind of payment
+// method holds
+var paymentMethodKinds = map[string]string{
+	"bank":   "account number",
+	"iban":   "IBAN",
+	"paypal": "PayPal address",
+	"crypto": "wallet address",
+	"other":  "account or reference",
+}
+
+func validatePaymentMethodKind(kind string) error {
+	if _, ok := paymentMethodKinds[kind]; !ok {
+		return validationError("invalid payment method kind '%s': must be bank, iban, paypal, crypto or other", kind)
+	}
+	return nil
+}
+
+const paymentMethodColumns = `id, name, kind, currency, bank_name, account_number, routing_number, swift_code, notes, is_default, updated_at`
+
+// loadPaymentMethod returns the decrypted payment method matching where, a
+// condition on payment_methods, or nil when there is none
+func (h *Handler) loadPaymentMethod(ctx context.Context, where string, args ...interface{}) (*models.PaymentMethod, error) {
+	var m models.PaymentMethod
+	err := h.db.QueryRowContext(ctx, `SELECT `+paymentMethodColumns+` FROM payment_methods WHERE `+where+` ORDER BY is_default DESC, id LIMIT 1`, args...).
+		Scan(&m.ID, &m.Name, &m.Kind, &m.Currency, &m.BankName, &m.AccountNumber, &m.RoutingNumber, &m.SwiftCode, &m.Notes, &m.IsDefault, &m.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load payment method: %w", err)
+	}
+	if err := decryptFields(&m.AccountNumber, &m.RoutingNumber, &m.SwiftCode, &m.Notes); err != nil {
+		return nil, fmt.Errorf("failed to decrypt payment method: %w", err)
+	}
+	return &m, nil
+}
+
+// paymentMethodID returns the ID of the payment method with a name, or 0
+// for an empty name
+func (h *Handler) paymentMethodID(ctx context.Context, name string) (int, error) {
+	if name == "" {
+		return 0, nil
+	}
+	var id int
+	err := h.db.QueryRowContext(ctx, "SELECT id FROM payment_methods WHERE name = ?", name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, notFoundError("payment_method", "payment method '%s' not found; see list_payment_methods", name)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find payment method: %w", err)
+	}
+	return id, nil
+}
+
+// invoicePaymentDetails returns the payment details to print on a client's
+// invoice in currency billing the given contracts: the payment method named,
+// else the one set on the contracts or the client, else the client's own
+// payment details, else a payment method in the invoice's currency or the
+// default one. It returns nil when none are configured.
+func (h *Handler) invoicePaymentDetails(ctx context.Context, clientID int, contractIDs []int, currency, name string) (*models.PaymentDetails, error) {
+	legacy, err := h.loadPaymentDetails(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	var method *models.PaymentMethod
+	if name != "" {
+		if method, err = h.loadPaymentMethod(ctx, "name = ?", name); err != nil {
+			return nil, err
+		}
+		if method == nil {
+			return nil, notFoundError("payment_method", "payment method '%s' not found; see list_payment_methods", name)
+		}
+	}
+
+	// Contracts billed together must agree on where they are paid
+	if method == nil && len(contractIDs) > 0 {
+		query := "SELECT DISTINCT payment_method_id FROM contracts WHERE payment_method_id IS NOT NULL AND id IN (?" +
+			strings.Repeat(", ?", len(contractIDs)-1) + ")"
+		args := make([]interface{}, len(contractIDs))
+		for i, id := range contractIDs {
+			args[i] = id
+		}
+		rows, err := h.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load contract payment methods: %w", err)
+		}
+		var ids []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan payment method: %w", err)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if len(ids) > 1 {
+			return nil, conflictError("the contracts on this invoice are paid into different payment methods; pass payment_method to choose one")
+		}
+		if len(ids) == 1 {
+			if method, err = h.loadPaymentMethod(ctx, "id = ?", ids[0]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if method == nil {
+		if method, err = h.loadPaymentMethod(ctx, "id = (SELECT payment_method_id FROM clients WHERE id = ?)", clientID); err != nil {
+			return nil, err
+		}
+	}
+	// A client's own payment details take precedence over the business's
+	// general accounts
+	if method == nil && legacy != nil {
+		return legacy, nil
+	}
+	if method == nil {
+		if method, err = h.loadPaymentMethod(ctx, "currency = ?", currency); err != nil {
+			return nil, err
+		}
+	}
+	if method == nil {
+		if method, err = h.loadPaymentMethod(ctx, "is_default"); err != nil {
+			return nil, err
+		}
+	}
+	if method == nil {
+		return nil, nil
+	}
+
+	details := &models.PaymentDetails{
+		ClientID:      clientID,
+		BankName:      method.BankName,
+		AccountNumber: method.AccountNumber,
+		RoutingNumber: method.RoutingNumber,
+		SwiftCode:     method.SwiftCode,
+		Notes:         method.Notes,
+		UpdatedAt:     method.UpdatedAt,
+		Method:        method.Name,
+		Kind:          method.Kind,
+	}
+	if legacy != nil {
+		details.PaymentTerms = legacy.PaymentTerms
+	}
+	return details, nil
+}
+
+// maskPaymentMethod returns a copy of a payment method safe to show in tool
+// output, like maskPaymentDetails
+func maskPaymentMethod(m models.PaymentMethod) models.PaymentMethod {
+	if m.Kind != "paypal" {
+		m.AccountNumber = maskNumber(m.AccountNumber)
+	}
+	m.RoutingNumber = maskNumber(m.RoutingNumber)
+	if m.Notes != "" {
+		m.Notes = "(hidden)"
+	}
+	return m
+}
+
+// registerPaymentMethodTools registers the tools managing the accounts
+// invoices ask to be paid into
+func registerPaymentMethodTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Set Payment Method tool
+	type setPaymentMethodArgs struct {
+		Name          string `json:"name" jsonschema:"Name of the payment method, e.g. 'USD ACH' or 'EUR IBAN'"`
+		Kind          string `json:"kind,omitempty" jsonschema:"bank, iban, paypal, crypto or other (default: bank)"`
+		Currency      string `json:"currency,omitempty" jsonschema:"Currency paid into it; invoices in this currency use it unless their client or contract has another (optional)"`
+		BankName      string `json:"bank_name,omitempty" jsonschema:"Bank name, or the network of a crypto wallet"`
+		AccountNumber string `json:"account_number,omitempty" jsonschema:"Account number, IBAN, PayPal address or wallet address"`
+		RoutingNumber string `json:"routing_number,omitempty" jsonschema:"Routing number"`
+		SwiftCode     string `json:"swift_code,omitempty" jsonschema:"SWIFT/BIC code"`
+		Notes         string `json:"notes,omitempty" jsonschema:"Additional payment notes"`
+		Default       bool   `json:"default,omitempty" jsonschema:"Use it for invoices no other payment method applies to (default: false)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_payment_method",
+		Description: "Add or replace an account invoices can ask to be paid into, such as a USD bank account, a EUR IBAN, PayPal or a crypto wallet; assign it to clients or contracts with edit_client and edit_contract",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setPaymentMethodArgs) (*mcp.CallToolResult, *models.PaymentMethod, error) {
+		name := strings.TrimSpace(args.Name)
+		if name == "" {
+			return nil, nil, validationError("name is required")
+		}
+		if args.Kind == "" {
+			args.Kind = "bank"
+		}
+		if err := validatePaymentMethodKind(args.Kind); err != nil {
+			return nil, nil, err
+		}
+		currency := strings.ToUpper(strings.TrimSpace(args.Currency))
+		if currency != "" {
+			if err := validateCurrencyCode(currency); err != nil {
+				return nil, nil, validationError("invalid currency: %w", err)
+			}
+		}
+		if args.AccountNumber == "" {
+			return nil, nil, validationError("account_number is required: the %s to pay into", paymentMethodKinds[args.Kind])
+		}
+
+		// Bank numbers are only stored encrypted
+		accountNumber, routingNumber, swiftCode, notes := args.AccountNumber, args.RoutingNumber, args.SwiftCode, args.Notes
+		if err := encryptFields(&accountNumber, &routingNumber, &swiftCode, &notes); err != nil {
+			return nil, nil, err
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		if args.Default {
+			if _, err := tx.ExecContext(ctx, "UPDATE payment_methods SET is_default = FALSE WHERE is_default"); err != nil {
+				return nil, nil, fmt.Errorf("failed to clear the default payment method: %w", err)
+			}
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO payment_methods (name, kind, currency, bank_name, account_number, routing_number, swift_code, notes, is_default)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(name) DO UPDATE SET
+				kind = excluded.kind,
+				currency = excluded.currency,
+				bank_name = excluded.bank_name,
+				account_number = excluded.account_number,
+				routing_number = excluded.routing_number,
+				swift_code = excluded.swift_code,
+				notes = excluded.notes,
+				is_default = excluded.is_default,
+				updated_at = CURRENT_TIMESTAMP
+		`, name, args.Kind, currency, args.BankName, accountNumber, routingNumber, swiftCode, notes, args.Default)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to save payment method: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		method, err := h.loadPaymentMethod(ctx, "name = ?", name)
+		if err != nil {
+			return nil, nil, err
+		}
+		masked := maskPaymentMethod(*method)
+
+		text := fmt.Sprintf("Payment method '%s' saved (%s %s)", method.Name, method.Kind, masked.AccountNumber)
+		if method.IsDefault {
+			text += "; it is the default"
+		}
+
+		// Bank numbers are masked in results, as when listing a client
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &masked, nil
+	})
+
+	// List Payment Methods tool
+	type listPaymentMethodsArgs struct {
+		ShowBanking bool `json:"show_banking,omitempty" jsonschema:"Show full account and routing numbers and notes (default: false, only the last 4 digits)"`
+	}
+
+	type paymentMethodListing struct {
+		models.PaymentMethod
+		Clients   []string `json:"clients,omitempty" jsonschema:"Clients invoiced with it"`
+		Contracts []string `json:"contracts,omitempty" jsonschema:"Contracts invoiced with it"`
+	}
+
+	type listPaymentMethodsResult struct {
+		Methods []paymentMethodListing `json:"payment_methods"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_payment_methods",
+		Description: "List the accounts invoices can ask to be paid into, with the clients and contracts using each",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listPaymentMethodsArgs) (*mcp.CallToolResult, *listPaymentMethodsResult, error) {
+		rows, err := db.QueryContext(ctx, `SELECT `+paymentMethodColumns+` FROM payment_methods ORDER BY name`)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list payment methods: %w", err)
+		}
+		result := &listPaymentMethodsResult{Methods: []paymentMethodListing{}}
+		byID := map[int]*paymentMethodListing{}
+		for rows.Next() {
+			var m models.PaymentMethod
+			if err := rows.Scan(&m.ID, &m.Name, &m.Kind, &m.Currency, &m.BankName, &m.AccountNumber, &m.RoutingNumber,
+				&m.SwiftCode, &m.Notes, &m.IsDefault, &m.UpdatedAt); err != nil {
+				rows.Close()
+				return nil, nil, fmt.Errorf("failed to scan payment method: %w", err)
+			}
+			if err := decryptFields(&m.AccountNumber, &m.RoutingNumber, &m.SwiftCode, &m.Notes); err != nil {
+				rows.Close()
+				return nil, nil, fmt.Errorf("failed to decrypt payment method: %w", err)
+			}
+			if !args.ShowBanking {
+				m = maskPaymentMethod(m)
+			}
+			result.Methods = append(result.Methods, paymentMethodListing{PaymentMethod: m})
+		}
+		rows.Close()
+		for i := range result.Methods {
+			byID[result.Methods[i].ID] = &result.Methods[i]
+		}
+
+		rows, err = db.QueryContext(ctx, `
+			SELECT payment_method_id, 'client', name FROM clients WHERE payment_method_id IS NOT NULL
+			UNION ALL
+			SELECT payment_method_id, 'contract', contract_number FROM contracts WHERE payment_method_id IS NOT NULL
+		`)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list payment method use: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int
+			var kind, name string
+			if err := rows.Scan(&id, &kind, &name); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan payment method use: %w", err)
+			}
+			if m := byID[id]; m != nil && kind == "client" {
+				m.Clients = append(m.Clients, name)
+			} else if m != nil {
+				m.Contracts = append(m.Contracts, name)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		if len(result.Methods) == 0 {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: "No payment methods configured. Use set_payment_method to add the accounts you are paid into; until then invoices use each client's set_payment_details."},
+				},
+			}, result, nil
+		}
+
+		text := "Payment methods:\n"
+		for _, m := range result.Methods {
+			sort.Strings(m.Clients)
+			sort.Strings(m.Contracts)
+			text += fmt.Sprintf("- %s (%s", m.Name, m.Kind)
+			if m.Currency != "" {
+				text += ", " + m.Currency
+			}
+			text += ")"
+			if m.BankName != "" {
+				text += " " + m.BankName
+			}
+			text += " " + m.AccountNumber
+			if m.IsDefault {
+				text += " [default]"
+			}
+			if len(m.Clients) > 0 {
+				text += "; clients: " + strings.Join(m.Clients, ", ")
+			}
+			if len(m.Contracts) > 0 {
+				text += "; contracts: " + strings.Join(m.Contracts, ", ")
+			}
+			text += "\n"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+
+	// Delete Payment Method tool
+	type deletePaymentMethodArgs struct {
+		Name string `json:"name" jsonschema:"Name of the payment method to delete"`
+	}
+
+	type deletePaymentMethodResult struct {
+		Name      string `json:"name"`
+		Clients   int    `json:"clients" jsonschema:"Clients that no longer have a payment method of their own"`
+		Contracts int    `json:"contracts" jsonschema:"Contracts that no longer have a payment method of their own"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "delete_payment_method",
+		Description: "Delete a payment method; clients and contracts using it fall back to the other payment methods",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args deletePaymentMethodArgs) (*mcp.CallToolResult, *deletePaymentMethodResult, error) {
+		id, err := h.paymentMethodID(ctx, args.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if id == 0 {
+			return nil, nil, validationError("name is required")
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		result := &deletePaymentMethodResult{Name: args.Name}
+		for table, count := range map[string]*int{"clients": &result.Clients, "contracts": &result.Contracts} {
+			res, err := tx.ExecContext(ctx, "UPDATE "+table+" SET payment_method_id = NULL WHERE payment_method_id = ?", id)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to unassign payment method: %w", err)
+			}
+			n, _ := res.RowsAffected()
+			*count = int(n)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM payment_methods WHERE id = ?", id); err != nil {
+			return nil, nil, fmt.Errorf("failed to delete payment method: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		text := fmt.Sprintf("Deleted payment method '%s'", args.Name)
+		if result.Clients > 0 || result.Contracts > 0 {
+			text += fmt.Sprintf("; %d clients and %d contracts no longer have one of their own", result.Clients, result.Contracts)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+}
@@ -0,0 +1,285 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/austin/hours-mcp/internal/paypal"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// payPalItemNameLimit is the longest item name PayPal accepts
+const payPalItemNameLimit = 200
+
+// payPalInvoice builds the PayPal draft for an invoice. Its lines, tax and
+// rounding included, become items; withholding and any negative lines are
+// taken off as a discount, so PayPal asks for the same amount due.
+func payPalInvoice(inv *exportInvoice, business *models.BusinessInfo, withholdingNote string) *paypal.Invoice {
+	amount := func(c money.Cents) paypal.Money {
+		return paypal.Money{CurrencyCode: inv.currency, Value: c.String()}
+	}
+
+	invoice := &paypal.Invoice{
+		Detail: paypal.Detail{
+			InvoiceNumber:      inv.number,
+			InvoiceDate:        inv.issueDate.Format("2006-01-02"),
+			CurrencyCode:       inv.currency,
+			TermsAndConditions: inv.paymentTerms,
+			PaymentTerm:        &paypal.PaymentTerm{DueDate: inv.dueDate.Format("2006-01-02")},
+		},
+		PrimaryRecipients: []paypal.Recipient{{
+			BillingInfo: paypal.BillingInfo{BusinessName: inv.clientName, EmailAddress: inv.email},
+		}},
+		Items: []paypal.Item{},
+	}
+	if business != nil {
+		invoice.Invoicer = &paypal.Invoicer{BusinessName: business.BusinessName, Website: business.Website}
+	}
+
+	discount := inv.withholding
+	for _, line := range inv.lines {
+		if line.amount < 0 {
+			discount -= line.amount
+			continue
+		}
+		item := paypal.Item{
+			Name:       line.description,
+			Quantity:   strconv.FormatFloat(line.quantity, 'f', -1, 64),
+			UnitAmount: amount(line.rate),
+		}
+		// PayPal prices each item itself; a line whose amount doesn't come
+		// out of its hours and rate, such as one summed from entries
+		// rounded one by one, is billed as a single unit instead
+		if money.ForHours(line.rate, line.quantity) != line.amount {
+			item.Name = fmt.Sprintf("%s, %.2f hours", line.description, line.quantity)
+			item.Quantity = "1"
+			item.UnitAmount = amount(line.amount)
+		}
+		if runes := []rune(item.Name); len(runes) > payPalItemNameLimit {
+			item.Name = string(runes[:payPalItemNameLimit])
+		}
+		invoice.Items = append(invoice.Items, item)
+	}
+	if discount > 0 {
+		invoice.Amount = &paypal.Amount{Breakdown: paypal.Breakdown{
+			Discount: &paypal.Discount{InvoiceDiscount: paypal.InvoiceDiscount{Amount: amount(discount)}},
+		}}
+	}
+	if inv.withholding > 0 {
+		invoice.Detail.Note = fmt.Sprintf("%s: %s", strings.TrimRight(withholdingNote, "."), inv.withholding.Format(inv.currency))
+	}
+	return invoice
+}
+
+// registerPayPalTools registers the tools that bill clients through PayPal
+// invoicing
+func registerPayPalTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Set PayPal Config tool
+	type setPayPalConfigArgs struct {
+		ClientID    string `json:"client_id" jsonschema:"Client ID of a PayPal REST app with the Invoicing feature, from the PayPal developer dashboard"`
+		Secret      string `json:"secret,omitempty" jsonschema:"The app's secret, stored outside the database (optional, keeps the stored one)"`
+		Environment string `json:"environment,omitempty" jsonschema:"live (default) or sandbox"`
+	}
+
+	type setPayPalConfigResult struct {
+		ClientID     string `json:"client_id"`
+		Environment  string `json:"environment"`
+		SecretStored bool   `json:"secret_stored"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_paypal_config",
+		Description: "Set the PayPal REST app export_paypal_invoice creates invoices with; the secret is kept outside the database and never shown",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setPayPalConfigArgs) (*mcp.CallToolResult, *setPayPalConfigResult, error) {
+		clientID := strings.TrimSpace(args.ClientID)
+		if clientID == "" {
+			return nil, nil, validationError("client_id is required")
+		}
+		environment := strings.ToLower(strings.TrimSpace(args.Environment))
+		if environment == "" {
+			environment = "live"
+		}
+		if err := knownSettings["paypal_environment"].validate(environment); err != nil {
+			return nil, nil, validationError("invalid environment: %w", err)
+		}
+
+		for key, value := range map[string]string{"paypal_client_id": clientID, "paypal_environment": environment} {
+			_, err := db.ExecContext(ctx, `
+				INSERT INTO settings (key, value, updated_at)
+				VALUES (?, ?, ?)
+				ON CONFLICT(key) DO UPDATE SET
+					value = excluded.value,
+					updated_at = excluded.updated_at
+			`, key, value, time.Now())
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to save setting: %w", err)
+			}
+		}
+		if args.Secret != "" {
+			if err := paypal.SaveSecret(strings.TrimSpace(args.Secret)); err != nil {
+				return nil, nil, err
+			}
+		}
+		secret, err := paypal.LoadSecret()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		text := fmt.Sprintf("PayPal configured: client ID %s (%s)", clientID, environment)
+		if secret == "" {
+			text += "\nNo secret is stored yet; pass secret to create invoices in PayPal"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &setPayPalConfigResult{ClientID: clientID, Environment: environment, SecretStored: secret != ""}, nil
+	})
+
+	// Export PayPal Invoice tool
+	type exportPayPalInvoiceArgs struct {
+		InvoiceNumber string `json:"invoice_number" jsonschema:"Invoice number to bill through PayPal"`
+		Send          bool   `json:"send,omitempty" jsonschema:"Send the PayPal invoice, which PayPal emails to the client's primary recipient (default: false, only creates a draft)"`
+	}
+
+	type exportPayPalInvoiceResult struct {
+		InvoiceNumber   string      `json:"invoice_number"`
+		FilePath        string      `json:"file_path" jsonschema:"The PayPal invoice request, as JSON"`
+		PayPalInvoiceID string      `json:"paypal_invoice_id,omitempty" jsonschema:"ID of the invoice in PayPal, once created there"`
+		AmountDue       money.Cents `json:"amount_due"`
+		Currency        string      `json:"currency"`
+		Sent            bool        `json:"sent"`
+		Warning         string      `json:"warning,omitempty"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_paypal_invoice",
+		Description: "Bill an invoice through PayPal invoicing for clients who pay by PayPal: writes it as a PayPal Invoicing API request and, once set_paypal_config is done, creates it in PayPal as a draft, or sends it with send=true",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args exportPayPalInvoiceArgs) (*mcp.CallToolResult, *exportPayPalInvoiceResult, error) {
+		inv, err := h.store.Invoices.ByNumber(ctx, args.InvoiceNumber)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("invoice", "invoice %s not found", args.InvoiceNumber)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to find invoice: %w", err)
+		}
+		switch inv.Status {
+		case "paid", "written_off", "cancelled":
+			return nil, nil, conflictError("invoice %s is %s, so there is nothing to bill", inv.InvoiceNumber, strings.ReplaceAll(inv.Status, "_", " "))
+		}
+
+		exports, err := h.loadExportInvoices(ctx, inv.IssueDate, inv.IssueDate)
+		if err != nil {
+			return nil, nil, err
+		}
+		var export *exportInvoice
+		for _, e := range exports {
+			if e.id == inv.ID {
+				export = e
+			}
+		}
+		if export == nil {
+			return nil, nil, notFoundError("invoice", "invoice %s not found", args.InvoiceNumber)
+		}
+		if args.Send && export.email == "" {
+			return nil, nil, validationError("%s has no recipient for PayPal to send the invoice to; add one with add_recipient", inv.Client.Name)
+		}
+		business, err := h.loadBusinessInfo(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		invoice := payPalInvoice(export, business, h.getSetting(ctx, "withholding_note"))
+		data, err := json.MarshalIndent(invoice, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode PayPal invoice: %w", err)
+		}
+		homeDir, _ := os.UserHomeDir()
+		path := filepath.Join(homeDir, "Downloads", "paypal_invoice_"+inv.InvoiceNumber+".json")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return nil, nil, fmt.Errorf("failed to write PayPal invoice: %w", err)
+		}
+
+		result := &exportPayPalInvoiceResult{
+			InvoiceNumber: inv.InvoiceNumber,
+			FilePath:      path,
+			AmountDue:     export.total - export.withholding,
+			Currency:      export.currency,
+		}
+		summary := fmt.Sprintf("%s for %s, %s due %s", inv.InvoiceNumber, inv.Client.Name,
+			result.AmountDue.Format(result.Currency), export.dueDate.Format("2006-01-02"))
+
+		clientID := h.getSetting(ctx, "paypal_client_id")
+		secret, err := paypal.LoadSecret()
+		if err != nil {
+			return nil, nil, err
+		}
+		if clientID == "" || secret == "" {
+			if args.Send {
+				return nil, nil, notConfiguredError("paypal", "PayPal is not configured. Use 'set_paypal_config' with a REST app's client ID and secret to send invoices")
+			}
+			text := fmt.Sprintf("Wrote PayPal invoice %s to %s\n", summary, path)
+			text += "PayPal is not configured, so nothing was created there; use set_paypal_config to create and send invoices directly\n"
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: text},
+				},
+			}, result, nil
+		}
+		client := paypal.New(clientID, secret, h.getSetting(ctx, "paypal_environment") == "sandbox")
+
+		if err := db.QueryRowContext(ctx, "SELECT paypal_invoice_id FROM invoices WHERE id = ?", inv.ID).Scan(&result.PayPalInvoiceID); err != nil {
+			return nil, nil, fmt.Errorf("failed to load invoice: %w", err)
+		}
+		var text string
+		if result.PayPalInvoiceID == "" {
+			created, err := client.CreateDraft(ctx, invoice)
+			if err != nil {
+				return nil, nil, err
+			}
+			result.PayPalInvoiceID = created.ID
+			if _, err := db.ExecContext(ctx, "UPDATE invoices SET paypal_invoice_id = ? WHERE id = ?", created.ID, inv.ID); err != nil {
+				return nil, nil, fmt.Errorf("failed to save PayPal invoice ID: %w", err)
+			}
+			text = fmt.Sprintf("Created PayPal invoice %s: %s\n", created.ID, summary)
+			if created.Amount.Value != "" && created.Amount.Value != result.AmountDue.String() {
+				result.Warning = fmt.Sprintf("PayPal totals it %s %s rather than %s; check it before sending",
+					created.Amount.CurrencyCode, created.Amount.Value, result.AmountDue.Format(result.Currency))
+				text += "Warning: " + result.Warning + "\n"
+			}
+		} else if !args.Send {
+			return nil, nil, conflictError("invoice %s was already created in PayPal as %s; pass send=true to send it", inv.InvoiceNumber, result.PayPalInvoiceID)
+		} else {
+			text = fmt.Sprintf("PayPal invoice %s: %s\n", result.PayPalInvoiceID, summary)
+		}
+
+		if args.Send && result.Warning == "" {
+			if err := client.Send(ctx, result.PayPalInvoiceID); err != nil {
+				return nil, nil, err
+			}
+			if err := h.store.Invoices.MarkSent(ctx, inv.ID, time.Now(), export.email, deliveryPayPal); err != nil {
+				return nil, nil, fmt.Errorf("failed to record delivery: %w", err)
+			}
+			result.Sent = true
+			text += fmt.Sprintf("Sent: PayPal emailed it to %s, and it is recorded as sent by paypal\n", export.email)
+		} else {
+			text += "It is a draft the client can't see yet; run again with send=true to send it\n"
+		}
+		text += fmt.Sprintf("Request written to %s\n", path)
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+}
@@ -0,0 +1,350 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
+)
+
+// Rate rule kinds. Entries only record a date and hours, so premiums can
+// depend on the day and the hours worked that day but not the time of day.
+const (
+	rateRuleWeekend  = "weekend"
+	rateRuleHoliday  = "holiday"
+	rateRuleOvertime = "overtime"
+)
+
+// rateKindNoCharge items list hours given at no cost, marked N/C
+const rateKindNoCharge = "no_charge"
+
+var rateRuleLabels = map[string]string{
+	rateRuleWeekend:  "Weekend",
+	rateRuleHoliday:  "Holiday",
+	rateRuleOvertime: "Overtime",
+}
+
+func validateRateRuleKind(kind string) error {
+	if _, ok := rateRuleLabels[kind]; !ok {
+		return validationError("invalid rule kind '%s': must be weekend, holiday or overtime", kind)
+	}
+	return nil
+}
+
+// rateLabel describes a premium for invoices, e.g. "Weekend (1.5x)"
+func rateLabel(kind string, multiplier float64) string {
+	switch kind {
+	case "":
+		return ""
+	case rateKindOverage:
+		return "Overage"
+	case rateKindNoCharge:
+		return "No charge"
+	}
+	return fmt.Sprintf("%s (%gx)", rateRuleLabels[kind], multiplier)
+}
+
+// serviceRateLabel prefixes a premium's label with the service the hours
+// were for, e.g. "Advisory, Weekend (1.5x)"
+func serviceRateLabel(service, label string) string {
+	switch {
+	case service == "":
+		return label
+	case label == "":
+		return service
+	}
+	return service + ", " + label
+}
+
+type rateRule struct {
+	Kind       string  `json:"kind"`
+	Multiplier float64 `json:"multiplier"`
+	DailyHours float64 `json:"daily_hours,omitempty"`
+}
+
+// contractRateRules loads the rate rules of every contract, by contract ID
+func (h *Handler) contractRateRules(ctx context.Context) (map[int]map[string]rateRule, error) {
+	rows, err := h.db.QueryContext(ctx, "SELECT contract_id, kind, multiplier, daily_hours FROM contract_rate_rules")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rate rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := map[int]map[string]rateRule{}
+	for rows.Next() {
+		var contractID int
+		var r rateRule
+		if err := rows.Scan(&contractID, &r.Kind, &r.Multiplier, &r.DailyHours); err != nil {
+			return nil, fmt.Errorf("failed to scan rate rule: %w", err)
+		}
+		if rules[contractID] == nil {
+			rules[contractID] = map[string]rateRule{}
+		}
+		rules[contractID][r.Kind] = r
+	}
+	return rules, rows.Err()
+}
+
+// priceEntries prices time entries under their contracts' rate rules. Each
+// entry's Contract must carry the hourly rate in effect on the entry date,
+// or the contract's rate for the entry's service.
+// Holidays and days outside the work week take their premium for the whole
+// day, hours past a contract's daily overtime threshold take the overtime
+// premium, and where several premiums apply the highest one wins. No-charge
+// entries are one item at no cost, outside premiums and retainers. Items are
+// returned in entry order.
+func (h *Handler) priceEntries(ctx context.Context, entries []models.TimeEntry) ([]models.InvoiceItem, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	rules, err := h.contractRateRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	workWeek, err := parseWorkWeek(h.getSetting(ctx, "work_week"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid work_week setting: %w", err)
+	}
+
+	first, last := entries[0].Date, entries[0].Date
+	for _, e := range entries {
+		if e.Date.Before(first) {
+			first = e.Date
+		}
+		if e.Date.After(last) {
+			last = e.Date
+		}
+	}
+	holidays, err := h.getHolidays(ctx, first, last)
+	if err != nil {
+		return nil, err
+	}
+	retainers, err := h.loadRetainers(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	included, err := h.retainerIncluded(ctx, entries, retainers)
+	if err != nil {
+		return nil, err
+	}
+
+	// Overtime counts hours per contract and day in date order
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return entries[order[a]].Date.Before(entries[order[b]].Date)
+	})
+
+	itemsByEntry := make([][]models.InvoiceItem, len(entries))
+	worked := map[string]float64{}
+	for _, i := range order {
+		e := entries[i]
+		if e.Contract == nil {
+			return nil, fmt.Errorf("time entry %s has no contract", e.ID)
+		}
+		if e.NoCharge {
+			itemsByEntry[i] = append(itemsByEntry[i], models.InvoiceItem{
+				TimeEntryID: e.ID,
+				ContractID:  e.Contract.ID,
+				Date:        e.Date,
+				Description: e.Description,
+				Hours:       e.Hours,
+				RateKind:    rateKindNoCharge,
+				RateLabel:   rateLabel(rateKindNoCharge, 0),
+			})
+			continue
+		}
+		contractRules := rules[e.Contract.ID]
+		day := e.Date.Format("2006-01-02")
+
+		dayKind, dayMultiplier := "", 1.0
+		premium := func(kind string) {
+			if r, ok := contractRules[kind]; ok && r.Multiplier > dayMultiplier {
+				dayKind, dayMultiplier = kind, r.Multiplier
+			}
+		}
+		if holidays[day] != "" {
+			premium(rateRuleHoliday)
+		}
+		if !workWeek[e.Date.Weekday()] {
+			premium(rateRuleWeekend)
+		}
+
+		regular, overtime := e.Hours, 0.0
+		if r, ok := contractRules[rateRuleOvertime]; ok {
+			key := fmt.Sprintf("%d|%s", e.Contract.ID, day)
+			remaining := max(0, r.DailyHours-worked[key])
+			if e.Hours > remaining {
+				regular, overtime = remaining, e.Hours-remaining
+			}
+			worked[key] += e.Hours
+		}
+
+		baseRate := e.Contract.HourlyRate
+		item := func(hours float64, kind string, multiplier float64) models.InvoiceItem {
+			rate := baseRate.Times(multiplier)
+			return models.InvoiceItem{
+				TimeEntryID: e.ID,
+				ContractID:  e.Contract.ID,
+				Date:        e.Date,
+				Description: e.Description,
+				Hours:       hours,
+				RateKind:    kind,
+				RateLabel:   serviceRateLabel(e.Service, rateLabel(kind, multiplier)),
+				Multiplier:  multiplier,
+				Rate:        rate,
+				Amount:      money.ForHours(rate, hours),
+			}
+		}
+
+		// Retainer hours covered by the monthly fee come first; the rest
+		// is overage, billed at the overage rate plus any premium
+		if r := retainers[e.Contract.ID]; r != nil {
+			covered := included[e.ID]
+			if covered > 0 {
+				itemsByEntry[i] = append(itemsByEntry[i], models.InvoiceItem{
+					TimeEntryID: e.ID,
+					ContractID:  e.Contract.ID,
+					Date:        e.Date,
+					Description: e.Description,
+					Hours:       covered,
+					RateKind:    rateKindIncluded,
+					RateLabel:   "Included in retainer",
+				})
+			}
+			if r.overageRate > 0 {
+				baseRate = r.overageRate
+			}
+			if dayKind == "" {
+				dayKind = rateKindOverage
+			}
+			regular, overtime = max(0, regular-covered), overtime-max(0, covered-regular)
+			if regular == 0 && overtime == 0 {
+				continue
+			}
+		}
+
+		if regular > 0 || overtime == 0 {
+			itemsByEntry[i] = append(itemsByEntry[i], item(regular, dayKind, dayMultiplier))
+		}
+		if overtime > 0 {
+			kind, multiplier := rateRuleOvertime, contractRules[rateRuleOvertime].Multiplier
+			if dayMultiplier > multiplier {
+				kind, multiplier = dayKind, dayMultiplier
+			}
+			itemsByEntry[i] = append(itemsByEntry[i], item(overtime, kind, multiplier))
+		}
+	}
+
+	var items []models.InvoiceItem
+	for _, entryItems := range itemsByEntry {
+		items = append(items, entryItems...)
+	}
+	return items, nil
+}
+
+// priceStoredEntries prices the time entries matching filter, a condition on
+// the time entry te and its contract ct
+func (h *Handler) priceStoredEntries(ctx context.Context, filter string, args ...interface{}) ([]models.InvoiceItem, error) {
+	return h.priceEntriesUnder(ctx, "te.contract_id", filter, args...)
+}
+
+// priceMove prices the time entries with the given IDs under their own
+// contract and under the contract contractID they are moved to
+func (h *Handler) priceMove(ctx context.Context, contractID int, ids []string) (from, to []models.InvoiceItem, err error) {
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+	filter := "te.id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	if from, err = h.priceStoredEntries(ctx, filter, args...); err != nil {
+		return nil, nil, err
+	}
+	to, err = h.priceEntriesUnder(ctx, "?", filter, append([]interface{}{contractID}, args...)...)
+	return from, to, err
+}
+
+// priceEntriesUnder prices the time entries matching filter under the
+// contract whose ID is the SQL expression contractID
+func (h *Handler) priceEntriesUnder(ctx context.Context, contractID, filter string, args ...interface{}) ([]models.InvoiceItem, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT te.id, te.date, te.hours, te.no_charge, COALESCE(s.name, ''), ct.id, `+entryRateSQL+`
+		FROM time_entries te
+		JOIN contracts ct ON ct.id = `+contractID+`
+		LEFT JOIN services s ON te.service_id = s.id
+		WHERE `+filter+`
+		ORDER BY te.date`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load time entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.TimeEntry
+	for rows.Next() {
+		var e models.TimeEntry
+		contract := &models.Contract{}
+		if err := rows.Scan(&e.ID, &e.Date, &e.Hours, &e.NoCharge, &e.Service, &contract.ID, &contract.HourlyRate); err != nil {
+			return nil, fmt.Errorf("failed to scan time entry: %w", err)
+		}
+		e.ContractID = contract.ID
+		e.Contract = contract
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return h.priceEntries(ctx, entries)
+}
+
+// invoiceLine is a group of invoice items billed under one contract at one
+// rate, as stored in invoice_lines and exported to accounting software
+type invoiceLine struct {
+	contractID int
+	rateKind   string
+	rateLabel  string
+	period     string
+	hours      float64
+	rate       money.Cents
+	amount     money.Cents
+}
+
+// groupInvoiceItems sums items into lines per contract, rate, service and
+// premium, with one line per month of retainer fees and one line per
+// contract for expenses, in order of first appearance
+func groupInvoiceItems(items []models.InvoiceItem) []*invoiceLine {
+	var lines []*invoiceLine
+	byKey := map[string]*invoiceLine{}
+	for _, item := range items {
+		period := ""
+		if item.RateKind == rateKindRetainerFee {
+			period = item.Date.Format("2006-01")
+		}
+		rate := item.Rate
+		if item.RateKind == rateKindExpense {
+			rate = 0
+		}
+		key := strings.Join([]string{fmt.Sprint(item.ContractID), item.RateKind, item.RateLabel, rate.String(), period}, "|")
+		line := byKey[key]
+		if line == nil {
+			line = &invoiceLine{contractID: item.ContractID, rateKind: item.RateKind, rateLabel: item.RateLabel, period: period, rate: rate}
+			byKey[key] = line
+			lines = append(lines, line)
+		}
+		line.hours += item.Hours
+		line.amount += item.Amount
+		// Expenses are a single flat amount per contract
+		if item.RateKind == rateKindExpense {
+			line.rate = line.amount
+		}
+	}
+	return lines
+}
@@ -0,0 +1,406 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/database"
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// clientDataExportFormat identifies the file written by export_client_data
+const clientDataExportFormat = "hours-mcp-client-data/1"
+
+// invoiceDelivery is where one invoice was sent
+type invoiceDelivery struct {
+	InvoiceNumber  string     `json:"invoice_number"`
+	IssueDate      time.Time  `json:"issue_date"`
+	CC             string     `json:"cc,omitempty"`
+	SentTo         string     `json:"sent_to,omitempty"`
+	DeliveryMethod string     `json:"delivery_method,omitempty"`
+	SentAt         *time.Time `json:"sent_at,omitempty"`
+}
+
+// emailRecord is one email about a client's invoice from the email log
+type emailRecord struct {
+	InvoiceNumber string    `json:"invoice_number"`
+	Recipients    string    `json:"recipients"`
+	Subject       string    `json:"subject"`
+	Status        string    `json:"status"`
+	Error         string    `json:"error,omitempty"`
+	SentAt        time.Time `json:"sent_at"`
+}
+
+// clientDataExport is the personal data held about a client
+type clientDataExport struct {
+	Format         string                 `json:"format"`
+	ExportedAt     time.Time              `json:"exported_at"`
+	Client         *models.Client         `json:"client"`
+	Aliases        []string               `json:"aliases"`
+	Recipients     []models.Recipient     `json:"recipients"`
+	PaymentDetails *models.PaymentDetails `json:"payment_details,omitempty"`
+	Deliveries     []invoiceDelivery      `json:"invoice_deliveries"`
+	Emails         []emailRecord          `json:"emails"`
+	InvoiceNotes   []models.InvoiceNote   `json:"invoice_notes"`
+}
+
+// clientPersonalData is the personal data about a client kept outside its
+// own record: for each kind, a query counting the rows and the statement
+// erasing them, both taking the client's ID. Invoices keep their numbers
+// and amounts; only where they were sent is cleared.
+var clientPersonalData = []struct {
+	label string
+	count string
+	erase string
+}{
+	{"aliases",
+		"SELECT COUNT(*) FROM client_aliases WHERE client_id = ?",
+		"DELETE FROM client_aliases WHERE client_id = ?"},
+	{"recipients",
+		"SELECT COUNT(*) FROM recipients WHERE client_id = ?",
+		"DELETE FROM recipients WHERE client_id = ?"},
+	{"payment details",
+		"SELECT COUNT(*) FROM payment_details WHERE client_id = ?",
+		"DELETE FROM payment_details WHERE client_id = ?"},
+	{"invoice addresses",
+		"SELECT COUNT(*) FROM invoices WHERE client_id = ? AND (cc != '' OR sent_to != '')",
+		"UPDATE invoices SET cc = '', sent_to = '' WHERE client_id = ?"},
+	{"email log entries",
+		"SELECT COUNT(*) FROM email_log WHERE invoice_id IN (SELECT id FROM invoices WHERE client_id = ?)",
+		"UPDATE email_log SET recipients = '[erased]', error = NULL WHERE invoice_id IN (SELECT id FROM invoices WHERE client_id = ?)"},
+	{"invoice notes",
+		"SELECT COUNT(*) FROM invoice_notes WHERE invoice_id IN (SELECT id FROM invoices WHERE client_id = ?)",
+		"DELETE FROM invoice_notes WHERE invoice_id IN (SELECT id FROM invoices WHERE client_id = ?)"},
+}
+
+// clientAuditScrubs remove the erased data from the audit log's snapshots,
+// each taking the client's ID. Rows of the personal tables lose their
+// snapshots entirely; the client's and its invoices' keep everything but
+// the erased fields, so the accounting history stays readable.
+var clientAuditScrubs = []string{
+	`UPDATE audit_log SET before = NULL, after = NULL, row_key = CASE WHEN table_name = 'client_aliases' THEN '[erased]' ELSE row_key END
+	 WHERE table_name IN ('client_aliases', 'recipients', 'payment_details')
+	   AND ?1 IN (json_extract(before, '$.client_id'), json_extract(after, '$.client_id'))`,
+	`UPDATE audit_log SET
+	   before = json_replace(before, '$.name', 'Erased client ' || ?1, '$.address', '', '$.city', '', '$.state', '', '$.zip_code', '',
+	                         '$.country', '', '$.tax_id', '', '$.notes', '', '$.custom_fields', '{}'),
+	   after = json_replace(after, '$.name', 'Erased client ' || ?1, '$.address', '', '$.city', '', '$.state', '', '$.zip_code', '',
+	                        '$.country', '', '$.tax_id', '', '$.notes', '', '$.custom_fields', '{}')
+	 WHERE table_name = 'clients' AND row_key = CAST(?1 AS TEXT)`,
+	`UPDATE audit_log SET
+	   before = json_replace(before, '$.cc', '', '$.sent_to', ''),
+	   after = json_replace(after, '$.cc', '', '$.sent_to', '')
+	 WHERE table_name = 'invoices'
+	   AND ?1 IN (json_extract(before, '$.client_id'), json_extract(after, '$.client_id'))`,
+	`UPDATE audit_log SET
+	   before = json_replace(before, '$.recipients', '[erased]', '$.error', NULL),
+	   after = json_replace(after, '$.recipients', '[erased]', '$.error', NULL)
+	 WHERE table_name = 'email_log'
+	   AND (json_extract(before, '$.invoice_id') IN (SELECT id FROM invoices WHERE client_id = ?1)
+	     OR json_extract(after, '$.invoice_id') IN (SELECT id FROM invoices WHERE client_id = ?1))`,
+	`UPDATE audit_log SET before = NULL, after = NULL
+	 WHERE table_name = 'invoice_notes'
+	   AND (json_extract(before, '$.invoice_id') IN (SELECT id FROM invoices WHERE client_id = ?1)
+	     OR json_extract(after, '$.invoice_id') IN (SELECT id FROM invoices WHERE client_id = ?1))`,
+}
+
+// registerPrivacyTools registers the tools exporting and erasing the
+// personal data held about a client
+func registerPrivacyTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Export Client Data tool
+	type exportClientDataArgs struct {
+		ClientName string `json:"client_name" jsonschema:"Client whose personal data to export"`
+		FilePath   string `json:"file_path,omitempty" jsonschema:"Where to write the export (default: ~/Downloads/client_data_<id>_YYYY-MM-DD.json)"`
+	}
+
+	type exportClientDataResult struct {
+		FilePath string         `json:"file_path"`
+		Counts   map[string]int `json:"counts" jsonschema:"Records exported per kind of data"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "export_client_data",
+		Description: "Export all personal data held about a client (its record, aliases, recipients, payment details, invoice notes, and where invoices and emails were sent) to a JSON file, e.g. to answer a data access request",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args exportClientDataArgs) (*mcp.CallToolResult, *exportClientDataResult, error) {
+		clientID, err := h.getClientIDByName(ctx, args.ClientName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("client not found: %w", err)
+		}
+
+		export, err := h.clientDataExport(ctx, clientID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		path := expandHome(args.FilePath)
+		if path == "" {
+			homeDir, _ := os.UserHomeDir()
+			path = filepath.Join(homeDir, "Downloads", fmt.Sprintf("client_data_%d_%s.json", clientID, time.Now().Format("2006-01-02")))
+		}
+
+		data, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode export: %w", err)
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return nil, nil, fmt.Errorf("failed to write export: %w", err)
+		}
+
+		counts := map[string]int{
+			"aliases":            len(export.Aliases),
+			"recipients":         len(export.Recipients),
+			"invoice deliveries": len(export.Deliveries),
+			"emails":             len(export.Emails),
+			"invoice notes":      len(export.InvoiceNotes),
+		}
+		if export.PaymentDetails != nil {
+			counts["payment details"] = 1
+		}
+
+		text := fmt.Sprintf("Exported the personal data held about %s to %s\n", export.Client.Name, path)
+		for _, label := range []string{"aliases", "recipients", "payment details", "invoice deliveries", "emails", "invoice notes"} {
+			text += fmt.Sprintf("- %s: %d\n", label, counts[label])
+		}
+		if export.PaymentDetails != nil {
+			text += "The file holds unencrypted payment details; delete it once it has been handed over.\n"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &exportClientDataResult{FilePath: path, Counts: counts}, nil
+	})
+
+	// Erase Client Data tool
+	type eraseClientDataArgs struct {
+		ClientName string `json:"client_name" jsonschema:"Client whose personal data to erase"`
+		Confirm    bool   `json:"confirm,omitempty" jsonschema:"Erase the data (default: false, only shows what would be erased)"`
+	}
+
+	type eraseClientDataResult struct {
+		Confirmed bool           `json:"confirmed" jsonschema:"Whether the data was erased; false for a preview"`
+		NewName   string         `json:"new_name" jsonschema:"The name the client is kept under"`
+		Erase     map[string]int `json:"erase,omitempty" jsonschema:"Records per kind of data that would be erased"`
+		Erased    map[string]int `json:"erased,omitempty" jsonschema:"Records per kind of data erased"`
+		Retained  []string       `json:"retained,omitempty" jsonschema:"Files outside the database that may still hold the client's data"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "erase_client_data",
+		Description: "Anonymize a client on request: replace its name, clear its address, tax ID, notes and custom fields, and remove its aliases, recipients, payment details, invoice notes and the addresses invoices were sent to, including from the audit log. Invoices, time entries, contracts and expenses are kept for accounting. Archives the client, cannot be undone, and only shows what would be erased until run with confirm=true",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args eraseClientDataArgs) (*mcp.CallToolResult, *eraseClientDataResult, error) {
+		clientID, err := h.getClientIDByName(ctx, args.ClientName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("client not found: %w", err)
+		}
+		client, err := h.loadClient(ctx, clientID)
+		if err != nil {
+			return nil, nil, err
+		}
+		newName := fmt.Sprintf("Erased client %d", clientID)
+
+		counts := map[string]int{}
+		var summary []string
+		for _, d := range clientPersonalData {
+			var count int
+			if err := db.QueryRowContext(ctx, d.count, clientID).Scan(&count); err != nil {
+				return nil, nil, fmt.Errorf("failed to count %s: %w", d.label, err)
+			}
+			counts[d.label] = count
+			if count > 0 {
+				summary = append(summary, fmt.Sprintf("%d %s", count, d.label))
+			}
+		}
+
+		retained, err := h.clientFiles(ctx, clientID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !args.Confirm {
+			text := fmt.Sprintf("Would rename '%s' to '%s', clear its address, tax ID, notes and custom fields, and archive it", client.Name, newName)
+			if len(summary) > 0 {
+				text += "; and erase " + strings.Join(summary, ", ")
+			}
+			text += ".\nInvoices, time entries, contracts and expenses are kept. This cannot be undone; consider export_client_data first.\n"
+			return previewResult(text, &eraseClientDataResult{NewName: newName, Erase: counts, Retained: retained})
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE clients SET name = ?, address = '', city = '', state = '', zip_code = '', country = '', tax_id = '',
+			       notes = '', custom_fields = '{}', delivery_note = '', archived_at = COALESCE(archived_at, CURRENT_TIMESTAMP), updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, newName, clientID); err != nil {
+			return nil, nil, fmt.Errorf("failed to anonymize client: %w", err)
+		}
+		for _, d := range clientPersonalData {
+			if _, err := tx.ExecContext(ctx, d.erase, clientID); err != nil {
+				return nil, nil, fmt.Errorf("failed to erase %s: %w", d.label, err)
+			}
+		}
+		// The triggers have just logged the erasure itself, so the audit
+		// log is scrubbed last
+		for _, query := range clientAuditScrubs {
+			if _, err := tx.ExecContext(ctx, query, clientID); err != nil {
+				return nil, nil, fmt.Errorf("failed to scrub audit log: %w", err)
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		// Deleted values stay in the file's free pages until it is rebuilt
+		compactErr := database.Optimize(ctx, db)
+
+		text := fmt.Sprintf("Erased the personal data of '%s', now kept as '%s' (archived)", client.Name, newName)
+		if len(summary) > 0 {
+			text += ", including " + strings.Join(summary, ", ")
+		}
+		text += "\nInvoices, time entries, contracts and expenses were kept.\n"
+		if compactErr != nil {
+			text += fmt.Sprintf("Warning: the database could not be compacted (%v); run db_maintenance so erased values don't linger in free space.\n", compactErr)
+		}
+		if len(retained) > 0 {
+			text += "These files still hold the client's details and must be removed by hand if they aren't needed for accounting:\n"
+			for _, path := range retained {
+				text += "- " + path + "\n"
+			}
+		}
+		text += "Backups made before now still hold the old data until they are rotated out.\n"
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &eraseClientDataResult{Confirmed: true, NewName: newName, Erased: counts, Retained: retained}, nil
+	})
+}
+
+// clientDataExport gathers the personal data held about a client
+func (h *Handler) clientDataExport(ctx context.Context, clientID int) (*clientDataExport, error) {
+	client, err := h.loadClient(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := h.store.Clients.Aliases(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aliases: %w", err)
+	}
+	recipients, err := h.selectRecipients(ctx, clientID, nil)
+	if err != nil {
+		return nil, err
+	}
+	payment, err := h.loadPaymentDetails(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &clientDataExport{
+		Format:         clientDataExportFormat,
+		ExportedAt:     time.Now(),
+		Client:         client,
+		Aliases:        append([]string{}, aliases[clientID]...),
+		Recipients:     append([]models.Recipient{}, recipients...),
+		PaymentDetails: payment,
+		Deliveries:     []invoiceDelivery{},
+		Emails:         []emailRecord{},
+	}
+
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT invoice_number, issue_date, cc, sent_to, delivery_method, sent_at
+		FROM invoices WHERE client_id = ? AND (cc != '' OR sent_to != '' OR delivery_method != '')
+		ORDER BY issue_date, invoice_number
+	`, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoice deliveries: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d invoiceDelivery
+		var sentAt sql.NullTime
+		if err := rows.Scan(&d.InvoiceNumber, &d.IssueDate, &d.CC, &d.SentTo, &d.DeliveryMethod, &sentAt); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice delivery: %w", err)
+		}
+		if sentAt.Valid {
+			d.SentAt = &sentAt.Time
+		}
+		export.Deliveries = append(export.Deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	emails, err := h.db.QueryContext(ctx, `
+		SELECT i.invoice_number, e.recipients, e.subject, e.status, COALESCE(e.error, ''), e.sent_at
+		FROM email_log e JOIN invoices i ON i.id = e.invoice_id
+		WHERE i.client_id = ?
+		ORDER BY e.sent_at
+	`, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email log: %w", err)
+	}
+	defer emails.Close()
+	for emails.Next() {
+		var e emailRecord
+		if err := emails.Scan(&e.InvoiceNumber, &e.Recipients, &e.Subject, &e.Status, &e.Error, &e.SentAt); err != nil {
+			return nil, fmt.Errorf("failed to scan email: %w", err)
+		}
+		export.Emails = append(export.Emails, e)
+	}
+	if err := emails.Err(); err != nil {
+		return nil, err
+	}
+
+	if export.InvoiceNotes, err = h.invoiceNotes(ctx, "i.client_id = ?", clientID); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// clientFiles returns the invoice PDFs with their JSON, and the contract
+// documents on disk that name a client
+func (h *Handler) clientFiles(ctx context.Context, clientID int) ([]string, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT pdf_path, 1 FROM invoices WHERE client_id = ? AND COALESCE(pdf_path, '') != ''
+		UNION ALL
+		SELECT d.path, 0 FROM contract_documents d JOIN contracts c ON c.id = d.contract_id WHERE c.client_id = ?
+	`, clientID, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client files: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		var invoice bool
+		if err := rows.Scan(&path, &invoice); err != nil {
+			return nil, fmt.Errorf("failed to scan file path: %w", err)
+		}
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+		// Invoice PDFs may have their JSON next to them
+		if invoice {
+			if _, err := os.Stat(invoiceSidecarPath(path)); err == nil {
+				paths = append(paths, invoiceSidecarPath(path))
+			}
+		}
+	}
+	return paths, rows.Err()
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// registerPrompts registers prompts that gather the data for a common
+// workflow and tell the model which tools to use to finish it
+func registerPrompts(server *mcp.Server, h *Handler) {
+	server.AddPrompt(&mcp.Prompt{
+		Name:        "weekly_review",
+		Title:       "Weekly review",
+		Description: "Review the hours logged in a week: gaps, vague descriptions, budgets and unbilled work",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "week", Description: "Week to review: 'this week' (default) or 'last week'"},
+		},
+	}, func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		week := promptArgument(req, "week", "this week")
+		if week != "this week" && week != "last week" {
+			return nil, validationError("unknown week %q; use 'this week' or 'last week'", week)
+		}
+		startDate, endDate, err := h.parseRecapPeriod(ctx, week)
+		if err != nil {
+			return nil, err
+		}
+
+		logged, err := h.promptLoggedHours(ctx, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		summary, err := h.unbilledSummary(ctx, 0, time.Time{}, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "Help me review my week of %s to %s.\n\n", startDate.Format("Mon Jan 2"), endDate.Format("Mon Jan 2, 2006"))
+		b.WriteString(logged)
+		b.WriteString("\n")
+		b.WriteString(promptUnbilled(summary))
+		b.WriteString(`
+Please:
+1. Call find_missing_days for this week and list the working days with no hours, asking me what I worked on.
+2. Point out entries whose descriptions are too vague for a client to understand, and suggest better wording I can apply with update_time_entry.
+3. Call contract_budget_status and warn me about contracts close to or over budget.
+4. Finish with a short summary of the week and anything I should invoice soon.
+Don't change any data without asking me first.
+`)
+		return promptResult("Weekly review", b.String()), nil
+	})
+
+	server.AddPrompt(&mcp.Prompt{
+		Name:        "prepare_monthly_invoices",
+		Title:       "Prepare monthly invoices",
+		Description: "Plan and create the invoices for a month's unbilled work, one per client and currency",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "month", Description: "Month to invoice, e.g. 'last month' (default), 'this month' or 'January 2025'"},
+		},
+	}, func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		month := promptArgument(req, "month", "last month")
+		startDate, endDate, err := h.parsePeriod(ctx, month)
+		if err != nil {
+			return nil, validationError("invalid month: %w", err)
+		}
+
+		// invoice_all bills the work of the period only
+		summary, err := h.unbilledSummary(ctx, 0, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "Help me invoice my work for %s (%s to %s).\n\n",
+			month, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+		if len(summary.Contracts) == 0 {
+			b.WriteString("There is no unbilled work in this month.\n")
+		} else {
+			b.WriteString("Contracts with unbilled work in the month:\n")
+			for _, c := range summary.Contracts {
+				fmt.Fprintf(&b, "- %s, contract %s (%s): %.2f hours = %s, %s to %s\n", c.ClientName, c.ContractNumber, c.Currency,
+					c.Hours, c.Amount.Format(c.Currency), c.FirstDate.Format("2006-01-02"), c.LastDate.Format("2006-01-02"))
+			}
+		}
+		business, err := h.loadBusinessInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if business == nil {
+			b.WriteString("\nMy business information isn't set up yet, so invoices can't be created until I run set_business_info.\n")
+		}
+		fmt.Fprintf(&b, `
+Please:
+1. Call invoice_all with period '%s' to preview the invoices, which include the month's retainer fees and billable expenses, and show me its table.
+2. Once I agree, call invoice_all again with confirm true. For a client it skips because their work spans several currencies, call create_invoice once per currency instead.
+3. List the invoices created with their numbers, totals and due dates.
+4. Offer to email them: call email_invoice with dry_run true first so I can read each email before it is sent.
+`, month)
+		return promptResult("Prepare monthly invoices", b.String()), nil
+	})
+
+	server.AddPrompt(&mcp.Prompt{
+		Name:        "chase_overdue_invoices",
+		Title:       "Chase overdue invoices",
+		Description: "List unpaid invoices past their due date and draft a reminder for each",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "client_name", Description: "Only chase this client's invoices (optional)"},
+		},
+	}, func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		var clientID int
+		if name := promptArgument(req, "client_name", ""); name != "" {
+			var err error
+			if clientID, err = h.getClientIDByName(ctx, name); err != nil {
+				return nil, fmt.Errorf("client not found: %w", err)
+			}
+		}
+
+		overdue, err := h.overdueInvoices(ctx, clientID)
+		if err != nil {
+			return nil, err
+		}
+
+		var b strings.Builder
+		b.WriteString("Help me chase my overdue invoices.\n\n")
+		if len(overdue) == 0 {
+			b.WriteString("No unpaid invoices are past their due date. Tell me so, and stop there.\n")
+			return promptResult("Chase overdue invoices", b.String()), nil
+		}
+		b.WriteString("Unpaid invoices past their due date, longest overdue first:\n")
+		for _, inv := range overdue {
+			fmt.Fprintf(&b, "- %s for %s: %s outstanding, due %s (%d days overdue, status %s)", inv.InvoiceNumber, inv.ClientName,
+				inv.Balance.Format(inv.Currency), inv.DueDate.Format("2006-01-02"), inv.DaysOverdue, inv.Status)
+			if inv.LastEmailed != "" {
+				fmt.Fprintf(&b, ", last emailed %s", inv.LastEmailed)
+			}
+			if inv.LastNote != "" {
+				fmt.Fprintf(&b, "; latest note %s", inv.LastNote)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(`
+Please:
+1. Ask me whether any of these have been paid; mark those with update_invoice_status status 'paid' and leave them out. Invoices I give up on are written off with write_off_invoice, not cancelled. Record anything else I tell you, such as a dispute or a promised payment date, with add_invoice_note.
+2. Mark the rest as 'overdue' with update_invoice_status if they aren't already.
+3. For each, call email_invoice with template 'reminder' and dry_run true, and show me the draft. Be firmer with invoices that are long overdue or were already reminded recently.
+4. Send a reminder only after I approve its draft.
+`)
+		return promptResult("Chase overdue invoices", b.String()), nil
+	})
+}
+
+// promptArgument returns a prompt argument, or def when it is missing
+func promptArgument(req *mcp.GetPromptRequest, name, def string) string {
+	if v := strings.TrimSpace(req.Params.Arguments[name]); v != "" {
+		return strings.ToLower(v)
+	}
+	return def
+}
+
+func promptResult(description, text string) *mcp.GetPromptResult {
+	return &mcp.GetPromptResult{
+		Description: description,
+		Messages: []*mcp.PromptMessage{
+			{Role: "user", Content: &mcp.TextContent{Text: text}},
+		},
+	}
+}
+
+// promptLoggedHours describes the hours logged per client and contract
+// between two dates, with each entry's description
+func (h *Handler) promptLoggedHours(ctx context.Context, startDate, endDate time.Time) (string, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT te.id, te.date, cl.name, ct.contract_number, te.hours, te.description
+		FROM time_entries te
+		JOIN contracts ct ON te.contract_id = ct.id
+		JOIN clients cl ON ct.client_id = cl.id
+		WHERE te.date >= ? AND te.date <= ?
+		ORDER BY te.date, cl.name, ct.contract_number, te.created_at
+	`, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	if err != nil {
+		return "", fmt.Errorf("failed to load entries: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []string
+	var totalHours float64
+	for rows.Next() {
+		var id, clientName, contractNumber, description string
+		var date time.Time
+		var hours float64
+		if err := rows.Scan(&id, &date, &clientName, &contractNumber, &hours, &description); err != nil {
+			return "", fmt.Errorf("failed to scan entry: %w", err)
+		}
+		totalHours += hours
+		lines = append(lines, fmt.Sprintf("- %s %s %s: %.2fh %q (entry %s)", date.Format("Mon 2006-01-02"), clientName, contractNumber, hours, description, id))
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("failed to load entries: %w", err)
+	}
+
+	if len(lines) == 0 {
+		return "No hours are logged for this week.\n", nil
+	}
+	return fmt.Sprintf("Hours logged (%.2f in total):\n%s\n", totalHours, strings.Join(lines, "\n")), nil
+}
+
+// promptUnbilled describes the work not yet invoiced
+func promptUnbilled(summary *unbilled) string {
+	if len(summary.Contracts) == 0 {
+		return "Everything has been invoiced.\n"
+	}
+	text := "Unbilled work:\n"
+	for _, c := range summary.Contracts {
+		text += fmt.Sprintf("- %s %s: %.2f hours = %s since %s\n", c.ClientName, c.ContractNumber,
+			c.Hours, c.Amount.Format(c.Currency), c.FirstDate.Format("2006-01-02"))
+	}
+	text += fmt.Sprintf("Total in %s: %s\n", summary.BaseCurrency, formatConvertedTotal(summary.BaseCurrency, summary.BaseTotal, summary.Unconverted))
+	return text
+}
+
+// overdueInvoice is an unpaid invoice past its due date
+type overdueInvoice struct {
+	InvoiceNumber string
+	ClientName    string
+	Status        string
+	Currency      string
+	DueDate       time.Time
+	DaysOverdue   int
+	Balance       money.Cents
+	// LastEmailed is the date of the last email sent for the invoice, if any
+	LastEmailed string
+	// LastNote is the latest note added with add_invoice_note, if any
+	LastNote string
+}
+
+// overdueInvoices returns the unpaid invoices past their due date, for one
+// client or all of them when clientID is 0, longest overdue first
+func (h *Handler) overdueInvoices(ctx context.Context, clientID int) ([]overdueInvoice, error) {
+	query := `
+		SELECT i.invoice_number, c.name, i.status, i.currency, i.due_date, i.total_cents - i.withholding_cents,
+		       COALESCE((SELECT date(MAX(e.sent_at)) FROM email_log e WHERE e.invoice_id = i.id AND e.status = 'sent'), ''),
+		       COALESCE((SELECT n.date || ': ' || n.note FROM invoice_notes n WHERE n.invoice_id = i.id ORDER BY n.date DESC, n.id DESC LIMIT 1), '')
+		FROM invoices i
+		JOIN clients c ON i.client_id = c.id
+		WHERE i.status NOT IN ('paid', 'written_off', 'cancelled') AND i.due_date < date('now', 'localtime')
+	`
+	queryArgs := []interface{}{}
+	if clientID != 0 {
+		query += " AND i.client_id = ?"
+		queryArgs = append(queryArgs, clientID)
+	}
+	query += " ORDER BY i.due_date, i.invoice_number"
+
+	rows, err := h.db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list overdue invoices: %w", err)
+	}
+	defer rows.Close()
+
+	today := h.today(ctx)
+	var invoices []overdueInvoice
+	for rows.Next() {
+		var inv overdueInvoice
+		if err := rows.Scan(&inv.InvoiceNumber, &inv.ClientName, &inv.Status, &inv.Currency, &inv.DueDate, &inv.Balance, &inv.LastEmailed, &inv.LastNote); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice: %w", err)
+		}
+		inv.DaysOverdue = int(today.Sub(truncateDay(inv.DueDate)).Hours() / 24)
+		invoices = append(invoices, inv)
+	}
+	return invoices, rows.Err()
+}
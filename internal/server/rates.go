@@ -0,0 +1,434 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/austin/hours-mcp/internal/store"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// effectiveRateSQL and entryRateSQL are the rate expressions the store uses,
+// for queries made here
+var (
+	effectiveRateSQL = store.EffectiveRateSQL
+	entryRateSQL     = store.EntryRateSQL
+)
+
+type contractRate struct {
+	EffectiveFrom time.Time   `json:"effective_from"`
+	HourlyRate    money.Cents `json:"hourly_rate"`
+}
+
+// contractRateSchedules loads every scheduled rate change, by contract ID,
+// oldest first
+func (h *Handler) contractRateSchedules(ctx context.Context) (map[int][]contractRate, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT contract_id, effective_from, hourly_rate_cents
+		FROM contract_rates
+		ORDER BY contract_id, effective_from
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contract rates: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := map[int][]contractRate{}
+	for rows.Next() {
+		var contractID int
+		var r contractRate
+		if err := rows.Scan(&contractID, &r.EffectiveFrom, &r.HourlyRate); err != nil {
+			return nil, fmt.Errorf("failed to scan contract rate: %w", err)
+		}
+		schedules[contractID] = append(schedules[contractID], r)
+	}
+	return schedules, rows.Err()
+}
+
+// contractRateHistory returns a contract's own rate from its start, then
+// each scheduled change, and the index of the one in effect today
+func (h *Handler) contractRateHistory(ctx context.Context, contractID int) ([]contractRate, int, error) {
+	var start time.Time
+	var baseRate money.Cents
+	err := h.db.QueryRowContext(ctx, "SELECT start_date, hourly_rate_cents FROM contracts WHERE id = ?", contractID).Scan(&start, &baseRate)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load contract rate: %w", err)
+	}
+	schedules, err := h.contractRateSchedules(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	rates := append([]contractRate{{EffectiveFrom: start, HourlyRate: baseRate}}, schedules[contractID]...)
+
+	today := h.today(ctx).Format("2006-01-02")
+	current := 0
+	for i, r := range rates {
+		if r.EffectiveFrom.Format("2006-01-02") <= today {
+			current = i
+		}
+	}
+	return rates, current, nil
+}
+
+// rateOn picks the rate in effect on day from a schedule sorted oldest first
+func rateOn(base money.Cents, schedule []contractRate, day time.Time) money.Cents {
+	// Stored dates are UTC midnight, so compare calendar days
+	rate := base
+	for _, r := range schedule {
+		if r.EffectiveFrom.Format("2006-01-02") > day.Format("2006-01-02") {
+			break
+		}
+		rate = r.HourlyRate
+	}
+	return rate
+}
+
+// checkRateChangeAllowed refuses rate changes that would reprice hours that
+// have already been invoiced
+func (h *Handler) checkRateChangeAllowed(ctx context.Context, contractID int, from time.Time) error {
+	var count int
+	var last sql.NullString
+	err := h.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), MAX(date) FROM time_entries
+		WHERE contract_id = ? AND date >= ? AND invoice_id IS NOT NULL
+	`, contractID, from.Format("2006-01-02")).Scan(&count, &last)
+	if err != nil {
+		return fmt.Errorf("failed to check invoiced hours: %w", err)
+	}
+	if count > 0 {
+		return conflictError("%d invoiced time entries on or after %s would be repriced; choose a date after %s",
+			count, from.Format("2006-01-02"), last.String[:min(10, len(last.String))])
+	}
+	return nil
+}
+
+func registerRateTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	findContract := func(ctx context.Context, number string) (int, time.Time, string, error) {
+		var id int
+		var startDate time.Time
+		var currency string
+		err := db.QueryRowContext(ctx, "SELECT id, start_date, currency FROM contracts WHERE contract_number = ?", number).
+			Scan(&id, &startDate, &currency)
+		if err == sql.ErrNoRows {
+			return 0, time.Time{}, "", notFoundError("contract", "contract %s not found", number)
+		}
+		if err != nil {
+			return 0, time.Time{}, "", fmt.Errorf("failed to find contract: %w", err)
+		}
+		return id, startDate, currency, nil
+	}
+
+	// Set Contract Rate tool
+	type setContractRateArgs struct {
+		ContractNumber string  `json:"contract_number" jsonschema:"Contract number or name"`
+		HourlyRate     float64 `json:"hourly_rate" jsonschema:"New hourly rate"`
+		EffectiveFrom  string  `json:"effective_from" jsonschema:"First day the new rate applies (e.g. 2027-01-01)"`
+	}
+
+	type setContractRateResult struct {
+		ContractNumber string `json:"contract_number"`
+		Currency       string `json:"currency"`
+		contractRate
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_contract_rate",
+		Description: "Schedule a new hourly rate for a contract from a given date; hours are priced at the rate in effect on their date",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setContractRateArgs) (*mcp.CallToolResult, *setContractRateResult, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		contractID, startDate, currency, err := findContract(ctx, args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		if args.HourlyRate <= 0 {
+			return nil, nil, validationError("hourly rate must be positive")
+		}
+		from, err := h.parseDate(ctx, args.EffectiveFrom)
+		if err != nil {
+			return nil, nil, validationError("invalid effective date: %w", err)
+		}
+		if from.Format("2006-01-02") <= startDate.Format("2006-01-02") {
+			return nil, nil, validationError("effective date must be after the contract start (%s); the contract's own rate applies from its start",
+				startDate.Format("2006-01-02"))
+		}
+		if err := h.checkRateChangeAllowed(ctx, contractID, from); err != nil {
+			return nil, nil, err
+		}
+
+		rate := money.FromFloat(args.HourlyRate)
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO contract_rates (contract_id, effective_from, hourly_rate_cents)
+			VALUES (?, ?, ?)
+			ON CONFLICT(contract_id, effective_from) DO UPDATE SET
+				hourly_rate_cents = excluded.hourly_rate_cents
+		`, contractID, from.Format("2006-01-02"), rate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to save contract rate: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("Rate for %s set to %s per hour from %s", args.ContractNumber, rate.Format(currency), from.Format("2006-01-02")),
+				},
+			},
+		}, &setContractRateResult{ContractNumber: args.ContractNumber, Currency: currency, contractRate: contractRate{EffectiveFrom: from, HourlyRate: rate}}, nil
+	})
+
+	// List Contract Rates tool
+	type listContractRatesArgs struct {
+		ContractNumber string `json:"contract_number" jsonschema:"Contract number or name"`
+	}
+
+	type listContractRatesResult struct {
+		ContractNumber string         `json:"contract_number"`
+		Currency       string         `json:"currency"`
+		CurrentRate    money.Cents    `json:"current_rate"`
+		Rates          []contractRate `json:"rates" jsonschema:"The contract's own rate from its start, then each scheduled change"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_contract_rates",
+		Description: "Show the rate schedule of a contract",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listContractRatesArgs) (*mcp.CallToolResult, *listContractRatesResult, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		contractID, _, currency, err := findContract(ctx, args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		rates, current, err := h.contractRateHistory(ctx, contractID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		text := fmt.Sprintf("Rate schedule for %s:\n", args.ContractNumber)
+		for i, r := range rates {
+			text += fmt.Sprintf("- from %s: %s per hour", r.EffectiveFrom.Format("2006-01-02"), r.HourlyRate.Format(currency))
+			if i == current {
+				text += " (current)"
+			}
+			text += "\n"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &listContractRatesResult{
+			ContractNumber: args.ContractNumber,
+			Currency:       currency,
+			CurrentRate:    rates[current].HourlyRate,
+			Rates:          rates,
+		}, nil
+	})
+
+	// Remove Contract Rate tool
+	type removeContractRateArgs struct {
+		ContractNumber string `json:"contract_number" jsonschema:"Contract number or name"`
+		EffectiveFrom  string `json:"effective_from" jsonschema:"Effective date of the scheduled rate to remove"`
+	}
+
+	type removeContractRateResult struct {
+		ContractNumber string    `json:"contract_number"`
+		EffectiveFrom  time.Time `json:"effective_from"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "remove_contract_rate",
+		Description: "Remove a scheduled rate change from a contract",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args removeContractRateArgs) (*mcp.CallToolResult, *removeContractRateResult, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		contractID, _, _, err := findContract(ctx, args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		from, err := h.parseDate(ctx, args.EffectiveFrom)
+		if err != nil {
+			return nil, nil, validationError("invalid effective date: %w", err)
+		}
+		if err := h.checkRateChangeAllowed(ctx, contractID, from); err != nil {
+			return nil, nil, err
+		}
+
+		result, err := db.ExecContext(ctx, "DELETE FROM contract_rates WHERE contract_id = ? AND effective_from = ?", contractID, from.Format("2006-01-02"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to remove contract rate: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return nil, nil, notFoundError("rate", "no rate for %s takes effect on %s", args.ContractNumber, from.Format("2006-01-02"))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("Removed the %s rate for %s", from.Format("2006-01-02"), args.ContractNumber),
+				},
+			},
+		}, &removeContractRateResult{ContractNumber: args.ContractNumber, EffectiveFrom: from}, nil
+	})
+
+	// Set Rate Rule tool
+	type setRateRuleArgs struct {
+		ContractNumber string  `json:"contract_number" jsonschema:"Contract number or name"`
+		Kind           string  `json:"kind" jsonschema:"When the premium applies: weekend (days outside the work_week setting), holiday (days in the holiday calendar) or overtime (hours past daily_hours in a day)"`
+		Multiplier     float64 `json:"multiplier" jsonschema:"Rate multiplier, e.g. 1.5 for time and a half"`
+		DailyHours     float64 `json:"daily_hours,omitempty" jsonschema:"For overtime: hours per day billed at the normal rate (e.g. 8)"`
+	}
+
+	type setRateRuleResult struct {
+		ContractNumber string `json:"contract_number"`
+		rateRule
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_rate_rule",
+		Description: "Bill weekend, holiday or overtime hours on a contract at a multiple of its rate",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setRateRuleArgs) (*mcp.CallToolResult, *setRateRuleResult, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		contractID, _, _, err := findContract(ctx, args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		kind := strings.ToLower(strings.TrimSpace(args.Kind))
+		if err := validateRateRuleKind(kind); err != nil {
+			return nil, nil, err
+		}
+		if args.Multiplier <= 0 {
+			return nil, nil, validationError("multiplier must be positive")
+		}
+		if kind == rateRuleOvertime {
+			if args.DailyHours <= 0 || args.DailyHours >= 24 {
+				return nil, nil, validationError("overtime rules need daily_hours between 0 and 24")
+			}
+		} else {
+			args.DailyHours = 0
+		}
+
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO contract_rate_rules (contract_id, kind, multiplier, daily_hours)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(contract_id, kind) DO UPDATE SET
+				multiplier = excluded.multiplier,
+				daily_hours = excluded.daily_hours
+		`, contractID, kind, args.Multiplier, args.DailyHours)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to save rate rule: %w", err)
+		}
+
+		text := fmt.Sprintf("%s hours on %s are billed at %gx", rateRuleLabels[kind], args.ContractNumber, args.Multiplier)
+		if kind == rateRuleOvertime {
+			text += fmt.Sprintf(" after %g hours a day", args.DailyHours)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &setRateRuleResult{ContractNumber: args.ContractNumber, rateRule: rateRule{Kind: kind, Multiplier: args.Multiplier, DailyHours: args.DailyHours}}, nil
+	})
+
+	// List Rate Rules tool
+	type listRateRulesArgs struct {
+		ContractNumber string `json:"contract_number" jsonschema:"Contract number or name"`
+	}
+
+	type listRateRulesResult struct {
+		ContractNumber string     `json:"contract_number"`
+		Rules          []rateRule `json:"rules"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_rate_rules",
+		Description: "List the weekend, holiday and overtime rate rules of a contract",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listRateRulesArgs) (*mcp.CallToolResult, *listRateRulesResult, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		contractID, _, _, err := findContract(ctx, args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		all, err := h.contractRateRules(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var rules []rateRule
+		for _, kind := range []string{rateRuleOvertime, rateRuleWeekend, rateRuleHoliday} {
+			if r, ok := all[contractID][kind]; ok {
+				rules = append(rules, r)
+			}
+		}
+
+		text := fmt.Sprintf("Rate rules for %s:\n", args.ContractNumber)
+		if len(rules) == 0 {
+			text += "No rate rules; all hours are billed at the contract rate.\n"
+		}
+		for _, r := range rules {
+			text += fmt.Sprintf("- %s: %gx", rateRuleLabels[r.Kind], r.Multiplier)
+			if r.Kind == rateRuleOvertime {
+				text += fmt.Sprintf(" after %g hours a day", r.DailyHours)
+			}
+			text += "\n"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &listRateRulesResult{ContractNumber: args.ContractNumber, Rules: rules}, nil
+	})
+
+	// Remove Rate Rule tool
+	type removeRateRuleArgs struct {
+		ContractNumber string `json:"contract_number" jsonschema:"Contract number or name"`
+		Kind           string `json:"kind" jsonschema:"Rule to remove: weekend, holiday or overtime"`
+	}
+
+	type removeRateRuleResult struct {
+		ContractNumber string `json:"contract_number"`
+		Kind           string `json:"kind"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "remove_rate_rule",
+		Description: "Remove a weekend, holiday or overtime rate rule from a contract",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args removeRateRuleArgs) (*mcp.CallToolResult, *removeRateRuleResult, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		contractID, _, _, err := findContract(ctx, args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		kind := strings.ToLower(strings.TrimSpace(args.Kind))
+
+		result, err := db.ExecContext(ctx, "DELETE FROM contract_rate_rules WHERE contract_id = ? AND kind = ?", contractID, kind)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to remove rate rule: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return nil, nil, notFoundError("rate_rule", "%s has no %s rule", args.ContractNumber, kind)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("Removed the %s rule from %s", kind, args.ContractNumber),
+				},
+			},
+		}, &removeRateRuleResult{ContractNumber: args.ContractNumber, Kind: kind}, nil
+	})
+}
@@ -0,0 +1,367 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// selectRecipients returns the client's recipients with the given IDs, or
+// all of them when ids is empty, primary contact first
+func (h *Handler) selectRecipients(ctx context.Context, clientID int, ids []int) ([]models.Recipient, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT id, name, email, COALESCE(title, ''), COALESCE(phone, ''), is_primary
+		FROM recipients WHERE client_id = ?
+		ORDER BY is_primary DESC, id
+	`, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recipients: %w", err)
+	}
+	defer rows.Close()
+
+	wanted := map[int]bool{}
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	var recipients []models.Recipient
+	for rows.Next() {
+		r := models.Recipient{ClientID: clientID}
+		if err := rows.Scan(&r.ID, &r.Name, &r.Email, &r.Title, &r.Phone, &r.IsPrimary); err != nil {
+			return nil, fmt.Errorf("failed to scan recipient: %w", err)
+		}
+		if len(ids) == 0 || wanted[r.ID] {
+			recipients = append(recipients, r)
+			delete(wanted, r.ID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for id := range wanted {
+		return nil, notFoundError("recipient", "recipient with ID %d is not a recipient of this client", id)
+	}
+	return recipients, nil
+}
+
+// loadRecipient returns a recipient by ID
+func (h *Handler) loadRecipient(ctx context.Context, id int) (*models.Recipient, error) {
+	var r models.Recipient
+	err := h.db.QueryRowContext(ctx, `
+		SELECT id, client_id, name, email, COALESCE(title, ''), COALESCE(phone, ''), is_primary, created_at
+		FROM recipients WHERE id = ?
+	`, id).Scan(&r.ID, &r.ClientID, &r.Name, &r.Email, &r.Title, &r.Phone, &r.IsPrimary, &r.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, notFoundError("recipient", "recipient with ID %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recipient: %w", err)
+	}
+	return &r, nil
+}
+
+// normalizeCcAddresses validates addresses to copy, keeping display names
+func normalizeCcAddresses(cc []string) ([]string, error) {
+	var addresses []string
+	for _, addr := range cc {
+		parsed, err := mail.ParseAddress(strings.TrimSpace(addr))
+		if err != nil {
+			return nil, validationError("invalid cc address '%s'", addr)
+		}
+		addresses = append(addresses, parsed.String())
+	}
+	return addresses, nil
+}
+
+// normalizeRecipientEmail validates a recipient's email address and returns
+// the bare address
+func normalizeRecipientEmail(email string) (string, error) {
+	parsed, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		return "", validationError("invalid email address '%s'", email)
+	}
+	return parsed.Address, nil
+}
+
+// checkDuplicateRecipient refuses a second recipient with the same email for
+// a client. Emails are compared case-insensitively; excludeID is the
+// recipient being edited, if any.
+func (h *Handler) checkDuplicateRecipient(ctx context.Context, clientID int, email string, excludeID int) error {
+	var id int
+	var name string
+	err := h.db.QueryRowContext(ctx, `
+		SELECT id, name FROM recipients
+		WHERE client_id = ? AND email = ? COLLATE NOCASE AND id != ?
+	`, clientID, email, excludeID).Scan(&id, &name)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check for duplicate recipients: %w", err)
+	}
+	return conflictError("%s <%s> is already a recipient for this client (ID: %d); use edit_recipient to change it", name, email, id)
+}
+
+// registerRecipientTools registers tools to edit recipients
+func registerRecipientTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Add Recipient tool
+	type addRecipientArgs struct {
+		ClientName    string `json:"client_name" jsonschema:"Client name"`
+		RecipientName string `json:"recipient_name" jsonschema:"Recipient's name"`
+		Email         string `json:"email" jsonschema:"Recipient's email"`
+		Title         string `json:"title,omitempty" jsonschema:"Recipient's job title (optional)"`
+		Phone         string `json:"phone,omitempty" jsonschema:"Recipient's phone number (optional)"`
+		IsPrimary     bool   `json:"is_primary,omitempty" jsonschema:"Is this the primary recipient"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "add_recipient",
+		Description: "Add a recipient for a client",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args addRecipientArgs) (*mcp.CallToolResult, *models.Recipient, error) {
+		clientID, err := h.getClientIDByName(ctx, args.ClientName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("client not found: %w", err)
+		}
+
+		email, err := normalizeRecipientEmail(args.Email)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := h.checkDuplicateRecipient(ctx, clientID, email, 0); err != nil {
+			return nil, nil, err
+		}
+
+		if args.IsPrimary {
+			_, err = db.ExecContext(ctx, `
+				UPDATE recipients SET is_primary = FALSE
+				WHERE client_id = ?
+			`, clientID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to update primary recipient: %w", err)
+			}
+		}
+
+		result, err := db.ExecContext(ctx, `
+			INSERT INTO recipients (client_id, name, email, title, phone, is_primary)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, clientID, args.RecipientName, email, args.Title, args.Phone, args.IsPrimary)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to add recipient: %w", err)
+		}
+
+		id, _ := result.LastInsertId()
+		recipient, err := h.loadRecipient(ctx, int(id))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("Recipient '%s' added for client '%s' (ID: %d)", args.RecipientName, args.ClientName, id),
+				},
+			},
+		}, recipient, nil
+	})
+
+	// List Recipients tool
+	type listRecipientsArgs struct {
+		ClientName string `json:"client_name" jsonschema:"Client name"`
+	}
+
+	type listRecipientsResult struct {
+		Recipients []models.Recipient `json:"recipients"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_recipients",
+		Description: "List all recipients for a client",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listRecipientsArgs) (*mcp.CallToolResult, *listRecipientsResult, error) {
+		clientID, err := h.getClientIDByName(ctx, args.ClientName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("client not found: %w", err)
+		}
+
+		rows, err := db.QueryContext(ctx, `
+			SELECT id, name, email, COALESCE(title, ''), COALESCE(phone, ''), is_primary, created_at
+			FROM recipients
+			WHERE client_id = ?
+			ORDER BY is_primary DESC, name
+		`, clientID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list recipients: %w", err)
+		}
+		defer rows.Close()
+
+		var recipients []models.Recipient
+
+		text := fmt.Sprintf("Recipients for %s:\n", args.ClientName)
+		for rows.Next() {
+			r := models.Recipient{ClientID: clientID}
+			err := rows.Scan(&r.ID, &r.Name, &r.Email, &r.Title, &r.Phone, &r.IsPrimary, &r.CreatedAt)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to scan recipient: %w", err)
+			}
+			recipients = append(recipients, r)
+
+			primaryLabel := ""
+			if r.IsPrimary {
+				primaryLabel = " (PRIMARY)"
+			}
+			text += fmt.Sprintf("- ID %d: %s <%s>%s", r.ID, r.Name, r.Email, primaryLabel)
+			if r.Title != "" {
+				text += fmt.Sprintf(" - %s", r.Title)
+			}
+			if r.Phone != "" {
+				text += fmt.Sprintf(" - %s", r.Phone)
+			}
+			text += "\n"
+		}
+
+		if len(recipients) == 0 {
+			text += "No recipients found.\n"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &listRecipientsResult{Recipients: recipients}, nil
+	})
+
+	// Remove Recipient tool
+	type removeRecipientArgs struct {
+		RecipientID int `json:"recipient_id" jsonschema:"Recipient ID to remove"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "remove_recipient",
+		Description: "Remove a recipient by ID",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args removeRecipientArgs) (*mcp.CallToolResult, *models.Recipient, error) {
+		// First check if recipient exists and get details
+		recipient, err := h.loadRecipient(ctx, args.RecipientID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Remove the recipient
+		result, err := db.ExecContext(ctx, `DELETE FROM recipients WHERE id = ?`, args.RecipientID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to remove recipient: %w", err)
+		}
+
+		rowsAffected, _ := result.RowsAffected()
+		if rowsAffected == 0 {
+			return nil, nil, notFoundError("recipient", "recipient with ID %d not found", args.RecipientID)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("Recipient '%s <%s>' (ID: %d) removed successfully", recipient.Name, recipient.Email, args.RecipientID),
+				},
+			},
+		}, recipient, nil
+	})
+
+	// Edit Recipient tool
+	type editRecipientArgs struct {
+		RecipientID int     `json:"recipient_id" jsonschema:"Recipient ID to edit"`
+		Name        string  `json:"name,omitempty" jsonschema:"New name (optional)"`
+		Email       string  `json:"email,omitempty" jsonschema:"New email address (optional)"`
+		Title       *string `json:"title,omitempty" jsonschema:"New job title, empty to clear (optional)"`
+		Phone       *string `json:"phone,omitempty" jsonschema:"New phone number, empty to clear (optional)"`
+		IsPrimary   *bool   `json:"is_primary,omitempty" jsonschema:"Make this the client's primary recipient, or stop it being primary (optional)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "edit_recipient",
+		Description: "Edit a recipient's name, email, title, phone or primary flag",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args editRecipientArgs) (*mcp.CallToolResult, *models.Recipient, error) {
+		var clientID int
+		var name, email string
+		err := db.QueryRowContext(ctx, "SELECT client_id, name, email FROM recipients WHERE id = ?", args.RecipientID).
+			Scan(&clientID, &name, &email)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("recipient", "recipient with ID %d not found", args.RecipientID)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check recipient: %w", err)
+		}
+
+		updates := []string{}
+		updateArgs := []interface{}{}
+
+		if args.Name != "" {
+			name = args.Name
+			updates = append(updates, "name = ?")
+			updateArgs = append(updateArgs, args.Name)
+		}
+		if args.Email != "" {
+			if email, err = normalizeRecipientEmail(args.Email); err != nil {
+				return nil, nil, err
+			}
+			if err := h.checkDuplicateRecipient(ctx, clientID, email, args.RecipientID); err != nil {
+				return nil, nil, err
+			}
+			updates = append(updates, "email = ?")
+			updateArgs = append(updateArgs, email)
+		}
+		if args.Title != nil {
+			updates = append(updates, "title = ?")
+			updateArgs = append(updateArgs, *args.Title)
+		}
+		if args.Phone != nil {
+			updates = append(updates, "phone = ?")
+			updateArgs = append(updateArgs, *args.Phone)
+		}
+		if args.IsPrimary != nil {
+			updates = append(updates, "is_primary = ?")
+			updateArgs = append(updateArgs, *args.IsPrimary)
+		}
+
+		if len(updates) == 0 {
+			return nil, nil, validationError("no updates provided")
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		// A client has one primary recipient
+		if args.IsPrimary != nil && *args.IsPrimary {
+			if _, err := tx.ExecContext(ctx, "UPDATE recipients SET is_primary = FALSE WHERE client_id = ?", clientID); err != nil {
+				return nil, nil, fmt.Errorf("failed to update primary recipient: %w", err)
+			}
+		}
+
+		updateArgs = append(updateArgs, args.RecipientID)
+		query := fmt.Sprintf("UPDATE recipients SET %s WHERE id = ?", strings.Join(updates, ", "))
+		if _, err := tx.ExecContext(ctx, query, updateArgs...); err != nil {
+			return nil, nil, fmt.Errorf("failed to update recipient: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		recipient, err := h.loadRecipient(ctx, args.RecipientID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("Recipient %d updated: %s <%s>", args.RecipientID, recipient.Name, recipient.Email),
+				},
+			},
+		}, recipient, nil
+	})
+}
@@ -0,0 +1,1292 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// registerReportTools registers read-only reporting tools
+func registerReportTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Forecast tool
+	type forecastArgs struct {
+		Months        int    `json:"months,omitempty" jsonschema:"Number of months to project, 1-3 (default: 3)"`
+		LookbackWeeks int    `json:"lookback_weeks,omitempty" jsonschema:"Weeks of history used for the run rate (default: 8)"`
+		ClientName    string `json:"client_name,omitempty" jsonschema:"Limit the forecast to one client (optional)"`
+		Format        string `json:"format,omitempty" jsonschema:"Output format: text (default) or markdown"`
+	}
+
+	type contractForecast struct {
+		ContractNumber    string                 `json:"contract_number"`
+		ContractName      string                 `json:"contract_name"`
+		ClientName        string                 `json:"client_name"`
+		ContractType      string                 `json:"contract_type"`
+		Currency          string                 `json:"currency"`
+		HourlyRate        money.Cents            `json:"hourly_rate"`
+		WeeklyHours       float64                `json:"weekly_hours"`
+		RetainerFee       money.Cents            `json:"retainer_fee,omitempty" jsonschema:"Monthly fee of a retainer, which covers retainer_hours"`
+		RetainerHours     float64                `json:"retainer_hours,omitempty"`
+		MonthlyRate       map[string]money.Cents `json:"monthly_rate" jsonschema:"Hourly rate per month; for a retainer, the rate of hours beyond retainer_hours"`
+		MonthlyProjection map[string]money.Cents `json:"monthly_projection"`
+	}
+
+	type forecastResult struct {
+		LookbackWeeks int                               `json:"lookback_weeks"`
+		Contracts     []contractForecast                `json:"contracts"`
+		Totals        map[string]map[string]money.Cents `json:"totals" jsonschema:"Projected amount per month and currency"`
+		BaseCurrency  string                            `json:"base_currency"`
+		BaseTotals    map[string]money.Cents            `json:"base_totals" jsonschema:"Projected amount per month converted into the base currency"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "forecast",
+		Description: "Project revenue for the next 1-3 months from active contracts and the recent weekly run rate. Retainers project their monthly fee plus overage for hours beyond those included",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args forecastArgs) (*mcp.CallToolResult, *forecastResult, error) {
+		markdown, err := parseOutputFormat(args.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if args.Months == 0 {
+			args.Months = 3
+		}
+		if args.Months < 1 || args.Months > 3 {
+			return nil, nil, validationError("months must be between 1 and 3")
+		}
+		if args.LookbackWeeks == 0 {
+			args.LookbackWeeks = 8
+		}
+		if args.LookbackWeeks < 1 {
+			return nil, nil, validationError("lookback_weeks must be positive")
+		}
+
+		today := h.today(ctx)
+		lookbackStart := today.AddDate(0, 0, -7*args.LookbackWeeks)
+
+		query := `
+			SELECT c.id, c.contract_number, c.name, c.hourly_rate_cents, c.currency, c.contract_type,
+			       c.start_date, c.end_date, cl.name, COALESCE(SUM(te.hours), 0)
+			FROM contracts c
+			JOIN clients cl ON c.client_id = cl.id
+			LEFT JOIN time_entries te ON te.contract_id = c.id AND te.date >= ? AND te.date < ?
+			WHERE c.status = 'active'
+		`
+		queryArgs := []interface{}{lookbackStart.Format("2006-01-02"), today.Format("2006-01-02")}
+
+		if args.ClientName != "" {
+			clientID, err := h.getClientIDByName(ctx, args.ClientName)
+			if err != nil {
+				return nil, nil, fmt.Errorf("client not found: %w", err)
+			}
+			query += " AND c.client_id = ?"
+			queryArgs = append(queryArgs, clientID)
+		}
+
+		query += " GROUP BY c.id ORDER BY cl.name, c.contract_number"
+
+		rows, err := db.QueryContext(ctx, query, queryArgs...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load contracts: %w", err)
+		}
+		defer rows.Close()
+
+		type monthWindow struct {
+			label string
+			start time.Time
+			end   time.Time
+		}
+
+		var months []monthWindow
+		for i := 1; i <= args.Months; i++ {
+			start := time.Date(today.Year(), today.Month()+time.Month(i), 1, 0, 0, 0, 0, today.Location())
+			months = append(months, monthWindow{
+				label: start.Format("January 2006"),
+				start: start,
+				end:   start.AddDate(0, 1, -1),
+			})
+		}
+
+		// Scheduled rate changes apply from the month they take effect
+		schedules, err := h.contractRateSchedules(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		retainers, err := h.loadRetainers(ctx, "status = 'active'")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var forecasts []contractForecast
+		totals := map[string]map[string]money.Cents{} // month -> currency -> amount
+
+		for rows.Next() {
+			var f contractForecast
+			var startDate time.Time
+			var endDate sql.NullTime
+			var loggedHours float64
+			var contractID int
+			if err := rows.Scan(&contractID, &f.ContractNumber, &f.ContractName, &f.HourlyRate, &f.Currency, &f.ContractType,
+				&startDate, &endDate, &f.ClientName, &loggedHours); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan contract: %w", err)
+			}
+
+			f.WeeklyHours = loggedHours / float64(args.LookbackWeeks)
+			f.MonthlyRate = map[string]money.Cents{}
+			f.MonthlyProjection = map[string]money.Cents{}
+			baseRate := f.HourlyRate
+			f.HourlyRate = rateOn(baseRate, schedules[contractID], today)
+			r := retainers[contractID]
+			if r != nil {
+				f.RetainerFee, f.RetainerHours = r.fee, r.hours
+			}
+
+			var contractEnd *time.Time
+			if endDate.Valid {
+				contractEnd = &endDate.Time
+			}
+
+			for _, m := range months {
+				from, to := m.start, m.end
+				if startDate.After(from) {
+					from = startDate
+				}
+				if contractEnd != nil && contractEnd.Before(to) {
+					to = *contractEnd
+				}
+
+				var amount money.Cents
+				rate := rateOn(baseRate, schedules[contractID], from)
+				if r != nil && r.overageRate > 0 {
+					rate = r.overageRate
+				}
+				if !from.After(to) {
+					hours := f.WeeklyHours * float64(countWeekdays(from, to)) / 5
+					// A retainer bills its fee for every month it runs and
+					// only the hours beyond those included at the rate
+					if r != nil {
+						amount = r.fee
+						hours = max(0, hours-r.hours)
+					}
+					amount += money.ForHours(rate, hours)
+				}
+				f.MonthlyRate[m.label] = rate
+				f.MonthlyProjection[m.label] = amount
+
+				if totals[m.label] == nil {
+					totals[m.label] = map[string]money.Cents{}
+				}
+				totals[m.label][f.Currency] += amount
+			}
+
+			forecasts = append(forecasts, f)
+		}
+
+		// Mixed or foreign currencies also get a total in the base currency
+		baseCurrency := h.baseCurrency(ctx)
+		baseTotals := map[string]money.Cents{}
+		converted := map[string]string{}
+		for _, m := range months {
+			monthTotals := totals[m.label]
+			if len(monthTotals) == 0 || (len(monthTotals) == 1 && monthTotals[baseCurrency] != 0) {
+				continue
+			}
+			total, missing, err := h.convertTotals(ctx, monthTotals, baseCurrency, today)
+			if err != nil {
+				return nil, nil, err
+			}
+			baseTotals[m.label] = total
+			converted[m.label] = formatConvertedTotal(baseCurrency, total, missing)
+		}
+
+		text := fmt.Sprintf("Revenue forecast (run rate from last %d weeks):\n", args.LookbackWeeks)
+		for _, m := range months {
+			if markdown {
+				var tableRows [][]string
+				for _, f := range forecasts {
+					amount := f.MonthlyProjection[m.label]
+					if amount == 0 {
+						continue
+					}
+					tableRows = append(tableRows, []string{f.ClientName, f.ContractNumber, f.ContractType,
+						fmt.Sprintf("%.1f", f.WeeklyHours), f.MonthlyRate[m.label].String(), amount.Format(f.Currency)})
+				}
+				tableRows = append(tableRows, []string{"**Total**", "", "", "", "", "**" + formatCurrencyTotals(totals[m.label]) + "**"})
+				if converted[m.label] != "" {
+					tableRows = append(tableRows, []string{"**Total in " + baseCurrency + "**", "", "", "", "", "**" + converted[m.label] + "**"})
+				}
+				text += fmt.Sprintf("\n### %s\n\n", m.label) +
+					markdownTable([]string{"Client", "Contract", "Type", "Hours/week", "Rate", "Projected"}, tableRows, 3, 4, 5)
+				continue
+			}
+			text += fmt.Sprintf("\n%s:\n", m.label)
+			for _, f := range forecasts {
+				amount := f.MonthlyProjection[m.label]
+				if amount == 0 {
+					continue
+				}
+				if f.RetainerHours > 0 {
+					text += fmt.Sprintf("- %s %s (%s): %s (%.1f h/week; fee %s for %g hours, overage at %s)\n",
+						f.ClientName, f.ContractNumber, f.ContractType, amount.Format(f.Currency), f.WeeklyHours,
+						f.RetainerFee, f.RetainerHours, f.MonthlyRate[m.label])
+					continue
+				}
+				text += fmt.Sprintf("- %s %s (%s): %s (%.1f h/week at %s)\n",
+					f.ClientName, f.ContractNumber, f.ContractType, amount.Format(f.Currency), f.WeeklyHours, f.MonthlyRate[m.label])
+			}
+			text += fmt.Sprintf("  Total: %s\n", formatCurrencyTotals(totals[m.label]))
+			if converted[m.label] != "" {
+				text += fmt.Sprintf("  Total in %s: %s\n", baseCurrency, converted[m.label])
+			}
+		}
+
+		if len(forecasts) == 0 {
+			text += "\nNo active contracts found.\n"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &forecastResult{
+			LookbackWeeks: args.LookbackWeeks,
+			Contracts:     forecasts,
+			Totals:        totals,
+			BaseCurrency:  baseCurrency,
+			BaseTotals:    baseTotals,
+		}, nil
+	})
+
+	// Tax Year Summary tool
+	type taxYearSummaryArgs struct {
+		Year             int    `json:"year,omitempty" jsonschema:"Year to summarize; for fiscal years this is the year the fiscal year starts in (default: last year)"`
+		FiscalStartMonth int    `json:"fiscal_start_month,omitempty" jsonschema:"First month of the fiscal year, 1-12 (default: the fiscal_year_start_month setting)"`
+		SaveCSV          bool   `json:"save_csv,omitempty" jsonschema:"Also write the CSV to ~/Downloads"`
+		Format           string `json:"format,omitempty" jsonschema:"Output format: text (default) or markdown"`
+	}
+
+	type clientTaxSummary struct {
+		ClientName   string      `json:"client_name"`
+		InvoiceCount int         `json:"invoice_count"`
+		Invoiced     money.Cents `json:"invoiced" jsonschema:"Invoiced in the year, by issue date"`
+		Paid         money.Cents `json:"paid" jsonschema:"Payments received in the year, whenever the invoice was issued"`
+		WrittenOff   money.Cents `json:"written_off,omitempty" jsonschema:"Invoiced amounts written off as uncollectable"`
+		Outstanding  money.Cents `json:"outstanding" jsonschema:"Invoiced in the year and still unpaid"`
+	}
+
+	type taxYearSummaryResult struct {
+		PeriodStart     string                 `json:"period_start"`
+		PeriodEnd       string                 `json:"period_end"`
+		Clients         []*clientTaxSummary    `json:"clients"`
+		TotalInvoiced   money.Cents            `json:"total_invoiced" jsonschema:"Total invoiced in the base currency"`
+		TotalPaid       money.Cents            `json:"total_paid" jsonschema:"Total paid in the base currency"`
+		TotalWrittenOff money.Cents            `json:"total_written_off,omitempty" jsonschema:"Total written off in the base currency"`
+		BaseCurrency    string                 `json:"base_currency"`
+		Unconverted     map[string]money.Cents `json:"unconverted" jsonschema:"Amounts per currency left out of the totals for lack of an exchange rate"`
+		CSV             string                 `json:"csv"`
+		CSVPath         string                 `json:"csv_path,omitempty"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "tax_year_summary",
+		Description: "Summarize per client the amounts invoiced in a calendar or fiscal year and the payments received in it, as text and CSV for your accountant",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args taxYearSummaryArgs) (*mcp.CallToolResult, *taxYearSummaryResult, error) {
+		markdown, err := parseOutputFormat(args.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+		if args.Year == 0 {
+			args.Year = h.today(ctx).Year() - 1
+		}
+		if args.FiscalStartMonth == 0 {
+			args.FiscalStartMonth = h.getIntSetting(ctx, "fiscal_year_start_month")
+		}
+		if args.FiscalStartMonth < 1 || args.FiscalStartMonth > 12 {
+			return nil, nil, validationError("fiscal_start_month must be between 1 and 12")
+		}
+
+		start := time.Date(args.Year, time.Month(args.FiscalStartMonth), 1, 0, 0, 0, 0, time.Local)
+		end := start.AddDate(1, 0, -1)
+
+		baseCurrency := h.baseCurrency(ctx)
+
+		// Invoices issued in the year count as invoiced, and written off or
+		// outstanding by their status; payments count in the year they were
+		// received, like tax_report's cash basis, whenever the invoice was
+		// issued. A payment without a date is taken to be on the issue date.
+		first, last := start.Format("2006-01-02"), end.Format("2006-01-02")
+		rows, err := db.QueryContext(ctx, `
+			SELECT c.name, i.total_cents, i.status, i.issue_date, i.paid_date, COALESCE(i.currency, '')
+			FROM invoices i
+			JOIN clients c ON i.client_id = c.id
+			WHERE (i.issue_date >= ?1 AND i.issue_date <= ?2 AND i.status != 'cancelled')
+			   OR (i.status = 'paid' AND COALESCE(i.paid_date, i.issue_date) >= ?1 AND COALESCE(i.paid_date, i.issue_date) <= ?2)
+			ORDER BY c.name, i.issue_date
+		`, first, last)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to summarize invoices: %w", err)
+		}
+		defer rows.Close()
+
+		// Amounts are converted into the base currency at the issue date,
+		// and payments at the date they were received. An invoice with
+		// either conversion missing is listed as unconverted once.
+		var summaries []*clientTaxSummary
+		byClient := map[string]*clientTaxSummary{}
+		unconverted := map[string]money.Cents{}
+		var totalInvoiced, totalPaid, totalWrittenOff, totalOutstanding money.Cents
+		for rows.Next() {
+			var clientName, status, currency string
+			var amount money.Cents
+			var issueDate time.Time
+			var paid sql.NullTime
+			if err := rows.Scan(&clientName, &amount, &status, &issueDate, &paid, &currency); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan invoice: %w", err)
+			}
+			if currency == "" {
+				currency = baseCurrency
+			}
+			paidDate := issueDate
+			if paid.Valid {
+				paidDate = paid.Time
+			}
+
+			s, ok := byClient[clientName]
+			if !ok {
+				s = &clientTaxSummary{ClientName: clientName}
+				byClient[clientName] = s
+				summaries = append(summaries, s)
+			}
+
+			missing := false
+			if day := issueDate.Format("2006-01-02"); day >= first && day <= last && status != "cancelled" {
+				rate, ok, err := h.exchangeRate(ctx, currency, baseCurrency, issueDate)
+				if err != nil {
+					return nil, nil, err
+				}
+				if ok {
+					s.InvoiceCount++
+					s.Invoiced += amount.Convert(rate)
+					totalInvoiced += amount.Convert(rate)
+					switch status {
+					case "paid":
+					case "written_off":
+						s.WrittenOff += amount.Convert(rate)
+						totalWrittenOff += amount.Convert(rate)
+					default:
+						s.Outstanding += amount.Convert(rate)
+						totalOutstanding += amount.Convert(rate)
+					}
+				} else {
+					missing = true
+				}
+			}
+			if day := paidDate.Format("2006-01-02"); status == "paid" && day >= first && day <= last {
+				rate, ok, err := h.exchangeRate(ctx, currency, baseCurrency, paidDate)
+				if err != nil {
+					return nil, nil, err
+				}
+				if ok {
+					s.Paid += amount.Convert(rate)
+					totalPaid += amount.Convert(rate)
+				} else {
+					missing = true
+				}
+			}
+			if missing {
+				unconverted[currency] += amount
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return nil, nil, fmt.Errorf("failed to summarize invoices: %w", err)
+		}
+
+		periodLabel := fmt.Sprintf("%d", args.Year)
+		if args.FiscalStartMonth != 1 {
+			periodLabel = fmt.Sprintf("FY %s to %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+		}
+
+		var csvBuf strings.Builder
+		w := csv.NewWriter(&csvBuf)
+		w.Write([]string{"client", "invoice_count", "invoiced", "paid", "outstanding", "currency", "written_off"})
+		for _, s := range summaries {
+			w.Write([]string{s.ClientName, fmt.Sprintf("%d", s.InvoiceCount),
+				s.Invoiced.String(), s.Paid.String(), s.Outstanding.String(), baseCurrency, s.WrittenOff.String()})
+		}
+		w.Write([]string{"TOTAL", "", totalInvoiced.String(), totalPaid.String(),
+			totalOutstanding.String(), baseCurrency, totalWrittenOff.String()})
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, nil, fmt.Errorf("failed to build CSV: %w", err)
+		}
+
+		formatAmount := func(amount money.Cents) string {
+			return amount.Format(baseCurrency)
+		}
+
+		// Written-off amounts are only shown when there are any
+		var text string
+		if markdown {
+			headers := []string{"Client", "Invoices", "Invoiced", "Paid", "Outstanding"}
+			if totalWrittenOff != 0 {
+				headers = append(headers, "Written off")
+			}
+			tableRows := make([][]string, 0, len(summaries)+1)
+			for _, s := range summaries {
+				row := []string{s.ClientName, fmt.Sprintf("%d", s.InvoiceCount),
+					formatAmount(s.Invoiced), formatAmount(s.Paid), formatAmount(s.Outstanding)}
+				if totalWrittenOff != 0 {
+					row = append(row, formatAmount(s.WrittenOff))
+				}
+				tableRows = append(tableRows, row)
+			}
+			total := []string{"**Total**", "", "**" + formatAmount(totalInvoiced) + "**",
+				"**" + formatAmount(totalPaid) + "**", "**" + formatAmount(totalOutstanding) + "**"}
+			if totalWrittenOff != 0 {
+				total = append(total, "**"+formatAmount(totalWrittenOff)+"**")
+			}
+			tableRows = append(tableRows, total)
+			text = fmt.Sprintf("### Income summary for %s\n\n_Invoiced by issue date, paid by payment date, in %s_\n\n", periodLabel, baseCurrency) +
+				markdownTable(headers, tableRows, 1, 2, 3, 4, 5)
+		} else {
+			text = fmt.Sprintf("Income summary for %s (invoiced by issue date, paid by payment date, in %s):\n", periodLabel, baseCurrency)
+			for _, s := range summaries {
+				text += fmt.Sprintf("- %s: invoiced %s, paid %s, outstanding %s", s.ClientName, formatAmount(s.Invoiced), formatAmount(s.Paid), formatAmount(s.Outstanding))
+				if s.WrittenOff != 0 {
+					text += ", written off " + formatAmount(s.WrittenOff)
+				}
+				text += fmt.Sprintf(" (%d invoices)\n", s.InvoiceCount)
+			}
+			if len(summaries) == 0 {
+				text += "No invoices found for this period.\n"
+			}
+			text += fmt.Sprintf("Total: invoiced %s, paid %s, outstanding %s", formatAmount(totalInvoiced), formatAmount(totalPaid), formatAmount(totalOutstanding))
+			if totalWrittenOff != 0 {
+				text += ", written off " + formatAmount(totalWrittenOff)
+			}
+			text += "\n"
+		}
+		if len(unconverted) > 0 {
+			text += fmt.Sprintf("Not included (no exchange rate to %s): %s. Use 'set_exchange_rate' or 'fetch_exchange_rates'.\n",
+				baseCurrency, formatCurrencyTotals(unconverted))
+		}
+
+		var csvPath string
+		if args.SaveCSV {
+			homeDir, _ := os.UserHomeDir()
+			csvPath = filepath.Join(homeDir, "Downloads", fmt.Sprintf("income_summary_%d.csv", args.Year))
+			if err := os.WriteFile(csvPath, []byte(csvBuf.String()), 0644); err != nil {
+				return nil, nil, fmt.Errorf("failed to write CSV: %w", err)
+			}
+			text += fmt.Sprintf("CSV saved to: %s\n", csvPath)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+				&mcp.TextContent{Text: csvBuf.String()},
+			},
+		}, &taxYearSummaryResult{
+			PeriodStart:     start.Format("2006-01-02"),
+			PeriodEnd:       end.Format("2006-01-02"),
+			Clients:         summaries,
+			TotalInvoiced:   totalInvoiced,
+			TotalPaid:       totalPaid,
+			TotalWrittenOff: totalWrittenOff,
+			BaseCurrency:    baseCurrency,
+			Unconverted:     unconverted,
+			CSV:             csvBuf.String(),
+			CSVPath:         csvPath,
+		}, nil
+	})
+
+	// Tax Report tool
+	type taxReportArgs struct {
+		Period string `json:"period,omitempty" jsonschema:"Filing period (e.g. 'last quarter' 'Q1 2025' 'H1 2025' 'March 2025' 'last month'; default: last quarter)"`
+		Basis  string `json:"basis,omitempty" jsonschema:"invoice (default) to count invoices by issue date, or cash to count paid invoices by payment date"`
+		Format string `json:"format,omitempty" jsonschema:"Output format: text (default) or markdown"`
+	}
+
+	type taxRateSummary struct {
+		Currency     string      `json:"currency"`
+		TaxRate      float64     `json:"tax_rate"`
+		TaxTreatment string      `json:"tax_treatment"`
+		InvoiceCount int         `json:"invoice_count"`
+		Net          money.Cents `json:"net"`
+		Tax          money.Cents `json:"tax"`
+		Gross        money.Cents `json:"gross"`
+		Withheld     money.Cents `json:"withheld"`
+	}
+
+	type taxReportResult struct {
+		PeriodStart string                 `json:"period_start"`
+		PeriodEnd   string                 `json:"period_end"`
+		Basis       string                 `json:"basis"`
+		Rates       []taxRateSummary       `json:"rates"`
+		Net         map[string]money.Cents `json:"net" jsonschema:"Net amount per currency"`
+		Tax         map[string]money.Cents `json:"tax" jsonschema:"Tax per currency"`
+		Gross       map[string]money.Cents `json:"gross" jsonschema:"Gross amount per currency"`
+		Withheld    map[string]money.Cents `json:"withheld" jsonschema:"Amount withheld by clients per currency"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "tax_report",
+		Description: "Sum net, tax and gross amounts per tax rate and currency for a month or quarter, as needed for VAT/GST returns",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args taxReportArgs) (*mcp.CallToolResult, *taxReportResult, error) {
+		markdown, err := parseOutputFormat(args.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+		if args.Period == "" {
+			args.Period = "last quarter"
+		}
+		if args.Basis == "" {
+			args.Basis = "invoice"
+		}
+
+		start, end, err := h.parsePeriod(ctx, args.Period)
+		if err != nil {
+			return nil, nil, validationError("invalid period: %w", err)
+		}
+
+		var dateFilter string
+		switch args.Basis {
+		case "invoice":
+			dateFilter = "i.issue_date >= ? AND i.issue_date <= ? AND i.status != 'cancelled'"
+		case "cash":
+			dateFilter = "i.status = 'paid' AND i.paid_date >= ? AND i.paid_date <= ?"
+		default:
+			return nil, nil, validationError("invalid basis '%s': must be invoice or cash", args.Basis)
+		}
+
+		rows, err := db.QueryContext(ctx, `
+			SELECT i.currency, COALESCE(i.tax_rate, 0), COALESCE(i.tax_treatment, 'standard'), COUNT(*),
+			       SUM(i.total_cents), SUM(i.tax_cents), SUM(i.withholding_cents)
+			FROM invoices i
+			WHERE `+dateFilter+`
+			GROUP BY i.currency, COALESCE(i.tax_rate, 0), COALESCE(i.tax_treatment, 'standard')
+			ORDER BY i.currency, COALESCE(i.tax_rate, 0) DESC, COALESCE(i.tax_treatment, 'standard') DESC
+		`, start.Format("2006-01-02"), end.Format("2006-01-02"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to summarize invoices: %w", err)
+		}
+		defer rows.Close()
+
+		var summaries []taxRateSummary
+		netTotals := map[string]money.Cents{}
+		taxTotals := map[string]money.Cents{}
+		grossTotals := map[string]money.Cents{}
+		withheldTotals := map[string]money.Cents{}
+		for rows.Next() {
+			var s taxRateSummary
+			if err := rows.Scan(&s.Currency, &s.TaxRate, &s.TaxTreatment, &s.InvoiceCount, &s.Gross, &s.Tax, &s.Withheld); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan tax summary: %w", err)
+			}
+			s.Net = s.Gross - s.Tax
+			summaries = append(summaries, s)
+			netTotals[s.Currency] += s.Net
+			taxTotals[s.Currency] += s.Tax
+			grossTotals[s.Currency] += s.Gross
+			if s.Withheld > 0 {
+				withheldTotals[s.Currency] += s.Withheld
+			}
+		}
+
+		basisLabel := "by invoice issue date"
+		if args.Basis == "cash" {
+			basisLabel = "by payment date"
+		}
+		periodLabel := fmt.Sprintf("%s to %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+		// Reverse-charge sales are reported separately from other 0% sales
+		rateLabel := func(s taxRateSummary) string {
+			if s.TaxTreatment == taxTreatmentReverseCharge {
+				return fmt.Sprintf("%g%% (reverse charge)", s.TaxRate)
+			}
+			return fmt.Sprintf("%g%%", s.TaxRate)
+		}
+
+		var text string
+		if markdown {
+			tableRows := make([][]string, 0, len(summaries)+1)
+			for _, s := range summaries {
+				tableRows = append(tableRows, []string{rateLabel(s), fmt.Sprintf("%d", s.InvoiceCount),
+					s.Net.Format(s.Currency), s.Tax.Format(s.Currency), s.Gross.Format(s.Currency)})
+			}
+			if len(summaries) > 0 {
+				tableRows = append(tableRows, []string{"**Total**", "", "**" + formatCurrencyTotals(netTotals) + "**",
+					"**" + formatCurrencyTotals(taxTotals) + "**", "**" + formatCurrencyTotals(grossTotals) + "**"})
+			}
+			text = fmt.Sprintf("### Tax report for %s\n\n_%s_\n\n", periodLabel, basisLabel) +
+				markdownTable([]string{"Rate", "Invoices", "Net", "Tax", "Gross"}, tableRows, 1, 2, 3, 4)
+			if len(withheldTotals) > 0 {
+				text += fmt.Sprintf("\nWithheld by clients: %s\n", formatCurrencyTotals(withheldTotals))
+			}
+		} else {
+			text = fmt.Sprintf("Tax report for %s (%s):\n", periodLabel, basisLabel)
+			for _, s := range summaries {
+				text += fmt.Sprintf("- %s: net %s, tax %s, gross %s (%d invoices)\n", rateLabel(s),
+					s.Net.Format(s.Currency), s.Tax.Format(s.Currency), s.Gross.Format(s.Currency), s.InvoiceCount)
+			}
+			if len(summaries) == 0 {
+				text += "No invoices found for this period.\n"
+			} else {
+				text += fmt.Sprintf("Total: net %s; tax %s; gross %s\n",
+					formatCurrencyTotals(netTotals), formatCurrencyTotals(taxTotals), formatCurrencyTotals(grossTotals))
+			}
+			if len(withheldTotals) > 0 {
+				text += fmt.Sprintf("Withheld by clients: %s\n", formatCurrencyTotals(withheldTotals))
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &taxReportResult{
+			PeriodStart: start.Format("2006-01-02"),
+			PeriodEnd:   end.Format("2006-01-02"),
+			Basis:       args.Basis,
+			Rates:       summaries,
+			Net:         netTotals,
+			Tax:         taxTotals,
+			Gross:       grossTotals,
+			Withheld:    withheldTotals,
+		}, nil
+	})
+
+	// Recap tool
+	type recapArgs struct {
+		Period     string `json:"period,omitempty" jsonschema:"Period to recap (e.g. 'yesterday' 'today' 'last week' 'this week' or a date; default: yesterday)"`
+		ClientName string `json:"client_name,omitempty" jsonschema:"Limit the recap to one client (optional)"`
+		Format     string `json:"format,omitempty" jsonschema:"Output format: text (default) or markdown"`
+	}
+
+	type clientRecap struct {
+		ClientName   string   `json:"client_name"`
+		Hours        float64  `json:"hours"`
+		Descriptions []string `json:"descriptions"`
+	}
+
+	type recapResult struct {
+		StartDate  string         `json:"start_date"`
+		EndDate    string         `json:"end_date"`
+		TotalHours float64        `json:"total_hours"`
+		Clients    []*clientRecap `json:"clients"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "recap",
+		Description: "Summarize what you worked on for a day or week, grouped by client, ready to paste into a standup or status email",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args recapArgs) (*mcp.CallToolResult, *recapResult, error) {
+		markdown, err := parseOutputFormat(args.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+		if args.Period == "" {
+			args.Period = "yesterday"
+		}
+
+		startDate, endDate, err := h.parseRecapPeriod(ctx, args.Period)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		query := `
+			SELECT cl.name, te.hours, te.description
+			FROM time_entries te
+			JOIN contracts ct ON te.contract_id = ct.id
+			JOIN clients cl ON ct.client_id = cl.id
+			WHERE te.date >= ? AND te.date <= ?
+		`
+		queryArgs := []interface{}{startDate.Format("2006-01-02"), endDate.Format("2006-01-02")}
+
+		if args.ClientName != "" {
+			clientID, err := h.getClientIDByName(ctx, args.ClientName)
+			if err != nil {
+				return nil, nil, fmt.Errorf("client not found: %w", err)
+			}
+			query += " AND cl.id = ?"
+			queryArgs = append(queryArgs, clientID)
+		}
+
+		query += " ORDER BY cl.name, te.date, te.created_at"
+
+		rows, err := db.QueryContext(ctx, query, queryArgs...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load entries: %w", err)
+		}
+		defer rows.Close()
+
+		var recaps []*clientRecap
+		byClient := map[string]*clientRecap{}
+		seen := map[string]bool{}
+		var totalHours float64
+
+		for rows.Next() {
+			var clientName, description string
+			var hours float64
+			if err := rows.Scan(&clientName, &hours, &description); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan entry: %w", err)
+			}
+
+			r, ok := byClient[clientName]
+			if !ok {
+				r = &clientRecap{ClientName: clientName}
+				byClient[clientName] = r
+				recaps = append(recaps, r)
+			}
+			r.Hours += hours
+			totalHours += hours
+
+			description = strings.TrimSpace(description)
+			key := clientName + "\x00" + strings.ToLower(description)
+			if description != "" && !seen[key] {
+				seen[key] = true
+				r.Descriptions = append(r.Descriptions, description)
+			}
+		}
+
+		label := startDate.Format("Mon Jan 2, 2006")
+		if !endDate.Equal(startDate) {
+			label = fmt.Sprintf("%s - %s", startDate.Format("Mon Jan 2"), endDate.Format("Mon Jan 2, 2006"))
+		}
+
+		var text string
+		if markdown && len(recaps) > 0 {
+			tableRows := make([][]string, 0, len(recaps))
+			for _, r := range recaps {
+				tableRows = append(tableRows, []string{r.ClientName, fmt.Sprintf("%.2f", r.Hours), strings.Join(r.Descriptions, "; ")})
+			}
+			text = fmt.Sprintf("### Recap for %s (%.2f hours)\n\n", label, totalHours) +
+				markdownTable([]string{"Client", "Hours", "Work"}, tableRows, 1)
+		} else {
+			text = fmt.Sprintf("Recap for %s (%.2f hours):\n", label, totalHours)
+			for _, r := range recaps {
+				text += fmt.Sprintf("- %s (%.2fh)", r.ClientName, r.Hours)
+				if len(r.Descriptions) > 0 {
+					text += ": " + strings.Join(r.Descriptions, "; ")
+				}
+				text += "\n"
+			}
+			if len(recaps) == 0 {
+				text += "No time logged for this period.\n"
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &recapResult{
+			StartDate:  startDate.Format("2006-01-02"),
+			EndDate:    endDate.Format("2006-01-02"),
+			TotalHours: totalHours,
+			Clients:    recaps,
+		}, nil
+	})
+
+	// Calendar View tool
+	type calendarViewArgs struct {
+		Month      string `json:"month,omitempty" jsonschema:"Month to show (e.g. 'this month' 'last month' 'January 2025'; default: this month)"`
+		ClientName string `json:"client_name,omitempty" jsonschema:"Only count hours for one client, leaving out internal time (optional)"`
+	}
+
+	type calendarViewResult struct {
+		StartDate  string             `json:"start_date"`
+		EndDate    string             `json:"end_date"`
+		HoursByDay map[string]float64 `json:"hours_by_day"`
+		TotalHours float64            `json:"total_hours"`
+		EmptyDays  []string           `json:"empty_days" jsonschema:"Working days up to today with no hours logged"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "calendar_view",
+		Description: "Render a month calendar grid (markdown) with hours logged per day, highlighting working days with no time logged",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args calendarViewArgs) (*mcp.CallToolResult, *calendarViewResult, error) {
+		if args.Month == "" {
+			args.Month = "this month"
+		}
+
+		startDate, endDate, err := h.parsePeriod(ctx, args.Month)
+		if err != nil {
+			return nil, nil, validationError("invalid month: %w", err)
+		}
+		startDate, endDate = truncateDay(startDate), truncateDay(endDate)
+
+		query := `
+			SELECT te.date, SUM(te.hours)
+			FROM time_entries te
+			JOIN contracts ct ON te.contract_id = ct.id
+			WHERE te.date >= ? AND te.date <= ?
+		`
+		queryArgs := []interface{}{startDate.Format("2006-01-02"), endDate.Format("2006-01-02")}
+
+		if args.ClientName != "" {
+			clientID, err := h.getClientIDByName(ctx, args.ClientName)
+			if err != nil {
+				return nil, nil, fmt.Errorf("client not found: %w", err)
+			}
+			query += " AND ct.client_id = ?"
+			queryArgs = append(queryArgs, clientID)
+		}
+
+		query += " GROUP BY te.date"
+
+		rows, err := db.QueryContext(ctx, query, queryArgs...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load hours: %w", err)
+		}
+		defer rows.Close()
+
+		hoursByDay := map[string]float64{}
+		var totalHours float64
+		for rows.Next() {
+			var date time.Time
+			var hours float64
+			if err := rows.Scan(&date, &hours); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan hours: %w", err)
+			}
+			hoursByDay[date.Format("2006-01-02")] += hours
+			totalHours += hours
+		}
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		// Internal work fills a day too, unless one client's hours are shown
+		if args.ClientName == "" {
+			internal, err := db.QueryContext(ctx, `
+				SELECT date, SUM(hours) FROM internal_entries
+				WHERE date >= ? AND date <= ?
+				GROUP BY date
+			`, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to load internal hours: %w", err)
+			}
+			defer internal.Close()
+			for internal.Next() {
+				var date time.Time
+				var hours float64
+				if err := internal.Scan(&date, &hours); err != nil {
+					return nil, nil, fmt.Errorf("failed to scan internal hours: %w", err)
+				}
+				hoursByDay[date.Format("2006-01-02")] += hours
+				totalHours += hours
+			}
+			if err := internal.Err(); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		workWeek, err := parseWorkWeek(h.getSetting(ctx, "work_week"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid work_week setting: %w", err)
+		}
+
+		holidays, err := h.getHolidays(ctx, startDate, endDate)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		today := h.today(ctx)
+		var emptyDays []string
+
+		text := fmt.Sprintf("### %s (%.2f hours)\n\n", startDate.Format("January 2006"), totalHours)
+		text += "| Mon | Tue | Wed | Thu | Fri | Sat | Sun |\n"
+		text += "|-----|-----|-----|-----|-----|-----|-----|\n"
+
+		// Pad the first week so the grid starts on Monday
+		offset := (int(startDate.Weekday()) + 6) % 7
+		cells := make([]string, offset)
+
+		for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
+			key := d.Format("2006-01-02")
+			_, isHoliday := holidays[key]
+			isWorkday := workWeek[d.Weekday()] && !isHoliday
+
+			cell := fmt.Sprintf("%d", d.Day())
+			if hours, ok := hoursByDay[key]; ok {
+				cell += fmt.Sprintf(" %sh", strconv.FormatFloat(hours, 'f', -1, 64))
+			} else if isWorkday && !d.After(today) {
+				cell = fmt.Sprintf("**%d ⚠**", d.Day())
+				emptyDays = append(emptyDays, key)
+			}
+			cells = append(cells, cell)
+
+			if len(cells) == 7 {
+				text += "| " + strings.Join(cells, " | ") + " |\n"
+				cells = cells[:0]
+			}
+		}
+		if len(cells) > 0 {
+			for len(cells) < 7 {
+				cells = append(cells, "")
+			}
+			text += "| " + strings.Join(cells, " | ") + " |\n"
+		}
+
+		if len(emptyDays) > 0 {
+			text += fmt.Sprintf("\n⚠ %d working day(s) with no hours logged: %s\n", len(emptyDays), strings.Join(emptyDays, ", "))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &calendarViewResult{
+			StartDate:  startDate.Format("2006-01-02"),
+			EndDate:    endDate.Format("2006-01-02"),
+			HoursByDay: hoursByDay,
+			TotalHours: totalHours,
+			EmptyDays:  emptyDays,
+		}, nil
+	})
+
+	// Find Missing Days tool
+	type findMissingDaysArgs struct {
+		StartDate string `json:"start_date,omitempty" jsonschema:"Start date (YYYY-MM-DD or natural language; default: first day of this month)"`
+		EndDate   string `json:"end_date,omitempty" jsonschema:"End date (YYYY-MM-DD or natural language; default: today)"`
+		Format    string `json:"format,omitempty" jsonschema:"Output format: text (default) or markdown"`
+	}
+
+	type findMissingDaysResult struct {
+		StartDate       string   `json:"start_date"`
+		EndDate         string   `json:"end_date"`
+		WorkingDays     int      `json:"working_days"`
+		MissingDays     []string `json:"missing_days"`
+		SkippedHolidays []string `json:"skipped_holidays"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "find_missing_days",
+		Description: "List working days in a date range with no hours logged, for clients or internal work, using the configured work week and holiday calendar",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args findMissingDaysArgs) (*mcp.CallToolResult, *findMissingDaysResult, error) {
+		markdown, err := parseOutputFormat(args.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		endDate := h.today(ctx)
+		startDate := time.Date(endDate.Year(), endDate.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+		if args.StartDate != "" {
+			sd, err := h.parseDate(ctx, args.StartDate)
+			if err != nil {
+				return nil, nil, validationError("invalid start date: %w", err)
+			}
+			startDate = truncateDay(sd)
+		}
+		if args.EndDate != "" {
+			ed, err := h.parseDate(ctx, args.EndDate)
+			if err != nil {
+				return nil, nil, validationError("invalid end date: %w", err)
+			}
+			endDate = truncateDay(ed)
+		}
+		if endDate.Before(startDate) {
+			return nil, nil, validationError("end date must not be before start date")
+		}
+
+		workWeek, err := parseWorkWeek(h.getSetting(ctx, "work_week"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid work_week setting: %w", err)
+		}
+
+		holidays, err := h.getHolidays(ctx, startDate, endDate)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// A day spent on internal work such as admin is not missing
+		rows, err := db.QueryContext(ctx, `
+			SELECT date FROM time_entries WHERE date >= ?1 AND date <= ?2 AND hours > 0
+			UNION
+			SELECT date FROM internal_entries WHERE date >= ?1 AND date <= ?2 AND hours > 0
+		`, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load logged days: %w", err)
+		}
+		defer rows.Close()
+
+		logged := map[string]bool{}
+		for rows.Next() {
+			var date time.Time
+			if err := rows.Scan(&date); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan date: %w", err)
+			}
+			logged[date.Format("2006-01-02")] = true
+		}
+
+		var missing []string
+		var skippedHolidays []string
+		workdays := 0
+		for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
+			if !workWeek[d.Weekday()] {
+				continue
+			}
+			key := d.Format("2006-01-02")
+			if name, ok := holidays[key]; ok {
+				skippedHolidays = append(skippedHolidays, fmt.Sprintf("%s (%s)", key, name))
+				continue
+			}
+			workdays++
+			if !logged[key] {
+				missing = append(missing, key)
+			}
+		}
+
+		text := fmt.Sprintf("Checked %d working days from %s to %s:\n",
+			workdays, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+		if len(missing) == 0 {
+			text += "No missing days - every working day has hours logged.\n"
+		} else {
+			text += fmt.Sprintf("%d day(s) with no hours logged:\n", len(missing))
+			var tableRows [][]string
+			for _, day := range missing {
+				d, _ := time.Parse("2006-01-02", day)
+				if markdown {
+					tableRows = append(tableRows, []string{day, d.Weekday().String()})
+					continue
+				}
+				text += fmt.Sprintf("- %s (%s)\n", day, d.Weekday())
+			}
+			if markdown {
+				text += "\n" + markdownTable([]string{"Date", "Weekday"}, tableRows)
+			}
+		}
+		if len(skippedHolidays) > 0 {
+			text += fmt.Sprintf("Skipped holidays: %s\n", strings.Join(skippedHolidays, ", "))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &findMissingDaysResult{
+			StartDate:       startDate.Format("2006-01-02"),
+			EndDate:         endDate.Format("2006-01-02"),
+			WorkingDays:     workdays,
+			MissingDays:     missing,
+			SkippedHolidays: skippedHolidays,
+		}, nil
+	})
+
+	// Unbilled Summary tool
+	type unbilledSummaryArgs struct {
+		ClientName string `json:"client_name,omitempty" jsonschema:"Limit the summary to one client (optional)"`
+		Format     string `json:"format,omitempty" jsonschema:"Output format: text (default) or markdown"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "unbilled_summary",
+		Description: "Show hours and amounts not yet invoiced per contract, with a total converted into the base currency",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args unbilledSummaryArgs) (*mcp.CallToolResult, *unbilled, error) {
+		markdown, err := parseOutputFormat(args.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var clientID int
+		if args.ClientName != "" {
+			if clientID, err = h.getClientIDByName(ctx, args.ClientName); err != nil {
+				return nil, nil, fmt.Errorf("client not found: %w", err)
+			}
+		}
+		summary, err := h.unbilledSummary(ctx, clientID, time.Time{}, time.Time{})
+		if err != nil {
+			return nil, nil, err
+		}
+		contracts, totals, totalHours, baseCurrency := summary.Contracts, summary.Totals, summary.TotalHours, summary.BaseCurrency
+		converted := formatConvertedTotal(baseCurrency, summary.BaseTotal, summary.Unconverted)
+
+		var text string
+		if markdown {
+			tableRows := make([][]string, 0, len(contracts)+2)
+			for _, c := range contracts {
+				tableRows = append(tableRows, []string{c.ClientName, c.ContractNumber,
+					fmt.Sprintf("%s to %s", c.FirstDate.Format("2006-01-02"), c.LastDate.Format("2006-01-02")),
+					fmt.Sprintf("%.2f", c.Hours), c.Amount.Format(c.Currency)})
+			}
+			tableRows = append(tableRows, []string{"**Total**", "", "", fmt.Sprintf("**%.2f**", totalHours), "**" + formatCurrencyTotals(totals) + "**"})
+			tableRows = append(tableRows, []string{"**Total in " + baseCurrency + "**", "", "", "", "**" + converted + "**"})
+			text = "### Unbilled work\n\n" +
+				markdownTable([]string{"Client", "Contract", "Dates", "Hours", "Amount"}, tableRows, 3, 4)
+		} else {
+			text = fmt.Sprintf("Unbilled work (%.2f hours):\n", totalHours)
+			for _, c := range contracts {
+				text += fmt.Sprintf("- %s %s: %.2f hours = %s (%s to %s)\n", c.ClientName, c.ContractNumber,
+					c.Hours, c.Amount.Format(c.Currency), c.FirstDate.Format("2006-01-02"), c.LastDate.Format("2006-01-02"))
+			}
+			if len(contracts) == 0 {
+				text += "Everything has been invoiced.\n"
+			}
+			text += fmt.Sprintf("Total: %s\n", formatCurrencyTotals(totals))
+			text += fmt.Sprintf("Total in %s: %s\n", baseCurrency, converted)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, summary, nil
+	})
+}
+
+// contractUnbilled is the work not yet invoiced on a contract
+type contractUnbilled struct {
+	ClientName     string      `json:"client_name"`
+	ContractNumber string      `json:"contract_number"`
+	Currency       string      `json:"currency"`
+	HourlyRate     money.Cents `json:"hourly_rate"`
+	Hours          float64     `json:"hours"`
+	Amount         money.Cents `json:"amount"`
+	FirstDate      time.Time   `json:"first_date"`
+	LastDate       time.Time   `json:"last_date"`
+}
+
+// unbilled is the work not yet invoiced, per contract and in total
+type unbilled struct {
+	Contracts    []contractUnbilled     `json:"contracts"`
+	TotalHours   float64                `json:"total_hours"`
+	Totals       map[string]money.Cents `json:"totals"`
+	BaseCurrency string                 `json:"base_currency"`
+	BaseTotal    money.Cents            `json:"base_total"`
+	Unconverted  []string               `json:"unconverted"`
+}
+
+// unbilledSummary totals the hours not yet invoiced per contract, for one
+// client or all of them when clientID is 0, worked between from and to; a
+// zero time leaves that end open
+func (h *Handler) unbilledSummary(ctx context.Context, clientID int, from, to time.Time) (*unbilled, error) {
+	query := `
+		SELECT ct.id, cl.name, ct.contract_number, ct.currency, ` + effectiveRateSQL("ct", "date('now', 'localtime')") + `,
+		       SUM(te.hours), MIN(te.date), MAX(te.date)
+		FROM time_entries te
+		JOIN contracts ct ON te.contract_id = ct.id
+		JOIN clients cl ON ct.client_id = cl.id
+		WHERE te.invoice_id IS NULL AND te.billable
+	`
+	filter := "te.invoice_id IS NULL AND te.billable"
+	queryArgs := []interface{}{}
+
+	if clientID != 0 {
+		query += " AND cl.id = ?"
+		filter += " AND ct.client_id = ?"
+		queryArgs = append(queryArgs, clientID)
+	}
+	if !from.IsZero() {
+		query += " AND te.date >= ?"
+		filter += " AND te.date >= ?"
+		queryArgs = append(queryArgs, from.Format("2006-01-02"))
+	}
+	if !to.IsZero() {
+		query += " AND te.date <= ?"
+		filter += " AND te.date <= ?"
+		queryArgs = append(queryArgs, to.Format("2006-01-02"))
+	}
+
+	query += " GROUP BY ct.id ORDER BY cl.name, ct.contract_number"
+
+	// Entries are priced one by one, rounded to the cent, as on an invoice
+	items, err := h.priceStoredEntries(ctx, filter, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	amounts := map[int]money.Cents{}
+	for _, item := range items {
+		amounts[item.ContractID] += item.Amount
+	}
+
+	rows, err := h.db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize unbilled hours: %w", err)
+	}
+	defer rows.Close()
+
+	summary := &unbilled{Totals: map[string]money.Cents{}}
+	for rows.Next() {
+		var c contractUnbilled
+		var contractID int
+		var firstDate, lastDate string
+		if err := rows.Scan(&contractID, &c.ClientName, &c.ContractNumber, &c.Currency, &c.HourlyRate,
+			&c.Hours, &firstDate, &lastDate); err != nil {
+			return nil, fmt.Errorf("failed to scan unbilled hours: %w", err)
+		}
+		c.Amount = amounts[contractID]
+		// Aggregates lose the DATE column type, so parse the stored text
+		c.FirstDate, _ = time.Parse("2006-01-02", firstDate[:min(10, len(firstDate))])
+		c.LastDate, _ = time.Parse("2006-01-02", lastDate[:min(10, len(lastDate))])
+		summary.Contracts = append(summary.Contracts, c)
+		summary.Totals[c.Currency] += c.Amount
+		summary.TotalHours += c.Hours
+	}
+
+	summary.BaseCurrency = h.baseCurrency(ctx)
+	summary.BaseTotal, summary.Unconverted, err = h.convertTotals(ctx, summary.Totals, summary.BaseCurrency, h.today(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// parseRecapPeriod accepts either a period ("last week") or a single date ("yesterday")
+func (h *Handler) parseRecapPeriod(ctx context.Context, period string) (time.Time, time.Time, error) {
+	if start, end, err := h.parsePeriod(ctx, period); err == nil {
+		return truncateDay(start), truncateDay(end), nil
+	}
+
+	date, err := h.parseDate(ctx, period)
+	if err != nil {
+		return time.Time{}, time.Time{}, validationError("invalid period: %s", period)
+	}
+	date = truncateDay(date)
+	return date, date, nil
+}
+
+// truncateDay drops the time-of-day portion of t
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// countWeekdays counts Monday-Friday dates between from and to inclusive
+func countWeekdays(from, to time.Time) int {
+	count := 0
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			count++
+		}
+	}
+	return count
+}
+
+// formatCurrencyTotals renders per-currency amounts without mixing currencies
+func formatCurrencyTotals(totals map[string]money.Cents) string {
+	if len(totals) == 0 {
+		return "0.00"
+	}
+
+	currencies := make([]string, 0, len(totals))
+	for currency := range totals {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	text := ""
+	for i, currency := range currencies {
+		if i > 0 {
+			text += ", "
+		}
+		text += totals[currency].Format(currency)
+	}
+	return text
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/store"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// resourceScheme prefixes the URIs of the resources the server exposes
+const resourceScheme = "hours://"
+
+// hoursResource is a resource, or a resource template when uri has a
+// {placeholder}, with the tables its data is read from so subscribers can
+// be told when it changes
+type hoursResource struct {
+	uri         string
+	name        string
+	description string
+	tables      []string
+	// read returns the data at uri, or a NOT_FOUND error when there is none
+	read func(ctx context.Context, uri string) (any, error)
+}
+
+func (h *Handler) resources() []hoursResource {
+	return []hoursResource{
+		{
+			uri:         resourceScheme + "clients",
+			name:        "clients",
+			description: "Every client, including archived ones",
+			tables:      []string{"clients", "client_aliases"},
+			read: func(ctx context.Context, uri string) (any, error) {
+				return h.store.Clients.List(ctx, true)
+			},
+		},
+		{
+			uri:         resourceScheme + "clients/{name}",
+			name:        "client",
+			description: "A client with its recipients, active contracts, unbilled work and open invoices; banking details are masked",
+			tables:      []string{"clients", "client_aliases", "recipients", "payment_details", "payment_methods", "contracts", "contract_rates", "contract_services", "time_entries", "invoices"},
+			read: func(ctx context.Context, uri string) (any, error) {
+				name, err := resourceParam(uri, "clients/")
+				if err != nil {
+					return nil, err
+				}
+				clientID, err := h.getClientIDByName(ctx, name)
+				if err != nil {
+					return nil, err
+				}
+				d, err := h.loadClientDetails(ctx, clientID)
+				if err != nil {
+					return nil, err
+				}
+				d.PaymentDetails = maskPaymentDetails(d.PaymentDetails)
+				return d, nil
+			},
+		},
+		{
+			uri:         resourceScheme + "contracts",
+			name:        "contracts",
+			description: "Every contract of active clients",
+			tables:      []string{"contracts", "contract_rates", "clients"},
+			read: func(ctx context.Context, uri string) (any, error) {
+				return h.store.Contracts.List(ctx, store.ContractFilter{})
+			},
+		},
+		{
+			uri:         resourceScheme + "invoices",
+			name:        "invoices",
+			description: "Every invoice, most recently issued first",
+			tables:      []string{"invoices", "clients"},
+			read: func(ctx context.Context, uri string) (any, error) {
+				return h.store.Invoices.List(ctx, store.InvoiceFilter{})
+			},
+		},
+		{
+			uri:         resourceScheme + "invoices/{number}",
+			name:        "invoice",
+			description: "An invoice with the time entries and expenses it bills",
+			tables:      []string{"invoices", "time_entries", "expenses"},
+			read: func(ctx context.Context, uri string) (any, error) {
+				number, err := resourceParam(uri, "invoices/")
+				if err != nil {
+					return nil, err
+				}
+				invoice, err := h.store.Invoices.ByNumber(ctx, number)
+				if err != nil {
+					return nil, err
+				}
+				if invoice.TimeEntries, err = h.store.Entries.ForInvoice(ctx, invoice.ID); err != nil {
+					return nil, err
+				}
+				expenses, err := h.store.Invoices.Expenses(ctx, invoice.ID)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{
+					"invoice":  invoice,
+					"expenses": expenses,
+				}, nil
+			},
+		},
+		{
+			uri:         resourceScheme + "reports/unbilled",
+			name:        "unbilled",
+			description: "Hours and amounts not yet invoiced per contract, with a total in the base currency",
+			tables:      []string{"time_entries", "contracts", "contract_rates", "contract_services", "contract_rate_rules", "exchange_rates", "settings"},
+			read: func(ctx context.Context, uri string) (any, error) {
+				return h.unbilledSummary(ctx, 0, time.Time{}, time.Time{})
+			},
+		},
+	}
+}
+
+// registerResources exposes clients, contracts, invoices and the unbilled
+// report as JSON resources
+func registerResources(server *mcp.Server, h *Handler) {
+	for _, r := range h.resources() {
+		handler := resourceHandler(r)
+		if strings.Contains(r.uri, "{") {
+			server.AddResourceTemplate(&mcp.ResourceTemplate{
+				URITemplate: r.uri,
+				Name:        r.name,
+				Description: r.description,
+				MIMEType:    "application/json",
+			}, handler)
+			continue
+		}
+		server.AddResource(&mcp.Resource{
+			URI:         r.uri,
+			Name:        r.name,
+			Description: r.description,
+			MIMEType:    "application/json",
+		}, handler)
+	}
+}
+
+func resourceHandler(r hoursResource) mcp.ResourceHandler {
+	return func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		uri := req.Params.URI
+		data, err := r.read(ctx, uri)
+		if err != nil {
+			if code, _ := classifyError(err); code == codeNotFound {
+				return nil, mcp.ResourceNotFoundError(uri)
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", uri, err)
+		}
+		text, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", uri, err)
+		}
+		return &mcp.ReadResourceResult{
+			Contents: []*mcp.ResourceContents{
+				{URI: uri, MIMEType: "application/json", Text: string(text)},
+			},
+		}, nil
+	}
+}
+
+// resourceParam returns the unescaped part of uri after the scheme and
+// prefix, e.g. the client name of hours://clients/Acme%20Corp
+func resourceParam(uri, prefix string) (string, error) {
+	value, ok := strings.CutPrefix(uri, resourceScheme+prefix)
+	if !ok || value == "" || strings.Contains(value, "/") {
+		return "", sql.ErrNoRows
+	}
+	return url.PathUnescape(value)
+}
+
+// resourceSubscriptions counts the sessions subscribed to each resource
+// URI, so changes are only announced for resources someone is watching
+type resourceSubscriptions struct {
+	mu   sync.Mutex
+	uris map[string]int
+}
+
+func newResourceSubscriptions() *resourceSubscriptions {
+	return &resourceSubscriptions{uris: map[string]int{}}
+}
+
+func (s *resourceSubscriptions) subscribe(ctx context.Context, req *mcp.SubscribeRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uris[req.Params.URI]++
+	return nil
+}
+
+func (s *resourceSubscriptions) unsubscribe(ctx context.Context, req *mcp.UnsubscribeRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.uris[req.Params.URI]--; s.uris[req.Params.URI] <= 0 {
+		delete(s.uris, req.Params.URI)
+	}
+	return nil
+}
+
+// notifyResourceChanges tells subscribers of every resource read from one
+// of tables that it has changed
+func (h *Handler) notifyResourceChanges(ctx context.Context, server *mcp.Server, subs *resourceSubscriptions, tables []string) {
+	changed := map[string]bool{}
+	for _, table := range tables {
+		changed[table] = true
+	}
+
+	subs.mu.Lock()
+	var uris []string
+	for uri := range subs.uris {
+		uris = append(uris, uri)
+	}
+	subs.mu.Unlock()
+
+	for _, uri := range uris {
+		r, ok := h.resourceFor(uri)
+		if !ok {
+			continue
+		}
+		for _, table := range r.tables {
+			if !changed[table] {
+				continue
+			}
+			if err := server.ResourceUpdated(ctx, &mcp.ResourceUpdatedNotificationParams{URI: uri}); err != nil {
+				slog.Warn("failed to notify resource subscribers", "uri", uri, "error", err)
+			}
+			break
+		}
+	}
+}
+
+// resourceFor returns the resource or template uri belongs to
+func (h *Handler) resourceFor(uri string) (hoursResource, bool) {
+	for _, r := range h.resources() {
+		if base, _, isTemplate := strings.Cut(r.uri, "{"); isTemplate {
+			if strings.HasPrefix(uri, base) && !strings.Contains(strings.TrimPrefix(uri, base), "/") {
+				return r, true
+			}
+		} else if r.uri == uri {
+			return r, true
+		}
+	}
+	return hoursResource{}, false
+}
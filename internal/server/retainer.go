@@ -0,0 +1,467 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Invoice item kinds for retainer contracts
+const (
+	rateKindIncluded    = "included"
+	rateKindOverage     = "overage"
+	rateKindRetainerFee = "retainer_fee"
+)
+
+// retainer holds the terms of a retainer contract: a monthly fee covers a
+// number of hours, hours beyond those are billed at the overage rate, and
+// unused hours stay available for rolloverMonths further months
+type retainer struct {
+	contractID     int
+	contractNumber string
+	currency       string
+	start          time.Time
+	end            *time.Time
+	hours          float64
+	fee            money.Cents
+	overageRate    money.Cents
+	rolloverMonths int
+}
+
+// loadRetainers returns retainer contracts with included hours, by contract
+// ID, optionally narrowed by a condition on the contracts table
+func (h *Handler) loadRetainers(ctx context.Context, filter string, args ...interface{}) (map[int]*retainer, error) {
+	query := `
+		SELECT id, contract_number, currency, start_date, end_date, retainer_hours,
+		       retainer_fee_cents, overage_rate_cents, rollover_months
+		FROM contracts
+		WHERE contract_type = 'retainer' AND retainer_hours > 0`
+	if filter != "" {
+		query += " AND " + filter
+	}
+	rows, err := h.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load retainers: %w", err)
+	}
+	defer rows.Close()
+
+	retainers := map[int]*retainer{}
+	for rows.Next() {
+		r := &retainer{}
+		var end sql.NullTime
+		if err := rows.Scan(&r.contractID, &r.contractNumber, &r.currency, &r.start, &end, &r.hours,
+			&r.fee, &r.overageRate, &r.rolloverMonths); err != nil {
+			return nil, fmt.Errorf("failed to scan retainer: %w", err)
+		}
+		if end.Valid {
+			r.end = &end.Time
+		}
+		retainers[r.contractID] = r
+	}
+	return retainers, rows.Err()
+}
+
+// retainerMonth is one month of a retainer's hour balance
+type retainerMonth struct {
+	Month      string  `json:"month"`
+	Included   float64 `json:"included"`
+	RolledOver float64 `json:"rolled_over"`
+	Used       float64 `json:"used"`
+	Overage    float64 `json:"overage"`
+	Expired    float64 `json:"expired"`
+	Remaining  float64 `json:"remaining"`
+}
+
+// available is what the month's hours are drawn from
+func (m retainerMonth) available() float64 {
+	return m.Included + m.RolledOver
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// retainerLedger walks a retainer month by month from its start through the
+// given date. Hours are drawn from the oldest unused allowance first, and an
+// allowance expires once it has been rolled over rolloverMonths times.
+func (h *Handler) retainerLedger(ctx context.Context, r *retainer, through time.Time) ([]retainerMonth, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT substr(date, 1, 7), SUM(hours) FROM time_entries
+		WHERE contract_id = ?
+		GROUP BY 1
+	`, r.contractID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load retainer hours: %w", err)
+	}
+	defer rows.Close()
+
+	usage := map[string]float64{}
+	for rows.Next() {
+		var month string
+		var hours float64
+		if err := rows.Scan(&month, &hours); err != nil {
+			return nil, fmt.Errorf("failed to scan retainer hours: %w", err)
+		}
+		usage[month] = hours
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	last := monthStart(through)
+	if r.end != nil && r.end.Before(last) {
+		last = monthStart(*r.end)
+	}
+
+	type allowance struct {
+		month int
+		hours float64
+	}
+	var allowances []allowance
+	var ledger []retainerMonth
+	for i, month := 0, monthStart(r.start); !month.After(last); i, month = i+1, month.AddDate(0, 1, 0) {
+		m := retainerMonth{Month: month.Format("2006-01"), Included: r.hours}
+		for _, a := range allowances {
+			m.RolledOver += a.hours
+		}
+		allowances = append(allowances, allowance{month: i, hours: r.hours})
+
+		m.Used = usage[m.Month]
+		need := m.Used
+		for j := range allowances {
+			take := min(need, allowances[j].hours)
+			allowances[j].hours -= take
+			need -= take
+		}
+		m.Overage = need
+
+		kept := allowances[:0]
+		for _, a := range allowances {
+			if a.month+r.rolloverMonths <= i {
+				m.Expired += a.hours
+			} else if a.hours > 0 {
+				kept = append(kept, a)
+			}
+		}
+		allowances = kept
+		for _, a := range allowances {
+			m.Remaining += a.hours
+		}
+		ledger = append(ledger, m)
+	}
+	return ledger, nil
+}
+
+// retainerIncluded returns, for each retainer time entry in entries, how many
+// of its hours are covered by the retainer. Entries draw on the month's
+// allowance in date order, counting every entry in the month whether or not
+// it is being priced now.
+func (h *Handler) retainerIncluded(ctx context.Context, entries []models.TimeEntry, retainers map[int]*retainer) (map[string]float64, error) {
+	latest := map[int]time.Time{}
+	earliest := map[int]time.Time{}
+	months := map[int]map[string]bool{}
+	for _, e := range entries {
+		if retainers[e.ContractID] == nil {
+			continue
+		}
+		if e.Date.After(latest[e.ContractID]) {
+			latest[e.ContractID] = e.Date
+		}
+		if first, ok := earliest[e.ContractID]; !ok || e.Date.Before(first) {
+			earliest[e.ContractID] = monthStart(e.Date)
+		}
+		if months[e.ContractID] == nil {
+			months[e.ContractID] = map[string]bool{}
+		}
+		months[e.ContractID][e.Date.Format("2006-01")] = true
+	}
+
+	included := map[string]float64{}
+	for contractID, through := range latest {
+		ledger, err := h.retainerLedger(ctx, retainers[contractID], through)
+		if err != nil {
+			return nil, err
+		}
+		available := map[string]float64{}
+		for _, m := range ledger {
+			available[m.Month] = m.available()
+		}
+
+		// Every entry of the months being priced, in one query per contract
+		rows, err := h.db.QueryContext(ctx, `
+			SELECT id, substr(date, 1, 7), hours FROM time_entries
+			WHERE contract_id = ? AND date >= ? AND date < ?
+			ORDER BY date, created_at, id
+		`, contractID, earliest[contractID].Format("2006-01-02"), monthStart(through).AddDate(0, 1, 0).Format("2006-01-02"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load retainer hours: %w", err)
+		}
+		for rows.Next() {
+			var id, month string
+			var hours float64
+			if err := rows.Scan(&id, &month, &hours); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan retainer hours: %w", err)
+			}
+			if !months[contractID][month] {
+				continue
+			}
+			take := max(0, min(hours, available[month]))
+			included[id] = take
+			available[month] -= take
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load retainer hours: %w", err)
+		}
+	}
+	return included, nil
+}
+
+// retainerFeeItems returns the monthly fees of a client's retainers for the
+// months overlapping start to end that no invoice has billed yet
+func (h *Handler) retainerFeeItems(ctx context.Context, clientID int, start, end time.Time) ([]models.InvoiceItem, error) {
+	retainers, err := h.loadRetainers(ctx, "client_id = ? AND status = 'active'", clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(retainers))
+	for id := range retainers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	// The months whose fee an invoice already bills, by contract and month
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT l.contract_id, l.period FROM invoice_lines l
+		JOIN invoices i ON l.invoice_id = i.id
+		WHERE i.client_id = ? AND l.rate_kind = ? AND i.status != 'cancelled'
+	`, clientID, rateKindRetainerFee)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check retainer fees: %w", err)
+	}
+	defer rows.Close()
+	billed := map[string]bool{}
+	for rows.Next() {
+		var contractID int
+		var period string
+		if err := rows.Scan(&contractID, &period); err != nil {
+			return nil, fmt.Errorf("failed to scan retainer fees: %w", err)
+		}
+		billed[fmt.Sprintf("%d|%s", contractID, period)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check retainer fees: %w", err)
+	}
+
+	var items []models.InvoiceItem
+	for _, id := range ids {
+		r := retainers[id]
+		if r.fee == 0 {
+			continue
+		}
+		for month := monthStart(start); !month.After(end); month = month.AddDate(0, 1, 0) {
+			if month.Before(monthStart(r.start)) || (r.end != nil && month.After(*r.end)) {
+				continue
+			}
+			if billed[fmt.Sprintf("%d|%s", r.contractID, month.Format("2006-01"))] {
+				continue
+			}
+			items = append(items, models.InvoiceItem{
+				ContractID:  r.contractID,
+				Date:        month,
+				Description: fmt.Sprintf("%s retainer, %s (%g hours included)", r.contractNumber, month.Format("January 2006"), r.hours),
+				RateKind:    rateKindRetainerFee,
+				RateLabel:   "Retainer fee",
+				Multiplier:  1,
+				Rate:        r.fee,
+				Amount:      r.fee,
+			})
+		}
+	}
+	return items, nil
+}
+
+func registerRetainerTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Set Retainer tool
+	type setRetainerArgs struct {
+		ContractNumber string  `json:"contract_number" jsonschema:"Contract number or name"`
+		MonthlyHours   float64 `json:"monthly_hours" jsonschema:"Hours included each month"`
+		MonthlyFee     float64 `json:"monthly_fee" jsonschema:"Fee invoiced each month for the included hours"`
+		OverageRate    float64 `json:"overage_rate,omitempty" jsonschema:"Hourly rate beyond the included hours (default: the contract rate)"`
+		RolloverMonths int     `json:"rollover_months,omitempty" jsonschema:"Months unused hours stay available (default: 0, unused hours expire at month end)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_retainer",
+		Description: "Make a contract a retainer with monthly included hours, a monthly fee, an overage rate and a rollover policy",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setRetainerArgs) (*mcp.CallToolResult, *models.Contract, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		if args.MonthlyHours <= 0 {
+			return nil, nil, validationError("monthly_hours must be positive")
+		}
+		if args.MonthlyFee < 0 || args.OverageRate < 0 {
+			return nil, nil, validationError("monthly_fee and overage_rate cannot be negative")
+		}
+		if args.RolloverMonths < 0 {
+			return nil, nil, validationError("rollover_months cannot be negative")
+		}
+
+		var currency string
+		err := db.QueryRowContext(ctx, "SELECT currency FROM contracts WHERE contract_number = ?", args.ContractNumber).Scan(&currency)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("contract", "contract %s not found", args.ContractNumber)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find contract: %w", err)
+		}
+
+		fee, overageRate := money.FromFloat(args.MonthlyFee), money.FromFloat(args.OverageRate)
+		_, err = db.ExecContext(ctx, `
+			UPDATE contracts SET contract_type = 'retainer', retainer_hours = ?, retainer_fee_cents = ?,
+			       overage_rate_cents = ?, rollover_months = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE contract_number = ?
+		`, args.MonthlyHours, fee, overageRate, args.RolloverMonths, args.ContractNumber)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to update contract: %w", err)
+		}
+
+		text := fmt.Sprintf("%s is now a retainer: %g hours a month for %s", args.ContractNumber, args.MonthlyHours, fee.Format(currency))
+		if overageRate > 0 {
+			text += fmt.Sprintf(", overage at %s per hour", overageRate.Format(currency))
+		} else {
+			text += ", overage at the contract rate"
+		}
+		if args.RolloverMonths > 0 {
+			text += fmt.Sprintf(", unused hours roll over for %d months", args.RolloverMonths)
+		} else {
+			text += ", unused hours expire at month end"
+		}
+		contract, err := h.loadContract(ctx, args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, contract, nil
+	})
+
+	// Retainer Balance tool
+	type retainerBalanceArgs struct {
+		ContractNumber string `json:"contract_number" jsonschema:"Retainer contract number or name"`
+		Months         int    `json:"months,omitempty" jsonschema:"Number of recent months to show (default: 6)"`
+		Through        string `json:"through,omitempty" jsonschema:"Last month to show (default: this month)"`
+		Format         string `json:"format,omitempty" jsonschema:"Output format: text (default) or markdown"`
+	}
+
+	type retainerBalanceResult struct {
+		ContractNumber string          `json:"contract_number"`
+		MonthlyHours   float64         `json:"monthly_hours"`
+		MonthlyFee     money.Cents     `json:"monthly_fee"`
+		Currency       string          `json:"currency"`
+		RolloverMonths int             `json:"rollover_months"`
+		Months         []retainerMonth `json:"months" jsonschema:"Hours per month, oldest first"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "retainer_balance",
+		Description: "Show included, used, rolled over, expired and overage hours per month for a retainer contract",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args retainerBalanceArgs) (*mcp.CallToolResult, *retainerBalanceResult, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		markdown, err := parseOutputFormat(args.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+		if args.Months <= 0 {
+			args.Months = 6
+		}
+		through := h.today(ctx)
+		if args.Through != "" {
+			if _, through, err = h.parsePeriod(ctx, args.Through); err != nil {
+				return nil, nil, validationError("invalid month: %w", err)
+			}
+		}
+
+		retainers, err := h.loadRetainers(ctx, "contract_number = ?", args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		var r *retainer
+		for _, found := range retainers {
+			r = found
+		}
+		if r == nil {
+			return nil, nil, notConfiguredError("retainer", "%s is not a retainer contract; use set_retainer first", args.ContractNumber)
+		}
+
+		ledger, err := h.retainerLedger(ctx, r, through)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(ledger) > args.Months {
+			ledger = ledger[len(ledger)-args.Months:]
+		}
+
+		text := fmt.Sprintf("Retainer %s: %g hours a month for %s", r.contractNumber, r.hours, r.fee.Format(r.currency))
+		if r.rolloverMonths > 0 {
+			text += fmt.Sprintf(", unused hours roll over for %d months\n", r.rolloverMonths)
+		} else {
+			text += ", unused hours expire at month end\n"
+		}
+		if len(ledger) == 0 {
+			text += "\nThe retainer has not started yet.\n"
+		}
+
+		if markdown {
+			tableRows := make([][]string, 0, len(ledger))
+			for _, m := range ledger {
+				tableRows = append(tableRows, []string{m.Month, fmt.Sprintf("%.2f", m.Included), fmt.Sprintf("%.2f", m.RolledOver),
+					fmt.Sprintf("%.2f", m.Used), fmt.Sprintf("%.2f", m.Overage), fmt.Sprintf("%.2f", m.Expired), fmt.Sprintf("%.2f", m.Remaining)})
+			}
+			text += "\n" + markdownTable([]string{"Month", "Included", "Rolled over", "Used", "Overage", "Expired", "Remaining"},
+				tableRows, 1, 2, 3, 4, 5, 6)
+		} else {
+			for _, m := range ledger {
+				text += fmt.Sprintf("- %s: %.2f of %.2f hours used", m.Month, m.Used, m.available())
+				if m.RolledOver > 0 {
+					text += fmt.Sprintf(" (%.2f rolled over)", m.RolledOver)
+				}
+				if m.Overage > 0 {
+					text += fmt.Sprintf(", %.2f overage", m.Overage)
+				}
+				if m.Expired > 0 {
+					text += fmt.Sprintf(", %.2f expired", m.Expired)
+				}
+				text += fmt.Sprintf(", %.2f remaining\n", m.Remaining)
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &retainerBalanceResult{
+			ContractNumber: r.contractNumber,
+			MonthlyHours:   r.hours,
+			MonthlyFee:     r.fee,
+			Currency:       r.currency,
+			RolloverMonths: r.rolloverMonths,
+			Months:         ledger,
+		}, nil
+	})
+}
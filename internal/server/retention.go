@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/database"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// purgedInvoices selects the invoices purge_old_data removes: settled ones
+// issued before the cutoff, ?1. Unpaid invoices are kept however old.
+const purgedInvoices = "SELECT id FROM invoices WHERE issue_date < ?1 AND status IN ('paid', 'written_off', 'cancelled')"
+
+// purgeTables lists what purge_old_data removes, dependents first, with the
+// WHERE clause selecting the rows given the cutoff date as ?1. Time entries
+// and expenses go with their invoice, or when never invoiced, by their own
+// date; ones on a kept invoice stay so it can still be reprinted.
+var purgeTables = []struct {
+	table string
+	label string
+	where string
+}{
+	{"time_entries", "time entries", "invoice_id IN (" + purgedInvoices + ") OR (invoice_id IS NULL AND date < ?1)"},
+	{"expenses", "expenses", "invoice_id IN (" + purgedInvoices + ") OR (invoice_id IS NULL AND date < ?1)"},
+	{"email_log", "email log entries", "invoice_id IN (" + purgedInvoices + ")"},
+	{"invoice_notes", "invoice notes", "invoice_id IN (" + purgedInvoices + ")"},
+	{"invoice_recipients", "invoice recipients", "invoice_id IN (" + purgedInvoices + ")"},
+	{"invoice_lines", "invoice lines", "invoice_id IN (" + purgedInvoices + ")"},
+	{"invoices", "invoices", "issue_date < ?1 AND status IN ('paid', 'written_off', 'cancelled')"},
+	{"internal_entries", "internal time entries", "date < ?1"},
+	{"audit_log", "audit log entries", "changed_at < ?1"},
+}
+
+// registerRetentionTools registers the tool purging data past the
+// retention period
+func registerRetentionTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	type purgeOldDataArgs struct {
+		Years    int    `json:"years,omitempty" jsonschema:"Keep this many whole fiscal years before the current one (default: the retention_years setting)"`
+		FilePath string `json:"file_path,omitempty" jsonschema:"Where to write the archive of purged rows (default: the archives folder next to the database)"`
+		Confirm  bool   `json:"confirm,omitempty" jsonschema:"Archive and delete the data (default: false, only shows what would be purged)"`
+	}
+
+	type purgeOldDataResult struct {
+		Confirmed    bool           `json:"confirmed" jsonschema:"Whether the data was purged; false for a preview"`
+		Cutoff       string         `json:"cutoff" jsonschema:"Data dated before this day is purged"`
+		Purge        map[string]int `json:"purge,omitempty" jsonschema:"Rows per kind of data that would be purged"`
+		Purged       map[string]int `json:"purged,omitempty" jsonschema:"Rows per kind of data archived and deleted"`
+		UnpaidKept   int            `json:"unpaid_kept,omitempty" jsonschema:"Unpaid invoices from before the cutoff that were kept"`
+		ArchivePath  string         `json:"archive_path,omitempty"`
+		ArchiveBytes int64          `json:"archive_bytes,omitempty"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "purge_old_data",
+		Description: "Archive to a JSON file, then delete, time entries, internal time, expenses and paid, written off or cancelled invoices older than the retention period (retention_years whole fiscal years before the current one, default never), plus audit log entries as old. Unpaid invoices and their hours are kept. Only shows what would be purged until run with confirm=true",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args purgeOldDataArgs) (*mcp.CallToolResult, *purgeOldDataResult, error) {
+		if args.Years < 0 {
+			return nil, nil, validationError("years must not be negative")
+		}
+		years := args.Years
+		if years == 0 {
+			years = h.getIntSetting(ctx, "retention_years")
+		}
+		if years == 0 {
+			return nil, nil, notConfiguredError("retention_years", "no retention period is set, so nothing is ever purged. Set retention_years with set_setting or pass years")
+		}
+
+		thisYear, _, err := h.parsePeriod(ctx, "this year")
+		if err != nil {
+			return nil, nil, err
+		}
+		cutoff := thisYear.AddDate(-years, 0, 0).Format("2006-01-02")
+
+		counts := map[string]int{}
+		var summary []string
+		total := 0
+		for _, t := range purgeTables {
+			var count int
+			if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", t.table, t.where), cutoff).Scan(&count); err != nil {
+				return nil, nil, fmt.Errorf("failed to count %s: %w", t.label, err)
+			}
+			counts[t.label] = count
+			total += count
+			if count > 0 {
+				summary = append(summary, fmt.Sprintf("%d %s", count, t.label))
+			}
+		}
+
+		var unpaid int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM invoices WHERE issue_date < ? AND status NOT IN ('paid', 'written_off', 'cancelled')", cutoff).Scan(&unpaid); err != nil {
+			return nil, nil, fmt.Errorf("failed to count unpaid invoices: %w", err)
+		}
+		unpaidNote := ""
+		if unpaid > 0 {
+			unpaidNote = fmt.Sprintf("%d unpaid invoices issued before then are kept with their hours until they are paid, written off or cancelled.\n", unpaid)
+		}
+
+		if total == 0 {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Nothing dated before %s to purge.\n%s", cutoff, unpaidNote)},
+				},
+			}, &purgeOldDataResult{Cutoff: cutoff, UnpaidKept: unpaid}, nil
+		}
+
+		if !args.Confirm {
+			text := fmt.Sprintf("Would archive and delete everything dated before %s (keeping %d fiscal years): %s.\n%s",
+				cutoff, years, strings.Join(summary, ", "), unpaidNote)
+			return previewResult(text, &purgeOldDataResult{Cutoff: cutoff, Purge: counts, UnpaidKept: unpaid})
+		}
+
+		path := expandHome(args.FilePath)
+		if path == "" {
+			dir, err := database.ArchiveDir()
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := os.MkdirAll(dir, 0700); err != nil {
+				return nil, nil, fmt.Errorf("failed to create archive folder: %w", err)
+			}
+			path = filepath.Join(dir, fmt.Sprintf("hours_archive_before_%s_%s.json", cutoff, time.Now().Format("20060102-150405")))
+		}
+
+		// The archive is written and synced before anything is deleted, so
+		// a failed purge loses nothing
+		where := map[string]string{}
+		for _, t := range purgeTables {
+			where[t.table] = t.where
+		}
+		archive, err := database.ExportRows(ctx, db, where, cutoff)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to archive data: %w", err)
+		}
+		data, err := json.MarshalIndent(archive, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode archive: %w", err)
+		}
+		if err := writeFileSynced(path, data); err != nil {
+			return nil, nil, fmt.Errorf("failed to write archive: %w", err)
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		var lastAudit int64
+		if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM audit_log").Scan(&lastAudit); err != nil {
+			return nil, nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+		purged := map[string]int{}
+		for _, t := range purgeTables {
+			result, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", t.table, t.where), cutoff)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to delete %s: %w", t.label, err)
+			}
+			n, _ := result.RowsAffected()
+			purged[t.label] = int(n)
+		}
+		// The audit log records the deletions, but the rows themselves live
+		// on in the archive rather than in snapshots
+		if _, err := tx.ExecContext(ctx, "UPDATE audit_log SET before = NULL WHERE id > ? AND action = 'delete'", lastAudit); err != nil {
+			return nil, nil, fmt.Errorf("failed to update audit log: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		compactErr := database.Optimize(ctx, db)
+
+		text := fmt.Sprintf("Archived to %s (%d bytes) and deleted everything dated before %s: %s.\n%s",
+			path, len(data), cutoff, strings.Join(summary, ", "), unpaidNote)
+		text += "Reports and unbilled summaries no longer include the purged periods; the archive can be read with any JSON tool but not restored with import_data.\n"
+		if compactErr != nil {
+			text += fmt.Sprintf("Warning: the database could not be compacted (%v); run db_maintenance to reclaim the space.\n", compactErr)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &purgeOldDataResult{
+			Confirmed:    true,
+			Cutoff:       cutoff,
+			Purged:       purged,
+			UnpaidKept:   unpaid,
+			ArchivePath:  path,
+			ArchiveBytes: int64(len(data)),
+		}, nil
+	})
+}
+
+// writeFileSynced writes data to a new file at path, readable only by its
+// owner, and flushes it to disk. It never replaces an existing file, so an
+// earlier archive can't be overwritten
+func writeFileSynced(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
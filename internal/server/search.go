@@ -0,0 +1,43 @@
+package server
+
+import (
+	"strings"
+	"unicode"
+)
+
+// buildFTSQuery turns free-form search text into a safe FTS5 MATCH expression.
+// "quoted phrases" are kept together, a trailing * marks a prefix search and
+// all other punctuation is dropped so user input can't produce syntax errors.
+func buildFTSQuery(input string) string {
+	var terms []string
+
+	clean := func(s string) string {
+		return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		}), " ")
+	}
+
+	parts := strings.Split(input, `"`)
+	for i, part := range parts {
+		if i%2 == 1 {
+			// Inside quotes: exact phrase
+			if phrase := clean(part); phrase != "" {
+				terms = append(terms, `"`+phrase+`"`)
+			}
+			continue
+		}
+
+		for _, word := range strings.Fields(part) {
+			prefix := strings.HasSuffix(word, "*")
+			for _, token := range strings.Fields(clean(word)) {
+				term := `"` + token + `"`
+				if prefix {
+					term += "*"
+				}
+				terms = append(terms, term)
+			}
+		}
+	}
+
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/secrets"
+)
+
+// Bank numbers, payment notes and the business tax ID are encrypted at rest.
+// They are encrypted when saved and decrypted when loaded through the
+// helpers below, so everything else, the PDF generator included, sees plain
+// values.
+
+// encryptFields encrypts each value in place
+func encryptFields(values ...*string) error {
+	for _, v := range values {
+		encrypted, err := secrets.Encrypt(*v)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt: %w", err)
+		}
+		*v = encrypted
+	}
+	return nil
+}
+
+// decryptFields decrypts each value in place
+func decryptFields(values ...*string) error {
+	for _, v := range values {
+		plain, err := secrets.Decrypt(*v)
+		if err != nil {
+			return err
+		}
+		*v = plain
+	}
+	return nil
+}
+
+// maskNumber hides all but the last 4 characters of an account or routing
+// number, e.g. "****6789"
+func maskNumber(number string) string {
+	compact := strings.Join(strings.Fields(number), "")
+	if len(compact) <= 4 {
+		return strings.Repeat("*", len(compact))
+	}
+	return "****" + compact[len(compact)-4:]
+}
+
+// maskPaymentDetails returns a copy of payment details safe to show in tool
+// output: numbers reduced to their last 4 digits and notes, which may hold
+// an IBAN, left out
+func maskPaymentDetails(p *models.PaymentDetails) *models.PaymentDetails {
+	if p == nil {
+		return nil
+	}
+	masked := *p
+	masked.AccountNumber = maskNumber(p.AccountNumber)
+	masked.RoutingNumber = maskNumber(p.RoutingNumber)
+	if p.Notes != "" {
+		masked.Notes = "(hidden)"
+	}
+	return &masked
+}
+
+// loadPaymentDetails returns a client's decrypted payment details, or nil
+// when none are set
+func (h *Handler) loadPaymentDetails(ctx context.Context, clientID int) (*models.PaymentDetails, error) {
+	p := &models.PaymentDetails{ClientID: clientID}
+	err := h.db.QueryRowContext(ctx, `
+		SELECT id, COALESCE(bank_name, ''), COALESCE(account_number, ''), COALESCE(routing_number, ''),
+		       COALESCE(swift_code, ''), COALESCE(payment_terms, ''), COALESCE(notes, '')
+		FROM payment_details WHERE client_id = ?
+	`, clientID).Scan(&p.ID, &p.BankName, &p.AccountNumber, &p.RoutingNumber, &p.SwiftCode, &p.PaymentTerms, &p.Notes)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load payment details: %w", err)
+	}
+	if err := decryptFields(&p.AccountNumber, &p.RoutingNumber, &p.SwiftCode, &p.Notes); err != nil {
+		return nil, fmt.Errorf("failed to decrypt payment details: %w", err)
+	}
+	return p, nil
+}
+
+// loadBusinessInfo returns the decrypted business information, or nil when
+// none is configured
+func (h *Handler) loadBusinessInfo(ctx context.Context) (*models.BusinessInfo, error) {
+	var b models.BusinessInfo
+	err := h.db.QueryRowContext(ctx, `
+		SELECT id, business_name, contact_name, email, phone, address, city, state, zip_code, country, tax_id, website, logo_path, invoice_prefix, updated_at
+		FROM business_info WHERE id = 1
+	`).Scan(&b.ID, &b.BusinessName, &b.ContactName, &b.Email,
+		&b.Phone, &b.Address, &b.City, &b.State,
+		&b.ZipCode, &b.Country, &b.TaxID, &b.Website,
+		&b.LogoPath, &b.InvoicePrefix, &b.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get business info: %w", err)
+	}
+	if err := decryptFields(&b.TaxID); err != nil {
+		return nil, fmt.Errorf("failed to decrypt business tax ID: %w", err)
+	}
+	return &b, nil
+}
@@ -0,0 +1,335 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// serviceRate is the rate a contract agrees for a service on the rate card
+type serviceRate struct {
+	Service    string      `json:"service"`
+	HourlyRate money.Cents `json:"hourly_rate"`
+}
+
+// loadService returns the rate card service with a name
+func (h *Handler) loadService(ctx context.Context, name string) (*models.Service, error) {
+	var s models.Service
+	err := h.db.QueryRowContext(ctx, `
+		SELECT id, name, hourly_rate_cents, currency, description, created_at FROM services WHERE name = ?
+	`, strings.TrimSpace(name)).Scan(&s.ID, &s.Name, &s.HourlyRate, &s.Currency, &s.Description, &s.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, notFoundError("service", "service '%s' not found; see list_services", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find service: %w", err)
+	}
+	return &s, nil
+}
+
+// contractServiceRates returns the service rates of a contract, by service
+// name
+func (h *Handler) contractServiceRates(ctx context.Context, contractID int) ([]serviceRate, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT s.name, cs.hourly_rate_cents
+		FROM contract_services cs
+		JOIN services s ON s.id = cs.service_id
+		WHERE cs.contract_id = ?
+		ORDER BY s.name
+	`, contractID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load service rates: %w", err)
+	}
+	defer rows.Close()
+
+	rates := []serviceRate{}
+	for rows.Next() {
+		var r serviceRate
+		if err := rows.Scan(&r.Service, &r.HourlyRate); err != nil {
+			return nil, fmt.Errorf("failed to scan service rate: %w", err)
+		}
+		rates = append(rates, r)
+	}
+	return rates, rows.Err()
+}
+
+// entryService resolves the service time entries on a contract are logged
+// for, returning nil for an empty name and a warning when the contract has
+// no rate for the service, so its hours are billed at the contract rate
+func (h *Handler) entryService(ctx context.Context, contractID int, contractNumber, name string) (*int, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, "", nil
+	}
+	s, err := h.loadService(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+	var priced int
+	err = h.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM contract_services WHERE contract_id = ? AND service_id = ?", contractID, s.ID).Scan(&priced)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to check service rate: %w", err)
+	}
+	var warning string
+	if priced == 0 {
+		warning = fmt.Sprintf("%s has no rate for %s, so these hours are billed at the contract rate; add one with set_contract_service_rate", contractNumber, s.Name)
+	}
+	return &s.ID, warning, nil
+}
+
+// registerServiceTools registers the tools managing the rate card and the
+// rates contracts agree for its services
+func registerServiceTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Set Service tool
+	type setServiceArgs struct {
+		Name        string  `json:"name" jsonschema:"Service name, e.g. 'Development' or 'Advisory'"`
+		HourlyRate  float64 `json:"hourly_rate" jsonschema:"Default hourly rate offered for the service"`
+		Currency    string  `json:"currency,omitempty" jsonschema:"Currency of the rate (default: USD)"`
+		Description string  `json:"description,omitempty" jsonschema:"What the service covers (optional)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_service",
+		Description: "Add or update a service on the rate card, with the default rate new contracts are priced at for it; existing contracts keep the rate they agreed",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setServiceArgs) (*mcp.CallToolResult, *models.Service, error) {
+		name := strings.TrimSpace(args.Name)
+		if name == "" {
+			return nil, nil, validationError("name is required")
+		}
+		if args.HourlyRate <= 0 {
+			return nil, nil, validationError("hourly rate must be positive")
+		}
+		currency := strings.ToUpper(strings.TrimSpace(args.Currency))
+		if currency == "" {
+			currency = "USD"
+		}
+		if err := validateCurrencyCode(currency); err != nil {
+			return nil, nil, validationError("invalid currency: %w", err)
+		}
+
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO services (name, hourly_rate_cents, currency, description) VALUES (?, ?, ?, ?)
+			ON CONFLICT(name) DO UPDATE SET
+				hourly_rate_cents = excluded.hourly_rate_cents,
+				currency = excluded.currency,
+				description = excluded.description
+		`, name, money.FromFloat(args.HourlyRate), currency, strings.TrimSpace(args.Description))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to save service: %w", err)
+		}
+		s, err := h.loadService(ctx, name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Service '%s' saved at %s per hour", s.Name, s.HourlyRate.Format(s.Currency))},
+			},
+		}, s, nil
+	})
+
+	// List Services tool
+	type listServicesArgs struct{}
+
+	type serviceListing struct {
+		models.Service
+		Contracts []string `json:"contracts,omitempty" jsonschema:"Contracts with a rate for the service"`
+	}
+
+	type listServicesResult struct {
+		Services []serviceListing `json:"services"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_services",
+		Description: "List the rate card: each service with its default rate and the contracts that have a rate for it",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listServicesArgs) (*mcp.CallToolResult, *listServicesResult, error) {
+		rows, err := db.QueryContext(ctx, `
+			SELECT s.id, s.name, s.hourly_rate_cents, s.currency, s.description, s.created_at,
+			       COALESCE((SELECT GROUP_CONCAT(ct.contract_number, ', ') FROM contract_services cs
+			                 JOIN contracts ct ON ct.id = cs.contract_id WHERE cs.service_id = s.id), '')
+			FROM services s
+			ORDER BY s.name
+		`)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list services: %w", err)
+		}
+		defer rows.Close()
+
+		result := &listServicesResult{Services: []serviceListing{}}
+		for rows.Next() {
+			var s serviceListing
+			var contracts string
+			if err := rows.Scan(&s.ID, &s.Name, &s.HourlyRate, &s.Currency, &s.Description, &s.CreatedAt, &contracts); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan service: %w", err)
+			}
+			if contracts != "" {
+				s.Contracts = strings.Split(contracts, ", ")
+			}
+			result.Services = append(result.Services, s)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		if len(result.Services) == 0 {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: "The rate card is empty. Use set_service to add the services you offer, e.g. development and advisory, with their default rates."},
+				},
+			}, result, nil
+		}
+
+		text := "Rate card:\n"
+		for _, s := range result.Services {
+			text += fmt.Sprintf("- %s: %s per hour", s.Name, s.HourlyRate.Format(s.Currency))
+			if s.Description != "" {
+				text += " - " + s.Description
+			}
+			if len(s.Contracts) > 0 {
+				text += "; contracts: " + strings.Join(s.Contracts, ", ")
+			}
+			text += "\n"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+
+	// Delete Service tool
+	type deleteServiceArgs struct {
+		Name string `json:"name" jsonschema:"Name of the service to delete"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "delete_service",
+		Description: "Remove a service from the rate card, with the rates contracts agreed for it; a service time entries were logged for can't be deleted",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args deleteServiceArgs) (*mcp.CallToolResult, *models.Service, error) {
+		s, err := h.loadService(ctx, args.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		var entries int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM time_entries WHERE service_id = ?", s.ID).Scan(&entries); err != nil {
+			return nil, nil, fmt.Errorf("failed to check time entries: %w", err)
+		}
+		if entries > 0 {
+			return nil, nil, conflictError("cannot delete service '%s'; %d time entries were logged for it", s.Name, entries)
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+		if _, err := tx.ExecContext(ctx, "DELETE FROM contract_services WHERE service_id = ?", s.ID); err != nil {
+			return nil, nil, fmt.Errorf("failed to delete service rates: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM services WHERE id = ?", s.ID); err != nil {
+			return nil, nil, fmt.Errorf("failed to delete service: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Deleted service '%s'", s.Name)},
+			},
+		}, s, nil
+	})
+
+	// Set Contract Service Rate tool
+	type setContractServiceRateArgs struct {
+		ContractNumber string   `json:"contract_number" jsonschema:"Contract number or name"`
+		Service        string   `json:"service" jsonschema:"Service on the rate card"`
+		HourlyRate     *float64 `json:"hourly_rate,omitempty" jsonschema:"Rate agreed for the service (default: its rate on the rate card)"`
+		Remove         bool     `json:"remove,omitempty" jsonschema:"Remove the service's rate, so its hours are billed at the contract rate (default: false)"`
+	}
+
+	type setContractServiceRateResult struct {
+		ContractNumber string        `json:"contract_number"`
+		Currency       string        `json:"currency"`
+		ServiceRates   []serviceRate `json:"service_rates"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_contract_service_rate",
+		Description: "Set the rate a contract bills a service at, e.g. advisory at a higher rate than development on the same contract; hours logged for the service use it instead of the contract rate",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setContractServiceRateArgs) (*mcp.CallToolResult, *setContractServiceRateResult, error) {
+		if err := h.resolveContractNumber(ctx, &args.ContractNumber); err != nil {
+			return nil, nil, err
+		}
+		c, err := h.loadContract(ctx, args.ContractNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		s, err := h.loadService(ctx, args.Service)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Invoiced hours keep the rate they were billed at
+		var invoiced int
+		err = db.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM time_entries WHERE contract_id = ? AND service_id = ? AND invoice_id IS NOT NULL
+		`, c.ID, s.ID).Scan(&invoiced)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check invoiced hours: %w", err)
+		}
+		if invoiced > 0 {
+			return nil, nil, conflictError("%d invoiced time entries for %s on %s would be repriced; renew the contract to change the rate",
+				invoiced, s.Name, c.ContractNumber)
+		}
+
+		var text string
+		if args.Remove {
+			result, err := db.ExecContext(ctx, "DELETE FROM contract_services WHERE contract_id = ? AND service_id = ?", c.ID, s.ID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to remove service rate: %w", err)
+			}
+			if n, _ := result.RowsAffected(); n == 0 {
+				return nil, nil, notFoundError("service_rate", "%s has no rate for %s", c.ContractNumber, s.Name)
+			}
+			text = fmt.Sprintf("Removed the %s rate from %s; its hours are billed at the contract rate", s.Name, c.ContractNumber)
+		} else {
+			rate := s.HourlyRate
+			if args.HourlyRate != nil {
+				if *args.HourlyRate <= 0 {
+					return nil, nil, validationError("hourly rate must be positive")
+				}
+				rate = money.FromFloat(*args.HourlyRate)
+			} else if s.Currency != c.Currency {
+				return nil, nil, validationError("%s is priced in %s on the rate card but %s is in %s; pass hourly_rate in %s",
+					s.Name, s.Currency, c.ContractNumber, c.Currency, c.Currency)
+			}
+			_, err = db.ExecContext(ctx, `
+				INSERT INTO contract_services (contract_id, service_id, hourly_rate_cents) VALUES (?, ?, ?)
+				ON CONFLICT(contract_id, service_id) DO UPDATE SET hourly_rate_cents = excluded.hourly_rate_cents
+			`, c.ID, s.ID, rate)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to save service rate: %w", err)
+			}
+			text = fmt.Sprintf("%s now bills %s at %s per hour", c.ContractNumber, s.Name, rate.Format(c.Currency))
+		}
+
+		rates, err := h.contractServiceRates(ctx, c.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &setContractServiceRateResult{ContractNumber: c.ContractNumber, Currency: c.Currency, ServiceRates: rates}, nil
+	})
+}
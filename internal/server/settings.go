@@ -0,0 +1,609 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/austin/hours-mcp/internal/pdf"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// setting describes a configurable option stored in the settings table
+type setting struct {
+	description  string
+	defaultValue string
+	validate     func(value string) error
+}
+
+// knownSettings lists every key accepted by set_setting
+var knownSettings = map[string]setting{
+	"accounting_date_format": {
+		description:  "Date order used in accounting exports: mdy, dmy or ymd",
+		defaultValue: "mdy",
+		validate: func(value string) error {
+			if _, ok := dateOrderLayouts[value]; !ok {
+				return validationError("must be one of mdy, dmy, ymd")
+			}
+			return nil
+		},
+	},
+	"attach_receipts": {
+		description:  "Append the receipts of rebilled expenses, JPEG, PNG or PDF files, to invoice PDFs: true or false",
+		defaultValue: "false",
+		validate:     validateBool,
+	},
+	"backup_interval_hours": {
+		description:  "Hours between automatic database backups (0 disables scheduled backups)",
+		defaultValue: "24",
+		validate:     validateNonNegativeInt,
+	},
+	"backup_keep_daily": {
+		description:  "Number of most recent days to keep one backup for",
+		defaultValue: "7",
+		validate:     validateNonNegativeInt,
+	},
+	"backup_keep_monthly": {
+		description:  "Number of most recent months to keep one backup for",
+		defaultValue: "12",
+		validate:     validateNonNegativeInt,
+	},
+	"base_currency": {
+		description:  "Currency reports convert totals into using the exchange_rates table (e.g. USD)",
+		defaultValue: "USD",
+		validate:     validateCurrencyCode,
+	},
+	"budget_alert_thresholds": {
+		description:  "Comma-separated shares of a contract's budget, in percent, at which add_hours alerts as hours cross them, besides the contract's own alert_percent",
+		defaultValue: "50,80,95",
+		validate: func(value string) error {
+			_, err := parseBudgetThresholds(value)
+			return err
+		},
+	},
+	"compliance_mode": {
+		description:  "Lock time entries 24 hours after they are logged; later corrections are adjustment entries from adjust_time_entry (true or false)",
+		defaultValue: "false",
+		validate:     validateBool,
+	},
+	"contract_expiry_days": {
+		description:  "Warn about active contracts ending within this many days, at server start and in check_contract_expirations",
+		defaultValue: "30",
+		validate:     validateNonNegativeInt,
+	},
+	"date_order": {
+		description:  "How numeric dates like 03/04/2025 are read: mdy, dmy or ymd (empty accepts either order and rejects dates valid both ways)",
+		defaultValue: "",
+		validate: func(value string) error {
+			if _, ok := dateOrderLayouts[value]; !ok {
+				return validationError("must be one of mdy, dmy, ymd")
+			}
+			return nil
+		},
+	},
+	"distance_unit": {
+		description:  "Unit mileage is recorded in: km or mi",
+		defaultValue: "km",
+		validate: func(value string) error {
+			if value != "km" && value != "mi" {
+				return validationError("must be km or mi")
+			}
+			return nil
+		},
+	},
+	"entry_date_check": {
+		description:  "What to do with time entries dated in the future or more than max_entry_age_days ago, which usually means a misread date: warn, reject or off",
+		defaultValue: "warn",
+		validate: func(value string) error {
+			if value != "warn" && value != "reject" && value != "off" {
+				return validationError("must be warn, reject or off")
+			}
+			return nil
+		},
+	},
+	"expense_markup": {
+		description:  "Markup in percent added to billable expenses on new invoices (e.g. 10)",
+		defaultValue: "0",
+		validate:     validatePercentage,
+	},
+	"fiscal_year_start_month": {
+		description:  "First month of the fiscal year, 1-12; quarters, halves and years in periods like 'Q1 2025' or 'year to date' count from it",
+		defaultValue: "1",
+		validate: func(value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 12 {
+				return validationError("must be a month number between 1 and 12")
+			}
+			return nil
+		},
+	},
+	"hours_rounding": {
+		description:  "Minutes hours given to add_hours, bulk_add_hours and timers are rounded to the nearest multiple of, e.g. 6 for tenths or 15 for quarter hours, never down to 0; 0 keeps them exact, so 1h07m is 1.1167 hours",
+		defaultValue: "0",
+		validate: func(value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 || n > 60 {
+				return validationError("must be a number of minutes between 0 and 60")
+			}
+			return nil
+		},
+	},
+	"invoice_json_sidecar": {
+		description:  "Write a JSON file with the invoice data (lines, rates, tax, client and totals) next to each invoice PDF, for automation: true or false",
+		defaultValue: "false",
+		validate:     validateBool,
+	},
+	"invoice_minimum_amount": {
+		description:  "Smallest net amount invoice_all bills, in the base currency; less is left unbilled until it adds up (0 bills everything)",
+		defaultValue: "0",
+		validate:     validateNonNegativeAmount,
+	},
+	"mask_account_numbers": {
+		description:  "Print only the last 4 digits of account numbers on invoice PDFs: true or false",
+		defaultValue: "false",
+		validate:     validateBool,
+	},
+	"max_entry_age_days": {
+		description:  "Time entries dated more than this many days ago are checked by entry_date_check (0 checks only future dates)",
+		defaultValue: "60",
+		validate:     validateNonNegativeInt,
+	},
+	"pdf_archival": {
+		description:  "Write invoice PDFs as PDF/A-2b for long-term archival, with fonts embedded and XMP metadata: true or false",
+		defaultValue: "false",
+		validate:     validateBool,
+	},
+	"paypal_client_id": {
+		description:  "Client ID of the PayPal REST app export_paypal_invoice creates invoices with; set it and the secret with set_paypal_config",
+		defaultValue: "",
+	},
+	"paypal_environment": {
+		description:  "PayPal environment export_paypal_invoice uses: live or sandbox, for trying it with a sandbox app",
+		defaultValue: "live",
+		validate: func(value string) error {
+			if value != "live" && value != "sandbox" {
+				return validationError("must be live or sandbox")
+			}
+			return nil
+		},
+	},
+	"pdf_font_path": {
+		description:  "TrueType (.ttf) font embedded in PDF/A invoices, its bold and italic styles found next to it by name (empty uses DejaVu Sans, Liberation Sans or Arial where installed)",
+		defaultValue: "",
+		validate: func(value string) error {
+			if value == "" {
+				return nil
+			}
+			_, err := pdf.FindFonts(expandHome(value))
+			return err
+		},
+	},
+	"quickbooks_item": {
+		description:  "QuickBooks product/service name used for invoice lines",
+		defaultValue: "Services",
+	},
+	"quickbooks_income_account": {
+		description:  "QuickBooks income account the product/service posts to",
+		defaultValue: "Services",
+	},
+	"quickbooks_deposit_account": {
+		description:  "QuickBooks account payments are deposited to",
+		defaultValue: "Undeposited Funds",
+	},
+	"retention_years": {
+		description:  "Whole fiscal years before the current one that purge_old_data keeps; 0 keeps everything",
+		defaultValue: "0",
+		validate:     validateNonNegativeInt,
+	},
+	"reverse_charge_note": {
+		description:  "Legal note printed on invoices to reverse-charge clients",
+		defaultValue: "Reverse charge: VAT to be accounted for by the recipient.",
+	},
+	"tax_rate": {
+		description:  "Default tax rate in percent added to new invoices (e.g. 20 for 20% VAT, 0 for none)",
+		defaultValue: "0",
+		validate:     validatePercentage,
+	},
+	"timer_max_hours": {
+		description:  "Hours after which a running timer is stopped automatically, its entry capped there and flagged for review (0 for no limit)",
+		defaultValue: "12",
+		validate:     validateNonNegativeInt,
+	},
+	"xero_sales_account": {
+		description:  "Xero revenue account code for invoice lines",
+		defaultValue: "200",
+	},
+	"xero_tax_type": {
+		description:  "Xero tax type for invoice lines",
+		defaultValue: "Tax Exempt",
+	},
+	"withholding_note": {
+		description:  "Note printed on invoices to clients that withhold tax",
+		defaultValue: "Withholding tax retained by the client",
+	},
+	"total_rounding": {
+		description:  "Round the amount due on new invoices to a multiple of this, e.g. 0.05 for Swiss rounding or 1 for whole units, with the difference as a rounding line (0 for none)",
+		defaultValue: "0",
+		validate:     validateNonNegativeAmount,
+	},
+	"time_zone": {
+		description:  "Time zone 'today' and relative dates are taken in, e.g. Europe/Berlin (empty uses the server's local time zone)",
+		defaultValue: "",
+		validate:     validateTimeZone,
+	},
+	"weekly_digest_day": {
+		description:  "Weekday on which a server running over HTTP emails the digest of the previous week, e.g. mon (empty turns it off)",
+		defaultValue: "",
+		validate:     validateDigestDay,
+	},
+	"weekly_digest_to": {
+		description:  "Comma-separated addresses the weekly digest is emailed to (empty uses the business email)",
+		defaultValue: "",
+		validate:     validateAddressList,
+	},
+	"work_week": {
+		description:  "Comma-separated working days used for gap detection and weekend rate rules (e.g. mon,tue,wed,thu,fri)",
+		defaultValue: "mon,tue,wed,thu,fri",
+		validate: func(value string) error {
+			_, err := parseWorkWeek(value)
+			return err
+		},
+	},
+}
+
+func validateNonNegativeInt(value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return validationError("must be a whole number of 0 or more")
+	}
+	return nil
+}
+
+func validatePercentage(value string) error {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 || rate > 100 {
+		return validationError("must be a percentage between 0 and 100")
+	}
+	return nil
+}
+
+func validateNonNegativeAmount(value string) error {
+	amount, err := money.Parse(value)
+	if err != nil || amount < 0 {
+		return validationError("must be an amount of 0 or more, e.g. 250 or 99.50")
+	}
+	return nil
+}
+
+func validateBool(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return validationError("must be true or false")
+	}
+	return nil
+}
+
+// getBoolSetting returns a true/false setting, falling back to its default
+func (h *Handler) getBoolSetting(ctx context.Context, key string) bool {
+	b, _ := h.lookupBoolSetting(ctx, key)
+	return b
+}
+
+// lookupBoolSetting is getBoolSetting for settings that must not quietly
+// fall back when the database can't be read, such as compliance_mode
+func (h *Handler) lookupBoolSetting(ctx context.Context, key string) (bool, error) {
+	value, err := h.lookupSetting(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read setting %s: %w", key, err)
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		b, _ = strconv.ParseBool(knownSettings[key].defaultValue)
+	}
+	return b, nil
+}
+
+// getIntSetting returns an integer setting, falling back to its default
+func (h *Handler) getIntSetting(ctx context.Context, key string) int {
+	n, err := strconv.Atoi(h.getSetting(ctx, key))
+	if err != nil {
+		n, _ = strconv.Atoi(knownSettings[key].defaultValue)
+	}
+	return n
+}
+
+// dateOrderLayouts maps a date order setting to its Go layout
+var dateOrderLayouts = map[string]string{
+	"mdy": "01/02/2006",
+	"dmy": "02/01/2006",
+	"ymd": "2006-01-02",
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseWorkWeek converts "mon,tue,..." into a weekday set
+func parseWorkWeek(value string) (map[time.Weekday]bool, error) {
+	days := map[time.Weekday]bool{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		if len(part) > 3 {
+			part = part[:3]
+		}
+		day, ok := weekdayNames[part]
+		if !ok {
+			return nil, validationError("invalid weekday '%s'", part)
+		}
+		days[day] = true
+	}
+	if len(days) == 0 {
+		return nil, validationError("work week must contain at least one day")
+	}
+	return days, nil
+}
+
+// registerSettingsTools registers configuration and holiday calendar tools
+func registerSettingsTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Set Setting tool
+	type setSettingArgs struct {
+		Key   string `json:"key" jsonschema:"Setting name (use list_settings to see available settings)"`
+		Value string `json:"value" jsonschema:"New value (empty string resets to the default)"`
+	}
+
+	type setSettingResult struct {
+		Key     string `json:"key"`
+		Value   string `json:"value"`
+		Default bool   `json:"default" jsonschema:"Whether the setting was reset to its default"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_setting",
+		Description: "Change a configuration setting",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setSettingArgs) (*mcp.CallToolResult, *setSettingResult, error) {
+		def, ok := knownSettings[args.Key]
+		if !ok {
+			return nil, nil, validationError("unknown setting '%s'. Use 'list_settings' to see available settings", args.Key)
+		}
+
+		if args.Value == "" {
+			if _, err := db.ExecContext(ctx, "DELETE FROM settings WHERE key = ?", args.Key); err != nil {
+				return nil, nil, fmt.Errorf("failed to reset setting: %w", err)
+			}
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Setting '%s' reset to default (%s)", args.Key, def.defaultValue)},
+				},
+			}, &setSettingResult{Key: args.Key, Value: def.defaultValue, Default: true}, nil
+		}
+
+		if def.validate != nil {
+			if err := def.validate(args.Value); err != nil {
+				return nil, nil, validationError("invalid value for %s: %w", args.Key, err)
+			}
+		}
+
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO settings (key, value, updated_at)
+			VALUES (?, ?, ?)
+			ON CONFLICT(key) DO UPDATE SET
+				value = excluded.value,
+				updated_at = excluded.updated_at
+		`, args.Key, args.Value, time.Now())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to save setting: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Setting '%s' set to '%s'", args.Key, args.Value)},
+			},
+		}, &setSettingResult{Key: args.Key, Value: args.Value}, nil
+	})
+
+	// List Settings tool
+	type listSettingsArgs struct{}
+
+	type listSettingsResult struct {
+		Settings map[string]string `json:"settings" jsonschema:"Current value of each setting"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_settings",
+		Description: "List all configuration settings with their current values",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listSettingsArgs) (*mcp.CallToolResult, *listSettingsResult, error) {
+		keys := make([]string, 0, len(knownSettings))
+		for key := range knownSettings {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		values := map[string]string{}
+		text := "Settings:\n"
+		for _, key := range keys {
+			value := h.getSetting(ctx, key)
+			values[key] = value
+			text += fmt.Sprintf("- %s = %s\n  %s\n", key, value, knownSettings[key].description)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &listSettingsResult{Settings: values}, nil
+	})
+
+	// Add Holiday tool
+	type addHolidayArgs struct {
+		Date string `json:"date" jsonschema:"Holiday date (YYYY-MM-DD or natural language)"`
+		Name string `json:"name" jsonschema:"Holiday name"`
+	}
+
+	type holiday struct {
+		Date string `json:"date"`
+		Name string `json:"name"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "add_holiday",
+		Description: "Add a holiday to the calendar so it is not reported as a missing work day",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args addHolidayArgs) (*mcp.CallToolResult, *holiday, error) {
+		date, err := h.parseDate(ctx, args.Date)
+		if err != nil {
+			return nil, nil, validationError("invalid date: %w", err)
+		}
+
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO holidays (date, name) VALUES (?, ?)
+			ON CONFLICT(date) DO UPDATE SET name = excluded.name
+		`, date.Format("2006-01-02"), args.Name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to add holiday: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Holiday '%s' added on %s", args.Name, date.Format("2006-01-02"))},
+			},
+		}, &holiday{Date: date.Format("2006-01-02"), Name: args.Name}, nil
+	})
+
+	// List Holidays tool
+	type listHolidaysArgs struct {
+		Year int `json:"year,omitempty" jsonschema:"Only list holidays in this year (optional)"`
+	}
+
+	type listHolidaysResult struct {
+		Holidays []holiday `json:"holidays"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_holidays",
+		Description: "List holidays in the calendar",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listHolidaysArgs) (*mcp.CallToolResult, *listHolidaysResult, error) {
+		query := "SELECT date, name FROM holidays"
+		queryArgs := []interface{}{}
+		if args.Year != 0 {
+			query += " WHERE date >= ? AND date <= ?"
+			queryArgs = append(queryArgs, fmt.Sprintf("%d-01-01", args.Year), fmt.Sprintf("%d-12-31", args.Year))
+		}
+		query += " ORDER BY date"
+
+		rows, err := db.QueryContext(ctx, query, queryArgs...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list holidays: %w", err)
+		}
+		defer rows.Close()
+
+		var holidays []holiday
+		text := "Holidays:\n"
+		for rows.Next() {
+			var date time.Time
+			var hol holiday
+			if err := rows.Scan(&date, &hol.Name); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan holiday: %w", err)
+			}
+			hol.Date = date.Format("2006-01-02")
+			holidays = append(holidays, hol)
+			text += fmt.Sprintf("- %s: %s\n", hol.Date, hol.Name)
+		}
+		if len(holidays) == 0 {
+			text += "No holidays found.\n"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &listHolidaysResult{Holidays: holidays}, nil
+	})
+
+	// Remove Holiday tool
+	type removeHolidayArgs struct {
+		Date string `json:"date" jsonschema:"Holiday date to remove (YYYY-MM-DD or natural language)"`
+	}
+
+	type removeHolidayResult struct {
+		Date string `json:"date"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "remove_holiday",
+		Description: "Remove a holiday from the calendar",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args removeHolidayArgs) (*mcp.CallToolResult, *removeHolidayResult, error) {
+		date, err := h.parseDate(ctx, args.Date)
+		if err != nil {
+			return nil, nil, validationError("invalid date: %w", err)
+		}
+
+		result, err := db.ExecContext(ctx, "DELETE FROM holidays WHERE date = ?", date.Format("2006-01-02"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to remove holiday: %w", err)
+		}
+
+		rowsAffected, _ := result.RowsAffected()
+		if rowsAffected == 0 {
+			return nil, nil, notFoundError("holiday", "no holiday found on %s", date.Format("2006-01-02"))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Removed holiday on %s", date.Format("2006-01-02"))},
+			},
+		}, &removeHolidayResult{Date: date.Format("2006-01-02")}, nil
+	})
+}
+
+// getSetting returns the stored value for key, or its default when unset
+func (h *Handler) getSetting(ctx context.Context, key string) string {
+	value, err := h.lookupSetting(ctx, key)
+	if err != nil {
+		return knownSettings[key].defaultValue
+	}
+	return value
+}
+
+// lookupSetting returns a setting, or its default when it isn't set, and
+// any error reading it
+func (h *Handler) lookupSetting(ctx context.Context, key string) (string, error) {
+	var value string
+	err := h.db.QueryRowContext(ctx, "SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return knownSettings[key].defaultValue, nil
+	}
+	return value, err
+}
+
+// getHolidays returns holiday names keyed by YYYY-MM-DD within the range
+func (h *Handler) getHolidays(ctx context.Context, start, end time.Time) (map[string]string, error) {
+	rows, err := h.db.QueryContext(ctx, "SELECT date, name FROM holidays WHERE date >= ? AND date <= ?",
+		start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load holidays: %w", err)
+	}
+	defer rows.Close()
+
+	holidays := map[string]string{}
+	for rows.Next() {
+		var date time.Time
+		var name string
+		if err := rows.Scan(&date, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan holiday: %w", err)
+		}
+		holidays[date.Format("2006-01-02")] = name
+	}
+	return holidays, nil
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/austin/hours-mcp/internal/store"
+)
+
+// invoiceSidecarFormat identifies the JSON written next to invoice PDFs;
+// the version goes up when fields are renamed or removed, not when added
+const (
+	invoiceSidecarFormat  = "hours-mcp-invoice"
+	invoiceSidecarVersion = 1
+)
+
+// invoiceSidecar is everything printed on an invoice PDF, for automation
+// that shouldn't have to parse the PDF. Amounts are in the invoice currency.
+type invoiceSidecar struct {
+	Format        string `json:"format"`
+	Version       int    `json:"version"`
+	InvoiceNumber string `json:"invoice_number"`
+	IssueDate     string `json:"issue_date"`
+	DueDate       string `json:"due_date"`
+	Currency      string `json:"currency"`
+	PDFFile       string `json:"pdf_file"`
+
+	Seller     sidecarParty       `json:"seller"`
+	Client     sidecarParty       `json:"client"`
+	Recipients []sidecarRecipient `json:"recipients,omitempty"`
+	Cc         []string           `json:"cc,omitempty"`
+
+	// Lines are grouped per contract, rate and premium as on accounting
+	// exports; Items are the priced entries, expenses and fees behind them
+	Lines []sidecarLine `json:"lines"`
+	Items []sidecarItem `json:"items"`
+
+	TotalHours        float64     `json:"total_hours"`
+	Subtotal          money.Cents `json:"subtotal"`
+	TaxRate           float64     `json:"tax_rate"`
+	TaxAmount         money.Cents `json:"tax_amount"`
+	TaxTreatment      string      `json:"tax_treatment"`
+	TaxNote           string      `json:"tax_note,omitempty"`
+	Rounding          money.Cents `json:"rounding,omitempty"`
+	Total             money.Cents `json:"total"`
+	WithholdingRate   float64     `json:"withholding_rate,omitempty"`
+	WithholdingAmount money.Cents `json:"withholding_amount,omitempty"`
+	AmountDue         money.Cents `json:"amount_due"`
+
+	Payment sidecarPayment `json:"payment"`
+}
+
+type sidecarParty struct {
+	Name        string `json:"name"`
+	ContactName string `json:"contact_name,omitempty"`
+	Email       string `json:"email,omitempty"`
+	Phone       string `json:"phone,omitempty"`
+	Address     string `json:"address,omitempty"`
+	City        string `json:"city,omitempty"`
+	State       string `json:"state,omitempty"`
+	ZipCode     string `json:"zip_code,omitempty"`
+	Country     string `json:"country,omitempty"`
+	TaxID       string `json:"tax_id,omitempty"`
+	Website     string `json:"website,omitempty"`
+}
+
+type sidecarRecipient struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type sidecarContract struct {
+	Number string `json:"contract_number"`
+	Name   string `json:"contract_name"`
+}
+
+// sidecarLine is an invoice line summed as on accounting exports. Its kind
+// is time for hours at the contract rate, else the rate kind: a premium such
+// as weekend or overtime, no_charge, included, overage, retainer_fee or
+// expense.
+type sidecarLine struct {
+	sidecarContract
+	Kind   string      `json:"kind"`
+	Label  string      `json:"label,omitempty"`
+	Period string      `json:"period,omitempty"`
+	Hours  float64     `json:"hours,omitempty"`
+	Rate   money.Cents `json:"rate"`
+	Amount money.Cents `json:"amount"`
+}
+
+// sidecarItem is a priced time entry, or part of one split off at another
+// rate, an expense or a retainer fee
+type sidecarItem struct {
+	sidecarContract
+	Date        string      `json:"date"`
+	Kind        string      `json:"kind"`
+	Description string      `json:"description,omitempty"`
+	Label       string      `json:"label,omitempty"`
+	Hours       float64     `json:"hours,omitempty"`
+	Multiplier  float64     `json:"multiplier,omitempty"`
+	Rate        money.Cents `json:"rate"`
+	Amount      money.Cents `json:"amount"`
+	TimeEntryID string      `json:"time_entry_id,omitempty"`
+	ExpenseID   int         `json:"expense_id,omitempty"`
+}
+
+// sidecarPayment is where the invoice asks to be paid, with the account
+// number masked when it is on the PDF
+type sidecarPayment struct {
+	Method        string `json:"method,omitempty"`
+	Kind          string `json:"kind,omitempty"`
+	BankName      string `json:"bank_name,omitempty"`
+	AccountNumber string `json:"account_number,omitempty"`
+	RoutingNumber string `json:"routing_number,omitempty"`
+	SwiftCode     string `json:"swift_code,omitempty"`
+	Terms         string `json:"terms,omitempty"`
+	Notes         string `json:"notes,omitempty"`
+}
+
+// invoiceSidecarPath returns where the JSON for an invoice PDF is written:
+// next to it, with the same name
+func invoiceSidecarPath(pdfPath string) string {
+	return strings.TrimSuffix(pdfPath, filepath.Ext(pdfPath)) + ".json"
+}
+
+// sidecarContracts returns the number and name of a client's contracts by
+// ID, for labelling invoice lines
+func sidecarContracts(ctx context.Context, q store.Querier, clientID int) (map[int]sidecarContract, error) {
+	rows, err := q.QueryContext(ctx, "SELECT id, contract_number, name FROM contracts WHERE client_id = ?", clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contracts: %w", err)
+	}
+	defer rows.Close()
+
+	contracts := map[int]sidecarContract{}
+	for rows.Next() {
+		var id int
+		var c sidecarContract
+		if err := rows.Scan(&id, &c.Number, &c.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan contract: %w", err)
+		}
+		contracts[id] = c
+	}
+	return contracts, rows.Err()
+}
+
+// writeInvoiceSidecar writes the JSON for an invoice next to its PDF and
+// returns its path. The invoice is as passed to the PDF generator, and the
+// bill's payment details are masked like the PDF's.
+func writeInvoiceSidecar(pdfPath string, invoice models.Invoice, bill *invoiceBill, contracts map[int]sidecarContract,
+	business models.BusinessInfo, recipients []models.Recipient, cc []string) (string, error) {
+	payment := bill.payment
+	sidecar := invoiceSidecar{
+		Format:        invoiceSidecarFormat,
+		Version:       invoiceSidecarVersion,
+		InvoiceNumber: invoice.InvoiceNumber,
+		IssueDate:     invoice.IssueDate.Format("2006-01-02"),
+		DueDate:       invoice.DueDate.Format("2006-01-02"),
+		Currency:      invoice.Currency,
+		PDFFile:       filepath.Base(pdfPath),
+		Seller: sidecarParty{
+			Name:        business.BusinessName,
+			ContactName: business.ContactName,
+			Email:       business.Email,
+			Phone:       business.Phone,
+			Address:     business.Address,
+			City:        business.City,
+			State:       business.State,
+			ZipCode:     business.ZipCode,
+			Country:     business.Country,
+			TaxID:       business.TaxID,
+			Website:     business.Website,
+		},
+		Cc:                cc,
+		Lines:             []sidecarLine{},
+		Items:             []sidecarItem{},
+		TotalHours:        bill.hours,
+		Subtotal:          bill.subtotal,
+		TaxRate:           invoice.TaxRate,
+		TaxAmount:         invoice.TaxAmount,
+		TaxTreatment:      invoice.TaxTreatment,
+		TaxNote:           invoice.TaxNote,
+		Rounding:          invoice.Rounding,
+		Total:             invoice.TotalAmount,
+		WithholdingRate:   invoice.WithholdingRate,
+		WithholdingAmount: invoice.WithholdingAmount,
+		AmountDue:         invoice.TotalAmount - invoice.WithholdingAmount,
+		Payment: sidecarPayment{
+			Method:        payment.Method,
+			Kind:          payment.Kind,
+			BankName:      payment.BankName,
+			AccountNumber: payment.AccountNumber,
+			RoutingNumber: payment.RoutingNumber,
+			SwiftCode:     payment.SwiftCode,
+			Terms:         payment.PaymentTerms,
+			Notes:         payment.Notes,
+		},
+	}
+	if c := invoice.Client; c != nil {
+		sidecar.Client = sidecarParty{
+			Name:    c.Name,
+			Address: c.Address,
+			City:    c.City,
+			State:   c.State,
+			ZipCode: c.ZipCode,
+			Country: c.Country,
+			TaxID:   c.TaxID,
+		}
+	}
+	for _, r := range recipients {
+		sidecar.Recipients = append(sidecar.Recipients, sidecarRecipient{Name: r.Name, Email: r.Email})
+	}
+
+	// Hours billed at the contract rate have no kind of their own
+	kind := func(rateKind string) string {
+		if rateKind == "" {
+			return "time"
+		}
+		return rateKind
+	}
+	for _, line := range groupInvoiceItems(bill.items) {
+		sidecar.Lines = append(sidecar.Lines, sidecarLine{
+			sidecarContract: contracts[line.contractID],
+			Kind:            kind(line.rateKind),
+			Label:           line.rateLabel,
+			Period:          line.period,
+			Hours:           line.hours,
+			Rate:            line.rate,
+			Amount:          line.amount,
+		})
+	}
+	for _, item := range invoice.Items {
+		sidecar.Items = append(sidecar.Items, sidecarItem{
+			sidecarContract: contracts[item.ContractID],
+			Date:            item.Date.Format("2006-01-02"),
+			Kind:            kind(item.RateKind),
+			Description:     item.Description,
+			Label:           item.RateLabel,
+			Hours:           item.Hours,
+			Multiplier:      item.Multiplier,
+			Rate:            item.Rate,
+			Amount:          item.Amount,
+			TimeEntryID:     item.TimeEntryID,
+			ExpenseID:       item.ExpenseID,
+		})
+	}
+
+	// Addresses such as "Name <ap@example.com>" are kept readable
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(sidecar); err != nil {
+		return "", fmt.Errorf("failed to encode invoice JSON: %w", err)
+	}
+	path := invoiceSidecarPath(pdfPath)
+	if err := os.WriteFile(path, data.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("failed to write invoice JSON: %w", err)
+	}
+	return path, nil
+}
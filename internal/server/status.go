@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/database"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// registerStatusTools registers the tool describing the server and its
+// database
+func registerStatusTools(server *mcp.Server, db *sql.DB, h *Handler, impl *mcp.Implementation) {
+	type serverStatusArgs struct{}
+
+	type recordCounts struct {
+		Clients         int `json:"clients" jsonschema:"Clients that aren't archived"`
+		ActiveContracts int `json:"active_contracts"`
+		TimeEntries     int `json:"time_entries"`
+		UnbilledEntries int `json:"unbilled_entries"`
+		Invoices        int `json:"invoices"`
+		UnpaidInvoices  int `json:"unpaid_invoices" jsonschema:"Invoices neither paid, written off nor cancelled"`
+	}
+
+	type serverStatusResult struct {
+		Version                string                  `json:"version"`
+		DatabasePath           string                  `json:"database_path"`
+		DatabaseSize           int64                   `json:"database_size" jsonschema:"Database size in bytes"`
+		Encrypted              bool                    `json:"encrypted"`
+		SearchAvailable        bool                    `json:"search_available" jsonschema:"Whether full-text search is available for search_time_entries"`
+		Schema                 *database.SchemaVersion `json:"schema"`
+		Counts                 recordCounts            `json:"counts"`
+		LastBackup             *database.BackupInfo    `json:"last_backup,omitempty" jsonschema:"The newest backup, if any"`
+		BusinessInfoConfigured bool                    `json:"business_info_configured"`
+		SMTPConfigured         bool                    `json:"smtp_configured"`
+		ExpiryDays             int                     `json:"expiry_days" jsonschema:"How many days ahead contracts were checked"`
+		ExpiringContracts      []contractExpiry        `json:"expiring_contracts"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "server_status",
+		Description: "Report the server version, database path, size and schema version, record counts, the last backup, setup still missing and contracts about to expire. A good first call in a session",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args serverStatusArgs) (*mcp.CallToolResult, *serverStatusResult, error) {
+		status := &serverStatusResult{
+			Version:         impl.Version,
+			DatabasePath:    database.Path(),
+			Encrypted:       database.Encrypted(),
+			SearchAvailable: h.search,
+		}
+
+		var err error
+		if status.DatabaseSize, err = database.Size(ctx, db); err != nil {
+			return nil, nil, err
+		}
+		if status.Schema, err = database.ReadSchemaVersion(ctx, db); err != nil {
+			return nil, nil, err
+		}
+
+		c := &status.Counts
+		var business, smtp int
+		err = db.QueryRowContext(ctx, `
+			SELECT (SELECT COUNT(*) FROM clients WHERE archived_at IS NULL),
+			       (SELECT COUNT(*) FROM contracts WHERE status = 'active'),
+			       (SELECT COUNT(*) FROM time_entries),
+			       (SELECT COUNT(*) FROM time_entries WHERE invoice_id IS NULL AND billable),
+			       (SELECT COUNT(*) FROM invoices),
+			       (SELECT COUNT(*) FROM invoices WHERE status NOT IN ('paid', 'written_off', 'cancelled')),
+			       (SELECT COUNT(*) FROM business_info),
+			       (SELECT COUNT(*) FROM smtp_config)
+		`).Scan(&c.Clients, &c.ActiveContracts, &c.TimeEntries, &c.UnbilledEntries, &c.Invoices, &c.UnpaidInvoices, &business, &smtp)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to count records: %w", err)
+		}
+		status.BusinessInfoConfigured = business > 0
+		status.SMTPConfigured = smtp > 0
+
+		// In-memory databases have no backups
+		if !database.InMemory() {
+			backups, err := database.ListBackups()
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(backups) > 0 {
+				status.LastBackup = &backups[0]
+			}
+		}
+
+		status.ExpiryDays = h.getIntSetting(ctx, "contract_expiry_days")
+		if status.ExpiringContracts, err = h.expiringContracts(ctx, status.ExpiryDays); err != nil {
+			return nil, nil, err
+		}
+
+		text := fmt.Sprintf("hours-mcp %s\n", status.Version)
+		text += fmt.Sprintf("Database: %s (%.1f KB", status.DatabasePath, float64(status.DatabaseSize)/1024)
+		if status.Encrypted {
+			text += ", encrypted"
+		}
+		text += ")\n"
+		text += fmt.Sprintf("Schema: %d of %d migrations applied", status.Schema.Applied, status.Schema.Known)
+		if status.Schema.Latest != "" {
+			text += fmt.Sprintf(", latest %s on %s", status.Schema.Latest, status.Schema.LatestAppliedAt.Local().Format("2006-01-02"))
+		}
+		text += "\n"
+		if status.Schema.Applied > status.Schema.Known {
+			text += "Warning: a newer version of hours-mcp has migrated this database\n"
+		}
+		text += fmt.Sprintf("Records: %d clients, %d active contracts, %d time entries (%d unbilled), %d invoices (%d unpaid)\n",
+			c.Clients, c.ActiveContracts, c.TimeEntries, c.UnbilledEntries, c.Invoices, c.UnpaidInvoices)
+
+		switch {
+		case database.InMemory():
+			text += "Last backup: none, in-memory databases are not backed up\n"
+		case status.LastBackup == nil:
+			text += "Last backup: none yet; use backup_now to take one\n"
+		default:
+			text += fmt.Sprintf("Last backup: %s (%s, %s ago)\n", status.LastBackup.CreatedAt.Format("2006-01-02 15:04"),
+				status.LastBackup.Reason, time.Since(status.LastBackup.CreatedAt).Round(time.Minute))
+		}
+
+		if !status.BusinessInfoConfigured {
+			text += "Setup: business information is missing; use set_business_info before creating invoices\n"
+		}
+		if !status.SMTPConfigured {
+			text += "Setup: SMTP is not configured; use set_smtp_config to email invoices\n"
+		}
+		if !status.SearchAvailable {
+			text += "Full-text search is unavailable; search_time_entries matches descriptions as substrings\n"
+		}
+
+		if len(status.ExpiringContracts) == 0 {
+			text += fmt.Sprintf("No active contracts end within %d days\n", status.ExpiryDays)
+		} else {
+			text += fmt.Sprintf("Contracts ending within %d days:\n", status.ExpiryDays)
+			for _, e := range status.ExpiringContracts {
+				text += fmt.Sprintf("- %s\n", e)
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, status, nil
+	})
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/austin/hours-mcp/internal/store"
+)
+
+// descriptionGroup totals the time entries sharing a description
+type descriptionGroup struct {
+	Description string  `json:"description" jsonschema:"The description as most often written; empty for entries without one"`
+	Hours       float64 `json:"hours"`
+	Entries     int     `json:"entries"`
+	FirstDate   string  `json:"first_date"`
+	LastDate    string  `json:"last_date"`
+}
+
+// validateGroupBy checks a list's group_by argument; true groups by
+// description
+func validateGroupBy(groupBy string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(groupBy)) {
+	case "", "entry":
+		return false, nil
+	case "description", "task":
+		return true, nil
+	}
+	return false, validationError("invalid group_by '%s': must be entry or description", groupBy)
+}
+
+// normalizeDescription makes descriptions that differ only in case,
+// spacing or trailing punctuation the same: "Code review." and "code
+// review" group together
+func normalizeDescription(description string) string {
+	return strings.ToLower(strings.TrimRight(strings.Join(strings.Fields(description), " "), ".,;:!"))
+}
+
+// groupEntriesByDescription totals entries by normalized description, most
+// hours first. Adjustments count toward the entry they adjust when it is
+// listed too, so a corrected task still adds up.
+func groupEntriesByDescription(entries []store.EntryListing) []descriptionGroup {
+	keys := map[string]string{}
+	for _, e := range entries {
+		keys[e.ID] = normalizeDescription(e.Description)
+	}
+
+	groups := map[string]*descriptionGroup{}
+	spellings := map[string]map[string]int{}
+	var order []string
+	for _, e := range entries {
+		key := keys[e.ID]
+		if original, ok := keys[e.AdjustsEntryID]; ok && e.AdjustsEntryID != "" {
+			key = original
+		}
+		date := e.Date.Format("2006-01-02")
+		g, ok := groups[key]
+		if !ok {
+			g = &descriptionGroup{FirstDate: date, LastDate: date}
+			groups[key] = g
+			spellings[key] = map[string]int{}
+			order = append(order, key)
+		}
+		g.Hours += e.Hours
+		g.Entries++
+		g.FirstDate = min(g.FirstDate, date)
+		g.LastDate = max(g.LastDate, date)
+		if e.AdjustsEntryID == "" {
+			spellings[key][strings.TrimSpace(e.Description)]++
+		}
+	}
+
+	result := make([]descriptionGroup, 0, len(order))
+	for _, key := range order {
+		g := groups[key]
+		// Show the spelling used most, the first of equals in sorted order
+		best := -1
+		for spelling, n := range spellings[key] {
+			if n > best || (n == best && spelling < g.Description) {
+				g.Description, best = spelling, n
+			}
+		}
+		result = append(result, *g)
+	}
+	slices.SortStableFunc(result, func(a, b descriptionGroup) int {
+		if a.Hours != b.Hours {
+			if a.Hours > b.Hours {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(strings.ToLower(a.Description), strings.ToLower(b.Description))
+	})
+	return result
+}
+
+// descriptionGroupsText lists the groups, e.g. "- Code review: 6.50 hours
+// across 9 entries (2026-10-01 to 2026-10-14)"
+func descriptionGroupsText(groups []descriptionGroup, totalHours float64, entries int, markdown bool) string {
+	label := func(g descriptionGroup) string {
+		if g.Description == "" {
+			return "(no description)"
+		}
+		return g.Description
+	}
+	dates := func(g descriptionGroup) string {
+		if g.FirstDate == g.LastDate {
+			return g.FirstDate
+		}
+		return g.FirstDate + " to " + g.LastDate
+	}
+
+	tasks := fmt.Sprintf("%d tasks", len(groups))
+	if len(groups) == 1 {
+		tasks = "1 task"
+	}
+	if markdown {
+		tableRows := make([][]string, 0, len(groups)+1)
+		for _, g := range groups {
+			tableRows = append(tableRows, []string{label(g), fmt.Sprintf("%d", g.Entries), fmt.Sprintf("%.2f", g.Hours), dates(g)})
+		}
+		tableRows = append(tableRows, []string{"**Total**", fmt.Sprintf("**%d**", entries), fmt.Sprintf("**%.2f**", totalHours), ""})
+		return fmt.Sprintf("**%s, %.2f total hours**\n\n", tasks, totalHours) +
+			markdownTable([]string{"Description", "Entries", "Hours", "Dates"}, tableRows, 1, 2)
+	}
+
+	text := fmt.Sprintf("Found %d entries (%.2f total hours) in %s:\n", entries, totalHours, tasks)
+	for _, g := range groups {
+		noun := "entries"
+		if g.Entries == 1 {
+			noun = "entry"
+		}
+		text += fmt.Sprintf("- %s: %.2f hours across %d %s (%s)\n", label(g), g.Hours, g.Entries, noun, dates(g))
+	}
+	return text
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/austin/hours-mcp/internal/money"
+)
+
+// Client tax treatments
+const (
+	taxTreatmentStandard      = "standard"
+	taxTreatmentReverseCharge = "reverse_charge"
+)
+
+func validateTaxTreatment(value string) error {
+	switch value {
+	case taxTreatmentStandard, taxTreatmentReverseCharge:
+		return nil
+	}
+	return validationError("invalid tax treatment '%s': must be standard or reverse_charge", value)
+}
+
+func validateWithholdingRate(rate float64) error {
+	if rate < 0 || rate >= 100 {
+		return validationError("withholding_rate must be a percentage from 0 up to 100")
+	}
+	return nil
+}
+
+// invoiceTax works out the tax lines for an invoice. Reverse-charge clients
+// are never charged tax, and withholding is taken from the net amount.
+type invoiceTax struct {
+	treatment         string
+	taxRate           float64
+	taxAmount         money.Cents
+	withholdingRate   float64
+	withholdingAmount money.Cents
+	note              string
+}
+
+func (h *Handler) computeInvoiceTax(ctx context.Context, subtotal money.Cents, taxRate float64, treatment string, withholdingRate float64, clientTaxID, currency string) invoiceTax {
+	t := invoiceTax{treatment: treatment, taxRate: taxRate, withholdingRate: withholdingRate}
+	var notes []string
+	if treatment == taxTreatmentReverseCharge {
+		t.taxRate = 0
+		note := h.getSetting(ctx, "reverse_charge_note")
+		if clientTaxID != "" {
+			note += fmt.Sprintf(" Customer VAT ID: %s.", clientTaxID)
+		}
+		notes = append(notes, note)
+	}
+	t.taxAmount = subtotal.Percent(t.taxRate)
+	if withholdingRate > 0 {
+		t.withholdingAmount = subtotal.Percent(withholdingRate)
+		notes = append(notes, fmt.Sprintf("%s (%g%%, %s).", strings.TrimRight(h.getSetting(ctx, "withholding_note"), "."),
+			withholdingRate, t.withholdingAmount.Format(currency)))
+	}
+	t.note = strings.Join(notes, " ")
+	return t
+}
@@ -0,0 +1,427 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Template kinds; each has a built-in fallback in builtinTemplates
+const (
+	templateInvoice  = "invoice"
+	templateReminder = "reminder"
+	templateThankYou = "thank_you"
+)
+
+// emailTemplate is a subject and body with {name} placeholders
+type emailTemplate struct {
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	ClientName string `json:"client_name,omitempty"`
+	Language   string `json:"language,omitempty"`
+	Subject    string `json:"subject"`
+	Body       string `json:"body"`
+	IsDefault  bool   `json:"is_default"`
+	BuiltIn    bool   `json:"built_in,omitempty"`
+}
+
+// builtinTemplates are used when no stored template applies
+var builtinTemplates = map[string]emailTemplate{
+	templateInvoice: {
+		Subject: "Invoice {invoice_number} from {business}",
+		Body: `Hi {recipient_name},
+
+Please find attached invoice {invoice_number} for {amount}, due on {due_date}.
+
+Thank you for your business.
+
+{contact_name}
+{business}`,
+	},
+	templateReminder: {
+		Subject: "Reminder: invoice {invoice_number} is due",
+		Body: `Hi {recipient_name},
+
+This is a friendly reminder that invoice {invoice_number}, issued on {issue_date}, has an outstanding balance of {balance} and was due on {due_date} ({days_overdue} days ago).
+
+I've attached a copy for your convenience. Please let me know if you have any questions.
+
+{contact_name}
+{business}`,
+	},
+	templateThankYou: {
+		Subject: "Thank you for your payment of invoice {invoice_number}",
+		Body: `Hi {recipient_name},
+
+Thank you for your payment of {amount} for invoice {invoice_number}, received on {paid_date}.
+
+It's a pleasure working with you.
+
+{contact_name}
+{business}`,
+	},
+}
+
+// templateVariables documents the placeholders templates may use
+var templateVariables = []string{
+	"{client}", "{recipient_name}", "{invoice_number}", "{amount}", "{balance}",
+	"{issue_date}", "{due_date}", "{paid_date}", "{days_overdue}", "{business}", "{contact_name}",
+}
+
+func validTemplateKind(kind string) bool {
+	_, ok := builtinTemplates[kind]
+	return ok
+}
+
+// normalizeTemplateLanguage validates the language a default template is
+// written in, a tag like de or the language of a locale like de-DE; empty
+// clears it
+func normalizeTemplateLanguage(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	m := localePattern.FindStringSubmatch(value)
+	if m == nil {
+		return "", validationError("invalid language '%s': use a language tag like de or fr", value)
+	}
+	return strings.ToLower(m[1]), nil
+}
+
+// renderTemplate replaces {name} placeholders with values from vars.
+// Unknown placeholders are left as they are.
+func renderTemplate(template string, vars map[string]string) string {
+	pairs := make([]string, 0, len(vars)*2)
+	for key, value := range vars {
+		pairs = append(pairs, "{"+key+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+func (h *Handler) getEmailTemplate(ctx context.Context, name string) (*emailTemplate, error) {
+	var t emailTemplate
+	err := h.db.QueryRowContext(ctx, `
+		SELECT t.name, t.kind, COALESCE(c.name, ''), t.language, t.subject, t.body, t.is_default
+		FROM email_templates t
+		LEFT JOIN clients c ON t.client_id = c.id
+		WHERE t.name = ?
+	`, name).Scan(&t.Name, &t.Kind, &t.ClientName, &t.Language, &t.Subject, &t.Body, &t.IsDefault)
+	if err == sql.ErrNoRows {
+		return nil, notFoundError("email_template", "email template '%s' not found. Use 'list_email_templates' to see available templates", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email template: %w", err)
+	}
+	return &t, nil
+}
+
+// templateFor picks the template of a kind for a client: the client's own
+// template first, then the default for the kind in the language of the
+// client's locale, then the default in no particular language, then the
+// built-in one
+func (h *Handler) templateFor(ctx context.Context, kind string, clientID int) (*emailTemplate, error) {
+	var locale string
+	err := h.db.QueryRowContext(ctx, "SELECT locale FROM clients WHERE id = ?", clientID).Scan(&locale)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to load client locale: %w", err)
+	}
+	language, _, _ := strings.Cut(locale, "-")
+
+	var name string
+	err = h.db.QueryRowContext(ctx, `
+		SELECT name FROM email_templates
+		WHERE kind = ? AND (client_id = ? OR (client_id IS NULL AND is_default AND language IN ('', ?)))
+		ORDER BY client_id IS NULL, language = '', updated_at DESC
+		LIMIT 1
+	`, kind, clientID, language).Scan(&name)
+	if err == sql.ErrNoRows {
+		t := builtinTemplates[kind]
+		t.Name, t.Kind, t.BuiltIn = kind, kind, true
+		return &t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find email template: %w", err)
+	}
+	return h.getEmailTemplate(ctx, name)
+}
+
+// registerTemplateTools registers email template management and payment reminders
+func registerTemplateTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Set Email Template tool
+	type setEmailTemplateArgs struct {
+		Name       string `json:"name" jsonschema:"Template name"`
+		Kind       string `json:"kind" jsonschema:"What the template is for: invoice, reminder or thank_you"`
+		Subject    string `json:"subject" jsonschema:"Subject line with placeholders such as {invoice_number}"`
+		Body       string `json:"body" jsonschema:"Message body with placeholders such as {client} {invoice_number} {balance}"`
+		ClientName string `json:"client_name,omitempty" jsonschema:"Use this template for one client only (optional)"`
+		IsDefault  bool   `json:"is_default,omitempty" jsonschema:"Use this template for every client without their own template of this kind"`
+		Language   string `json:"language,omitempty" jsonschema:"Language the template is written in, e.g. de; a default template with a language is used for clients whose locale is in it (optional)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "set_email_template",
+		Description: "Create or update a reusable email template for invoices, payment reminders or thank-you notes",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args setEmailTemplateArgs) (*mcp.CallToolResult, *emailTemplate, error) {
+		args.Name = strings.TrimSpace(args.Name)
+		if args.Name == "" {
+			return nil, nil, validationError("template name is required")
+		}
+		if !validTemplateKind(args.Kind) {
+			return nil, nil, validationError("invalid kind '%s'. Valid kinds are: invoice, reminder, thank_you", args.Kind)
+		}
+		if strings.TrimSpace(args.Subject) == "" || strings.TrimSpace(args.Body) == "" {
+			return nil, nil, validationError("subject and body are required")
+		}
+		language, err := normalizeTemplateLanguage(args.Language)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var clientID interface{}
+		if args.ClientName != "" {
+			id, err := h.getClientIDByName(ctx, args.ClientName)
+			if err != nil {
+				return nil, nil, fmt.Errorf("client not found: %w", err)
+			}
+			clientID = id
+			args.IsDefault = false
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		// There is one default per kind and language
+		if args.IsDefault {
+			if _, err := tx.ExecContext(ctx, "UPDATE email_templates SET is_default = FALSE WHERE kind = ? AND language = ?", args.Kind, language); err != nil {
+				return nil, nil, fmt.Errorf("failed to clear default template: %w", err)
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO email_templates (name, kind, client_id, language, subject, body, is_default, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(name) DO UPDATE SET
+				kind = excluded.kind,
+				client_id = excluded.client_id,
+				language = excluded.language,
+				subject = excluded.subject,
+				body = excluded.body,
+				is_default = excluded.is_default,
+				updated_at = excluded.updated_at
+		`, args.Name, args.Kind, clientID, language, args.Subject, args.Body, args.IsDefault, time.Now())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to save email template: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		scope := "available by name"
+		if args.ClientName != "" {
+			scope = fmt.Sprintf("used for %s", args.ClientName)
+		} else if args.IsDefault && language != "" {
+			scope = fmt.Sprintf("used by default for clients in %s", language)
+		} else if args.IsDefault {
+			scope = "used by default"
+		}
+		tpl, err := h.getEmailTemplate(ctx, args.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("Saved %s template '%s' (%s)", args.Kind, args.Name, scope),
+				},
+			},
+		}, tpl, nil
+	})
+
+	// List Email Templates tool
+	type listEmailTemplatesArgs struct {
+		Kind string `json:"kind,omitempty" jsonschema:"Only list templates of this kind (optional)"`
+	}
+
+	type listEmailTemplatesResult struct {
+		Templates    []emailTemplate `json:"templates"`
+		Placeholders []string        `json:"placeholders" jsonschema:"Placeholders subjects and bodies can use"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "list_email_templates",
+		Description: "List stored email templates and the built-in fallbacks, with the placeholders they can use",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listEmailTemplatesArgs) (*mcp.CallToolResult, *listEmailTemplatesResult, error) {
+		if args.Kind != "" && !validTemplateKind(args.Kind) {
+			return nil, nil, validationError("invalid kind '%s'. Valid kinds are: invoice, reminder, thank_you", args.Kind)
+		}
+
+		rows, err := db.QueryContext(ctx, `
+			SELECT t.name, t.kind, COALESCE(c.name, ''), t.language, t.subject, t.body, t.is_default
+			FROM email_templates t
+			LEFT JOIN clients c ON t.client_id = c.id
+			WHERE ? = '' OR t.kind = ?
+			ORDER BY t.kind, t.name
+		`, args.Kind, args.Kind)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list email templates: %w", err)
+		}
+		defer rows.Close()
+
+		var templates []emailTemplate
+		for rows.Next() {
+			var t emailTemplate
+			if err := rows.Scan(&t.Name, &t.Kind, &t.ClientName, &t.Language, &t.Subject, &t.Body, &t.IsDefault); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan email template: %w", err)
+			}
+			templates = append(templates, t)
+		}
+
+		kinds := make([]string, 0, len(builtinTemplates))
+		for kind := range builtinTemplates {
+			if args.Kind == "" || args.Kind == kind {
+				kinds = append(kinds, kind)
+			}
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			t := builtinTemplates[kind]
+			t.Name, t.Kind, t.BuiltIn = kind, kind, true
+			templates = append(templates, t)
+		}
+
+		text := fmt.Sprintf("Found %d email templates:\n", len(templates))
+		for _, t := range templates {
+			text += fmt.Sprintf("- %s [%s]", t.Name, t.Kind)
+			switch {
+			case t.BuiltIn:
+				text += " (built-in)"
+			case t.ClientName != "":
+				text += fmt.Sprintf(" (for %s)", t.ClientName)
+			case t.IsDefault && t.Language != "":
+				text += fmt.Sprintf(" (default, %s)", t.Language)
+			case t.IsDefault:
+				text += " (default)"
+			}
+			text += fmt.Sprintf(": %s\n", t.Subject)
+		}
+		text += fmt.Sprintf("Placeholders: %s\n", strings.Join(templateVariables, " "))
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &listEmailTemplatesResult{Templates: templates, Placeholders: templateVariables}, nil
+	})
+
+	// Delete Email Template tool
+	type deleteEmailTemplateArgs struct {
+		Name string `json:"name" jsonschema:"Template name to delete"`
+	}
+
+	type deleteEmailTemplateResult struct {
+		Name string `json:"name"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "delete_email_template",
+		Description: "Delete a stored email template",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args deleteEmailTemplateArgs) (*mcp.CallToolResult, *deleteEmailTemplateResult, error) {
+		result, err := db.ExecContext(ctx, "DELETE FROM email_templates WHERE name = ?", args.Name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to delete email template: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return nil, nil, notFoundError("email_template", "email template '%s' not found", args.Name)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Deleted email template '%s'", args.Name)},
+			},
+		}, &deleteEmailTemplateResult{Name: args.Name}, nil
+	})
+
+	// Generate Payment Reminder tool
+	type generatePaymentReminderArgs struct {
+		InvoiceNumber string   `json:"invoice_number" jsonschema:"Unpaid invoice to remind the client about"`
+		Template      string   `json:"template,omitempty" jsonschema:"Template name (default: the client's reminder template, the default one, or the built-in)"`
+		Send          bool     `json:"send,omitempty" jsonschema:"Email the reminder with the invoice attached instead of only drafting it"`
+		To            []string `json:"to,omitempty" jsonschema:"Recipient addresses (default: the recipients the invoice was created for, primary first)"`
+		RecipientIDs  []int    `json:"recipient_ids,omitempty" jsonschema:"IDs of the client's recipients to send to instead of 'to' (see list_recipients)"`
+		Cc            []string `json:"cc,omitempty" jsonschema:"Addresses to copy in addition to those stored on the invoice (optional)"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "generate_payment_reminder",
+		Description: "Draft, and optionally email, a payment reminder for an unpaid invoice using an email template",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args generatePaymentReminderArgs) (*mcp.CallToolResult, *invoiceEmailResult, error) {
+		inv, err := h.loadInvoiceEmail(ctx, args.InvoiceNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		if inv.Status == "paid" || inv.Status == "written_off" || inv.Status == "cancelled" {
+			return nil, nil, conflictError("invoice %s is %s; no reminder needed", inv.InvoiceNumber, strings.ReplaceAll(inv.Status, "_", " "))
+		}
+
+		tpl, err := h.resolveTemplate(ctx, args.Template, templateReminder, inv.ClientID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		msg, err := h.composeInvoiceEmail(ctx, inv, tpl, args.To, args.RecipientIDs, args.Cc, args.Send)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		result := newInvoiceEmailResult(tpl, msg)
+		if !args.Send {
+			result.Body = msg.Body
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Reminder draft (template '%s'):\n\n%s", tpl.Name, messagePreview(msg))},
+				},
+			}, result, nil
+		}
+
+		if err := h.sendInvoiceEmail(ctx, inv, msg); err != nil {
+			return nil, nil, fmt.Errorf("failed to send reminder for %s: %w", inv.InvoiceNumber, err)
+		}
+		result.Sent = true
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("Reminder for %s emailed to %s", inv.InvoiceNumber, strings.Join(msg.To, ", ")),
+				},
+			},
+		}, result, nil
+	})
+}
+
+// resolveTemplate loads a template by name, or picks the one for kind and
+// client when name is empty
+func (h *Handler) resolveTemplate(ctx context.Context, name, kind string, clientID int) (*emailTemplate, error) {
+	if name == "" {
+		return h.templateFor(ctx, kind, clientID)
+	}
+	t, err := h.getEmailTemplate(ctx, name)
+	if err != nil {
+		// Built-in templates can be named by their kind
+		if builtin, ok := builtinTemplates[name]; ok {
+			builtin.Name, builtin.Kind, builtin.BuiltIn = name, name, true
+			return &builtin, nil
+		}
+		return nil, err
+	}
+	return t, nil
+}
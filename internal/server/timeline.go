@@ -0,0 +1,295 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// timelineEvent is one dated event in a client's history
+type timelineEvent struct {
+	Date        time.Time `json:"date"`
+	Kind        string    `json:"kind" jsonschema:"client_added, contract_started, contract_ended, invoice_issued, invoice_sent, invoice_paid, invoice_overdue, first_work, last_work or work_gap"`
+	Description string    `json:"description"`
+	Reference   string    `json:"reference,omitempty" jsonschema:"The contract or invoice number the event is about"`
+}
+
+// registerTimelineTools registers the tool summarizing a client's history
+func registerTimelineTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	type clientTimelineArgs struct {
+		ClientName string `json:"client_name" jsonschema:"Client whose history to show"`
+		StartDate  string `json:"start_date,omitempty" jsonschema:"Only events on or after this date (optional)"`
+		EndDate    string `json:"end_date,omitempty" jsonschema:"Only events on or before this date (optional)"`
+		GapDays    int    `json:"gap_days,omitempty" jsonschema:"Report breaks in work longer than this many days (default 30)"`
+		Limit      int    `json:"limit,omitempty" jsonschema:"Maximum number of events, keeping the most recent (default 50)"`
+	}
+
+	type clientTimelineResult struct {
+		ClientName string          `json:"client_name"`
+		Events     []timelineEvent `json:"events" jsonschema:"Events oldest first"`
+		Omitted    int             `json:"omitted,omitempty" jsonschema:"Older events left out by limit"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "client_timeline",
+		Description: "List the significant events in a client's history oldest first: contracts starting and ending, invoices issued, sent, paid, overdue and written off, and long breaks in work. Useful to refresh context before a call",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args clientTimelineArgs) (*mcp.CallToolResult, *clientTimelineResult, error) {
+		if args.GapDays < 0 {
+			return nil, nil, validationError("gap_days must not be negative")
+		}
+		if args.GapDays == 0 {
+			args.GapDays = 30
+		}
+		if args.Limit <= 0 {
+			args.Limit = 50
+		}
+
+		clientID, err := h.getClientIDByName(ctx, args.ClientName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("client not found: %w", err)
+		}
+		startDate, err := h.parseDateFilter(ctx, args.StartDate, "start")
+		if err != nil {
+			return nil, nil, err
+		}
+		endDate, err := h.parseDateFilter(ctx, args.EndDate, "end")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		client, err := h.loadClient(ctx, clientID)
+		if err != nil {
+			return nil, nil, err
+		}
+		loc := h.location(ctx)
+		today := h.clientToday(ctx, clientID)
+
+		events := []timelineEvent{{
+			Date:        calendarDay(client.CreatedAt.In(loc)),
+			Kind:        "client_added",
+			Description: fmt.Sprintf("%s added as a client", client.Name),
+		}}
+
+		contracts, err := h.timelineContracts(ctx, clientID, today)
+		if err != nil {
+			return nil, nil, err
+		}
+		events = append(events, contracts...)
+
+		invoices, err := h.timelineInvoices(ctx, clientID, today, loc)
+		if err != nil {
+			return nil, nil, err
+		}
+		events = append(events, invoices...)
+
+		work, err := h.timelineWork(ctx, clientID, today, args.GapDays)
+		if err != nil {
+			return nil, nil, err
+		}
+		events = append(events, work...)
+
+		sort.SliceStable(events, func(i, j int) bool {
+			return events[i].Date.Before(events[j].Date)
+		})
+
+		result := &clientTimelineResult{ClientName: client.Name, Events: []timelineEvent{}}
+		for _, e := range events {
+			if startDate != nil && e.Date.Before(*startDate) {
+				continue
+			}
+			if endDate != nil && e.Date.After(*endDate) {
+				continue
+			}
+			result.Events = append(result.Events, e)
+		}
+		if len(result.Events) > args.Limit {
+			result.Omitted = len(result.Events) - args.Limit
+			result.Events = result.Events[result.Omitted:]
+		}
+
+		var text string
+		if len(result.Events) == 0 {
+			text = fmt.Sprintf("No events for %s in that period\n", client.Name)
+		} else {
+			text = fmt.Sprintf("Timeline for %s:\n", client.Name)
+			if result.Omitted > 0 {
+				text += fmt.Sprintf("(%d older events left out; raise limit or set start_date to see them)\n", result.Omitted)
+			}
+			for _, e := range result.Events {
+				text += fmt.Sprintf("%s  %s\n", e.Date.Format("2006-01-02"), e.Description)
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+}
+
+// timelineContracts returns when each of a client's contracts started and,
+// if by today, ended
+func (h *Handler) timelineContracts(ctx context.Context, clientID int, today time.Time) ([]timelineEvent, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT contract_number, name, hourly_rate_cents, currency, contract_type, start_date, end_date
+		FROM contracts WHERE client_id = ?
+	`, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contracts: %w", err)
+	}
+	defer rows.Close()
+
+	var events []timelineEvent
+	for rows.Next() {
+		var number, name, currency, contractType string
+		var rate money.Cents
+		var start time.Time
+		var end sql.NullTime
+		if err := rows.Scan(&number, &name, &rate, &currency, &contractType, &start, &end); err != nil {
+			return nil, fmt.Errorf("failed to scan contract: %w", err)
+		}
+
+		terms := fmt.Sprintf("at %s/hour", rate.Format(currency))
+		if contractType != "hourly" {
+			terms = "as a " + contractType + " contract"
+		}
+		events = append(events, timelineEvent{
+			Date:        start,
+			Kind:        "contract_started",
+			Description: fmt.Sprintf("Contract %s (%s) started %s", number, name, terms),
+			Reference:   number,
+		})
+		if end.Valid && !end.Time.After(today) {
+			events = append(events, timelineEvent{
+				Date:        end.Time,
+				Kind:        "contract_ended",
+				Description: fmt.Sprintf("Contract %s (%s) ended", number, name),
+				Reference:   number,
+			})
+		}
+	}
+	return events, rows.Err()
+}
+
+// timelineInvoices returns when each of a client's invoices was issued,
+// sent and paid, and when unpaid ones fell overdue
+func (h *Handler) timelineInvoices(ctx context.Context, clientID int, today time.Time, loc *time.Location) ([]timelineEvent, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT invoice_number, issue_date, due_date, total_cents, currency, status, paid_date, sent_at, delivery_method, written_off_date
+		FROM invoices WHERE client_id = ?
+	`, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoices: %w", err)
+	}
+	defer rows.Close()
+
+	var events []timelineEvent
+	for rows.Next() {
+		var number, currency, status, method string
+		var total money.Cents
+		var issued, due time.Time
+		var paid, sent, writtenOff sql.NullTime
+		if err := rows.Scan(&number, &issued, &due, &total, &currency, &status, &paid, &sent, &method, &writtenOff); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice: %w", err)
+		}
+
+		issue := fmt.Sprintf("Invoice %s issued for %s, due %s", number, total.Format(currency), due.Format("2006-01-02"))
+		if status == "cancelled" {
+			issue += " (since cancelled)"
+		}
+		events = append(events, timelineEvent{Date: issued, Kind: "invoice_issued", Description: issue, Reference: number})
+
+		if sent.Valid {
+			description := fmt.Sprintf("Invoice %s sent", number)
+			if method != "" {
+				description += " by " + method
+			}
+			events = append(events, timelineEvent{
+				Date:        calendarDay(sent.Time.In(loc)),
+				Kind:        "invoice_sent",
+				Description: description,
+				Reference:   number,
+			})
+		}
+
+		switch {
+		case status == "paid" && paid.Valid:
+			events = append(events, timelineEvent{
+				Date:        paid.Time,
+				Kind:        "invoice_paid",
+				Description: fmt.Sprintf("Invoice %s paid, %d days after issue", number, int(paid.Time.Sub(issued).Hours()/24)),
+				Reference:   number,
+			})
+		case status == "written_off" && writtenOff.Valid:
+			events = append(events, timelineEvent{
+				Date:        writtenOff.Time,
+				Kind:        "invoice_written_off",
+				Description: fmt.Sprintf("Invoice %s written off as uncollectable", number),
+				Reference:   number,
+			})
+		case status != "paid" && status != "written_off" && status != "cancelled" && status != "draft" && due.Before(today):
+			events = append(events, timelineEvent{
+				Date:        due,
+				Kind:        "invoice_overdue",
+				Description: fmt.Sprintf("Invoice %s fell overdue and is still unpaid (%d days)", number, int(today.Sub(due).Hours()/24)),
+				Reference:   number,
+			})
+		}
+	}
+	return events, rows.Err()
+}
+
+// timelineWork returns the first and last days of work for a client and
+// every break between them, or since the last day, longer than gapDays
+func (h *Handler) timelineWork(ctx context.Context, clientID int, today time.Time, gapDays int) ([]timelineEvent, error) {
+	rows, err := h.db.QueryContext(ctx, "SELECT DISTINCT date FROM time_entries WHERE client_id = ? ORDER BY date", clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load time entries: %w", err)
+	}
+	defer rows.Close()
+
+	var days []time.Time
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			return nil, fmt.Errorf("failed to scan time entry: %w", err)
+		}
+		days = append(days, day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(days) == 0 {
+		return nil, nil
+	}
+
+	first, last := days[0], days[len(days)-1]
+	events := []timelineEvent{{Date: first, Kind: "first_work", Description: "First hours logged"}}
+	for i := 1; i < len(days); i++ {
+		gap := int(days[i].Sub(days[i-1]).Hours() / 24)
+		if gap > gapDays {
+			events = append(events, timelineEvent{
+				Date:        days[i],
+				Kind:        "work_gap",
+				Description: fmt.Sprintf("Work resumed after %d days without hours (last worked %s)", gap, days[i-1].Format("2006-01-02")),
+			})
+		}
+	}
+	if len(days) > 1 {
+		events = append(events, timelineEvent{Date: last, Kind: "last_work", Description: "Last hours logged"})
+	}
+	if gap := int(today.Sub(last).Hours() / 24); gap > gapDays {
+		events = append(events, timelineEvent{
+			Date:        today,
+			Kind:        "work_gap",
+			Description: fmt.Sprintf("No hours logged for %d days", gap),
+		})
+	}
+	return events, nil
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultToolTimeout bounds a tool call not listed in toolTimeouts
+const defaultToolTimeout = 30 * time.Second
+
+// toolTimeouts are the tools that may legitimately run longer than the
+// default: reports over long periods, bulk imports and exports, database
+// maintenance, and tools waiting on the network
+var toolTimeouts = map[string]time.Duration{
+	"calendar_view":         2 * time.Minute,
+	"find_missing_days":     2 * time.Minute,
+	"forecast":              2 * time.Minute,
+	"recap":                 2 * time.Minute,
+	"tax_report":            2 * time.Minute,
+	"tax_year_summary":      2 * time.Minute,
+	"unbilled_summary":      2 * time.Minute,
+	"create_invoice":        2 * time.Minute,
+	"invoice_all":           5 * time.Minute,
+	"email_invoice":         2 * time.Minute,
+	"weekly_digest":         2 * time.Minute,
+	"fetch_exchange_rates":  time.Minute,
+	"export_accounting":     5 * time.Minute,
+	"export_data":           5 * time.Minute,
+	"export_excel":          5 * time.Minute,
+	"export_paypal_invoice": 2 * time.Minute,
+	"import_calendar":       5 * time.Minute,
+	"import_data":           5 * time.Minute,
+	"import_git_log":        5 * time.Minute,
+	"import_time_entries":   5 * time.Minute,
+	"backup_now":            5 * time.Minute,
+	"restore_backup":        5 * time.Minute,
+	"db_maintenance":        10 * time.Minute,
+	"purge_old_data":        10 * time.Minute,
+}
+
+// toolTimeout returns how long the named tool may run
+func toolTimeout(name string) time.Duration {
+	if timeout, ok := toolTimeouts[name]; ok {
+		return timeout
+	}
+	return defaultToolTimeout
+}
+
+// withToolTimeouts bounds each tool call by its timeout. The call's context
+// is also cancelled when the client aborts the request, so queries run with
+// it stop early either way.
+func withToolTimeouts(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if !ok || call.Params == nil {
+			return next(ctx, method, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, toolTimeout(call.Params.Name))
+		defer cancel()
+		return next(ctx, method, req)
+	}
+}
@@ -0,0 +1,586 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/store"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// timerCheckInterval is how often the watchdog looks for timers running
+// past the timer_max_hours limit
+const timerCheckInterval = 15 * time.Minute
+
+// runningTimer is a timer that was started and not yet stopped
+type runningTimer struct {
+	ID             int       `json:"id"`
+	Name           string    `json:"name"`
+	ContractNumber string    `json:"contract_number"`
+	ClientName     string    `json:"client_name"`
+	Service        string    `json:"service,omitempty"`
+	Description    string    `json:"description,omitempty"`
+	StartedAt      time.Time `json:"started_at"`
+	ElapsedHours   float64   `json:"elapsed_hours"`
+
+	contractID int
+	clientID   int
+	serviceID  *int
+}
+
+// stoppedTimer is what a stopped timer was logged as
+type stoppedTimer struct {
+	Name           string  `json:"name"`
+	EntryID        string  `json:"entry_id,omitempty" jsonschema:"The time entry logged, empty when the timer was discarded or ran under a minute"`
+	ClientName     string  `json:"client_name"`
+	ContractNumber string  `json:"contract_number"`
+	Date           string  `json:"date"`
+	Hours          float64 `json:"hours"`
+	Description    string  `json:"description,omitempty"`
+	AutoStopped    bool    `json:"auto_stopped,omitempty" jsonschema:"Whether the timer ran past timer_max_hours and was stopped there, its entry flagged for review"`
+}
+
+// StartTimerWatchdog stops timers that run past the timer_max_hours limit,
+// so a timer left running logs no more than the limit. It runs until ctx
+// is cancelled.
+func StartTimerWatchdog(ctx context.Context, db *sql.DB) {
+	h := newHandler(db)
+	go func() {
+		ticker := time.NewTicker(timerCheckInterval)
+		defer ticker.Stop()
+		for {
+			stopped, err := h.stopOverdueTimers(ctx)
+			if err != nil {
+				slog.Error("failed to stop timers past their limit", "error", err)
+			}
+			for _, s := range stopped {
+				slog.Warn("stopped timer past its limit", "timer", s.Name, "contract", s.ContractNumber, "hours", s.Hours, "entry_id", s.EntryID)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// loadTimers returns the running timers, longest running first
+func (h *Handler) loadTimers(ctx context.Context) ([]runningTimer, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT t.id, t.name, t.contract_id, ct.contract_number, ct.client_id, cl.name, t.service_id, COALESCE(s.name, ''),
+		       t.description, t.started_at
+		FROM timers t
+		JOIN contracts ct ON t.contract_id = ct.id
+		JOIN clients cl ON ct.client_id = cl.id
+		LEFT JOIN services s ON t.service_id = s.id
+		ORDER BY t.started_at, t.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load timers: %w", err)
+	}
+	defer rows.Close()
+
+	var timers []runningTimer
+	now := time.Now()
+	for rows.Next() {
+		var t runningTimer
+		if err := rows.Scan(&t.ID, &t.Name, &t.contractID, &t.ContractNumber, &t.clientID, &t.ClientName, &t.serviceID, &t.Service,
+			&t.Description, &t.StartedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan timer: %w", err)
+		}
+		t.ElapsedHours = math.Round(now.Sub(t.StartedAt).Hours()*100) / 100
+		timers = append(timers, t)
+	}
+	return timers, rows.Err()
+}
+
+// stopTimer stops a timer in a transaction of its own
+func (h *Handler) stopTimer(ctx context.Context, t *runningTimer, end time.Time, description string, discard bool) (*stoppedTimer, error) {
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stopped, err := h.stopTimerTx(ctx, tx, t, end, description, discard)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return stopped, nil
+}
+
+// stopTimerTx logs a timer's hours until end as a time entry dated the day
+// it was started on in the client's time zone, in tx. A timer past the
+// timer_max_hours limit is stopped at the limit and its entry flagged for
+// review; with discard, nothing is logged.
+func (h *Handler) stopTimerTx(ctx context.Context, tx *sql.Tx, t *runningTimer, end time.Time, description string, discard bool) (*stoppedTimer, error) {
+	date := calendarDay(t.StartedAt.In(h.clientLocation(ctx, t.clientID)))
+	stopped := &stoppedTimer{
+		Name:           t.Name,
+		ClientName:     t.ClientName,
+		ContractNumber: t.ContractNumber,
+		Date:           date.Format("2006-01-02"),
+		Description:    description,
+	}
+	if limit := h.getIntSetting(ctx, "timer_max_hours"); limit > 0 {
+		if capped := t.StartedAt.Add(time.Duration(limit) * time.Hour); end.After(capped) {
+			end, stopped.AutoStopped = capped, true
+		}
+	}
+	stopped.Hours = h.roundHours(ctx, math.Round(end.Sub(t.StartedAt).Hours()*100)/100)
+
+	// Deleting first makes sure a timer stopped twice at once, by a tool
+	// and the watchdog, is logged once
+	result, err := tx.ExecContext(ctx, "DELETE FROM timers WHERE id = ?", t.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stop timer: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, conflictError("timer %s was already stopped", timerLabel(t.Name, t.ContractNumber))
+	}
+
+	if !discard && end.Sub(t.StartedAt) >= time.Minute {
+		entries := h.store.WithTx(tx).Entries
+		stopped.EntryID, err = entries.Create(ctx, t.clientID, t.contractID, t.ContractNumber, date, stopped.Hours, description, t.serviceID, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to log timer hours: %w", err)
+		}
+		if stopped.AutoStopped {
+			if err := entries.Update(ctx, stopped.EntryID, store.EntryChanges{NeedsReview: &stopped.AutoStopped}); err != nil {
+				return nil, fmt.Errorf("failed to flag time entry for review: %w", err)
+			}
+		}
+	}
+	return stopped, nil
+}
+
+// stopOverdueTimers stops the timers that have run past the timer_max_hours
+// limit, logging the limit's hours flagged for review
+func (h *Handler) stopOverdueTimers(ctx context.Context) ([]stoppedTimer, error) {
+	limit := h.getIntSetting(ctx, "timer_max_hours")
+	if limit == 0 {
+		return nil, nil
+	}
+	timers, err := h.loadTimers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var stopped []stoppedTimer
+	now := time.Now()
+	for i := range timers {
+		t := &timers[i]
+		if now.Sub(t.StartedAt) <= time.Duration(limit)*time.Hour {
+			continue
+		}
+		s, err := h.stopTimer(ctx, t, now, t.Description, false)
+		if err != nil {
+			return stopped, err
+		}
+		stopped = append(stopped, *s)
+	}
+	return stopped, nil
+}
+
+// timerLabel names a timer in tool output: its contract, and its name when
+// it has one of its own
+func timerLabel(name, contractNumber string) string {
+	if strings.EqualFold(name, contractNumber) {
+		return contractNumber
+	}
+	return fmt.Sprintf("'%s' on %s", name, contractNumber)
+}
+
+// findTimer returns the running timer with a name, or the only one running
+// when name is empty
+func findTimer(timers []runningTimer, name string) (*runningTimer, error) {
+	name = strings.TrimSpace(name)
+	if len(timers) == 0 {
+		return nil, notFoundError("timer", "no timer is running; start one with start_timer")
+	}
+	if name == "" {
+		if len(timers) > 1 {
+			return nil, validationError("%d timers are running (%s); name the one to stop", len(timers), timerNames(timers))
+		}
+		return &timers[0], nil
+	}
+	for i := range timers {
+		if strings.EqualFold(timers[i].Name, name) {
+			return &timers[i], nil
+		}
+	}
+	return nil, notFoundError("timer", "no timer named '%s' is running (running: %s)", name, timerNames(timers))
+}
+
+// timerNames lists the names of running timers
+func timerNames(timers []runningTimer) string {
+	names := make([]string, len(timers))
+	for i, t := range timers {
+		names[i] = t.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// timerStart is a checked request to start a timer
+type timerStart struct {
+	contract       *models.Contract
+	name           string
+	description    string
+	serviceID      *int
+	serviceWarning string
+}
+
+// checkTimerStart checks that a timer can be started on a contract under a
+// name, by default the contract number, that no running timer has, other
+// than the one with ID replacing
+func (h *Handler) checkTimerStart(ctx context.Context, contractNumber, name, description, service string, timers []runningTimer, replacing int) (*timerStart, error) {
+	if err := h.resolveContractNumber(ctx, &contractNumber); err != nil {
+		return nil, err
+	}
+	contract, err := h.store.Contracts.ByNumber(ctx, contractNumber)
+	if err == sql.ErrNoRows {
+		return nil, notFoundError("contract", "contract %s not found", contractNumber)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find contract: %w", err)
+	}
+	if contract.Status != "active" {
+		return nil, conflictError("contract %s is not active (status: %s)", contractNumber, contract.Status)
+	}
+	if err := h.checkClientActive(ctx, contract.ClientID); err != nil {
+		return nil, err
+	}
+
+	start := &timerStart{contract: contract, name: strings.TrimSpace(name), description: strings.TrimSpace(description)}
+	if start.name == "" {
+		start.name = contract.ContractNumber
+	}
+	for _, t := range timers {
+		if t.ID != replacing && strings.EqualFold(t.Name, start.name) {
+			return nil, conflictError("timer %s is already running since %s; stop it first or start this one under another name",
+				timerLabel(t.Name, t.ContractNumber), t.StartedAt.In(h.location(ctx)).Format("2006-01-02 15:04"))
+		}
+	}
+	if start.serviceID, start.serviceWarning, err = h.entryService(ctx, contract.ID, contract.ContractNumber, service); err != nil {
+		return nil, err
+	}
+	return start, nil
+}
+
+// startTimer starts a checked timer now
+func (h *Handler) startTimer(ctx context.Context, start *timerStart) (*runningTimer, error) {
+	id, err := insertTimer(ctx, h.db, start)
+	if err != nil {
+		return nil, err
+	}
+	return h.loadTimer(ctx, id)
+}
+
+// insertTimer saves a checked timer as started now on q, returning its ID
+func insertTimer(ctx context.Context, q store.Querier, start *timerStart) (int, error) {
+	result, err := q.ExecContext(ctx, `
+		INSERT INTO timers (name, contract_id, service_id, description, started_at) VALUES (?, ?, ?, ?, ?)
+	`, start.name, start.contract.ID, start.serviceID, start.description, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to start timer: %w", err)
+	}
+	id, _ := result.LastInsertId()
+	return int(id), nil
+}
+
+// loadTimer returns the running timer with an ID
+func (h *Handler) loadTimer(ctx context.Context, id int) (*runningTimer, error) {
+	timers, err := h.loadTimers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range timers {
+		if timers[i].ID == id {
+			return &timers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("failed to load started timer %d", id)
+}
+
+// startedText describes a timer just started
+func (h *Handler) startedText(ctx context.Context, t *runningTimer, start *timerStart) string {
+	text := fmt.Sprintf("Timer %s (%s) started at %s", timerLabel(t.Name, t.ContractNumber), t.ClientName, t.StartedAt.In(h.location(ctx)).Format("15:04"))
+	if t.Description != "" {
+		text += " - " + t.Description
+	}
+	if limit := h.getIntSetting(ctx, "timer_max_hours"); limit > 0 {
+		text += fmt.Sprintf("\nIt stops automatically after %d hours", limit)
+	}
+	if start.serviceWarning != "" {
+		text += "\nNote: " + start.serviceWarning
+	}
+	return text
+}
+
+// stoppedText describes what stopping a timer logged
+func stoppedText(t *runningTimer, stopped *stoppedTimer, discard bool) string {
+	switch {
+	case discard:
+		return fmt.Sprintf("Timer %s discarded after %.2f hours; nothing logged", timerLabel(t.Name, t.ContractNumber), t.ElapsedHours)
+	case stopped.EntryID == "":
+		return fmt.Sprintf("Timer %s stopped after less than a minute; nothing logged", timerLabel(t.Name, t.ContractNumber))
+	}
+	text := fmt.Sprintf("Logged %.2f hours for %s (%s) on %s", stopped.Hours, stopped.ClientName, stopped.ContractNumber, stopped.Date)
+	if stopped.Description != "" {
+		text += " - " + stopped.Description
+	}
+	text += fmt.Sprintf(" (ID: %s)", stopped.EntryID)
+	if stopped.AutoStopped {
+		text += fmt.Sprintf("\nThe timer ran %.2f hours, past the timer_max_hours limit, so only %.2f were logged and the entry is flagged for review; correct it with update_time_entry",
+			t.ElapsedHours, stopped.Hours)
+	}
+	return text
+}
+
+// autoStopText tells which timers were stopped at the timer_max_hours limit
+func autoStopText(stopped []stoppedTimer) string {
+	var text string
+	for _, s := range stopped {
+		text += fmt.Sprintf("Timer %s (%s) ran past the limit and was stopped at %.2f hours on %s, flagged for review (ID: %s); correct it with update_time_entry\n",
+			timerLabel(s.Name, s.ContractNumber), s.ClientName, s.Hours, s.Date, s.EntryID)
+	}
+	return text
+}
+
+// entriesNeedingReview lists the entries flagged for review, by date and
+// ID
+func entriesNeedingReview(entries []models.TimeEntry) []string {
+	var flagged []string
+	for _, e := range entries {
+		if e.NeedsReview {
+			flagged = append(flagged, fmt.Sprintf("%s %.2f hours (ID %s)", e.Date.Format("2006-01-02"), e.Hours, e.ID))
+		}
+	}
+	return flagged
+}
+
+// registerTimerTools registers the tools that time work as it happens and
+// log it as a time entry when stopped. Several named timers can run at once.
+func registerTimerTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Start Timer tool
+	type startTimerArgs struct {
+		ContractNumber string `json:"contract_number" jsonschema:"Contract number, or the client and contract name like 'Acme maintenance', to time work on"`
+		Name           string `json:"name,omitempty" jsonschema:"Name for the timer, needed to run two on one contract (default: the contract number)"`
+		Description    string `json:"description,omitempty" jsonschema:"Description of the work, used for the time entry (can be changed when stopping)"`
+		Service        string `json:"service,omitempty" jsonschema:"Service from the rate card the work is for (optional)"`
+	}
+
+	type startTimerResult struct {
+		Timer       *runningTimer  `json:"timer"`
+		AutoStopped []stoppedTimer `json:"auto_stopped,omitempty" jsonschema:"Timers stopped at the timer_max_hours limit before this one started"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "start_timer",
+		Description: "Start timing work on a contract; stop_timer logs the time as an entry. Several timers can run at once under different names. A timer running past the timer_max_hours setting (default 12) is stopped there automatically and its entry flagged for review",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args startTimerArgs) (*mcp.CallToolResult, *startTimerResult, error) {
+		result := &startTimerResult{}
+		var err error
+		if result.AutoStopped, err = h.stopOverdueTimers(ctx); err != nil {
+			return nil, nil, err
+		}
+		timers, err := h.loadTimers(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		start, err := h.checkTimerStart(ctx, args.ContractNumber, args.Name, args.Description, args.Service, timers, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		if result.Timer, err = h.startTimer(ctx, start); err != nil {
+			return nil, nil, err
+		}
+
+		text := autoStopText(result.AutoStopped) + h.startedText(ctx, result.Timer, start)
+		if len(timers) > 0 {
+			text += fmt.Sprintf("\nAlso running: %s", timerNames(timers))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+
+	// Stop Timer tool
+	type stopTimerArgs struct {
+		Name        string  `json:"name,omitempty" jsonschema:"Name of the timer to stop; needed when several are running"`
+		Description *string `json:"description,omitempty" jsonschema:"Description for the time entry, replacing the one given when starting (optional)"`
+		Discard     bool    `json:"discard,omitempty" jsonschema:"Stop the timer without logging its time (default: false)"`
+	}
+
+	type stopTimerResult struct {
+		Stopped *stoppedTimer `json:"stopped"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "stop_timer",
+		Description: "Stop a running timer and log its time as an entry on the day it started, in hours to two decimals. Time past the timer_max_hours setting is not logged; the entry is capped and flagged for review",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args stopTimerArgs) (*mcp.CallToolResult, *stopTimerResult, error) {
+		timers, err := h.loadTimers(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		t, err := findTimer(timers, args.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// A timer past the limit is stopped at it, as the watchdog would
+		// have done
+		description := t.Description
+		if args.Description != nil {
+			description = strings.TrimSpace(*args.Description)
+		}
+		stopped, err := h.stopTimer(ctx, t, time.Now(), description, args.Discard)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: stoppedText(t, stopped, args.Discard)},
+			},
+		}, &stopTimerResult{Stopped: stopped}, nil
+	})
+
+	// Switch Timer tool
+	type switchTimerArgs struct {
+		ContractNumber  string  `json:"contract_number" jsonschema:"Contract number, or the client and contract name, to switch to"`
+		Name            string  `json:"name,omitempty" jsonschema:"Name for the new timer (default: the contract number)"`
+		Description     string  `json:"description,omitempty" jsonschema:"Description of the work switched to"`
+		Service         string  `json:"service,omitempty" jsonschema:"Service from the rate card the work switched to is for (optional)"`
+		Stop            string  `json:"stop,omitempty" jsonschema:"Name of the timer to stop (default: the one started last)"`
+		StopDescription *string `json:"stop_description,omitempty" jsonschema:"Description for the stopped timer's entry, replacing the one given when it started (optional)"`
+	}
+
+	type switchTimerResult struct {
+		Stopped *stoppedTimer `json:"stopped"`
+		Timer   *runningTimer `json:"timer"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "switch_timer",
+		Description: "Stop the current timer, logging its time, and start one on another contract in a single call, for switching between tasks. Checks the new contract before stopping anything",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args switchTimerArgs) (*mcp.CallToolResult, *switchTimerResult, error) {
+		autoStopped, err := h.stopOverdueTimers(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		timers, err := h.loadTimers(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		var current *runningTimer
+		if args.Stop != "" || len(timers) <= 1 {
+			if current, err = findTimer(timers, args.Stop); err != nil {
+				return nil, nil, err
+			}
+		} else {
+			current = &timers[len(timers)-1]
+		}
+		start, err := h.checkTimerStart(ctx, args.ContractNumber, args.Name, args.Description, args.Service, timers, current.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		description := current.Description
+		if args.StopDescription != nil {
+			description = strings.TrimSpace(*args.StopDescription)
+		}
+		// The old timer stops and the new one starts together, so a
+		// failure leaves the old one running rather than neither
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		result := &switchTimerResult{}
+		if result.Stopped, err = h.stopTimerTx(ctx, tx, current, time.Now(), description, false); err != nil {
+			return nil, nil, err
+		}
+		id, err := insertTimer(ctx, tx, start)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		if result.Timer, err = h.loadTimer(ctx, id); err != nil {
+			return nil, nil, err
+		}
+
+		text := autoStopText(autoStopped) + stoppedText(current, result.Stopped, false) + "\n" + h.startedText(ctx, result.Timer, start)
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+
+	// Timer Status tool
+	type timerStatusArgs struct{}
+
+	type timerStatusResult struct {
+		Timers      []runningTimer `json:"timers"`
+		AutoStopped []stoppedTimer `json:"auto_stopped,omitempty" jsonschema:"Timers just stopped at the timer_max_hours limit"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "timer_status",
+		Description: "Show the running timers and how long each has run, first stopping any past the timer_max_hours limit",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args timerStatusArgs) (*mcp.CallToolResult, *timerStatusResult, error) {
+		result := &timerStatusResult{}
+		var err error
+		if result.AutoStopped, err = h.stopOverdueTimers(ctx); err != nil {
+			return nil, nil, err
+		}
+		if result.Timers, err = h.loadTimers(ctx); err != nil {
+			return nil, nil, err
+		}
+		if result.Timers == nil {
+			result.Timers = []runningTimer{}
+		}
+
+		text := autoStopText(result.AutoStopped)
+		if len(result.Timers) == 0 {
+			text += "No timer is running"
+		} else {
+			text += "Running timers:\n"
+			for _, t := range result.Timers {
+				text += fmt.Sprintf("- %s (%s) since %s: %.2f hours", timerLabel(t.Name, t.ContractNumber), t.ClientName,
+					t.StartedAt.In(h.location(ctx)).Format("2006-01-02 15:04"), t.ElapsedHours)
+				if t.Description != "" {
+					text += " - " + t.Description
+				}
+				text += "\n"
+			}
+			if limit := h.getIntSetting(ctx, "timer_max_hours"); limit > 0 {
+				text += fmt.Sprintf("Timers stop automatically at %d hours", limit)
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/timeparse"
+)
+
+// validateTimeZone accepts an IANA time zone name such as Europe/Berlin
+func validateTimeZone(value string) error {
+	if _, err := loadTimeZone(value); err != nil {
+		return validationError("unknown time zone '%s': use an IANA name like Europe/Berlin or America/New_York", value)
+	}
+	return nil
+}
+
+// loadTimeZone returns the named time zone; empty is the server's local
+// time zone
+func loadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// location returns the time zone of the business, from the time_zone
+// setting
+func (h *Handler) location(ctx context.Context) *time.Location {
+	name := h.getSetting(ctx, "time_zone")
+	loc, err := loadTimeZone(name)
+	if err != nil {
+		slog.Warn("ignoring unknown time zone", "time_zone", name, "error", err)
+		return time.Local
+	}
+	return loc
+}
+
+// clientLocation returns the client's time zone, falling back to the
+// business's when the client has none
+func (h *Handler) clientLocation(ctx context.Context, clientID int) *time.Location {
+	name, err := h.store.Clients.TimeZone(ctx, clientID)
+	if err != nil || name == "" {
+		return h.location(ctx)
+	}
+	loc, err := loadTimeZone(name)
+	if err != nil {
+		slog.Warn("ignoring unknown client time zone", "client_id", clientID, "time_zone", name, "error", err)
+		return h.location(ctx)
+	}
+	return loc
+}
+
+// now returns the current time in the business's time zone, so "today"
+// is the business's day rather than the server's
+func (h *Handler) now(ctx context.Context) time.Time {
+	return time.Now().In(h.location(ctx))
+}
+
+// clientNow returns the current time in the client's time zone
+func (h *Handler) clientNow(ctx context.Context, clientID int) time.Time {
+	return time.Now().In(h.clientLocation(ctx, clientID))
+}
+
+// dateOrder returns how numeric dates like 03/04/2025 are read, from the
+// date_order setting
+func (h *Handler) dateOrder(ctx context.Context) timeparse.DateOrder {
+	return timeparse.DateOrder(h.getSetting(ctx, "date_order"))
+}
+
+// calendarDay returns the day t falls on in its own time zone at midnight
+// UTC, the way dates are read from the database, so days taken in any time
+// zone compare equal to stored dates
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// today returns the current day in the business's time zone
+func (h *Handler) today(ctx context.Context) time.Time {
+	return calendarDay(h.now(ctx))
+}
+
+// clientToday returns the current day in the client's time zone
+func (h *Handler) clientToday(ctx context.Context, clientID int) time.Time {
+	return calendarDay(h.clientNow(ctx, clientID))
+}
+
+// parseDate parses a date, taking relative dates like "yesterday" in the
+// business's time zone
+func (h *Handler) parseDate(ctx context.Context, value string) (time.Time, error) {
+	return parseDateAt(value, h.now(ctx), h.dateOrder(ctx))
+}
+
+// parseClientDate parses a date, taking relative dates in the client's
+// time zone
+func (h *Handler) parseClientDate(ctx context.Context, clientID int, value string) (time.Time, error) {
+	return parseDateAt(value, h.clientNow(ctx, clientID), h.dateOrder(ctx))
+}
+
+func parseDateAt(value string, now time.Time, order timeparse.DateOrder) (time.Time, error) {
+	date, err := timeparse.ParseDateInOrder(value, now, order)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return calendarDay(date), nil
+}
+
+// parsePeriod parses a period, taking relative periods like "last month"
+// in the business's time zone and quarters and years from the
+// fiscal_year_start_month setting
+func (h *Handler) parsePeriod(ctx context.Context, value string) (time.Time, time.Time, error) {
+	fiscalStart := time.Month(h.getIntSetting(ctx, "fiscal_year_start_month"))
+	start, end, err := timeparse.ParseFiscalPeriodAt(value, h.now(ctx), fiscalStart, h.dateOrder(ctx))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return calendarDay(start), calendarDay(end), nil
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// registerWriteOffTools registers the tools writing off uncollectable
+// invoices as bad debt and reporting the write-offs for tax purposes
+func registerWriteOffTools(server *mcp.Server, db *sql.DB, h *Handler) {
+	// Write Off Invoice tool
+	type writeOffInvoiceArgs struct {
+		InvoiceNumber string `json:"invoice_number" jsonschema:"Invoice number to write off"`
+		Date          string `json:"date,omitempty" jsonschema:"Date of the write-off, which places it in a tax period (default: today)"`
+		Reason        string `json:"reason,omitempty" jsonschema:"Why it can't be collected, e.g. 'client insolvent' (optional)"`
+		Confirm       bool   `json:"confirm,omitempty" jsonschema:"Write the invoice off (default: false, only shows what would be written off)"`
+	}
+
+	type writeOffInvoiceResult struct {
+		Confirmed bool            `json:"confirmed" jsonschema:"Whether the invoice was written off; false for a preview"`
+		Invoice   *models.Invoice `json:"invoice"`
+		Amount    money.Cents     `json:"amount" jsonschema:"Balance written off: the total less any tax the client withheld"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "write_off_invoice",
+		Description: "Write off an unpaid invoice as uncollectable bad debt. Unlike cancelling, it still counts as invoiced, but leaves unpaid-invoice lists and reminders and appears in write_off_report. Only shows what would be written off until run with confirm=true",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args writeOffInvoiceArgs) (*mcp.CallToolResult, *writeOffInvoiceResult, error) {
+		inv, err := h.store.Invoices.ByNumber(ctx, args.InvoiceNumber)
+		if err == sql.ErrNoRows {
+			return nil, nil, notFoundError("invoice", "invoice %s not found", args.InvoiceNumber)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to find invoice: %w", err)
+		}
+		switch inv.Status {
+		case "paid", "cancelled":
+			return nil, nil, conflictError("invoice %s is %s, so there is nothing to write off", inv.InvoiceNumber, inv.Status)
+		case "written_off":
+			return nil, nil, conflictError("invoice %s was already written off on %s", inv.InvoiceNumber, inv.WrittenOffDate.Format("2006-01-02"))
+		case "draft":
+			return nil, nil, conflictError("invoice %s is a draft that was never sent; cancel it with update_invoice_status instead", inv.InvoiceNumber)
+		}
+
+		date := h.today(ctx)
+		if args.Date != "" {
+			if date, err = h.parseDate(ctx, args.Date); err != nil {
+				return nil, nil, validationError("invalid date: %w", err)
+			}
+		}
+		if date.Before(truncateDay(inv.IssueDate)) {
+			return nil, nil, validationError("invoice %s was issued on %s; it can't be written off before then", inv.InvoiceNumber, inv.IssueDate.Format("2006-01-02"))
+		}
+		reason := strings.TrimSpace(args.Reason)
+		amount := inv.TotalAmount - inv.WithholdingAmount
+
+		if !args.Confirm {
+			text := fmt.Sprintf("Would write off invoice %s for %s on %s: %s (issued %s, due %s, status %s).\n",
+				inv.InvoiceNumber, inv.Client.Name, date.Format("2006-01-02"), amount.Format(inv.Currency),
+				inv.IssueDate.Format("2006-01-02"), inv.DueDate.Format("2006-01-02"), inv.Status)
+			text += "It would still count as invoiced, but drop out of unpaid invoices and reminders and appear in write_off_report. " +
+				"Setting another status with update_invoice_status undoes it, e.g. if the client pays after all.\n"
+			return previewResult(text, &writeOffInvoiceResult{Invoice: inv, Amount: amount})
+		}
+
+		if err := h.store.Invoices.WriteOff(ctx, inv.ID, date, reason); err != nil {
+			return nil, nil, fmt.Errorf("failed to write off invoice: %w", err)
+		}
+		inv, err = h.store.Invoices.ByNumber(ctx, inv.InvoiceNumber)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load invoice: %w", err)
+		}
+
+		text := fmt.Sprintf("Wrote off invoice %s for %s on %s: %s", inv.InvoiceNumber, inv.Client.Name, date.Format("2006-01-02"), amount.Format(inv.Currency))
+		if reason != "" {
+			text += " (" + reason + ")"
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, &writeOffInvoiceResult{Confirmed: true, Invoice: inv, Amount: amount}, nil
+	})
+
+	// Write-Off Report tool
+	type writeOffReportArgs struct {
+		Period string `json:"period,omitempty" jsonschema:"Period the invoices were written off in (e.g. 'this year' 'last year' 'Q1 2025'; default: this year)"`
+		Format string `json:"format,omitempty" jsonschema:"Output format: text (default) or markdown"`
+	}
+
+	type writtenOffInvoice struct {
+		InvoiceNumber  string      `json:"invoice_number"`
+		ClientName     string      `json:"client_name"`
+		IssueDate      string      `json:"issue_date"`
+		WrittenOffDate string      `json:"written_off_date"`
+		Currency       string      `json:"currency"`
+		Net            money.Cents `json:"net"`
+		Tax            money.Cents `json:"tax"`
+		Amount         money.Cents `json:"amount" jsonschema:"Balance written off: the total less any tax the client withheld"`
+		Reason         string      `json:"reason,omitempty"`
+	}
+
+	type writeOffReportResult struct {
+		PeriodStart string                 `json:"period_start"`
+		PeriodEnd   string                 `json:"period_end"`
+		Invoices    []writtenOffInvoice    `json:"invoices"`
+		Net         map[string]money.Cents `json:"net" jsonschema:"Net amount written off per currency"`
+		Tax         map[string]money.Cents `json:"tax" jsonschema:"Tax charged on the written-off invoices per currency"`
+		Amount      map[string]money.Cents `json:"amount" jsonschema:"Balance written off per currency"`
+	}
+
+	addTool(server, &mcp.Tool{
+		Name:        "write_off_report",
+		Description: "List the invoices written off as bad debt in a period, by write-off date, with the net, tax and balance written off per currency, e.g. for a bad debt deduction or VAT relief",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args writeOffReportArgs) (*mcp.CallToolResult, *writeOffReportResult, error) {
+		markdown, err := parseOutputFormat(args.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+		if args.Period == "" {
+			args.Period = "this year"
+		}
+		start, end, err := h.parsePeriod(ctx, args.Period)
+		if err != nil {
+			return nil, nil, validationError("invalid period: %w", err)
+		}
+
+		rows, err := db.QueryContext(ctx, `
+			SELECT i.invoice_number, c.name, i.issue_date, i.written_off_date, i.currency,
+			       i.total_cents, i.tax_cents, i.withholding_cents, i.write_off_reason
+			FROM invoices i
+			JOIN clients c ON c.id = i.client_id
+			WHERE i.status = 'written_off' AND i.written_off_date >= ? AND i.written_off_date <= ?
+			ORDER BY i.written_off_date, i.invoice_number
+		`, start.Format("2006-01-02"), end.Format("2006-01-02"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list written-off invoices: %w", err)
+		}
+		defer rows.Close()
+
+		result := &writeOffReportResult{
+			PeriodStart: start.Format("2006-01-02"),
+			PeriodEnd:   end.Format("2006-01-02"),
+			Invoices:    []writtenOffInvoice{},
+			Net:         map[string]money.Cents{},
+			Tax:         map[string]money.Cents{},
+			Amount:      map[string]money.Cents{},
+		}
+		for rows.Next() {
+			var w writtenOffInvoice
+			var issued, writtenOff time.Time
+			var total, withheld money.Cents
+			if err := rows.Scan(&w.InvoiceNumber, &w.ClientName, &issued, &writtenOff, &w.Currency,
+				&total, &w.Tax, &withheld, &w.Reason); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan invoice: %w", err)
+			}
+			w.IssueDate = issued.Format("2006-01-02")
+			w.WrittenOffDate = writtenOff.Format("2006-01-02")
+			w.Net = total - w.Tax
+			w.Amount = total - withheld
+			result.Invoices = append(result.Invoices, w)
+			result.Net[w.Currency] += w.Net
+			result.Tax[w.Currency] += w.Tax
+			result.Amount[w.Currency] += w.Amount
+		}
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		periodLabel := fmt.Sprintf("%s to %s", result.PeriodStart, result.PeriodEnd)
+		var text string
+		switch {
+		case len(result.Invoices) == 0:
+			text = fmt.Sprintf("No invoices were written off from %s.\n", periodLabel)
+		case markdown:
+			tableRows := make([][]string, 0, len(result.Invoices)+1)
+			for _, w := range result.Invoices {
+				tableRows = append(tableRows, []string{w.InvoiceNumber, w.ClientName, w.IssueDate, w.WrittenOffDate,
+					w.Net.Format(w.Currency), w.Tax.Format(w.Currency), w.Amount.Format(w.Currency), w.Reason})
+			}
+			tableRows = append(tableRows, []string{"**Total**", "", "", "", "**" + formatCurrencyTotals(result.Net) + "**",
+				"**" + formatCurrencyTotals(result.Tax) + "**", "**" + formatCurrencyTotals(result.Amount) + "**", ""})
+			text = fmt.Sprintf("### Write-offs for %s\n\n_By write-off date_\n\n", periodLabel) +
+				markdownTable([]string{"Invoice", "Client", "Issued", "Written off", "Net", "Tax", "Balance", "Reason"}, tableRows, 4, 5, 6)
+		default:
+			text = fmt.Sprintf("Write-offs for %s (by write-off date):\n", periodLabel)
+			for _, w := range result.Invoices {
+				text += fmt.Sprintf("- %s for %s, issued %s, written off %s: net %s, tax %s, balance %s",
+					w.InvoiceNumber, w.ClientName, w.IssueDate, w.WrittenOffDate,
+					w.Net.Format(w.Currency), w.Tax.Format(w.Currency), w.Amount.Format(w.Currency))
+				if w.Reason != "" {
+					text += " (" + w.Reason + ")"
+				}
+				text += "\n"
+			}
+			text += fmt.Sprintf("Total: net %s; tax %s; balance %s\n",
+				formatCurrencyTotals(result.Net), formatCurrencyTotals(result.Tax), formatCurrencyTotals(result.Amount))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+}
@@ -0,0 +1,204 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/austin/hours-mcp/internal/models"
+)
+
+// ClientStore reads and writes clients
+type ClientStore struct {
+	q Querier
+}
+
+// ClientChanges are the fields an edit sets; nil fields are left as they are
+type ClientChanges struct {
+	Name            *string
+	Address         *string
+	City            *string
+	State           *string
+	ZipCode         *string
+	Country         *string
+	TaxID           *string
+	TaxTreatment    *string
+	WithholdingRate *float64
+	Notes           *string
+	DefaultCurrency *string
+	Locale          *string
+	TimeZone        *string
+	// CustomFields is the complete JSON object to store
+	CustomFields *string
+	// PaymentMethodID is the payment method invoices ask to be paid into,
+	// 0 for none
+	PaymentMethodID *int
+	DeliveryMethod  *string
+	DeliveryNote    *string
+	InvoiceFormat   *string
+	InvoiceDetail   *string
+	BillingCycle    *string
+}
+
+// Create adds a client with its custom fields given as a JSON object and
+// returns its ID
+func (s *ClientStore) Create(ctx context.Context, c *models.Client, customFields string) (int, error) {
+	result, err := s.q.ExecContext(ctx, `
+		INSERT INTO clients (name, address, city, state, zip_code, country, tax_id, tax_treatment, withholding_rate,
+		                     notes, default_currency, locale, time_zone, custom_fields,
+		                     delivery_method, delivery_note, invoice_format, invoice_detail, billing_cycle)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, c.Name, c.Address, c.City, c.State, c.ZipCode, c.Country,
+		c.TaxID, c.TaxTreatment, c.WithholdingRate, c.Notes, c.DefaultCurrency, c.Locale, c.TimeZone, customFields,
+		c.DeliveryMethod, c.DeliveryNote, c.InvoiceFormat, c.InvoiceDetail, c.BillingCycle)
+	if err != nil {
+		return 0, err
+	}
+	id, _ := result.LastInsertId()
+	return int(id), nil
+}
+
+// IDByName returns the ID of the client with exactly this name, or
+// sql.ErrNoRows
+func (s *ClientStore) IDByName(ctx context.Context, name string) (int, error) {
+	var id int
+	err := s.q.QueryRowContext(ctx, "SELECT id FROM clients WHERE name = ?", name).Scan(&id)
+	return id, err
+}
+
+// Get returns a client's name, address and tax details
+func (s *ClientStore) Get(ctx context.Context, id int) (*models.Client, error) {
+	c := &models.Client{}
+	err := s.q.QueryRowContext(ctx, `
+		SELECT id, name, address, city, state, zip_code, country, COALESCE(tax_id, ''),
+		       COALESCE(tax_treatment, 'standard'), COALESCE(withholding_rate, 0)
+		FROM clients WHERE id = ?
+	`, id).Scan(&c.ID, &c.Name, &c.Address, &c.City, &c.State, &c.ZipCode, &c.Country,
+		&c.TaxID, &c.TaxTreatment, &c.WithholdingRate)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// List returns clients by name, leaving out archived ones unless asked
+func (s *ClientStore) List(ctx context.Context, includeArchived bool) ([]models.Client, error) {
+	query := `
+		SELECT id, name, address, city, state, zip_code, country, COALESCE(tax_id, ''),
+		       COALESCE(tax_treatment, 'standard'), COALESCE(withholding_rate, 0), archived_at, created_at, updated_at
+		FROM clients
+	`
+	if !includeArchived {
+		query += " WHERE archived_at IS NULL"
+	}
+	rows, err := s.q.QueryContext(ctx, query+" ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clients []models.Client
+	for rows.Next() {
+		var c models.Client
+		if err := rows.Scan(&c.ID, &c.Name, &c.Address, &c.City, &c.State, &c.ZipCode, &c.Country,
+			&c.TaxID, &c.TaxTreatment, &c.WithholdingRate, &c.ArchivedAt, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan client: %w", err)
+		}
+		clients = append(clients, c)
+	}
+	return clients, rows.Err()
+}
+
+// Aliases returns every client's aliases, alphabetically, by client ID
+func (s *ClientStore) Aliases(ctx context.Context) (map[int][]string, error) {
+	rows, err := s.q.QueryContext(ctx, "SELECT client_id, alias FROM client_aliases ORDER BY alias")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := map[int][]string{}
+	for rows.Next() {
+		var clientID int
+		var alias string
+		if err := rows.Scan(&clientID, &alias); err != nil {
+			return nil, fmt.Errorf("failed to scan client alias: %w", err)
+		}
+		aliases[clientID] = append(aliases[clientID], alias)
+	}
+	return aliases, rows.Err()
+}
+
+// DefaultCurrency returns the currency new contracts for the client use, ""
+// when none is set
+func (s *ClientStore) DefaultCurrency(ctx context.Context, id int) (string, error) {
+	var currency string
+	err := s.q.QueryRowContext(ctx, "SELECT default_currency FROM clients WHERE id = ?", id).Scan(&currency)
+	return currency, err
+}
+
+// TimeZone returns the time zone set for the client, "" when none is set
+func (s *ClientStore) TimeZone(ctx context.Context, id int) (string, error) {
+	var zone string
+	err := s.q.QueryRowContext(ctx, "SELECT time_zone FROM clients WHERE id = ?", id).Scan(&zone)
+	return zone, err
+}
+
+// CustomFields returns a client's custom fields as stored, a JSON object
+func (s *ClientStore) CustomFields(ctx context.Context, id int) (string, error) {
+	var fields string
+	err := s.q.QueryRowContext(ctx, "SELECT custom_fields FROM clients WHERE id = ?", id).Scan(&fields)
+	return fields, err
+}
+
+// Update applies changes to a client, returning ErrNoChanges when there are
+// none and sql.ErrNoRows when the client doesn't exist
+func (s *ClientStore) Update(ctx context.Context, id int, changes ClientChanges) error {
+	var u update
+	for _, field := range []struct {
+		column string
+		value  *string
+	}{
+		{"name", changes.Name},
+		{"address", changes.Address},
+		{"city", changes.City},
+		{"state", changes.State},
+		{"zip_code", changes.ZipCode},
+		{"country", changes.Country},
+		{"tax_id", changes.TaxID},
+		{"tax_treatment", changes.TaxTreatment},
+		{"notes", changes.Notes},
+		{"default_currency", changes.DefaultCurrency},
+		{"locale", changes.Locale},
+		{"time_zone", changes.TimeZone},
+		{"custom_fields", changes.CustomFields},
+		{"delivery_method", changes.DeliveryMethod},
+		{"delivery_note", changes.DeliveryNote},
+		{"invoice_format", changes.InvoiceFormat},
+		{"invoice_detail", changes.InvoiceDetail},
+		{"billing_cycle", changes.BillingCycle},
+	} {
+		if field.value != nil {
+			u.set(field.column, *field.value)
+		}
+	}
+	if changes.WithholdingRate != nil {
+		u.set("withholding_rate", *changes.WithholdingRate)
+	}
+	if changes.PaymentMethodID != nil {
+		var id interface{}
+		if *changes.PaymentMethodID != 0 {
+			id = *changes.PaymentMethodID
+		}
+		u.set("payment_method_id", id)
+	}
+
+	found, err := u.exec(ctx, s.q, "clients", id, "updated_at = CURRENT_TIMESTAMP")
+	if err != nil {
+		return err
+	}
+	if !found {
+		return sql.ErrNoRows
+	}
+	return nil
+}
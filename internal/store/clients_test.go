@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/austin/hours-mcp/internal/models"
+)
+
+func TestClientCreateAndGet(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	id, err := f.store.Clients.Create(ctx, &models.Client{Name: "Globex", City: "Springfield", TaxID: "DE123", DefaultCurrency: "EUR"}, `{"po": "42"}`)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if got, err := f.store.Clients.IDByName(ctx, "Globex"); err != nil || got != id {
+		t.Errorf("IDByName = %d, %v; want %d", got, err, id)
+	}
+
+	c, err := f.store.Clients.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if c.Name != "Globex" || c.City != "Springfield" || c.TaxID != "DE123" {
+		t.Errorf("got %+v", c)
+	}
+	if currency, err := f.store.Clients.DefaultCurrency(ctx, id); err != nil || currency != "EUR" {
+		t.Errorf("DefaultCurrency = %q, %v; want EUR", currency, err)
+	}
+	if fields, err := f.store.Clients.CustomFields(ctx, id); err != nil || fields != `{"po": "42"}` {
+		t.Errorf("CustomFields = %q, %v", fields, err)
+	}
+}
+
+func TestClientCreateDuplicateName(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	if _, err := f.store.Clients.Create(ctx, &models.Client{Name: "Acme"}, "{}"); err == nil {
+		t.Fatal("created a second client named Acme")
+	}
+}
+
+func TestClientIDByNameMissing(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	if _, err := f.store.Clients.IDByName(ctx, "Nobody"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("got %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestClientListLeavesOutArchived(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	archived, err := f.store.Clients.Create(ctx, &models.Client{Name: "Initech"}, "{}")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := f.db.Exec("UPDATE clients SET archived_at = CURRENT_TIMESTAMP WHERE id = ?", archived); err != nil {
+		t.Fatal(err)
+	}
+
+	clients, err := f.store.Clients.List(ctx, false)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(clients) != 1 || clients[0].Name != "Acme" {
+		t.Errorf("got %+v, want only Acme", clients)
+	}
+
+	clients, err = f.store.Clients.List(ctx, true)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(clients) != 2 || clients[0].Name != "Acme" || clients[1].Name != "Initech" {
+		t.Errorf("got %+v, want Acme and Initech by name", clients)
+	}
+}
+
+func TestClientUpdate(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	city, rate := "Berlin", 15.0
+
+	if err := f.store.Clients.Update(ctx, f.clientID, ClientChanges{City: &city, WithholdingRate: &rate}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	c, err := f.store.Clients.Get(ctx, f.clientID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if c.City != "Berlin" || c.WithholdingRate != 15 || c.Name != "Acme" {
+		t.Errorf("got %+v, want Acme in Berlin withholding 15%%", c)
+	}
+}
+
+func TestClientUpdateErrors(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	city := "Berlin"
+
+	if err := f.store.Clients.Update(ctx, f.clientID, ClientChanges{}); !errors.Is(err, ErrNoChanges) {
+		t.Errorf("no changes: got %v, want ErrNoChanges", err)
+	}
+	if err := f.store.Clients.Update(ctx, 9999, ClientChanges{City: &city}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("missing client: got %v, want sql.ErrNoRows", err)
+	}
+}
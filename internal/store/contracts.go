@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/austin/hours-mcp/internal/models"
+)
+
+// ContractStore reads and writes contracts
+type ContractStore struct {
+	q Querier
+}
+
+// ContractFilter narrows the contracts List returns; zero fields match all
+type ContractFilter struct {
+	// ClientName matches part of the client's name
+	ClientName string
+	Status     string
+	// FTSQuery is a full-text query on names and notes. When it is empty,
+	// Search is matched as a substring instead.
+	FTSQuery string
+	Search   string
+
+	IncludeArchived bool
+}
+
+// Create adds a contract and returns its ID
+func (s *ContractStore) Create(ctx context.Context, c *models.Contract) (int, error) {
+	var endDate any
+	if c.EndDate != nil {
+		endDate = c.EndDate.Format("2006-01-02")
+	}
+	var id int
+	err := s.q.QueryRowContext(ctx, `
+		INSERT INTO contracts (client_id, contract_number, name, hourly_rate_cents, currency, contract_type, start_date, end_date, payment_terms, notes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, c.ClientID, c.ContractNumber, c.Name, c.HourlyRate, c.Currency, c.ContractType, c.StartDate.Format("2006-01-02"),
+		endDate, c.PaymentTerms, c.Notes).Scan(&id)
+	return id, err
+}
+
+// ByNumber returns the contract with a contract number, with its currency,
+// dates and status, along with its client's name, or sql.ErrNoRows
+func (s *ContractStore) ByNumber(ctx context.Context, number string) (*models.Contract, error) {
+	c := &models.Contract{ContractNumber: number, Client: &models.Client{}}
+	var endDate sql.NullTime
+	err := s.q.QueryRowContext(ctx, `
+		SELECT c.id, c.client_id, cl.name, c.name, c.currency, c.start_date, c.end_date, c.status
+		FROM contracts c
+		JOIN clients cl ON c.client_id = cl.id
+		WHERE c.contract_number = ?
+	`, number).Scan(&c.ID, &c.ClientID, &c.Client.Name, &c.Name, &c.Currency, &c.StartDate, &endDate, &c.Status)
+	if err != nil {
+		return nil, err
+	}
+	if endDate.Valid {
+		c.EndDate = &endDate.Time
+	}
+	c.Client.ID = c.ClientID
+	return c, nil
+}
+
+// IDByNumber returns the ID of the contract with a contract number, or
+// sql.ErrNoRows
+func (s *ContractStore) IDByNumber(ctx context.Context, number string) (int, error) {
+	var id int
+	err := s.q.QueryRowContext(ctx, "SELECT id FROM contracts WHERE contract_number = ?", number).Scan(&id)
+	return id, err
+}
+
+// ActiveCounts returns how many active contracts each client has, by client
+// ID; clients without any are left out
+func (s *ContractStore) ActiveCounts(ctx context.Context) (map[int]int, error) {
+	rows, err := s.q.QueryContext(ctx, "SELECT client_id, COUNT(*) FROM contracts WHERE status = 'active' GROUP BY client_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[int]int{}
+	for rows.Next() {
+		var clientID, count int
+		if err := rows.Scan(&clientID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan contract count: %w", err)
+		}
+		counts[clientID] = count
+	}
+	return counts, rows.Err()
+}
+
+// Currencies returns the currency of each of a client's contracts by
+// contract ID
+func (s *ContractStore) Currencies(ctx context.Context, clientID int) (map[int]string, error) {
+	rows, err := s.q.QueryContext(ctx, "SELECT id, currency FROM contracts WHERE client_id = ?", clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	currencies := map[int]string{}
+	for rows.Next() {
+		var id int
+		var currency string
+		if err := rows.Scan(&id, &currency); err != nil {
+			return nil, fmt.Errorf("failed to scan contract: %w", err)
+		}
+		currencies[id] = currency
+	}
+	return currencies, rows.Err()
+}
+
+// List returns the contracts matching filter, newest first, each with its
+// client's name
+func (s *ContractStore) List(ctx context.Context, filter ContractFilter) ([]models.Contract, error) {
+	query := `
+		SELECT c.id, c.client_id, c.contract_number, c.name, c.hourly_rate_cents, c.currency, c.contract_type,
+		       c.start_date, c.end_date, c.status, c.payment_terms, c.retainer_hours, c.retainer_fee_cents,
+		       c.overage_rate_cents, c.rollover_months, c.budget_hours, c.budget_amount_cents, c.budget_alert_percent,
+		       c.budget_hard_limit, cl.name as client_name
+		FROM contracts c
+		JOIN clients cl ON c.client_id = cl.id
+		WHERE 1=1
+	`
+	args := []any{}
+
+	if filter.ClientName != "" {
+		query += " AND cl.name LIKE ?"
+		args = append(args, "%"+filter.ClientName+"%")
+	}
+	if filter.Status != "" {
+		query += " AND c.status = ?"
+		args = append(args, filter.Status)
+	}
+	if !filter.IncludeArchived {
+		query += " AND cl.archived_at IS NULL"
+	}
+	if filter.FTSQuery != "" {
+		query += " AND c.id IN (SELECT contract_id FROM contracts_fts WHERE contracts_fts MATCH ?)"
+		args = append(args, filter.FTSQuery)
+	} else if filter.Search != "" {
+		term := "%" + strings.Trim(filter.Search, "\"*") + "%"
+		query += " AND (c.name LIKE ? OR c.notes LIKE ?)"
+		args = append(args, term, term)
+	}
+
+	query += " ORDER BY c.start_date DESC, c.contract_number"
+
+	rows, err := s.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contracts []models.Contract
+	for rows.Next() {
+		var c models.Contract
+		var clientName string
+		var endDate sql.NullTime
+		if err := rows.Scan(&c.ID, &c.ClientID, &c.ContractNumber, &c.Name, &c.HourlyRate, &c.Currency, &c.ContractType,
+			&c.StartDate, &endDate, &c.Status, &c.PaymentTerms, &c.RetainerHours, &c.RetainerFee,
+			&c.OverageRate, &c.RolloverMonths, &c.BudgetHours, &c.BudgetAmount, &c.BudgetAlert,
+			&c.BudgetLimit, &clientName); err != nil {
+			return nil, fmt.Errorf("failed to scan contract: %w", err)
+		}
+		if endDate.Valid {
+			c.EndDate = &endDate.Time
+		}
+		c.Client = &models.Client{ID: c.ClientID, Name: clientName}
+		contracts = append(contracts, c)
+	}
+	return contracts, rows.Err()
+}
@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/austin/hours-mcp/internal/models"
+)
+
+func TestContractByNumber(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	c, err := f.store.Contracts.ByNumber(ctx, "AC-001")
+	if err != nil {
+		t.Fatalf("ByNumber: %v", err)
+	}
+	if c.ID != f.contractID || c.ClientID != f.clientID || c.Client.Name != "Acme" || c.Status != "active" {
+		t.Errorf("got %+v", c)
+	}
+	if id, err := f.store.Contracts.IDByNumber(ctx, "AC-001"); err != nil || id != f.contractID {
+		t.Errorf("IDByNumber = %d, %v; want %d", id, err, f.contractID)
+	}
+	if _, err := f.store.Contracts.ByNumber(ctx, "NOPE"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("missing contract: got %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestContractActiveCountsAndCurrencies(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	end := date(t, "2026-06-30")
+	euroID, err := f.store.Contracts.Create(ctx, &models.Contract{
+		ClientID:       f.clientID,
+		ContractNumber: "AC-002",
+		Name:           "Migration",
+		HourlyRate:     9000,
+		Currency:       "EUR",
+		ContractType:   "hourly",
+		StartDate:      date(t, "2026-02-01"),
+		EndDate:        &end,
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := f.db.Exec("UPDATE contracts SET status = 'completed' WHERE id = ?", euroID); err != nil {
+		t.Fatal(err)
+	}
+
+	counts, err := f.store.Contracts.ActiveCounts(ctx)
+	if err != nil {
+		t.Fatalf("ActiveCounts: %v", err)
+	}
+	if len(counts) != 1 || counts[f.clientID] != 1 {
+		t.Errorf("got %v, want one active contract for Acme", counts)
+	}
+	currencies, err := f.store.Contracts.Currencies(ctx, f.clientID)
+	if err != nil {
+		t.Fatalf("Currencies: %v", err)
+	}
+	if len(currencies) != 2 || currencies[f.contractID] != "USD" || currencies[euroID] != "EUR" {
+		t.Errorf("got %v", currencies)
+	}
+}
+
+func TestContractList(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	end := date(t, "2026-06-30")
+	if _, err := f.store.Contracts.Create(ctx, &models.Contract{
+		ClientID:       f.clientID,
+		ContractNumber: "AC-002",
+		Name:           "Data migration",
+		HourlyRate:     9000,
+		Currency:       "EUR",
+		ContractType:   "hourly",
+		StartDate:      date(t, "2026-02-01"),
+		EndDate:        &end,
+		Notes:          "moving the warehouse",
+	}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	contracts, err := f.store.Contracts.List(ctx, ContractFilter{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	// Newest first
+	if len(contracts) != 2 || contracts[0].ContractNumber != "AC-002" || contracts[1].ContractNumber != "AC-001" {
+		t.Fatalf("got %+v, want AC-002 then AC-001", contracts)
+	}
+	if c := contracts[0]; c.EndDate == nil || !c.EndDate.Equal(end) || c.Client.Name != "Acme" {
+		t.Errorf("got %+v, want the end date and client", c)
+	}
+	if contracts[1].EndDate != nil {
+		t.Errorf("AC-001 has end date %v, want none", contracts[1].EndDate)
+	}
+
+	contracts, err = f.store.Contracts.List(ctx, ContractFilter{Search: "warehouse"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(contracts) != 1 || contracts[0].ContractNumber != "AC-002" {
+		t.Errorf("search: got %+v, want AC-002", contracts)
+	}
+
+	contracts, err = f.store.Contracts.List(ctx, ContractFilter{ClientName: "Glob"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(contracts) != 0 {
+		t.Errorf("other client: got %+v, want none", contracts)
+	}
+}
@@ -0,0 +1,417 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
+	"github.com/google/uuid"
+)
+
+// EntryStore reads and writes time entries
+type EntryStore struct {
+	q Querier
+}
+
+// EntryListing is a time entry with the contract and client it was logged
+// against, as listed and searched
+type EntryListing struct {
+	models.TimeEntry
+	ClientName     string      `json:"client_name"`
+	ContractNumber string      `json:"contract_number"`
+	ContractName   string      `json:"contract_name"`
+	HourlyRate     money.Cents `json:"hourly_rate"`
+	Currency       string      `json:"currency"`
+}
+
+// EntrySummary identifies a time entry in tool output
+type EntrySummary struct {
+	ID          string    `json:"id"`
+	ClientName  string    `json:"client_name"`
+	Date        time.Time `json:"date"`
+	Hours       float64   `json:"hours"`
+	Description string    `json:"description,omitempty"`
+	InvoiceID   *int      `json:"invoice_id,omitempty"`
+	// InvoiceNumber is set when the entry is invoiced
+	InvoiceNumber *string   `json:"invoice_number,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// EntryFilter narrows the entries List returns; zero fields match all
+type EntryFilter struct {
+	// IDs limits the list to these entries
+	IDs        []string
+	ClientID   int
+	ContractID int
+	StartDate  *time.Time
+	EndDate    *time.Time
+	// FTSQuery is a full-text query on descriptions, and ranks the best
+	// matches first. When it is empty, Description is matched as a
+	// substring instead.
+	FTSQuery    string
+	Description string
+	// ContractRef matches part of the contract number
+	ContractRef string
+	MinHours    *float64
+	MaxHours    *float64
+	Invoiced    *bool
+}
+
+// EntryChanges are the fields an edit sets; nil fields are left as they are
+type EntryChanges struct {
+	Hours       *float64
+	Date        *time.Time
+	Description *string
+	Billable    *bool
+	NoCharge    *bool
+	// ServiceID sets the entry's service; 0 clears it
+	ServiceID *int
+	// Contract moves the entry to another contract, and its client
+	Contract *models.Contract
+	// NeedsReview flags the entry's hours for review, or clears the flag
+	NeedsReview *bool
+}
+
+// Create logs hours against a contract, for a service when serviceID is
+// not nil, and returns the new entry's ID
+func (s *EntryStore) Create(ctx context.Context, clientID, contractID int, contractNumber string, date time.Time, hours float64, description string, serviceID *int, noCharge bool) (string, error) {
+	id := uuid.New().String()
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO time_entries (id, client_id, contract_id, date, hours, description, contract_ref, service_id, no_charge)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, clientID, contractID, date.Format("2006-01-02"), hours, description, contractNumber, serviceID, noCharge)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// CreateAdjustment logs hours, possibly negative, correcting the entry id
+// on its date, contract and service, and returns the new entry's ID or
+// sql.ErrNoRows when the entry doesn't exist
+func (s *EntryStore) CreateAdjustment(ctx context.Context, id string, hours float64, description string) (string, error) {
+	adjustmentID := uuid.New().String()
+	result, err := s.q.ExecContext(ctx, `
+		INSERT INTO time_entries (id, client_id, contract_id, date, hours, description, contract_ref, service_id, billable, no_charge, adjusts_entry_id)
+		SELECT ?, client_id, contract_id, date, ?, ?, contract_ref, service_id, billable, no_charge, id
+		FROM time_entries WHERE id = ?
+	`, adjustmentID, hours, description, id)
+	if err != nil {
+		return "", err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return "", sql.ErrNoRows
+	}
+	return adjustmentID, nil
+}
+
+// Adjustments returns the entries adjusting the entry id, oldest first
+func (s *EntryStore) Adjustments(ctx context.Context, id string) ([]models.TimeEntry, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT id, contract_id, date, hours, description, invoice_id, billable, no_charge, adjusts_entry_id, created_at
+		FROM time_entries WHERE adjusts_entry_id = ?
+		ORDER BY created_at, id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.TimeEntry
+	for rows.Next() {
+		var e models.TimeEntry
+		if err := rows.Scan(&e.ID, &e.ContractID, &e.Date, &e.Hours, &e.Description, &e.InvoiceID, &e.Billable, &e.NoCharge, &e.AdjustsEntryID, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Get returns a time entry with its client's name, or sql.ErrNoRows
+func (s *EntryStore) Get(ctx context.Context, id string) (*models.TimeEntry, string, error) {
+	var e models.TimeEntry
+	var clientName string
+	err := s.q.QueryRowContext(ctx, `
+		SELECT te.id, te.contract_id, te.date, te.hours, te.description, te.invoice_id, te.billable, te.no_charge, COALESCE(s.name, ''),
+		       COALESCE(te.adjusts_entry_id, ''), te.needs_review, te.created_at, cl.name
+		FROM time_entries te
+		JOIN contracts ct ON te.contract_id = ct.id
+		JOIN clients cl ON ct.client_id = cl.id
+		LEFT JOIN services s ON te.service_id = s.id
+		WHERE te.id = ?
+	`, id).Scan(&e.ID, &e.ContractID, &e.Date, &e.Hours, &e.Description, &e.InvoiceID, &e.Billable, &e.NoCharge, &e.Service,
+		&e.AdjustsEntryID, &e.NeedsReview, &e.CreatedAt, &clientName)
+	if err != nil {
+		return nil, "", err
+	}
+	return &e, clientName, nil
+}
+
+// Summary returns what identifies a time entry to the user, or
+// sql.ErrNoRows
+func (s *EntryStore) Summary(ctx context.Context, id string) (*EntrySummary, error) {
+	e := &EntrySummary{ID: id}
+	err := s.q.QueryRowContext(ctx, `
+		SELECT c.name, te.date, te.hours, te.description, te.invoice_id, i.invoice_number, te.created_at
+		FROM time_entries te
+		JOIN clients c ON te.client_id = c.id
+		LEFT JOIN invoices i ON te.invoice_id = i.id
+		WHERE te.id = ?
+	`, id).Scan(&e.ClientName, &e.Date, &e.Hours, &e.Description, &e.InvoiceID, &e.InvoiceNumber, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Update applies changes to a time entry, returning ErrNoChanges when there
+// are none and sql.ErrNoRows when the entry doesn't exist
+func (s *EntryStore) Update(ctx context.Context, id string, changes EntryChanges) error {
+	var u update
+	if changes.Hours != nil {
+		u.set("hours", *changes.Hours)
+	}
+	if changes.Date != nil {
+		u.set("date", changes.Date.Format("2006-01-02"))
+	}
+	if changes.Description != nil {
+		u.set("description", *changes.Description)
+	}
+	if changes.Billable != nil {
+		u.set("billable", *changes.Billable)
+	}
+	if changes.NoCharge != nil {
+		u.set("no_charge", *changes.NoCharge)
+	}
+	if changes.NeedsReview != nil {
+		u.set("needs_review", *changes.NeedsReview)
+	}
+	if changes.ServiceID != nil {
+		var serviceID any
+		if *changes.ServiceID != 0 {
+			serviceID = *changes.ServiceID
+		}
+		u.set("service_id", serviceID)
+	}
+	if c := changes.Contract; c != nil {
+		u.set("contract_id", c.ID)
+		u.set("client_id", c.ClientID)
+		u.set("contract_ref", c.ContractNumber)
+	}
+	found, err := u.exec(ctx, s.q, "time_entries", id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Delete removes a time entry, returning whether it existed
+func (s *EntryStore) Delete(ctx context.Context, id string) (bool, error) {
+	result, err := s.q.ExecContext(ctx, "DELETE FROM time_entries WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// SetInvoice links a time entry to an invoice, or unlinks it when invoiceID
+// is nil, returning whether the entry exists
+func (s *EntryStore) SetInvoice(ctx context.Context, id string, invoiceID *int) (bool, error) {
+	result, err := s.q.ExecContext(ctx, "UPDATE time_entries SET invoice_id = ? WHERE id = ?", invoiceID, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// SetInvoiceAll links several unbilled time entries to an invoice with one
+// prepared statement, failing with ErrAlreadyInvoiced if any of them is on
+// an invoice by now and sql.ErrNoRows if any doesn't exist
+func (s *EntryStore) SetInvoiceAll(ctx context.Context, ids []string, invoiceID int) error {
+	stmt, err := s.q.PrepareContext(ctx, "UPDATE time_entries SET invoice_id = ? WHERE id = ? AND invoice_id IS NULL")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, id := range ids {
+		result, err := stmt.ExecContext(ctx, invoiceID, id)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("time entry %s: %w", id, unlinkedReason(ctx, s.q, "time_entries", id))
+		}
+	}
+	return nil
+}
+
+// List returns the time entries matching filter, most recent first or, for
+// a full-text query, best match first
+func (s *EntryStore) List(ctx context.Context, filter EntryFilter) ([]EntryListing, error) {
+	query := `
+		SELECT te.id, te.contract_id, te.date, te.hours, te.description, te.invoice_id, te.billable, te.no_charge, COALESCE(s.name, ''),
+		       COALESCE(te.adjusts_entry_id, ''), te.needs_review, te.created_at, cl.name, ct.contract_number, ct.name, ` + EntryRateSQL + `, ct.currency
+		FROM time_entries te
+		JOIN contracts ct ON te.contract_id = ct.id
+		JOIN clients cl ON ct.client_id = cl.id
+		LEFT JOIN services s ON te.service_id = s.id
+	`
+	args := []any{}
+	orderBy := " ORDER BY te.date DESC, te.created_at DESC"
+
+	if filter.FTSQuery != "" {
+		query += " JOIN time_entries_fts fts ON fts.entry_id = te.id WHERE time_entries_fts MATCH ?"
+		args = append(args, filter.FTSQuery)
+		orderBy = " ORDER BY fts.rank, te.date DESC"
+	} else {
+		query += " WHERE 1=1"
+		if filter.Description != "" {
+			query += " AND te.description LIKE ?"
+			args = append(args, "%"+strings.Trim(filter.Description, "\"*")+"%")
+		}
+	}
+
+	if len(filter.IDs) > 0 {
+		query += " AND te.id IN (?" + strings.Repeat(", ?", len(filter.IDs)-1) + ")"
+		for _, id := range filter.IDs {
+			args = append(args, id)
+		}
+	}
+	if filter.ClientID != 0 {
+		query += " AND cl.id = ?"
+		args = append(args, filter.ClientID)
+	}
+	if filter.ContractID != 0 {
+		query += " AND ct.id = ?"
+		args = append(args, filter.ContractID)
+	}
+	if filter.ContractRef != "" {
+		query += " AND ct.contract_number LIKE ?"
+		args = append(args, "%"+filter.ContractRef+"%")
+	}
+	if filter.MinHours != nil {
+		query += " AND te.hours >= ?"
+		args = append(args, *filter.MinHours)
+	}
+	if filter.MaxHours != nil {
+		query += " AND te.hours <= ?"
+		args = append(args, *filter.MaxHours)
+	}
+	if filter.StartDate != nil {
+		query += " AND te.date >= ?"
+		args = append(args, filter.StartDate.Format("2006-01-02"))
+	}
+	if filter.EndDate != nil {
+		query += " AND te.date <= ?"
+		args = append(args, filter.EndDate.Format("2006-01-02"))
+	}
+	if filter.Invoiced != nil {
+		if *filter.Invoiced {
+			query += " AND te.invoice_id IS NOT NULL"
+		} else {
+			query += " AND te.invoice_id IS NULL"
+		}
+	}
+
+	rows, err := s.q.QueryContext(ctx, query+orderBy, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []EntryListing
+	for rows.Next() {
+		var e EntryListing
+		if err := rows.Scan(&e.ID, &e.ContractID, &e.Date, &e.Hours, &e.Description, &e.InvoiceID, &e.Billable, &e.NoCharge, &e.Service,
+			&e.AdjustsEntryID, &e.NeedsReview, &e.CreatedAt, &e.ClientName, &e.ContractNumber, &e.ContractName, &e.HourlyRate, &e.Currency); err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Unbilled returns a client's billable entries in a period that are not on
+// an invoice yet, oldest first, each with the contract it was logged
+// against and the rate in effect that day
+func (s *EntryStore) Unbilled(ctx context.Context, clientID int, start, end time.Time) ([]models.TimeEntry, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT te.id, te.date, te.hours, te.description, te.no_charge, COALESCE(s.name, ''), te.needs_review,
+		       ct.id, ct.contract_number, ct.name, `+EntryRateSQL+`, ct.currency, ct.payment_terms
+		FROM time_entries te
+		JOIN contracts ct ON te.contract_id = ct.id
+		LEFT JOIN services s ON te.service_id = s.id
+		WHERE ct.client_id = ? AND te.date >= ? AND te.date <= ? AND te.invoice_id IS NULL AND te.billable
+		ORDER BY te.date
+	`, clientID, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.TimeEntry
+	for rows.Next() {
+		var e models.TimeEntry
+		var contract models.Contract
+		if err := rows.Scan(&e.ID, &e.Date, &e.Hours, &e.Description, &e.NoCharge, &e.Service, &e.NeedsReview, &contract.ID,
+			&contract.ContractNumber, &contract.Name, &contract.HourlyRate, &contract.Currency, &contract.PaymentTerms); err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		e.ContractID = contract.ID
+		e.Contract = &contract
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// InvoicedFrom returns the invoice billing the contract's earliest work on
+// or after day, leaving out cancelled invoices, with the date of that work.
+// It returns sql.ErrNoRows when none of the work from day on is invoiced.
+func (s *EntryStore) InvoicedFrom(ctx context.Context, contractID int, day time.Time) (string, time.Time, error) {
+	var number string
+	var date time.Time
+	err := s.q.QueryRowContext(ctx, `
+		SELECT i.invoice_number, te.date
+		FROM time_entries te
+		JOIN invoices i ON te.invoice_id = i.id
+		WHERE te.contract_id = ? AND te.date >= ? AND i.status != 'cancelled'
+		ORDER BY te.date
+		LIMIT 1
+	`, contractID, day.Format("2006-01-02")).Scan(&number, &date)
+	return number, date, err
+}
+
+// ForInvoice returns the entries billed on an invoice, oldest first
+func (s *EntryStore) ForInvoice(ctx context.Context, invoiceID int) ([]models.TimeEntry, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT id, contract_id, date, hours, description, no_charge
+		FROM time_entries
+		WHERE invoice_id = ?
+		ORDER BY date
+	`, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.TimeEntry
+	for rows.Next() {
+		e := models.TimeEntry{InvoiceID: &invoiceID}
+		if err := rows.Scan(&e.ID, &e.ContractID, &e.Date, &e.Hours, &e.Description, &e.NoCharge); err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
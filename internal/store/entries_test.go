@@ -0,0 +1,275 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/austin/hours-mcp/internal/models"
+)
+
+func TestEntryCreateAndGet(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	id := f.addEntry(t, "2026-01-05", 2.5)
+
+	e, clientName, err := f.store.Entries.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if e.Hours != 2.5 || e.ContractID != f.contractID || e.Description != "work" || e.InvoiceID != nil || clientName != "Acme" {
+		t.Errorf("got %+v for %s", e, clientName)
+	}
+	if !e.Date.Equal(date(t, "2026-01-05")) {
+		t.Errorf("date = %v, want 2026-01-05", e.Date)
+	}
+	if _, _, err := f.store.Entries.Get(ctx, "no-such-entry"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("missing entry: got %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestEntryUpdate(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	id := f.addEntry(t, "2026-01-05", 2)
+	hours, description := 3.25, "review"
+
+	if err := f.store.Entries.Update(ctx, id, EntryChanges{Hours: &hours, Description: &description}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	e, _, err := f.store.Entries.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if e.Hours != 3.25 || e.Description != "review" {
+		t.Errorf("got %+v", e)
+	}
+}
+
+func TestEntryUpdateMovesContract(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	id := f.addEntry(t, "2026-01-05", 2)
+	clientID, err := f.store.Clients.Create(ctx, &models.Client{Name: "Globex"}, "{}")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	contract := &models.Contract{ClientID: clientID, ContractNumber: "GX-001", Name: "Audit", HourlyRate: 12000,
+		Currency: "USD", ContractType: "hourly", StartDate: date(t, "2026-01-01")}
+	if contract.ID, err = f.store.Contracts.Create(ctx, contract); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if err := f.store.Entries.Update(ctx, id, EntryChanges{Contract: contract}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	e, clientName, err := f.store.Entries.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if e.ContractID != contract.ID || clientName != "Globex" {
+		t.Errorf("entry is on contract %d of %s, want GX-001 of Globex", e.ContractID, clientName)
+	}
+}
+
+func TestEntryUpdateNoChanges(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	id := f.addEntry(t, "2026-01-05", 2)
+
+	if err := f.store.Entries.Update(ctx, id, EntryChanges{}); !errors.Is(err, ErrNoChanges) {
+		t.Fatalf("got %v, want ErrNoChanges", err)
+	}
+}
+
+func TestEntryUpdateMissing(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	hours := 1.5
+
+	if err := f.store.Entries.Update(ctx, "no-such-entry", EntryChanges{Hours: &hours}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("got %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestEntryDelete(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	id := f.addEntry(t, "2026-01-05", 2)
+
+	if found, err := f.store.Entries.Delete(ctx, id); err != nil || !found {
+		t.Fatalf("Delete = %v, %v; want true", found, err)
+	}
+	if found, err := f.store.Entries.Delete(ctx, id); err != nil || found {
+		t.Errorf("second Delete = %v, %v; want false", found, err)
+	}
+}
+
+func TestEntrySetInvoice(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	id := f.addEntry(t, "2026-01-05", 2)
+	invoiceID := f.addInvoice(t, "INV-1")
+
+	if found, err := f.store.Entries.SetInvoice(ctx, id, &invoiceID); err != nil || !found {
+		t.Fatalf("SetInvoice = %v, %v; want true", found, err)
+	}
+	entries, err := f.store.Entries.ForInvoice(ctx, invoiceID)
+	if err != nil {
+		t.Fatalf("ForInvoice: %v", err)
+	}
+	if len(entries) != 1 || entries[0].ID != id {
+		t.Fatalf("got %+v, want %s on the invoice", entries, id)
+	}
+
+	if _, err := f.store.Entries.SetInvoice(ctx, id, nil); err != nil {
+		t.Fatalf("SetInvoice(nil): %v", err)
+	}
+	if entries, err = f.store.Entries.ForInvoice(ctx, invoiceID); err != nil || len(entries) != 0 {
+		t.Errorf("after unlinking got %d entries, %v; want none", len(entries), err)
+	}
+	if found, err := f.store.Entries.SetInvoice(ctx, "no-such-entry", &invoiceID); err != nil || found {
+		t.Errorf("missing entry: SetInvoice = %v, %v; want false", found, err)
+	}
+}
+
+func TestEntrySetInvoiceAll(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	ids := []string{f.addEntry(t, "2026-01-05", 2), f.addEntry(t, "2026-01-06", 3)}
+	invoiceID := f.addInvoice(t, "INV-1")
+
+	if err := f.store.Entries.SetInvoiceAll(ctx, ids, invoiceID); err != nil {
+		t.Fatalf("SetInvoiceAll: %v", err)
+	}
+	entries, err := f.store.Entries.ForInvoice(ctx, invoiceID)
+	if err != nil {
+		t.Fatalf("ForInvoice: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("got %d entries on the invoice, want 2", len(entries))
+	}
+}
+
+func TestEntrySetInvoiceAllAlreadyInvoiced(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	billed := f.addEntry(t, "2026-01-05", 2)
+	unbilled := f.addEntry(t, "2026-01-06", 3)
+	first := f.addInvoice(t, "INV-1")
+	second := f.addInvoice(t, "INV-2")
+
+	if err := f.store.Entries.SetInvoiceAll(ctx, []string{billed}, first); err != nil {
+		t.Fatalf("SetInvoiceAll: %v", err)
+	}
+	err := f.store.Entries.SetInvoiceAll(ctx, []string{unbilled, billed}, second)
+	if !errors.Is(err, ErrAlreadyInvoiced) {
+		t.Fatalf("got %v, want ErrAlreadyInvoiced", err)
+	}
+
+	// The entry stays on the invoice that billed it first
+	var invoiceID int
+	if err := f.db.QueryRow("SELECT invoice_id FROM time_entries WHERE id = ?", billed).Scan(&invoiceID); err != nil {
+		t.Fatal(err)
+	}
+	if invoiceID != first {
+		t.Errorf("entry moved to invoice %d, want %d", invoiceID, first)
+	}
+}
+
+func TestEntrySetInvoiceAllMissing(t *testing.T) {
+	f := newFixture(t)
+	invoiceID := f.addInvoice(t, "INV-1")
+
+	err := f.store.Entries.SetInvoiceAll(context.Background(), []string{"no-such-entry"}, invoiceID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("got %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestEntryList(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	older := f.addEntry(t, "2026-01-05", 2)
+	newer := f.addEntry(t, "2026-01-09", 6)
+	invoiceID := f.addInvoice(t, "INV-1")
+	if _, err := f.store.Entries.SetInvoice(ctx, older, &invoiceID); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := f.store.Entries.List(ctx, EntryFilter{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	// Most recent first
+	if len(entries) != 2 || entries[0].ID != newer || entries[1].ID != older {
+		t.Fatalf("got %+v, want %s then %s", entries, newer, older)
+	}
+	if e := entries[0]; e.ClientName != "Acme" || e.ContractNumber != "AC-001" || e.HourlyRate != 10000 || e.Currency != "USD" {
+		t.Errorf("got %+v", e)
+	}
+
+	minHours := 4.0
+	if entries, err = f.store.Entries.List(ctx, EntryFilter{MinHours: &minHours}); err != nil || len(entries) != 1 || entries[0].ID != newer {
+		t.Errorf("min hours: got %+v, %v; want %s", entries, err, newer)
+	}
+	invoiced := true
+	if entries, err = f.store.Entries.List(ctx, EntryFilter{Invoiced: &invoiced}); err != nil || len(entries) != 1 || entries[0].ID != older {
+		t.Errorf("invoiced: got %+v, %v; want %s", entries, err, older)
+	}
+	if entries, err = f.store.Entries.List(ctx, EntryFilter{ContractRef: "ac-0"}); err != nil || len(entries) != 2 {
+		t.Errorf("contract ref: got %d entries, %v; want 2", len(entries), err)
+	}
+	if entries, err = f.store.Entries.List(ctx, EntryFilter{IDs: []string{older, "no-such-entry"}}); err != nil || len(entries) != 1 || entries[0].ID != older {
+		t.Errorf("ids: got %+v, %v; want %s", entries, err, older)
+	}
+}
+
+func TestEntryUnbilled(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	want := f.addEntry(t, "2026-01-05", 2)
+	nonBillable := f.addEntry(t, "2026-01-06", 1)
+	invoiced := f.addEntry(t, "2026-01-07", 4)
+	f.addEntry(t, "2026-02-10", 5) // outside the period
+
+	billable := false
+	if err := f.store.Entries.Update(ctx, nonBillable, EntryChanges{Billable: &billable}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	invoiceID := f.addInvoice(t, "INV-1")
+	if _, err := f.store.Entries.SetInvoice(ctx, invoiced, &invoiceID); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := f.store.Entries.Unbilled(ctx, f.clientID, date(t, "2026-01-01"), date(t, "2026-01-31"))
+	if err != nil {
+		t.Fatalf("Unbilled: %v", err)
+	}
+	if len(entries) != 1 || entries[0].ID != want {
+		t.Fatalf("got %d entries, want only %s", len(entries), want)
+	}
+	if e := entries[0]; e.Hours != 2 || e.Contract == nil || e.Contract.HourlyRate != 10000 || e.Contract.ContractNumber != "AC-001" {
+		t.Errorf("got %+v, want 2 hours at the contract rate", e)
+	}
+}
+
+func TestEntryUnbilledScheduledRate(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	f.addEntry(t, "2026-01-05", 1)
+	f.addEntry(t, "2026-01-20", 1)
+	if _, err := f.db.Exec("INSERT INTO contract_rates (contract_id, hourly_rate_cents, effective_from) VALUES (?, 12000, '2026-01-15')", f.contractID); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := f.store.Entries.Unbilled(ctx, f.clientID, date(t, "2026-01-01"), date(t, "2026-01-31"))
+	if err != nil {
+		t.Fatalf("Unbilled: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Contract.HourlyRate != 10000 || entries[1].Contract.HourlyRate != 12000 {
+		t.Errorf("got %+v, want the rate in effect on each day", entries)
+	}
+}
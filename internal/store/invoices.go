@@ -0,0 +1,293 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/models"
+	"github.com/austin/hours-mcp/internal/money"
+)
+
+// InvoiceStore reads and writes invoices
+type InvoiceStore struct {
+	q Querier
+}
+
+// InvoiceListing is an invoice with its client's name, as listed
+type InvoiceListing struct {
+	ID            int         `json:"id"`
+	InvoiceNumber string      `json:"invoice_number"`
+	IssueDate     time.Time   `json:"issue_date"`
+	DueDate       time.Time   `json:"due_date"`
+	TotalAmount   money.Cents `json:"total_amount"`
+	Currency      string      `json:"currency"`
+	Status        string      `json:"status"`
+	ClientName    string      `json:"client_name"`
+	// SentAt is when the invoice was last sent, nil if it never was
+	SentAt         *time.Time `json:"sent_at,omitempty"`
+	DeliveryMethod string     `json:"delivery_method,omitempty"`
+}
+
+// InvoiceFilter narrows the invoices List returns; zero fields match all
+type InvoiceFilter struct {
+	ClientID int
+	Status   string
+	// StartDate and EndDate bound the issue date
+	StartDate *time.Time
+	EndDate   *time.Time
+}
+
+// InvoiceLine is a priced line of an invoice as kept for exports
+type InvoiceLine struct {
+	ContractID int
+	RateKind   string
+	RateLabel  string
+	Period     string
+	Hours      float64
+	Rate       money.Cents
+	Amount     money.Cents
+}
+
+// Create saves a new invoice, pending, and returns its ID. Cc is the list of
+// addresses to copy, comma separated.
+func (s *InvoiceStore) Create(ctx context.Context, inv *models.Invoice, cc string) (int, error) {
+	result, err := s.q.ExecContext(ctx, `
+		INSERT INTO invoices (client_id, invoice_number, issue_date, due_date, total_cents, tax_rate, tax_cents,
+		                      tax_treatment, tax_note, withholding_rate, withholding_cents, rounding_cents, currency, cc, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'pending')
+	`, inv.ClientID, inv.InvoiceNumber, inv.IssueDate.Format("2006-01-02"), inv.DueDate.Format("2006-01-02"), inv.TotalAmount,
+		inv.TaxRate, inv.TaxAmount, inv.TaxTreatment, inv.TaxNote, inv.WithholdingRate, inv.WithholdingAmount, inv.Rounding, inv.Currency, cc)
+	if err != nil {
+		return 0, err
+	}
+	id, _ := result.LastInsertId()
+	return int(id), nil
+}
+
+// AddRecipient addresses an invoice to one of its client's recipients
+func (s *InvoiceStore) AddRecipient(ctx context.Context, invoiceID, recipientID int) error {
+	_, err := s.q.ExecContext(ctx, "INSERT INTO invoice_recipients (invoice_id, recipient_id) VALUES (?, ?)", invoiceID, recipientID)
+	return err
+}
+
+// AddLines keeps the priced lines of an invoice
+func (s *InvoiceStore) AddLines(ctx context.Context, invoiceID int, lines []InvoiceLine) error {
+	stmt, err := s.q.PrepareContext(ctx, `
+		INSERT INTO invoice_lines (invoice_id, contract_id, rate_kind, rate_label, period, hours, rate_cents, amount_cents)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, line := range lines {
+		_, err := stmt.ExecContext(ctx, invoiceID, line.ContractID, line.RateKind, line.RateLabel, line.Period, line.Hours, line.Rate, line.Amount)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LinkExpenses bills unbilled expenses on an invoice, failing with
+// ErrAlreadyInvoiced if any of them is on an invoice by now
+func (s *InvoiceStore) LinkExpenses(ctx context.Context, invoiceID int, expenseIDs []int) error {
+	stmt, err := s.q.PrepareContext(ctx, "UPDATE expenses SET invoice_id = ? WHERE id = ? AND invoice_id IS NULL")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, id := range expenseIDs {
+		result, err := stmt.ExecContext(ctx, invoiceID, id)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("expense %d: %w", id, unlinkedReason(ctx, s.q, "expenses", id))
+		}
+	}
+	return nil
+}
+
+// FeeBilled reports whether an invoice other than invoiceID, and not
+// cancelled, has a line of rateKind for the contract and period, e.g. the
+// month's retainer fee
+func (s *InvoiceStore) FeeBilled(ctx context.Context, invoiceID, contractID int, rateKind, period string) (bool, error) {
+	var count int
+	err := s.q.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM invoice_lines l
+		JOIN invoices i ON l.invoice_id = i.id
+		WHERE l.contract_id = ? AND l.rate_kind = ? AND l.period = ? AND i.id != ? AND i.status != 'cancelled'
+	`, contractID, rateKind, period, invoiceID).Scan(&count)
+	return count > 0, err
+}
+
+// SetPDFPath records where an invoice's PDF was saved
+func (s *InvoiceStore) SetPDFPath(ctx context.Context, invoiceID int, path string) error {
+	_, err := s.q.ExecContext(ctx, "UPDATE invoices SET pdf_path = ? WHERE id = ?", path, invoiceID)
+	return err
+}
+
+// IDByNumber returns the ID of the invoice with an invoice number, or
+// sql.ErrNoRows
+func (s *InvoiceStore) IDByNumber(ctx context.Context, number string) (int, error) {
+	var id int
+	err := s.q.QueryRowContext(ctx, "SELECT id FROM invoices WHERE invoice_number = ?", number).Scan(&id)
+	return id, err
+}
+
+// NumberByID returns the invoice number of an invoice, or sql.ErrNoRows
+func (s *InvoiceStore) NumberByID(ctx context.Context, id int) (string, error) {
+	var number string
+	err := s.q.QueryRowContext(ctx, "SELECT invoice_number FROM invoices WHERE id = ?", id).Scan(&number)
+	return number, err
+}
+
+// ByNumber returns an invoice with its client's name, or sql.ErrNoRows
+func (s *InvoiceStore) ByNumber(ctx context.Context, number string) (*models.Invoice, error) {
+	inv := &models.Invoice{Client: &models.Client{}}
+	var sentAt, writtenOff sql.NullTime
+	err := s.q.QueryRowContext(ctx, `
+		SELECT i.id, i.client_id, i.invoice_number, i.issue_date, i.due_date,
+		       i.total_cents, COALESCE(i.tax_rate, 0), i.tax_cents, COALESCE(i.withholding_rate, 0),
+		       i.withholding_cents, i.rounding_cents, COALESCE(i.tax_note, ''), i.currency, i.status, i.pdf_path, i.created_at,
+		       i.sent_at, i.sent_to, i.delivery_method, i.written_off_date, i.write_off_reason, c.name
+		FROM invoices i
+		JOIN clients c ON i.client_id = c.id
+		WHERE i.invoice_number = ?
+	`, number).Scan(&inv.ID, &inv.ClientID, &inv.InvoiceNumber,
+		&inv.IssueDate, &inv.DueDate, &inv.TotalAmount, &inv.TaxRate, &inv.TaxAmount,
+		&inv.WithholdingRate, &inv.WithholdingAmount, &inv.Rounding, &inv.TaxNote, &inv.Currency,
+		&inv.Status, &inv.PDFPath, &inv.CreatedAt, &sentAt, &inv.SentTo, &inv.DeliveryMethod,
+		&writtenOff, &inv.WriteOffReason, &inv.Client.Name)
+	if err != nil {
+		return nil, err
+	}
+	inv.Client.ID = inv.ClientID
+	if sentAt.Valid {
+		inv.SentAt = &sentAt.Time
+	}
+	if writtenOff.Valid {
+		inv.WrittenOffDate = &writtenOff.Time
+	}
+	return inv, nil
+}
+
+// Expenses returns the expenses billed on an invoice, oldest first, at the
+// amounts recorded
+func (s *InvoiceStore) Expenses(ctx context.Context, invoiceID int) ([]models.Expense, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT id, contract_id, kind, date, amount_cents, currency, quantity, unit, unit_rate_cents,
+		       category, description, receipt_path
+		FROM expenses
+		WHERE invoice_id = ?
+		ORDER BY date, id
+	`, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var expenses []models.Expense
+	for rows.Next() {
+		e := models.Expense{Billable: true, InvoiceID: &invoiceID}
+		if err := rows.Scan(&e.ID, &e.ContractID, &e.Kind, &e.Date, &e.Amount, &e.Currency, &e.Quantity,
+			&e.Unit, &e.UnitRate, &e.Category, &e.Description, &e.ReceiptPath); err != nil {
+			return nil, fmt.Errorf("failed to scan expense: %w", err)
+		}
+		expenses = append(expenses, e)
+	}
+	return expenses, rows.Err()
+}
+
+// SetStatus changes an invoice's status and paid date, returning whether
+// the invoice exists. PaidDate is cleared when nil, and so is any write-off.
+func (s *InvoiceStore) SetStatus(ctx context.Context, number, status string, paidDate *time.Time) (bool, error) {
+	var paid any
+	if paidDate != nil {
+		paid = paidDate.Format("2006-01-02")
+	}
+	result, err := s.q.ExecContext(ctx, `
+		UPDATE invoices SET status = ?, paid_date = ?, written_off_date = NULL, write_off_reason = '' WHERE invoice_number = ?
+	`, status, paid, number)
+	if err != nil {
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// WriteOff gives an invoice the written_off status as of date, for a
+// reason
+func (s *InvoiceStore) WriteOff(ctx context.Context, invoiceID int, date time.Time, reason string) error {
+	_, err := s.q.ExecContext(ctx, `
+		UPDATE invoices SET status = 'written_off', written_off_date = ?, write_off_reason = ? WHERE id = ?
+	`, date.Format("2006-01-02"), reason, invoiceID)
+	return err
+}
+
+// MarkSent records that an invoice was sent at a time, to the addresses or
+// people in to, by a delivery method such as email. Pending and draft
+// invoices become sent.
+func (s *InvoiceStore) MarkSent(ctx context.Context, invoiceID int, at time.Time, to, method string) error {
+	_, err := s.q.ExecContext(ctx, `
+		UPDATE invoices SET sent_at = ?, sent_to = ?, delivery_method = ?,
+			status = CASE WHEN status IN ('pending', 'draft') THEN 'sent' ELSE status END
+		WHERE id = ?
+	`, at.UTC().Format("2006-01-02 15:04:05"), to, method, invoiceID)
+	return err
+}
+
+// List returns the invoices matching filter, most recently issued first
+func (s *InvoiceStore) List(ctx context.Context, filter InvoiceFilter) ([]InvoiceListing, error) {
+	query := `
+		SELECT i.id, i.invoice_number, i.issue_date, i.due_date, i.total_cents, i.currency, i.status, c.name,
+		       i.sent_at, i.delivery_method
+		FROM invoices i
+		JOIN clients c ON i.client_id = c.id
+		WHERE 1=1
+	`
+	args := []any{}
+
+	if filter.ClientID != 0 {
+		query += " AND i.client_id = ?"
+		args = append(args, filter.ClientID)
+	}
+	if filter.Status != "" {
+		query += " AND i.status = ?"
+		args = append(args, filter.Status)
+	}
+	if filter.StartDate != nil {
+		query += " AND i.issue_date >= ?"
+		args = append(args, filter.StartDate.Format("2006-01-02"))
+	}
+	if filter.EndDate != nil {
+		query += " AND i.issue_date <= ?"
+		args = append(args, filter.EndDate.Format("2006-01-02"))
+	}
+
+	rows, err := s.q.QueryContext(ctx, query+" ORDER BY i.issue_date DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invoices []InvoiceListing
+	for rows.Next() {
+		var inv InvoiceListing
+		var sentAt sql.NullTime
+		if err := rows.Scan(&inv.ID, &inv.InvoiceNumber, &inv.IssueDate, &inv.DueDate,
+			&inv.TotalAmount, &inv.Currency, &inv.Status, &inv.ClientName, &sentAt, &inv.DeliveryMethod); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice: %w", err)
+		}
+		if sentAt.Valid {
+			inv.SentAt = &sentAt.Time
+		}
+		invoices = append(invoices, inv)
+	}
+	return invoices, rows.Err()
+}
@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/austin/hours-mcp/internal/models"
+)
+
+func TestInvoiceCreateAndByNumber(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	id := f.addInvoice(t, "INV-1")
+	if err := f.store.Invoices.SetPDFPath(ctx, id, "/tmp/invoice.pdf"); err != nil {
+		t.Fatalf("SetPDFPath: %v", err)
+	}
+
+	inv, err := f.store.Invoices.ByNumber(ctx, "INV-1")
+	if err != nil {
+		t.Fatalf("ByNumber: %v", err)
+	}
+	if inv.ID != id || inv.Status != "pending" || inv.TotalAmount != 20000 || inv.Currency != "USD" ||
+		inv.PDFPath != "/tmp/invoice.pdf" || inv.Client.Name != "Acme" {
+		t.Errorf("got %+v", inv)
+	}
+	if got, err := f.store.Invoices.IDByNumber(ctx, "INV-1"); err != nil || got != id {
+		t.Errorf("IDByNumber = %d, %v; want %d", got, err, id)
+	}
+	if number, err := f.store.Invoices.NumberByID(ctx, id); err != nil || number != "INV-1" {
+		t.Errorf("NumberByID = %q, %v; want INV-1", number, err)
+	}
+	if _, err := f.store.Invoices.ByNumber(ctx, "INV-404"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("missing invoice: got %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestInvoiceCreateDuplicateNumber(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	f.addInvoice(t, "INV-1")
+
+	inv := &models.Invoice{ClientID: f.clientID, InvoiceNumber: "INV-1", IssueDate: date(t, "2026-02-02"), DueDate: date(t, "2026-03-02"), Currency: "USD"}
+	if _, err := f.store.Invoices.Create(ctx, inv, ""); err == nil {
+		t.Fatal("created a second invoice numbered INV-1")
+	}
+}
+
+func TestInvoiceAddLines(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	id := f.addInvoice(t, "INV-1")
+
+	lines := []InvoiceLine{
+		{ContractID: f.contractID, RateKind: "regular", Hours: 2, Rate: 10000, Amount: 20000},
+		{ContractID: f.contractID, RateKind: "weekend", RateLabel: "Weekend", Hours: 1, Rate: 15000, Amount: 15000},
+	}
+	if err := f.store.Invoices.AddLines(ctx, id, lines); err != nil {
+		t.Fatalf("AddLines: %v", err)
+	}
+	var count int
+	var hours float64
+	var amount int64
+	if err := f.db.QueryRow("SELECT COUNT(*), SUM(hours), SUM(amount_cents) FROM invoice_lines WHERE invoice_id = ?", id).Scan(&count, &hours, &amount); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || hours != 3 || amount != 35000 {
+		t.Errorf("got %d lines, %g hours for %d; want 2 lines, 3 hours for 35000", count, hours, amount)
+	}
+}
+
+func TestInvoiceLinkExpenses(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	id := f.addInvoice(t, "INV-1")
+	var expenseIDs []int
+	for _, e := range []struct {
+		amount      int
+		description string
+	}{{4500, "train"}, {1200, "taxi"}, {800, "coffee"}} {
+		result, err := f.db.Exec("INSERT INTO expenses (contract_id, date, amount_cents, currency, description) VALUES (?, '2026-01-10', ?, 'USD', ?)",
+			f.contractID, e.amount, e.description)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expenseID, _ := result.LastInsertId()
+		expenseIDs = append(expenseIDs, int(expenseID))
+	}
+
+	if err := f.store.Invoices.LinkExpenses(ctx, id, expenseIDs[:2]); err != nil {
+		t.Fatalf("LinkExpenses: %v", err)
+	}
+	expenses, err := f.store.Invoices.Expenses(ctx, id)
+	if err != nil {
+		t.Fatalf("Expenses: %v", err)
+	}
+	if len(expenses) != 2 || expenses[0].Amount+expenses[1].Amount != 5700 {
+		t.Errorf("got %+v, want the train and taxi", expenses)
+	}
+}
+
+func TestInvoiceLinkExpensesAlreadyInvoiced(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	first := f.addInvoice(t, "INV-1")
+	second := f.addInvoice(t, "INV-2")
+	result, err := f.db.Exec("INSERT INTO expenses (contract_id, date, amount_cents, currency) VALUES (?, '2026-01-10', 4500, 'USD')", f.contractID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expenseID, _ := result.LastInsertId()
+
+	if err := f.store.Invoices.LinkExpenses(ctx, first, []int{int(expenseID)}); err != nil {
+		t.Fatalf("LinkExpenses: %v", err)
+	}
+	if err := f.store.Invoices.LinkExpenses(ctx, second, []int{int(expenseID)}); !errors.Is(err, ErrAlreadyInvoiced) {
+		t.Errorf("got %v, want ErrAlreadyInvoiced", err)
+	}
+	if err := f.store.Invoices.LinkExpenses(ctx, second, []int{9999}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("missing expense: got %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestInvoiceFeeBilled(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	first := f.addInvoice(t, "INV-1")
+	second := f.addInvoice(t, "INV-2")
+	fee := InvoiceLine{ContractID: f.contractID, RateKind: "retainer", Period: "2026-01", Hours: 10, Amount: 100000}
+	if err := f.store.Invoices.AddLines(ctx, first, []InvoiceLine{fee}); err != nil {
+		t.Fatalf("AddLines: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		invoiceID int
+		period    string
+		want      bool
+	}{
+		{"billed on another invoice", second, "2026-01", true},
+		{"the invoice billing it", first, "2026-01", false},
+		{"another month", second, "2026-02", false},
+	} {
+		got, err := f.store.Invoices.FeeBilled(ctx, tc.invoiceID, f.contractID, "retainer", tc.period)
+		if err != nil {
+			t.Fatalf("%s: FeeBilled: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	if _, err := f.store.Invoices.SetStatus(ctx, "INV-1", "cancelled", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := f.store.Invoices.FeeBilled(ctx, second, f.contractID, "retainer", "2026-01"); err != nil || got {
+		t.Errorf("cancelled invoice: got %v, %v; want false", got, err)
+	}
+}
+
+func TestInvoiceSetStatus(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	f.addInvoice(t, "INV-1")
+	paid := date(t, "2026-02-20")
+
+	if found, err := f.store.Invoices.SetStatus(ctx, "INV-1", "paid", &paid); err != nil || !found {
+		t.Fatalf("SetStatus = %v, %v; want true", found, err)
+	}
+	var status, paidDate string
+	if err := f.db.QueryRow("SELECT status, substr(paid_date, 1, 10) FROM invoices WHERE invoice_number = 'INV-1'").Scan(&status, &paidDate); err != nil {
+		t.Fatal(err)
+	}
+	if status != "paid" || paidDate != "2026-02-20" {
+		t.Errorf("got %s on %s, want paid on 2026-02-20", status, paidDate)
+	}
+	if found, err := f.store.Invoices.SetStatus(ctx, "INV-404", "paid", &paid); err != nil || found {
+		t.Errorf("missing invoice: SetStatus = %v, %v; want false", found, err)
+	}
+}
+
+func TestInvoiceList(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	f.addInvoice(t, "INV-1")
+	second := f.addInvoice(t, "INV-2")
+	if _, err := f.db.Exec("UPDATE invoices SET issue_date = '2026-03-01' WHERE id = ?", second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.store.Invoices.SetStatus(ctx, "INV-1", "paid", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	invoices, err := f.store.Invoices.List(ctx, InvoiceFilter{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	// Most recently issued first
+	if len(invoices) != 2 || invoices[0].InvoiceNumber != "INV-2" || invoices[1].InvoiceNumber != "INV-1" {
+		t.Fatalf("got %+v, want INV-2 then INV-1", invoices)
+	}
+	if invoices[0].ClientName != "Acme" {
+		t.Errorf("client = %q, want Acme", invoices[0].ClientName)
+	}
+
+	if invoices, err = f.store.Invoices.List(ctx, InvoiceFilter{Status: "pending"}); err != nil || len(invoices) != 1 || invoices[0].InvoiceNumber != "INV-2" {
+		t.Errorf("pending: got %+v, %v; want INV-2", invoices, err)
+	}
+	end := date(t, "2026-02-15")
+	if invoices, err = f.store.Invoices.List(ctx, InvoiceFilter{EndDate: &end}); err != nil || len(invoices) != 1 || invoices[0].InvoiceNumber != "INV-1" {
+		t.Errorf("issued by %v: got %+v, %v; want INV-1", end, invoices, err)
+	}
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoChanges is returned by edits that were given nothing to change
+var ErrNoChanges = errors.New("no fields provided to update")
+
+// ErrAlreadyInvoiced is returned when billing a time entry, expense or
+// retainer fee that another invoice billed first, e.g. one created at the
+// same time by another session
+var ErrAlreadyInvoiced = errors.New("already invoiced")
+
+// Querier is what the stores run their statements on: the database, or a
+// transaction when several changes must succeed or fail together
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// Store groups the typed queries for the core records the tools work with
+type Store struct {
+	Clients   *ClientStore
+	Contracts *ContractStore
+	Entries   *EntryStore
+	Invoices  *InvoiceStore
+}
+
+// New returns a store running its queries on q
+func New(q Querier) *Store {
+	return &Store{
+		Clients:   &ClientStore{q: q},
+		Contracts: &ContractStore{q: q},
+		Entries:   &EntryStore{q: q},
+		Invoices:  &InvoiceStore{q: q},
+	}
+}
+
+// WithTx returns a store running its queries in tx
+func (s *Store) WithTx(tx *sql.Tx) *Store {
+	return New(tx)
+}
+
+// EffectiveRateSQL returns an SQL expression for the hourly rate (in cents)
+// of the contract aliased as contract on the date expression day: the latest
+// scheduled rate effective on or before that day, otherwise the contract's
+// own rate.
+func EffectiveRateSQL(contract, day string) string {
+	return `COALESCE((SELECT r.hourly_rate_cents FROM contract_rates r
+		WHERE r.contract_id = ` + contract + `.id AND r.effective_from <= ` + day + `
+		ORDER BY r.effective_from DESC LIMIT 1), ` + contract + `.hourly_rate_cents)`
+}
+
+// EntryRateSQL is the rate of the time entry te under its contract ct: the
+// contract's rate for the entry's service when it has one, otherwise the
+// contract's rate on the entry's date
+var EntryRateSQL = `COALESCE((SELECT cs.hourly_rate_cents FROM contract_services cs
+		WHERE cs.contract_id = ct.id AND cs.service_id = te.service_id), ` + EffectiveRateSQL("ct", "te.date") + `)`
+
+// update collects the columns an edit changes
+type update struct {
+	columns []string
+	values  []any
+}
+
+func (u *update) set(column string, value any) {
+	u.columns = append(u.columns, column+" = ?")
+	u.values = append(u.values, value)
+}
+
+// exec updates the row of table with the given id, returning whether it
+// exists. Extra is added to the SET clause as is, e.g. to bump updated_at.
+func (u *update) exec(ctx context.Context, q Querier, table string, id any, extra ...string) (bool, error) {
+	if len(u.columns) == 0 {
+		return false, ErrNoChanges
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = ?", table, strings.Join(append(u.columns, extra...), ", "))
+	result, err := q.ExecContext(ctx, query, append(u.values, id)...)
+	if err != nil {
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// unlinkedReason explains why a row of table couldn't be linked to an
+// invoice: it is on another one by now, or it doesn't exist
+func unlinkedReason(ctx context.Context, q Querier, table string, id any) error {
+	var count int
+	if err := q.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE id = ?", table), id).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return sql.ErrNoRows
+	}
+	return ErrAlreadyInvoiced
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/austin/hours-mcp/internal/database"
+	"github.com/austin/hours-mcp/internal/models"
+)
+
+// fixture is an in-memory database with one client and contract to log
+// hours against
+type fixture struct {
+	store      *Store
+	db         *sql.DB
+	clientID   int
+	contractID int
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	db, err := database.Initialize(database.MemoryPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	s := New(db)
+	clientID, err := s.Clients.Create(ctx, &models.Client{Name: "Acme"}, "{}")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	contractID, err := s.Contracts.Create(ctx, &models.Contract{
+		ClientID:       clientID,
+		ContractNumber: "AC-001",
+		Name:           "Support",
+		HourlyRate:     10000,
+		Currency:       "USD",
+		ContractType:   "hourly",
+		StartDate:      date(t, "2026-01-01"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create contract: %v", err)
+	}
+	return &fixture{store: s, db: db, clientID: clientID, contractID: contractID}
+}
+
+func date(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func (f *fixture) addEntry(t *testing.T, day string, hours float64) string {
+	t.Helper()
+	ctx := context.Background()
+	id, err := f.store.Entries.Create(ctx, f.clientID, f.contractID, "AC-001", date(t, day), hours, "work", nil, false)
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	return id
+}
+
+func (f *fixture) addInvoice(t *testing.T, number string) int {
+	t.Helper()
+	ctx := context.Background()
+	id, err := f.store.Invoices.Create(ctx, &models.Invoice{
+		ClientID:      f.clientID,
+		InvoiceNumber: number,
+		IssueDate:     date(t, "2026-02-01"),
+		DueDate:       date(t, "2026-03-01"),
+		TotalAmount:   20000,
+		Currency:      "USD",
+	}, "")
+	if err != nil {
+		t.Fatalf("failed to create invoice: %v", err)
+	}
+	return id
+}
+
+func TestUpdateWithoutColumns(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	var u update
+	if _, err := u.exec(ctx, f.db, "clients", f.clientID); !errors.Is(err, ErrNoChanges) {
+		t.Fatalf("got %v, want ErrNoChanges", err)
+	}
+}
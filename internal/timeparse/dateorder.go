@@ -0,0 +1,86 @@
+package timeparse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// DateOrder says how numeric dates such as 03/04/2025 are read
+type DateOrder string
+
+const (
+	// DateOrderAuto reads numeric dates either way round, refusing those
+	// that are valid both ways
+	DateOrderAuto DateOrder = ""
+	DateOrderMDY  DateOrder = "mdy"
+	DateOrderDMY  DateOrder = "dmy"
+	DateOrderYMD  DateOrder = "ymd"
+)
+
+var numericDate = regexp.MustCompile(`^(\d{1,4})[/.-](\d{1,2})[/.-](\d{1,4})$`)
+
+// parseNumericDate parses dates like 03/04/2025, 3.4.2025 or 2025/04/03 in
+// the given order. ok is false when dateStr isn't a numeric date.
+func parseNumericDate(dateStr string, order DateOrder) (date time.Time, ok bool, err error) {
+	matches := numericDate.FindStringSubmatch(dateStr)
+	if matches == nil {
+		return time.Time{}, false, nil
+	}
+	a, b, c := matches[1], matches[2], matches[3]
+
+	// A leading year can only be year/month/day
+	if len(a) == 4 {
+		if len(c) > 2 {
+			return time.Time{}, true, fmt.Errorf("unable to parse date: %s", dateStr)
+		}
+		if date, valid := calendarDate(a, b, c); valid {
+			return date, true, nil
+		}
+		return time.Time{}, true, fmt.Errorf("%s is not a real date", dateStr)
+	}
+	if len(c) != 4 {
+		return time.Time{}, true, fmt.Errorf("unable to parse date: %s (use a 4-digit year)", dateStr)
+	}
+
+	mdy, mdyValid := calendarDate(c, a, b)
+	dmy, dmyValid := calendarDate(c, b, a)
+	switch order {
+	case DateOrderMDY:
+		if !mdyValid {
+			return time.Time{}, true, fmt.Errorf("%s is not a valid month/day/year date", dateStr)
+		}
+		return mdy, true, nil
+	case DateOrderDMY:
+		if !dmyValid {
+			return time.Time{}, true, fmt.Errorf("%s is not a valid day/month/year date", dateStr)
+		}
+		return dmy, true, nil
+	case DateOrderYMD:
+		return time.Time{}, true, fmt.Errorf("%s is not a year/month/day date", dateStr)
+	}
+
+	switch {
+	case mdyValid && dmyValid && !mdy.Equal(dmy):
+		return time.Time{}, true, fmt.Errorf("%s is ambiguous: %s or %s; write it as YYYY-MM-DD",
+			dateStr, mdy.Format("January 2, 2006"), dmy.Format("2 January 2006"))
+	case mdyValid:
+		return mdy, true, nil
+	case dmyValid:
+		return dmy, true, nil
+	}
+	return time.Time{}, true, fmt.Errorf("%s is not a real date", dateStr)
+}
+
+// calendarDate returns the date if the numbers name a real day
+func calendarDate(year, month, day string) (time.Time, bool) {
+	y, _ := strconv.Atoi(year)
+	m, _ := strconv.Atoi(month)
+	d, _ := strconv.Atoi(day)
+	date := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	if date.Year() != y || int(date.Month()) != m || date.Day() != d {
+		return time.Time{}, false
+	}
+	return date, true
+}
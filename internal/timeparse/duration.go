@@ -0,0 +1,61 @@
+package timeparse
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	decimalHours  = regexp.MustCompile(`^(?:\d+(?:\.\d*)?|\.\d+)$`)
+	clockDuration = regexp.MustCompile(`^(\d+):([0-5]\d)$`)
+	unitDuration  = regexp.MustCompile(`^(?:(\d+(?:\.\d+)?)\s*(?:h|hr|hrs|hours?))?\s*(?:(\d+(?:\.\d+)?)\s*(?:m|min|mins|minutes?))?$`)
+)
+
+// ParseHours converts a duration to decimal hours. It accepts decimal hours
+// ("1.5"), hours and minutes ("1h30m", "1.5h", "2 hours", "90 minutes",
+// "45m") and clock notation ("2:15"). The result is always more than 0.
+func ParseHours(input string) (float64, error) {
+	hours, err := parseDuration(strings.ToLower(strings.TrimSpace(input)))
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(hours) || math.IsInf(hours, 0) || hours <= 0 {
+		return 0, fmt.Errorf("duration must be more than 0: %s", strings.TrimSpace(input))
+	}
+	return hours, nil
+}
+
+// parseDuration reads a duration in any of ParseHours' forms
+func parseDuration(input string) (float64, error) {
+	if input == "" {
+		return 0, fmt.Errorf("no duration given")
+	}
+
+	// Plain decimals only: ParseFloat alone would take NaN, Inf, signs and
+	// hex floats too
+	if decimalHours.MatchString(input) {
+		return strconv.ParseFloat(input, 64)
+	}
+
+	if matches := clockDuration.FindStringSubmatch(input); len(matches) == 3 {
+		hours, _ := strconv.Atoi(matches[1])
+		minutes, _ := strconv.Atoi(matches[2])
+		return float64(hours) + float64(minutes)/60, nil
+	}
+
+	if matches := unitDuration.FindStringSubmatch(input); len(matches) == 3 && (matches[1] != "" || matches[2] != "") {
+		var hours, minutes float64
+		if matches[1] != "" {
+			hours, _ = strconv.ParseFloat(matches[1], 64)
+		}
+		if matches[2] != "" {
+			minutes, _ = strconv.ParseFloat(matches[2], 64)
+		}
+		return hours + minutes/60, nil
+	}
+
+	return 0, fmt.Errorf("unable to parse duration: %s (use e.g. 1.5, 1h30m, 90 minutes or 1:30)", input)
+}
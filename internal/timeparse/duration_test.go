@@ -0,0 +1,60 @@
+package timeparse
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseHours(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		want  float64
+	}{
+		{"1.5", 1.5},
+		{".25", 0.25},
+		{"2", 2},
+		{"1h30m", 1.5},
+		{"1h07m", 67.0 / 60},
+		{"1.5h", 1.5},
+		{"2 hours", 2},
+		{"1 hr 15 mins", 1.25},
+		{"90 minutes", 1.5},
+		{"45m", 0.75},
+		{"2:15", 2.25},
+		{"0:05", 5.0 / 60},
+		{" 1H30M ", 1.5},
+	} {
+		got, err := ParseHours(tc.input)
+		if err != nil {
+			t.Errorf("ParseHours(%q): %v", tc.input, err)
+			continue
+		}
+		if math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("ParseHours(%q) = %g, want %g", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestParseHoursRejects(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"0",
+		"0:00",
+		"0h",
+		"-1",
+		"+1",
+		"NaN",
+		"Inf",
+		"1e3",
+		"0x1p-2",
+		"2:60",
+		"1:5",
+		"h",
+		"an hour",
+		"1h30",
+	} {
+		if got, err := ParseHours(input); err == nil {
+			t.Errorf("ParseHours(%q) = %g, want an error", input, got)
+		}
+	}
+}
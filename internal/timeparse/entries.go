@@ -0,0 +1,198 @@
+package timeparse
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ParsedEntry is the work one part of a sentence like "2 hours for Acme
+// Monday 'API work'" describes
+type ParsedEntry struct {
+	ClientName  string
+	Hours       float64
+	Dates       []time.Time
+	Description string
+}
+
+var (
+	entryDuration    = regexp.MustCompile(`(?i)\b(?:\d+(?:\.\d+)?\s*(?:hours?|hrs?|h)(?:\s*\d+\s*(?:minutes?|mins?|m))?|\d+(?:\.\d+)?\s*(?:minutes?|mins?|m)|\d+:[0-5]\d)\b`)
+	entryDescription = regexp.MustCompile(`"([^"]+)"|(?:^|\s)'([^']+)'`)
+)
+
+// entryFillers are words around a client name that aren't part of it
+var entryFillers = map[string]bool{
+	"log": true, "logged": true, "add": true, "added": true, "record": true, "i": true,
+	"worked": true, "spent": true, "did": true, "on": true, "at": true, "with": true,
+	"and": true, "then": true, "plus": true, "also": true, "&": true,
+}
+
+// ParseNaturalLanguage reads the entries in input, such as "2 hours for
+// Acme Monday and 3 hours for Globex Tuesday 'API work'"
+func ParseNaturalLanguage(input string) ([]ParsedEntry, error) {
+	return ParseNaturalLanguageAt(input, time.Now(), DateOrderAuto)
+}
+
+// ParseNaturalLanguageAt parses entries like ParseNaturalLanguage, taking
+// relative dates from now and reading numeric dates in order. Every
+// duration starts an entry that runs up to the next one and names its
+// client, after "for" or on its own, its day and a quoted description. An
+// entry without a client is for the client of the one before it and an
+// entry without a day is for today; "this week" and "last week" stand for
+// each of their weekdays.
+func ParseNaturalLanguageAt(input string, now time.Time, order DateOrder) ([]ParsedEntry, error) {
+	// Durations inside descriptions don't start entries
+	quoted := entryDescription.FindAllStringIndex(input, -1)
+	var starts []int
+	for _, loc := range entryDuration.FindAllStringIndex(input, -1) {
+		inside := false
+		for _, q := range quoted {
+			inside = inside || (loc[0] >= q[0] && loc[0] < q[1])
+		}
+		if !inside {
+			starts = append(starts, loc[0])
+		}
+	}
+	if len(starts) == 0 {
+		return nil, fmt.Errorf("no hours found in %q; say e.g. '2 hours for Acme yesterday'", input)
+	}
+
+	entries := make([]ParsedEntry, 0, len(starts))
+	for i := range starts {
+		// Words before the first duration, like "Log", belong to the first
+		// entry
+		start, end := 0, len(input)
+		if i > 0 {
+			start = starts[i]
+		}
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		entry, err := parseEntry(input[start:end], now, order)
+		if err != nil {
+			return nil, err
+		}
+		if entry.ClientName == "" {
+			if i == 0 {
+				return nil, fmt.Errorf("no client found in %q; say who the work was for, e.g. 'for Acme'", strings.TrimSpace(input[start:end]))
+			}
+			entry.ClientName = entries[i-1].ClientName
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseEntry parses the part of a sentence describing one entry
+func parseEntry(text string, now time.Time, order DateOrder) (ParsedEntry, error) {
+	var entry ParsedEntry
+	if matches := entryDescription.FindStringSubmatch(text); matches != nil {
+		entry.Description = strings.TrimSpace(matches[1] + matches[2])
+		text = strings.Replace(text, matches[0], " ", 1)
+	}
+
+	loc := entryDuration.FindStringIndex(text)
+	if loc == nil {
+		return entry, fmt.Errorf("no hours found in %q", strings.TrimSpace(text))
+	}
+	hours, err := ParseHours(text[loc[0]:loc[1]])
+	if err != nil {
+		return entry, err
+	}
+	if hours <= 0 {
+		return entry, fmt.Errorf("hours must be positive in %q", strings.TrimSpace(text))
+	}
+	entry.Hours = hours
+
+	var words, lower []string
+	for _, word := range strings.Fields(text[:loc[0]] + " " + text[loc[1]:]) {
+		if word = strings.TrimRight(word, ",;:!?."); word != "" {
+			words = append(words, word)
+			lower = append(lower, strings.ToLower(word))
+		}
+	}
+
+	// The longest run of words that reads as a day, the last one if several
+	// do and never the word after "for", so clients like "Sun Corp" aren't
+	// taken for Sunday
+	dateAt, dateLen := -1, 0
+	for n := min(4, len(words)); n >= 1 && dateAt < 0; n-- {
+		for i := len(words) - n; i >= 0; i-- {
+			if i > 0 && lower[i-1] == "for" {
+				continue
+			}
+			dates, ok, err := entryDates(strings.Join(lower[i:i+n], " "), now, order)
+			if err != nil {
+				return entry, err
+			}
+			if ok {
+				entry.Dates, dateAt, dateLen = dates, i, n
+				break
+			}
+		}
+	}
+	if dateAt < 0 {
+		entry.Dates = []time.Time{now}
+	} else {
+		if dateAt > 0 && lower[dateAt-1] == "on" {
+			dateAt, dateLen = dateAt-1, dateLen+1
+		}
+		words = append(words[:dateAt:dateAt], words[dateAt+dateLen:]...)
+		lower = append(lower[:dateAt:dateAt], lower[dateAt+dateLen:]...)
+	}
+
+	// The client follows "for", or is what's left
+	for i, word := range lower {
+		if word == "for" {
+			words, lower = words[i+1:], lower[i+1:]
+			break
+		}
+	}
+	for len(lower) > 0 && entryFillers[lower[0]] {
+		words, lower = words[1:], lower[1:]
+	}
+	for len(lower) > 0 && entryFillers[lower[len(lower)-1]] {
+		words, lower = words[:len(words)-1], lower[:len(lower)-1]
+	}
+	entry.ClientName = strings.Join(words, " ")
+	return entry, nil
+}
+
+// entryDates returns the days phrase stands for. ok is false when it isn't
+// a day; err is set for numeric dates that don't exist or are ambiguous.
+func entryDates(phrase string, now time.Time, order DateOrder) (dates []time.Time, ok bool, err error) {
+	switch phrase {
+	case "this week":
+		return weekDates(now, 0), true, nil
+	case "last week":
+		return weekDates(now, -1), true, nil
+	}
+	// Other weeks and months aren't one day to log work on
+	if strings.HasSuffix(phrase, "week") || strings.HasSuffix(phrase, "month") {
+		return nil, false, nil
+	}
+	if _, ok, err := parseNumericDate(phrase, order); ok && err != nil {
+		return nil, true, err
+	}
+	date, _, err := parseRangeDate(phrase, now, now.Year(), order)
+	if err != nil {
+		return nil, false, nil
+	}
+	return []time.Time{date}, true, nil
+}
+
+// weekDates returns Monday to Friday of the week weekOffset weeks from now's
+func weekDates(now time.Time, weekOffset int) []time.Time {
+	weekday := int(now.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	monday := now.AddDate(0, 0, 1-weekday+weekOffset*7)
+
+	dates := []time.Time{}
+	for i := 0; i < 5; i++ {
+		dates = append(dates, monday.AddDate(0, 0, i))
+	}
+	return dates
+}
@@ -0,0 +1,94 @@
+package timeparse
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseNaturalLanguageAt(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+
+	type entry struct {
+		client      string
+		hours       float64
+		dates       []string
+		description string
+	}
+	for _, tc := range []struct {
+		input string
+		order DateOrder
+		want  []entry
+	}{
+		{
+			input: "2 hours for Acme",
+			want:  []entry{{"Acme", 2, []string{"2026-10-14"}, ""}},
+		},
+		{
+			input: "Log 2 hours for Acme Monday and 3 hours for Globex Tuesday 'API work'",
+			want: []entry{
+				{"Acme", 2, []string{"2026-10-12"}, ""},
+				{"Globex", 3, []string{"2026-10-13"}, "API work"},
+			},
+		},
+		{
+			input: "1h30m for Sun Corp yesterday",
+			want:  []entry{{"Sun Corp", 1.5, []string{"2026-10-13"}, ""}},
+		},
+		{
+			input: "45m for Acme on last tuesday and 2:15 on friday",
+			want: []entry{
+				{"Acme", 0.75, []string{"2026-10-13"}, ""},
+				{"Acme", 2.25, []string{"2026-10-09"}, ""},
+			},
+		},
+		{
+			input: `8 hours for AC-001 last week "migration, 2 hours of it reviews"`,
+			want: []entry{{"AC-001", 8, []string{"2026-10-05", "2026-10-06", "2026-10-07", "2026-10-08", "2026-10-09"},
+				"migration, 2 hours of it reviews"}},
+		},
+		{
+			input: "3 hours for Acme 03/04/2026",
+			order: DateOrderDMY,
+			want:  []entry{{"Acme", 3, []string{"2026-04-03"}, ""}},
+		},
+		{
+			input: "3 hours for Acme 03/04/2026",
+			order: DateOrderMDY,
+			want:  []entry{{"Acme", 3, []string{"2026-03-04"}, ""}},
+		},
+	} {
+		parsed, err := ParseNaturalLanguageAt(tc.input, now, tc.order)
+		if err != nil {
+			t.Errorf("ParseNaturalLanguageAt(%q): %v", tc.input, err)
+			continue
+		}
+		var got []entry
+		for _, p := range parsed {
+			e := entry{client: p.ClientName, hours: p.Hours, description: p.Description}
+			for _, d := range p.Dates {
+				e.dates = append(e.dates, d.Format("2006-01-02"))
+			}
+			got = append(got, e)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseNaturalLanguageAt(%q) = %+v, want %+v", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestParseNaturalLanguageAtRejects(t *testing.T) {
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+
+	for _, input := range []string{
+		"worked on Acme yesterday",
+		"2 hours yesterday",
+		"3 hours for Acme 03/04/2026",
+		"3 hours for Acme 31/02/2026",
+	} {
+		if got, err := ParseNaturalLanguageAt(input, now, DateOrderAuto); err == nil {
+			t.Errorf("ParseNaturalLanguageAt(%q) = %+v, want an error", input, got)
+		}
+	}
+}
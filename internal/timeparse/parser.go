@@ -8,94 +8,59 @@ import (
 	"time"
 )
 
-type ParsedEntry struct {
-	ClientName  string
-	Hours       float64
-	Dates       []time.Time
-	Description string
+func ParseDate(dateStr string) (time.Time, error) {
+	return ParseDateAt(dateStr, time.Now())
 }
 
-func ParseNaturalLanguage(input string) (*ParsedEntry, error) {
-	input = strings.ToLower(input)
-	entry := &ParsedEntry{}
-
-	hoursPattern := regexp.MustCompile(`(\d+(?:\.\d+)?)\s*hours?`)
-	if matches := hoursPattern.FindStringSubmatch(input); len(matches) > 1 {
-		hours, err := strconv.ParseFloat(matches[1], 64)
-		if err != nil {
-			return nil, fmt.Errorf("invalid hours format: %w", err)
-		}
-		entry.Hours = hours
-	} else {
-		return nil, fmt.Errorf("no hours specified in input")
-	}
-
-	clientPattern := regexp.MustCompile(`for\s+(\w+(?:\s+\w+)?)`)
-	if matches := clientPattern.FindStringSubmatch(input); len(matches) > 1 {
-		entry.ClientName = strings.TrimSpace(matches[1])
-	} else {
-		words := strings.Fields(input)
-		for i, word := range words {
-			if i > 0 && !strings.Contains(word, "hour") && !isTimeKeyword(word) {
-				entry.ClientName = word
-				break
-			}
-		}
-	}
-
-	if entry.ClientName == "" {
-		return nil, fmt.Errorf("no client name found in input")
-	}
-
-	entry.ClientName = strings.Title(entry.ClientName)
-
-	if strings.Contains(input, "this week") {
-		entry.Dates = getThisWeekDates()
-	} else if strings.Contains(input, "last week") {
-		entry.Dates = getLastWeekDates()
-	} else if strings.Contains(input, "today") {
-		entry.Dates = []time.Time{time.Now()}
-	} else if strings.Contains(input, "yesterday") {
-		entry.Dates = []time.Time{time.Now().AddDate(0, 0, -1)}
-	} else {
-		entry.Dates = []time.Time{time.Now()}
-	}
-
-	descPattern := regexp.MustCompile(`"([^"]+)"`)
-	if matches := descPattern.FindStringSubmatch(input); len(matches) > 1 {
-		entry.Description = matches[1]
-	}
-
-	return entry, nil
+// ParseDateAt parses a date like ParseDate, taking relative dates such as
+// "today" or "last week" from now. Relative dates keep now's location, so
+// the day they fall on is the day in that time zone.
+func ParseDateAt(dateStr string, now time.Time) (time.Time, error) {
+	return ParseDateInOrder(dateStr, now, DateOrderAuto)
 }
 
-func ParseDate(dateStr string) (time.Time, error) {
+// ParseDateInOrder parses a date like ParseDateAt, reading numeric dates
+// such as 03/04/2025 in the given order
+func ParseDateInOrder(dateStr string, now time.Time, order DateOrder) (time.Time, error) {
 	dateStr = strings.ToLower(strings.TrimSpace(dateStr))
 
 	if dateStr == "today" {
-		return time.Now(), nil
+		return now, nil
 	}
 	if dateStr == "yesterday" {
-		return time.Now().AddDate(0, 0, -1), nil
+		return now.AddDate(0, 0, -1), nil
 	}
 	if dateStr == "tomorrow" {
-		return time.Now().AddDate(0, 0, 1), nil
+		return now.AddDate(0, 0, 1), nil
+	}
+
+	// A bare weekday is the latest one, today included
+	if day, ok := weekdays[dateStr]; ok {
+		return now.AddDate(0, 0, -((int(now.Weekday()) - int(day) + 7) % 7)), nil
+	}
+	if matches := ago.FindStringSubmatch(dateStr); len(matches) == 3 {
+		n, _ := strconv.Atoi(matches[1])
+		if matches[2] == "week" {
+			n *= 7
+		}
+		return now.AddDate(0, 0, -n), nil
 	}
 
 	if strings.HasPrefix(dateStr, "this ") {
-		return parseRelativeDate(dateStr, 0)
+		return parseRelativeDate(dateStr, 0, now)
 	}
 	if strings.HasPrefix(dateStr, "last ") {
-		return parseRelativeDate(dateStr, -1)
+		return parseRelativeDate(dateStr, -1, now)
 	}
 	if strings.HasPrefix(dateStr, "next ") {
-		return parseRelativeDate(dateStr, 1)
+		return parseRelativeDate(dateStr, 1, now)
+	}
+
+	if date, ok, err := parseNumericDate(dateStr, order); ok {
+		return date, err
 	}
 
 	formats := []string{
-		"2006-01-02",
-		"01/02/2006",
-		"02/01/2006",
 		"January 2, 2006",
 		"Jan 2, 2006",
 		"2 January 2006",
@@ -111,8 +76,35 @@ func ParseDate(dateStr string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
 }
 
-func parseRelativeDate(dateStr string, weekOffset int) (time.Time, error) {
-	now := time.Now()
+var ago = regexp.MustCompile(`^(\d+)\s+(day|week)s?\s+ago$`)
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// parseRelativeDate parses "this", "last" or "next" (weekOffset 0, -1 or
+// 1) followed by week, month or a weekday. "last tuesday" is the latest
+// Tuesday before today and "next tuesday" the first one after it, while
+// "this tuesday" is the Tuesday of the current week.
+func parseRelativeDate(dateStr string, weekOffset int, now time.Time) (time.Time, error) {
+	_, name, _ := strings.Cut(dateStr, " ")
+	if day, ok := weekdays[name]; ok {
+		switch weekOffset {
+		case -1:
+			back := (int(now.Weekday())-int(day)+6)%7 + 1
+			return now.AddDate(0, 0, -back), nil
+		case 1:
+			ahead := (int(day)-int(now.Weekday())+6)%7 + 1
+			return now.AddDate(0, 0, ahead), nil
+		}
+		return now.AddDate(0, 0, int(day)-int(now.Weekday())), nil
+	}
 
 	if strings.Contains(dateStr, "week") {
 		startOfWeek := now.AddDate(0, 0, -int(now.Weekday())+weekOffset*7)
@@ -130,8 +122,40 @@ func parseRelativeDate(dateStr string, weekOffset int) (time.Time, error) {
 }
 
 func ParsePeriod(period string) (time.Time, time.Time, error) {
+	return ParsePeriodAt(period, time.Now())
+}
+
+// ParsePeriodAt parses a period like ParsePeriod, taking relative periods
+// such as "this month" from now in now's location
+func ParsePeriodAt(period string, now time.Time) (time.Time, time.Time, error) {
+	return ParseFiscalPeriodAt(period, now, time.January, DateOrderAuto)
+}
+
+var (
+	quarterYear = regexp.MustCompile(`^q([1-4])\s+(?:fy\s*)?(\d{4})$`)
+	yearQuarter = regexp.MustCompile(`^(?:fy\s*)?(\d{4})[\s-]*q([1-4])$`)
+	halfYear    = regexp.MustCompile(`^h([12])\s+(?:fy\s*)?(\d{4})$`)
+	yearHalf    = regexp.MustCompile(`^(?:fy\s*)?(\d{4})[\s-]*h([12])$`)
+	wholeYear   = regexp.MustCompile(`^(?:fy\s*)?(\d{4})$`)
+	lastDays    = regexp.MustCompile(`^(?:last|past)\s+(\d+)\s+days?$`)
+	dayRange    = regexp.MustCompile(`^([a-z]+)\s+(\d{1,2})\s*(?:-|–|to)\s*(\d{1,2})(?:,?\s+(\d{4}))?$`)
+	rangeSplit  = regexp.MustCompile(`\s+(?:to|until|through|[-–])\s+`)
+)
+
+// ParseFiscalPeriodAt parses a period like ParsePeriodAt, with years,
+// halves and quarters counted from the fiscal year starting in
+// fiscalStart. Fiscal year 2025 is the one starting in 2025, so with an
+// April start "Q1 2025" is April to June 2025. Besides months and weeks it
+// understands "this quarter", "last quarter", "Q1 2025", "2025-Q1",
+// "this half", "last half", "H1 2025", "this year", "last year", "2025",
+// "FY2025" and "year to date", which ends today, and ranges such as
+// "last 30 days", "June 1-15" or "June 1 to July 3", whose numeric dates
+// are read in order.
+func ParseFiscalPeriodAt(period string, now time.Time, fiscalStart time.Month, order DateOrder) (time.Time, time.Time, error) {
 	period = strings.ToLower(strings.TrimSpace(period))
-	now := time.Now()
+	if fiscalStart < time.January || fiscalStart > time.December {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid fiscal year start month: %d", fiscalStart)
+	}
 
 	if period == "this month" || period == "current month" {
 		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
@@ -157,6 +181,79 @@ func ParsePeriod(period string) (time.Time, time.Time, error) {
 		return start, end, nil
 	}
 
+	// Months into the fiscal year, 0 in its first month
+	intoYear := (int(now.Month()) - int(fiscalStart) + 12) % 12
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	switch period {
+	case "this quarter", "current quarter", "last quarter":
+		start := thisMonth.AddDate(0, -(intoYear % 3), 0)
+		if period == "last quarter" {
+			start = start.AddDate(0, -3, 0)
+		}
+		return start, start.AddDate(0, 3, -1), nil
+	case "this half", "current half", "last half":
+		start := thisMonth.AddDate(0, -(intoYear % 6), 0)
+		if period == "last half" {
+			start = start.AddDate(0, -6, 0)
+		}
+		return start, start.AddDate(0, 6, -1), nil
+	case "this year", "current year", "last year":
+		start := thisMonth.AddDate(0, -intoYear, 0)
+		if period == "last year" {
+			start = start.AddDate(-1, 0, 0)
+		}
+		return start, start.AddDate(1, 0, -1), nil
+	case "year to date", "ytd":
+		return thisMonth.AddDate(0, -intoYear, 0), now, nil
+	}
+
+	// fiscalMonths returns the span of months starting months into the
+	// fiscal year starting in year
+	fiscalMonths := func(yearStr string, months, length int) (time.Time, time.Time, error) {
+		year, _ := strconv.Atoi(yearStr)
+		start := time.Date(year, fiscalStart+time.Month(months), 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, length, -1), nil
+	}
+
+	// Quarters such as "Q1 2025" or "2025-Q1"
+	if matches := quarterYear.FindStringSubmatch(period); len(matches) == 3 {
+		quarter, _ := strconv.Atoi(matches[1])
+		return fiscalMonths(matches[2], (quarter-1)*3, 3)
+	}
+	if matches := yearQuarter.FindStringSubmatch(period); len(matches) == 3 {
+		quarter, _ := strconv.Atoi(matches[2])
+		return fiscalMonths(matches[1], (quarter-1)*3, 3)
+	}
+
+	// Halves such as "H1 2025" or "2025-H2"
+	if matches := halfYear.FindStringSubmatch(period); len(matches) == 3 {
+		half, _ := strconv.Atoi(matches[1])
+		return fiscalMonths(matches[2], (half-1)*6, 6)
+	}
+	if matches := yearHalf.FindStringSubmatch(period); len(matches) == 3 {
+		half, _ := strconv.Atoi(matches[2])
+		return fiscalMonths(matches[1], (half-1)*6, 6)
+	}
+
+	// Whole years such as "2025" or "FY2025"
+	if matches := wholeYear.FindStringSubmatch(period); len(matches) == 2 {
+		return fiscalMonths(matches[1], 0, 12)
+	}
+
+	// The last n days up to and including today
+	if matches := lastDays.FindStringSubmatch(period); len(matches) == 2 {
+		days, _ := strconv.Atoi(matches[1])
+		if days < 1 {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid number of days: %d", days)
+		}
+		return now.AddDate(0, 0, 1-days), now, nil
+	}
+
+	if start, end, ok, err := parseRange(period, now, order); ok {
+		return start, end, err
+	}
+
 	monthYear := regexp.MustCompile(`(\w+)\s+(\d{4})`)
 	if matches := monthYear.FindStringSubmatch(period); len(matches) == 3 {
 		monthName := matches[1]
@@ -180,6 +277,73 @@ func ParsePeriod(period string) (time.Time, time.Time, error) {
 	return time.Time{}, time.Time{}, fmt.Errorf("unable to parse period: %s", period)
 }
 
+// parseRange parses a range of days such as "june 1-15", "june 1 to july
+// 3" or "2025-06-01 to 2025-06-30". Days without a year are in the year of
+// the end of the range, or this year. ok is false when period isn't a
+// range.
+func parseRange(period string, now time.Time, order DateOrder) (start, end time.Time, ok bool, err error) {
+	if matches := dayRange.FindStringSubmatch(period); len(matches) == 5 {
+		month, err := parseMonth(matches[1])
+		if err != nil {
+			return time.Time{}, time.Time{}, false, nil
+		}
+		year := now.Year()
+		if matches[4] != "" {
+			year, _ = strconv.Atoi(matches[4])
+		}
+		first, _ := strconv.Atoi(matches[2])
+		last, _ := strconv.Atoi(matches[3])
+		start = time.Date(year, month, first, 0, 0, 0, 0, now.Location())
+		end = time.Date(year, month, last, 0, 0, 0, 0, now.Location())
+		if start.Month() != month || end.Month() != month {
+			return time.Time{}, time.Time{}, true, fmt.Errorf("invalid day in %s", period)
+		}
+		if end.Before(start) {
+			return time.Time{}, time.Time{}, true, fmt.Errorf("range ends before it starts: %s", period)
+		}
+		return start, end, true, nil
+	}
+
+	parts := rangeSplit.Split(period, 2)
+	if len(parts) != 2 {
+		return time.Time{}, time.Time{}, false, nil
+	}
+	end, _, err = parseRangeDate(parts[1], now, now.Year(), order)
+	if err != nil {
+		return time.Time{}, time.Time{}, true, err
+	}
+	start, withYear, err := parseRangeDate(parts[0], now, end.Year(), order)
+	if err != nil {
+		return time.Time{}, time.Time{}, true, err
+	}
+	// "December 20 to January 5" crosses into the end's year
+	if !withYear && start.After(end) {
+		start = start.AddDate(-1, 0, 0)
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, true, fmt.Errorf("range ends before it starts: %s", period)
+	}
+	return start, end, true, nil
+}
+
+// parseRangeDate parses one end of a range: a date ParseDateInOrder accepts,
+// or a day like "june 1" in year. withYear tells whether the date named
+// its year.
+func parseRangeDate(dateStr string, now time.Time, year int, order DateOrder) (date time.Time, withYear bool, err error) {
+	dateStr = strings.TrimSuffix(strings.TrimSpace(dateStr), ",")
+	for _, format := range []string{"January 2", "Jan 2", "2 January", "2 Jan"} {
+		if t, err := time.ParseInLocation(format, dateStr, now.Location()); err == nil {
+			return time.Date(year, t.Month(), t.Day(), 0, 0, 0, 0, now.Location()), false, nil
+		}
+	}
+	date, err = ParseDateInOrder(dateStr, now, order)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	// Absolute dates are parsed as UTC; keep both ends in one location
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, now.Location()), true, nil
+}
+
 func parseMonth(monthStr string) (time.Month, error) {
 	monthStr = strings.ToLower(monthStr)
 	months := map[string]time.Month{
@@ -214,43 +378,3 @@ func parseMonth(monthStr string) (time.Month, error) {
 
 	return 0, fmt.Errorf("invalid month: %s", monthStr)
 }
-
-func getThisWeekDates() []time.Time {
-	now := time.Now()
-	weekday := int(now.Weekday())
-	if weekday == 0 {
-		weekday = 7
-	}
-	monday := now.AddDate(0, 0, 1-weekday)
-
-	dates := []time.Time{}
-	for i := 0; i < 5; i++ {
-		dates = append(dates, monday.AddDate(0, 0, i))
-	}
-	return dates
-}
-
-func getLastWeekDates() []time.Time {
-	now := time.Now()
-	weekday := int(now.Weekday())
-	if weekday == 0 {
-		weekday = 7
-	}
-	lastMonday := now.AddDate(0, 0, 1-weekday-7)
-
-	dates := []time.Time{}
-	for i := 0; i < 5; i++ {
-		dates = append(dates, lastMonday.AddDate(0, 0, i))
-	}
-	return dates
-}
-
-func isTimeKeyword(word string) bool {
-	keywords := []string{"today", "yesterday", "tomorrow", "week", "month", "this", "last", "next", "for"}
-	for _, kw := range keywords {
-		if word == kw {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,226 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Workbook is a minimal Office Open XML spreadsheet writer supporting text,
+// number and date cells with a bold header row
+type Workbook struct {
+	sheets []*Sheet
+}
+
+// Sheet is a named worksheet; the first row is styled as a header
+type Sheet struct {
+	Name   string
+	rows   [][]interface{}
+	widths []float64
+}
+
+// Style indices into the cellXfs table in styles.xml
+const (
+	styleDefault = 0
+	styleHeader  = 1
+	styleDate    = 2
+	styleNumber  = 3
+)
+
+// AddSheet appends a worksheet. Names longer than 31 characters are truncated.
+func (w *Workbook) AddSheet(name string) *Sheet {
+	if len(name) > 31 {
+		name = name[:31]
+	}
+	s := &Sheet{Name: name}
+	w.sheets = append(w.sheets, s)
+	return s
+}
+
+// AddRow appends a row. Cells may be string, float64, int or time.Time.
+func (s *Sheet) AddRow(cells ...interface{}) {
+	s.rows = append(s.rows, cells)
+	for i, cell := range cells {
+		width := 10.0
+		switch v := cell.(type) {
+		case string:
+			width = math.Min(float64(len(v))+2, 60)
+		case time.Time:
+			width = 12
+		}
+		for len(s.widths) <= i {
+			s.widths = append(s.widths, 8)
+		}
+		if width > s.widths[i] {
+			s.widths[i] = width
+		}
+	}
+}
+
+// Write encodes the workbook as an .xlsx file
+func (w *Workbook) Write(out io.Writer) error {
+	if len(w.sheets) == 0 {
+		return fmt.Errorf("workbook has no sheets")
+	}
+
+	zw := zip.NewWriter(out)
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", w.contentTypes()},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", w.workbook()},
+		{"xl/_rels/workbook.xml.rels", w.workbookRels()},
+		{"xl/styles.xml", styles},
+	}
+	for i, sheet := range w.sheets {
+		files = append(files, struct {
+			name    string
+			content string
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheet.xml()})
+	}
+
+	for _, file := range files {
+		f, err := zw.Create(file.name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", file.name, err)
+		}
+		if _, err := io.WriteString(f, file.content); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+	return zw.Close()
+}
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+const rootRels = xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const styles = xmlHeader + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="14" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="2" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+func (w *Workbook) contentTypes() string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range w.sheets {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func (w *Workbook) workbook() string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, sheet := range w.sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(sheet.Name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+	return b.String()
+}
+
+func (w *Workbook) workbookRels() string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range w.sheets {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(w.sheets)+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func (s *Sheet) xml() string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	// Keep the header row visible while scrolling
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	if len(s.widths) > 0 {
+		b.WriteString(`<cols>`)
+		for i, width := range s.widths {
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%.1f" customWidth="1"/>`, i+1, i+1, width)
+		}
+		b.WriteString(`</cols>`)
+	}
+	b.WriteString(`<sheetData>`)
+	for r, row := range s.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := columnName(c) + strconv.Itoa(r+1)
+			style := styleDefault
+			if r == 0 {
+				style = styleHeader
+			}
+			switch v := cell.(type) {
+			case nil:
+				continue
+			case string:
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr" s="%d"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(v))
+			case int:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%d</v></c>`, ref, style, v)
+			case float64:
+				if style == styleDefault {
+					style = styleNumber
+				}
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(v, 'f', -1, 64))
+			case time.Time:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, styleDate, strconv.FormatFloat(serialDate(v), 'f', -1, 64))
+			default:
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr" s="%d"><is><t>%s</t></is></c>`, ref, style, escape(fmt.Sprint(v)))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// columnName converts a zero-based column index to A, B, ..., Z, AA, ...
+func columnName(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
+
+// serialDate converts a date to an Excel serial day number (1900 date system)
+func serialDate(t time.Time) float64 {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.Sub(epoch).Hours() / 24
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -2,10 +2,18 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
+	// Time zone names work on systems without a zoneinfo database
+	_ "time/tzdata"
 
 	"github.com/austin/hours-mcp/internal/database"
+	"github.com/austin/hours-mcp/internal/logging"
+	"github.com/austin/hours-mcp/internal/secrets"
 	"github.com/austin/hours-mcp/internal/server"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
@@ -14,32 +22,142 @@ import (
 var version = "dev"
 
 func main() {
+	showVersion := flag.Bool("version", false, "Print the version and exit")
+	flag.BoolVar(showVersion, "v", false, "Print the version and exit")
+	dbFlag := flag.String("db", "", "Database file, or :memory: for a temporary in-memory database (default: $"+database.PathEnv+" or ~/.hours/db)")
+	encryptDB := flag.Bool("encrypt-db", false, "Encrypt the existing plaintext database with the key from $"+secrets.DatabaseKeyEnv+" or the keychain, then exit (sqlcipher builds only)")
+	migrateDryRun := flag.Bool("migrate-dry-run", false, "Print the migrations that would run (or be rolled back with --migrate-rollback), then exit without changing the database")
+	logLevel := flag.String("log-level", "", "Log level: debug, info, warn or error (default: $"+logging.LevelEnv+" or info)")
+	logFile := flag.String("log-file", "", "Append logs to this file instead of stderr (default: $"+logging.FileEnv+")")
+	httpAddr := flag.String("http", "", "Serve MCP over streamable HTTP on this address, e.g. :8080, instead of stdio; clients must send the bearer token from $"+secrets.HTTPTokenEnv+" or the keychain")
+	migrateRollback := flag.Int("migrate-rollback", 0, "Roll back the last N applied migrations after a backup, then exit; run the version you are downgrading to afterwards, as starting this one applies them again")
+	flag.Parse()
+
 	// Handle version flag
-	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-v") {
+	if *showVersion {
 		fmt.Printf("hours-mcp version %s\n", version)
 		os.Exit(0)
 	}
 
+	// Logs never go to stdout, which carries the MCP protocol
+	closeLog, err := logging.Setup(*logLevel, *logFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to set up logging: %v\n", err)
+		os.Exit(1)
+	}
+	defer closeLog()
+
+	// Check the token before opening the database, so a misconfigured
+	// server doesn't run migrations it then can't serve
+	var httpToken string
+	if *httpAddr != "" {
+		if httpToken = secrets.HTTPToken(); httpToken == "" {
+			slog.Error("serving over HTTP requires a bearer token", "env", secrets.HTTPTokenEnv)
+			os.Exit(1)
+		}
+	}
+
 	// Initialize database
-	db, err := database.Initialize()
+	dbPath, err := database.ResolvePath(*dbFlag)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to initialize database: %v\n", err)
+		slog.Error("failed to initialize database", "error", err)
+		os.Exit(1)
+	}
+	if *encryptDB {
+		plaintextPath, err := database.EncryptDatabase(dbPath)
+		if err != nil {
+			slog.Error("failed to encrypt database", "error", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Encrypted %s. The plaintext copy is at %s; delete it, and any older backups, once the server starts with the encrypted database.\n", dbPath, plaintextPath)
+		os.Exit(0)
+	}
+	if *migrateRollback > 0 {
+		steps, err := database.RollbackMigrations(dbPath, *migrateRollback, *migrateDryRun)
+		if *migrateDryRun && err == nil {
+			fmt.Printf("Would roll back %d migrations of %s, newest first:\n", len(steps), dbPath)
+			printMigrationSteps(steps)
+			os.Exit(0)
+		}
+		if err != nil {
+			slog.Error("failed to roll back migrations", "error", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Rolled back %d migrations of %s. A pre-rollback backup was taken first.\n", len(steps), dbPath)
+		os.Exit(0)
+	}
+	if *migrateDryRun {
+		steps, err := database.PendingMigrations(dbPath)
+		if err != nil {
+			slog.Error("failed to plan migrations", "error", err)
+			os.Exit(1)
+		}
+		if len(steps) == 0 {
+			fmt.Printf("%s is up to date; no migrations would run.\n", dbPath)
+			os.Exit(0)
+		}
+		fmt.Printf("Would apply %d migrations to %s, after backing it up:\n", len(steps), dbPath)
+		printMigrationSteps(steps)
+		os.Exit(0)
+	}
+	db, err := database.Initialize(dbPath)
+	if err != nil {
+		slog.Error("failed to initialize database", "error", err)
 		os.Exit(1)
 	}
 	defer db.Close()
 
-	// Create MCP server
-	mcpServer := mcp.NewServer(&mcp.Implementation{
+	// Create the MCP server with its tools and resources
+	mcpServer := server.New(&mcp.Implementation{
 		Name:    "hours-mcp",
 		Version: version,
-	}, nil)
+	}, db)
+
+	// Run a subcommand from the terminal instead of serving
+	if flag.NArg() > 0 {
+		if err := server.RunCLI(context.Background(), mcpServer, flag.Args(), os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Warn about contracts that need renewing
+	server.WarnExpiringContracts(db)
 
-	// Register tools with the server
-	server.RegisterTools(mcpServer, db)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	// Take scheduled backups while the server runs
+	server.StartBackupScheduler(ctx, db)
+	// Stop timers left running past the timer_max_hours limit
+	server.StartTimerWatchdog(ctx, db)
+
+	if *httpAddr != "" {
+		// Email the weekly digest while serving; stdio sessions end too
+		// soon to keep a schedule
+		server.StartDigestScheduler(ctx, db)
+		if err := server.ServeHTTP(ctx, mcpServer, *httpAddr, httpToken); err != nil {
+			slog.Error("server error", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	// Run the server on stdio transport
-	if err := mcpServer.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
-		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+	if err := mcpServer.Run(ctx, &mcp.StdioTransport{}); err != nil {
+		slog.Error("server error", "error", err)
 		os.Exit(1)
 	}
 }
+
+// printMigrationSteps lists migrations for a dry run
+func printMigrationSteps(steps []database.MigrationStep) {
+	for _, step := range steps {
+		note := ""
+		if !step.Reversible {
+			note = " (can't be rolled back)"
+		}
+		fmt.Printf("- %s: %s%s\n", step.Name, step.Description, note)
+	}
+}
@@ -5,6 +5,15 @@ set -e
 
 VERSION=${1:-"dev"}
 OUTPUT_DIR="dist"
+TAGS=${TAGS:-"sqlite_fts5"}
+# PUREGO=1 builds every target with the pure Go SQLite driver, without cgo
+# or a C cross-compiler
+PUREGO=${PUREGO:-""}
+CGO=1
+if [ -n "$PUREGO" ]; then
+    TAGS="purego"
+    CGO=0
+fi
 
 echo "🚀 Building Hours MCP v${VERSION} for all platforms..."
 
@@ -31,8 +40,8 @@ for target in "${targets[@]}"; do
 
     echo "📦 Building ${GOOS}/${GOARCH}..."
 
-    env GOOS=$GOOS GOARCH=$GOARCH CGO_ENABLED=1 \
-        go build -ldflags="-s -w -X main.version=${VERSION}" \
+    env GOOS=$GOOS GOARCH=$GOARCH CGO_ENABLED=$CGO \
+        go build -tags "${TAGS}" -ldflags="-s -w -X main.version=${VERSION}" \
         -o "${OUTPUT_DIR}/${output_name}" .
 
     echo "✅ Built ${output_name}"